@@ -0,0 +1,80 @@
+package rex
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// AcceptContentTypes returns a middleware that rejects requests whose Content-Type
+// does not match one of the allowed media types before the body is read.
+// Wildcards like "application/*" and "*/*" are supported. Parameters such as
+// charset or the multipart boundary are ignored when matching.
+//
+// Requests without a body (e.g GET, HEAD, DELETE) pass through untouched.
+// Rejected requests receive a FormError of kind InvalidContentType which the
+// default error handler maps to 415 Unsupported Media Type.
+// The Accept-Post or Accept-Patch header is set to the list of allowed types.
+//
+// Example:
+//
+//	r.POST("/users", createUser, rex.AcceptContentTypes("application/json"))
+func AcceptContentTypes(contentTypes ...string) Middleware {
+	allowed := make([]string, 0, len(contentTypes))
+	for _, ct := range contentTypes {
+		allowed = append(allowed, strings.ToLower(strings.TrimSpace(ct)))
+	}
+	acceptValue := strings.Join(allowed, ", ")
+
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			if !hasBody(c.Request) {
+				return next(c)
+			}
+
+			mediaType, _, err := mime.ParseMediaType(c.Request.Header.Get("Content-Type"))
+			if err == nil && matchMediaType(allowed, mediaType) {
+				return next(c)
+			}
+
+			switch c.Request.Method {
+			case http.MethodPost:
+				c.SetHeader("Accept-Post", acceptValue)
+			case http.MethodPatch:
+				c.SetHeader("Accept-Patch", acceptValue)
+			}
+
+			return FormError{
+				Err:  fmt.Errorf("unsupported content type: %q, expected one of: %s", c.ContentType(), acceptValue),
+				Kind: InvalidContentType,
+			}
+		}
+	}
+}
+
+// hasBody reports whether the request is expected to carry a body.
+func hasBody(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return false
+	}
+	return req.ContentLength != 0 || len(req.TransferEncoding) > 0
+}
+
+// matchMediaType reports whether mediaType matches any of the allowed patterns.
+func matchMediaType(allowed []string, mediaType string) bool {
+	mediaType = strings.ToLower(mediaType)
+	for _, pattern := range allowed {
+		if pattern == "*/*" || pattern == mediaType {
+			return true
+		}
+
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok {
+			if strings.HasPrefix(mediaType, prefix+"/") {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package rex_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestAcceptContentTypes(t *testing.T) {
+	r := rex.NewRouter()
+
+	handler := func(c *rex.Context) error {
+		return c.String("ok")
+	}
+
+	r.POST("/json", handler, rex.AcceptContentTypes("application/json"))
+	r.PATCH("/json", handler, rex.AcceptContentTypes("application/json"))
+	r.GET("/json", handler, rex.AcceptContentTypes("application/json"))
+	r.DELETE("/json", handler, rex.AcceptContentTypes("application/json"))
+	r.POST("/any", handler, rex.AcceptContentTypes("application/*"))
+	r.POST("/upload", handler, rex.AcceptContentTypes("multipart/form-data"))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		header      string
+	}{
+		{"json allowed", http.MethodPost, "/json", "application/json", `{}`, http.StatusOK, ""},
+		{"json with charset", http.MethodPost, "/json", "application/json; charset=utf-8", `{}`, http.StatusOK, ""},
+		{"text plain rejected", http.MethodPost, "/json", "text/plain", `{}`, http.StatusUnsupportedMediaType, "Accept-Post"},
+		{"missing content type", http.MethodPost, "/json", "", `{}`, http.StatusUnsupportedMediaType, "Accept-Post"},
+		{"patch rejected", http.MethodPatch, "/json", "text/plain", `{}`, http.StatusUnsupportedMediaType, "Accept-Patch"},
+		{"get without body", http.MethodGet, "/json", "", "", http.StatusOK, ""},
+		{"delete passes through", http.MethodDelete, "/json", "text/plain", "", http.StatusOK, ""},
+		{"wildcard xml", http.MethodPost, "/any", "application/xml", `<a/>`, http.StatusOK, ""},
+		{"wildcard rejects text", http.MethodPost, "/any", "text/html", `<a/>`, http.StatusUnsupportedMediaType, "Accept-Post"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req *http.Request
+			if tt.body == "" {
+				req = httptest.NewRequest(tt.method, tt.path, nil)
+			} else {
+				req = httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			}
+
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if tt.header != "" && w.Header().Get(tt.header) == "" {
+				t.Errorf("expected %s header to be set", tt.header)
+			}
+		})
+	}
+
+	// The multipart boundary must not confuse the matcher.
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	mw.WriteField("name", "rex")
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for multipart with boundary, got %d", w.Code)
+	}
+}
+
+func TestUnsupportedContentTypeStatus(t *testing.T) {
+	r := rex.NewRouter()
+
+	r.POST("/users", func(c *rex.Context) error {
+		var u User
+		return c.BodyParser(&u)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(`{"name": "rex"}`))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status 415, got %d", w.Code)
+	}
+}
@@ -0,0 +1,337 @@
+package rex
+
+import (
+	_ "embed"
+	"html/template"
+	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BuildInfo describes the deployed build, served by AdminHandler.
+type BuildInfo struct {
+	Version   string `json:"version,omitempty"`    // Release version, e.g "v1.4.2".
+	Commit    string `json:"commit,omitempty"`     // VCS revision.
+	Date      string `json:"date,omitempty"`       // Build or commit time.
+	Modified  bool   `json:"modified,omitempty"`   // The working tree had uncommitted changes.
+	Module    string `json:"module,omitempty"`     // Path of the main module.
+	GoVersion string `json:"go_version,omitempty"` // Go version the binary was built with.
+}
+
+// WithBuildInfo sets the build information served by AdminHandler, usually injected
+// with -ldflags:
+//
+//	// go build -ldflags "-X main.version=v1.4.2 -X main.commit=$(git rev-parse HEAD)"
+//	r := rex.NewRouter(rex.WithBuildInfo(rex.BuildInfo{Version: version, Commit: commit}))
+//
+// Empty fields are filled from the build information embedded by the go command.
+func WithBuildInfo(info BuildInfo) RouterOption {
+	return func(r *Router) {
+		r.buildInfo = info
+	}
+}
+
+// AdminOption configures AdminHandler.
+type AdminOption func(*adminConfig)
+
+type adminConfig struct {
+	sensitive bool
+}
+
+// IncludeSensitive adds values that help to map the deployment to the admin endpoints:
+// the trusted proxy ranges, the source locations of the routes and the last error messages.
+func IncludeSensitive() AdminOption {
+	return func(cfg *adminConfig) {
+		cfg.sensitive = true
+	}
+}
+
+// AdminRoute is a route listed by the /routes admin endpoint.
+type AdminRoute struct {
+	Method     string   `json:"method"`
+	Path       string   `json:"path"`
+	Handler    string   `json:"handler"`
+	Group      string   `json:"group,omitempty"`      // Prefix of the group the route was registered on.
+	Middleware []string `json:"middleware,omitempty"` // Function names, from the outermost.
+	Location   string   `json:"location,omitempty"`   // file:line of the registration, with IncludeSensitive.
+}
+
+// AdminTemplates is the template configuration served by the /templates admin endpoint.
+type AdminTemplates struct {
+	Names         []string `json:"names"`
+	BaseLayout    string   `json:"base_layout"`
+	ContentBlock  string   `json:"content_block"`
+	ErrorTemplate string   `json:"error_template"`
+}
+
+// AdminConfig is the router configuration served by the /config admin endpoint.
+type AdminConfig struct {
+	StrictHome          bool     `json:"strict_home"`
+	NoTrailingSlash     bool     `json:"no_trailing_slash"`
+	ServeMinified       bool     `json:"serve_minified"`
+	Debug               bool     `json:"debug"`
+	BasePath            string   `json:"base_path"`
+	AutoHEAD            bool     `json:"auto_head"`
+	AutoContinue        bool     `json:"auto_continue"`
+	NormalizePaths      bool     `json:"normalize_paths"`
+	AllowEncodedSlashes bool     `json:"allow_encoded_slashes"`
+	MaxResponseBytes    int64    `json:"max_response_bytes"`
+	MultipartMemory     int64    `json:"multipart_memory"`
+	Frozen              bool     `json:"frozen"`
+	TrustedProxies      []string `json:"trusted_proxies,omitempty"` // With IncludeSensitive.
+}
+
+// AdminRouteStats are the counters of a route served by the /stats admin endpoint.
+type AdminRouteStats struct {
+	Requests  int64            `json:"requests"`
+	Errors    int64            `json:"errors"`
+	InFlight  int64            `json:"in_flight"`
+	Statuses  map[string]int64 `json:"statuses,omitempty"`   // Responses by status class, e.g "2xx".
+	LastError string           `json:"last_error,omitempty"` // With IncludeSensitive.
+	LastAt    *time.Time       `json:"last_at,omitempty"`
+}
+
+// AdminStats are the request counters served by the /stats admin endpoint.
+type AdminStats struct {
+	AdminRouteStats
+	Routes map[string]AdminRouteStats `json:"routes"` // Keyed by method and pattern.
+}
+
+// AdminOverview is served by the admin index, with all the sections.
+type AdminOverview struct {
+	Build     BuildInfo      `json:"build"`
+	Config    AdminConfig    `json:"config"`
+	Templates AdminTemplates `json:"templates"`
+	Routes    []AdminRoute   `json:"routes"`
+	Stats     AdminStats     `json:"stats"`
+}
+
+//go:embed admin.html
+var adminPage string
+
+var adminTemplate = template.Must(template.New("admin").Parse(adminPage))
+
+// AdminHandler mounts read-only JSON endpoints describing what is deployed at prefix:
+//
+//   - /routes: the registered routes with their group, handler and middleware names.
+//   - /templates: the defined template names and the layout configuration.
+//   - /config: a snapshot of the router options and package settings.
+//   - /build: the build information, see WithBuildInfo.
+//   - /stats: the request, error, in-flight and status class counters of the routes.
+//
+// The index at prefix serves all the sections as JSON, or as an HTML page to browsers
+// accepting text/html. All routes run guard, which should restrict access e.g with basic auth.
+// It panics if guard is nil. Sensitive values are left out unless IncludeSensitive is passed.
+//
+// Example:
+//
+//	r.AdminHandler("/_admin", auth.BasicAuth("admin", os.Getenv("ADMIN_PASSWORD")))
+func (r *Router) AdminHandler(prefix string, guard Middleware, options ...AdminOption) {
+	if guard == nil {
+		panic("rex: AdminHandler requires a guard middleware")
+	}
+
+	var cfg adminConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	prefix = strings.TrimSuffix(prefix, "/")
+	build := r.fillBuildInfo()
+
+	serve := func(section func() any) HandlerFunc {
+		return func(c *Context) error {
+			c.SetHeader("Cache-Control", "no-store")
+			return c.JSON(section())
+		}
+	}
+
+	index := prefix
+	if index == "" {
+		index = "/{$}"
+	}
+
+	r.GET(index, func(c *Context) error {
+		overview := AdminOverview{
+			Build:     build,
+			Config:    r.adminConfig(cfg),
+			Templates: r.adminTemplates(),
+			Routes:    r.adminRoutes(cfg),
+			Stats:     r.adminStats(cfg),
+		}
+
+		c.SetHeader("Cache-Control", "no-store")
+		if !strings.Contains(c.Request.Header.Get("Accept"), ContentTypeHTML) {
+			return c.JSON(overview)
+		}
+
+		var buf strings.Builder
+		if err := adminTemplate.Execute(&buf, overview); err != nil {
+			return err
+		}
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		return c.HTML(buf.String())
+	}, guard)
+
+	r.GET(prefix+"/routes", serve(func() any { return r.adminRoutes(cfg) }), guard)
+	r.GET(prefix+"/templates", serve(func() any { return r.adminTemplates() }), guard)
+	r.GET(prefix+"/config", serve(func() any { return r.adminConfig(cfg) }), guard)
+	r.GET(prefix+"/build", serve(func() any { return build }), guard)
+	r.GET(prefix+"/stats", serve(func() any { return r.adminStats(cfg) }), guard)
+}
+
+// fillBuildInfo returns the build information set with WithBuildInfo, completed
+// with the information embedded in the binary.
+func (r *Router) fillBuildInfo() BuildInfo {
+	info := r.buildInfo
+	if info.GoVersion == "" {
+		info.GoVersion = runtime.Version()
+	}
+
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+
+	if info.Module == "" {
+		info.Module = bi.Main.Path
+	}
+
+	if info.Version == "" && bi.Main.Version != "(devel)" {
+		info.Version = bi.Main.Version
+	}
+
+	for _, setting := range bi.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if info.Commit == "" {
+				info.Commit = setting.Value
+			}
+		case "vcs.time":
+			if info.Date == "" {
+				info.Date = setting.Value
+			}
+		case "vcs.modified":
+			info.Modified = info.Modified || setting.Value == "true"
+		}
+	}
+	return info
+}
+
+// adminRoutes lists the registered routes sorted by pattern and method.
+func (r *Router) adminRoutes(cfg adminConfig) []AdminRoute {
+	routes := make([]AdminRoute, 0, len(r.routes))
+	for _, route := range r.routes {
+		ar := AdminRoute{
+			Method:  route.method,
+			Path:    route.pattern,
+			Handler: route.handlerName(),
+			Group:   route.group,
+		}
+
+		for _, mw := range slices.Concat(route.chain, route.inner) {
+			ar.Middleware = append(ar.Middleware, getFuncName(mw))
+		}
+
+		if cfg.sensitive {
+			ar.Location = route.location
+		}
+		routes = append(routes, ar)
+	}
+
+	slices.SortFunc(routes, func(a, b AdminRoute) int {
+		if c := strings.Compare(a.Path, b.Path); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Method, b.Method)
+	})
+	return routes
+}
+
+func (r *Router) adminTemplates() AdminTemplates {
+	names := r.DefinedTemplateNames()
+	if names == nil {
+		names = []string{}
+	}
+
+	return AdminTemplates{
+		Names:         names,
+		BaseLayout:    r.baseLayout,
+		ContentBlock:  r.contentBlock,
+		ErrorTemplate: r.errorTemplate,
+	}
+}
+
+func (r *Router) adminConfig(cfg adminConfig) AdminConfig {
+	config := AdminConfig{
+		StrictHome:          StrictHome,
+		NoTrailingSlash:     NoTrailingSlash,
+		ServeMinified:       ServeMinified,
+		Debug:               Debug,
+		BasePath:            r.basePath,
+		AutoHEAD:            r.autoHEAD,
+		AutoContinue:        r.autoContinue,
+		NormalizePaths:      r.normalizePaths,
+		AllowEncodedSlashes: r.allowEncodedSlashes,
+		MaxResponseBytes:    r.maxResponseBytes,
+		MultipartMemory:     r.multipartMemory,
+		Frozen:              r.frozen,
+	}
+
+	if cfg.sensitive {
+		config.TrustedProxies = []string{}
+		for _, prefix := range r.trustedProxies {
+			config.TrustedProxies = append(config.TrustedProxies, prefix.String())
+		}
+	}
+	return config
+}
+
+// adminStats returns the counters of each route and their totals.
+func (r *Router) adminStats(cfg adminConfig) AdminStats {
+	stats := AdminStats{Routes: make(map[string]AdminRouteStats, len(r.routes))}
+	for prefix, route := range r.routes {
+		rs := AdminRouteStats{
+			Requests: route.stats.requests.Load(),
+			Errors:   route.stats.errors.Load(),
+			InFlight: route.stats.inFlight.Load(),
+		}
+
+		for class := range route.stats.statuses {
+			if n := route.stats.statuses[class].Load(); n > 0 {
+				if rs.Statuses == nil {
+					rs.Statuses = make(map[string]int64)
+				}
+				rs.Statuses[statusClass(class)] = n
+
+				if stats.Statuses == nil {
+					stats.Statuses = make(map[string]int64)
+				}
+				stats.Statuses[statusClass(class)] += n
+			}
+		}
+
+		if last := route.stats.last.Load(); last != nil {
+			rs.LastAt = &last.at
+			if cfg.sensitive {
+				rs.LastError = last.message
+			}
+		}
+
+		stats.Requests += rs.Requests
+		stats.Errors += rs.Errors
+		stats.InFlight += rs.InFlight
+		stats.Routes[prefix] = rs
+	}
+	return stats
+}
+
+// statusClass returns the name of the status class at index class of routeStats.statuses.
+func statusClass(class int) string {
+	if class == 0 {
+		return "other"
+	}
+	return string(rune('0'+class)) + "xx"
+}
@@ -0,0 +1,238 @@
+package rex_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func adminGuard(next rex.HandlerFunc) rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		if c.Request.Header.Get("Authorization") != "Bearer admin" {
+			return rex.NewError(http.StatusUnauthorized, "unauthorized")
+		}
+		return next(c)
+	}
+}
+
+func adminTagger(next rex.HandlerFunc) rex.HandlerFunc {
+	return next
+}
+
+func adminGet(t *testing.T, r *rex.Router, path string, v any) *rex.TestResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	res := r.Test(req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("GET %s: expected 200, got %d %q", path, res.Code, res.BodyString())
+	}
+
+	if err := json.Unmarshal([]byte(res.BodyString()), v); err != nil {
+		t.Fatalf("GET %s: invalid JSON %q: %v", path, res.BodyString(), err)
+	}
+	return res
+}
+
+func newAdminRouter(t *testing.T, options ...rex.AdminOption) *rex.Router {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "home.html"), []byte(`home`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	templ, err := rex.ParseTemplates(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rex.NewRouter(
+		rex.WithTemplates(templ),
+		rex.BaseLayout("layout.html"),
+		rex.AllowEncodedSlashes(true),
+		rex.TrustForwardedPrefix("10.0.0.0/8"),
+		rex.WithBuildInfo(rex.BuildInfo{Version: "v1.4.2", Commit: "abc123"}),
+	)
+
+	api := r.Group("/api", adminTagger)
+	api.GET("/users", func(c *rex.Context) error { return c.String("users") })
+	r.GET("/fail", func(c *rex.Context) error { return rex.NewError(http.StatusBadGateway, "upstream down") })
+
+	r.AdminHandler("/_admin", adminGuard, options...)
+	return r
+}
+
+func TestAdminGuard(t *testing.T) {
+	r := newAdminRouter(t)
+	for _, path := range []string{"/_admin", "/_admin/routes", "/_admin/templates", "/_admin/config", "/_admin/build", "/_admin/stats"} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Code != http.StatusUnauthorized {
+			t.Errorf("GET %s: expected 401 without credentials, got %d", path, res.Code)
+		}
+	}
+}
+
+func TestAdminRequiresGuard(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected AdminHandler to panic without a guard")
+		}
+	}()
+	rex.NewRouter().AdminHandler("/_admin", nil)
+}
+
+func TestAdminRoutes(t *testing.T) {
+	r := newAdminRouter(t)
+
+	var routes []rex.AdminRoute
+	adminGet(t, r, "/_admin/routes", &routes)
+
+	i := slices.IndexFunc(routes, func(rt rex.AdminRoute) bool { return rt.Path == "/api/users" })
+	if i < 0 {
+		t.Fatalf("expected /api/users to be listed, got %+v", routes)
+	}
+
+	users := routes[i]
+	if users.Method != http.MethodGet || users.Group != "/api" || !strings.HasSuffix(users.Handler, ".func1") {
+		t.Errorf("unexpected route %+v", users)
+	}
+
+	if len(users.Middleware) != 1 || !strings.HasSuffix(users.Middleware[0], "adminTagger") {
+		t.Errorf("expected the group middleware to be listed, got %v", users.Middleware)
+	}
+
+	if users.Location != "" {
+		t.Errorf("expected no location without IncludeSensitive, got %q", users.Location)
+	}
+
+	r = newAdminRouter(t, rex.IncludeSensitive())
+	adminGet(t, r, "/_admin/routes", &routes)
+	for _, rt := range routes {
+		if rt.Location == "" {
+			t.Errorf("expected a location with IncludeSensitive, got %+v", rt)
+		}
+	}
+}
+
+func TestAdminTemplates(t *testing.T) {
+	r := newAdminRouter(t)
+
+	var templates rex.AdminTemplates
+	adminGet(t, r, "/_admin/templates", &templates)
+
+	if !slices.Contains(templates.Names, "home.html") {
+		t.Errorf("expected home.html in %v", templates.Names)
+	}
+
+	if templates.BaseLayout != "layout.html" || templates.ContentBlock != "Content" {
+		t.Errorf("unexpected layout config %+v", templates)
+	}
+}
+
+func TestAdminConfig(t *testing.T) {
+	r := newAdminRouter(t)
+
+	var config map[string]any
+	adminGet(t, r, "/_admin/config", &config)
+
+	if config["allow_encoded_slashes"] != true || config["auto_head"] != true || config["debug"] != rex.Debug {
+		t.Errorf("unexpected config %v", config)
+	}
+
+	if _, ok := config["trusted_proxies"]; ok {
+		t.Errorf("expected the trusted proxies to be hidden, got %v", config["trusted_proxies"])
+	}
+
+	r = newAdminRouter(t, rex.IncludeSensitive())
+
+	var sensitive rex.AdminConfig
+	adminGet(t, r, "/_admin/config", &sensitive)
+	if !slices.Equal(sensitive.TrustedProxies, []string{"10.0.0.0/8"}) {
+		t.Errorf("expected the trusted proxies with IncludeSensitive, got %v", sensitive.TrustedProxies)
+	}
+}
+
+func TestAdminBuild(t *testing.T) {
+	r := newAdminRouter(t)
+
+	var build rex.BuildInfo
+	res := adminGet(t, r, "/_admin/build", &build)
+
+	if build.Version != "v1.4.2" || build.Commit != "abc123" || build.GoVersion == "" {
+		t.Errorf("unexpected build info %+v", build)
+	}
+
+	if res.Header("Cache-Control") != "no-store" {
+		t.Errorf("expected the admin responses not to be cached, got %q", res.Header("Cache-Control"))
+	}
+}
+
+func TestAdminStats(t *testing.T) {
+	r := newAdminRouter(t)
+	r.Test(httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	r.Test(httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	r.Test(httptest.NewRequest(http.MethodGet, "/fail", nil))
+
+	var stats rex.AdminStats
+	adminGet(t, r, "/_admin/stats", &stats)
+
+	users := stats.Routes["GET /api/users"]
+	if users.Requests != 2 || users.Statuses["2xx"] != 2 || users.InFlight != 0 {
+		t.Errorf("unexpected stats for /api/users %+v", users)
+	}
+
+	fail := stats.Routes["GET /fail"]
+	if fail.Errors != 1 || fail.Statuses["5xx"] != 1 || fail.LastAt == nil || fail.LastError != "" {
+		t.Errorf("unexpected stats for /fail %+v", fail)
+	}
+
+	if stats.Requests < 3 || stats.Errors != 1 || stats.Statuses["2xx"] < 2 {
+		t.Errorf("unexpected totals %+v", stats.AdminRouteStats)
+	}
+}
+
+func TestAdminInFlight(t *testing.T) {
+	r := rex.NewRouter()
+	var inFlight int64
+	r.GET("/slow", func(c *rex.Context) error {
+		var stats rex.AdminStats
+		adminGet(t, r, "/_admin/stats", &stats)
+		inFlight = stats.Routes["GET /slow"].InFlight
+		return nil
+	})
+	r.AdminHandler("/_admin", adminGuard)
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	if inFlight != 1 {
+		t.Errorf("expected the running request to be in flight, got %d", inFlight)
+	}
+}
+
+func TestAdminIndex(t *testing.T) {
+	r := newAdminRouter(t)
+
+	var overview rex.AdminOverview
+	adminGet(t, r, "/_admin", &overview)
+	if overview.Build.Version != "v1.4.2" || len(overview.Routes) == 0 || !overview.Config.AllowEncodedSlashes {
+		t.Errorf("unexpected overview %+v", overview)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/_admin", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	res := r.Test(req)
+
+	if !strings.HasPrefix(res.Header("Content-Type"), "text/html") {
+		t.Fatalf("expected an HTML page, got %q", res.Header("Content-Type"))
+	}
+
+	if body := res.BodyString(); !strings.Contains(body, "v1.4.2") || !strings.Contains(body, "/api/users") {
+		t.Errorf("expected the page to show the build and routes, got %q", body)
+	}
+}
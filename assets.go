@@ -0,0 +1,159 @@
+package rex
+
+import (
+	"encoding/json"
+	"html/template"
+	"io/fs"
+	"strings"
+	"sync"
+)
+
+// Cache-Control header for fingerprinted assets listed in the asset manifest.
+const immutableCacheControl = "public, max-age=31536000, immutable"
+
+// assetManifest maps logical asset names to fingerprinted file names.
+type assetManifest struct {
+	fsys   fs.FS
+	path   string
+	reload bool
+
+	mu      sync.RWMutex
+	entries map[string]string // logical name => hashed path
+	hashed  map[string]bool   // hashed paths without the leading slash
+
+	warned sync.Map // logical names already reported as missing
+}
+
+// WithAssetManifest loads a JSON manifest mapping logical asset names to
+// fingerprinted file names e.g {"app.js": "app.8f3a2.js"} as produced by frontend bundlers.
+// The manifest is read once at startup and the router panics if it is missing or invalid.
+// Pass reload=true in development to re-read the manifest on every lookup.
+//
+// Use r.AssetPath or the "asset" template func to resolve names.
+// Files in the manifest are served by StaticFS with an immutable Cache-Control header.
+func WithAssetManifest(fsys fs.FS, manifestPath string, reload ...bool) RouterOption {
+	return func(r *Router) {
+		m := &assetManifest{fsys: fsys, path: manifestPath}
+		if len(reload) > 0 {
+			m.reload = reload[0]
+		}
+
+		if err := m.load(); err != nil {
+			panic(err)
+		}
+		r.assets = m
+	}
+}
+
+func (m *assetManifest) load() error {
+	data, err := fs.ReadFile(m.fsys, m.path)
+	if err != nil {
+		return err
+	}
+
+	var entries map[string]string
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return err
+	}
+
+	hashed := make(map[string]bool, len(entries))
+	for _, v := range entries {
+		hashed[strings.TrimPrefix(v, "/")] = true
+	}
+
+	m.mu.Lock()
+	m.entries, m.hashed = entries, hashed
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *assetManifest) lookup(logical string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	path, ok := m.entries[logical]
+	return path, ok
+}
+
+func (m *assetManifest) isHashed(paths ...string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range paths {
+		if m.hashed[strings.TrimPrefix(p, "/")] {
+			return true
+		}
+	}
+	return false
+}
+
+// AssetPath returns the fingerprinted path for the logical asset name from the manifest
+// loaded with WithAssetManifest. Names missing from the manifest are returned unchanged
+// and a warning is logged once per name.
+func (r *Router) AssetPath(logical string) string {
+	m := r.assets
+	if m == nil {
+		return logical
+	}
+
+	if m.reload {
+		if err := m.load(); err != nil {
+			r.logger.Warn("failed to reload asset manifest", "path", m.path, "error", err)
+		}
+	}
+
+	if path, ok := m.lookup(logical); ok {
+		return path
+	}
+
+	if _, warned := m.warned.LoadOrStore(logical, true); !warned {
+		r.logger.Warn("asset not found in manifest", "asset", logical, "manifest", m.path)
+	}
+	return logical
+}
+
+// builtinFuncs are added to templates parsed with ParseTemplates and ParseTemplatesFS
+// and rebound to the router in NewRouter.
+func builtinFuncs() template.FuncMap {
+	return template.FuncMap{
+		"asset":        func(logical string) string { return logical },
+		"url":          func(p string) string { return p },
+		"formatDate":   formatDate,
+		"formatNumber": formatNumber,
+		"timeago":      timeago,
+		"dict":         dict,
+		"include":      includeUnbound,
+		"iter":         templateIter,
+		"flush":        templateFlush,
+	}
+}
+
+// withBuiltinFuncs returns the builtin funcs overridden by funcMap.
+func withBuiltinFuncs(funcMap template.FuncMap) template.FuncMap {
+	funcs := builtinFuncs()
+	for name, fn := range funcMap {
+		funcs[name] = fn
+	}
+	return funcs
+}
+
+// bindTemplateFuncs binds the builtin template funcs to the router.
+func (r *Router) bindTemplateFuncs() {
+	if r.template == nil {
+		return
+	}
+
+	if r.templateInfo == nil || !r.templateInfo.customInclude {
+		bindInclude(r.template)
+	}
+
+	if r.templateMissingKey != "" {
+		r.template.Option("missingkey=" + r.templateMissingKey)
+	}
+
+	if r.assets != nil && (r.templateInfo == nil || !r.templateInfo.customAsset) {
+		r.template.Funcs(template.FuncMap{"asset": r.AssetPath})
+	}
+
+	if r.basePath != "" && (r.templateInfo == nil || !r.templateInfo.customURL) {
+		r.template.Funcs(template.FuncMap{"url": r.urlFor})
+	}
+}
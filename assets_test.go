@@ -0,0 +1,111 @@
+package rex_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestAssetManifest(t *testing.T) {
+	assets := fstest.MapFS{
+		"manifest.json":     {Data: []byte(`{"app.js": "app.8f3a2.js", "app.css": "css/app.1b2c3.css"}`)},
+		"app.8f3a2.js":      {Data: []byte("console.log('app')")},
+		"css/app.1b2c3.css": {Data: []byte("body{}")},
+		"logo.svg":          {Data: []byte("<svg/>")},
+	}
+
+	dir := t.TempDir()
+	page := `<script src="/static/{{asset "app.js"}}"></script><link href="/static/{{asset "app.css"}}"><img src="/static/{{asset "logo.svg"}}">`
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(page), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	templ := rex.Must(rex.ParseTemplates(dir, nil))
+	r := rex.NewRouter(rex.WithTemplates(templ), rex.WithAssetManifest(assets, "manifest.json"))
+	r.StaticFS("/static", http.FS(assets), 3600)
+
+	r.GET("/", func(c *rex.Context) error {
+		return c.ExecuteTemplate("index.html", rex.Map{})
+	})
+
+	if got := r.AssetPath("app.js"); got != "app.8f3a2.js" {
+		t.Errorf("expected app.8f3a2.js, got %s", got)
+	}
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	want := `<script src="/static/app.8f3a2.js"></script><link href="/static/css/app.1b2c3.css"><img src="/static/logo.svg">`
+	if res.BodyString() != want {
+		t.Fatalf("expected %q, got %q", want, res.BodyString())
+	}
+
+	tests := []struct {
+		path         string
+		cacheControl string
+	}{
+		{"/static/app.8f3a2.js", "public, max-age=31536000, immutable"},
+		{"/static/css/app.1b2c3.css", "public, max-age=31536000, immutable"},
+		{"/static/logo.svg", "public, max-age=3600"},
+	}
+
+	for _, tt := range tests {
+		res := r.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if res.Status() != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tt.path, res.Status())
+		}
+
+		if got := res.Header("Cache-Control"); got != tt.cacheControl {
+			t.Errorf("%s: expected Cache-Control %q, got %q", tt.path, tt.cacheControl, got)
+		}
+	}
+}
+
+func TestAssetManifestCustomAssetFunc(t *testing.T) {
+	assets := fstest.MapFS{
+		"manifest.json": {Data: []byte(`{"app.js": "app.8f3a2.js"}`)},
+	}
+
+	views := fstest.MapFS{"views/index.html": {Data: []byte(`<script src="{{asset "app.js"}}"></script>`)}}
+	funcs := template.FuncMap{"asset": func(logical string) string { return "https://cdn.example.com/" + logical }}
+
+	templ := rex.Must(rex.ParseTemplatesFS(views, "views", funcs))
+	r := rex.NewRouter(rex.WithTemplates(templ), rex.WithAssetManifest(assets, "manifest.json"))
+	r.GET("/", func(c *rex.Context) error {
+		return c.ExecuteTemplate("views/index.html", rex.Map{})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := `<script src="https://cdn.example.com/app.js"></script>`; res.BodyString() != want {
+		t.Errorf("expected the user asset func to be kept, got %q", res.BodyString())
+	}
+}
+
+func TestAssetManifestReload(t *testing.T) {
+	assets := fstest.MapFS{
+		"manifest.json": {Data: []byte(`{"app.js": "app.1.js"}`)},
+	}
+
+	r := rex.NewRouter(rex.WithAssetManifest(assets, "manifest.json", true))
+	if got := r.AssetPath("app.js"); got != "app.1.js" {
+		t.Fatalf("expected app.1.js, got %s", got)
+	}
+
+	assets["manifest.json"] = &fstest.MapFile{Data: []byte(`{"app.js": "app.2.js"}`)}
+	if got := r.AssetPath("app.js"); got != "app.2.js" {
+		t.Errorf("expected reloaded app.2.js, got %s", got)
+	}
+}
+
+func TestAssetManifestMissing(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for missing manifest")
+		}
+	}()
+	rex.NewRouter(rex.WithAssetManifest(fstest.MapFS{}, "manifest.json"))
+}
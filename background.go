@@ -0,0 +1,70 @@
+package rex
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// Snapshot is a read-only copy of request values taken before the request
+// completes. It is safe to use from goroutines started with c.Go.
+type Snapshot struct {
+	RequestID string      // Value of the X-Request-ID header, if any.
+	Method    string      // Request method.
+	Path      string      // Request path.
+	Locals    map[any]any // Copies of the allowlisted context locals.
+}
+
+type snapshotContextKey struct{}
+
+// SnapshotFrom returns the Snapshot stored in a context passed to a c.Go function.
+func SnapshotFrom(ctx context.Context) (Snapshot, bool) {
+	s, ok := ctx.Value(snapshotContextKey{}).(Snapshot)
+	return s, ok
+}
+
+// Go runs fn in a new goroutine on a context that is detached from the request.
+// The context is not canceled when the request completes and carries a Snapshot
+// of the request ID, method, path and the locals listed in keys.
+// Retrieve it with rex.SnapshotFrom(ctx). Allowlisted locals are also available via ctx.Value(key).
+//
+// fn must NOT capture the *rex.Context: it is returned to the pool and reused
+// once the handler returns. Panics in fn are recovered and logged.
+func (c *Context) Go(fn func(ctx context.Context), keys ...any) {
+	c.checkReleased()
+
+	ctx, snap := c.snapshot(context.Background(), keys...)
+
+	logger := c.router.logger
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Error("panic in background task",
+					"error", fmt.Sprint(r),
+					"request_id", snap.RequestID,
+					"path", snap.Path,
+					"stack", string(debug.Stack()))
+			}
+		}()
+		fn(ctx)
+	}()
+}
+
+// snapshot returns parent with a Snapshot of the request and the locals listed in keys.
+func (c *Context) snapshot(parent context.Context, keys ...any) (context.Context, Snapshot) {
+	snap := Snapshot{
+		RequestID: c.RequestID(),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+		Locals:    make(map[any]any, len(keys)),
+	}
+
+	ctx := parent
+	for _, key := range keys {
+		if value, ok := c.Get(key); ok {
+			snap.Locals[key] = value
+			ctx = context.WithValue(ctx, key, value)
+		}
+	}
+	return context.WithValue(ctx, snapshotContextKey{}, snap), snap
+}
@@ -0,0 +1,106 @@
+package rex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextGo(t *testing.T) {
+	r := NewRouter()
+	done := make(chan Snapshot, 1)
+
+	r.GET("/users/{id}", func(c *Context) error {
+		c.Set("user", "john")
+		c.Set("secret", "hidden")
+
+		c.Go(func(ctx context.Context) {
+			snap, ok := SnapshotFrom(ctx)
+			if !ok {
+				t.Error("expected snapshot in context")
+			}
+
+			if ctx.Value("user") != "john" {
+				t.Errorf("expected allowlisted local in context, got %v", ctx.Value("user"))
+			}
+			done <- snap
+		}, "user")
+		return c.String("accepted")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "accepted" {
+		t.Fatalf("expected accepted, got %s", w.Body.String())
+	}
+
+	select {
+	case snap := <-done:
+		if snap.RequestID != "req-1" || snap.Path != "/users/42" || snap.Method != http.MethodGet {
+			t.Errorf("unexpected snapshot: %+v", snap)
+		}
+
+		if _, ok := snap.Locals["secret"]; ok {
+			t.Error("expected only allowlisted locals in snapshot")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("background task did not run")
+	}
+}
+
+func TestContextGoRecoversPanic(t *testing.T) {
+	r := NewRouter()
+	done := make(chan struct{})
+
+	r.GET("/", func(c *Context) error {
+		c.Go(func(ctx context.Context) {
+			defer close(done)
+			panic("boom")
+		})
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("background task did not run")
+	}
+}
+
+func TestDebugContextUseAfterRelease(t *testing.T) {
+	Debug = true
+	defer func() { Debug = false }()
+
+	r := NewRouter()
+	var captured *Context
+
+	r.GET("/users/{id}", func(c *Context) error {
+		captured = c
+		return nil
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/users/1", nil))
+
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			t.Fatal("expected panic for use after release")
+		}
+
+		if msg, _ := rec.(string); !strings.Contains(msg, "context used after release") {
+			t.Errorf("unexpected panic message: %v", rec)
+		}
+	}()
+
+	captured.Param("id")
+}
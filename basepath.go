@@ -0,0 +1,227 @@
+package rex
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ForwardedPrefixHeader is the header read by TrustForwardedPrefix.
+var ForwardedPrefixHeader = "X-Forwarded-Prefix"
+
+type basePathContextKey struct{}
+
+// WithBasePath serves the router under base, e.g "/myapp" behind a gateway that forwards
+// /myapp/* with the prefix intact. Routes, groups, static mounts, SPA and favicon routes are
+// registered without the base, the router strips it before dispatching and answers 404 for
+// paths outside of it. c.Path, c.OriginalPath and RegisteredRoutes are relative to the base.
+//
+// c.Redirect and the helpers built on it, c.URLFor and the "url" template func add the base
+// to paths, so application code never mentions it.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.WithBasePath("/myapp"))
+//	r.GET("/login", login) // served at /myapp/login
+func WithBasePath(base string) RouterOption {
+	base = strings.TrimRight(base, "/")
+	if base != "" && !validBasePath(base) {
+		panic(fmt.Sprintf("rex: invalid base path %q: must be a clean path starting with \"/\" without characters that need escaping", base))
+	}
+
+	return func(r *Router) {
+		r.basePath = base
+	}
+}
+
+// TrustForwardedPrefix prepends the X-Forwarded-Prefix header of requests from the given
+// proxies to the base path used by c.Redirect and c.URLFor, for proxies that strip a prefix
+// before forwarding. proxies are IP addresses or CIDR ranges like "10.0.0.0/8", the header
+// of other clients is ignored. It panics if no proxy is given or one is invalid.
+// The "url" template func only knows the base set with WithBasePath.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.TrustForwardedPrefix("10.0.0.0/8"))
+func TrustForwardedPrefix(proxies ...string) RouterOption {
+	if len(proxies) == 0 {
+		panic("rex: TrustForwardedPrefix requires at least one trusted proxy")
+	}
+
+	prefixes := make([]netip.Prefix, 0, len(proxies))
+	for _, proxy := range proxies {
+		if prefix, err := netip.ParsePrefix(proxy); err == nil {
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			panic(fmt.Sprintf("rex: invalid trusted proxy %q: must be an IP address or CIDR range", proxy))
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+
+	return func(r *Router) {
+		r.trustedProxies = prefixes
+	}
+}
+
+// BasePath returns the base path of the request without a trailing slash, "" if the router
+// is served at the root. It includes a trusted X-Forwarded-Prefix, see TrustForwardedPrefix.
+func (c *Context) BasePath() string {
+	if base, ok := c.Request.Context().Value(basePathContextKey{}).(string); ok {
+		return base
+	}
+
+	if c.router == nil {
+		return ""
+	}
+	return c.router.basePath
+}
+
+// URLFor returns the path p of this application as seen by the client, i.e with the base path.
+// References relative to the request URL, e.g "edit" or "?page=2", are resolved first. Absolute and protocol-relative
+// URLs are returned unchanged.
+func (c *Context) URLFor(p string) string {
+	base := c.BasePath()
+	if base == "" || strings.HasPrefix(p, "//") {
+		return p
+	}
+
+	u, err := url.Parse(p)
+	if err != nil || u.Scheme != "" || u.Host != "" {
+		return p
+	}
+
+	// Relative references like "edit" or "?page=2" are resolved like a browser would.
+	if !strings.HasPrefix(p, "/") {
+		p = (&url.URL{Path: c.Request.URL.Path, RawQuery: c.Request.URL.RawQuery}).ResolveReference(u).String()
+	}
+	return base + p
+}
+
+// trimBasePath returns the path p of a client URL relative to the base path.
+// ok is false if p is outside of the base.
+func (c *Context) trimBasePath(p string) (string, bool) {
+	return trimBase(p, c.BasePath())
+}
+
+// trimBase strips base from p, which may carry a query. "/base" becomes "/".
+func trimBase(p, base string) (string, bool) {
+	if base == "" {
+		return p, true
+	}
+
+	rest, ok := strings.CutPrefix(p, base)
+	if !ok {
+		return "", false
+	}
+
+	switch {
+	case rest == "":
+		return "/", true
+	case rest[0] == '/':
+		return rest, true
+	case rest[0] == '?' || rest[0] == '#':
+		return "/" + rest, true
+	}
+	return "", false
+}
+
+// urlFor adds the base path set with WithBasePath to p, for the "url" template func.
+func (r *Router) urlFor(p string) string {
+	if r.basePath == "" || !strings.HasPrefix(p, "/") || strings.HasPrefix(p, "//") {
+		return p
+	}
+	return r.basePath + p
+}
+
+// applyBasePath strips the base path from the request and records the base of the request.
+// It returns nil if the response has already been written.
+func (r *Router) applyBasePath(w http.ResponseWriter, req *http.Request) *http.Request {
+	base := r.forwardedPrefix(req)
+
+	u := *req.URL
+	if r.basePath != "" {
+		rest, ok := trimBase(req.URL.Path, r.basePath)
+		if !ok {
+			if r.NotFoundHandler != nil {
+				r.NotFoundHandler.ServeHTTP(w, req)
+			} else {
+				http.NotFound(w, req)
+			}
+			return nil
+		}
+
+		u.Path = rest
+		if u.RawPath != "" {
+			// The base has no escaped characters, so it is a prefix of the raw path too.
+			u.RawPath, _ = trimBase(u.RawPath, r.basePath)
+		}
+		base += r.basePath
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), basePathContextKey{}, base))
+	req.URL = &u
+	return req
+}
+
+// forwardedPrefix returns the X-Forwarded-Prefix of req without a trailing slash, if req
+// comes from a trusted proxy and the header is a clean absolute path. Otherwise it returns "".
+func (r *Router) forwardedPrefix(req *http.Request) string {
+	if len(r.trustedProxies) == 0 {
+		return ""
+	}
+
+	header := req.Header.Get(ForwardedPrefixHeader)
+	if header == "" {
+		return ""
+	}
+
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return ""
+	}
+
+	trusted := false
+	for _, proxy := range r.trustedProxies {
+		if proxy.Contains(addr.Unmap()) {
+			trusted = true
+			break
+		}
+	}
+
+	if !trusted {
+		return ""
+	}
+
+	prefix := strings.TrimRight(strings.TrimSpace(header), "/")
+	if !validBasePath(prefix) {
+		return ""
+	}
+	return prefix
+}
+
+// validBasePath reports whether base is a clean absolute path without a trailing slash
+// made of characters that need no escaping, e.g "/myapp" or "/api/v2".
+func validBasePath(base string) bool {
+	if !strings.HasPrefix(base, "/") || path.Clean(base) != base || base == "/" {
+		return false
+	}
+
+	invalid := func(r rune) bool {
+		return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("-._~/", r))
+	}
+	return strings.IndexFunc(base, invalid) < 0
+}
@@ -0,0 +1,201 @@
+package rex_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/abiiranathan/rex"
+)
+
+func basePathRouter(options ...rex.RouterOption) *rex.Router {
+	r := rex.NewRouter(options...)
+	r.GET("/", func(c *rex.Context) error {
+		return c.String("home " + c.Path())
+	})
+	r.GET("/login", func(c *rex.Context) error {
+		return c.Redirect("/dashboard")
+	})
+	r.GET("/docs/intro", func(c *rex.Context) error {
+		return c.Redirect("setup")
+	})
+	r.GET("/next", func(c *rex.Context) error {
+		return c.String(c.URLFor("/items?page=2"))
+	})
+	r.GET("/items", func(c *rex.Context) error {
+		return c.String(c.URLFor("?page=3"))
+	})
+
+	api := r.Group("/api")
+	api.GET("/users", func(c *rex.Context) error {
+		return c.String("users " + c.Path())
+	})
+
+	r.StaticFS("/static", http.FS(fstest.MapFS{"app.css": {Data: []byte("body{}")}}))
+	return r
+}
+
+func serveBasePath(r *rex.Router, target string, header ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestWithBasePathRoutes(t *testing.T) {
+	r := basePathRouter(rex.WithBasePath("/myapp/"))
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/myapp/", http.StatusOK, "home /"},
+		{"/myapp", http.StatusOK, "home /"},
+		{"/myapp/api/users", http.StatusOK, "users /api/users"},
+		{"/myapp/static/app.css", http.StatusOK, "body{}"},
+		{"/api/users", http.StatusNotFound, ""},
+		{"/static/app.css", http.StatusNotFound, ""},
+		{"/myappx/api/users", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		w := serveBasePath(r, tt.target)
+		if w.Code != tt.status || (tt.body != "" && w.Body.String() != tt.body) {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.target, tt.status, tt.body, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestWithBasePathRedirects(t *testing.T) {
+	r := basePathRouter(rex.WithBasePath("/myapp"))
+
+	tests := []struct {
+		target, location string
+	}{
+		{"/myapp/login", "/myapp/dashboard"},
+		{"/myapp/docs/intro", "/myapp/docs/setup"},
+		{"/myapp/static?v=1", "/myapp/static/?v=1"},
+	}
+
+	for _, tt := range tests {
+		w := serveBasePath(r, tt.target)
+		if got := w.Header().Get("Location"); got != tt.location {
+			t.Errorf("%s: expected Location %q, got %q (%d)", tt.target, tt.location, got, w.Code)
+		}
+	}
+
+	if w := serveBasePath(r, "/myapp/next"); w.Body.String() != "/myapp/items?page=2" {
+		t.Errorf("expected URLFor to add the base, got %q", w.Body.String())
+	}
+
+	if w := serveBasePath(r, "/myapp/items?page=2"); w.Body.String() != "/myapp/items?page=3" {
+		t.Errorf("expected URLFor to resolve the query against the request path, got %q", w.Body.String())
+	}
+}
+
+func TestRedirectBackWithBasePath(t *testing.T) {
+	r := rex.NewRouter(rex.WithBasePath("/myapp"))
+	r.POST("/cart", func(c *rex.Context) error {
+		return c.RedirectBack("/")
+	})
+
+	for referer, location := range map[string]string{
+		"http://example.com/myapp/products?id=2": "/myapp/products?id=2",
+		"http://example.com/otherapp/":           "/myapp/",
+	} {
+		req := httptest.NewRequest(http.MethodPost, "http://example.com/myapp/cart", nil)
+		req.Header.Set("Referer", referer)
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Location"); got != location {
+			t.Errorf("%s: expected %q, got %q", referer, location, got)
+		}
+	}
+}
+
+func TestBasePathTemplateURL(t *testing.T) {
+	tmpl := template.Must(template.New("nav.html").Funcs(template.FuncMap{"url": func(p string) string { return p }}).
+		Parse(`<a href="{{ url "/login" }}">`))
+	r := rex.NewRouter(rex.WithTemplates(tmpl), rex.WithBasePath("/myapp"))
+	r.GET("/", func(c *rex.Context) error {
+		return c.ExecuteTemplate("nav.html", rex.Map{})
+	})
+
+	if w := serveBasePath(r, "/myapp/"); w.Body.String() != `<a href="/myapp/login">` {
+		t.Errorf("expected the url func to add the base, got %q", w.Body.String())
+	}
+}
+
+func TestBasePathCustomTemplateURL(t *testing.T) {
+	fsys := fstest.MapFS{"views/nav.html": {Data: []byte(`<a href="{{ url "/login" }}">`)}}
+	funcs := template.FuncMap{"url": func(p string) string { return "https://cdn.example.com" + p }}
+
+	tmpl, err := rex.ParseTemplatesFS(fsys, "views", funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := rex.NewRouter(rex.WithTemplates(tmpl), rex.WithBasePath("/myapp"))
+	r.GET("/", func(c *rex.Context) error {
+		return c.ExecuteTemplate("views/nav.html", rex.Map{})
+	})
+
+	if w := serveBasePath(r, "/myapp/"); w.Body.String() != `<a href="https://cdn.example.com/login">` {
+		t.Errorf("expected the user url func to be kept, got %q", w.Body.String())
+	}
+}
+
+func TestTrustForwardedPrefix(t *testing.T) {
+	r := basePathRouter(rex.TrustForwardedPrefix("192.0.2.0/24"))
+
+	// httptest requests come from 192.0.2.1.
+	w := serveBasePath(r, "/login", "X-Forwarded-Prefix", "/gateway/shop/")
+	if got := w.Header().Get("Location"); got != "/gateway/shop/dashboard" {
+		t.Errorf("expected the forwarded prefix, got %q", got)
+	}
+
+	w = serveBasePath(r, "/login")
+	if got := w.Header().Get("Location"); got != "/dashboard" {
+		t.Errorf("expected no prefix without the header, got %q", got)
+	}
+
+	for _, invalid := range []string{"//evil.com", "/a/../b", "relative", "/a b"} {
+		w = serveBasePath(r, "/login", "X-Forwarded-Prefix", invalid)
+		if got := w.Header().Get("Location"); got != "/dashboard" {
+			t.Errorf("%q: expected the invalid prefix to be ignored, got %q", invalid, got)
+		}
+	}
+
+	untrusted := basePathRouter(rex.TrustForwardedPrefix("10.0.0.1"))
+	w = serveBasePath(untrusted, "/login", "X-Forwarded-Prefix", "/gateway")
+	if got := w.Header().Get("Location"); got != "/dashboard" {
+		t.Errorf("expected the header of untrusted clients to be ignored, got %q", got)
+	}
+
+	combined := basePathRouter(rex.WithBasePath("/myapp"), rex.TrustForwardedPrefix("192.0.2.1"))
+	w = serveBasePath(combined, "/myapp/login", "X-Forwarded-Prefix", "/gateway")
+	if got := w.Header().Get("Location"); got != "/gateway/myapp/dashboard" {
+		t.Errorf("expected the forwarded prefix before the base path, got %q", got)
+	}
+}
+
+func TestWithBasePathInvalid(t *testing.T) {
+	for _, base := range []string{"myapp", "/my app", "/a/../b", "/{id}"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%q: expected a panic", base)
+				}
+			}()
+			rex.WithBasePath(base)
+		}()
+	}
+}
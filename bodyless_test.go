@@ -0,0 +1,78 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestHeadErrorHasNoBody(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/item", func(c *rex.Context) error {
+		c.SetHeader("X-Item", "1")
+		return rex.NewError(http.StatusConflict, "item is locked")
+	})
+
+	r.HEAD("/status", func(c *rex.Context) error {
+		return errors.New("status unavailable")
+	})
+
+	r.GET("/json", func(c *rex.Context) error {
+		return c.JSON(rex.Map{"missing": true})
+	})
+
+	for _, path := range []string{"/item", "/status"} {
+		t.Run(path, func(t *testing.T) {
+			res := r.Test(httptest.NewRequest(http.MethodHead, path, nil))
+			if res.Code < 400 {
+				t.Errorf("expected an error status, got %d", res.Code)
+			}
+
+			if res.Body.Len() != 0 {
+				t.Errorf("expected no body for HEAD, got %q", res.Body.String())
+			}
+
+			if cl := res.Header("Content-Length"); cl != "" && cl != "0" {
+				t.Errorf("expected no Content-Length, got %q", cl)
+			}
+		})
+	}
+
+	res := r.Test(httptest.NewRequest(http.MethodHead, "/item", nil))
+	if res.Code != http.StatusConflict || res.Header("X-Item") != "1" {
+		t.Errorf("expected the error status and headers, got %d %v", res.Code, res.Result().Header)
+	}
+
+	// HEAD for a successful GET keeps its Content-Length.
+	res = r.Test(httptest.NewRequest(http.MethodHead, "/json", nil))
+	if res.Body.Len() != 0 || res.Header("Content-Length") == "" {
+		t.Errorf("expected headers without body, got %q %v", res.Body.String(), res.Result().Header)
+	}
+}
+
+func TestBodylessStatusHasNoBody(t *testing.T) {
+	r := rex.NewRouter()
+	r.DELETE("/item", func(c *rex.Context) error {
+		c.WriteHeader(http.StatusNoContent)
+		return errors.New("cleanup failed")
+	})
+
+	r.GET("/cached", func(c *rex.Context) error {
+		c.SetHeader("Content-Length", "5")
+		c.WriteHeader(http.StatusNotModified)
+		return c.String("stale")
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodDelete, "/item", nil))
+	if res.Code != http.StatusNoContent || res.Body.Len() != 0 {
+		t.Errorf("expected 204 without body, got %d %q", res.Code, res.Body.String())
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/cached", nil))
+	if res.Code != http.StatusNotModified || res.Body.Len() != 0 || res.Header("Content-Length") != "" {
+		t.Errorf("expected 304 without body or Content-Length, got %d %q %v", res.Code, res.Body.String(), res.Result().Header)
+	}
+}
@@ -0,0 +1,81 @@
+package rex
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Codec encodes and decodes bodies of a content type that rex does not support natively,
+// e.g YAML. See RegisterCodec and the codec/yaml package.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// RegisterCodec registers codec for contentType, e.g "application/yaml", on the router.
+// BodyParser decodes request bodies of that type with the codec and validates the result
+// like JSON bodies, and c.Encode writes responses with it.
+// Codecs do not replace the built-in JSON, XML and form decoding.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.RegisterCodec(yaml.ContentType, yaml.Codec{}))
+func RegisterCodec(contentType string, codec Codec) RouterOption {
+	return func(r *Router) {
+		if r.codecs == nil {
+			r.codecs = make(map[string]Codec)
+		}
+		r.codecs[strings.ToLower(contentType)] = codec
+	}
+}
+
+// codec returns the codec registered for contentType.
+func (c *Context) codec(contentType string) (Codec, bool) {
+	if c.router == nil {
+		return nil, false
+	}
+
+	codec, ok := c.router.codecs[strings.ToLower(contentType)]
+	return codec, ok
+}
+
+// decodeCodec reads the request body and decodes it into v with codec.
+func (c *Context) decodeCodec(codec Codec, v any) error {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return bodyReadError(err, "")
+	}
+
+	if err := codec.Unmarshal(data, v); err != nil {
+		return FormError{Err: err, Kind: ParseError}
+	}
+	return c.validateBody(v)
+}
+
+// Encode writes v encoded for contentType with the codec registered with RegisterCodec
+// and sets the Content-Type header. JSON and XML use the built-in encoders.
+func (c *Context) Encode(contentType string, v any) error {
+	c.checkReleased()
+
+	switch contentType {
+	case ContentTypeJSON:
+		return c.JSON(v)
+	case ContentTypeXML:
+		return c.XML(v)
+	}
+
+	codec, ok := c.codec(contentType)
+	if !ok {
+		return fmt.Errorf("rex: no codec registered for %s", contentType)
+	}
+
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	c.SetHeader("Content-Type", contentType)
+	_, err = c.Write(data)
+	return err
+}
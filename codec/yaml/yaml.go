@@ -0,0 +1,37 @@
+// Package yaml provides a rex.Codec for YAML request and response bodies using gopkg.in/yaml.v3.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.RegisterCodec(yaml.ContentType, yaml.Codec{}))
+//
+//	r.POST("/manifests", func(c *rex.Context) error {
+//		var m Manifest
+//		if err := c.BodyParser(&m); err != nil {
+//			return err
+//		}
+//		return c.Encode(yaml.ContentType, m)
+//	})
+//
+// Register the codec for "application/x-yaml" or "text/yaml" too if clients send those.
+package yaml
+
+import (
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// ContentType is the media type of YAML documents, RFC 9512.
+const ContentType = "application/yaml"
+
+// Codec encodes and decodes YAML with gopkg.in/yaml.v3. Struct fields are named
+// with their yaml tag, or the lowercased field name.
+type Codec struct{}
+
+// Marshal encodes v as a YAML document.
+func (Codec) Marshal(v any) ([]byte, error) {
+	return yamlv3.Marshal(v)
+}
+
+// Unmarshal decodes the first YAML document of data into v.
+func (Codec) Unmarshal(data []byte, v any) error {
+	return yamlv3.Unmarshal(data, v)
+}
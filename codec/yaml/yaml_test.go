@@ -0,0 +1,111 @@
+package yaml_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/codec/yaml"
+	"github.com/go-playground/validator/v10"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+type manifest struct {
+	Name     string            `yaml:"name" validate:"required"`
+	Replicas int               `yaml:"replicas" validate:"min=1"`
+	Labels   map[string]string `yaml:"labels"`
+}
+
+func manifestRouter(t *testing.T, handle func(c *rex.Context, m manifest, err error) error) *rex.Router {
+	t.Helper()
+	r := rex.NewRouter(rex.RegisterCodec(yaml.ContentType, yaml.Codec{}))
+	r.POST("/manifests", func(c *rex.Context) error {
+		var m manifest
+		err := c.BodyParser(&m)
+		return handle(c, m, err)
+	})
+	return r
+}
+
+func postManifest(r *rex.Router, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/manifests", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/yaml; charset=utf-8")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestBodyParserDecodesYAML(t *testing.T) {
+	var got manifest
+	r := manifestRouter(t, func(c *rex.Context, m manifest, err error) error {
+		got = m
+		return err
+	})
+
+	w := postManifest(r, "name: api\nreplicas: 3\nlabels:\n  tier: web\n")
+	if w.Code != http.StatusOK || got.Name != "api" || got.Replicas != 3 || got.Labels["tier"] != "web" {
+		t.Errorf("expected the manifest to be bound, got %d %+v", w.Code, got)
+	}
+}
+
+func TestBodyParserValidatesYAML(t *testing.T) {
+	var bindErr error
+	r := manifestRouter(t, func(c *rex.Context, m manifest, err error) error {
+		bindErr = err
+		return err
+	})
+
+	w := postManifest(r, "name: api\nreplicas: 0\n")
+
+	var errs validator.ValidationErrors
+	if !errors.As(bindErr, &errs) || len(errs) != 1 || errs[0].Tag() != "min" {
+		t.Fatalf("expected the replicas to fail validation, got %v", bindErr)
+	}
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", w.Code)
+	}
+}
+
+func TestBodyParserInvalidYAML(t *testing.T) {
+	var bindErr error
+	r := manifestRouter(t, func(c *rex.Context, m manifest, err error) error {
+		bindErr = err
+		return err
+	})
+
+	postManifest(r, "name: [api\n")
+
+	var fe rex.FormError
+	if !errors.As(bindErr, &fe) || fe.Kind != rex.ParseError {
+		t.Errorf("expected a parse error, got %v", bindErr)
+	}
+}
+
+func TestEncodeYAMLRoundTrip(t *testing.T) {
+	r := manifestRouter(t, func(c *rex.Context, m manifest, err error) error {
+		if err != nil {
+			return err
+		}
+		m.Replicas++
+		return c.Encode(yaml.ContentType, m)
+	})
+
+	w := postManifest(r, "name: api\nreplicas: 3\nlabels:\n  tier: web\n")
+	if ct := w.Header().Get("Content-Type"); ct != yaml.ContentType {
+		t.Errorf("expected %s, got %q", yaml.ContentType, ct)
+	}
+
+	var got manifest
+	if err := yamlv3.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Name != "api" || got.Replicas != 4 || got.Labels["tier"] != "web" {
+		t.Errorf("expected the manifest to round-trip, got %+v from %q", got, w.Body.String())
+	}
+}
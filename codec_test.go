@@ -0,0 +1,82 @@
+package rex_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+// upperCodec is a toy codec: the body is the upper-cased Name of a struct.
+type upperCodec struct{}
+
+type named struct {
+	Name string `validate:"required"`
+}
+
+func (upperCodec) Marshal(v any) ([]byte, error) {
+	return []byte(strings.ToUpper(v.(named).Name)), nil
+}
+
+func (upperCodec) Unmarshal(data []byte, v any) error {
+	v.(*named).Name = string(bytes.ToLower(data))
+	return nil
+}
+
+func TestRegisterCodec(t *testing.T) {
+	r := rex.NewRouter(rex.RegisterCodec("text/x-upper", upperCodec{}))
+	r.POST("/", func(c *rex.Context) error {
+		var n named
+		if err := c.BodyParser(&n); err != nil {
+			return err
+		}
+		n.Name += "!"
+		return c.Encode("text/x-upper", n)
+	})
+
+	post := func(contentType, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post("Text/X-Upper", "ANN")
+	if w.Code != http.StatusOK || w.Body.String() != "ANN!" || w.Header().Get("Content-Type") != "text/x-upper" {
+		t.Errorf("expected the codec to decode and encode, got %d %q %q", w.Code, w.Body.String(), w.Header().Get("Content-Type"))
+	}
+
+	if w := post("text/x-upper", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("expected the decoded value to be validated, got %d", w.Code)
+	}
+}
+
+func TestUnregisteredContentType(t *testing.T) {
+	r := rex.NewRouter(rex.RegisterCodec("text/x-upper", upperCodec{}))
+
+	var bindErr, encodeErr error
+	r.POST("/", func(c *rex.Context) error {
+		var n named
+		bindErr = c.BodyParser(&n)
+		encodeErr = c.Encode("application/toml", n)
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("ann"))
+	req.Header.Set("Content-Type", "application/toml")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var fe rex.FormError
+	if !errors.As(bindErr, &fe) || fe.Kind != rex.InvalidContentType {
+		t.Errorf("expected an invalid content type error, got %v", bindErr)
+	}
+
+	if encodeErr == nil {
+		t.Error("expected Encode to fail without a codec")
+	}
+}
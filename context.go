@@ -1,6 +1,7 @@
 package rex
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"encoding/xml"
@@ -9,7 +10,9 @@ import (
 	"mime/multipart"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,13 +26,59 @@ import (
 type Context struct {
 	Request  *http.Request
 	Response http.ResponseWriter
+	rw       *ResponseWriter // The writer created for the request, even if Response is swapped
 	router   *Router
 	locals   map[any]any
 	mu       sync.RWMutex
+
+	// The matched route. nil for requests not dispatched through a registered route.
+	currentRoute *Route
+
+	// Set in Debug mode when the context is released back to the router.
+	released bool
+
+	// Request start time and segments recorded with c.Timing.
+	startTime   time.Time
+	timings     []Timing
+	timingsSent bool
+
+	// Per-request timezone set with c.SetTimezone. nil uses DefaultTimezone.
+	timezone *time.Location
+
+	// Log sampling decision of the request: 0 if not drawn yet, 1 to log and -1 to skip.
+	logSample int8
+
+	// Callbacks registered with c.OnFinished.
+	onFinished []func(c *Context)
+
+	// Parsed query of the request and the raw query it was parsed from, see queryValues.
+	query    url.Values
+	queryRaw string
+
+	// JSON keys of the patch applied with ApplyMergePatch, nil if none was applied.
+	patchedKeys []string
+
+	// Set in Debug mode once the middleware a panic started in has been reported, see debugMiddleware.
+	panicReported bool
+
+	// Body of a request expecting 100 Continue held until AcceptContinue, see AutoContinue.
+	continueGate *continueGate
+}
+
+// errContextReleased is the panic message for use of a released context.
+const errContextReleased = "rex: context used after release. Do not capture *rex.Context in goroutines, use c.Go instead"
+
+// checkReleased panics if the context has been released back to the router.
+// Only released contexts in Debug mode are flagged.
+func (c *Context) checkReleased() {
+	if c.released {
+		panic(errContextReleased)
+	}
 }
 
 // SetHeader sets a header in the response
 func (c *Context) SetHeader(key, value string) {
+	c.checkReleased()
 	if wrapped, ok := c.Response.(*ResponseWriter); ok {
 		wrapped.writer.Header().Set(key, value)
 	} else {
@@ -37,6 +86,55 @@ func (c *Context) SetHeader(key, value string) {
 	}
 }
 
+// AddHeader adds a value to a header in the response, keeping existing values.
+// Use it for multi-value headers like Link.
+func (c *Context) AddHeader(key, value string) {
+	c.checkReleased()
+	if wrapped, ok := c.Response.(*ResponseWriter); ok {
+		wrapped.writer.Header().Add(key, value)
+	} else {
+		c.Response.Header().Add(key, value)
+	}
+}
+
+// AppendVary adds field to the Vary header unless it is already listed or Vary is "*".
+// Existing values are merged into a single comma-separated header.
+func (c *Context) AppendVary(field string) {
+	header := c.Response.Header()
+
+	var fields []string
+	add := func(f string) {
+		if f != "" && !slices.ContainsFunc(fields, func(s string) bool { return strings.EqualFold(s, f) }) {
+			fields = append(fields, f)
+		}
+	}
+
+	for _, value := range header.Values("Vary") {
+		for _, f := range strings.Split(value, ",") {
+			f = strings.TrimSpace(f)
+			if f == "*" {
+				return
+			}
+			add(f)
+		}
+	}
+
+	add(field)
+	header.Set("Vary", strings.Join(fields, ", "))
+}
+
+// DeclareTrailers announces trailer keys in the Trailer header.
+// It must be called before the first write, otherwise it returns an error.
+func (c *Context) DeclareTrailers(keys ...string) error {
+	return c.rw.DeclareTrailers(keys...)
+}
+
+// SetTrailer sets a trailer sent after the body once the handler returns,
+// e.g. a checksum or row count of a streamed response. See ResponseWriter.SetTrailer.
+func (c *Context) SetTrailer(key, value string) {
+	c.rw.SetTrailer(key, value)
+}
+
 // DelHeader deletes a header in the response
 func (c *Context) DelHeader(key string) {
 	if wrapped, ok := c.Response.(*ResponseWriter); ok {
@@ -57,21 +155,48 @@ func (c *Context) SetStatus(status int) error {
 	return nil
 }
 
+// bufferPool is a pool of buffers used to encode responses before writing.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
 // Context helper methods
-// JSON sends a JSON response
+// JSON sends a JSON response.
+// The data is encoded into a buffer before anything is written, so marshal errors
+// are returned with the response untouched and Content-Length is set.
+// See JSONNoSniff and JSONPrefix for the headers and prefix added by the router.
 func (c *Context) JSON(data interface{}) error {
-	c.Response.Header().Set("Content-Type", "application/json")
-	return json.NewEncoder(c.Response).Encode(data)
+	c.checkReleased()
+	return c.jsonStatus(0, data)
+}
+
+// jsonStatus sends data as JSON with status. The error helpers use it rather than
+// WriteHeader followed by JSON, which would send the status before the JSON headers.
+func (c *Context) jsonStatus(status int, data any) error {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		bufferPool.Put(buf)
+	}()
+
+	if err := json.NewEncoder(buf).Encode(data); err != nil {
+		return err
+	}
+	return c.writeJSON(status, buf.Bytes())
 }
 
 // XML sends an XML response
 func (c *Context) XML(data interface{}) error {
+	c.checkReleased()
 	c.Response.Header().Set("Content-Type", "application/xml")
 	return xml.NewEncoder(c.Response).Encode(data)
 }
 
 // String sends a string response
 func (c *Context) String(text string) error {
+	c.checkReleased()
 	c.Response.Header().Set("Content-Type", "text/plain")
 	_, err := c.Response.Write([]byte(text))
 	return err
@@ -93,6 +218,7 @@ func (c *Context) AcceptHeader() string {
 
 // Send HTML response.
 func (c *Context) HTML(html string) error {
+	c.checkReleased()
 	c.Response.Header().Set("Content-Type", "text/html")
 	_, err := c.Response.Write([]byte(html))
 	return err
@@ -105,12 +231,14 @@ func (c *Context) WriteHeader(status int) error {
 
 // Write sends a raw response
 func (c *Context) Write(data []byte) (int, error) {
+	c.checkReleased()
 	return c.Response.Write(data)
 }
 
 // Send sends a raw response and returns an error.
 // This conveniently returns only the error from the response writer.
 func (c *Context) Send(data []byte) error {
+	c.checkReleased()
 	_, err := c.Response.Write(data)
 	return err
 }
@@ -136,7 +264,14 @@ func (c *Context) Error(err error, status int, contentType ...string) error {
 
 // Param gets a path parameter value by name from the request.
 // If the parameter is not found, it checks the redirect options.
+//
+// For a catch-all parameter like {path...} it returns the decoded remainder of the path,
+// e.g "a/b/c" for "/files/a/b/c" on "/files/{path...}", and "" for "/files/".
+// Values are percent-decoded, except for encoded slashes which stay "%2F" unless the router
+// is created with AllowEncodedSlashes, e.g "a%2Fb/c" for "/files/a%2Fb/c". Use c.ParamRaw
+// for the escaped value.
 func (c *Context) Param(name string) string {
+	c.checkReleased()
 	p := c.Request.PathValue(name)
 	if p == "" {
 		// check redirect params
@@ -144,6 +279,14 @@ func (c *Context) Param(name string) string {
 		if ok {
 			p = opts.Params[name]
 		}
+		return p
+	}
+
+	// ServeMux decodes encoded slashes, which are only in RawPath.
+	if raw := c.Request.URL.RawPath; raw != "" && hasEncodedSlash(raw) && c.router != nil && !c.router.allowEncodedSlashes {
+		if raw, ok := c.rawParam(name); ok {
+			p = decodeParam(raw)
+		}
 	}
 	return p
 }
@@ -186,8 +329,10 @@ func (c *Context) ParamUInt(key string, defaults ...uint) uint {
 
 // Query returns the value of the query as a string.
 // If the query is not found, it checks the redirect options.
+// The query is parsed on first use and cached for the request, see ForEachQuery for repeated keys.
 func (c *Context) Query(key string, defaults ...string) string {
-	v := c.Request.URL.Query().Get(key)
+	c.checkReleased()
+	v := c.queryValues().Get(key)
 	if v == "" {
 		// check redirect query params
 		opts, ok := c.redirectOptions()
@@ -240,6 +385,7 @@ func (c *Context) QueryUInt(key string, defaults ...uint) uint {
 
 // Set stores a value in the context
 func (c *Context) Set(key interface{}, value interface{}) {
+	c.checkReleased()
 	c.mu.Lock()
 	defer c.mu.Unlock()
 	c.locals[key] = value
@@ -252,6 +398,7 @@ func (c *Context) Set(key interface{}, value interface{}) {
 
 // Get retrieves a value from the context
 func (c *Context) Get(key interface{}) (value interface{}, exists bool) {
+	c.checkReleased()
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 	value, exists = c.locals[key]
@@ -282,14 +429,31 @@ func (c *Context) Locals() map[any]any {
 	return c.locals
 }
 
+// SetTimezone sets the timezone of the request, e.g from the user's profile.
+// BodyParser, QueryParser and the date parameter helpers parse times in it
+// instead of DefaultTimezone. A nil loc restores DefaultTimezone.
+func (c *Context) SetTimezone(loc *time.Location) {
+	c.checkReleased()
+	c.timezone = loc
+}
+
+// Timezone returns the timezone set with SetTimezone or DefaultTimezone if none is set.
+func (c *Context) Timezone() *time.Location {
+	if c.timezone != nil {
+		return c.timezone
+	}
+	return DefaultTimezone
+}
+
 // Redirects the request to the given url.
 // Default status code is 303 (http.StatusSeeOther)
+// Paths get the base path of the router, see WithBasePath.
 func (c *Context) Redirect(url string, status ...int) error {
 	var statusCode = http.StatusSeeOther
 	if len(status) > 0 {
 		statusCode = status[0]
 	}
-	http.Redirect(c.Response, c.Request, url, statusCode)
+	http.Redirect(c.Response, c.Request, c.URLFor(url), statusCode)
 	return nil
 }
 
@@ -378,15 +542,16 @@ func (c *Context) FormValueUInt(key string, defaults ...uint) uint {
 }
 
 func (c *Context) FormFile(key string) (multipart.File, *multipart.FileHeader, error) {
+	if err := c.ParseMultipartForm(); err != nil {
+		return nil, nil, err
+	}
 	return c.Request.FormFile(key)
 }
 
+// FormFiles returns the files uploaded with the given key.
+// maxMemory overrides the in-memory threshold set with WithMultipartMemory.
 func (c *Context) FormFiles(key string, maxMemory ...int64) ([]*multipart.FileHeader, error) {
-	var memory int64 = 10 << 20 // 10 MB
-	if len(maxMemory) > 0 {
-		memory = maxMemory[0]
-	}
-	err := c.Request.ParseMultipartForm(memory)
+	err := c.ParseMultipartForm(maxMemory...)
 	if err != nil {
 		return nil, err
 	}
@@ -417,9 +582,13 @@ func (c *Context) SaveFile(fh *multipart.FileHeader, target string) error {
 }
 
 // Returns the status code of the response.
+// If the response writer was swapped by middleware, the status of the underlying
+// rex ResponseWriter is returned.
 func (c *Context) Status() int {
 	if wrapped, ok := c.Response.(*ResponseWriter); ok {
 		return wrapped.status
+	} else if c.rw != nil {
+		return c.rw.status
 	} else {
 		return 0
 	}
@@ -435,6 +604,49 @@ func (c *Context) Latency() time.Duration {
 	}
 }
 
+// ResponseSize returns the number of body bytes written to the client so far.
+func (c *Context) ResponseSize() int {
+	if c.rw == nil {
+		return 0
+	}
+	return c.rw.size
+}
+
+// Pattern returns the matched route pattern without the method prefix e.g "/users/{id}".
+// Static mounts report their prefix. It returns an empty string for unmatched requests.
+// Use it instead of the path for logging and metrics labels to avoid cardinality explosions.
+func (c *Context) Pattern() string {
+	if c.currentRoute == nil {
+		return ""
+	}
+	return c.currentRoute.pattern
+}
+
+// RouteMethod returns the method the matched route was registered with.
+// This is "GET" for HEAD requests served by a GET route.
+func (c *Context) RouteMethod() string {
+	if c.currentRoute == nil {
+		return ""
+	}
+	return c.currentRoute.method
+}
+
+// HandlerName returns the function name of the matched route's handler.
+func (c *Context) HandlerName() string {
+	if c.currentRoute == nil {
+		return ""
+	}
+	return getFuncName(c.currentRoute.handler)
+}
+
+// RouteMeta returns the metadata value stored under key for the matched route.
+func (c *Context) RouteMeta(key string) (any, bool) {
+	if c.currentRoute == nil {
+		return nil, false
+	}
+	return c.currentRoute.GetMeta(key)
+}
+
 // Returns the *rex.Router instance.
 func (c *Context) Router() *Router {
 	return c.router
@@ -442,11 +654,13 @@ func (c *Context) Router() *Router {
 
 // Path returns the request path.
 func (c *Context) Path() string {
+	c.checkReleased()
 	return c.Request.URL.Path
 }
 
 // Method returns the request method.
 func (c *Context) Method() string {
+	c.checkReleased()
 	return c.Request.Method
 }
 
@@ -459,3 +673,58 @@ func (c *Context) Host() string {
 func (c *Context) URL() string {
 	return c.Request.URL.String()
 }
+
+// responseState returns the state of the response writer. Writers swapped in by
+// middleware that do not track state fall back to the rex ResponseWriter of the request.
+func (c *Context) responseState() responseState {
+	if state, ok := c.Response.(responseState); ok {
+		return state
+	}
+
+	if c.rw != nil {
+		return c.rw
+	}
+	return nil
+}
+
+// WroteHeader reports whether the status and headers have been sent.
+// Headers can no longer be modified once it returns true.
+func (c *Context) WroteHeader() bool {
+	if state := c.responseState(); state != nil {
+		return state.WroteHeader()
+	}
+	return false
+}
+
+// Written reports whether any part of the response body has been written.
+func (c *Context) Written() bool {
+	return c.BytesWritten() > 0
+}
+
+// BytesWritten returns the number of body bytes written so far.
+func (c *Context) BytesWritten() int {
+	if state := c.responseState(); state != nil {
+		return state.Size()
+	}
+	return 0
+}
+
+// ResponseHeader returns the response header map.
+// It works whether Response is the rex ResponseWriter or a writer swapped in by middleware.
+func (c *Context) ResponseHeader() http.Header {
+	if wrapped, ok := c.Response.(*ResponseWriter); ok {
+		return wrapped.writer.Header()
+	}
+	return c.Response.Header()
+}
+
+// ResponseContentType returns the media type of the response Content-Type header
+// without parameters like charset.
+func (c *Context) ResponseContentType() string {
+	return strings.TrimSpace(strings.Split(c.ResponseHeader().Get("Content-Type"), ";")[0])
+}
+
+// SetContentType sets the Content-Type response header.
+func (c *Context) SetContentType(contentType string) {
+	c.SetHeader("Content-Type", contentType)
+}
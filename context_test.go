@@ -2,13 +2,17 @@ package rex
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -285,3 +289,327 @@ func TestFormFileOperations(t *testing.T) {
 		t.Error("status code is not OK")
 	}
 }
+
+func namedPatternHandler(c *Context) error {
+	return c.String(c.RouteMethod() + " " + c.Pattern())
+}
+
+func TestContextPatternAndHandlerName(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users/{id}", namedPatternHandler)
+
+	api := r.Group("/api")
+	api.POST("/posts/{slug}/comments", namedPatternHandler)
+
+	r.GET("/handler", func(c *Context) error {
+		return c.String(c.HandlerName())
+	})
+
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("js"), 0644)
+
+	var staticPattern string
+	r.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			if c.Path() == "/static/app.js" {
+				staticPattern = c.Pattern()
+			}
+			return next(c)
+		}
+	})
+	r.Static("/static", dir)
+
+	tests := []struct {
+		method   string
+		path     string
+		expected string
+	}{
+		{http.MethodGet, "/users/42", "GET /users/{id}"},
+		{http.MethodHead, "/users/42", ""},
+		{http.MethodPost, "/api/posts/hello/comments", "POST /api/posts/{slug}/comments"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Body.String() != tt.expected {
+			t.Errorf("%s %s: expected %q, got %q", tt.method, tt.path, tt.expected, w.Body.String())
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/handler", nil))
+	if !strings.Contains(w.Body.String(), "TestContextPatternAndHandlerName") {
+		t.Errorf("expected handler name to contain the test name, got %q", w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+	if staticPattern != "/static/" {
+		t.Errorf("expected static pattern /static/, got %q", staticPattern)
+	}
+
+	// unmatched requests have no pattern
+	c := &Context{}
+	if c.Pattern() != "" || c.RouteMethod() != "" || c.HandlerName() != "" {
+		t.Error("expected empty route info for unmatched context")
+	}
+}
+
+type failingMarshaler struct{}
+
+func (failingMarshaler) MarshalJSON() ([]byte, error) {
+	return nil, errors.New("cannot marshal")
+}
+
+// failAfterFirstWrite fails every write after the first one, like a broken pipe.
+type failAfterFirstWrite struct {
+	*httptest.ResponseRecorder
+	writes int
+}
+
+func (w *failAfterFirstWrite) Write(b []byte) (int, error) {
+	w.writes++
+	if w.writes > 1 {
+		return 0, errors.New("broken pipe")
+	}
+	return w.ResponseRecorder.Write(b)
+}
+
+func TestContextJSONMarshalError(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", func(c *Context) error {
+		return c.JSON(map[string]any{"value": failingMarshaler{}})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.Status() != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", res.Status())
+	}
+
+	if res.Header("Content-Type") == "application/json" {
+		t.Error("expected no JSON content type for failed encoding")
+	}
+
+	if !strings.Contains(res.BodyString(), "cannot marshal") {
+		t.Errorf("expected clean error body, got %q", res.BodyString())
+	}
+}
+
+func TestContextJSONContentLength(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", func(c *Context) error {
+		return c.JSON(map[string]string{"name": "rex"})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if want := strconv.Itoa(len(res.BodyString())); res.Header("Content-Length") != want {
+		t.Errorf("expected Content-Length %s, got %q", want, res.Header("Content-Length"))
+	}
+}
+
+func TestErrorAfterOutputStarted(t *testing.T) {
+	var logs bytes.Buffer
+	r := NewRouter(WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	r.GET("/", func(c *Context) error {
+		if _, err := c.Write([]byte("partial")); err != nil {
+			return err
+		}
+		_, err := c.Write([]byte("rest of the body"))
+		return err
+	})
+
+	w := &failAfterFirstWrite{ResponseRecorder: httptest.NewRecorder()}
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", w.Code)
+	}
+
+	if w.Body.String() != "partial" {
+		t.Errorf("expected only the partial body, got %q", w.Body.String())
+	}
+
+	if !strings.Contains(logs.String(), "broken pipe") {
+		t.Errorf("expected the write error to be logged, got %q", logs.String())
+	}
+}
+
+// plainWriter hides the rex ResponseWriter behind a writer without state tracking.
+type plainWriter struct {
+	http.ResponseWriter
+}
+
+func TestContextResponseState(t *testing.T) {
+	swap := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&plainWriter{w}, r)
+		})
+	}
+
+	for _, swapped := range []bool{false, true} {
+		r := NewRouter()
+		if swapped {
+			r.Use(r.WrapMiddleware(swap))
+		}
+
+		r.GET("/", func(c *Context) error {
+			if _, ok := c.Response.(*ResponseWriter); ok == swapped {
+				t.Fatalf("swapped=%v: unexpected writer %T", swapped, c.Response)
+			}
+
+			if c.WroteHeader() || c.Written() || c.BytesWritten() != 0 {
+				t.Errorf("swapped=%v: expected nothing written yet", swapped)
+			}
+
+			c.SetContentType("application/json; charset=utf-8")
+			c.ResponseHeader().Set("X-Custom", "1")
+
+			if ct := c.ResponseContentType(); ct != "application/json" {
+				t.Errorf("swapped=%v: expected application/json, got %q", swapped, ct)
+			}
+
+			c.Write([]byte(`{"ok":true}`))
+
+			if !c.WroteHeader() || !c.Written() {
+				t.Errorf("swapped=%v: expected header and body to be written", swapped)
+			}
+
+			if n := c.BytesWritten(); n != 11 {
+				t.Errorf("swapped=%v: expected 11 bytes written, got %d", swapped, n)
+			}
+
+			if c.Status() != http.StatusOK {
+				t.Errorf("swapped=%v: expected status 200, got %d", swapped, c.Status())
+			}
+			return nil
+		})
+
+		res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+		if res.Header("X-Custom") != "1" || res.Header("Content-Type") != "application/json; charset=utf-8" {
+			t.Errorf("swapped=%v: headers not sent: %v", swapped, res.Result().Header)
+		}
+	}
+}
+
+func TestAddHeaderAndAppendVary(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", func(c *Context) error {
+		c.AddHeader("Link", `</app.css>; rel=preload; as=style`)
+		c.AddHeader("Link", `</app.js>; rel=preload; as=script`)
+
+		c.Response.Header().Add("Vary", "Accept-Encoding")
+		c.Response.Header().Add("Vary", "origin, Accept")
+		c.AppendVary("Origin")
+		c.AppendVary("Cookie")
+		return c.String("ok")
+	})
+	r.GET("/star", func(c *Context) error {
+		c.SetHeader("Vary", "*")
+		c.AppendVary("Origin")
+		return nil
+	})
+
+	res := r.Test(NewTestRequest(http.MethodGet, "/").Build())
+
+	if links := res.Result().Header.Values("Link"); len(links) != 2 {
+		t.Errorf("expected both Link headers, got %q", links)
+	}
+
+	if vary := res.Result().Header.Values("Vary"); len(vary) != 1 || vary[0] != "Accept-Encoding, origin, Accept, Cookie" {
+		t.Errorf("expected merged Vary without duplicates, got %q", vary)
+	}
+
+	res = r.Test(NewTestRequest(http.MethodGet, "/star").Build())
+	if vary := res.Result().Header.Get("Vary"); vary != "*" {
+		t.Errorf("expected Vary: * to be kept, got %q", vary)
+	}
+}
+
+func TestTrailers(t *testing.T) {
+	r := NewRouter()
+	r.GET("/export", func(c *Context) error {
+		if err := c.DeclareTrailers("X-Row-Count"); err != nil {
+			return err
+		}
+
+		rows := 0
+		for i := 0; i < 3; i++ {
+			fmt.Fprintf(c.Response, "row %d\n", i)
+			c.Response.(http.Flusher).Flush()
+			rows++
+		}
+
+		c.SetTrailer("X-Row-Count", strconv.Itoa(rows))
+		c.SetTrailer("X-Checksum", "abc123") // undeclared
+		return nil
+	})
+	r.GET("/late", func(c *Context) error {
+		c.String("body")
+		if err := c.DeclareTrailers("X-Late"); err == nil {
+			t.Error("expected declaring trailers after the first write to fail")
+		}
+		return nil
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/export")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	if res.Header.Get("X-Row-Count") != "" {
+		t.Error("expected the trailer not to be sent as a header")
+	}
+
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "row 0\nrow 1\nrow 2\n" {
+		t.Errorf("unexpected body %q", body)
+	}
+
+	// Trailers are available once the body is read.
+	if got := res.Trailer.Get("X-Row-Count"); got != "3" {
+		t.Errorf("expected X-Row-Count trailer 3, got %q", got)
+	}
+
+	if got := res.Trailer.Get("X-Checksum"); got != "abc123" {
+		t.Errorf("expected undeclared X-Checksum trailer, got %q", got)
+	}
+
+	late, err := http.Get(srv.URL + "/late")
+	if err != nil {
+		t.Fatal(err)
+	}
+	late.Body.Close()
+}
+
+func TestErrorAfterStatusSent(t *testing.T) {
+	var logs bytes.Buffer
+	r := NewRouter(WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	r.GET("/", func(c *Context) error {
+		c.WriteHeader(http.StatusAccepted)
+		return errors.New("late failure")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("expected the sent status 202, got %d", w.Code)
+	}
+
+	if w.Body.Len() != 0 {
+		t.Errorf("expected no error body after the status was sent, got %q", w.Body.String())
+	}
+
+	if !strings.Contains(logs.String(), "late failure") {
+		t.Errorf("expected the error to be logged, got %q", logs.String())
+	}
+}
@@ -0,0 +1,88 @@
+package rex
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrContinuePending is returned by reads of the body of a request expecting 100 Continue
+// before c.AcceptContinue is called, when the router was created with AutoContinue(false).
+var ErrContinuePending = errors.New("rex: request body read before AcceptContinue")
+
+// AutoContinue sets whether reading the body of a request with "Expect: 100-continue"
+// sends the interim 100 Continue response. It is enabled by default.
+//
+// net/http answers the expectation of HTTP/1.1 and HTTP/2 requests with a body, announced
+// with a non-zero Content-Length or chunked encoding, on the first read of the body. If the
+// handler writes a final response without reading the body, no 100 Continue is sent,
+// the client does not transmit the body and net/http does not drain it.
+// A middleware reading the body, like decompression or body hashing, therefore lets the
+// client send the body before auth or quota middleware have rejected the request.
+//
+// When disabled, reads of the body return ErrContinuePending until c.AcceptContinue is
+// called, so that the handler decides when the client may send the body. Requests without
+// the expectation are not affected.
+func AutoContinue(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.autoContinue = enabled
+	}
+}
+
+// ExpectsContinue reports whether the client sent "Expect: 100-continue" and waits for
+// the interim 100 Continue response before sending the body.
+func (c *Context) ExpectsContinue() bool {
+	req := c.Request
+	return req.ProtoAtLeast(1, 1) && req.ContentLength != 0 &&
+		strings.EqualFold(strings.TrimSpace(req.Header.Get("Expect")), "100-continue")
+}
+
+// AcceptContinue sends the interim 100 Continue response, telling the client to send the body,
+// and allows the body to be read when the router was created with AutoContinue(false).
+// Call it once the headers, auth and quota of the request were validated.
+// It does nothing if the request does not expect 100 Continue, it was already sent,
+// or the response header was written.
+//
+// The response is written with WriteHeader(http.StatusContinue) on the writer of net/http,
+// which then does not send its own 100 Continue on the first read of the body.
+func (c *Context) AcceptContinue() {
+	c.checkReleased()
+
+	if !c.ExpectsContinue() || (c.continueGate != nil && c.continueGate.accepted.Load()) {
+		return
+	}
+
+	if c.continueGate != nil {
+		c.continueGate.accepted.Store(true)
+	}
+
+	if c.rw == nil || c.rw.statusSent {
+		return
+	}
+	c.rw.writer.WriteHeader(http.StatusContinue)
+}
+
+// continueGate is the body of a request expecting 100 Continue with AutoContinue(false).
+type continueGate struct {
+	io.ReadCloser
+	accepted atomic.Bool
+}
+
+func (g *continueGate) Read(p []byte) (int, error) {
+	if !g.accepted.Load() {
+		return 0, ErrContinuePending
+	}
+	return g.ReadCloser.Read(p)
+}
+
+// gateContinue holds the body of the request until AcceptContinue if it expects 100 Continue.
+func (c *Context) gateContinue() {
+	if !c.ExpectsContinue() || c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return
+	}
+
+	c.continueGate = &continueGate{ReadCloser: c.Request.Body}
+	c.Request.Body = c.continueGate
+}
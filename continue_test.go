@@ -0,0 +1,139 @@
+package rex_test
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// dialExpectContinue sends the headers of a POST with "Expect: 100-continue" and no body yet.
+func dialExpectContinue(t *testing.T, srv *httptest.Server, body string, header string) (net.Conn, *bufio.Reader, *http.Request) {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/upload", nil)
+	headers := "POST /upload HTTP/1.1\r\nHost: example.com\r\nExpect: 100-continue\r\n" +
+		"Content-Type: text/plain\r\nContent-Length: " + strconv.Itoa(len(body)) + "\r\n" + header + "\r\n"
+	if _, err := io.WriteString(conn, headers); err != nil {
+		t.Fatal(err)
+	}
+	return conn, bufio.NewReader(conn), req
+}
+
+func newUploadRouter(options ...rex.RouterOption) (*rex.Router, *int) {
+	r := rex.NewRouter(options...)
+	var handled int
+
+	requireAuth := func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if c.GetHeader("Authorization") == "" {
+				return rex.NewError(http.StatusUnauthorized, "unauthorized")
+			}
+			c.AcceptContinue()
+			return next(c)
+		}
+	}
+
+	r.POST("/upload", func(c *rex.Context) error {
+		handled++
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		return c.String("got " + string(body))
+	}, requireAuth)
+	return r, &handled
+}
+
+func TestExpectContinueRejectedWithoutBody(t *testing.T) {
+	r, handled := newUploadRouter(rex.AutoContinue(false))
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close) // After the connection is closed.
+
+	_, br, req := dialExpectContinue(t, srv, "0123456789", "")
+
+	// The body is never sent: the server must answer from the headers alone.
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusUnauthorized || *handled != 0 {
+		t.Errorf("expected 401 without running the handler, got %d, handled %d", res.StatusCode, *handled)
+	}
+}
+
+func TestAcceptContinue(t *testing.T) {
+	for _, auto := range []bool{true, false} {
+		r, _ := newUploadRouter(rex.AutoContinue(auto))
+		srv := httptest.NewServer(r)
+		t.Cleanup(srv.Close) // After the connection is closed.
+
+		conn, br, req := dialExpectContinue(t, srv, "0123456789", "Authorization: Bearer token\r\n")
+
+		interim, err := http.ReadResponse(br, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if interim.StatusCode != http.StatusContinue {
+			t.Fatalf("auto %v: expected 100 Continue before the body, got %d", auto, interim.StatusCode)
+		}
+
+		if _, err := io.WriteString(conn, "0123456789"); err != nil {
+			t.Fatal(err)
+		}
+
+		res, err := http.ReadResponse(br, req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(res.Body)
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK || string(body) != "got 0123456789" {
+			t.Errorf("auto %v: expected the upload to be processed, got %d %q", auto, res.StatusCode, body)
+		}
+	}
+}
+
+func TestContinuePending(t *testing.T) {
+	r := rex.NewRouter(rex.AutoContinue(false))
+
+	var readErr error
+	var expects bool
+	r.POST("/upload", func(c *rex.Context) error {
+		expects = c.ExpectsContinue()
+		_, readErr = io.ReadAll(c.Request.Body)
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("data"))
+	req.Header.Set("Expect", "100-continue")
+	r.Test(req)
+
+	if !expects || !errors.Is(readErr, rex.ErrContinuePending) {
+		t.Errorf("expected the read to fail with ErrContinuePending, got %v (expects %v)", readErr, expects)
+	}
+
+	r.Test(httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader("data")))
+	if expects || readErr != nil {
+		t.Errorf("expected requests without the expectation to read the body, got %v", readErr)
+	}
+}
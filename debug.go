@@ -0,0 +1,225 @@
+package rex
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// PanicError is a recovered panic along with the stack of the goroutine that panicked.
+// The recovery middleware returns it to the error handler in Debug mode.
+type PanicError struct {
+	Value any       // The value passed to panic.
+	Stack []uintptr // Program counters of the panicking goroutine.
+}
+
+// NewPanicError captures the current stack for the recovered value v.
+// Call it from the deferred function that recovered the panic.
+func NewPanicError(v any) *PanicError {
+	pcs := make([]uintptr, 64)
+	n := runtime.Callers(2, pcs)
+	return &PanicError{Value: v, Stack: pcs[:n]}
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprint(e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// StackFrame is a single frame of a stack trace shown on the debug error page.
+type StackFrame struct {
+	Function string `json:"function"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	User     bool   `json:"user"` // Frame belongs to application code.
+}
+
+// Frames returns the stack frames of the panic, skipping the runtime frames.
+func (e *PanicError) Frames() []StackFrame {
+	frames := runtime.CallersFrames(e.Stack)
+	var out []StackFrame
+	for {
+		frame, more := frames.Next()
+		if pkg := framePackage(frame.Function); pkg != "runtime" {
+			out = append(out, StackFrame{
+				Function: frame.Function,
+				File:     frame.File,
+				Line:     frame.Line,
+				User:     isUserPackage(pkg),
+			})
+		}
+
+		if !more {
+			break
+		}
+	}
+	return out
+}
+
+var rexPackage = reflect.TypeOf(Context{}).PkgPath()
+
+// framePackage returns the import path of the package of a fully qualified function name.
+func framePackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	if dot := strings.Index(function[slash+1:], "."); dot >= 0 {
+		return function[:slash+1+dot]
+	}
+	return function
+}
+
+// isUserPackage reports whether pkg is neither part of the standard library nor of rex.
+// External test packages are always user code.
+func isUserPackage(pkg string) bool {
+	if strings.HasSuffix(pkg, "_test") {
+		return true
+	}
+
+	if pkg == rexPackage || strings.HasPrefix(pkg, rexPackage+"/") {
+		return false
+	}
+
+	first, _, _ := strings.Cut(pkg, "/")
+	return pkg == "main" || strings.Contains(first, ".")
+}
+
+// sensitiveKeys are masked in headers, form values and locals on the debug page.
+var sensitiveKeys = []string{"auth", "cookie", "csrf", "key", "password", "secret", "session", "token"}
+
+const maskedValue = "********"
+
+func isSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range sensitiveKeys {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// debugValue is a key and its (possibly masked) values.
+type debugValue struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
+}
+
+func maskValues(values map[string][]string) []debugValue {
+	out := make([]debugValue, 0, len(values))
+	for k, v := range values {
+		if isSensitive(k) {
+			v = []string{maskedValue}
+		}
+		out = append(out, debugValue{Key: k, Values: v})
+	}
+	slices.SortFunc(out, func(a, b debugValue) int { return strings.Compare(a.Key, b.Key) })
+	return out
+}
+
+// debugTemplate describes a failed template on the debug page.
+type debugTemplate struct {
+	Name    string       `json:"name"`
+	Keys    []string     `json:"keys"`
+	Defined []string     `json:"defined"` // All template names, to spot typos.
+	File    string       `json:"file,omitempty"`
+	Line    int          `json:"line,omitempty"`
+	Source  []SourceLine `json:"source,omitempty"`
+}
+
+// debugInfo is rendered by the debug error page and sent to JSON clients in Debug mode.
+type debugInfo struct {
+	Status   int            `json:"status"`
+	Error    string         `json:"error"`
+	Type     string         `json:"type"`
+	Panic    bool           `json:"panic"`
+	Stack    []StackFrame   `json:"stack,omitempty"`
+	Method   string         `json:"method"`
+	Path     string         `json:"path"`
+	Pattern  string         `json:"pattern,omitempty"`
+	Headers  []debugValue   `json:"headers"`
+	Form     []debugValue   `json:"form"`
+	Locals   []debugValue   `json:"locals"`
+	Template *debugTemplate `json:"template,omitempty"`
+}
+
+func (c *Context) debugInfo(err error) debugInfo {
+	info := debugInfo{
+		Status:  http.StatusInternalServerError,
+		Error:   err.Error(),
+		Type:    fmt.Sprintf("%T", err),
+		Method:  c.Request.Method,
+		Path:    c.Request.URL.Path,
+		Pattern: c.Pattern(),
+		Headers: maskValues(c.Request.Header),
+	}
+
+	var pe *PanicError
+	if errors.As(err, &pe) {
+		info.Panic = true
+		info.Stack = pe.Frames()
+	}
+
+	var te TemplateError
+	if errors.As(err, &te) {
+		info.Template = &debugTemplate{
+			Name:    te.Name,
+			Keys:    te.Keys,
+			Defined: c.router.DefinedTemplateNames(),
+			File:    te.File,
+			Line:    te.Line,
+			Source:  te.Source,
+		}
+	}
+
+	// Only report values that were already parsed, the body must not be consumed here.
+	form := c.Request.Form
+	if form == nil {
+		form = c.Request.URL.Query()
+	}
+	info.Form = maskValues(form)
+
+	c.mu.RLock()
+	locals := make(url.Values, len(c.locals))
+	for k, v := range c.locals {
+		locals.Set(fmt.Sprint(k), fmt.Sprintf("%+v", v))
+	}
+	c.mu.RUnlock()
+	info.Locals = maskValues(locals)
+	return info
+}
+
+//go:embed debug.html
+var debugPage string
+
+var debugTemplateHTML = template.Must(template.New("debug").Parse(debugPage))
+
+// renderDebugError writes the development error page for err.
+// It must only be called when Debug is true.
+func (c *Context) renderDebugError(err error) {
+	info := c.debugInfo(err)
+	c.SetHeader("Cache-Control", "no-store")
+
+	accept := strings.Split(c.Request.Header.Get("Accept"), ";")[0]
+	if accept == "application/json" {
+		c.jsonStatus(info.Status, info)
+		return
+	}
+
+	c.SetContentType("text/html; charset=utf-8")
+	c.WriteHeader(info.Status)
+	if err := debugTemplateHTML.Execute(c.Response, info); err != nil {
+		c.router.logger.Error("failed to render debug error page", "error", err)
+	}
+}
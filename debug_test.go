@@ -0,0 +1,140 @@
+package rex_test
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestDebugErrorJSON(t *testing.T) {
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	tmpl := template.Must(template.New("page.html").Parse(`{{index .items 5}}`))
+	r := rex.NewRouter(rex.WithTemplates(tmpl))
+	r.GET("/page", func(c *rex.Context) error {
+		c.Set("user", "john")
+		c.Set("session_id", "abc123")
+		return c.ExecuteTemplate("page.html", rex.Map{"items": []int{1}, "title": "Home"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/page?q=search&password=hunter2", nil)
+	req.Header.Set("Accept", "application/json")
+	res := r.Test(req)
+
+	if res.Status() != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", res.Status())
+	}
+
+	var info struct {
+		Error    string `json:"error"`
+		Pattern  string `json:"pattern"`
+		Template struct {
+			Name string   `json:"name"`
+			Keys []string `json:"keys"`
+		} `json:"template"`
+		Form []struct {
+			Key    string   `json:"key"`
+			Values []string `json:"values"`
+		} `json:"form"`
+	}
+
+	if err := res.JSON(&info); err != nil {
+		t.Fatalf("expected JSON body: %v\n%s", err, res.BodyString())
+	}
+
+	if info.Template.Name != "page.html" || strings.Join(info.Template.Keys, ",") != "items,title" {
+		t.Errorf("unexpected template info %+v", info.Template)
+	}
+
+	if info.Pattern != "/page" {
+		t.Errorf("expected pattern /page, got %q", info.Pattern)
+	}
+
+	body := res.BodyString()
+	if strings.Contains(body, "hunter2") || strings.Contains(body, "abc123") {
+		t.Errorf("expected sensitive values to be masked, got %s", body)
+	}
+
+	if !strings.Contains(body, "john") || !strings.Contains(body, "search") {
+		t.Errorf("expected locals and query values in the debug output, got %s", body)
+	}
+}
+
+func TestDebugErrorDisabled(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/fail", func(c *rex.Context) error {
+		return errors.New("database unavailable")
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/fail", nil))
+	if res.Status() != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", res.Status())
+	}
+
+	if res.BodyString() != "database unavailable" {
+		t.Errorf("expected terse error body, got %q", res.BodyString())
+	}
+}
+
+func panickingMiddleware(next rex.HandlerFunc) rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		panic("boom")
+	}
+}
+
+func passMiddleware(next rex.HandlerFunc) rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		return next(c)
+	}
+}
+
+func TestDebugMiddlewarePanicIsNamed(t *testing.T) {
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	var logs bytes.Buffer
+	r := rex.NewRouter(rex.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	var recovered any
+	r.Use(func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) (err error) {
+			defer func() {
+				if recovered = recover(); recovered != nil {
+					err = errors.New("recovered")
+				}
+			}()
+			return next(c)
+		}
+	})
+
+	r.GET("/mw", func(c *rex.Context) error { return nil }, passMiddleware, panickingMiddleware)
+	r.GET("/handler", func(c *rex.Context) error { panic("handler boom") }, passMiddleware)
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/mw", nil))
+	if recovered != "boom" {
+		t.Fatalf("expected the recovery middleware to get the panic, got %v", recovered)
+	}
+
+	out := logs.String()
+	if strings.Count(out, "panic in middleware") != 1 || !strings.Contains(out, "panickingMiddleware") {
+		t.Errorf("expected one report naming panickingMiddleware, got %q", out)
+	}
+
+	logs.Reset()
+	r.Test(httptest.NewRequest(http.MethodGet, "/handler", nil))
+	if recovered != "handler boom" {
+		t.Fatalf("expected the recovery middleware to get the panic, got %v", recovered)
+	}
+
+	if strings.Contains(logs.String(), "panic in middleware") {
+		t.Errorf("expected a handler panic not to be reported as a middleware panic, got %q", logs.String())
+	}
+}
@@ -0,0 +1,124 @@
+package rex
+
+import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// ErrDecompressedTooLarge is returned when a compressed request body expands past its limit.
+var ErrDecompressedTooLarge = errors.New("rex: decompressed request body too large")
+
+// DecompressLimits bounds the size of decompressed request bodies to protect against zip bombs.
+type DecompressLimits struct {
+	// Ratio is the maximum expansion of the body relative to its Content-Length.
+	// It is ignored if the request has no Content-Length or Ratio is zero.
+	Ratio int64
+
+	// MaxBytes is the absolute maximum size of the decompressed body.
+	MaxBytes int64
+}
+
+// DefaultDecompressLimits allows bodies to expand 10 times up to 32 MB.
+var DefaultDecompressLimits = DecompressLimits{Ratio: 10, MaxBytes: 32 << 20}
+
+// limit returns the maximum decompressed size for a body of compressed length n.
+// Zero limits are not enforced.
+func (l DecompressLimits) limit(n int64) int64 {
+	limit := int64(math.MaxInt64)
+	if l.MaxBytes > 0 {
+		limit = l.MaxBytes
+	}
+
+	if l.Ratio > 0 && n > 0 && n*l.Ratio < limit {
+		limit = n * l.Ratio
+	}
+	return limit
+}
+
+// WithDecompressLimits sets the limits for request bodies decompressed by BodyParser.
+// The default is DefaultDecompressLimits.
+func WithDecompressLimits(limits DecompressLimits) RouterOption {
+	return func(r *Router) {
+		r.decompressLimits = limits
+	}
+}
+
+// DecompressBody replaces the body of a request sent with Content-Encoding gzip or deflate
+// by a reader of the decompressed body. Reads past the limit fail with ErrDecompressedTooLarge.
+// The Content-Encoding and Content-Length headers are removed afterwards.
+// Requests without a supported encoding are left unchanged.
+// Errors are returned as a FormError of kind DecompressionFailed.
+func DecompressBody(req *http.Request, limits DecompressLimits) error {
+	encoding := strings.ToLower(strings.TrimSpace(req.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" || req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+
+	var (
+		reader io.ReadCloser
+		err    error
+	)
+
+	switch encoding {
+	case "gzip", "x-gzip":
+		reader, err = gzip.NewReader(req.Body)
+	case "deflate":
+		reader, err = zlib.NewReader(req.Body)
+	default:
+		return FormError{
+			Err:  fmt.Errorf("unsupported content encoding: %q", encoding),
+			Kind: DecompressionFailed,
+		}
+	}
+
+	if err != nil {
+		return FormError{Err: err, Kind: DecompressionFailed}
+	}
+
+	req.Body = &decompressedBody{
+		reader:    reader,
+		body:      req.Body,
+		remaining: limits.limit(req.ContentLength),
+	}
+	req.Header.Del("Content-Encoding")
+	req.Header.Del("Content-Length")
+	req.ContentLength = -1
+	return nil
+}
+
+// decompressedBody reads at most remaining decompressed bytes.
+type decompressedBody struct {
+	reader    io.ReadCloser
+	body      io.ReadCloser
+	remaining int64
+}
+
+func (d *decompressedBody) Read(p []byte) (int, error) {
+	if d.remaining <= 0 {
+		// Allow the reader to report a clean EOF at the limit.
+		var probe [1]byte
+		if n, err := d.reader.Read(probe[:]); n == 0 && err == io.EOF {
+			return 0, io.EOF
+		}
+		return 0, ErrDecompressedTooLarge
+	}
+
+	if int64(len(p)) > d.remaining {
+		p = p[:d.remaining]
+	}
+
+	n, err := d.reader.Read(p)
+	d.remaining -= int64(n)
+	return n, err
+}
+
+func (d *decompressedBody) Close() error {
+	d.reader.Close()
+	return d.body.Close()
+}
@@ -0,0 +1,100 @@
+package rex_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(data))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBodyParserGzip(t *testing.T) {
+	type payload struct {
+		Name  string `json:"name"`
+		Items []int  `json:"items"`
+	}
+
+	var kind rex.FormErrorKind
+	r := rex.NewRouter()
+	r.POST("/items", func(c *rex.Context) error {
+		var p payload
+		if err := c.BodyParser(&p); err != nil {
+			var fe rex.FormError
+			if errors.As(err, &fe) {
+				kind = fe.Kind
+			}
+			return err
+		}
+		return c.JSON(p)
+	})
+
+	post := func(body []byte) *rex.TestResponse {
+		req := httptest.NewRequest(http.MethodPost, "/items", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		return r.Test(req)
+	}
+
+	res := post(gzipBytes(t, `{"name":"rex","items":[1,2,3]}`))
+	if res.Status() != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", res.Status(), res.BodyString())
+	}
+
+	var got payload
+	if err := res.JSON(&got); err != nil || got.Name != "rex" || len(got.Items) != 3 {
+		t.Errorf("unexpected payload %+v (%v)", got, err)
+	}
+
+	res = post([]byte("not gzip"))
+	if res.Status() != http.StatusBadRequest || kind != rex.DecompressionFailed {
+		t.Errorf("expected 400 with kind %s, got %d with kind %s", rex.DecompressionFailed, res.Status(), kind)
+	}
+
+	// A small body that expands far beyond 10x its size.
+	kind = ""
+	bomb := `{"name":"` + strings.Repeat("a", 4<<20) + `"}`
+	res = post(gzipBytes(t, bomb))
+	if res.Status() != http.StatusBadRequest || kind != rex.DecompressionFailed {
+		t.Errorf("expected 400 with kind %s for a bomb, got %d with kind %s", rex.DecompressionFailed, res.Status(), kind)
+	}
+}
+
+func TestDecompressLimitsOption(t *testing.T) {
+	type payload struct {
+		Name string `json:"name"`
+	}
+
+	r := rex.NewRouter(rex.WithDecompressLimits(rex.DecompressLimits{MaxBytes: 1 << 20}))
+	r.POST("/", func(c *rex.Context) error {
+		var p payload
+		if err := c.BodyParser(&p); err != nil {
+			return err
+		}
+		return c.String(p.Name)
+	})
+
+	// Without a ratio, a highly compressible body below MaxBytes is accepted.
+	name := strings.Repeat("a", 512<<10)
+	req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(gzipBytes(t, `{"name":"`+name+`"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Content-Encoding", "gzip")
+
+	if res := r.Test(req); res.Status() != http.StatusOK || res.BodyString() != name {
+		t.Errorf("expected body below MaxBytes to parse, got %d", res.Status())
+	}
+}
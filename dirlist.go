@@ -0,0 +1,205 @@
+package rex
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DirListTemplate enables a formatted directory index for Static and StaticFS mounts
+// of directories without an index.html file. name is a template in the router's template set
+// that is passed "path", "entries" ([]DirEntry, directories first) and "readme",
+// the contents of a README.md in the directory if present.
+// If the router has no templates configured or name is empty, a built-in template is used.
+// Hidden files are listed only if ServeDotfiles is true.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.DirListTemplate("")) // use the built-in template
+func DirListTemplate(name string) RouterOption {
+	return func(r *Router) {
+		r.dirListing = true
+		r.dirListTemplate = name
+	}
+}
+
+// DirEntry is a file or directory shown in a directory listing.
+type DirEntry struct {
+	Name    string    // Base name of the entry. Directories end with a slash.
+	Size    int64     // Size in bytes. Zero for directories.
+	ModTime time.Time // Modification time.
+	IsDir   bool      // Whether the entry is a directory.
+}
+
+// Href returns the escaped relative link to the entry.
+func (e DirEntry) Href() string {
+	if e.IsDir {
+		return url.PathEscape(strings.TrimSuffix(e.Name, "/")) + "/"
+	}
+	return url.PathEscape(e.Name)
+}
+
+// HumanSize returns the size formatted with binary units like 1.5 KiB.
+func (e DirEntry) HumanSize() string {
+	if e.IsDir {
+		return "-"
+	}
+
+	const unit = 1024
+	if e.Size < unit {
+		return fmt.Sprintf("%d B", e.Size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := e.Size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(e.Size)/float64(div), "KMGTPE"[exp])
+}
+
+// maxReadmeSize is the maximum number of bytes of README.md shown above a listing.
+const maxReadmeSize = 64 << 10
+
+//go:embed dirlist.html
+var dirListPage string
+
+var dirListTemplateHTML = template.Must(template.New("dirlist").Parse(dirListPage))
+
+// dirLister renders directory listings for static mounts.
+// A nil *dirLister leaves directories to http.FileServer.
+type dirLister struct {
+	router *Router
+	prefix string // prefix stripped from the request path before serve is called
+}
+
+// dirLister returns the directory lister of the router or nil if listings are not enabled.
+func (r *Router) dirLister(strippedPrefix string) *dirLister {
+	if !r.dirListing {
+		return nil
+	}
+	return &dirLister{router: r, prefix: strippedPrefix}
+}
+
+// serve handles a request for the directory name in fsys.
+// It returns false if the request should be handled by the file server instead,
+// which is the case for directories with an index.html, paths without a trailing slash
+// and all directories if l is nil and DisableDirListing is false.
+func (l *dirLister) serve(w http.ResponseWriter, req *http.Request, fsys http.FileSystem, name string) bool {
+	if index, err := fsys.Open(path.Join(name, "index.html")); err == nil {
+		index.Close()
+		return false
+	}
+
+	if DisableDirListing {
+		http.NotFound(w, req)
+		return true
+	}
+
+	if l == nil {
+		return false
+	}
+
+	urlPath := l.prefix + req.URL.Path
+	if !strings.HasSuffix(urlPath, "/") {
+		return false
+	}
+
+	dir, err := fsys.Open(name)
+	if err != nil {
+		http.NotFound(w, req)
+		return true
+	}
+	defer dir.Close()
+
+	infos, err := dir.Readdir(-1)
+	if err != nil {
+		http.Error(w, "error reading directory", http.StatusInternalServerError)
+		return true
+	}
+
+	entries := make([]DirEntry, 0, len(infos))
+	for _, info := range infos {
+		if !ServeDotfiles && strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		entries = append(entries, newDirEntry(info))
+	}
+	sortDirEntries(entries)
+
+	data := Map{
+		"path":    urlPath,
+		"entries": entries,
+		"readme":  readme(fsys, name),
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	tmpl, tmplName := dirListTemplateHTML, "dirlist"
+	if r := l.router; r.template != nil && r.dirListTemplate != "" {
+		tmpl, tmplName = r.template, r.dirListTemplate
+	}
+
+	var buf strings.Builder
+	if err := tmpl.ExecuteTemplate(&buf, tmplName, data); err != nil {
+		l.router.logger.Error("failed to render directory listing", "error", err, "path", urlPath)
+		http.Error(w, "error rendering directory listing", http.StatusInternalServerError)
+		return true
+	}
+	io.WriteString(w, buf.String())
+	return true
+}
+
+func newDirEntry(info fs.FileInfo) DirEntry {
+	entry := DirEntry{
+		Name:    info.Name(),
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}
+
+	if entry.IsDir {
+		entry.Name += "/"
+	} else {
+		entry.Size = info.Size()
+	}
+	return entry
+}
+
+// sortDirEntries sorts directories first, then by case-insensitive name.
+func sortDirEntries(entries []DirEntry) {
+	slices.SortFunc(entries, func(a, b DirEntry) int {
+		if a.IsDir != b.IsDir {
+			if a.IsDir {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+}
+
+// readme returns up to maxReadmeSize bytes of README.md in the directory name.
+func readme(fsys http.FileSystem, name string) string {
+	f, err := fsys.Open(path.Join(name, "README.md"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	if stat, err := f.Stat(); err != nil || stat.IsDir() {
+		return ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(f, maxReadmeSize))
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
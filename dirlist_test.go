@@ -0,0 +1,112 @@
+package rex_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func setupListingDir(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, "docs", "zeta"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]string{
+		"docs/alpha.txt": strings.Repeat("a", 2048),
+		"docs/README.md": "# Shared <docs>",
+		"docs/.secret":   "hidden",
+		"docs/zeta/a.go": "package a",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestStaticDirListing(t *testing.T) {
+	root := setupListingDir(t)
+
+	r := rex.NewRouter(rex.DirListTemplate(""))
+	r.Static("/files", root)
+	r.StaticFS("/fs", http.Dir(root))
+
+	for _, prefix := range []string{"/files", "/fs"} {
+		t.Run(prefix, func(t *testing.T) {
+			res := r.Test(httptest.NewRequest(http.MethodGet, prefix+"/docs/", nil))
+			if res.Status() != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", res.Status())
+			}
+
+			body := res.BodyString()
+			for _, want := range []string{"Index of " + prefix + "/docs/", "alpha.txt", "2.0 KiB", "README.md", "&lt;docs&gt;", `href="zeta/"`} {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected listing to contain %q, got:\n%s", want, body)
+				}
+			}
+
+			if strings.Contains(body, ".secret") {
+				t.Error("expected hidden files to be excluded from the listing")
+			}
+
+			if strings.Index(body, "zeta/") > strings.Index(body, "alpha.txt") {
+				t.Error("expected directories to be listed first")
+			}
+
+			res = r.Test(httptest.NewRequest(http.MethodGet, prefix+"/", nil))
+			if !strings.Contains(res.BodyString(), `href="docs/"`) {
+				t.Errorf("expected root listing to link docs/, got:\n%s", res.BodyString())
+			}
+
+			res = r.Test(httptest.NewRequest(http.MethodGet, prefix+"/docs/alpha.txt", nil))
+			if res.Status() != http.StatusOK || res.BodyString() != strings.Repeat("a", 2048) {
+				t.Errorf("expected file to be served, got %d", res.Status())
+			}
+		})
+	}
+}
+
+func TestStaticDirListingTemplate(t *testing.T) {
+	root := setupListingDir(t)
+
+	tmpl := template.Must(template.New("listing.html").Parse(
+		`{{.path}}:{{range .entries}}{{.Name}}={{.Size}};{{end}}`))
+
+	r := rex.NewRouter(rex.WithTemplates(tmpl), rex.DirListTemplate("listing.html"))
+	r.Static("/files", root)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/files/docs/", nil))
+	want := "/files/docs/:zeta/=0;alpha.txt=2048;README.md=15;"
+	if res.BodyString() != want {
+		t.Errorf("expected %q, got %q", want, res.BodyString())
+	}
+}
+
+func TestDisableDirListing(t *testing.T) {
+	root := setupListingDir(t)
+
+	rex.DisableDirListing = true
+	defer func() { rex.DisableDirListing = false }()
+
+	r := rex.NewRouter()
+	r.Static("/files", root)
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/files/docs/", nil)); res.Status() != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", res.Status())
+	}
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/files/docs/alpha.txt", nil)); res.Status() != http.StatusOK {
+		t.Errorf("expected file to be served, got %d", res.Status())
+	}
+}
@@ -0,0 +1,203 @@
+package rex
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Route metadata keys set by Route.Input and Route.Output.
+const (
+	MetaInput  = "rex.input"
+	MetaOutput = "rex.output"
+)
+
+// Input declares the request body or query type of the route for the docs page.
+// Pass a value or a nil pointer of the type e.g route.Input(CreateUser{}).
+func (rt *Route) Input(v any) *Route {
+	return rt.Meta(MetaInput, reflect.TypeOf(v))
+}
+
+// Output declares the response type of the route for the docs page.
+func (rt *Route) Output(v any) *Route {
+	return rt.Meta(MetaOutput, reflect.TypeOf(v))
+}
+
+// DocsOption configures DocsHandler.
+type DocsOption func(*docsConfig)
+
+type docsConfig struct {
+	guard func(c *Context) bool
+}
+
+// DocsGuard only serves the docs page to requests for which allow returns true.
+// Other requests get 404 Not Found so the page is not advertised.
+//
+// Example:
+//
+//	r.DocsHandler("/_docs", rex.DocsGuard(func(c *rex.Context) bool {
+//		return c.Request.Header.Get("X-Internal") == "1"
+//	}))
+func DocsGuard(allow func(c *Context) bool) DocsOption {
+	return func(cfg *docsConfig) {
+		cfg.guard = allow
+	}
+}
+
+//go:embed docs.html
+var docsPage string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// DocsHandler registers a GET route at path serving an HTML page that lists the registered
+// routes grouped by their first path segment, with their handler names, metadata and the
+// fields of the types declared with Route.Input and Route.Output.
+// The page is built on each request, so routes registered later are included.
+// The docs route itself is not listed.
+func (r *Router) DocsHandler(path string, options ...DocsOption) *Route {
+	var cfg docsConfig
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	var self *Route
+	self = r.GET(path, func(c *Context) error {
+		if cfg.guard != nil && !cfg.guard(c) {
+			c.WriteHeader(http.StatusNotFound)
+			return c.String(http.StatusText(http.StatusNotFound))
+		}
+
+		var buf strings.Builder
+		if err := docsTemplate.Execute(&buf, r.docsData(self)); err != nil {
+			return err
+		}
+		c.SetHeader("Content-Type", "text/html; charset=utf-8")
+		return c.HTML(buf.String())
+	})
+	return self
+}
+
+type docsGroup struct {
+	Prefix string
+	Routes []docsRoute
+}
+
+type docsRoute struct {
+	Method  string
+	Pattern string
+	Handler string
+	Meta    []docsMeta
+	Input   *docsType
+	Output  *docsType
+}
+
+type docsMeta struct {
+	Key   string
+	Value string
+}
+
+type docsType struct {
+	Label  string
+	Name   string
+	Fields []docsField
+}
+
+type docsField struct {
+	Name, Type, JSON, Form, Validate string
+}
+
+type docsData struct {
+	Groups []docsGroup
+}
+
+// docsData collects the routes except self, sorted by prefix, pattern and method.
+func (r *Router) docsData(self *Route) docsData {
+	groups := make(map[string][]docsRoute)
+	for _, route := range r.routes {
+		if route == self {
+			continue
+		}
+
+		dr := docsRoute{
+			Method:  route.method,
+			Pattern: route.pattern,
+			Handler: route.handlerName(),
+		}
+
+		for key, value := range route.meta {
+			switch key {
+			case MetaInput:
+				dr.Input = newDocsType("Input", value)
+			case MetaOutput:
+				dr.Output = newDocsType("Output", value)
+			default:
+				dr.Meta = append(dr.Meta, docsMeta{Key: key, Value: fmt.Sprint(value)})
+			}
+		}
+		slices.SortFunc(dr.Meta, func(a, b docsMeta) int { return strings.Compare(a.Key, b.Key) })
+
+		prefix := docsPrefix(route.pattern)
+		groups[prefix] = append(groups[prefix], dr)
+	}
+
+	var data docsData
+	for prefix, routes := range groups {
+		slices.SortFunc(routes, func(a, b docsRoute) int {
+			if c := strings.Compare(a.Pattern, b.Pattern); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Method, b.Method)
+		})
+		data.Groups = append(data.Groups, docsGroup{Prefix: prefix, Routes: routes})
+	}
+	slices.SortFunc(data.Groups, func(a, b docsGroup) int { return strings.Compare(a.Prefix, b.Prefix) })
+	return data
+}
+
+// docsPrefix returns the first segment of the pattern e.g "/api" for "/api/users/{id}".
+func docsPrefix(pattern string) string {
+	// Patterns may start with a host.
+	if i := strings.Index(pattern, "/"); i > 0 {
+		pattern = pattern[i:]
+	}
+
+	segment, _, _ := strings.Cut(strings.TrimPrefix(pattern, "/"), "/")
+	return "/" + segment
+}
+
+// newDocsType describes the declared type t, listing the exported fields of structs.
+func newDocsType(label string, value any) *docsType {
+	t, ok := value.(reflect.Type)
+	if !ok || t == nil {
+		return nil
+	}
+
+	dt := &docsType{Label: label, Name: t.String()}
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	if t.Kind() != reflect.Struct {
+		return dt
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		dt.Fields = append(dt.Fields, docsField{
+			Name:     field.Name,
+			Type:     field.Type.String(),
+			JSON:     field.Tag.Get("json"),
+			Form:     field.Tag.Get("form"),
+			Validate: field.Tag.Get("validate"),
+		})
+	}
+	return dt
+}
@@ -0,0 +1,91 @@
+package rex_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+type createUser struct {
+	Email    string `json:"email" form:"email" validate:"required,email"`
+	Name     string `json:"name" form:"name" validate:"min=2"`
+	internal string
+}
+
+type userResponse struct {
+	ID int `json:"id"`
+}
+
+func listUsers(c *rex.Context) error { return nil }
+
+func TestDocsHandler(t *testing.T) {
+	r := rex.NewRouter()
+	r.DocsHandler("/_docs")
+
+	api := r.Group("/api")
+	api.GET("/users", listUsers).Meta("permission", "users.read")
+	api.POST("/users", func(c *rex.Context) error { return nil }).
+		Input(createUser{}).
+		Output(&userResponse{})
+	r.GET("/health", func(c *rex.Context) error { return nil })
+
+	res := r.Test(rex.NewTestRequest(http.MethodGet, "/_docs").Build())
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Code)
+	}
+
+	body := res.Body.String()
+	for _, want := range []string{
+		"/api/users",
+		"rex_test.listUsers",
+		"permission", "users.read",
+		"rex_test.createUser", "Email", "required,email", "min=2",
+		"*rex_test.userResponse",
+		"/health",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected the docs page to contain %q", want)
+		}
+	}
+
+	if strings.Contains(body, "internal") {
+		t.Error("expected unexported fields to be hidden")
+	}
+
+	if strings.Contains(body, "/_docs") {
+		t.Error("expected the docs route to be excluded from itself")
+	}
+
+	// Groups and routes are sorted.
+	if strings.Index(body, "<h2>/api</h2>") > strings.Index(body, "<h2>/health</h2>") {
+		t.Error("expected groups to be sorted by prefix")
+	}
+
+	if strings.Index(body, `GET</span> /api/users`) > strings.Index(body, `POST</span> /api/users`) {
+		t.Error("expected routes to be sorted by method")
+	}
+
+	again := r.Test(rex.NewTestRequest(http.MethodGet, "/_docs").Build())
+	if again.Body.String() != body {
+		t.Error("expected deterministic output")
+	}
+}
+
+func TestDocsGuard(t *testing.T) {
+	r := rex.NewRouter()
+	r.DocsHandler("/_docs", rex.DocsGuard(func(c *rex.Context) bool {
+		return c.Request.Header.Get("X-Internal") == "1"
+	}))
+
+	res := r.Test(rex.NewTestRequest(http.MethodGet, "/_docs").Build())
+	if res.Code != http.StatusNotFound {
+		t.Errorf("expected 404 without the header, got %d", res.Code)
+	}
+
+	res = r.Test(rex.NewTestRequest(http.MethodGet, "/_docs").Header("X-Internal", "1").Build())
+	if res.Code != http.StatusOK {
+		t.Errorf("expected 200 with the header, got %d", res.Code)
+	}
+}
@@ -1,6 +1,8 @@
 package rex
 
 import (
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strings"
@@ -8,47 +10,131 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
+// HandleValidationErrors responds with status 400. JSON clients receive the result of the
+// router's ValidationErrorFormatter. HTML clients get the error template with "validation_errors"
+// set to the same result if configured, or a list of the messages.
 func HandleValidationErrors(c *Context, errs validator.ValidationErrors) {
 	log.Println("handling validation errors")
 
 	accept := strings.Split(c.Request.Header.Get("Accept"), ";")[0]
-	c.WriteHeader(http.StatusBadRequest)
 
-	switch accept {
-	case "application/json":
-		c.JSON(errs.Translate(c.router.translator))
+	switch {
+	case accept == "application/json":
+		c.jsonStatus(http.StatusBadRequest, c.router.validationErrorFormatter(c, errs))
+	case c.router.errorTemplate != "" && c.router.template != nil:
+		c.SetHeader("Content-Type", "text/html")
+		c.Response.WriteHeader(http.StatusBadRequest)
+		c.renderTemplate(c.router.errorTemplate, Map{
+			"status":            http.StatusBadRequest,
+			"status_text":       http.StatusText(http.StatusBadRequest),
+			"error":             errs,
+			"validation_errors": c.router.validationErrorFormatter(c, errs),
+		})
 	default:
-		{
-			var htmlReply strings.Builder
-			htmlReply.WriteString(`<div class="rex_error">`)
-			for _, value := range errs.Translate(c.router.translator) {
-				htmlReply.WriteString(`<p class="rex_error_item">`)
-				htmlReply.WriteString(value)
-				htmlReply.WriteString("</p>")
-			}
-			htmlReply.WriteString("</div>")
-			c.HTML(htmlReply.String())
+		c.WriteHeader(http.StatusBadRequest)
+
+		var htmlReply strings.Builder
+		htmlReply.WriteString(`<div class="rex_error">`)
+		for _, item := range c.ValidationErrorItems(errs) {
+			htmlReply.WriteString(`<p class="rex_error_item">`)
+			htmlReply.WriteString(item.Message)
+			htmlReply.WriteString("</p>")
 		}
+		htmlReply.WriteString("</div>")
+		c.HTML(htmlReply.String())
 	}
 }
 
 func HandleFormErrors(c *Context, err FormError) {
 	log.Println("handling form errors")
 	accept := strings.Split(c.Request.Header.Get("Accept"), ";")[0]
-	c.WriteHeader(http.StatusBadRequest)
-
 	switch accept {
 	case "application/json":
-		c.JSON(err)
+		c.jsonStatus(formErrorStatus(err), err)
 	default:
 		{
+			c.WriteHeader(formErrorStatus(err))
+
 			var htmlReply strings.Builder
 			htmlReply.WriteString(`<div class="rex_error">`)
 			htmlReply.WriteString(`<p class="rex_error_item">`)
 			htmlReply.WriteString(err.Err.Error())
+			if err.Line > 0 {
+				fmt.Fprintf(&htmlReply, " (line %d, column %d)", err.Line, err.Column)
+			}
 			htmlReply.WriteString("</p>")
 			htmlReply.WriteString("</div>")
 			c.HTML(htmlReply.String())
 		}
 	}
 }
+
+// HandleParamErrors responds with 400 Bad Request for invalid query or form values.
+func HandleParamErrors(c *Context, err ParamError) {
+	accept := strings.Split(c.Request.Header.Get("Accept"), ";")[0]
+	switch accept {
+	case "application/json":
+		c.jsonStatus(http.StatusBadRequest, map[string]string{
+			"error":  err.Error(),
+			"source": err.Source,
+			"key":    err.Key,
+		})
+	default:
+		c.WriteHeader(http.StatusBadRequest)
+		c.String(err.Error())
+	}
+}
+
+// HandleJSONPatchErrors responds with 422 Unprocessable Entity for JSON patch operations
+// that can not be applied, with the index of the operation.
+func HandleJSONPatchErrors(c *Context, err JSONPatchError) {
+	accept := strings.Split(c.Request.Header.Get("Accept"), ";")[0]
+	switch accept {
+	case "application/json":
+		c.jsonStatus(http.StatusUnprocessableEntity, map[string]any{
+			"error": err.Error(),
+			"index": err.Index,
+			"op":    err.Op,
+			"path":  err.Path,
+		})
+	default:
+		c.WriteHeader(http.StatusUnprocessableEntity)
+		c.String(err.Error())
+	}
+}
+
+// HandleSignatureErrors responds with 410 Gone for expired signed URLs
+// and 403 Forbidden for missing or invalid signatures.
+func HandleSignatureErrors(c *Context, err error) {
+	status := http.StatusForbidden
+	if errors.Is(err, ErrSignatureExpired) {
+		status = http.StatusGone
+	}
+
+	accept := strings.Split(c.Request.Header.Get("Accept"), ";")[0]
+	switch accept {
+	case "application/json":
+		c.jsonStatus(status, map[string]string{"error": err.Error()})
+	default:
+		c.WriteHeader(status)
+		c.String(err.Error())
+	}
+}
+
+// formErrorStatus returns the http status code for the FormError.
+// Unsupported content types map to 415, bodies over the size limit to 413
+// and everything else to 400.
+func formErrorStatus(err FormError) int {
+	kind := err.Kind
+	if wrappedError, ok := err.Err.(FormError); ok {
+		kind = wrappedError.Kind
+	}
+
+	switch kind {
+	case InvalidContentType:
+		return http.StatusUnsupportedMediaType
+	case BodyTooLarge:
+		return http.StatusRequestEntityTooLarge
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,148 @@
+package rex
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ErrorStat is the number of error responses of a route, see ErrorStats.
+type ErrorStat struct {
+	Count     int64     // Responses with a status of 500 or above.
+	Requests  int64     // All requests served by the route.
+	LastError string    // Message of the last error.
+	LastAt    time.Time // Time of the last error.
+}
+
+// routeStats holds the counters of a route. It is allocated when the route is registered
+// and only updated atomically, so recording a request takes no lock.
+type routeStats struct {
+	requests atomic.Int64
+	errors   atomic.Int64
+	inFlight atomic.Int64
+	statuses [6]atomic.Int64 // responses by status class, index 2 for 2xx
+	last     atomic.Pointer[lastError]
+	windows  []atomic.Pointer[rateWindow] // current window of each OnErrorRateExceeded hook
+}
+
+type lastError struct {
+	message string
+	at      time.Time
+}
+
+// rateWindow counts the requests of a window of an OnErrorRateExceeded hook.
+// A new window replaces it as a whole, so that no request is counted into both.
+type rateWindow struct {
+	start    int64 // unix nanoseconds
+	requests atomic.Int64
+	errors   atomic.Int64
+	fired    atomic.Bool
+}
+
+type errorRateHook struct {
+	threshold   float64
+	window      time.Duration
+	minRequests int64
+	fn          func(pattern string, rate float64)
+}
+
+func newRouteStats(hooks int) *routeStats {
+	return &routeStats{windows: make([]atomic.Pointer[rateWindow], hooks)}
+}
+
+// OnErrorRateExceeded calls fn when the share of error responses (status 500 or above) of a route
+// within window reaches threshold, e.g 0.05 for 5%. The rate is only evaluated once the window
+// has minRequests requests, so that a single failure after a quiet period does not alert.
+// It is evaluated as requests complete and fn is called at most once per route and window,
+// on the goroutine of the request, so start a goroutine for slow work like paging.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.OnErrorRateExceeded(0.1, time.Minute, 20, func(pattern string, rate float64) {
+//		go alerts.Page(fmt.Sprintf("%s is failing: %.0f%% errors", pattern, rate*100))
+//	}))
+func OnErrorRateExceeded(threshold float64, window time.Duration, minRequests int64, fn func(pattern string, rate float64)) RouterOption {
+	return func(r *Router) {
+		r.errorRateHooks = append(r.errorRateHooks, errorRateHook{
+			threshold:   threshold,
+			window:      window,
+			minRequests: max(minRequests, 1),
+			fn:          fn,
+		})
+	}
+}
+
+// ErrorStats returns the error counters of the routes that had error responses,
+// keyed by the method and pattern of the route e.g "GET /users/{id}".
+func (r *Router) ErrorStats() map[string]ErrorStat {
+	stats := make(map[string]ErrorStat)
+	for prefix, route := range r.routes {
+		count := route.stats.errors.Load()
+		if count == 0 {
+			continue
+		}
+
+		stat := ErrorStat{Count: count, Requests: route.stats.requests.Load()}
+		if last := route.stats.last.Load(); last != nil {
+			stat.LastError, stat.LastAt = last.message, last.at
+		}
+		stats[prefix] = stat
+	}
+	return stats
+}
+
+// recordResult counts the request in the stats of the route once the error handler has
+// set the final status, and runs the OnErrorRateExceeded hooks.
+func (r *Router) recordResult(rt *Route, c *Context, err error) {
+	stats := rt.stats
+	stats.requests.Add(1)
+
+	status := c.rw.Status()
+	failed := status >= http.StatusInternalServerError
+	stats.statuses[min(max(status/100, 0), len(stats.statuses)-1)].Add(1)
+
+	if failed {
+		stats.errors.Add(1)
+
+		message := http.StatusText(status)
+		if err != nil {
+			message = err.Error()
+		}
+		stats.last.Store(&lastError{message: message, at: time.Now()})
+	}
+
+	for i, hook := range r.errorRateHooks {
+		w := stats.window(i, hook.window)
+
+		requests := w.requests.Add(1)
+		failures := w.errors.Load()
+		if failed {
+			failures = w.errors.Add(1)
+		}
+
+		if requests < hook.minRequests || failures == 0 {
+			continue
+		}
+
+		rate := float64(failures) / float64(requests)
+		if rate >= hook.threshold && w.fired.CompareAndSwap(false, true) {
+			hook.fn(rt.prefix, rate)
+		}
+	}
+}
+
+// window returns the current window of hook i, replacing the window once it is older than d.
+func (s *routeStats) window(i int, d time.Duration) *rateWindow {
+	now := time.Now().UnixNano()
+	for {
+		w := s.windows[i].Load()
+		if w != nil && now-w.start < int64(d) {
+			return w
+		}
+
+		next := &rateWindow{start: now}
+		if s.windows[i].CompareAndSwap(w, next) {
+			return next
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestErrorStats(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/users/{id}", func(c *rex.Context) error {
+		if c.Param("id") == "0" {
+			return errors.New("database is down")
+		}
+
+		if c.Param("id") == "404" {
+			return rex.NewError(http.StatusNotFound, "no such user")
+		}
+		return c.String("ok")
+	})
+
+	r.GET("/healthy", func(c *rex.Context) error {
+		return c.String("ok")
+	})
+
+	for _, path := range []string{"/users/1", "/users/0", "/users/404", "/users/0", "/healthy"} {
+		r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	stats := r.ErrorStats()
+	if len(stats) != 1 {
+		t.Fatalf("expected stats for the failing route only, got %v", stats)
+	}
+
+	stat := stats["GET /users/{id}"]
+	if stat.Count != 2 || stat.Requests != 4 {
+		t.Errorf("expected 2 errors of 4 requests, got %+v", stat)
+	}
+
+	if stat.LastError != "database is down" || time.Since(stat.LastAt) > time.Minute {
+		t.Errorf("expected the last error, got %+v", stat)
+	}
+}
+
+func TestOnErrorRateExceeded(t *testing.T) {
+	type alert struct {
+		pattern string
+		rate    float64
+	}
+
+	var mu sync.Mutex
+	var alerts []alert
+
+	window := 100 * time.Millisecond
+	r := rex.NewRouter(rex.OnErrorRateExceeded(0.5, window, 4, func(pattern string, rate float64) {
+		mu.Lock()
+		defer mu.Unlock()
+		alerts = append(alerts, alert{pattern, rate})
+	}))
+
+	r.GET("/flaky", func(c *rex.Context) error {
+		if c.Query("fail") != "" {
+			return errors.New("timeout")
+		}
+		return nil
+	})
+
+	request := func(path string) {
+		r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	count := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(alerts)
+	}
+
+	request("/flaky")
+	request("/flaky")
+	request("/flaky?fail=1") // 1 of 3
+	if count() != 0 {
+		t.Fatalf("expected no alert below the threshold, got %v", alerts)
+	}
+
+	for i := 0; i < 5; i++ {
+		request("/flaky?fail=1")
+	}
+
+	if count() != 1 {
+		t.Fatalf("expected one alert per window, got %v", alerts)
+	}
+
+	if alerts[0].pattern != "GET /flaky" || alerts[0].rate != 0.5 {
+		t.Errorf("expected the alert at 2 of 4 requests, got %+v", alerts[0])
+	}
+
+	time.Sleep(window)
+	request("/flaky?fail=1")
+	if count() != 1 {
+		t.Fatalf("expected no alert below the minimum number of requests, got %v", alerts)
+	}
+
+	for i := 0; i < 3; i++ {
+		request("/flaky?fail=1")
+	}
+
+	if count() != 2 {
+		t.Errorf("expected another alert in the next window, got %v", alerts)
+	}
+}
+
+func TestOnErrorRateExceededConcurrent(t *testing.T) {
+	var alerts atomic.Int64
+	r := rex.NewRouter(rex.OnErrorRateExceeded(0.5, time.Hour, 10, func(pattern string, rate float64) {
+		alerts.Add(1)
+	}))
+
+	r.GET("/down", func(c *rex.Context) error {
+		return errors.New("database is down")
+	})
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				r.Test(httptest.NewRequest(http.MethodGet, "/down", nil))
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := alerts.Load(); n != 1 {
+		t.Errorf("expected a single alert in the window, got %d", n)
+	}
+}
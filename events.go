@@ -0,0 +1,182 @@
+package rex
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+var (
+	// EventWorkers is the number of goroutines delivering events emitted with c.Emit.
+	EventWorkers = 4
+
+	// EventQueueSize is the number of deliveries buffered for the workers.
+	// Emit blocks when the queue is full.
+	EventQueueSize = 1024
+)
+
+// EventHandler handles the payload of an event. ctx carries the Snapshot of the emitting
+// request, see SnapshotFrom.
+type EventHandler func(ctx context.Context, payload any)
+
+// EventBus dispatches events emitted by handlers to the subscribers of their topic,
+// for in-process decoupling like sending an email after "user.registered".
+// Events are not persisted, they are lost if the process exits before delivery.
+type EventBus struct {
+	router *Router
+
+	mu     sync.RWMutex
+	subs   map[string][]EventHandler
+	closed bool
+
+	start   sync.Once
+	queue   chan delivery
+	pending sync.WaitGroup // deliveries emitted and not yet handled
+}
+
+// delivery is an event queued for one subscriber.
+type delivery struct {
+	ctx     context.Context
+	topic   string
+	payload any
+	handler EventHandler
+}
+
+func newEventBus(r *Router) *EventBus {
+	return &EventBus{router: r, subs: make(map[string][]EventHandler)}
+}
+
+// Events returns the event bus of the router.
+//
+// Example:
+//
+//	r.Events().Subscribe("user.registered", func(ctx context.Context, payload any) {
+//		mailer.SendWelcome(payload.(User))
+//	})
+//
+//	r.POST("/register", func(c *rex.Context) error {
+//		// ...
+//		c.Emit("user.registered", user)
+//		return c.Redirect("/welcome")
+//	})
+func (r *Router) Events() *EventBus {
+	return r.events
+}
+
+// Subscribe registers fn for the events of topic. Subscribe at startup, before events are emitted.
+// Subscribers run on the worker pool for c.Emit and on the request goroutine for c.EmitSync.
+// Panics in fn are recovered and logged.
+func (b *EventBus) Subscribe(topic string, fn EventHandler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[topic] = append(b.subs[topic], fn)
+}
+
+// subscribers returns the subscribers of topic.
+func (b *EventBus) subscribers(topic string) []EventHandler {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.subs[topic]
+}
+
+// emit queues the event for every subscriber of topic.
+func (b *EventBus) emit(ctx context.Context, topic string, payload any) {
+	handlers := b.subscribers(topic)
+	if len(handlers) == 0 {
+		b.router.logger.Debug("event has no subscribers", "topic", topic)
+		return
+	}
+
+	b.start.Do(b.startWorkers)
+
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		b.router.logger.Warn("event emitted after the event bus was closed, dropping it", "topic", topic)
+		return
+	}
+	b.pending.Add(len(handlers))
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		b.queue <- delivery{ctx: ctx, topic: topic, payload: payload, handler: handler}
+	}
+}
+
+func (b *EventBus) startWorkers() {
+	b.queue = make(chan delivery, EventQueueSize)
+	for i := 0; i < max(EventWorkers, 1); i++ {
+		go func() {
+			for d := range b.queue {
+				b.deliver(d)
+				b.pending.Done()
+			}
+		}()
+	}
+}
+
+// deliver calls the subscriber, recovering and logging a panic.
+func (b *EventBus) deliver(d delivery) {
+	defer func() {
+		if err := recover(); err != nil {
+			snap, _ := SnapshotFrom(d.ctx)
+			b.router.logger.Error("panic in event subscriber",
+				"error", fmt.Sprint(err),
+				"topic", d.topic,
+				"request_id", snap.RequestID,
+				"stack", string(debug.Stack()))
+		}
+	}()
+	d.handler(d.ctx, d.payload)
+}
+
+// Close stops accepting events and waits until the emitted events are delivered or ctx is done.
+// Server.ShutdownContext closes the event bus of its router after the requests completed.
+func (b *EventBus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Emit dispatches the event to the subscribers of topic asynchronously and returns immediately.
+// Subscribers get a context detached from the request that carries a Snapshot of it,
+// see c.Go. Emitting to a topic without subscribers only logs at debug level.
+func (c *Context) Emit(topic string, payload any) {
+	c.checkReleased()
+
+	ctx, _ := c.snapshot(context.Background())
+	c.router.events.emit(ctx, topic, payload)
+}
+
+// EmitSync calls the subscribers of topic in order and returns when they are done,
+// for work that must complete before responding. Subscribers get the request context
+// with a Snapshot of the request.
+func (c *Context) EmitSync(topic string, payload any) {
+	c.checkReleased()
+
+	bus := c.router.events
+	handlers := bus.subscribers(topic)
+	if len(handlers) == 0 {
+		c.router.logger.Debug("event has no subscribers", "topic", topic)
+		return
+	}
+
+	ctx, _ := c.snapshot(c.Request.Context())
+	for _, handler := range handlers {
+		bus.deliver(delivery{ctx: ctx, topic: topic, payload: payload, handler: handler})
+	}
+}
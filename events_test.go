@@ -0,0 +1,149 @@
+package rex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEmitReachesSubscribers(t *testing.T) {
+	r := NewRouter()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	var mu sync.Mutex
+	got := map[string]any{}
+	record := func(name string) EventHandler {
+		return func(ctx context.Context, payload any) {
+			defer wg.Done()
+			snap, _ := SnapshotFrom(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			got[name] = payload
+			got[name+".request_id"] = snap.RequestID
+		}
+	}
+
+	r.Events().Subscribe("user.registered", record("email"))
+	r.Events().Subscribe("user.registered", record("cache"))
+
+	r.POST("/register", func(c *Context) error {
+		c.Emit("user.registered", "ann@example.com")
+		c.Emit("user.deleted", "nobody listens")
+		return c.String("ok")
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/register", nil)
+	req.Header.Set("X-Request-ID", "req-9")
+	r.Test(req)
+
+	if err := r.Events().Close(timeoutContext(t)); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+
+	for _, name := range []string{"email", "cache"} {
+		if got[name] != "ann@example.com" || got[name+".request_id"] != "req-9" {
+			t.Errorf("%s: expected the payload and request ID, got %v", name, got)
+		}
+	}
+}
+
+func TestSubscriberPanicIsIsolated(t *testing.T) {
+	r := NewRouter()
+
+	var delivered atomic.Int64
+	r.Events().Subscribe("order.placed", func(ctx context.Context, payload any) {
+		panic("mailer is down")
+	})
+	r.Events().Subscribe("order.placed", func(ctx context.Context, payload any) {
+		delivered.Add(1)
+	})
+
+	r.POST("/orders", func(c *Context) error {
+		c.Emit("order.placed", 1)
+		c.EmitSync("order.placed", 2)
+		return c.String("placed")
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodPost, "/orders", nil))
+	if res.Code != http.StatusOK || res.BodyString() != "placed" {
+		t.Errorf("expected the request to succeed, got %d %q", res.Code, res.BodyString())
+	}
+
+	if err := r.Events().Close(timeoutContext(t)); err != nil {
+		t.Fatal(err)
+	}
+
+	if delivered.Load() != 2 {
+		t.Errorf("expected the other subscriber to get both events, got %d", delivered.Load())
+	}
+}
+
+func TestEmitSyncCompletesBeforeResponse(t *testing.T) {
+	r := NewRouter()
+
+	var invalidated atomic.Bool
+	r.Events().Subscribe("post.updated", func(ctx context.Context, payload any) {
+		if ctx.Err() != nil {
+			t.Error("expected the request context to be active")
+		}
+		invalidated.Store(true)
+	})
+
+	r.PUT("/posts/1", func(c *Context) error {
+		c.EmitSync("post.updated", 1)
+		if !invalidated.Load() {
+			t.Error("expected EmitSync to run the subscriber before returning")
+		}
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodPut, "/posts/1", nil))
+}
+
+func TestShutdownWaitsForEvents(t *testing.T) {
+	r := NewRouter()
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	r.Events().Subscribe("report.requested", func(ctx context.Context, payload any) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+	})
+
+	r.POST("/reports", func(c *Context) error {
+		c.Emit("report.requested", nil)
+		return c.String("queued")
+	})
+
+	srv := NewServer("", r)
+	url := startServer(t, srv)
+
+	res, err := http.Post(url+"/reports", "text/plain", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	<-started
+
+	if err := srv.ShutdownContext(timeoutContext(t)); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	if !finished.Load() {
+		t.Error("expected shutdown to wait for the in-flight delivery")
+	}
+}
+
+func timeoutContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
@@ -0,0 +1,221 @@
+package rex
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// ErrUnknownField is wrapped by ParamError for fields requested with JSONFields and StrictFields
+// that the response does not have.
+var ErrUnknownField = errors.New("unknown field")
+
+// FieldsOption configures JSONFields.
+type FieldsOption func(*fieldsConfig)
+
+type fieldsConfig struct {
+	strict bool
+}
+
+// StrictFields makes JSONFields fail with a ParamError wrapping ErrUnknownField for fields
+// the response does not have, which the default error handler answers with 400 Bad Request.
+// By default unknown fields are ignored.
+func StrictFields() FieldsOption {
+	return func(cfg *fieldsConfig) {
+		cfg.strict = true
+	}
+}
+
+// RequestedFields parses the comma-separated "fields" query parameter e.g ?fields=id,name,author.name.
+// The parameter name can be changed with param. Repeated fields are ignored.
+// It returns nil, meaning all fields, if the parameter is absent or empty.
+func (c *Context) RequestedFields(param ...string) []string {
+	key := "fields"
+	if len(param) > 0 {
+		key = param[0]
+	}
+
+	var fields []string
+	seen := make(map[string]bool)
+	for _, field := range strings.Split(c.Query(key), ",") {
+		field = strings.TrimSpace(field)
+		if field != "" && !seen[field] {
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+// JSONFields sends v as JSON like c.JSON with only the requested fields, matched against
+// the JSON names of v. v may be a struct, a map or a slice of them, whose elements are all pruned.
+// Dotted fields select nested fields e.g "author.name". The order of the fields is kept.
+// All fields are sent if fields is empty.
+//
+// Example:
+//
+//	// GET /posts?fields=id,title,author.name
+//	return c.JSONFields(posts, c.RequestedFields())
+func (c *Context) JSONFields(v any, fields []string, opts ...FieldsOption) error {
+	if len(fields) == 0 {
+		return c.JSON(v)
+	}
+
+	var cfg fieldsConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	p := fieldPruner{visited: make(map[string]bool), matched: make(map[string]bool)}
+	data, err = p.prune(data, newFieldTree(fields), "")
+	if err != nil {
+		return err
+	}
+
+	if cfg.strict {
+		for _, field := range fields {
+			if p.unknown(field) {
+				return ParamError{Source: "query", Key: "fields", Value: field, Err: ErrUnknownField}
+			}
+		}
+	}
+
+	return c.writeJSON(0, append(data, '\n'))
+}
+
+// fieldTree holds the requested fields by name. A nil subtree selects the whole value.
+type fieldTree map[string]fieldTree
+
+func newFieldTree(fields []string) fieldTree {
+	tree := fieldTree{}
+	for _, field := range fields {
+		node := tree
+		parts := strings.Split(field, ".")
+		for i, part := range parts {
+			sub, exists := node[part]
+			if exists && sub == nil {
+				break // The whole value is already selected.
+			}
+
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+
+			if sub == nil {
+				sub = fieldTree{}
+				node[part] = sub
+			}
+			node = sub
+		}
+	}
+	return tree
+}
+
+// fieldPruner removes the fields that were not requested from JSON values.
+type fieldPruner struct {
+	visited map[string]bool // paths of the objects pruned, "" for the top level
+	matched map[string]bool // paths of the requested fields found
+}
+
+// unknown reports whether field was not found in any pruned object that could have it.
+func (p *fieldPruner) unknown(field string) bool {
+	parent := ""
+	if i := strings.LastIndexByte(field, '.'); i >= 0 {
+		parent = field[:i]
+	}
+	return p.visited[parent] && !p.matched[field]
+}
+
+// prune returns data with only the fields of tree. path is the dotted path of data.
+func (p *fieldPruner) prune(data json.RawMessage, tree fieldTree, path string) (json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return data, nil
+	}
+
+	switch data[0] {
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range items {
+			pruned, err := p.prune(item, tree, path)
+			if err != nil {
+				return nil, err
+			}
+
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.Write(pruned)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	case '{':
+		return p.pruneObject(data, tree, path)
+	}
+	return data, nil
+}
+
+// pruneObject prunes a JSON object, keeping the order of its keys.
+func (p *fieldPruner) pruneObject(data json.RawMessage, tree fieldTree, path string) (json.RawMessage, error) {
+	p.visited[path] = true
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key := token.(string)
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+
+		sub, ok := tree[key]
+		if !ok {
+			continue
+		}
+
+		fieldPath := key
+		if path != "" {
+			fieldPath = path + "." + key
+		}
+		p.matched[fieldPath] = true
+
+		if sub != nil {
+			if value, err = p.prune(value, sub, fieldPath); err != nil {
+				return nil, err
+			}
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
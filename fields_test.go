@@ -0,0 +1,141 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+type fieldsAuthor struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+type fieldsPost struct {
+	ID        int          `json:"id"`
+	Title     string       `json:"title"`
+	Body      string       `json:"body"`
+	Author    fieldsAuthor `json:"author"`
+	CreatedAt string       `json:"created_at"`
+}
+
+var fieldsPosts = []fieldsPost{
+	{ID: 1, Title: "First", Body: "...", Author: fieldsAuthor{ID: 7, Name: "Ann"}, CreatedAt: "2024-01-01"},
+	{ID: 2, Title: "Second", Body: "...", Author: fieldsAuthor{ID: 8, Name: "Bob"}, CreatedAt: "2024-01-02"},
+}
+
+func newFieldsRouter(opts ...rex.FieldsOption) *rex.Router {
+	r := rex.NewRouter()
+	r.GET("/posts", func(c *rex.Context) error {
+		return c.JSONFields(fieldsPosts, c.RequestedFields(), opts...)
+	})
+
+	r.GET("/posts/first", func(c *rex.Context) error {
+		return c.JSONFields(fieldsPosts[0], c.RequestedFields("only"), opts...)
+	})
+
+	r.GET("/stats", func(c *rex.Context) error {
+		return c.JSONFields(rex.Map{"users": 3, "posts": 2}, c.RequestedFields(), opts...)
+	})
+	return r
+}
+
+func TestJSONFieldsSlice(t *testing.T) {
+	res := newFieldsRouter().Test(httptest.NewRequest(http.MethodGet, "/posts?fields=title,id,created_at", nil))
+
+	want := `[{"id":1,"title":"First","created_at":"2024-01-01"},{"id":2,"title":"Second","created_at":"2024-01-02"}]`
+	if got := strings.TrimSpace(res.BodyString()); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+
+	if res.Header("Content-Type") != "application/json" {
+		t.Errorf("expected a JSON response, got %q", res.Header("Content-Type"))
+	}
+}
+
+func TestJSONFieldsNestedAndMaps(t *testing.T) {
+	r := newFieldsRouter()
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/posts/first?only=id,author.name", nil))
+	if got := strings.TrimSpace(res.BodyString()); got != `{"id":1,"author":{"name":"Ann"}}` {
+		t.Errorf("expected the nested field, got %s", got)
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/stats?fields=users", nil))
+	if got := strings.TrimSpace(res.BodyString()); got != `{"users":3}` {
+		t.Errorf("expected the map to be filtered, got %s", got)
+	}
+}
+
+func TestJSONFieldsEmptyReturnsAll(t *testing.T) {
+	for _, path := range []string{"/posts", "/posts?fields=", "/posts?fields=,"} {
+		res := newFieldsRouter().Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if !strings.Contains(res.BodyString(), `"body":"..."`) || !strings.Contains(res.BodyString(), `"author":{"id":7`) {
+			t.Errorf("%s: expected all fields, got %s", path, res.BodyString())
+		}
+	}
+}
+
+func TestJSONFieldsUnknown(t *testing.T) {
+	path := "/posts?fields=id,secret"
+
+	res := newFieldsRouter().Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if got := strings.TrimSpace(res.BodyString()); res.Code != http.StatusOK || got != `[{"id":1},{"id":2}]` {
+		t.Errorf("expected unknown fields to be ignored, got %d %s", res.Code, got)
+	}
+
+	res = newFieldsRouter(rex.StrictFields()).Test(httptest.NewRequest(http.MethodGet, path, nil))
+	if res.Code != http.StatusBadRequest || !strings.Contains(res.BodyString(), "secret") {
+		t.Errorf("expected 400 for the unknown field, got %d %s", res.Code, res.BodyString())
+	}
+
+	res = newFieldsRouter(rex.StrictFields()).Test(httptest.NewRequest(http.MethodGet, "/posts?fields=id,author.email", nil))
+	if res.Code != http.StatusBadRequest || !strings.Contains(res.BodyString(), "author.email") {
+		t.Errorf("expected 400 for the unknown nested field, got %d %s", res.Code, res.BodyString())
+	}
+
+	res = newFieldsRouter(rex.StrictFields()).Test(httptest.NewRequest(http.MethodGet, "/posts?fields=id,author.name", nil))
+	if res.Code != http.StatusOK {
+		t.Errorf("expected known fields to pass in strict mode, got %d %s", res.Code, res.BodyString())
+	}
+}
+
+func TestRequestedFields(t *testing.T) {
+	r := rex.NewRouter()
+
+	var fields []string
+	r.GET("/", func(c *rex.Context) error {
+		fields = c.RequestedFields()
+		return nil
+	})
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/?fields=+id,+name,id,", nil))
+	if strings.Join(fields, ",") != "id,name" {
+		t.Errorf("expected [id name], got %q", fields)
+	}
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if fields != nil {
+		t.Errorf("expected nil without the parameter, got %q", fields)
+	}
+}
+
+func TestStrictFieldsError(t *testing.T) {
+	r := rex.NewRouter()
+
+	var err error
+	r.GET("/", func(c *rex.Context) error {
+		err = c.JSONFields(fieldsPosts[0], []string{"nope"}, rex.StrictFields())
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var paramErr rex.ParamError
+	if !errors.As(err, &paramErr) || !errors.Is(err, rex.ErrUnknownField) || paramErr.Value != "nope" {
+		t.Errorf("expected a ParamError wrapping ErrUnknownField, got %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package rex
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+)
+
+// OnFinished registers fn to run after the request is handled: once the latency is recorded
+// and the error handler has written the response, so c.Status(), c.Latency() and the
+// response size are final. Callbacks run in reverse order of registration,
+// like deferred calls. Panics in fn are recovered and logged.
+// The context is released after the callbacks return, do not retain it.
+//
+// Example:
+//
+//	func Audit(next rex.HandlerFunc) rex.HandlerFunc {
+//		return func(c *rex.Context) error {
+//			c.OnFinished(func(c *rex.Context) {
+//				log.Println(c.Pattern(), c.Status(), c.Latency())
+//			})
+//			return next(c)
+//		}
+//	}
+func (c *Context) OnFinished(fn func(c *Context)) {
+	c.checkReleased()
+	c.onFinished = append(c.onFinished, fn)
+}
+
+// Elapsed returns the time since the router started dispatching the request.
+// Unlike Latency, it can be called while the request is handled, e.g to skip
+// optional work when a deadline is close.
+func (c *Context) Elapsed() time.Duration {
+	return time.Since(c.startTime)
+}
+
+// runFinished calls the OnFinished callbacks in reverse order.
+func (c *Context) runFinished() {
+	for i := len(c.onFinished) - 1; i >= 0; i-- {
+		c.runFinishedCallback(c.onFinished[i])
+	}
+	c.onFinished = nil
+}
+
+// runFinishedCallback calls fn, recovering and logging a panic.
+func (c *Context) runFinishedCallback(fn func(c *Context)) {
+	defer func() {
+		if err := recover(); err != nil {
+			c.router.logger.Error("panic in OnFinished callback",
+				"error", fmt.Sprint(err),
+				"path", c.Request.URL.Path,
+				"stack", string(debug.Stack()))
+		}
+	}()
+	fn(c)
+}
@@ -0,0 +1,91 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestOnFinishedSeesFinalStatus(t *testing.T) {
+	r := rex.NewRouter()
+
+	var status int
+	var latency time.Duration
+	audit := func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			c.OnFinished(func(c *rex.Context) {
+				status = c.Status()
+				latency = c.Latency()
+			})
+			return next(c)
+		}
+	}
+
+	r.GET("/boom", func(c *rex.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("database is down")
+	}, audit)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/boom", nil))
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", res.Code)
+	}
+
+	if status != http.StatusInternalServerError {
+		t.Errorf("expected the status set by the error handler, got %d", status)
+	}
+
+	if latency < 5*time.Millisecond {
+		t.Errorf("expected the recorded latency, got %s", latency)
+	}
+}
+
+func TestOnFinishedOrder(t *testing.T) {
+	r := rex.NewRouter()
+
+	var order []string
+	register := func(name string) rex.Middleware {
+		return func(next rex.HandlerFunc) rex.HandlerFunc {
+			return func(c *rex.Context) error {
+				c.OnFinished(func(c *rex.Context) { order = append(order, name) })
+				return next(c)
+			}
+		}
+	}
+
+	r.GET("/", func(c *rex.Context) error {
+		c.OnFinished(func(c *rex.Context) { panic("broken callback") })
+		c.OnFinished(func(c *rex.Context) { order = append(order, "handler") })
+		return c.String("ok")
+	}, register("outer"), register("inner"))
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.BodyString() != "ok" {
+		t.Errorf("expected the response, got %q", res.BodyString())
+	}
+
+	if got := strings.Join(order, ","); got != "handler,inner,outer" {
+		t.Errorf("expected LIFO order despite the panic, got %s", got)
+	}
+}
+
+func TestElapsed(t *testing.T) {
+	r := rex.NewRouter()
+
+	r.GET("/", func(c *rex.Context) error {
+		first := c.Elapsed()
+		time.Sleep(2 * time.Millisecond)
+		second := c.Elapsed()
+
+		if first <= 0 || second-first < 2*time.Millisecond {
+			t.Errorf("expected Elapsed to grow, got %s then %s", first, second)
+		}
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+}
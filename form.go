@@ -1,10 +1,14 @@
 package rex
 
 import (
+	"bytes"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"io"
 	"mime/multipart"
+	"net/http"
+	"net/url"
 	"reflect"
 	"slices"
 	"strconv"
@@ -23,6 +27,8 @@ const (
 	ContentTypeCSV           string = "text/csv"
 	ContentTypeText          string = "text/plain"
 	ContentTypeEventStream   string = "text/event-stream"
+	ContentTypeMergePatch    string = "application/merge-patch+json" // see Context.ApplyMergePatch
+	ContentTypeJSONPatch     string = "application/json-patch+json"  // see Context.ApplyJSONPatch
 )
 
 // FormError represents an error encountered during body parsing.
@@ -34,7 +40,20 @@ type FormError struct {
 	Kind FormErrorKind `json:"kind,omitempty"`
 
 	// Struct field name causing error.
+	// For JSON type mismatches this is the dotted JSON field path e.g "address.zip".
 	Field string `json:"field,omitempty"`
+
+	// Byte offset in the body where a JSON syntax or type error occurred.
+	Offset int64 `json:"offset,omitempty"`
+
+	// 1-based line and column of Offset in the body.
+	// They are left zero for errors past the first jsonErrorContext bytes.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+
+	// Expected and actual types for JSON type mismatches e.g "int" and "string".
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
 }
 
 // FormErrorKind represents the kind of error encountered during body parsing.
@@ -55,6 +74,13 @@ const (
 
 	// ParseError indicates that an error occurred during parsing.
 	ParseError FormErrorKind = "parse_error"
+
+	// BodyTooLarge indicates that the body exceeded the limit set with http.MaxBytesReader.
+	BodyTooLarge FormErrorKind = "body_too_large"
+
+	// DecompressionFailed indicates that a compressed body could not be decompressed
+	// or expanded past its limit. See DecompressBody.
+	DecompressionFailed FormErrorKind = "decompression_failed"
 )
 
 // Error implements the error interface.
@@ -68,117 +94,154 @@ func (e FormError) Error() string {
 // MarshalJSON marshals the error to JSON.
 func (e FormError) MarshalJSON() ([]byte, error) {
 	if wrappedError, ok := e.Err.(FormError); ok {
-		return json.Marshal(map[string]interface{}{
-			"err":   wrappedError.Err.Error(),
-			"kind":  wrappedError.Kind,
-			"field": wrappedError.Field,
-		})
+		return wrappedError.MarshalJSON()
 	}
 
-	return json.Marshal(map[string]interface{}{
+	m := map[string]interface{}{
 		"err":   e.Err.Error(),
 		"kind":  e.Kind,
 		"field": e.Field,
-	})
+	}
+
+	if e.Line > 0 {
+		m["offset"] = e.Offset
+		m["line"] = e.Line
+		m["column"] = e.Column
+	}
+
+	if e.Expected != "" {
+		m["expected"] = e.Expected
+		m["actual"] = e.Actual
+	}
+	return json.Marshal(m)
+}
+
+// jsonErrorContext is the number of leading body bytes kept to locate JSON errors.
+const jsonErrorContext = 1 << 20
+
+// prefixBuffer keeps the first limit bytes written to it and discards the rest.
+type prefixBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *prefixBuffer) Write(p []byte) (int, error) {
+	if n := b.limit - b.Len(); n > 0 {
+		b.Buffer.Write(p[:min(n, len(p))])
+	}
+	return len(p), nil
+}
+
+// jsonFormError converts a json decoding error into a FormError.
+// consumed holds the leading body bytes read by the decoder and is used to compute
+// the line and column of the error, if it occurred within them.
+func jsonFormError(err error, consumed []byte) FormError {
+	fe := FormError{Err: err, Kind: ParseError}
+
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		fe.Kind = BodyTooLarge
+	case errors.Is(err, ErrDecompressedTooLarge):
+		fe.Kind = DecompressionFailed
+	case errors.As(err, &syntaxErr):
+		fe.Offset = syntaxErr.Offset
+		fe.Line, fe.Column = lineColumn(consumed, syntaxErr.Offset)
+	case errors.As(err, &typeErr):
+		fe.Field = typeErr.Field
+		fe.Offset = typeErr.Offset
+		fe.Expected = typeErr.Type.String()
+		fe.Actual = typeErr.Value
+		fe.Line, fe.Column = lineColumn(consumed, typeErr.Offset)
+	}
+	return fe
+}
+
+// lineColumn returns the 1-based line and column of the last byte read
+// by the json decoder when it failed after reading offset bytes of data.
+// It returns zeros if offset is past the end of data.
+func lineColumn(data []byte, offset int64) (line, column int) {
+	if offset > int64(len(data)) {
+		return 0, 0
+	}
+	offset = max(offset-1, 0)
+
+	line, column = 1, 1
+	for _, b := range data[:offset] {
+		if b == '\n' {
+			line++
+			column = 1
+		} else {
+			column++
+		}
+	}
+	return line, column
 }
 
 var DefaultTimezone = time.UTC
 
 // BodyParser parses the request body and stores the result in v.
-// v must be a pointer to a struct.
+// For JSON and XML, v may be a pointer to any decodable value, e.g a struct, a slice of items
+// or a string. Structs are validated, and so are slices, arrays and maps of structs element-wise.
+// For form content types, v must be a pointer to a struct.
 // If timezone is provided, all date and time fields in forms are parsed with the provided location info.
-// Otherwise rex.DefaultTimezone is used and defaults to UTC.
+// Otherwise the timezone set with c.SetTimezone is used, falling back to rex.DefaultTimezone (UTC by default).
 //
 // Supported content types: application/json, application/x-www-form-urlencoded, multipart/form-data, application/xml
-// If you need to parse a different content type, you can implement a custom parser or use a third-party package like
-// https://github.com/gorilla/schema package.
+// and the content types of codecs registered with RegisterCodec, e.g YAML with the codec/yaml package.
 // Any form value can implement the FormScanner interface to implement custom form scanning.
+//
+// Form and query values follow a tri-state contract, which lets PATCH handlers use
+// pointer fields to tell omitted fields from cleared ones (see also ChangedFields):
+//   - an absent key leaves the field untouched, pointers stay nil.
+//   - a key with an empty value sets pointer fields to nil and other fields to their zero value.
+//   - a non-empty value is parsed into the field, allocating pointers as needed.
+//
 // Struct tags are used to specify the form field name.
 // If parsing forms, the default tag name is "form",
 // followed by the "json" tag name, and then snake case of the field name.
 func (c *Context) BodyParser(v interface{}, loc ...*time.Location) error {
 	r := c.Request
-	// Make sure v is a pointer to a struct
+	// Make sure v is a pointer, the content type decides what it may point to.
 	rv := reflect.ValueOf(v)
-	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
 		return FormError{
-			Err:   fmt.Errorf("v must be a pointer to a struct"),
+			Err:   fmt.Errorf("v must be a non-nil pointer"),
 			Kind:  InvalidStructPointer,
 			Field: "",
 		}
 	}
 
+	limits := DefaultDecompressLimits
+	if c.router != nil {
+		limits = c.router.decompressLimits
+	}
+
+	// Transparently decompress gzip and deflate encoded bodies.
+	if err := DecompressBody(r, limits); err != nil {
+		return err
+	}
+
 	contentType := c.ContentType()
-	timezone := DefaultTimezone
+	timezone := c.Timezone()
 	if len(loc) > 0 && loc[0] != nil {
 		timezone = loc[0]
 	}
 
 	if contentType == ContentTypeJSON {
-		decoder := json.NewDecoder(r.Body)
+		// Keep the leading bytes read by the decoder to locate syntax errors.
+		consumed := prefixBuffer{limit: jsonErrorContext}
+		decoder := json.NewDecoder(io.TeeReader(r.Body, &consumed))
 		err := decoder.Decode(v)
 		if err != nil {
-			return FormError{
-				Err:  err,
-				Kind: ParseError,
-			}
-		}
-
-		// validate the struct here
-		if c.router != nil && c.router.validator != nil {
-			err := c.router.validator.Struct(v)
-			return err
+			return jsonFormError(err, consumed.Bytes())
 		}
-		return nil
+		return c.validateBody(v)
 	} else if contentType == ContentTypeUrlEncoded || contentType == ContentTypeMultipartForm {
-		var form *multipart.Form
-		var err error
-		if contentType == ContentTypeMultipartForm {
-			err = r.ParseMultipartForm(r.ContentLength)
-			if err != nil {
-				return FormError{
-					Err:  err,
-					Kind: ParseError,
-				}
-			}
-			form = r.MultipartForm
-		} else {
-			err = r.ParseForm()
-			if err != nil {
-				return FormError{
-					Err:  err,
-					Kind: ParseError,
-				}
-			}
-			form = &multipart.Form{
-				Value: r.Form,
-			}
-		}
-
-		data := make(map[string]interface{})
-
-		for k, v := range form.Value {
-			vLen := len(v)
-			if vLen == 0 {
-				continue // The struct will have the default value
-			}
-
-			if vLen == 1 {
-				// skip empty values. Parsing "" to int, float, bool, etc causes errors.
-				// Ignore to keep the default value of the struct field.
-				// The user can check if the field is empty using the required tag.
-				if v[0] == "" {
-					continue
-				}
-				data[k] = v[0] // if there's only one value.
-			} else {
-				data[k] = v // array of values
-			}
-		}
-
-		err = c.parseFormData(data, v, timezone)
-		if err != nil {
-			// propagate the error
+		if err := c.decodeForm(v, contentType, timezone); err != nil {
 			return err
 		}
 
@@ -197,12 +260,14 @@ func (c *Context) BodyParser(v interface{}, loc ...*time.Location) error {
 				Kind: ParseError,
 			}
 		}
-		// validate the struct here
-		if c.router != nil && c.router.validator != nil {
-			err := c.router.validator.Struct(v)
-			return err
+		return c.validateBody(v)
+	} else if contentType == ContentTypeMergePatch || contentType == ContentTypeJSONPatch {
+		return FormError{
+			Err:  fmt.Errorf("%s is a patch, apply it with c.ApplyMergePatch or c.ApplyJSONPatch", contentType),
+			Kind: InvalidContentType,
 		}
-		return nil
+	} else if codec, ok := c.codec(contentType); ok {
+		return c.decodeCodec(codec, v)
 	} else {
 		return FormError{
 			Err:  fmt.Errorf("unsupported content type: %s", contentType),
@@ -211,6 +276,124 @@ func (c *Context) BodyParser(v interface{}, loc ...*time.Location) error {
 	}
 }
 
+// DecodeForm parses the submitted urlencoded or multipart form into v, a pointer to a struct,
+// like BodyParser but without validating v. Use it to fill a struct over several requests,
+// e.g the steps of a wizard, and validate the fields of each step with ValidateFields.
+func (c *Context) DecodeForm(v any) error {
+	contentType := c.ContentType()
+	if contentType != ContentTypeUrlEncoded && contentType != ContentTypeMultipartForm {
+		return FormError{
+			Err:  fmt.Errorf("unsupported content type: %s", contentType),
+			Kind: InvalidContentType,
+		}
+	}
+
+	limits := DefaultDecompressLimits
+	if c.router != nil {
+		limits = c.router.decompressLimits
+	}
+
+	if err := DecompressBody(c.Request, limits); err != nil {
+		return err
+	}
+	return c.decodeForm(v, contentType, c.Timezone())
+}
+
+// decodeForm parses the urlencoded or multipart form of the request into the struct pointed to by v.
+func (c *Context) decodeForm(v any, contentType string, timezone *time.Location) error {
+	r := c.Request
+	if rv := reflect.ValueOf(v); rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return FormError{
+			Err:  fmt.Errorf("v must be a pointer to a struct to parse %s", contentType),
+			Kind: InvalidStructPointer,
+		}
+	}
+
+	var form *multipart.Form
+	var err error
+	if contentType == ContentTypeMultipartForm {
+		err = c.ParseMultipartForm()
+		if err != nil {
+			return FormError{
+				Err:  err,
+				Kind: ParseError,
+			}
+		}
+		form = r.MultipartForm
+	} else {
+		err = r.ParseForm()
+		if err != nil {
+			return FormError{
+				Err:  err,
+				Kind: ParseError,
+			}
+		}
+		form = &multipart.Form{
+			Value: r.Form,
+		}
+	}
+
+	data := make(map[string]interface{})
+
+	for k, v := range form.Value {
+		vLen := len(v)
+		if vLen == 0 {
+			continue // The struct will have the default value
+		}
+
+		if vLen == 1 {
+			// Empty values are kept so that setField can reset the field,
+			// see the tri-state contract of BodyParser.
+			data[k] = v[0] // if there's only one value.
+		} else {
+			data[k] = v // array of values
+		}
+	}
+
+	// propagate the error
+	return c.parseFormData(data, v, timezone)
+}
+
+// validateBody validates a decoded JSON or XML body. Structs are validated with their tags
+// and slices, arrays and maps of structs element-wise, with the index or key in the
+// namespace of the errors e.g "[1].Name". Other values are not validated.
+func (c *Context) validateBody(v any) error {
+	if c.router == nil || c.router.validator == nil {
+		return nil
+	}
+
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	switch rv.Kind() {
+	case reflect.Struct:
+		return c.router.validator.Struct(v)
+	case reflect.Slice, reflect.Array, reflect.Map:
+		elem := rv.Type().Elem()
+		if elem.Kind() == reflect.Ptr {
+			elem = elem.Elem()
+		}
+
+		if elem.Kind() == reflect.Struct {
+			return c.router.validator.Var(rv.Interface(), "dive")
+		}
+	}
+	return nil
+}
+
+// ValidateFields validates the fields of the struct v with the router's validator, e.g
+// the fields of one step of a multi-step form. Fields are struct field names, nested
+// fields are namespaced relative to v e.g "Address.City". Without fields, all of v is validated.
+// The error is a validator.ValidationErrors, handled by the error handler like BodyParser errors.
+func (c *Context) ValidateFields(v any, fields ...string) error {
+	if c.router == nil || c.router.validator == nil {
+		return nil
+	}
+
+	if len(fields) == 0 {
+		return c.router.validator.Struct(v)
+	}
+	return c.router.validator.StructPartial(v, fields...)
+}
+
 // SnakeCase converts a string to snake_case.
 // For more complex cases, use a third-party package like github.com/iancoleman/strcase.
 func SnakeCase(s string) string {
@@ -251,28 +434,11 @@ func (c *Context) parseFormData(data map[string]interface{}, v interface{}, time
 
 	for i := 0; i < rt.NumField(); i++ {
 		field := rt.Field(i)
-		tag := field.Tag.Get(tagName)
-		if tag == "" {
-			// try json tag name and fallback to snake case
-			tag = field.Tag.Get("json")
-
-			// If there is no json tag, use the snake_case of the field name
-			if tag == "" {
-				tag = SnakeCase(field.Name)
-			}
-		}
-
-		tagList := strings.Split(tag, ",")
-		for i := range tagList {
-			tagList[i] = strings.TrimSpace(tagList[i])
-		}
-
-		// Take tag name to be the first in the tagList
-		tag = tagList[0]
+		tag, tagList := formFieldKey(field, tagName)
 
 		required := slices.Contains(tagList, "required") || field.Tag.Get("required") == "true"
 		value, ok := data[tag]
-		if !ok && required {
+		if required && (!ok || value == "") {
 			return FormError{
 				Err:   fmt.Errorf("field '%s' is required", tag),
 				Kind:  RequiredFieldMissing,
@@ -294,11 +460,41 @@ func (c *Context) parseFormData(data map[string]interface{}, v interface{}, time
 	return nil
 }
 
+// formFieldKey returns the form key of the struct field and the options of its tag.
+// The key is taken from tagName, then the json tag and then the snake_case field name.
+func formFieldKey(field reflect.StructField, tagName string) (string, []string) {
+	tag := field.Tag.Get(tagName)
+	if tag == "" {
+		// try json tag name and fallback to snake case
+		tag = field.Tag.Get("json")
+
+		// If there is no json tag, use the snake_case of the field name
+		if tag == "" {
+			tag = SnakeCase(field.Name)
+		}
+	}
+
+	tagList := strings.Split(tag, ",")
+	for i := range tagList {
+		tagList[i] = strings.TrimSpace(tagList[i])
+	}
+
+	// Take tag name to be the first in the tagList
+	return tagList[0], tagList
+}
+
 func setField(name string, fieldVal reflect.Value, value interface{}, timezone ...*time.Location) error {
 	if value == nil {
 		return nil
 	}
 
+	// A present but empty value clears the field: nil for pointers, zero otherwise.
+	// Parsing "" as a number, bool or time would fail.
+	if value == "" {
+		fieldVal.Set(reflect.Zero(fieldVal.Type()))
+		return nil
+	}
+
 	tz := DefaultTimezone
 	if len(timezone) > 0 {
 		tz = timezone[0]
@@ -559,6 +755,7 @@ type FormScanner interface {
 }
 
 // QueryParser parses the query string and stores the result in v.
+// Date and time fields are parsed in the request timezone, see Context.Timezone.
 func (c *Context) QueryParser(v interface{}, tag ...string) error {
 	var tagName string = "query"
 	if len(tag) > 0 {
@@ -574,7 +771,7 @@ func (c *Context) QueryParser(v interface{}, tag ...string) error {
 		}
 	}
 
-	data := c.Request.URL.Query()
+	data := c.queryValues()
 	dataMap := make(map[string]interface{}, len(data))
 	for k, v := range data {
 		if len(v) == 1 {
@@ -584,7 +781,7 @@ func (c *Context) QueryParser(v interface{}, tag ...string) error {
 		}
 	}
 
-	err := c.parseFormData(dataMap, v, time.UTC, tagName)
+	err := c.parseFormData(dataMap, v, c.Timezone(), tagName)
 	if err != nil {
 		return errors.Wrap(err, "query parser error")
 	}
@@ -597,6 +794,70 @@ func (c *Context) QueryParser(v interface{}, tag ...string) error {
 	return nil
 }
 
+// ChangedFields returns the names of the fields of the struct v whose keys are present
+// in the submitted form or query, including keys with empty values, in declaration order.
+// Form keys are resolved like BodyParser and query keys like QueryParser.
+// Call it after BodyParser or QueryParser, it does not read the request body.
+// After ApplyMergePatch, it returns the fields whose JSON keys are in the patch instead.
+//
+// Example:
+//
+//	var input struct {
+//		Name   *string `form:"name"`
+//		Active *bool   `form:"active"`
+//	}
+//	c.BodyParser(&input)
+//	for _, field := range c.ChangedFields(&input) { ... } // e.g ["Active"]
+func (c *Context) ChangedFields(v any) []string {
+	rt := reflect.TypeOf(v)
+	for rt != nil && rt.Kind() == reflect.Ptr {
+		rt = rt.Elem()
+	}
+
+	if rt == nil || rt.Kind() != reflect.Struct {
+		return nil
+	}
+
+	if c.patchedKeys != nil {
+		var changed []string
+		for i := 0; i < rt.NumField(); i++ {
+			if field := rt.Field(i); field.IsExported() && c.patchedField(field) {
+				changed = append(changed, field.Name)
+			}
+		}
+		return changed
+	}
+
+	form := url.Values{}
+	for k, values := range c.Request.PostForm {
+		form[k] = values
+	}
+
+	if c.Request.MultipartForm != nil {
+		for k, values := range c.Request.MultipartForm.Value {
+			form[k] = values
+		}
+	}
+	query := c.queryValues()
+
+	var changed []string
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		formKey, _ := formFieldKey(field, "form")
+		queryKey, _ := formFieldKey(field, "query")
+		if _, ok := form[formKey]; ok {
+			changed = append(changed, field.Name)
+		} else if _, ok := query[queryKey]; ok {
+			changed = append(changed, field.Name)
+		}
+	}
+	return changed
+}
+
 // Parse time from string using specified timezone. If timezone is nil,
 // UTC is used. Supported time formats are tried in order.
 /*
@@ -0,0 +1,150 @@
+package rex
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FormErrors converts validation, form and param errors to a map of form field name to message,
+// for re-rendering a form with the message next to each input. Joined errors are merged and the
+// first message of a field wins. Validation messages are translated with the router's translator.
+//
+// v is the struct passed to BodyParser or QueryParser. It maps struct field names to the form
+// field names, which follow the form and json tags like BodyParser. Errors of other types are ignored.
+func (c *Context) FormErrors(err error, v any) map[string]string {
+	out := make(map[string]string)
+	if err == nil {
+		return out
+	}
+
+	keys := formKeys(v)
+	add := func(field, msg string) {
+		if key, ok := keys[field]; ok {
+			field = key
+		}
+
+		if _, exists := out[field]; !exists {
+			out[field] = msg
+		}
+	}
+
+	for _, leaf := range flattenErrors(err) {
+		var (
+			ve validator.ValidationErrors
+			fe FormError
+			pe ParamError
+		)
+
+		switch {
+		case errors.As(leaf, &ve):
+			for _, fieldErr := range ve {
+				add(fieldErr.StructField(), fieldErr.Translate(c.router.translator))
+			}
+		case errors.As(leaf, &fe):
+			field, msg := fe.Field, fe.Err.Error()
+			if inner, ok := fe.Err.(FormError); ok {
+				field, msg = inner.Field, inner.Err.Error()
+			}
+			add(field, msg)
+		case errors.As(leaf, &pe):
+			add(pe.Key, pe.Error())
+		}
+	}
+	return out
+}
+
+// formKeys maps the field names of the struct v to their form field names.
+func formKeys(v any) map[string]string {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	keys := make(map[string]string)
+	if t == nil || t.Kind() != reflect.Struct {
+		return keys
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() {
+			keys[field.Name], _ = formFieldKey(field, "form")
+		}
+	}
+	return keys
+}
+
+// formValues returns the exported field values of the struct v keyed by form field name.
+// Pointers are dereferenced and nil pointers are empty strings, so templates can
+// repopulate inputs with {{ .form.email }}.
+func formValues(v any) map[string]any {
+	values := make(map[string]any)
+
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return values
+		}
+		rv = rv.Elem()
+	}
+
+	if rv.Kind() != reflect.Struct {
+		return values
+	}
+
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		key, _ := formFieldKey(field, "form")
+		value := rv.Field(i)
+		for value.Kind() == reflect.Pointer && !value.IsNil() {
+			value = value.Elem()
+		}
+
+		if value.Kind() == reflect.Pointer {
+			values[key] = "" // nil pointer
+		} else {
+			values[key] = value.Interface()
+		}
+	}
+	return values
+}
+
+// RenderWithErrors re-renders a form page after a failed submission with status 422.
+// It sets "errors" in data to c.FormErrors(err, submitted) and "form" to the field values
+// of submitted keyed by form field name, unless data already has those keys.
+//
+// Example:
+//
+//	var user User
+//	if err := c.BodyParser(&user); err != nil {
+//		return c.RenderWithErrors("users/new", rex.Map{"Title": "New user"}, err, &user)
+//	}
+//
+// And in the template:
+//
+//	<input name="email" value="{{ .form.email }}">
+//	{{ with .errors.email }}<span class="error">{{ . }}</span>{{ end }}
+func (c *Context) RenderWithErrors(name string, data Map, err error, submitted any) error {
+	if data == nil {
+		data = Map{}
+	}
+
+	if _, ok := data["errors"]; !ok {
+		data["errors"] = c.FormErrors(err, submitted)
+	}
+
+	if _, ok := data["form"]; !ok {
+		data["form"] = formValues(submitted)
+	}
+
+	c.WriteHeader(http.StatusUnprocessableEntity)
+	return c.Render(name, data)
+}
@@ -0,0 +1,110 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+type signupForm struct {
+	Email    string  `form:"email" validate:"required,email"`
+	Username string  `form:"user_name" validate:"min=5"`
+	Bio      *string `form:"bio"`
+}
+
+func newSignupRouter(t *testing.T) *rex.Router {
+	t.Helper()
+	dir := t.TempDir()
+
+	page := `<form>
+<input name="email" value="{{ .form.email }}">{{ with .errors.email }}<span id="email-error">{{ . }}</span>{{ end }}
+<input name="user_name" value="{{ .form.user_name }}">{{ with .errors.user_name }}<span id="user_name-error">{{ . }}</span>{{ end }}
+<textarea name="bio">{{ .form.bio }}</textarea>
+</form>`
+
+	files := map[string]string{"base.html": `<main>{{ .Content }}</main>`, "signup.html": page}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r := rex.NewRouter(
+		rex.WithTemplates(rex.Must(rex.ParseTemplates(dir, nil))),
+		rex.BaseLayout("base.html"),
+		rex.ContentBlock("Content"),
+	)
+	r.POST("/signup", func(c *rex.Context) error {
+		var s signupForm
+		if err := c.BodyParser(&s); err != nil {
+			return c.RenderWithErrors("signup.html", nil, err, &s)
+		}
+		return c.String("welcome " + s.Username)
+	})
+	return r
+}
+
+func TestRenderWithErrors(t *testing.T) {
+	r := newSignupRouter(t)
+
+	req := rex.NewTestRequest(http.MethodPost, "/signup").
+		Form(url.Values{"email": {"not-an-email"}, "user_name": {"bob"}}).
+		Build()
+
+	res := r.Test(req)
+	if res.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422, got %d: %s", res.Code, res.Body.String())
+	}
+
+	body := res.Body.String()
+	for _, want := range []string{
+		`<input name="email" value="not-an-email"><span id="email-error">email must be a valid email address</span>`,
+		`<input name="user_name" value="bob"><span id="user_name-error">user_name must be at least 5 characters in length</span>`,
+		`<textarea name="bio"></textarea>`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %q in\n%s", want, body)
+		}
+	}
+
+	req = rex.NewTestRequest(http.MethodPost, "/signup").
+		Form(url.Values{"email": {"bob@example.com"}, "user_name": {"bobby"}}).
+		Build()
+
+	if res := r.Test(req); res.Code != http.StatusOK || res.Body.String() != "welcome bobby" {
+		t.Errorf("expected a valid submission to pass, got %d %q", res.Code, res.Body.String())
+	}
+}
+
+func TestFormErrors(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		formErr := rex.FormError{Field: "Username", Err: errors.New("invalid username")}
+		paramErr := rex.ParamError{Source: "query", Key: "page", Value: "x", Err: errors.New("not a number")}
+
+		got := c.FormErrors(rex.Errors(formErr, paramErr, errors.New("ignored")), &signupForm{})
+		if got["user_name"] != "invalid username" {
+			t.Errorf("expected the form error under the form field name, got %v", got)
+		}
+
+		if !strings.Contains(got["page"], "not a number") {
+			t.Errorf("expected the param error under its key, got %v", got)
+		}
+
+		if len(got) != 2 {
+			t.Errorf("expected other errors to be ignored, got %v", got)
+		}
+
+		if got := c.FormErrors(nil, &signupForm{}); len(got) != 0 {
+			t.Errorf("expected no errors, got %v", got)
+		}
+		return nil
+	})
+	r.Test(rex.NewTestRequest(http.MethodGet, "/").Build())
+}
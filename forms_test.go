@@ -1,8 +1,10 @@
 package rex
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -876,3 +878,348 @@ func TestErrorHandlerFormErrors(t *testing.T) {
 	}
 
 }
+
+func TestBodyParserJSONSyntaxErrorLocation(t *testing.T) {
+	type Payload struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	body := "{\n  \"name\": \"rex\",\n  \"age\": x\n}"
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", ContentTypeJSON)
+
+	ctx := &Context{Request: req}
+	err := ctx.BodyParser(&Payload{})
+
+	var fe FormError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FormError, got %T", err)
+	}
+
+	if fe.Kind != ParseError {
+		t.Errorf("expected kind %s, got %s", ParseError, fe.Kind)
+	}
+
+	if fe.Line != 3 || fe.Column != 10 {
+		t.Errorf("expected line 3 column 10, got line %d column %d", fe.Line, fe.Column)
+	}
+}
+
+func TestBodyParserJSONSyntaxErrorPastContext(t *testing.T) {
+	body := `{"name": "` + strings.Repeat("a", jsonErrorContext) + `", "age": x}`
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", ContentTypeJSON)
+
+	ctx := &Context{Request: req}
+	err := ctx.BodyParser(&map[string]any{})
+
+	var fe FormError
+	if !errors.As(err, &fe) {
+		t.Fatalf("expected FormError, got %T", err)
+	}
+
+	if fe.Offset <= jsonErrorContext {
+		t.Errorf("expected the offset past the kept bytes, got %d", fe.Offset)
+	}
+
+	if fe.Line != 0 || fe.Column != 0 {
+		t.Errorf("expected no line and column past the kept bytes, got line %d column %d", fe.Line, fe.Column)
+	}
+}
+
+func TestBodyParserJSONTypeMismatch(t *testing.T) {
+	type Address struct {
+		Zip int `json:"zip_code"`
+	}
+
+	type Payload struct {
+		Address Address `json:"address"`
+	}
+
+	r := NewRouter()
+	r.POST("/", func(c *Context) error {
+		return c.BodyParser(&Payload{})
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"address": {"zip_code": "256"}}`))
+	req.Header.Set("Content-Type", ContentTypeJSON)
+	req.Header.Set("Accept", ContentTypeJSON)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", w.Code)
+	}
+
+	var res map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatal(err)
+	}
+
+	if res["field"] != "address.zip_code" {
+		t.Errorf("expected field address.zip_code, got %v", res["field"])
+	}
+
+	if res["expected"] != "int" || res["actual"] != "string" {
+		t.Errorf("expected int/string, got %v/%v", res["expected"], res["actual"])
+	}
+
+	if res["line"] != float64(1) {
+		t.Errorf("expected line 1, got %v", res["line"])
+	}
+}
+
+func TestBodyParserJSONTooLarge(t *testing.T) {
+	r := NewRouter()
+	r.POST("/", func(c *Context) error {
+		c.Request.Body = http.MaxBytesReader(c.Response, c.Request.Body, 8)
+		var v struct {
+			Name string `json:"name"`
+		}
+		return c.BodyParser(&v)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name": "a very long name"}`))
+	req.Header.Set("Content-Type", ContentTypeJSON)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status 413, got %d", w.Code)
+	}
+}
+
+type patchUser struct {
+	Name   *string `form:"name" query:"name"`
+	Age    *int    `form:"age" query:"age"`
+	Active *bool   `form:"active" query:"active"`
+	Score  int     `form:"score" query:"score"`
+	Note   string  `form:"note" query:"note"`
+}
+
+func TestBinderTriState(t *testing.T) {
+	check := func(t *testing.T, u patchUser, changed []string) {
+		t.Helper()
+
+		// Absent key: untouched.
+		if u.Name != nil {
+			t.Errorf("expected absent name to stay nil, got %q", *u.Name)
+		}
+
+		// Present empty value: nil pointer and zeroed value field.
+		if u.Age != nil {
+			t.Errorf("expected empty age to be nil, got %d", *u.Age)
+		}
+
+		if u.Score != 0 {
+			t.Errorf("expected empty score to be reset to 0, got %d", u.Score)
+		}
+
+		// Present value: parsed.
+		if u.Active == nil || *u.Active {
+			t.Errorf("expected active to be a pointer to false, got %v", u.Active)
+		}
+
+		if u.Note != "hi" {
+			t.Errorf("expected note hi, got %q", u.Note)
+		}
+
+		want := []string{"Age", "Active", "Score", "Note"}
+		if !reflect.DeepEqual(changed, want) {
+			t.Errorf("expected changed fields %v, got %v", want, changed)
+		}
+	}
+
+	values := url.Values{"age": {""}, "active": {"false"}, "score": {""}, "note": {"hi"}}
+
+	run := func(t *testing.T, req *http.Request, parse func(c *Context, u *patchUser) error) {
+		t.Helper()
+
+		r := NewRouter()
+		r.PATCH("/users", func(c *Context) error {
+			u := patchUser{Score: 7}
+			if err := parse(c, &u); err != nil {
+				return err
+			}
+			check(t, u, c.ChangedFields(&u))
+			return nil
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Errorf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+	}
+
+	body := func(c *Context, u *patchUser) error { return c.BodyParser(u) }
+
+	t.Run("urlencoded", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, "/users", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", ContentTypeUrlEncoded)
+		run(t, req, body)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		var buf strings.Builder
+		w := multipart.NewWriter(&buf)
+		for k, v := range values {
+			w.WriteField(k, v[0])
+		}
+		w.Close()
+
+		req := httptest.NewRequest(http.MethodPatch, "/users", strings.NewReader(buf.String()))
+		req.Header.Set("Content-Type", w.FormDataContentType())
+		run(t, req, body)
+	})
+
+	t.Run("query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPatch, "/users?"+values.Encode(), nil)
+		run(t, req, func(c *Context, u *patchUser) error { return c.QueryParser(u) })
+	})
+}
+
+func TestBinderEmptyRequiredValue(t *testing.T) {
+	type input struct {
+		Email string `form:"email,required"`
+	}
+
+	r := NewRouter()
+	r.POST("/", func(c *Context) error {
+		var in input
+		return c.BodyParser(&in)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("email="))
+	req.Header.Set("Content-Type", ContentTypeUrlEncoded)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "required") {
+		t.Errorf("expected an empty required value to be reported as missing, got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestBodyParserJSONArray(t *testing.T) {
+	type Item struct {
+		ID   int    `json:"id" validate:"required"`
+		Name string `json:"name" validate:"required,min=2"`
+	}
+
+	r := NewRouter()
+
+	var items []Item
+	r.POST("/", func(c *Context) error {
+		items = nil
+		return c.BodyParser(&items)
+	})
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post(`[{"id": 1, "name": "pen"}, {"id": 2, "name": "ink"}]`); w.Code != http.StatusOK || len(items) != 2 || items[1].Name != "ink" {
+		t.Fatalf("expected the array to be bound, got %d %+v", w.Code, items)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`[{"id": 1, "name": "pen"}, {"name": "x"}]`))
+	req.Header.Set("Content-Type", ContentTypeJSON)
+	ctx := &Context{Request: req, router: r}
+
+	var invalid []Item
+	err := ctx.BodyParser(&invalid)
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Fatalf("expected the errors of the second item, got %v", err)
+	}
+
+	for _, fe := range verrs {
+		if !strings.HasPrefix(fe.Namespace(), "[1].") {
+			t.Errorf("expected the index in the namespace, got %q", fe.Namespace())
+		}
+	}
+
+	w := post(`[{"id": 1, "name": "pen"}, {"name": "x"}]`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "id is a required field") {
+		t.Errorf("expected the validation errors of all items, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestBodyParserJSONPrimitives(t *testing.T) {
+	parse := func(body string, v any) error {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Content-Type", ContentTypeJSON)
+		ctx := &Context{Request: req, router: NewRouter()}
+		return ctx.BodyParser(v)
+	}
+
+	var s string
+	if err := parse(`"hello"`, &s); err != nil || s != "hello" {
+		t.Errorf("expected a string body, got %q (%v)", s, err)
+	}
+
+	var n float64
+	if err := parse(`42.5`, &n); err != nil || n != 42.5 {
+		t.Errorf("expected a number body, got %v (%v)", n, err)
+	}
+
+	var m map[string]int
+	if err := parse(`{"a": 1}`, &m); err != nil || m["a"] != 1 {
+		t.Errorf("expected a map body, got %v (%v)", m, err)
+	}
+
+	var fe FormError
+	if err := parse(`"hello"`, s); !errors.As(err, &fe) || fe.Kind != InvalidStructPointer {
+		t.Errorf("expected InvalidStructPointer for a non-pointer, got %v", err)
+	}
+}
+
+func TestBodyParserFormRequiresStruct(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("id=1&id=2"))
+	req.Header.Set("Content-Type", ContentTypeUrlEncoded)
+	ctx := &Context{Request: req}
+
+	var ids []int
+	err := ctx.BodyParser(&ids)
+
+	var fe FormError
+	if !errors.As(err, &fe) || fe.Kind != InvalidStructPointer {
+		t.Fatalf("expected InvalidStructPointer, got %v", err)
+	}
+
+	if !strings.Contains(fe.Err.Error(), "pointer to a struct") || !strings.Contains(fe.Err.Error(), ContentTypeUrlEncoded) {
+		t.Errorf("expected the requirement of the content type in the message, got %q", fe.Err)
+	}
+}
+
+func TestDecodeFormAndValidateFields(t *testing.T) {
+	type signup struct {
+		Name  string `form:"name" validate:"required"`
+		Email string `form:"email" validate:"required,email"`
+	}
+
+	r := NewRouter()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("name=ann"))
+	req.Header.Set("Content-Type", ContentTypeUrlEncoded)
+	ctx := r.InitContext(httptest.NewRecorder(), req)
+
+	var input signup
+	if err := ctx.DecodeForm(&input); err != nil || input.Name != "ann" {
+		t.Fatalf("expected the form to decode without validation, got %v %+v", err, input)
+	}
+
+	if err := ctx.ValidateFields(&input, "Name"); err != nil {
+		t.Errorf("expected the name to be valid, got %v", err)
+	}
+
+	var ve validator.ValidationErrors
+	if err := ctx.ValidateFields(&input); !errors.As(err, &ve) || ve[0].Field() != "email" {
+		t.Errorf("expected the missing email to fail validation, got %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package rex
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Freeze ends route registration and validates the routes. Registering a route afterwards panics.
+// Call it after all routes are registered and before the server starts, or use WithFreeze.
+// It returns an error listing every problem found:
+//   - path parameters declared twice in a pattern and misplaced {$} or {name...} segments.
+//   - fields of the types declared with Route.Input whose `path:"name"` tag
+//     names a parameter missing from the route pattern, e.g a typo like "userid" for {userID}.
+//
+// Routers that are never frozen work as before.
+func (r *Router) Freeze() error {
+	r.frozen = true
+
+	prefixes := make([]string, 0, len(r.routes))
+	for prefix := range r.routes {
+		prefixes = append(prefixes, prefix)
+	}
+	slices.Sort(prefixes)
+
+	var errs []error
+	for _, prefix := range prefixes {
+		route := r.routes[prefix]
+
+		params, err := patternParams(route.pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rex: route %q: %w", prefix, err))
+			continue
+		}
+
+		input, ok := route.GetMeta(MetaInput)
+		if !ok {
+			continue
+		}
+
+		for _, mismatch := range pathTagMismatches(input.(reflect.Type), params) {
+			errs = append(errs, fmt.Errorf("rex: route %q: %s", prefix, mismatch))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Frozen reports whether Freeze was called.
+func (r *Router) Frozen() bool {
+	return r.frozen
+}
+
+// checkFrozen panics if a route is registered after Freeze.
+func (r *Router) checkFrozen(routePattern string) {
+	if r.frozen {
+		panic(fmt.Sprintf("rex: cannot register %q, the router is frozen. Register all routes before calling Freeze", routePattern))
+	}
+}
+
+// patternParams returns the names of the path parameters of a route pattern.
+func patternParams(pattern string) ([]string, error) {
+	segments := strings.Split(pattern, "/")
+
+	var params []string
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+
+		last := i == len(segments)-1
+		name := strings.TrimSuffix(segment[1:len(segment)-1], "...")
+		switch {
+		case segment == "{$}":
+			if !last {
+				return nil, fmt.Errorf("{$} must be the last segment")
+			}
+			continue
+		case strings.HasSuffix(segment, "...}") && !last:
+			return nil, fmt.Errorf("%s must be the last segment", segment)
+		case name == "":
+			return nil, fmt.Errorf("empty parameter name in %s", segment)
+		case slices.Contains(params, name):
+			return nil, fmt.Errorf("duplicate parameter {%s}", name)
+		}
+		params = append(params, name)
+	}
+	return params, nil
+}
+
+// pathTagMismatches returns a message for every `path` tag of t, a struct or a pointer to one,
+// that is not one of params. Embedded structs are included.
+func pathTagMismatches(t reflect.Type, params []string) []string {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var mismatches []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			mismatches = append(mismatches, pathTagMismatches(field.Type, params)...)
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("path"), ",")
+		if name == "" || name == "-" || slices.Contains(params, name) {
+			continue
+		}
+
+		msg := fmt.Sprintf("field %s.%s has path tag %q but the pattern has no {%s} parameter",
+			t.Name(), field.Name, name, name)
+		if suggestion := closestName(name, params); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean {%s}?)", suggestion)
+		}
+		mismatches = append(mismatches, msg)
+	}
+	return mismatches
+}
+
+// WithFreeze calls Freeze on the router served by NewServer and panics with the
+// validation errors, so that a broken route table fails at startup.
+// It has no effect if the handler is not a *Router.
+func WithFreeze() ServerOption {
+	return func(s *Server) {
+		if router, ok := s.Handler.(*Router); ok {
+			if err := router.Freeze(); err != nil {
+				panic(err.Error())
+			}
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package rex_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+type freezeBase struct {
+	OrgID string `path:"orgID"`
+}
+
+type freezeInput struct {
+	freezeBase
+	UserID string `path:"userid"`
+	PostID string `path:"postID,omitempty"`
+	Name   string `json:"name"`
+}
+
+func freezeHandler(c *rex.Context) error {
+	return c.String("ok")
+}
+
+func TestFreezeReportsPathTagMismatches(t *testing.T) {
+	r := rex.NewRouter()
+	r.PUT("/orgs/{orgID}/users/{userID}/posts/{postID}", freezeHandler).Input(freezeInput{})
+	r.GET("/orgs/{orgID}", freezeHandler).Input(&freezeInput{})
+	r.GET("/health", freezeHandler)
+
+	err := r.Freeze()
+	if err == nil {
+		t.Fatal("expected the mismatches to be reported")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		`route "PUT /orgs/{orgID}/users/{userID}/posts/{postID}": field freezeInput.UserID has path tag "userid" ` +
+			`but the pattern has no {userid} parameter (did you mean {userID}?)`,
+		`route "GET /orgs/{orgID}": field freezeInput.UserID has path tag "userid"`,
+		`route "GET /orgs/{orgID}": field freezeInput.PostID has path tag "postID"`,
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in:\n%s", want, msg)
+		}
+	}
+
+	if lines := strings.Count(msg, "\n") + 1; lines != 3 {
+		t.Errorf("expected 3 mismatches, got %d:\n%s", lines, msg)
+	}
+}
+
+func TestFreezeValidRoutes(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/orgs/{orgID}/users/{userid}/posts/{postID}", freezeHandler).Input(freezeInput{})
+	r.GET("/files/{path...}", freezeHandler)
+	r.GET("/{$}", freezeHandler)
+
+	if err := r.Freeze(); err != nil {
+		t.Fatalf("expected no errors, got %v", err)
+	}
+
+	if !r.Frozen() {
+		t.Error("expected the router to be frozen")
+	}
+}
+
+func TestRegisterAfterFreezePanics(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/", freezeHandler)
+	r.Freeze()
+
+	defer func() {
+		err := recover()
+		if err == nil || !strings.Contains(err.(string), `cannot register "POST /late"`) {
+			t.Errorf("expected a frozen router panic, got %v", err)
+		}
+	}()
+	r.POST("/late", freezeHandler)
+}
+
+func TestWithFreeze(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/users/{id}", freezeHandler).Input(struct {
+		ID string `path:"userID"`
+	}{})
+
+	defer func() {
+		if err := recover(); err == nil || !strings.Contains(err.(string), "userID") {
+			t.Errorf("expected NewServer to panic with the mismatch, got %v", err)
+		}
+	}()
+	rex.NewServer(":0", r, rex.WithFreeze())
+}
+
+func TestWithFreezeIgnoresOtherHandlers(t *testing.T) {
+	rex.NewServer(":0", http.NotFoundHandler(), rex.WithFreeze())
+}
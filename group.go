@@ -1,7 +1,10 @@
 package rex
 
 import (
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
 )
 
 // Group is a collection of routes with a common prefix.
@@ -12,10 +15,13 @@ type Group struct {
 }
 
 // Group creates a new group with the given prefix and options.
+// The prefix must start with "/" and trailing slashes are removed.
+// It panics if the prefix is invalid.
 func (r *Router) Group(prefix string, middlewares ...Middleware) *Group {
+	prefix = validGroupPrefix(prefix)
 	group := &Group{
 		prefix:      prefix,
-		middlewares: middlewares,
+		middlewares: slices.Clone(middlewares),
 		router:      r,
 	}
 
@@ -23,47 +29,108 @@ func (r *Router) Group(prefix string, middlewares ...Middleware) *Group {
 	return group
 }
 
-// Use adds middlewares to the group.
+// Use adds middlewares to the group, applied to the routes registered afterwards.
+// They run after the global middlewares and the middlewares of the parent groups, see Router.Use.
 func (g *Group) Use(middlewares ...Middleware) {
 	g.middlewares = append(g.middlewares, middlewares...)
 }
 
+// with returns the group middlewares followed by middlewares in a new slice,
+// so that routes and nested groups never share the backing array of g.middlewares.
+func (g *Group) with(middlewares []Middleware) []Middleware {
+	return slices.Concat(g.middlewares, middlewares)
+}
+
 // GET request.
-func (g *Group) GET(path string, handler HandlerFunc, middlewares ...Middleware) {
-	g.router.handle(http.MethodGet, g.prefix+path, handler, false, append(g.middlewares, middlewares...)...)
+func (g *Group) GET(path string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return g.handle(http.MethodGet, path, handler, middlewares)
 }
 
 // POST request.
-func (g *Group) POST(path string, handler HandlerFunc, middlewares ...Middleware) {
-	g.router.handle(http.MethodPost, g.prefix+path, handler, false, append(g.middlewares, middlewares...)...)
+func (g *Group) POST(path string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return g.handle(http.MethodPost, path, handler, middlewares)
 }
 
 // PUT request.
-func (g *Group) PUT(path string, handler HandlerFunc, middlewares ...Middleware) {
-	g.router.handle(http.MethodPut, g.prefix+path, handler, false, append(g.middlewares, middlewares...)...)
+func (g *Group) PUT(path string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return g.handle(http.MethodPut, path, handler, middlewares)
 }
 
 // PATCH request.
-func (g *Group) PATCH(path string, handler HandlerFunc, middlewares ...Middleware) {
-	g.router.handle(http.MethodPatch, g.prefix+path, handler, false, append(g.middlewares, middlewares...)...)
+func (g *Group) PATCH(path string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return g.handle(http.MethodPatch, path, handler, middlewares)
 }
 
 // DELETE request.
-func (g *Group) DELETE(path string, handler HandlerFunc, middlewares ...Middleware) {
-	g.router.handle(http.MethodDelete, g.prefix+path, handler, false, append(g.middlewares, middlewares...)...)
+func (g *Group) DELETE(path string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return g.handle(http.MethodDelete, path, handler, middlewares)
+}
+
+// OPTIONS request.
+func (g *Group) OPTIONS(path string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return g.handle(http.MethodOptions, path, handler, middlewares)
+}
+
+// handle registers a route of the group, recording the group prefix for AdminHandler.
+func (g *Group) handle(method, path string, handler HandlerFunc, middlewares []Middleware) *Route {
+	rt := g.router.handle(method, g.prefix+path, handler, false, g.with(middlewares)...)
+	rt.group = g.prefix
+	return rt
 }
 
 // Creates a nested group with the given prefix and middleware.
 func (g *Group) Group(prefix string, middlewares ...Middleware) *Group {
-	return g.router.Group(g.prefix+prefix, append(g.middlewares, middlewares...)...)
+	return g.router.Group(g.prefix+validGroupPrefix(prefix), g.with(middlewares)...)
 }
 
-// Static serves files from the given file system root.
+// Static serves files in the directory dir at prefix relative to the group prefix,
+// like Router.Static. Group middleware is applied to the file responses.
 func (g *Group) Static(prefix, dir string, maxAge ...int) {
-	g.router.Static(g.prefix+prefix, dir, maxAge...)
+	g.router.static(g.prefix+prefix, dir, maxAge, g.middlewares...)
 }
 
-// StaticFs serves files from the given file system.
+// StaticFs serves files from the given file system at prefix relative to
+// the group prefix, like Router.StaticFS. Group middleware is applied to the file responses.
 func (g *Group) StaticFs(prefix string, fs http.FileSystem, maxAge ...int) {
-	g.router.StaticFS(g.prefix+prefix, fs, maxAge...)
+	g.router.staticFS(g.prefix+prefix, fs, maxAge, g.middlewares...)
+}
+
+// StaticFS is an alias for StaticFs matching the Router method name.
+func (g *Group) StaticFS(prefix string, fs http.FileSystem, maxAge ...int) {
+	g.StaticFs(prefix, fs, maxAge...)
+}
+
+// File serves the file at path relative to the group prefix.
+// Group middleware is applied to the handler.
+func (g *Group) File(path, file string) {
+	g.GET(path, g.router.WrapHandler(fileHandler(file)))
+}
+
+// FileFS serves the file at path in the file system fs at the route prefix
+// relative to the group prefix. Group middleware is applied to the handler.
+func (g *Group) FileFS(fs http.FileSystem, prefix, path string) {
+	g.GET(prefix, g.router.WrapHandler(fileFSHandler(fs, path)))
+}
+
+// SPA serves a single page application under the group prefix.
+// Both asset requests and the index fallback go through the group middleware.
+// See Router.SPA for details on the options.
+func (g *Group) SPA(path string, index string, frontend http.FileSystem, options ...SPAOption) {
+	handler, err := newSPAHandler(frontend, index, options...)
+	if err != nil {
+		panic(fmt.Errorf("failed to create SPA handler: %w", err))
+	}
+
+	pattern := g.prefix + path
+	if !strings.HasSuffix(pattern, "/") {
+		pattern += "/"
+	}
+
+	handler.stripPrefix = strings.TrimSuffix(pattern, "/")
+	rt := g.router.handle(http.MethodGet, pattern, handler.serve, true, g.middlewares...)
+	rt.group = g.prefix
+	if len(handler.indexEncoded) > 0 {
+		// The index is already compressed.
+		rt.Meta(MetaSkipCompression, handler.skipCompression)
+	}
 }
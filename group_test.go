@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 
 	"github.com/abiiranathan/rex"
@@ -321,3 +322,245 @@ func TestRouterGroupStaticFS(t *testing.T) {
 		t.Errorf("expected hello world, got %s", string(data))
 	}
 }
+
+func TestRouterGroupSPA(t *testing.T) {
+	dirname := t.TempDir()
+	os.WriteFile(filepath.Join(dirname, "index.html"), []byte("<h1>app</h1>"), 0644)
+	os.WriteFile(filepath.Join(dirname, "app.js"), []byte("console.log(1)"), 0644)
+
+	r := rex.NewRouter()
+	auth := func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if c.Request.Header.Get("Authorization") == "" {
+				return c.WriteHeader(http.StatusUnauthorized)
+			}
+			return next(c)
+		}
+	}
+
+	admin := r.Group("/admin", auth)
+	admin.SPA("/app", "index.html", http.Dir(dirname))
+
+	tests := []struct {
+		name   string
+		path   string
+		auth   bool
+		status int
+		body   string
+	}{
+		{"asset unauthorized", "/admin/app/app.js", false, http.StatusUnauthorized, ""},
+		{"index unauthorized", "/admin/app/dashboard", false, http.StatusUnauthorized, ""},
+		{"asset", "/admin/app/app.js", true, http.StatusOK, "console.log(1)"},
+		{"index fallback", "/admin/app/dashboard/users", true, http.StatusOK, "<h1>app</h1>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth {
+				req.Header.Set("Authorization", "Bearer token")
+			}
+
+			res := r.Test(req)
+			if res.Status() != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, res.Status())
+			}
+
+			if tt.body != "" && res.BodyString() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, res.BodyString())
+			}
+		})
+	}
+}
+
+func TestRouterGroupFile(t *testing.T) {
+	dirname := t.TempDir()
+	file := filepath.Join(dirname, "robots.txt")
+	os.WriteFile(file, []byte("User-agent: *"), 0644)
+
+	r := rex.NewRouter()
+	var calls int
+	admin := r.Group("/admin", func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			calls++
+			return next(c)
+		}
+	})
+
+	admin.File("/robots.txt", file)
+	admin.FileFS(http.Dir(dirname), "/robots-fs.txt", "robots.txt")
+
+	for _, path := range []string{"/admin/robots.txt", "/admin/robots-fs.txt"} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Status() != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", path, res.Status())
+		}
+
+		if res.BodyString() != "User-agent: *" {
+			t.Errorf("%s: unexpected body %q", path, res.BodyString())
+		}
+	}
+
+	if calls != 2 {
+		t.Errorf("expected group middleware to run twice, ran %d times", calls)
+	}
+}
+
+func TestRouterGroupStaticMiddlewareAndMinified(t *testing.T) {
+	dirname := t.TempDir()
+	files := map[string]string{
+		"app.js":     "original",
+		"app.min.js": "minified",
+		"test.txt":   "hello world",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dirname, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	oldMinified := rex.ServeMinified
+	rex.ServeMinified = true
+	defer func() { rex.ServeMinified = oldMinified }()
+
+	marker := func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			c.SetHeader("X-Group", "admin")
+			return next(c)
+		}
+	}
+
+	r := rex.NewRouter()
+	admin := r.Group("/admin", marker)
+	admin.Static("/static", dirname, 60)
+	admin.StaticFS("/assets", http.Dir(dirname), 120)
+	admin.FileFS(http.Dir(dirname), "/robots", "test.txt")
+
+	tests := []struct {
+		path         string
+		body         string
+		cacheControl string
+	}{
+		{"/admin/static/test.txt", "hello world", "public, max-age=60"},
+		{"/admin/static/app.js", "minified", "public, max-age=60"},
+		{"/admin/assets/test.txt", "hello world", "public, max-age=120"},
+		{"/admin/assets/app.js", "minified", "public, max-age=120"},
+		{"/admin/robots", "hello world", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			res := r.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if res.Code != http.StatusOK || res.Body.String() != tt.body {
+				t.Fatalf("expected 200 %q, got %d %q", tt.body, res.Code, res.Body.String())
+			}
+
+			if got := res.Result().Header.Get("X-Group"); got != "admin" {
+				t.Errorf("expected the group middleware to run, got X-Group %q", got)
+			}
+
+			if got := res.Result().Header.Get("Cache-Control"); got != tt.cacheControl {
+				t.Errorf("expected Cache-Control %q, got %q", tt.cacheControl, got)
+			}
+		})
+	}
+
+	// The root router does not run group middleware.
+	r.Static("/public", dirname)
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/public/test.txt", nil))
+	if res.Result().Header.Get("X-Group") != "" {
+		t.Error("expected no group middleware for router static files")
+	}
+}
+
+func TestRouterGroupStaticRegisteredRoutes(t *testing.T) {
+	r := rex.NewRouter()
+	admin := r.Group("/admin")
+	admin.Static("/static", t.TempDir())
+	admin.StaticFS("/assets", http.Dir(t.TempDir()))
+
+	paths := make(map[string]string)
+	for _, route := range r.RegisteredRoutes() {
+		paths[route.Path] = route.Method
+	}
+
+	for _, path := range []string{"/admin/static/", "/admin/assets/"} {
+		if paths[path] != http.MethodGet {
+			t.Errorf("expected GET %s in registered routes, got %v", path, paths)
+		}
+	}
+}
+
+// recordMiddleware appends name to calls when the request goes through it.
+func recordMiddleware(calls *[]string, name string) rex.Middleware {
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			*calls = append(*calls, name)
+			return next(c)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	r := rex.NewRouter()
+	r.Use(recordMiddleware(&calls, "global"))
+
+	api := r.Group("/api", recordMiddleware(&calls, "api"))
+	v1 := api.Group("/v1", recordMiddleware(&calls, "v1"))
+	v1.GET("/users", func(c *rex.Context) error {
+		calls = append(calls, "handler")
+		return nil
+	}, recordMiddleware(&calls, "route")).Middleware(recordMiddleware(&calls, "builder"))
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/api/v1/users", nil))
+
+	want := []string{"global", "api", "v1", "route", "builder", "handler"}
+	if !slices.Equal(calls, want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
+
+func TestRouteMiddlewaresDoNotAlias(t *testing.T) {
+	var calls []string
+	r := rex.NewRouter()
+
+	// Grow the global and group slices so that they have spare capacity.
+	r.Use(recordMiddleware(&calls, "g1"))
+	r.Use(recordMiddleware(&calls, "g2"))
+	r.Use(recordMiddleware(&calls, "g3"))
+
+	group := r.Group("/g")
+	group.Use(recordMiddleware(&calls, "m1"))
+	group.Use(recordMiddleware(&calls, "m2"))
+	group.Use(recordMiddleware(&calls, "m3"))
+
+	ok := func(c *rex.Context) error { return nil }
+	r.GET("/a", ok, recordMiddleware(&calls, "a"))
+	r.GET("/b", ok, recordMiddleware(&calls, "b"))
+	group.GET("/c", ok, recordMiddleware(&calls, "c"))
+	group.GET("/d", ok, recordMiddleware(&calls, "d"))
+
+	nestedX := group.Group("/x", recordMiddleware(&calls, "x"))
+	nestedY := group.Group("/y", recordMiddleware(&calls, "y"))
+	nestedX.GET("/e", ok)
+	nestedY.GET("/f", ok)
+
+	tests := map[string][]string{
+		"/a":     {"g1", "g2", "g3", "a"},
+		"/b":     {"g1", "g2", "g3", "b"},
+		"/g/c":   {"g1", "g2", "g3", "m1", "m2", "m3", "c"},
+		"/g/d":   {"g1", "g2", "g3", "m1", "m2", "m3", "d"},
+		"/g/x/e": {"g1", "g2", "g3", "m1", "m2", "m3", "x"},
+		"/g/y/f": {"g1", "g2", "g3", "m1", "m2", "m3", "y"},
+	}
+
+	for path, want := range tests {
+		calls = nil
+		r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if !slices.Equal(calls, want) {
+			t.Errorf("%s: expected %v, got %v", path, want, calls)
+		}
+	}
+}
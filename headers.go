@@ -0,0 +1,74 @@
+package rex
+
+import "net/http"
+
+// headerPolicy holds the response headers set and removed on every request.
+type headerPolicy struct {
+	set    http.Header
+	remove []string
+}
+
+// WithResponseHeaderPolicy sets the headers in set on every response before the handler
+// and middleware run, so they can still be overridden per request. Headers in remove
+// are deleted right before the response header is written, which strips them even if
+// they are added later by middleware, handlers or proxied backends (e.g X-Powered-By or Server).
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.WithResponseHeaderPolicy(
+//		map[string]string{"X-Content-Type-Options": "nosniff"},
+//		[]string{"X-Powered-By", "Server"},
+//	))
+func WithResponseHeaderPolicy(set map[string]string, remove []string) RouterOption {
+	return func(r *Router) {
+		policy := &headerPolicy{set: make(http.Header, len(set))}
+		for k, v := range set {
+			policy.set.Set(k, v)
+		}
+
+		for _, k := range remove {
+			policy.remove = append(policy.remove, http.CanonicalHeaderKey(k))
+		}
+		r.headerPolicy = policy
+	}
+}
+
+// applyHeaderPolicy sets the policy headers and registers the removal hook.
+func (c *Context) applyHeaderPolicy(policy *headerPolicy) {
+	header := c.rw.Header()
+	for k, v := range policy.set {
+		header[k] = append([]string(nil), v...)
+	}
+
+	if len(policy.remove) > 0 {
+		c.rw.onBeforeWrite(func() {
+			for _, k := range policy.remove {
+				header.Del(k)
+			}
+		})
+	}
+}
+
+// RemoveHeaderOnWrite deletes the response header key right before the response header
+// is written, regardless of which middleware or handler sets it in the meantime.
+// It has no effect if the header has already been written.
+func (c *Context) RemoveHeaderOnWrite(key string) {
+	c.checkReleased()
+	c.rw.onBeforeWrite(func() {
+		c.rw.Header().Del(key)
+	})
+}
+
+// BeforeWriteHeader registers fn to run once right before the status and headers are sent,
+// while c.Status reports the status being written. Middleware use it to finish work the response
+// depends on, like committing a transaction, so that clients never see a success that was undone.
+//
+// If fn returns an error nothing is sent: writes of the handler fail with the error, and once
+// the handler returns the error goes to the error handler, which answers instead.
+// It has no effect if the header has already been written.
+func (c *Context) BeforeWriteHeader(fn func() error) {
+	c.checkReleased()
+	if !c.rw.statusSent {
+		c.rw.beforeHeader = append(c.rw.beforeHeader, fn)
+	}
+}
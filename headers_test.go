@@ -0,0 +1,116 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestResponseHeaderPolicy(t *testing.T) {
+	r := rex.NewRouter(rex.WithResponseHeaderPolicy(
+		map[string]string{
+			"X-Content-Type-Options": "nosniff",
+			"x-frame-options":        "DENY",
+		},
+		[]string{"x-powered-by", "Server"},
+	))
+
+	// Runs after the policy was applied, like a proxy copying upstream headers.
+	r.Use(func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			c.SetHeader("X-Powered-By", "PHP/5.6")
+			c.SetHeader("Server", "Apache")
+			return next(c)
+		}
+	})
+
+	r.GET("/", func(c *rex.Context) error {
+		c.SetHeader("X-Frame-Options", "SAMEORIGIN")
+		return c.String("ok")
+	})
+
+	r.GET("/json", func(c *rex.Context) error {
+		return c.JSON(map[string]string{"ok": "true"})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.Status() != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.Status())
+	}
+
+	if res.Header("X-Powered-By") != "" || res.Header("Server") != "" {
+		t.Errorf("expected headers set by middleware to be removed, got %v", res.Result().Header)
+	}
+
+	if got := res.Header("X-Frame-Options"); got != "SAMEORIGIN" {
+		t.Errorf("expected handler to override policy header, got %q", got)
+	}
+
+	if got := res.Header("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("expected policy header nosniff, got %q", got)
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/json", nil))
+	if res.Header("X-Frame-Options") != "DENY" || res.Header("Server") != "" {
+		t.Errorf("unexpected headers %v", res.Result().Header)
+	}
+}
+
+func TestRemoveHeaderOnWrite(t *testing.T) {
+	r := rex.NewRouter(rex.WithServerTiming(true))
+	r.GET("/", func(c *rex.Context) error {
+		c.RemoveHeaderOnWrite("X-Debug")
+		c.SetHeader("X-Debug", "internal")
+		c.SetHeader("X-Keep", "yes")
+		return c.String("ok")
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.Header("X-Debug") != "" {
+		t.Errorf("expected X-Debug to be removed, got %q", res.Header("X-Debug"))
+	}
+
+	if res.Header("X-Keep") != "yes" || res.Header("Server-Timing") == "" {
+		t.Errorf("expected other headers and earlier hooks to be kept, got %v", res.Result().Header)
+	}
+}
+
+func TestBeforeWriteHeader(t *testing.T) {
+	r := rex.NewRouter()
+
+	var seen int
+	r.GET("/ok", func(c *rex.Context) error {
+		c.BeforeWriteHeader(func() error {
+			seen = c.Status()
+			c.SetHeader("X-Checked", "1")
+			return nil
+		})
+		c.WriteHeader(http.StatusAccepted)
+		return c.String("accepted")
+	})
+
+	r.GET("/rejected", func(c *rex.Context) error {
+		c.BeforeWriteHeader(func() error {
+			return errors.New("commit failed")
+		})
+
+		if err := c.String("done"); err == nil {
+			t.Error("expected the write to fail")
+		}
+		return nil
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/ok", nil))
+	if seen != http.StatusAccepted || res.Header("X-Checked") != "1" || res.BodyString() != "accepted" {
+		t.Errorf("expected the hook to see the status and set headers, got %d %q %q", seen, res.Header("X-Checked"), res.BodyString())
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/rejected", nil))
+	if res.Code != http.StatusInternalServerError || strings.Contains(res.BodyString(), "done") {
+		t.Errorf("expected the error handler to answer, got %d %q", res.Code, res.BodyString())
+	}
+}
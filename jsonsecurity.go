@@ -0,0 +1,53 @@
+package rex
+
+import "strconv"
+
+// JSONNoSniff sets X-Content-Type-Options: nosniff on the responses of c.JSON and the
+// helpers built on it like c.JSONFields and Respond().JSON, without loading a security
+// middleware on every route. Like Content-Type, the header is only sent if the handler
+// did not write the status before. It is off by default.
+func JSONNoSniff(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.jsonNoSniff = enabled
+	}
+}
+
+// JSONPrefix prepends prefix to the bodies of c.JSON and the helpers built on it,
+// e.g the ")]}',\n" anti-JSON-hijacking prefix expected by some legacy clients, which strip
+// it before parsing. Content-Length includes the prefix. An empty prefix, the default, disables it.
+//
+// Streamed bodies, like NDJSON or server-sent events written with c.StreamingWriter,
+// are not single JSON documents and never get the prefix.
+func JSONPrefix(prefix string) RouterOption {
+	return func(r *Router) {
+		r.jsonPrefix = []byte(prefix)
+	}
+}
+
+// writeJSON sends the encoded JSON document data with the headers and prefix configured on the router.
+// A non-zero status is sent after the headers, status 0 leaves it to the first write.
+func (c *Context) writeJSON(status int, data []byte) error {
+	var prefix []byte
+	header := c.Response.Header()
+	if r := c.router; r != nil {
+		prefix = r.jsonPrefix
+		if r.jsonNoSniff {
+			header.Set("X-Content-Type-Options", "nosniff")
+		}
+	}
+
+	header.Set("Content-Type", "application/json")
+	header.Set("Content-Length", strconv.Itoa(len(prefix)+len(data)))
+	if status != 0 {
+		c.Response.WriteHeader(status)
+	}
+
+	if len(prefix) > 0 {
+		if _, err := c.Response.Write(prefix); err != nil {
+			return err
+		}
+	}
+
+	_, err := c.Response.Write(data)
+	return err
+}
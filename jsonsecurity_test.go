@@ -0,0 +1,115 @@
+package rex_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+const hijackPrefix = ")]}',\n"
+
+func newJSONRouter(options ...rex.RouterOption) *rex.Router {
+	r := rex.NewRouter(options...)
+	r.GET("/user", func(c *rex.Context) error {
+		return c.JSON(rex.Map{"id": 1, "name": "Ada"})
+	})
+	r.GET("/fields", func(c *rex.Context) error {
+		return c.JSONFields(rex.Map{"id": 1, "name": "Ada"}, []string{"id"})
+	})
+	r.GET("/events", func(c *rex.Context) error {
+		c.SetHeader("Content-Type", "application/x-ndjson")
+		w := c.StreamingWriter()
+		for i := range 2 {
+			if err := json.NewEncoder(w).Encode(rex.Map{"n": i}); err != nil {
+				return err
+			}
+		}
+		return w.Flush()
+	})
+	return r
+}
+
+func TestJSONSecurityDisabledByDefault(t *testing.T) {
+	r := newJSONRouter()
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/user", nil))
+
+	if res.Header("X-Content-Type-Options") != "" {
+		t.Errorf("expected no nosniff header by default, got %q", res.Header("X-Content-Type-Options"))
+	}
+
+	if body := res.BodyString(); body != `{"id":1,"name":"Ada"}`+"\n" {
+		t.Errorf("expected no prefix by default, got %q", body)
+	}
+}
+
+func TestJSONNoSniff(t *testing.T) {
+	r := newJSONRouter(rex.JSONNoSniff(true))
+
+	for _, path := range []string{"/user", "/fields"} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Header("X-Content-Type-Options") != "nosniff" {
+			t.Errorf("GET %s: expected nosniff, got %q", path, res.Header("X-Content-Type-Options"))
+		}
+	}
+}
+
+func TestJSONNoSniffErrors(t *testing.T) {
+	r := rex.NewRouter(rex.JSONNoSniff(true))
+	r.GET("/users", func(c *rex.Context) error {
+		return rex.ParamError{Source: "query", Key: "page", Value: "x", Err: strconv.ErrSyntax}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users?page=x", nil)
+	req.Header.Set("Accept", "application/json")
+	res := r.Test(req)
+
+	if res.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", res.Code)
+	}
+
+	if res.Header("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("expected nosniff on the JSON error, got %q", res.Header("X-Content-Type-Options"))
+	}
+
+	if res.Header("Content-Type") != "application/json" {
+		t.Errorf("expected a JSON content type, got %q", res.Header("Content-Type"))
+	}
+}
+
+func TestJSONPrefix(t *testing.T) {
+	r := newJSONRouter(rex.JSONPrefix(hijackPrefix))
+
+	for path, want := range map[string]string{
+		"/user":   `{"id":1,"name":"Ada"}` + "\n",
+		"/fields": `{"id":1}` + "\n",
+	} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		body := res.BodyString()
+		if body != hijackPrefix+want {
+			t.Errorf("GET %s: expected the prefixed body, got %q", path, body)
+		}
+
+		if res.Header("Content-Length") != strconv.Itoa(len(body)) {
+			t.Errorf("GET %s: expected Content-Length %d to include the prefix, got %q", path, len(body), res.Header("Content-Length"))
+		}
+
+		var v map[string]any
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(body, hijackPrefix)), &v); err != nil {
+			t.Errorf("GET %s: expected JSON after the prefix: %v", path, err)
+		}
+	}
+}
+
+func TestJSONPrefixSkipsStreams(t *testing.T) {
+	r := newJSONRouter(rex.JSONPrefix(hijackPrefix), rex.JSONNoSniff(true))
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/events", nil))
+
+	if body := res.BodyString(); body != "{\"n\":0}\n{\"n\":1}\n" {
+		t.Errorf("expected the NDJSON stream without prefix, got %q", body)
+	}
+}
@@ -0,0 +1,53 @@
+package rex
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// NotModifiedSince sets the Last-Modified header to lastModified and answers 304 Not Modified
+// if the If-Modified-Since header of a GET or HEAD request is not older, so that handlers can
+// skip expensive work:
+//
+//	if c.NotModifiedSince(doc.UpdatedAt) {
+//		return nil
+//	}
+//
+// Times are compared at second precision like HTTP dates. If-Modified-Since is ignored if the
+// request has If-None-Match or Cache-Control: no-cache, and a zero lastModified sets nothing.
+func (c *Context) NotModifiedSince(lastModified time.Time) bool {
+	if lastModified.IsZero() {
+		return false
+	}
+
+	lastModified = lastModified.UTC().Truncate(time.Second)
+	c.SetHeader("Last-Modified", lastModified.Format(http.TimeFormat))
+
+	req := c.Request
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+
+	if req.Header.Get("If-None-Match") != "" || strings.Contains(req.Header.Get("Cache-Control"), "no-cache") {
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil || lastModified.After(since) {
+		return false
+	}
+
+	c.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// RenderIfModified renders the template like Render with a Last-Modified header, e.g the
+// UpdatedAt of the record shown by the page. A request whose copy is up to date gets
+// 304 Not Modified without executing the template, see NotModifiedSince.
+func (c *Context) RenderIfModified(name string, data Map, lastModified time.Time) error {
+	if c.NotModifiedSince(lastModified) {
+		return nil
+	}
+	return c.Render(name, data)
+}
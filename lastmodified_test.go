@@ -0,0 +1,103 @@
+package rex_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestRenderIfModified(t *testing.T) {
+	var executions int
+	tmpl := template.Must(template.New("page.html").Funcs(template.FuncMap{
+		"count": func() string { executions++; return "" },
+	}).Parse(`{{ count }}{{ .title }}`))
+
+	updatedAt := time.Date(2024, 5, 1, 10, 30, 15, 500_000_000, time.UTC)
+	r := rex.NewRouter(rex.WithTemplates(tmpl), rex.BaseLayout("page.html"))
+	r.GET("/docs", func(c *rex.Context) error {
+		return c.RenderIfModified("page.html", rex.Map{"title": "Docs"}, updatedAt)
+	})
+
+	get := func(header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get()
+	lastModified := w.Header().Get("Last-Modified")
+	if w.Code != http.StatusOK || w.Body.String() != "Docs" || lastModified != "Wed, 01 May 2024 10:30:15 GMT" {
+		t.Fatalf("expected the page with Last-Modified, got %d %q %q", w.Code, w.Body.String(), lastModified)
+	}
+
+	rendered := executions
+	w = get("If-Modified-Since", lastModified)
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 || executions != rendered {
+		t.Errorf("expected 304 without executing the template, got %d %q", w.Code, w.Body.String())
+	}
+
+	w = get("If-Modified-Since", lastModified, "Cache-Control", "no-cache")
+	if w.Code != http.StatusOK || executions == rendered {
+		t.Errorf("expected no-cache to re-render, got %d", w.Code)
+	}
+
+	rendered = executions
+	updatedAt = updatedAt.Add(time.Minute)
+	w = get("If-Modified-Since", lastModified)
+	if w.Code != http.StatusOK || w.Body.String() != "Docs" || executions == rendered {
+		t.Errorf("expected a newer timestamp to re-render, got %d %q", w.Code, w.Body.String())
+	}
+
+	if got := w.Header().Get("Last-Modified"); got != "Wed, 01 May 2024 10:31:15 GMT" {
+		t.Errorf("expected the new Last-Modified, got %q", got)
+	}
+}
+
+func TestNotModifiedSince(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	r := rex.NewRouter()
+	report := func(c *rex.Context) error {
+		if c.NotModifiedSince(updatedAt) {
+			return nil
+		}
+		return c.String("report")
+	}
+	r.GET("/report", report)
+	r.POST("/report", report)
+
+	tests := []struct {
+		name   string
+		method string
+		header map[string]string
+		status int
+	}{
+		{"older copy", http.MethodGet, map[string]string{"If-Modified-Since": "Tue, 30 Apr 2024 10:00:00 GMT"}, http.StatusOK},
+		{"same second", http.MethodGet, map[string]string{"If-Modified-Since": "Wed, 01 May 2024 10:00:00 GMT"}, http.StatusNotModified},
+		{"newer copy", http.MethodHead, map[string]string{"If-Modified-Since": "Thu, 02 May 2024 10:00:00 GMT"}, http.StatusNotModified},
+		{"invalid date", http.MethodGet, map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		{"etag wins", http.MethodGet, map[string]string{"If-Modified-Since": "Wed, 01 May 2024 10:00:00 GMT", "If-None-Match": `"abc"`}, http.StatusOK},
+		{"unsafe method", http.MethodPost, map[string]string{"If-Modified-Since": "Wed, 01 May 2024 10:00:00 GMT"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/report", nil)
+		for k, v := range tt.header {
+			req.Header.Set(k, v)
+		}
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.status, w.Code)
+		}
+	}
+}
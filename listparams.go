@@ -0,0 +1,145 @@
+package rex
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrNotAllowed is wrapped by ParamError for sort and filter fields missing from the allowlist.
+var ErrNotAllowed = errors.New("field is not allowed")
+
+// QueryMap collects the query values with keys of the form prefix[key] into a map keyed by key.
+// Brackets may be URL-encoded (%5B and %5D). If a key is repeated, the first value is used.
+//
+// Example:
+//
+//	// ?filter[status]=active&filter[created_after]=2024-01-01
+//	filters := c.QueryMap("filter") // map[status:active created_after:2024-01-01]
+func (c *Context) QueryMap(prefix string) map[string]string {
+	m := make(map[string]string)
+	for key, values := range c.queryValues() {
+		name, ok := bracketKey(key, prefix)
+		if !ok || len(values) == 0 {
+			continue
+		}
+		m[name] = values[0]
+	}
+	return m
+}
+
+// bracketKey returns key from prefix[key].
+func bracketKey(s, prefix string) (string, bool) {
+	rest, ok := strings.CutPrefix(s, prefix+"[")
+	if !ok || !strings.HasSuffix(rest, "]") {
+		return "", false
+	}
+
+	key := strings.TrimSuffix(rest, "]")
+	if key == "" || strings.ContainsAny(key, "[]") {
+		return "", false
+	}
+	return key, true
+}
+
+// SortField is a field of the sort query parameter.
+type SortField struct {
+	Field string // Field name from the allowlist.
+	Desc  bool   // Descending order, requested with a leading "-".
+}
+
+// SortParams parses the comma-separated "sort" query parameter e.g ?sort=-created_at,name.
+// A leading "-" sorts the field in descending order. Fields not in allowed fail with a ParamError
+// wrapping ErrNotAllowed, which the default error handler answers with 400 Bad Request.
+// Repeated fields are ignored. It returns nil if the parameter is absent.
+func (c *Context) SortParams(allowed ...string) ([]SortField, error) {
+	raw := c.Query("sort")
+	if raw == "" {
+		return nil, nil
+	}
+
+	var fields []SortField
+	seen := make(map[string]bool)
+	for _, spec := range strings.Split(raw, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		field := SortField{Field: spec}
+		if name, ok := strings.CutPrefix(spec, "-"); ok {
+			field = SortField{Field: name, Desc: true}
+		} else if name, ok := strings.CutPrefix(spec, "+"); ok {
+			field.Field = name
+		}
+
+		if !slices.Contains(allowed, field.Field) {
+			return nil, ParamError{Source: "query", Key: "sort", Value: field.Field, Err: ErrNotAllowed}
+		}
+
+		if !seen[field.Field] {
+			seen[field.Field] = true
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// FilterType is the type a filter value is parsed as by FilterParams.
+type FilterType int
+
+const (
+	FilterString FilterType = iota // The raw string.
+	FilterInt                      // An int.
+	FilterBool                     // A bool as accepted by strconv.ParseBool.
+	FilterTime                     // A time.Time in the request timezone, see ParseTime.
+)
+
+// FilterParams parses the filter[key]=value query parameters with the type of each key in allowed.
+// The values in the returned map are string, int, bool or time.Time accordingly.
+// Unknown keys fail with a ParamError wrapping ErrNotAllowed and invalid values with a ParamError
+// wrapping the parse error, both answered with 400 Bad Request by the default error handler.
+//
+// Example:
+//
+//	filters, err := c.FilterParams(map[string]rex.FilterType{
+//		"status":        rex.FilterString,
+//		"created_after": rex.FilterTime,
+//	})
+func (c *Context) FilterParams(allowed map[string]FilterType) (map[string]any, error) {
+	raw := c.QueryMap("filter")
+	filters := make(map[string]any, len(raw))
+
+	for key, value := range raw {
+		paramKey := fmt.Sprintf("filter[%s]", key)
+
+		typ, ok := allowed[key]
+		if !ok {
+			return nil, ParamError{Source: "query", Key: paramKey, Value: value, Err: ErrNotAllowed}
+		}
+
+		var (
+			v   any
+			err error
+		)
+
+		switch typ {
+		case FilterInt:
+			v, err = parseParam("query", paramKey, value, strconv.Atoi)
+		case FilterBool:
+			v, err = parseParam("query", paramKey, value, strconv.ParseBool)
+		case FilterTime:
+			v, err = parseParam("query", paramKey, value, parseTimeLayout(c.Timezone()))
+		default:
+			v = value
+		}
+
+		if err != nil {
+			return nil, err
+		}
+		filters[key] = v
+	}
+	return filters, nil
+}
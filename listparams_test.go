@@ -0,0 +1,129 @@
+package rex_test
+
+import (
+	"errors"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestQueryMap(t *testing.T) {
+	r := rex.NewRouter()
+	var got map[string]string
+	r.GET("/", func(c *rex.Context) error {
+		got = c.QueryMap("filter")
+		return nil
+	})
+
+	// Encoded and plain brackets, repeated keys, malformed and unrelated keys.
+	target := "/?filter%5Bstatus%5D=active&filter[created_after]=2024-01-01&filter[status]=archived" +
+		"&filter[]=x&filter[a][b]=y&filterx=z&sort=name"
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+
+	want := map[string]string{"status": "active", "created_after": "2024-01-01"}
+	if !maps.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestSortParams(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []rex.SortField
+		err   bool
+	}{
+		{"", nil, false},
+		{"sort=-created_at,name", []rex.SortField{{Field: "created_at", Desc: true}, {Field: "name"}}, false},
+		{"sort=name,,-name", []rex.SortField{{Field: "name"}}, false},
+		{"sort=-password", nil, true},
+		{"sort=name%3Bdrop%20table%20users", nil, true},
+	}
+
+	for _, tt := range tests {
+		r := rex.NewRouter()
+		var (
+			got []rex.SortField
+			err error
+		)
+		r.GET("/", func(c *rex.Context) error {
+			got, err = c.SortParams("created_at", "name")
+			return err
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+tt.query, nil))
+
+		if tt.err {
+			var pe rex.ParamError
+			if !errors.As(err, &pe) || !errors.Is(err, rex.ErrNotAllowed) || pe.Key != "sort" {
+				t.Errorf("%q: expected a ParamError wrapping ErrNotAllowed, got %v", tt.query, err)
+			}
+
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("%q: expected 400, got %d", tt.query, w.Code)
+			}
+			continue
+		}
+
+		if err != nil || !slices.Equal(got, tt.want) {
+			t.Errorf("%q: expected %v, got %v (%v)", tt.query, tt.want, got, err)
+		}
+	}
+}
+
+func TestFilterParams(t *testing.T) {
+	allowed := map[string]rex.FilterType{
+		"status":        rex.FilterString,
+		"age":           rex.FilterInt,
+		"active":        rex.FilterBool,
+		"created_after": rex.FilterTime,
+	}
+
+	run := func(query string) (map[string]any, error, int) {
+		r := rex.NewRouter()
+		var (
+			got map[string]any
+			err error
+		)
+		r.GET("/", func(c *rex.Context) error {
+			got, err = c.FilterParams(allowed)
+			return err
+		})
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/?"+query, nil))
+		return got, err, w.Code
+	}
+
+	got, err, _ := run("filter[status]=active&filter%5Bage%5D=30&filter[active]=true&filter[created_after]=2024-01-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got["status"] != "active" || got["age"] != 30 || got["active"] != true {
+		t.Errorf("unexpected filters %v", got)
+	}
+
+	created, ok := got["created_after"].(time.Time)
+	if !ok || created.Year() != 2024 || created.Month() != time.January || created.Day() != 2 {
+		t.Errorf("expected a parsed time, got %v", got["created_after"])
+	}
+
+	for _, query := range []string{"filter[role]=admin", "filter[age]=old", "filter[active]=maybe"} {
+		_, err, code := run(query)
+
+		var pe rex.ParamError
+		if !errors.As(err, &pe) || code != http.StatusBadRequest {
+			t.Errorf("%q: expected a 400 ParamError, got %v (%d)", query, err, code)
+		}
+	}
+
+	if _, err, _ := run("filter[role]=admin"); !errors.Is(err, rex.ErrNotAllowed) {
+		t.Errorf("expected unknown filters to wrap ErrNotAllowed, got %v", err)
+	}
+}
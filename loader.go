@@ -0,0 +1,104 @@
+package rex
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+// ErrNotFound can be returned by handlers and loaders registered with Group.Load
+// when the requested entity does not exist. The default error handler responds with 404.
+var ErrNotFound = errors.New("rex: not found")
+
+// Loader loads a value shared by all routes of a group, e.g the organization in /orgs/{orgID}.
+type Loader func(c *Context) (any, error)
+
+// LoadError is returned when a loader registered with Group.Load fails.
+type LoadError struct {
+	Key string // Key the value would have been stored under.
+	Err error  // Error returned by the loader.
+}
+
+// Error implements the error interface.
+func (e LoadError) Error() string {
+	return fmt.Sprintf("load %v: %v", e.Key, e.Err)
+}
+
+// Unwrap returns the loader error.
+func (e LoadError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns 404 if the entity does not exist (ErrNotFound or sql.ErrNoRows)
+// and 500 for other errors.
+func (e LoadError) Status() int {
+	if errors.Is(e.Err, ErrNotFound) || errors.Is(e.Err, sql.ErrNoRows) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// Load registers a middleware that runs loader before every route in the group
+// and stores the result under key. Handlers retrieve it with c.MustGet(key) or Loaded.
+// If the loader returns ErrNotFound, sql.ErrNoRows or a nil value, the request is
+// answered with 404 Not Found and the handler never runs. Other errors go to the
+// error handler as a LoadError with status 500.
+//
+// Loaders run in the order they were registered, so a loader can use values loaded
+// by the parent group or an earlier Load. Nested groups inherit the loaders registered
+// before they are created.
+//
+// Example:
+//
+//	orgs := r.Group("/orgs/{orgID}").Load("org", func(c *rex.Context) (any, error) {
+//		return db.GetOrg(c.ParamInt("orgID"))
+//	})
+//	orgs.GET("/members", func(c *rex.Context) error {
+//		org := rex.Loaded[*Org](c, "org")
+//		...
+//	})
+func (g *Group) Load(key string, loader Loader) *Group {
+	g.Use(func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			value, err := loader(c)
+			if err == nil && isNil(value) {
+				err = ErrNotFound
+			}
+
+			if err != nil {
+				return LoadError{Key: key, Err: err}
+			}
+
+			c.Set(key, value)
+			return next(c)
+		}
+	})
+	return g
+}
+
+// isNil reports whether v is nil or a nil pointer.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// Loaded returns the value stored under key by Group.Load.
+// It panics if the key does not exist or the value is not a T, which means
+// the route is not part of the group that loads it.
+func Loaded[T any](c *Context, key string) T {
+	value, ok := c.MustGet(key).(T)
+	if !ok {
+		var zero T
+		panic(fmt.Sprintf("rex: loaded value %q is %T, not %T", key, c.MustGet(key), zero))
+	}
+	return value
+}
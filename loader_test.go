@@ -0,0 +1,108 @@
+package rex_test
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+type org struct {
+	ID   int64
+	Name string
+}
+
+type project struct {
+	Org  *org
+	Slug string
+}
+
+func TestGroupLoad(t *testing.T) {
+	orgsDB := map[int64]*org{1: {ID: 1, Name: "acme"}}
+
+	r := rex.NewRouter()
+	orgs := r.Group("/orgs/{orgID}").Load("org", func(c *rex.Context) (any, error) {
+		if o, ok := orgsDB[int64(c.ParamInt("orgID"))]; ok {
+			return o, nil
+		}
+		return nil, rex.ErrNotFound
+	})
+
+	handled := false
+	orgs.GET("/members", func(c *rex.Context) error {
+		handled = true
+		return c.String(rex.Loaded[*org](c, "org").Name)
+	})
+
+	// The nested group depends on the org loaded by the parent.
+	projects := orgs.Group("/projects/{slug}").Load("project", func(c *rex.Context) (any, error) {
+		o := rex.Loaded[*org](c, "org")
+		if c.Param("slug") != "web" {
+			return nil, sql.ErrNoRows
+		}
+		return &project{Org: o, Slug: "web"}, nil
+	})
+
+	projects.GET("/info", func(c *rex.Context) error {
+		p := rex.Loaded[*project](c, "project")
+		return c.String(p.Org.Name + "/" + p.Slug)
+	})
+
+	broken := r.Group("/broken").Load("thing", func(c *rex.Context) (any, error) {
+		return nil, errors.New("database down")
+	})
+	broken.GET("/info", func(c *rex.Context) error {
+		handled = true
+		return nil
+	})
+
+	tests := []struct {
+		path    string
+		status  int
+		body    string
+		handled bool
+	}{
+		{"/orgs/1/members", http.StatusOK, "acme", true},
+		{"/orgs/2/members", http.StatusNotFound, "", false},
+		{"/orgs/1/projects/web/info", http.StatusOK, "acme/web", false},
+		{"/orgs/1/projects/api/info", http.StatusNotFound, "", false},
+		{"/orgs/2/projects/web/info", http.StatusNotFound, "", false},
+		{"/broken/info", http.StatusInternalServerError, "", false},
+	}
+
+	for _, tt := range tests {
+		handled = false
+		res := r.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if res.Status() != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.path, tt.status, res.Status())
+		}
+
+		if tt.body != "" && res.BodyString() != tt.body {
+			t.Errorf("%s: expected body %q, got %q", tt.path, tt.body, res.BodyString())
+		}
+
+		if handled != tt.handled {
+			t.Errorf("%s: expected handler ran=%v, got %v", tt.path, tt.handled, handled)
+		}
+	}
+}
+
+func TestLoadedWrongType(t *testing.T) {
+	r := rex.NewRouter()
+	g := r.Group("/g").Load("value", func(c *rex.Context) (any, error) { return "text", nil })
+
+	var panicked bool
+	g.GET("/info", func(c *rex.Context) error {
+		defer func() { panicked = recover() != nil }()
+		rex.Loaded[int](c, "value")
+		return nil
+	})
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/g/info", nil))
+	if !panicked {
+		t.Error("expected Loaded to panic for a value of the wrong type")
+	}
+}
@@ -0,0 +1,143 @@
+package rex
+
+import (
+	"log/slog"
+	"time"
+)
+
+// LogField is a field of the request logs written by the router and the logger middleware.
+// Its value is the default key of the field.
+type LogField string
+
+// Built-in log fields.
+const (
+	LogError     LogField = "error"
+	LogLatency   LogField = "latency"
+	LogStatus    LogField = "status"
+	LogPath      LogField = "path"
+	LogMethod    LogField = "method"
+	LogRoute     LogField = "pattern"
+	LogRequestID LogField = "request_id"
+	LogIP        LogField = "ip"
+	LogBytes     LogField = "bytes"
+
+	// Keys of the slog handlers created by rex, see Router.ReplaceLogAttr.
+	LogTime    LogField = slog.TimeKey
+	LogLevel   LogField = slog.LevelKey
+	LogMessage LogField = slog.MessageKey
+)
+
+// LogValuer computes the logged value of a field from the request.
+type LogValuer func(c *Context) slog.Value
+
+// WithLogFieldNames renames log fields, e.g to the names required by a log pipeline:
+//
+//	rex.WithLogFieldNames(map[rex.LogField]string{
+//		rex.LogStatus: "http.status",
+//		rex.LogRoute:  "http.route",
+//		rex.LogTime:   "ts",
+//	})
+//
+// Fields without a mapping keep their default key. The names are used by the error handler,
+// the logger middleware and the handlers created by rex. The time, level and message keys
+// of a logger passed to WithLogger are only renamed if its handler uses Router.ReplaceLogAttr.
+func WithLogFieldNames(names map[LogField]string) RouterOption {
+	return func(r *Router) {
+		if r.logFieldNames == nil {
+			r.logFieldNames = make(map[LogField]string, len(names))
+		}
+
+		for field, name := range names {
+			r.logFieldNames[field] = name
+		}
+	}
+}
+
+// WithLogValuer overrides how a field is computed, e.g the latency in milliseconds:
+//
+//	rex.WithLogValuer(rex.LogLatency, func(c *rex.Context) slog.Value {
+//		return slog.Float64Value(float64(c.Elapsed()) / float64(time.Millisecond))
+//	})
+func WithLogValuer(field LogField, fn LogValuer) RouterOption {
+	return func(r *Router) {
+		if r.logValuers == nil {
+			r.logValuers = make(map[LogField]LogValuer)
+		}
+		r.logValuers[field] = fn
+	}
+}
+
+// WithLogTime formats the time of the handlers created by rex with layout in loc,
+// e.g time.RFC3339Nano in time.UTC. A nil loc keeps the location of the time.
+func WithLogTime(layout string, loc *time.Location) RouterOption {
+	return func(r *Router) {
+		r.logTimeLayout = layout
+		r.logTimeLocation = loc
+	}
+}
+
+// LogFieldName returns the key of field, renamed with WithLogFieldNames.
+func (r *Router) LogFieldName(field LogField) string {
+	if name, ok := r.logFieldNames[field]; ok {
+		return name
+	}
+	return string(field)
+}
+
+// ReplaceLogAttr renames the time, level and message keys and formats the time as configured
+// with WithLogFieldNames and WithLogTime. Use it as the slog.HandlerOptions.ReplaceAttr
+// of other handlers logging requests.
+func (r *Router) ReplaceLogAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return a
+	}
+
+	switch LogField(a.Key) {
+	case LogTime:
+		if r.logTimeLayout != "" && a.Value.Kind() == slog.KindTime {
+			t := a.Value.Time()
+			if r.logTimeLocation != nil {
+				t = t.In(r.logTimeLocation)
+			}
+			a.Value = slog.StringValue(t.Format(r.logTimeLayout))
+		}
+		a.Key = r.LogFieldName(LogTime)
+	case LogLevel, LogMessage:
+		a.Key = r.LogFieldName(LogField(a.Key))
+	}
+	return a
+}
+
+// AppendLogField appends the renamed key of field and its value to the slog arguments args.
+// The value is computed with the valuer of the field if one was set with WithLogValuer.
+func (c *Context) AppendLogField(args []any, field LogField, value any) []any {
+	if c.router == nil {
+		return append(args, string(field), value)
+	}
+
+	if valuer, ok := c.router.logValuers[field]; ok {
+		value = valuer(c)
+	}
+	return append(args, c.router.LogFieldName(field), value)
+}
+
+// RequestID returns the X-Request-ID of the response, or of the request if the response has none.
+func (c *Context) RequestID() string {
+	if id := c.Response.Header().Get("X-Request-ID"); id != "" {
+		return id
+	}
+	return c.Request.Header.Get("X-Request-ID")
+}
+
+// requestLogArgs returns the slog arguments of the error handler logs.
+func (c *Context) requestLogArgs(err error) []any {
+	args := c.AppendLogField(nil, LogError, err)
+	args = c.AppendLogField(args, LogStatus, c.Status())
+	args = c.AppendLogField(args, LogMethod, c.Request.Method)
+	args = c.AppendLogField(args, LogPath, c.Request.URL.Path)
+	args = c.AppendLogField(args, LogRoute, c.Pattern())
+	if id := c.RequestID(); id != "" {
+		args = c.AppendLogField(args, LogRequestID, id)
+	}
+	return args
+}
@@ -0,0 +1,126 @@
+package rex_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/logger"
+)
+
+var pipelineNames = rex.WithLogFieldNames(map[rex.LogField]string{
+	rex.LogStatus:  "http.status",
+	rex.LogMethod:  "http.method",
+	rex.LogRoute:   "http.route",
+	rex.LogTime:    "ts",
+	rex.LogLevel:   "lvl",
+	rex.LogMessage: "msg",
+})
+
+// logLines decodes the JSON log lines of buf.
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestLogFieldNamesErrorHandler(t *testing.T) {
+	var logs bytes.Buffer
+	r := rex.NewRouter(pipelineNames,
+		rex.WithLogger(slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))))
+
+	r.GET("/orders/{id}", func(c *rex.Context) error {
+		return rex.NewError(http.StatusConflict, "order is locked")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/orders/1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	r.Test(req)
+
+	lines := logLines(t, &logs)
+	if len(lines) != 1 {
+		t.Fatalf("expected one log line, got %d", len(lines))
+	}
+
+	line := lines[0]
+	if line["http.status"] != float64(http.StatusConflict) || line["http.route"] != "/orders/{id}" ||
+		line["http.method"] != "GET" || line["request_id"] != "req-1" {
+		t.Errorf("expected the renamed fields, got %v", line)
+	}
+
+	if _, ok := line["status"]; ok {
+		t.Errorf("expected no default status key, got %v", line)
+	}
+}
+
+func TestLogFieldNamesLoggerMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	r := rex.NewRouter(pipelineNames, rex.WithLogTime(time.RFC3339Nano, time.UTC))
+	r.Use(logger.New(&logger.Config{Output: &logs, Format: logger.JSONFormat, Flags: logger.LOG_LATENCY | logger.LOG_ROUTE}))
+
+	r.GET("/orders/{id}", func(c *rex.Context) error {
+		return c.String("ok")
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/orders/1", nil))
+
+	line := logLines(t, &logs)[0]
+	if line["http.status"] != float64(http.StatusOK) || line["http.route"] != "/orders/{id}" || line["lvl"] != "INFO" {
+		t.Errorf("expected the renamed fields, got %v", line)
+	}
+
+	if _, ok := line["status"]; ok {
+		t.Errorf("expected no default status key, got %v", line)
+	}
+
+	ts, _ := line["ts"].(string)
+	if parsed, err := time.Parse(time.RFC3339Nano, ts); err != nil || !strings.HasSuffix(ts, "Z") || parsed.IsZero() {
+		t.Errorf("expected an RFC3339Nano UTC timestamp, got %q", ts)
+	}
+
+	if _, ok := line["latency"].(string); !ok {
+		t.Errorf("expected the default latency string, got %v", line["latency"])
+	}
+}
+
+func TestLogValuerOverridesLatency(t *testing.T) {
+	var logs, errs bytes.Buffer
+	r := rex.NewRouter(
+		rex.WithLogger(slog.New(slog.NewJSONHandler(&errs, nil))),
+		rex.WithLogValuer(rex.LogLatency, func(c *rex.Context) slog.Value {
+			return slog.Float64Value(float64(c.Elapsed()) / float64(time.Millisecond))
+		}),
+		rex.WithLogValuer(rex.LogError, func(c *rex.Context) slog.Value {
+			return slog.StringValue("redacted")
+		}),
+	)
+	r.Use(logger.New(&logger.Config{Output: &logs, Format: logger.JSONFormat, Flags: logger.LOG_LATENCY}))
+
+	r.GET("/", func(c *rex.Context) error {
+		c.String("partial")
+		return errors.New("secret failure")
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if latency, ok := logLines(t, &logs)[0]["latency"].(float64); !ok || latency < 0 {
+		t.Errorf("expected the latency in milliseconds, got %v", logs.String())
+	}
+
+	if line := logLines(t, &errs)[0]; line["error"] != "redacted" {
+		t.Errorf("expected the error valuer to apply to the router logs, got %v", line)
+	}
+}
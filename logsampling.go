@@ -0,0 +1,61 @@
+package rex
+
+import (
+	"math/rand"
+	"net/http"
+)
+
+// MetaLogSampling is the route metadata key of the rate set with route.LogSampling.
+const MetaLogSampling = "rex.log_sampling"
+
+// LogSampling logs only rate (between 0 and 1) of the successful requests of the route,
+// in the logger middleware and the debug log of the default error handler.
+// Errors and responses with a status of 400 or above are always logged.
+//
+// Example:
+//
+//	r.GET("/healthz", healthHandler).LogSampling(0.01)
+func (rt *Route) LogSampling(rate float64) *Route {
+	return rt.Meta(MetaLogSampling, min(max(rate, 0), 1))
+}
+
+// WithLogSamplingRand sets the source of the LogSampling draws, e.g rand.New(rand.NewSource(1))
+// for reproducible tests. The default is the global math/rand source.
+func WithLogSamplingRand(rnd *rand.Rand) RouterOption {
+	return func(r *Router) {
+		r.samplingRand = rnd
+	}
+}
+
+// ShouldLog reports whether the request should be logged with err, the error returned
+// by the handler. It is false only for successful requests skipped by LogSampling.
+// The sampling draw is made once per request, so all loggers agree.
+func (c *Context) ShouldLog(err error) bool {
+	if err != nil || c.rw == nil || c.rw.Status() >= http.StatusBadRequest {
+		return true
+	}
+
+	rate, ok := c.RouteMeta(MetaLogSampling)
+	if !ok {
+		return true
+	}
+
+	if c.logSample == 0 {
+		c.logSample = -1
+		if c.router.sampleDraw() < rate.(float64) {
+			c.logSample = 1
+		}
+	}
+	return c.logSample == 1
+}
+
+// sampleDraw returns a random number in [0, 1).
+func (r *Router) sampleDraw() float64 {
+	if r.samplingRand == nil {
+		return rand.Float64()
+	}
+
+	r.samplingMu.Lock()
+	defer r.samplingMu.Unlock()
+	return r.samplingRand.Float64()
+}
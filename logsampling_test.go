@@ -0,0 +1,85 @@
+package rex_test
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/logger"
+)
+
+// sampledLogs serves 100 successful and 10 failing requests to /hot and 10 to /cold and
+// returns the request logs and the debug logs of the error handler.
+func sampledLogs(seed int64) (requestLog, debugLog string) {
+	var requests, debug bytes.Buffer
+
+	r := rex.NewRouter(
+		rex.WithLogSamplingRand(rand.New(rand.NewSource(seed))),
+		rex.WithLogger(slog.New(slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}))),
+	)
+	r.Use(logger.New(&logger.Config{Output: &requests}))
+
+	r.GET("/hot", func(c *rex.Context) error {
+		if c.Query("fail") != "" {
+			return errors.New("hot route failed")
+		}
+		return c.String("ok")
+	}).LogSampling(0.2)
+
+	r.GET("/cold", func(c *rex.Context) error {
+		return c.String("ok")
+	})
+
+	for i := 0; i < 100; i++ {
+		r.Test(httptest.NewRequest(http.MethodGet, "/hot", nil))
+	}
+
+	for i := 0; i < 10; i++ {
+		r.Test(httptest.NewRequest(http.MethodGet, "/hot?fail=1", nil))
+		r.Test(httptest.NewRequest(http.MethodGet, "/cold", nil))
+	}
+	return requests.String(), debug.String()
+}
+
+func TestLogSampling(t *testing.T) {
+	requestLog, debugLog := sampledLogs(1)
+
+	hot := strings.Count(requestLog, "path=/hot")
+	if cold := strings.Count(requestLog, "path=/cold"); cold != 10 {
+		t.Errorf("expected all requests of unsampled routes to be logged, got %d", cold)
+	}
+
+	// 10 errors are always logged, about 20 of the 100 successful requests are sampled.
+	if hot <= 10 || hot >= 50 {
+		t.Errorf("expected about 30 logged requests for /hot, got %d", hot)
+	}
+
+	if errs := strings.Count(debugLog, "hot route failed"); errs != 10 {
+		t.Errorf("expected all errors in the debug log, got %d", errs)
+	}
+
+	// Both loggers make the same decision for a request.
+	if debugHot := strings.Count(debugLog, "path=/hot"); debugHot != hot {
+		t.Errorf("expected the debug log to sample like the request log, got %d and %d", debugHot, hot)
+	}
+
+	again, _ := sampledLogs(1)
+	if strings.Count(again, "path=/hot") != hot {
+		t.Error("expected the same seed to sample the same requests")
+	}
+}
+
+func TestLogSamplingClampsRate(t *testing.T) {
+	r := rex.NewRouter()
+
+	rt := r.GET("/", func(c *rex.Context) error { return nil }).LogSampling(2)
+	if rate, _ := rt.GetMeta(rex.MetaLogSampling); rate != 1.0 {
+		t.Errorf("expected the rate to be clamped to 1, got %v", rate)
+	}
+}
@@ -0,0 +1,152 @@
+// Package longpoll holds requests until data is available or a timeout elapses,
+// for clients that poll instead of using server-sent events.
+//
+// Example:
+//
+//	hub := longpoll.NewHub[Notification]()
+//
+//	r.GET("/notifications", func(c *rex.Context) error {
+//		n, ok, err := hub.Wait(c, c.Query("user"), 30*time.Second)
+//		if err != nil {
+//			return nil // The client is gone.
+//		}
+//
+//		if !ok {
+//			c.WriteHeader(http.StatusNoContent)
+//			return nil
+//		}
+//		return c.JSON(n)
+//	})
+//
+//	// Elsewhere:
+//	hub.Publish(userID, Notification{Text: "Your report is ready"})
+package longpoll
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// WriteMargin is the time left to write the response after a wait times out.
+// Wait extends the write deadline of the connection to the timeout plus WriteMargin,
+// so waits longer than the server WriteTimeout are not cut off.
+var WriteMargin = 5 * time.Second
+
+// Wait blocks until a value is received from trigger, the timeout elapses,
+// the client goes away or the server starts shutting down.
+//
+// ok is true if a value was received. It is false on timeout, shutdown or if trigger
+// is closed, and the handler should respond with 204 No Content.
+// err is the request context error if the client went away, in which case
+// nothing should be written. A timeout <= 0 waits without a timeout.
+func Wait[T any](c *rex.Context, trigger <-chan T, timeout time.Duration) (value T, ok bool, err error) {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	extendWriteDeadline(c, timeout)
+
+	select {
+	case value, ok = <-trigger:
+		return value, ok, nil
+	case <-c.Request.Context().Done():
+		return value, false, c.Request.Context().Err()
+	case <-rex.ShutdownChannel(c):
+		return value, false, nil
+	case <-expired:
+		return value, false, nil
+	}
+}
+
+// extendWriteDeadline moves the write deadline past the wait.
+// Writers that do not support deadlines, like httptest.ResponseRecorder, are ignored.
+func extendWriteDeadline(c *rex.Context, timeout time.Duration) {
+	var deadline time.Time // No deadline when waiting without timeout.
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout + WriteMargin)
+	}
+	http.NewResponseController(c.Response).SetWriteDeadline(deadline)
+}
+
+// Hub delivers published values to the requests waiting on a topic.
+// The zero value is not usable, create one with NewHub.
+type Hub[T any] struct {
+	mu     sync.Mutex
+	topics map[string]map[chan T]struct{}
+}
+
+// NewHub creates an empty Hub.
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{topics: make(map[string]map[chan T]struct{})}
+}
+
+// Subscribe returns a channel that receives the values published to topic.
+// The subscription ends when unsubscribe is called or the request context is done.
+// The channel buffers one value; values published while it is full are dropped,
+// since a long poll returns after the first value.
+func (h *Hub[T]) Subscribe(c *rex.Context, topic string) (ch <-chan T, unsubscribe func()) {
+	sub := make(chan T, 1)
+
+	h.mu.Lock()
+	if h.topics[topic] == nil {
+		h.topics[topic] = make(map[chan T]struct{})
+	}
+	h.topics[topic][sub] = struct{}{}
+	h.mu.Unlock()
+
+	remove := sync.OnceFunc(func() { h.remove(topic, sub) })
+	stop := context.AfterFunc(c.Request.Context(), remove)
+	unsubscribe = func() {
+		stop()
+		remove()
+	}
+	return sub, unsubscribe
+}
+
+func (h *Hub[T]) remove(topic string, sub chan T) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.topics[topic], sub)
+	if len(h.topics[topic]) == 0 {
+		delete(h.topics, topic)
+	}
+}
+
+// Wait subscribes to topic and waits for a value like the Wait function.
+// The subscription ends when Wait returns.
+func (h *Hub[T]) Wait(c *rex.Context, topic string, timeout time.Duration) (value T, ok bool, err error) {
+	ch, unsubscribe := h.Subscribe(c, topic)
+	defer unsubscribe()
+	return Wait(c, ch, timeout)
+}
+
+// Publish sends value to the current subscribers of topic and returns how many received it.
+// It never blocks.
+func (h *Hub[T]) Publish(topic string, value T) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delivered := 0
+	for sub := range h.topics[topic] {
+		select {
+		case sub <- value:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+// Subscribers returns the number of subscribers of topic.
+func (h *Hub[T]) Subscribers(topic string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.topics[topic])
+}
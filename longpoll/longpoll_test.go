@@ -0,0 +1,216 @@
+package longpoll_test
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/longpoll"
+)
+
+type notification struct {
+	Text string `json:"text"`
+}
+
+func newRouter(hub *longpoll.Hub[notification], timeout time.Duration) *rex.Router {
+	r := rex.NewRouter()
+	r.GET("/notifications/{user}", func(c *rex.Context) error {
+		n, ok, err := hub.Wait(c, c.Param("user"), timeout)
+		if err != nil {
+			return nil
+		}
+
+		if !ok {
+			c.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+		return c.JSON(n)
+	})
+	return r
+}
+
+// waitSubscribers waits until topic has n subscribers.
+func waitSubscribers(t *testing.T, hub *longpoll.Hub[notification], topic string, n int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Subscribers(topic) != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers of %q, got %d", n, topic, hub.Subscribers(topic))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	hub := longpoll.NewHub[notification]()
+	r := newRouter(hub, 20*time.Millisecond)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/notifications/alice", nil))
+	if res.Status() != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", res.Status())
+	}
+
+	if hub.Subscribers("alice") != 0 {
+		t.Errorf("expected the subscription to end with the request")
+	}
+}
+
+func TestWaitTrigger(t *testing.T) {
+	trigger := make(chan int, 1)
+	trigger <- 42
+
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		v, ok, err := longpoll.Wait(c, trigger, time.Second)
+		if err != nil || !ok || v != 42 {
+			t.Errorf("expected 42, true, nil, got %d, %v, %v", v, ok, err)
+		}
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestPublishWakesMatchingSubscribers(t *testing.T) {
+	hub := longpoll.NewHub[notification]()
+	r := newRouter(hub, 2*time.Second)
+
+	type result struct {
+		user   string
+		status int
+		body   notification
+	}
+
+	users := []string{"alice", "alice", "bob"}
+	results := make(chan result, len(users))
+
+	var wg sync.WaitGroup
+	for _, user := range users {
+		wg.Add(1)
+		go func(user string) {
+			defer wg.Done()
+			res := r.Test(httptest.NewRequest(http.MethodGet, "/notifications/"+user, nil))
+
+			var n notification
+			json.Unmarshal(res.Body.Bytes(), &n)
+			results <- result{user, res.Status(), n}
+		}(user)
+	}
+
+	waitSubscribers(t, hub, "alice", 2)
+	waitSubscribers(t, hub, "bob", 1)
+
+	if n := hub.Publish("alice", notification{Text: "hello alice"}); n != 2 {
+		t.Errorf("expected 2 subscribers to receive the value, got %d", n)
+	}
+
+	for i := 0; i < 2; i++ {
+		select {
+		case res := <-results:
+			if res.user != "alice" || res.status != http.StatusOK || res.body.Text != "hello alice" {
+				t.Errorf("unexpected result %+v", res)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the alice subscribers to wake up")
+		}
+	}
+
+	// bob is still waiting.
+	if hub.Subscribers("bob") != 1 {
+		t.Errorf("expected bob to keep waiting")
+	}
+
+	hub.Publish("bob", notification{Text: "hello bob"})
+	wg.Wait()
+
+	if res := <-results; res.user != "bob" || res.body.Text != "hello bob" {
+		t.Errorf("unexpected result %+v", res)
+	}
+}
+
+func TestClientDisconnectReleasesSubscription(t *testing.T) {
+	hub := longpoll.NewHub[notification]()
+	returned := make(chan error, 1)
+
+	r := rex.NewRouter()
+	r.GET("/notifications/{user}", func(c *rex.Context) error {
+		_, _, err := hub.Wait(c, c.Param("user"), 10*time.Second)
+		returned <- err
+		return nil
+	})
+
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/notifications/alice", nil)
+	go http.DefaultClient.Do(req)
+
+	waitSubscribers(t, hub, "alice", 1)
+	cancel()
+
+	select {
+	case err := <-returned:
+		if err == nil {
+			t.Error("expected the context error after the client went away")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected Wait to return when the client went away")
+	}
+
+	waitSubscribers(t, hub, "alice", 0)
+	if n := hub.Publish("alice", notification{}); n != 0 {
+		t.Errorf("expected no subscribers after disconnect, got %d", n)
+	}
+}
+
+func TestSubscribeUnsubscribe(t *testing.T) {
+	hub := longpoll.NewHub[notification]()
+
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		ch, unsubscribe := hub.Subscribe(c, "news")
+		hub.Publish("news", notification{Text: "first"})
+		hub.Publish("news", notification{Text: "dropped"})
+
+		if n := <-ch; n.Text != "first" {
+			t.Errorf("expected the first value, got %q", n.Text)
+		}
+
+		unsubscribe()
+		unsubscribe()
+		if hub.Subscribers("news") != 0 {
+			t.Error("expected no subscribers after unsubscribe")
+		}
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestWaitExtendsWriteDeadline(t *testing.T) {
+	hub := longpoll.NewHub[notification]()
+	srv := rex.NewServer("", newRouter(hub, 150*time.Millisecond), rex.WithWriteTimeout(50*time.Millisecond))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+	defer srv.Close()
+
+	res, err := http.Get("http://" + ln.Addr().String() + "/notifications/alice")
+	if err != nil {
+		t.Fatalf("expected the response to outlive the write timeout: %v", err)
+	}
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusNoContent {
+		t.Errorf("expected status 204, got %d", res.StatusCode)
+	}
+}
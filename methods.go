@@ -0,0 +1,91 @@
+package rex
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// AutoHEAD sets whether GET routes also serve HEAD requests, without the body. It is enabled
+// by default. When disabled, HEAD requests to GET routes get 405 Method Not Allowed unless
+// a HEAD route is registered for the pattern, and HEAD is no longer listed by Methods,
+// MethodsByPrefix and AllowedMethods for GET routes. Use it for GET handlers with side effects.
+func AutoHEAD(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.autoHEAD = enabled
+	}
+}
+
+// sortedMethods sorts and deduplicates methods, adding HEAD when GET is present
+// because GET routes also serve HEAD requests, unless AutoHEAD is disabled.
+func (r *Router) sortedMethods(methods []string) []string {
+	if r.autoHEAD && slices.Contains(methods, http.MethodGet) {
+		methods = append(methods, http.MethodHead)
+	}
+	slices.Sort(methods)
+	return slices.Compact(methods)
+}
+
+// Methods returns the sorted methods registered for the exact pattern e.g "/users/{id}".
+// HEAD is included when GET is registered, see AutoHEAD. It returns nil if no route uses the pattern.
+func (r *Router) Methods(pattern string) []string {
+	normalized := normalizePattern(pattern, false)
+
+	var methods []string
+	for _, route := range r.routes {
+		if route.pattern == pattern || route.pattern == normalized {
+			methods = append(methods, route.method)
+		}
+	}
+
+	if methods == nil {
+		return nil
+	}
+	return r.sortedMethods(methods)
+}
+
+// MethodsByPrefix returns the sorted methods of every pattern that starts with prefix,
+// keyed by pattern. Use it to list the routes of a group.
+func (r *Router) MethodsByPrefix(prefix string) map[string][]string {
+	byPattern := make(map[string][]string)
+	for _, route := range r.routes {
+		if strings.HasPrefix(route.pattern, prefix) {
+			byPattern[route.pattern] = append(byPattern[route.pattern], route.method)
+		}
+	}
+
+	for pattern, methods := range byPattern {
+		byPattern[pattern] = r.sortedMethods(methods)
+	}
+	return byPattern
+}
+
+// Methods returns the sorted methods registered for the pattern relative to the group prefix.
+func (g *Group) Methods(pattern string) []string {
+	return g.router.Methods(g.prefix + pattern)
+}
+
+// AllowedMethods returns the sorted methods for which a route matches the request path.
+// It uses the same matching rules as the router, so it can be used in custom
+// OPTIONS and 405 handlers to build the Allow header.
+func (c *Context) AllowedMethods() []string {
+	var candidates []string
+	for _, route := range c.router.routes {
+		candidates = append(candidates, route.method)
+	}
+	slices.Sort(candidates)
+	candidates = slices.Compact(candidates)
+
+	var methods []string
+	for _, method := range candidates {
+		probe := &http.Request{Method: method, URL: c.Request.URL, Host: c.Request.Host, Header: http.Header{}}
+		if _, pattern := c.router.mux.Handler(probe); strings.HasPrefix(pattern, method+" ") {
+			methods = append(methods, method)
+		}
+	}
+
+	if methods == nil {
+		return nil
+	}
+	return c.router.sortedMethods(methods)
+}
@@ -0,0 +1,152 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestRouterMethods(t *testing.T) {
+	r := rex.NewRouter()
+	handler := func(c *rex.Context) error { return nil }
+
+	r.GET("/api/users", handler)
+	r.PUT("/api/users/{id}", handler)
+
+	api := r.Group("/api")
+	api.POST("/users", handler)
+	api.GET("/users/{id}", handler)
+	api.DELETE("/users/{id}", handler)
+
+	tests := []struct {
+		pattern string
+		want    []string
+	}{
+		{"/api/users", []string{"GET", "HEAD", "POST"}},
+		{"/api/users/{id}", []string{"DELETE", "GET", "HEAD", "PUT"}},
+		{"/api/missing", nil},
+	}
+
+	for _, tt := range tests {
+		if got := r.Methods(tt.pattern); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Methods(%q) = %v, want %v", tt.pattern, got, tt.want)
+		}
+	}
+
+	if got := api.Methods("/users"); !reflect.DeepEqual(got, []string{"GET", "HEAD", "POST"}) {
+		t.Errorf("group Methods = %v", got)
+	}
+
+	byPrefix := r.MethodsByPrefix("/api/users/")
+	if len(byPrefix) != 1 || !reflect.DeepEqual(byPrefix["/api/users/{id}"], []string{"DELETE", "GET", "HEAD", "PUT"}) {
+		t.Errorf("unexpected MethodsByPrefix result %v", byPrefix)
+	}
+}
+
+func TestContextAllowedMethods(t *testing.T) {
+	r := rex.NewRouter()
+	handler := func(c *rex.Context) error { return nil }
+
+	r.GET("/users/{id}", handler)
+	r.Group("/users").DELETE("/{id}", handler)
+
+	r.OPTIONS("/users/{id}", func(c *rex.Context) error {
+		c.SetHeader("Allow", strings.Join(c.AllowedMethods(), ", "))
+		return c.WriteHeader(http.StatusNoContent)
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodOptions, "/users/42", nil))
+	if res.Status() != http.StatusNoContent {
+		t.Fatalf("expected status 204, got %d", res.Status())
+	}
+
+	if got := res.Header("Allow"); got != "DELETE, GET, HEAD, OPTIONS" {
+		t.Errorf("expected Allow: DELETE, GET, HEAD, OPTIONS, got %q", got)
+	}
+}
+
+func TestAutoHEADDisabled(t *testing.T) {
+	r := rex.NewRouter(rex.AutoHEAD(false))
+	r.GET("/flags", func(c *rex.Context) error { return c.String("flags") })
+	r.GET("/status", func(c *rex.Context) error { return c.String("ok") })
+	r.HEAD("/status", func(c *rex.Context) error { return c.WriteHeader(http.StatusNoContent) })
+
+	if res := r.Test(httptest.NewRequest(http.MethodHead, "/flags", nil)); res.Code != http.StatusMethodNotAllowed {
+		t.Errorf("expected HEAD on a GET route to be rejected, got %d", res.Code)
+	}
+
+	if res := r.Test(httptest.NewRequest(http.MethodHead, "/status", nil)); res.Code != http.StatusNoContent {
+		t.Errorf("expected the explicit HEAD route, got %d", res.Code)
+	}
+
+	if got := r.Methods("/flags"); !reflect.DeepEqual(got, []string{http.MethodGet}) {
+		t.Errorf("expected only GET, got %v", got)
+	}
+
+	// Enabled by default.
+	r = rex.NewRouter()
+	r.GET("/flags", func(c *rex.Context) error { return c.String("flags") })
+	if res := r.Test(httptest.NewRequest(http.MethodHead, "/flags", nil)); res.Code != http.StatusOK || res.Body.Len() != 0 {
+		t.Errorf("expected HEAD to be served without a body, got %d %q", res.Code, res.BodyString())
+	}
+}
+
+func TestCatchAllRoute(t *testing.T) {
+	r := rex.NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom 404"))
+	})
+
+	r.POST("/webhooks/{path...}", func(c *rex.Context) error {
+		path := c.Param("path")
+		if strings.HasPrefix(path, "unknown") {
+			return rex.ErrNotFound
+		}
+		return c.String("path=" + path)
+	})
+
+	r.GET("/files/{path...}", func(c *rex.Context) error {
+		return c.String("file=" + c.Param("path"))
+	})
+
+	r.GET("/old", func(c *rex.Context) error {
+		return c.RedirectRoute("/files/{path...}", rex.RedirectOptions{Params: map[string]string{"path": "docs/guide.md"}})
+	})
+
+	tests := []struct {
+		target string
+		status int
+		body   string
+	}{
+		{"/webhooks/github/push/123", http.StatusOK, "path=github/push/123"},
+		{"/webhooks/", http.StatusOK, "path="},
+		{"/webhooks/a%2Fb/c", http.StatusOK, "path=a%2Fb/c"},
+		{"/webhooks/unknown/x", http.StatusNotFound, "custom 404"},
+		{"/old", http.StatusSeeOther, "file=docs/guide.md"},
+	}
+
+	for _, tt := range tests {
+		method := http.MethodPost
+		if !strings.HasPrefix(tt.target, "/webhooks/") {
+			method = http.MethodGet
+		}
+
+		res := r.Test(httptest.NewRequest(method, tt.target, nil))
+		if res.Code != tt.status || res.BodyString() != tt.body {
+			t.Errorf("%s: expected %d %q, got %d %q", tt.target, tt.status, tt.body, res.Code, res.BodyString())
+		}
+	}
+
+	found := false
+	for _, route := range r.RegisteredRoutes() {
+		found = found || (route.Method == http.MethodPost && route.Path == "/webhooks/{path...}")
+	}
+	if !found {
+		t.Errorf("expected the catch-all in RegisteredRoutes, got %v", r.RegisteredRoutes())
+	}
+}
@@ -24,6 +24,7 @@ func BasicAuth(username, password string, realm ...string) rex.Middleware {
 			if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(username)) != 1 ||
 				subtle.ConstantTimeCompare([]byte(pass), []byte(password)) != 1 {
 
+				ctx.AssertNotWritten("auth.BasicAuth")
 				ctx.Response.Header().Set("WWW-Authenticate", fmt.Sprintf(`Basic realm="%s"`, defaultRealm))
 				return ctx.WriteHeader(http.StatusUnauthorized)
 			}
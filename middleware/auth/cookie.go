@@ -8,6 +8,7 @@ package auth
 import (
 	"encoding/gob"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/abiiranathan/rex"
@@ -15,13 +16,24 @@ import (
 	"github.com/pkg/errors"
 )
 
-var store *sessions.CookieStore
+// sessionStore is the cookie store with the state of its keys. RotateKeys swaps it as a whole,
+// so that requests never see the keys of one store with the flags of another.
+type sessionStore struct {
+	*sessions.CookieStore
+	generation        int  // 1 for the keys passed to Cookie, incremented by each RotateKeys
+	encrypted         bool // whether the current keys encrypt
+	requireEncryption bool // from CookieConfig.RequireEncryption
+}
+
+var store atomic.Pointer[sessionStore] // replaced by RotateKeys
 var initialized bool
 var ErrNotInitialized = errors.New("auth: Store not initialized")
 
 const (
 	authKey     = "rex_authenticated"
 	stateKey    = "rex_auth_state"
+	issuedKey   = "rex_auth_issued"
+	keyGenKey   = "rex_auth_keygen"
 	sessionName = "rex_auth_session"
 )
 
@@ -39,6 +51,18 @@ type CookieConfig struct {
 
 	// Called when authentication fails
 	ErrorHandler func(c *rex.Context) error
+
+	// PreserveReturnTo appends the original URL of GET and HEAD requests to
+	// redirects issued by the ErrorHandler, e.g /login?next=%2Fdashboard.
+	// Use ConsumeReturnTo in the login handler to redirect back after SetAuthState.
+	PreserveReturnTo bool
+
+	// Query parameter carrying the return-to URL. Default: "next"
+	ReturnToParam string
+
+	// RequireEncryption panics if KeyPairs has no encryption key, so that session
+	// payloads are never stored in cookies in clear text. See GenerateKeyPair.
+	RequireEncryption bool
 }
 
 // Cookie creates a new authentication middleware with the given configuration.
@@ -60,7 +84,10 @@ func Cookie(config CookieConfig) rex.Middleware {
 		panic("Uninitialized: call auth.Register with your state value")
 	}
 
-	store = sessions.NewCookieStore(config.KeyPairs...)
+	if config.RequireEncryption && (len(config.KeyPairs) < 2 || len(config.KeyPairs[1]) == 0) {
+		panic("auth: RequireEncryption is set but no encryption key was provided, see auth.GenerateKeyPair")
+	}
+	cookieStore := sessions.NewCookieStore(config.KeyPairs...)
 
 	// Set default options if not provided
 	if config.Options == nil {
@@ -84,7 +111,19 @@ func Cookie(config CookieConfig) rex.Middleware {
 		}
 	}
 
-	store.Options = config.Options
+	cookieStore.Options = config.Options
+	store.Store(&sessionStore{
+		CookieStore:       cookieStore,
+		generation:        1,
+		encrypted:         len(config.KeyPairs) >= 2 && len(config.KeyPairs[1]) > 0,
+		requireEncryption: config.RequireEncryption,
+	})
+
+	preserveReturnTo = config.PreserveReturnTo
+	returnToParam = "next"
+	if config.ReturnToParam != "" {
+		returnToParam = config.ReturnToParam
+	}
 
 	return func(next rex.HandlerFunc) rex.HandlerFunc {
 		return func(c *rex.Context) error {
@@ -92,13 +131,9 @@ func Cookie(config CookieConfig) rex.Middleware {
 				return next(c)
 			}
 
-			session, err := store.Get(c.Request, sessionName)
-			if err != nil {
-				return config.ErrorHandler(c)
-			}
-
-			if session.Values[authKey] != true {
-				return config.ErrorHandler(c)
+			session, err := store.Load().Get(c.Request, sessionName)
+			if err != nil || session.Values[authKey] != true {
+				return handleAuthError(c, config.ErrorHandler)
 			}
 			return next(c)
 		}
@@ -120,22 +155,26 @@ func Register(value any) {
 // It could be the user object, userId or anything serializable into a cookie.
 // This is typically called following user login.
 func SetAuthState(c *rex.Context, state any) error {
-	if store == nil {
+	s := store.Load()
+	if s == nil {
 		return ErrNotInitialized
 	}
-	session, _ := store.Get(c.Request, sessionName)
+	session, _ := s.Get(c.Request, sessionName)
 	session.Values[authKey] = true
 	session.Values[stateKey] = state
+	session.Values[issuedKey] = time.Now().Unix()
+	session.Values[keyGenKey] = s.generation
 	return session.Save(c.Request, c.Response)
 }
 
 // GetAuthState returns the auth state for this request.
 func GetAuthState(c *rex.Context) (state any, authenticated bool) {
-	if store == nil {
+	s := store.Load()
+	if s == nil {
 		return nil, false
 	}
 
-	session, _ := store.Get(c.Request, sessionName)
+	session, _ := s.Get(c.Request, sessionName)
 	if session.IsNew {
 		return nil, false
 	}
@@ -146,11 +185,12 @@ func GetAuthState(c *rex.Context) (state any, authenticated bool) {
 
 // ClearAuthState deletes authentication state.
 func ClearAuthState(c *rex.Context) error {
-	if store == nil {
+	s := store.Load()
+	if s == nil {
 		return ErrNotInitialized
 	}
 
-	session, _ := store.Get(c.Request, sessionName)
+	session, _ := s.Get(c.Request, sessionName)
 	if session.IsNew {
 		return nil
 	}
@@ -169,3 +209,13 @@ func ClearAuthState(c *rex.Context) error {
 
 	return session.Save(c.Request, c.Response)
 }
+
+// ViewUser returns the auth state of an authenticated request and nil otherwise.
+// Use it with rex.WithViewUserFunc to expose the user to views as {{ .Request.User }}.
+func ViewUser(c *rex.Context) any {
+	state, authenticated := GetAuthState(c)
+	if !authenticated {
+		return nil
+	}
+	return state
+}
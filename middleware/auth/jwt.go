@@ -34,12 +34,14 @@ func JWT(secret string) rex.Middleware {
 			tokenString = strings.TrimSpace(tokenString)
 
 			if tokenString == "" {
+				ctx.AssertNotWritten("auth.JWT")
 				return ctx.WriteHeader(http.StatusUnauthorized)
 			}
 
 			// Verify the token
 			claims, err := VerifyJWToken(secret, tokenString)
 			if err != nil {
+				ctx.AssertNotWritten("auth.JWT")
 				return ctx.WriteHeader(http.StatusUnauthorized)
 			}
 
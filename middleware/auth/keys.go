@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+// ErrEncryptionRequired is returned by RotateKeys for a key pair without an
+// encryption key when CookieConfig.RequireEncryption is set.
+var ErrEncryptionRequired = errors.New("auth: an encryption key is required")
+
+// KeyPair is the key pair of cookie sessions.
+type KeyPair struct {
+	Auth       []byte // HMAC key authenticating the cookie, 32 or 64 bytes
+	Encryption []byte // AES key encrypting the session payload, 16, 24 or 32 bytes. nil disables encryption
+}
+
+// GenerateKeyPair returns a random 64-byte authentication key and 32-byte encryption key.
+// Store the keys in your secrets manager, sessions can not be read without them.
+// It panics if the system random number generator fails.
+func GenerateKeyPair() KeyPair {
+	pair := KeyPair{
+		Auth:       securecookie.GenerateRandomKey(64),
+		Encryption: securecookie.GenerateRandomKey(32),
+	}
+
+	if pair.Auth == nil || pair.Encryption == nil {
+		panic("auth: failed to generate random keys")
+	}
+	return pair
+}
+
+// RotateKeys replaces the keys of the cookie store created by Cookie. Sessions are written
+// with newPair and cookies written with any of oldPairs are still read, so that users are
+// not logged out during the rotation. Keep the old pairs for the session MaxAge, then
+// call RotateKeys again without them: cookies written with dropped keys fail to decode
+// and are treated as unauthenticated.
+//
+// Example:
+//
+//	err := auth.RotateKeys(newPair, currentPair)
+func RotateKeys(newPair KeyPair, oldPairs ...KeyPair) error {
+	var keys [][]byte
+	for _, pair := range append([]KeyPair{newPair}, oldPairs...) {
+		keys = append(keys, pair.Auth, pair.Encryption)
+	}
+
+	for {
+		current := store.Load()
+		if current == nil {
+			return ErrNotInitialized
+		}
+
+		if current.requireEncryption && len(newPair.Encryption) == 0 {
+			return ErrEncryptionRequired
+		}
+
+		rotated := sessions.NewCookieStore(keys...)
+		rotated.Options = current.Options
+
+		next := &sessionStore{
+			CookieStore:       rotated,
+			generation:        current.generation + 1,
+			encrypted:         len(newPair.Encryption) > 0,
+			requireEncryption: current.requireEncryption,
+		}
+
+		// A concurrent rotation won, rotate its store instead.
+		if store.CompareAndSwap(current, next) {
+			return nil
+		}
+	}
+}
+
+// Info describes the session of a request, for observability.
+type Info struct {
+	IssuedAt      time.Time // when SetAuthState saved the session, zero if unknown
+	KeyGeneration int       // 1 for the keys passed to Cookie, incremented by each RotateKeys
+	Encrypted     bool      // whether sessions are currently written encrypted
+}
+
+// SessionInfo returns the Info of the authenticated session of the request.
+// It returns false if the request has no valid session.
+func SessionInfo(c *rex.Context) (Info, bool) {
+	s := store.Load()
+	if s == nil {
+		return Info{}, false
+	}
+
+	session, err := s.Get(c.Request, sessionName)
+	if err != nil || session.IsNew || session.Values[authKey] != true {
+		return Info{}, false
+	}
+
+	info := Info{Encrypted: s.encrypted}
+	if issued, ok := session.Values[issuedKey].(int64); ok {
+		info.IssuedAt = time.Unix(issued, 0)
+	}
+
+	if generation, ok := session.Values[keyGenKey].(int); ok {
+		info.KeyGeneration = generation
+	}
+	return info, true
+}
@@ -0,0 +1,153 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/auth"
+)
+
+func newKeysRouter(t *testing.T, pair auth.KeyPair) *rex.Router {
+	t.Helper()
+	auth.Register(User{})
+
+	router := rex.NewRouter()
+	router.Use(auth.Cookie(auth.CookieConfig{
+		KeyPairs:          [][]byte{pair.Auth, pair.Encryption},
+		ErrorHandler:      errorCallback,
+		SkipAuth:          skipAuth,
+		RequireEncryption: true,
+	}))
+
+	router.POST("/login", func(c *rex.Context) error {
+		return auth.SetAuthState(c, User{Username: "ann"})
+	})
+
+	router.GET("/me", func(c *rex.Context) error {
+		info, ok := auth.SessionInfo(c)
+		if !ok {
+			t.Error("expected session info for an authenticated request")
+		}
+		if info.IssuedAt.IsZero() || !info.Encrypted {
+			t.Errorf("unexpected session info %+v", info)
+		}
+		state, _ := auth.GetAuthState(c)
+		return c.String(state.(User).Username)
+	})
+	return router
+}
+
+func loginWithKeys(t *testing.T, router *rex.Router) []*http.Cookie {
+	t.Helper()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login", nil))
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("expected a session cookie")
+	}
+	return cookies
+}
+
+func getWithCookies(router *rex.Router, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRotateKeysKeepsOldSessions(t *testing.T) {
+	oldPair := auth.GenerateKeyPair()
+	router := newKeysRouter(t, oldPair)
+	oldCookies := loginWithKeys(t, router)
+
+	newPair := auth.GenerateKeyPair()
+	if err := auth.RotateKeys(newPair, oldPair); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := getWithCookies(router, oldCookies); w.Code != http.StatusOK || w.Body.String() != "ann" {
+		t.Fatalf("expected the old session to be accepted, got %d %q", w.Code, w.Body.String())
+	}
+
+	newCookies := loginWithKeys(t, router)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range newCookies {
+		req.AddCookie(cookie)
+	}
+	c := router.InitContext(httptest.NewRecorder(), req)
+	if info, _ := auth.SessionInfo(c); info.KeyGeneration != 2 {
+		t.Errorf("expected new sessions to record the rotated key generation, got %d", info.KeyGeneration)
+	}
+
+	// Dropping the old keys logs out sessions written with them.
+	if err := auth.RotateKeys(newPair); err != nil {
+		t.Fatal(err)
+	}
+
+	if w := getWithCookies(router, oldCookies); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected a session under dropped keys to be rejected, got %d", w.Code)
+	}
+
+	if w := getWithCookies(router, newCookies); w.Code != http.StatusOK {
+		t.Errorf("expected the session under the current keys to be accepted, got %d", w.Code)
+	}
+}
+
+func TestRequireEncryption(t *testing.T) {
+	auth.Register(User{})
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected Cookie to panic without an encryption key")
+		}
+	}()
+
+	auth.Cookie(auth.CookieConfig{
+		KeyPairs:          [][]byte{auth.GenerateKeyPair().Auth},
+		RequireEncryption: true,
+	})
+}
+
+func TestRotateKeysRequiresEncryption(t *testing.T) {
+	newKeysRouter(t, auth.GenerateKeyPair())
+
+	err := auth.RotateKeys(auth.KeyPair{Auth: auth.GenerateKeyPair().Auth})
+	if err != auth.ErrEncryptionRequired {
+		t.Errorf("expected ErrEncryptionRequired, got %v", err)
+	}
+}
+
+func TestRotateKeysConcurrentRequests(t *testing.T) {
+	pair := auth.GenerateKeyPair()
+	router := newKeysRouter(t, pair)
+	cookies := loginWithKeys(t, router)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if err := auth.RotateKeys(auth.GenerateKeyPair(), pair); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if w := getWithCookies(router, cookies); w.Code != http.StatusOK {
+					t.Errorf("expected the session to be accepted during rotation, got %d", w.Code)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
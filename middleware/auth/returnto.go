@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/abiiranathan/rex"
+)
+
+var (
+	preserveReturnTo bool
+	returnToParam    = "next"
+)
+
+// ReturnTo returns the URL the user should be sent back to after logging in.
+// It is the original request URI for GET and HEAD requests when PreserveReturnTo
+// is enabled, and empty otherwise. Unsafe methods never carry a return-to URL
+// because replaying them after login is not safe.
+func ReturnTo(c *rex.Context) string {
+	if !preserveReturnTo {
+		return ""
+	}
+
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return ""
+	}
+	return c.Request.URL.RequestURI()
+}
+
+// ConsumeReturnTo returns the return-to URL sent with the login request in the
+// query string or form, or fallback if it is missing or not a local URL.
+// Absolute URLs to other hosts are rejected to prevent open redirects.
+//
+// Example:
+//
+//	if err := auth.SetAuthState(c, user); err != nil {
+//		return err
+//	}
+//	return c.Redirect(auth.ConsumeReturnTo(c, "/"))
+func ConsumeReturnTo(c *rex.Context, fallback string) string {
+	target := c.Query(returnToParam)
+	if target == "" {
+		target = c.FormValue(returnToParam)
+	}
+
+	if local, ok := localURL(c.Request, target); ok {
+		return local
+	}
+	return fallback
+}
+
+// localURL returns the path, query and fragment of target if it is a relative
+// path or an absolute URL on the request host.
+func localURL(req *http.Request, target string) (string, bool) {
+	// Browsers treat backslashes like slashes, "/\evil.com" is protocol relative.
+	if target == "" || strings.Contains(target, "\\") {
+		return "", false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+
+	if u.Scheme == "" && u.Host == "" {
+		if !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(target, "//") {
+			return "", false
+		}
+		return target, true
+	}
+
+	if (u.Scheme == "http" || u.Scheme == "https") && u.Host == req.Host {
+		local := u.RequestURI()
+		if u.Fragment != "" {
+			local += "#" + u.Fragment
+		}
+		return local, true
+	}
+	return "", false
+}
+
+// handleAuthError calls the error handler. Redirects it issues carry the
+// return-to URL if PreserveReturnTo is enabled.
+func handleAuthError(c *rex.Context, errorHandler func(c *rex.Context) error) error {
+	c.AssertNotWritten("auth.Cookie")
+
+	returnTo := ReturnTo(c)
+	if returnTo == "" {
+		return errorHandler(c)
+	}
+
+	w := c.Response
+	c.Response = &returnToWriter{ResponseWriter: w, returnTo: returnTo}
+	defer func() { c.Response = w }()
+	return errorHandler(c)
+}
+
+// returnToWriter appends the return-to parameter to the Location of redirects.
+type returnToWriter struct {
+	http.ResponseWriter
+	returnTo string
+}
+
+func (w *returnToWriter) WriteHeader(status int) {
+	if status >= 300 && status < 400 {
+		if location := w.Header().Get("Location"); location != "" {
+			w.Header().Set("Location", withReturnTo(location, w.returnTo))
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *returnToWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// withReturnTo adds the return-to parameter to location unless it is already set.
+func withReturnTo(location, returnTo string) string {
+	u, err := url.Parse(location)
+	if err != nil {
+		return location
+	}
+
+	q := u.Query()
+	if q.Has(returnToParam) {
+		return location
+	}
+
+	q.Set(returnToParam, returnTo)
+	u.RawQuery = q.Encode()
+	return u.String()
+}
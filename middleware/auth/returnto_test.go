@@ -0,0 +1,126 @@
+package auth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/auth"
+	"github.com/gorilla/securecookie"
+)
+
+func newReturnToRouter() *rex.Router {
+	auth.Register(User{})
+
+	router := rex.NewRouter()
+	router.Use(auth.Cookie(auth.CookieConfig{
+		KeyPairs: [][]byte{securecookie.GenerateRandomKey(32)},
+		ErrorHandler: func(c *rex.Context) error {
+			return c.Redirect("/login")
+		},
+		SkipAuth:         skipAuth,
+		PreserveReturnTo: true,
+	}))
+
+	router.POST("/login", func(c *rex.Context) error {
+		if err := auth.SetAuthState(c, User{Username: c.FormValue("username")}); err != nil {
+			return err
+		}
+		return c.Redirect(auth.ConsumeReturnTo(c, "/"))
+	})
+
+	router.GET("/dashboard", func(c *rex.Context) error {
+		return c.String("dashboard " + c.Query("tab"))
+	})
+
+	router.POST("/dashboard", func(c *rex.Context) error {
+		return c.String("updated")
+	})
+	return router
+}
+
+func login(router *rex.Router, next string) (*rex.TestResponse, string) {
+	form := url.Values{"username": {"abiiranathan"}, "next": {next}}
+	req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	res := router.Test(req)
+	return res, res.Header("Set-Cookie")
+}
+
+func TestCookieReturnToRoundTrip(t *testing.T) {
+	router := newReturnToRouter()
+
+	// Protected page redirects to the login page with the original URL.
+	res := router.Test(httptest.NewRequest(http.MethodGet, "/dashboard?tab=billing", nil))
+	if res.Status() != http.StatusSeeOther {
+		t.Fatalf("expected status 303, got %d", res.Status())
+	}
+
+	location, err := url.Parse(res.Header("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if location.Path != "/login" {
+		t.Fatalf("expected redirect to /login, got %s", location.Path)
+	}
+
+	next := location.Query().Get("next")
+	if next != "/dashboard?tab=billing" {
+		t.Fatalf("expected next=/dashboard?tab=billing, got %q", next)
+	}
+
+	// Login sends the user back to the protected page.
+	res, cookie := login(router, next)
+	if res.Status() != http.StatusSeeOther {
+		t.Fatalf("expected status 303, got %d", res.Status())
+	}
+
+	if res.Header("Location") != "/dashboard?tab=billing" {
+		t.Fatalf("expected redirect back to /dashboard?tab=billing, got %q", res.Header("Location"))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, res.Header("Location"), nil)
+	req.Header.Set("Cookie", cookie)
+	res = router.Test(req)
+
+	if res.Status() != http.StatusOK || res.BodyString() != "dashboard billing" {
+		t.Fatalf("expected dashboard page, got %d %q", res.Status(), res.BodyString())
+	}
+}
+
+func TestCookieReturnToUnsafeMethod(t *testing.T) {
+	router := newReturnToRouter()
+
+	res := router.Test(httptest.NewRequest(http.MethodPost, "/dashboard", nil))
+	if res.Header("Location") != "/login" {
+		t.Errorf("expected plain /login redirect for POST, got %q", res.Header("Location"))
+	}
+}
+
+func TestCookieReturnToRejectsForeignURLs(t *testing.T) {
+	router := newReturnToRouter()
+
+	for _, next := range []string{
+		"https://evil.com/phish",
+		"//evil.com",
+		"/\\evil.com",
+		"javascript:alert(1)",
+		"dashboard",
+	} {
+		res, _ := login(router, next)
+		if res.Header("Location") != "/" {
+			t.Errorf("next=%q: expected fallback redirect to /, got %q", next, res.Header("Location"))
+		}
+	}
+
+	// Absolute URLs on the same host are allowed and made relative.
+	res, _ := login(router, "http://example.com/dashboard")
+	if res.Header("Location") != "/dashboard" {
+		t.Errorf("expected same-host URL to be accepted, got %q", res.Header("Location"))
+	}
+}
@@ -0,0 +1,193 @@
+// Package authz authorizes requests declaratively from route metadata.
+// Routes declare what they require with route.Meta and a single middleware enforces it.
+//
+// Example:
+//
+//	r.Use(auth.Cookie(config), authz.New(authz.RoleCheck(nil, userRoles)))
+//
+//	r.GET("/admin", adminHandler).Meta(authz.DefaultKey, authz.RequireRole("admin"))
+//	r.GET("/posts/new", newPostHandler).Meta(authz.DefaultKey, authz.RequireAny("editor", "admin"))
+package authz
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/auth"
+)
+
+// DefaultKey is the route metadata key read by default.
+const DefaultKey = "authz"
+
+var (
+	// ErrUnauthenticated is returned by a Check when the request has no user. It is sent as 401.
+	ErrUnauthenticated = errors.New("authz: unauthenticated")
+
+	// ErrForbidden is returned by a Check when the user does not meet the requirement. It is sent as 403.
+	ErrForbidden = errors.New("authz: forbidden")
+)
+
+// Check authorizes the request against the requirement stored on the matched route.
+// It returns nil to allow the request, ErrUnauthenticated or ErrForbidden to deny it.
+// Other errors are passed to the router's error handler unchanged.
+type Check func(c *rex.Context, requirement any) error
+
+// Option configures the middleware.
+type Option func(*config)
+
+type config struct {
+	key         string
+	defaultDeny bool
+}
+
+// WithKey sets the route metadata key holding the requirement.
+func WithKey(key string) Option {
+	return func(cfg *config) {
+		cfg.key = key
+	}
+}
+
+// DefaultDeny denies routes without a requirement with 403 if deny is true.
+// By default they are allowed.
+func DefaultDeny(deny bool) Option {
+	return func(cfg *config) {
+		cfg.defaultDeny = deny
+	}
+}
+
+// New creates a middleware that calls check with the requirement stored under the "authz"
+// metadata key of the matched route. Denied requests are answered with 401 or 403 through
+// the router's error handler. The requirement is only included in the message in rex.Debug mode.
+func New(check Check, opts ...Option) rex.Middleware {
+	if check == nil {
+		panic("authz: check must not be nil")
+	}
+
+	cfg := config{key: DefaultKey}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			requirement, ok := c.RouteMeta(cfg.key)
+			if !ok {
+				if cfg.defaultDeny {
+					c.AssertNotWritten("authz")
+					return deny(ErrForbidden, nil)
+				}
+				return next(c)
+			}
+
+			if err := check(c, requirement); err != nil {
+				c.AssertNotWritten("authz")
+				return deny(err, requirement)
+			}
+			return next(c)
+		}
+	}
+}
+
+// deny converts the error of a check to the response error.
+func deny(err error, requirement any) error {
+	var status int
+	switch {
+	case errors.Is(err, ErrUnauthenticated):
+		status = http.StatusUnauthorized
+	case errors.Is(err, ErrForbidden):
+		status = http.StatusForbidden
+	default:
+		return err
+	}
+
+	msg := http.StatusText(status)
+	if rex.Debug {
+		if requirement == nil {
+			msg += ": route has no requirement"
+		} else {
+			msg += fmt.Sprintf(": requires %v", requirement)
+		}
+	}
+	return rex.NewError(status, msg)
+}
+
+// Roles is a requirement satisfied by a user with any of the roles.
+type Roles []string
+
+// RequireRole returns a requirement satisfied by users with the role.
+func RequireRole(role string) Roles {
+	return Roles{role}
+}
+
+// RequireAny returns a requirement satisfied by users with any of the roles.
+func RequireAny(roles ...string) Roles {
+	return Roles(roles)
+}
+
+// Allows reports whether a user with the roles meets the requirement.
+func (r Roles) Allows(roles []string) bool {
+	for _, role := range r {
+		if slices.Contains(roles, role) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the requirement for debug messages e.g "role editor or admin".
+func (r Roles) String() string {
+	return "role " + strings.Join(r, " or ")
+}
+
+// AuthUser returns the user of the cookie session or the JWT payload.
+// See auth.GetAuthState and auth.GetPayload.
+func AuthUser(c *rex.Context) (user any, ok bool) {
+	if state, authenticated := auth.GetAuthState(c); authenticated {
+		return state, true
+	}
+
+	if payload := auth.GetPayload(c.Request); payload != nil {
+		return payload, true
+	}
+	return nil, false
+}
+
+// RoleCheck returns a Check for Roles requirements.
+// user returns the user of the request and defaults to AuthUser if nil.
+// roles returns the roles of the user and defaults to calling its Roles() []string method if nil.
+// Requirements of other types are an error.
+func RoleCheck(user func(c *rex.Context) (any, bool), roles func(user any) []string) Check {
+	if user == nil {
+		user = AuthUser
+	}
+
+	if roles == nil {
+		roles = func(u any) []string {
+			if r, ok := u.(interface{ Roles() []string }); ok {
+				return r.Roles()
+			}
+			return nil
+		}
+	}
+
+	return func(c *rex.Context, requirement any) error {
+		required, ok := requirement.(Roles)
+		if !ok {
+			return fmt.Errorf("authz: unsupported requirement type %T", requirement)
+		}
+
+		u, ok := user(c)
+		if !ok {
+			return ErrUnauthenticated
+		}
+
+		if !required.Allows(roles(u)) {
+			return ErrForbidden
+		}
+		return nil
+	}
+}
@@ -0,0 +1,127 @@
+package authz_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/authz"
+)
+
+type user struct {
+	roles []string
+}
+
+func (u user) Roles() []string {
+	return u.roles
+}
+
+// fakeUser stores the user sent in the X-Roles header in the request locals.
+func fakeUser(next rex.HandlerFunc) rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		if roles, ok := c.Request.Header["X-Roles"]; ok {
+			c.Set("user", user{roles: strings.Split(roles[0], ",")})
+		}
+		return next(c)
+	}
+}
+
+func localUser(c *rex.Context) (any, bool) {
+	return c.Get("user")
+}
+
+func newRouter(opts ...authz.Option) *rex.Router {
+	r := rex.NewRouter()
+	r.Use(fakeUser, authz.New(authz.RoleCheck(localUser, nil), opts...))
+
+	ok := func(c *rex.Context) error { return c.String("ok") }
+	r.GET("/admin", ok).Meta(authz.DefaultKey, authz.RequireRole("admin"))
+	r.GET("/posts/new", ok).Meta(authz.DefaultKey, authz.RequireAny("editor", "admin"))
+	r.GET("/public", ok)
+	return r
+}
+
+func request(r *rex.Router, path string, roles ...string) *rex.TestResponse {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if roles != nil {
+		req.Header.Set("X-Roles", strings.Join(roles, ","))
+	}
+	return r.Test(req)
+}
+
+func TestRoleRequirements(t *testing.T) {
+	r := newRouter()
+
+	tests := []struct {
+		path  string
+		roles []string
+		want  int
+	}{
+		{"/admin", []string{"admin"}, http.StatusOK},
+		{"/admin", []string{"editor"}, http.StatusForbidden},
+		{"/admin", nil, http.StatusUnauthorized},
+		{"/posts/new", []string{"editor"}, http.StatusOK},
+		{"/posts/new", []string{"viewer", "admin"}, http.StatusOK},
+		{"/posts/new", []string{"viewer"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		res := request(r, tt.path, tt.roles...)
+		if res.Code != tt.want {
+			t.Errorf("%s with roles %v: expected %d, got %d %q", tt.path, tt.roles, tt.want, res.Code, res.BodyString())
+		}
+	}
+}
+
+func TestUntaggedRoutes(t *testing.T) {
+	res := request(newRouter(), "/public")
+	if res.Code != http.StatusOK {
+		t.Errorf("expected untagged routes to be allowed, got %d", res.Code)
+	}
+
+	res = request(newRouter(authz.DefaultDeny(true)), "/public", "admin")
+	if res.Code != http.StatusForbidden {
+		t.Errorf("expected untagged routes to be denied, got %d", res.Code)
+	}
+}
+
+func TestRequirementOnlyInDebug(t *testing.T) {
+	r := newRouter()
+
+	res := request(r, "/posts/new", "viewer")
+	if strings.Contains(res.BodyString(), "editor") {
+		t.Errorf("expected the requirement to be hidden, got %q", res.BodyString())
+	}
+
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	res = request(r, "/posts/new", "viewer")
+	if !strings.Contains(res.BodyString(), "role editor or admin") {
+		t.Errorf("expected the requirement in debug mode, got %q", res.BodyString())
+	}
+}
+
+func TestCustomKeyAndCheckErrors(t *testing.T) {
+	errDown := errors.New("policy store is down")
+
+	r := rex.NewRouter()
+	r.Use(authz.New(func(c *rex.Context, requirement any) error {
+		if requirement == "billing" {
+			return errDown
+		}
+		return nil
+	}, authz.WithKey("policy")))
+
+	r.GET("/invoices", func(c *rex.Context) error {
+		return c.String("ok")
+	}).Meta("policy", "billing")
+
+	res := request(r, "/invoices")
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected other check errors to reach the error handler, got %d", res.Code)
+	}
+}
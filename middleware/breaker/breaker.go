@@ -0,0 +1,343 @@
+// Package breaker isolates failing routes with circuit breakers. A route that keeps failing,
+// e.g because it panics on every request, is short-circuited with 503 Service Unavailable
+// for a cooldown period instead of running its handler, then probed with a few requests
+// before it is closed again.
+//
+// Each route pattern of the routes or groups a Breaker is attached to has its own circuit,
+// so a failing route does not affect its neighbours. Attach it inside the recovery middleware
+// so that panics are counted before they are recovered:
+//
+//	b := breaker.New(breaker.Config{FailureThreshold: 5, CooldownPeriod: 30 * time.Second})
+//
+//	api := r.Group("/api", recovery.New(false), b.Middleware())
+//	r.GET("/stats/breakers", func(c *rex.Context) error {
+//		return c.JSON(b.States())
+//	})
+package breaker
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+const (
+	// DefaultFailureThreshold is the default number of consecutive failures tripping a circuit.
+	DefaultFailureThreshold = 5
+
+	// DefaultWindow is the default time within which the consecutive failures must happen.
+	DefaultWindow = time.Minute
+
+	// DefaultCooldownPeriod is how long a tripped circuit rejects requests by default.
+	DefaultCooldownPeriod = 30 * time.Second
+)
+
+// ErrPanicked is passed to Config.TripOn when the handler panicked.
+var ErrPanicked = errors.New("breaker: handler panicked")
+
+// State is the state of a circuit.
+type State int32
+
+const (
+	Closed   State = iota // Requests run the handler.
+	Open                  // Requests are rejected until the cooldown period is over.
+	HalfOpen              // Probe requests run the handler, the others are rejected.
+)
+
+// String returns "closed", "open" or "half-open".
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "State(" + strconv.Itoa(int(s)) + ")"
+}
+
+// MarshalText encodes the state as its String, e.g in the JSON of States.
+func (s State) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// RouteState is the state of the circuit of a route, see Breaker.States.
+type RouteState struct {
+	State    State     `json:"state"`
+	Failures int64     `json:"failures"` // Consecutive failures counted towards tripping the circuit.
+	Trips    int64     `json:"trips"`    // Number of times the circuit opened.
+	Since    time.Time `json:"since"`    // Time of the last transition, zero if the circuit never opened.
+}
+
+// Config configures a Breaker.
+type Config struct {
+	// FailureThreshold is the number of consecutive failures tripping the circuit of a route.
+	// The default is DefaultFailureThreshold.
+	FailureThreshold int
+
+	// Window is the time within which the failures must happen. A failure after Window
+	// since the first failure of a streak starts a new streak. The default is DefaultWindow.
+	Window time.Duration
+
+	// CooldownPeriod is how long a tripped circuit rejects requests before probing the route.
+	// The default is DefaultCooldownPeriod.
+	CooldownPeriod time.Duration
+
+	// HalfOpenRequests is the number of probe requests let through at a time after the cooldown.
+	// The circuit closes once as many probes succeeded and opens again if one fails. The default is 1.
+	HalfOpenRequests int
+
+	// TripOn reports whether a request failed, with the final status of the response and the
+	// error returned by the handler, or ErrPanicked. The default counts statuses of 500 and above.
+	TripOn func(status int, err error) bool
+
+	// Logger logs the state transitions. The default is slog.Default().
+	Logger *slog.Logger
+
+	// Now returns the current time. The default is time.Now.
+	Now func() time.Time
+}
+
+// Breaker holds the circuits of the routes it is attached to, keyed by method and pattern.
+type Breaker struct {
+	config Config
+	routes sync.Map // "GET /users/{id}" -> *circuit
+}
+
+// circuit is the breaker of a route. Requests to a closed circuit only load its state
+// and reset the failure count with atomics. The mutex serializes failures and transitions.
+type circuit struct {
+	key       string
+	state     atomic.Int32
+	failures  atomic.Int64
+	openUntil atomic.Int64 // unix nanoseconds
+
+	mu          sync.Mutex
+	streakStart time.Time
+	probes      int // probes in flight
+	successes   int // successful probes since the circuit became half-open
+	trips       int64
+	since       time.Time
+}
+
+// New creates a Breaker with config.
+func New(config Config) *Breaker {
+	if config.FailureThreshold <= 0 {
+		config.FailureThreshold = DefaultFailureThreshold
+	}
+
+	if config.Window <= 0 {
+		config.Window = DefaultWindow
+	}
+
+	if config.CooldownPeriod <= 0 {
+		config.CooldownPeriod = DefaultCooldownPeriod
+	}
+
+	if config.HalfOpenRequests <= 0 {
+		config.HalfOpenRequests = 1
+	}
+
+	if config.TripOn == nil {
+		config.TripOn = func(status int, err error) bool {
+			return status >= http.StatusInternalServerError
+		}
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	if config.Now == nil {
+		config.Now = time.Now
+	}
+	return &Breaker{config: config}
+}
+
+// Middleware returns the breaker middleware. Requests to an open circuit get 503 Service
+// Unavailable with a Retry-After header, without running the handler.
+func (b *Breaker) Middleware() rex.Middleware {
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			cb := b.circuit(c.RouteMethod() + " " + c.Pattern())
+
+			probe, ok := b.allow(cb)
+			if !ok {
+				return b.reject(c, cb)
+			}
+
+			// Panics are counted without recovering them, so that the recovery
+			// middleware still sees the original stack.
+			returned := false
+			defer func() {
+				if !returned {
+					b.record(cb, probe, http.StatusInternalServerError, ErrPanicked)
+				}
+			}()
+
+			err := next(c)
+			returned = true
+
+			// The status is final once the error handler ran.
+			c.OnFinished(func(c *rex.Context) {
+				b.record(cb, probe, c.Status(), err)
+			})
+			return err
+		}
+	}
+}
+
+// States returns the state of the circuits of the routes that were requested,
+// keyed by the method and pattern of the route e.g "GET /users/{id}".
+func (b *Breaker) States() map[string]RouteState {
+	states := make(map[string]RouteState)
+	b.routes.Range(func(key, value any) bool {
+		cb := value.(*circuit)
+		cb.mu.Lock()
+		states[cb.key] = RouteState{
+			State:    State(cb.state.Load()),
+			Failures: cb.failures.Load(),
+			Trips:    cb.trips,
+			Since:    cb.since,
+		}
+		cb.mu.Unlock()
+		return true
+	})
+	return states
+}
+
+func (b *Breaker) circuit(key string) *circuit {
+	if cb, ok := b.routes.Load(key); ok {
+		return cb.(*circuit)
+	}
+	cb, _ := b.routes.LoadOrStore(key, &circuit{key: key})
+	return cb.(*circuit)
+}
+
+// allow reports whether the request may run the handler and whether it is a probe.
+func (b *Breaker) allow(cb *circuit) (probe bool, ok bool) {
+	switch State(cb.state.Load()) {
+	case Closed:
+		return false, true
+	case Open:
+		if b.config.Now().UnixNano() < cb.openUntil.Load() {
+			return false, false
+		}
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch State(cb.state.Load()) {
+	case Closed:
+		return false, true
+	case Open:
+		now := b.config.Now()
+		if now.UnixNano() < cb.openUntil.Load() {
+			return false, false
+		}
+		b.transition(cb, HalfOpen, now)
+		cb.probes, cb.successes = 0, 0
+	}
+
+	if cb.probes >= b.config.HalfOpenRequests {
+		return false, false
+	}
+	cb.probes++
+	return true, true
+}
+
+// reject answers a request to an open circuit.
+func (b *Breaker) reject(c *rex.Context, cb *circuit) error {
+	secs := 1
+	if wait := time.Duration(cb.openUntil.Load() - b.config.Now().UnixNano()); wait > 0 {
+		secs = int((wait + time.Second - 1) / time.Second)
+	}
+	c.SetHeader("Retry-After", strconv.Itoa(secs))
+	return rex.NewError(http.StatusServiceUnavailable, "service temporarily unavailable")
+}
+
+// record counts the result of a request.
+func (b *Breaker) record(cb *circuit, probe bool, status int, err error) {
+	failed := b.config.TripOn(status, err)
+	if probe {
+		b.probed(cb, failed)
+		return
+	}
+
+	if !failed {
+		if cb.failures.Load() != 0 {
+			cb.failures.Store(0)
+		}
+		return
+	}
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if State(cb.state.Load()) != Closed {
+		return // Tripped by a concurrent request.
+	}
+
+	now := b.config.Now()
+	if cb.failures.Load() == 0 || now.Sub(cb.streakStart) > b.config.Window {
+		cb.streakStart = now
+		cb.failures.Store(0)
+	}
+
+	if cb.failures.Add(1) >= int64(b.config.FailureThreshold) {
+		b.trip(cb, now)
+	}
+}
+
+// probed counts the result of a probe of a half-open circuit.
+func (b *Breaker) probed(cb *circuit, failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probes--
+	if State(cb.state.Load()) != HalfOpen {
+		return
+	}
+
+	now := b.config.Now()
+	if failed {
+		b.trip(cb, now)
+		return
+	}
+
+	cb.successes++
+	if cb.successes >= b.config.HalfOpenRequests {
+		cb.failures.Store(0)
+		b.transition(cb, Closed, now)
+	}
+}
+
+// trip opens the circuit for the cooldown period. cb.mu must be held.
+func (b *Breaker) trip(cb *circuit, now time.Time) {
+	cb.trips++
+	cb.openUntil.Store(now.Add(b.config.CooldownPeriod).UnixNano())
+	b.transition(cb, Open, now)
+}
+
+// transition sets the state of the circuit and logs it. cb.mu must be held.
+func (b *Breaker) transition(cb *circuit, state State, now time.Time) {
+	from := State(cb.state.Load())
+	cb.state.Store(int32(state))
+	cb.since = now
+
+	args := []any{"route", cb.key, "from", from.String(), "to", state.String()}
+	if state == Open {
+		b.config.Logger.Warn("breaker: circuit opened", append(args,
+			"failures", cb.failures.Load(), "cooldown", b.config.CooldownPeriod)...)
+		return
+	}
+	b.config.Logger.Info("breaker: circuit "+state.String(), args...)
+}
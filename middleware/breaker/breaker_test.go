@@ -0,0 +1,262 @@
+package breaker_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/breaker"
+	"github.com/abiiranathan/rex/middleware/recovery"
+)
+
+type testRouter struct {
+	*rex.Router
+	breaker *breaker.Breaker
+	clock   time.Time
+	failing atomic.Bool
+	calls   atomic.Int64
+	logs    bytes.Buffer
+}
+
+func newTestRouter(config breaker.Config) *testRouter {
+	tr := &testRouter{clock: time.Now()}
+	config.Now = func() time.Time { return tr.clock }
+	config.Logger = slog.New(slog.NewTextHandler(&tr.logs, nil))
+	tr.breaker = breaker.New(config)
+
+	tr.Router = rex.NewRouter()
+	api := tr.Group("/api", recovery.New(false, func(err error) {}), tr.breaker.Middleware())
+	api.GET("/reports/{id}", func(c *rex.Context) error {
+		tr.calls.Add(1)
+		if tr.failing.Load() {
+			panic("nil map")
+		}
+		return c.String("report")
+	})
+	api.GET("/health", func(c *rex.Context) error {
+		return c.String("ok")
+	})
+	return tr
+}
+
+func (tr *testRouter) get(path string) *rex.TestResponse {
+	return tr.Test(httptest.NewRequest(http.MethodGet, path, nil))
+}
+
+func TestConsecutiveFailuresTrip(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 3, CooldownPeriod: 10 * time.Second})
+	tr.failing.Store(true)
+
+	for range 3 {
+		tr.get("/api/reports/1")
+	}
+
+	if tr.calls.Load() != 3 {
+		t.Fatalf("expected the handler to run until the circuit opened, ran %d times", tr.calls.Load())
+	}
+
+	state := tr.breaker.States()["GET /api/reports/{id}"]
+	if state.State != breaker.Open || state.Trips != 1 {
+		t.Errorf("expected the circuit to be open, got %+v", state)
+	}
+
+	if !strings.Contains(tr.logs.String(), "breaker: circuit opened") {
+		t.Errorf("expected the transition to be logged, got %q", tr.logs.String())
+	}
+}
+
+func TestSuccessResetsFailures(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 3})
+
+	for range 5 {
+		tr.failing.Store(true)
+		tr.get("/api/reports/1")
+		tr.get("/api/reports/1")
+		tr.failing.Store(false)
+		tr.get("/api/reports/1")
+	}
+
+	if state := tr.breaker.States()["GET /api/reports/{id}"]; state.State != breaker.Closed {
+		t.Errorf("expected failures interleaved with successes not to trip, got %+v", state)
+	}
+}
+
+func TestFailuresOutsideWindowDoNotTrip(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 3, Window: time.Minute})
+	tr.failing.Store(true)
+
+	tr.get("/api/reports/1")
+	tr.get("/api/reports/1")
+	tr.clock = tr.clock.Add(2 * time.Minute)
+	tr.get("/api/reports/1")
+
+	if state := tr.breaker.States()["GET /api/reports/{id}"]; state.State != breaker.Closed || state.Failures != 1 {
+		t.Errorf("expected a new streak, got %+v", state)
+	}
+}
+
+func TestOpenCircuitRejectsFast(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 2, CooldownPeriod: 10 * time.Second})
+	tr.failing.Store(true)
+	tr.get("/api/reports/1")
+	tr.get("/api/reports/1")
+
+	tr.clock = tr.clock.Add(time.Second)
+	res := tr.get("/api/reports/2")
+
+	if res.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", res.Code)
+	}
+
+	if res.Header("Retry-After") != "9" {
+		t.Errorf("expected Retry-After to be the rest of the cooldown, got %q", res.Header("Retry-After"))
+	}
+
+	if tr.calls.Load() != 2 {
+		t.Errorf("expected the handler not to run while open, ran %d times", tr.calls.Load())
+	}
+}
+
+func TestHalfOpenProbeCloses(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 2, CooldownPeriod: 10 * time.Second})
+	tr.failing.Store(true)
+	tr.get("/api/reports/1")
+	tr.get("/api/reports/1")
+
+	tr.failing.Store(false)
+	tr.clock = tr.clock.Add(11 * time.Second)
+
+	if res := tr.get("/api/reports/1"); res.Code != http.StatusOK {
+		t.Fatalf("expected the probe to run the handler, got %d", res.Code)
+	}
+
+	if state := tr.breaker.States()["GET /api/reports/{id}"]; state.State != breaker.Closed || state.Failures != 0 {
+		t.Errorf("expected the successful probe to close the circuit, got %+v", state)
+	}
+
+	if !strings.Contains(tr.logs.String(), "to=half-open") || !strings.Contains(tr.logs.String(), "to=closed") {
+		t.Errorf("expected the transitions to be logged, got %q", tr.logs.String())
+	}
+}
+
+func TestHalfOpenProbeFailureReopens(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 2, CooldownPeriod: 10 * time.Second})
+	tr.failing.Store(true)
+	tr.get("/api/reports/1")
+	tr.get("/api/reports/1")
+
+	tr.clock = tr.clock.Add(11 * time.Second)
+	tr.get("/api/reports/1")
+
+	state := tr.breaker.States()["GET /api/reports/{id}"]
+	if state.State != breaker.Open || state.Trips != 2 {
+		t.Fatalf("expected the failed probe to open the circuit again, got %+v", state)
+	}
+
+	if res := tr.get("/api/reports/1"); res.Code != http.StatusServiceUnavailable || res.Header("Retry-After") != "10" {
+		t.Errorf("expected a new cooldown, got %d %q", res.Code, res.Header("Retry-After"))
+	}
+}
+
+func TestHalfOpenLimitsProbes(t *testing.T) {
+	b := breaker.New(breaker.Config{FailureThreshold: 1, CooldownPeriod: time.Millisecond, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var fail atomic.Bool
+	fail.Store(true)
+
+	r := rex.NewRouter()
+	r.GET("/slow", func(c *rex.Context) error {
+		if fail.Load() {
+			return rex.NewError(http.StatusBadGateway, "upstream down")
+		}
+		close(started)
+		<-release
+		return c.String("ok")
+	}, b.Middleware())
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	fail.Store(false)
+	time.Sleep(5 * time.Millisecond)
+
+	done := make(chan *rex.TestResponse)
+	go func() {
+		done <- r.Test(httptest.NewRequest(http.MethodGet, "/slow", nil))
+	}()
+	<-started
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/slow", nil)); res.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected requests beyond the probe to be rejected, got %d", res.Code)
+	}
+
+	close(release)
+	if res := <-done; res.Code != http.StatusOK {
+		t.Errorf("expected the probe to succeed, got %d", res.Code)
+	}
+}
+
+func TestUnrelatedRouteUnaffected(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 2})
+	tr.failing.Store(true)
+	tr.get("/api/reports/1")
+	tr.get("/api/reports/1")
+
+	if res := tr.get("/api/health"); res.Code != http.StatusOK || res.BodyString() != "ok" {
+		t.Errorf("expected the unrelated route to be served, got %d %q", res.Code, res.BodyString())
+	}
+
+	states := tr.breaker.States()
+	if states["GET /api/reports/{id}"].State != breaker.Open || states["GET /api/health"].State != breaker.Closed {
+		t.Errorf("expected independent circuits, got %+v", states)
+	}
+}
+
+func TestTripOn(t *testing.T) {
+	b := breaker.New(breaker.Config{
+		FailureThreshold: 1,
+		TripOn: func(status int, err error) bool {
+			return status == http.StatusTooManyRequests
+		},
+		Logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	r := rex.NewRouter()
+	r.GET("/missing", func(c *rex.Context) error {
+		return rex.NewError(http.StatusNotFound, "missing")
+	}, b.Middleware())
+	r.GET("/limited", func(c *rex.Context) error {
+		return rex.NewError(http.StatusTooManyRequests, "slow down")
+	}, b.Middleware())
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/missing", nil))
+	r.Test(httptest.NewRequest(http.MethodGet, "/limited", nil))
+
+	states := b.States()
+	if states["GET /missing"].State != breaker.Closed || states["GET /limited"].State != breaker.Open {
+		t.Errorf("expected TripOn to see the status set by the error handler, got %+v", states)
+	}
+}
+
+func TestStatesJSON(t *testing.T) {
+	tr := newTestRouter(breaker.Config{FailureThreshold: 1})
+	tr.failing.Store(true)
+	tr.get("/api/reports/1")
+
+	data, err := json.Marshal(tr.breaker.States())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(data), `"GET /api/reports/{id}":{"state":"open","failures":1,"trips":1`) {
+		t.Errorf("unexpected JSON %s", data)
+	}
+}
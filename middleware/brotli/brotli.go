@@ -3,6 +3,7 @@ package brotli
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 	"strings"
@@ -14,10 +15,21 @@ import (
 
 type brotliWriter struct {
 	http.ResponseWriter
-	bw *matchfinder.Writer
+	bw       *matchfinder.Writer
+	identity io.Writer // receives the uncompressed body, see rex.IdentityWriterKey
+}
+
+// Content-Length set by handlers refers to the uncompressed body, so it is removed.
+func (b *brotliWriter) WriteHeader(status int) {
+	b.Header().Del("Content-Length")
+	b.ResponseWriter.WriteHeader(status)
 }
 
 func (b *brotliWriter) Write(p []byte) (int, error) {
+	b.Header().Del("Content-Length")
+	if b.identity != nil {
+		b.identity.Write(p)
+	}
 	return b.bw.Write(p)
 }
 
@@ -35,20 +47,30 @@ func (b *brotliWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 }
 
 // Brotli compression middleware.
+// Routes with the rex.MetaSkipCompression metadata set are not compressed, see rex.Context.SkipCompression.
+// Other responses get Vary: Accept-Encoding, whether or not the client accepts br.
+// It sets rex.ContentEncodingKey so that etag.New gives compressed responses their own ETag.
 func Brotli(skipPaths ...string) rex.Middleware {
 	return func(next rex.HandlerFunc) rex.HandlerFunc {
 		return func(c *rex.Context) error {
+			if c.SkipCompression() {
+				return next(c)
+			}
+
 			for _, path := range skipPaths {
 				if strings.HasPrefix(c.Path(), path) {
 					return next(c)
 				}
 			}
 
+			// The response depends on Accept-Encoding even if it is not compressed.
+			c.AppendVary("Accept-Encoding")
 			if !strings.Contains(c.GetHeader("Accept-Encoding"), "br") {
 				return next(c)
 			}
 
 			c.SetHeader("Content-Encoding", "br")
+			c.Set(rex.ContentEncodingKey, "br")
 
 			// No content Length on compressed data.
 			c.DelHeader("Content-Length")
@@ -61,6 +83,10 @@ func Brotli(skipPaths ...string) rex.Middleware {
 				bw:             bw,
 			}
 
+			if identity, ok := c.Get(rex.IdentityWriterKey); ok {
+				brw.identity, _ = identity.(io.Writer)
+			}
+
 			originalWriter := c.Response
 			c.Response = brw
 			err := next(c)
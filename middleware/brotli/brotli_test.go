@@ -35,3 +35,41 @@ func TestBrotliMiddleware(t *testing.T) {
 	require.Equal(t, w.Body.Len(), 31)
 
 }
+
+func TestBrotliSkipCompressionMeta(t *testing.T) {
+	r := rex.NewRouter()
+	r.Use(brotli.Brotli())
+
+	r.GET("/", func(c *rex.Context) error {
+		return c.String("Hello World")
+	}).Meta(rex.MetaSkipCompression, true)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "br")
+	w := httptest.NewRecorder()
+
+	r.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Result().StatusCode)
+	require.Empty(t, w.Header().Get("Content-Encoding"))
+	require.Equal(t, "Hello World", w.Body.String())
+}
+
+func TestBrotliVary(t *testing.T) {
+	r := rex.NewRouter()
+	r.Use(brotli.Brotli())
+
+	r.GET("/", func(c *rex.Context) error {
+		c.AppendVary("Accept-Language")
+		return c.String("Hello World")
+	})
+
+	for _, acceptEncoding := range []string{"", "br"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		require.Equal(t, "Accept-Encoding, Accept-Language", w.Header().Get("Vary"))
+	}
+}
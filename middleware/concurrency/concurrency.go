@@ -0,0 +1,146 @@
+// Package concurrency limits the number of requests a route or group
+// handles at the same time.
+package concurrency
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// Option configures a Limiter.
+type Option func(*Limiter)
+
+// WithQueue lets up to depth requests wait for a free slot when the limiter is saturated.
+// A waiting request is rejected if no slot frees up within timeout or the client goes away.
+// A zero timeout waits until the request context is done.
+func WithQueue(depth int, timeout time.Duration) Option {
+	return func(l *Limiter) {
+		l.queueDepth = int64(depth)
+		l.timeout = timeout
+	}
+}
+
+// WithStatus sets the status code sent to rejected requests.
+// Default is 503 (http.StatusServiceUnavailable). 429 is also common.
+func WithStatus(status int) Option {
+	return func(l *Limiter) {
+		l.status = status
+	}
+}
+
+// WithRetryAfter sets the Retry-After header on rejected requests.
+func WithRetryAfter(d time.Duration) Option {
+	return func(l *Limiter) {
+		l.retryAfter = d
+	}
+}
+
+// Limiter is a semaphore that bounds the number of concurrent requests.
+// Use one Limiter per route or group that should share the limit.
+type Limiter struct {
+	sem        chan struct{}
+	queueDepth int64
+	timeout    time.Duration
+	status     int
+	retryAfter time.Duration
+
+	waiting atomic.Int64
+}
+
+// NewLimiter creates a Limiter that allows n concurrent requests.
+// It panics if n is less than 1.
+func NewLimiter(n int, opts ...Option) *Limiter {
+	if n < 1 {
+		panic("concurrency: limit must be at least 1")
+	}
+
+	l := &Limiter{
+		sem:    make(chan struct{}, n),
+		status: http.StatusServiceUnavailable,
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Limit returns a middleware that allows at most n requests to run the handler at the same time.
+// By default extra requests are rejected immediately with 503.
+//
+// Example:
+//
+//	r.GET("/reports/{id}", generateReport, concurrency.Limit(4, concurrency.WithStatus(429)))
+func Limit(n int, opts ...Option) rex.Middleware {
+	return NewLimiter(n, opts...).Middleware()
+}
+
+// InFlight returns the number of requests currently holding a slot.
+func (l *Limiter) InFlight() int {
+	return len(l.sem)
+}
+
+// Waiting returns the number of requests queued for a slot.
+func (l *Limiter) Waiting() int {
+	return int(l.waiting.Load())
+}
+
+// Middleware returns the limiter middleware.
+// The slot is released when the handler returns, even if it panics.
+func (l *Limiter) Middleware() rex.Middleware {
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if !l.acquire(c.Request) {
+				return l.reject(c)
+			}
+			defer l.release()
+			return next(c)
+		}
+	}
+}
+
+func (l *Limiter) acquire(req *http.Request) bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.waiting.Add(1) > l.queueDepth {
+		l.waiting.Add(-1)
+		return false
+	}
+	defer l.waiting.Add(-1)
+
+	var timeout <-chan time.Time
+	if l.timeout > 0 {
+		timer := time.NewTimer(l.timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-timeout:
+		return false
+	case <-req.Context().Done():
+		return false
+	}
+}
+
+func (l *Limiter) release() {
+	<-l.sem
+}
+
+func (l *Limiter) reject(c *rex.Context) error {
+	if l.retryAfter > 0 {
+		secs := int((l.retryAfter + time.Second - 1) / time.Second)
+		c.SetHeader("Retry-After", strconv.Itoa(secs))
+	}
+	return c.WriteHeader(l.status)
+}
@@ -0,0 +1,148 @@
+package concurrency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/concurrency"
+	"github.com/abiiranathan/rex/middleware/recovery"
+)
+
+// waitFor polls cond until it returns true or the test times out.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestLimitRejectsWhenSaturated(t *testing.T) {
+	const n = 2
+	limiter := concurrency.NewLimiter(n, concurrency.WithStatus(http.StatusTooManyRequests),
+		concurrency.WithRetryAfter(1500*time.Millisecond))
+
+	unblock := make(chan struct{})
+	r := rex.NewRouter()
+	r.GET("/report", func(c *rex.Context) error {
+		<-unblock
+		return c.String("done")
+	}, limiter.Middleware())
+
+	codes := make([]int, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = r.Test(httptest.NewRequest(http.MethodGet, "/report", nil)).Status()
+		}(i)
+	}
+
+	waitFor(t, func() bool { return limiter.InFlight() == n })
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/report", nil))
+	if res.Status() != http.StatusTooManyRequests {
+		t.Errorf("expected status 429, got %d", res.Status())
+	}
+
+	if res.Header("Retry-After") != "2" {
+		t.Errorf("expected Retry-After 2, got %q", res.Header("Retry-After"))
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: expected status 200, got %d", i, code)
+		}
+	}
+
+	if limiter.InFlight() != 0 {
+		t.Errorf("expected no slots in use, got %d", limiter.InFlight())
+	}
+}
+
+func TestLimitQueue(t *testing.T) {
+	limiter := concurrency.NewLimiter(1, concurrency.WithQueue(1, time.Second))
+
+	unblock := make(chan struct{})
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		<-unblock
+		return c.String("done")
+	}, limiter.Middleware())
+
+	var wg sync.WaitGroup
+	codes := make([]int, 2)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			codes[i] = r.Test(httptest.NewRequest(http.MethodGet, "/", nil)).Status()
+		}(i)
+	}
+
+	waitFor(t, func() bool { return limiter.InFlight() == 1 && limiter.Waiting() == 1 })
+
+	// The queue is full, so this one is rejected.
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.Status() != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503, got %d", res.Status())
+	}
+
+	close(unblock)
+	wg.Wait()
+
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("request %d: expected status 200, got %d", i, code)
+		}
+	}
+}
+
+func TestLimitQueueTimeout(t *testing.T) {
+	limiter := concurrency.NewLimiter(1, concurrency.WithQueue(1, 10*time.Millisecond))
+
+	unblock := make(chan struct{})
+	defer close(unblock)
+
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		<-unblock
+		return nil
+	}, limiter.Middleware())
+
+	go r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	waitFor(t, func() bool { return limiter.InFlight() == 1 })
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.Status() != http.StatusServiceUnavailable {
+		t.Errorf("expected status 503 after queue timeout, got %d", res.Status())
+	}
+}
+
+func TestLimitReleasesOnPanic(t *testing.T) {
+	limiter := concurrency.NewLimiter(1)
+
+	r := rex.NewRouter()
+	r.Use(recovery.New(false, func(err error) {}))
+	r.GET("/panic", func(c *rex.Context) error {
+		panic("boom")
+	}, limiter.Middleware())
+
+	for i := 0; i < 3; i++ {
+		r.Test(httptest.NewRequest(http.MethodGet, "/panic", nil))
+		if limiter.InFlight() != 0 {
+			t.Fatalf("request %d leaked a permit", i)
+		}
+	}
+}
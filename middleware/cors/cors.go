@@ -3,6 +3,7 @@ package cors
 import (
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/abiiranathan/rex"
@@ -95,3 +96,197 @@ func New(opts ...CORSOptions) rex.Middleware {
 func joinStrings(s []string) string {
 	return strings.Join(s, ", ")
 }
+
+// Config is a CORS policy for WithConfig and Mount.
+// Unlike New, requests from disallowed origins are passed through without CORS headers,
+// which makes the browser block the response, instead of failing with 403.
+type Config struct {
+	// AllowOrigins lists the allowed origins like "https://app.example.com".
+	// A "*" in an origin matches one or more subdomains: "https://*.example.com".
+	// The origin "*" allows all origins without credentials.
+	AllowOrigins []string
+
+	// AllowOriginFunc is called for origins not matched by AllowOrigins.
+	AllowOriginFunc func(origin string) bool
+
+	// AllowMethods are the methods allowed in preflight requests.
+	// The default is GET, HEAD, POST, PUT, PATCH and DELETE.
+	AllowMethods []string
+
+	// AllowHeaders are the request headers allowed in preflight requests.
+	// If empty, the headers requested by the preflight are allowed.
+	AllowHeaders []string
+
+	// ExposeHeaders are the response headers readable by the client.
+	ExposeHeaders []string
+
+	// AllowCredentials allows cookies and authorization headers.
+	// It cannot be combined with the origin "*".
+	AllowCredentials bool
+
+	// MaxAge is the number of seconds browsers may cache a preflight response.
+	MaxAge int
+}
+
+// policy is a compiled Config.
+type policy struct {
+	Config
+	anyOrigin bool
+	exact     map[string]bool
+	wildcards [][2]string // prefix and suffix around "*"
+	methods   string
+	headers   string
+	expose    string
+}
+
+func newPolicy(config Config) *policy {
+	p := &policy{Config: config, exact: make(map[string]bool)}
+
+	for _, origin := range config.AllowOrigins {
+		origin = strings.ToLower(origin)
+		switch {
+		case origin == "*":
+			p.anyOrigin = true
+		case strings.Contains(origin, "*"):
+			prefix, suffix, _ := strings.Cut(origin, "*")
+			p.wildcards = append(p.wildcards, [2]string{prefix, suffix})
+		default:
+			p.exact[origin] = true
+		}
+	}
+
+	if p.anyOrigin && config.AllowCredentials {
+		panic("cors: AllowCredentials cannot be used with the origin \"*\", list the allowed origins instead")
+	}
+
+	methods := config.AllowMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+	p.methods = joinStrings(methods)
+	p.headers = joinStrings(config.AllowHeaders)
+	p.expose = joinStrings(config.ExposeHeaders)
+	return p
+}
+
+// allowed reports whether origin is allowed by the policy.
+func (p *policy) allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	if p.anyOrigin {
+		return true
+	}
+
+	lower := strings.ToLower(origin)
+	if p.exact[lower] {
+		return true
+	}
+
+	for _, w := range p.wildcards {
+		if len(lower) > len(w[0])+len(w[1]) && strings.HasPrefix(lower, w[0]) && strings.HasSuffix(lower, w[1]) {
+			// The wildcard only matches subdomain labels.
+			if sub := lower[len(w[0]) : len(lower)-len(w[1])]; !strings.ContainsAny(sub, "/:@") {
+				return true
+			}
+		}
+	}
+	return p.AllowOriginFunc != nil && p.AllowOriginFunc(origin)
+}
+
+// WithConfig creates a CORS middleware for the policy in config.
+// Preflight requests (OPTIONS with Access-Control-Request-Method) are answered with 204
+// and do not reach the handler. Since the router only runs middleware for registered routes,
+// use Mount to also register the OPTIONS routes for preflights.
+// It panics if AllowCredentials is combined with the origin "*".
+func WithConfig(config Config) rex.Middleware {
+	p := newPolicy(config)
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			header := c.Response.Header()
+			origin := c.Request.Header.Get("Origin")
+			preflight := c.Request.Method == http.MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != ""
+
+			// The response differs by origin unless every origin gets the same "*".
+			if !p.anyOrigin {
+				header.Add("Vary", "Origin")
+			}
+
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			if !p.allowed(origin) {
+				if preflight {
+					c.Response.WriteHeader(http.StatusNoContent)
+					return nil
+				}
+				return next(c)
+			}
+
+			if p.anyOrigin {
+				header.Set("Access-Control-Allow-Origin", "*")
+			} else {
+				header.Set("Access-Control-Allow-Origin", origin)
+			}
+
+			if p.AllowCredentials {
+				header.Set("Access-Control-Allow-Credentials", "true")
+			}
+
+			if !preflight {
+				if p.expose != "" {
+					header.Set("Access-Control-Expose-Headers", p.expose)
+				}
+				return next(c)
+			}
+
+			header.Set("Access-Control-Allow-Methods", p.methods)
+			if p.headers != "" {
+				header.Set("Access-Control-Allow-Headers", p.headers)
+			} else if requested := c.Request.Header.Get("Access-Control-Request-Headers"); requested != "" {
+				header.Set("Access-Control-Allow-Headers", requested)
+			}
+
+			if p.MaxAge > 0 {
+				header.Set("Access-Control-Max-Age", strconv.Itoa(p.MaxAge))
+			}
+
+			c.Response.WriteHeader(http.StatusNoContent)
+			return nil
+		}
+	}
+}
+
+// Routes is implemented by *rex.Router and *rex.Group.
+type Routes interface {
+	Use(middlewares ...rex.Middleware)
+	OPTIONS(pattern string, handler rex.HandlerFunc, middlewares ...rex.Middleware) *rex.Route
+}
+
+// Mount adds the CORS middleware for config to a router or group and registers
+// an OPTIONS route for every path below it, so preflight requests are answered
+// instead of failing with 405 Method Not Allowed.
+// Call it before registering the routes, as middleware only applies to routes added later.
+//
+// Example:
+//
+//	public := r.Group("/public")
+//	cors.Mount(public, cors.Config{AllowOrigins: []string{"*"}})
+//
+//	api := r.Group("/api")
+//	cors.Mount(api, cors.Config{
+//		AllowOrigins:     []string{"https://app.example.com", "https://*.example.com"},
+//		AllowCredentials: true,
+//		MaxAge:           600,
+//	})
+func Mount(routes Routes, config Config) {
+	routes.Use(WithConfig(config))
+	routes.OPTIONS("/{path...}", func(c *rex.Context) error {
+		// Reached only by plain OPTIONS requests.
+		return c.WriteHeader(http.StatusNoContent)
+	})
+}
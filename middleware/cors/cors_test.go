@@ -3,6 +3,7 @@ package cors_test
 import (
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/abiiranathan/rex"
@@ -55,3 +56,171 @@ func TestCors(t *testing.T) {
 	}
 
 }
+
+func newPolicyRouter() *rex.Router {
+	router := rex.NewRouter()
+
+	public := router.Group("/public")
+	cors.Mount(public, cors.Config{AllowOrigins: []string{"*"}})
+	public.GET("/items", func(c *rex.Context) error {
+		return c.String("items")
+	})
+
+	app := router.Group("/app")
+	cors.Mount(app, cors.Config{
+		AllowOrigins:     []string{"https://app.example.com", "https://*.example.org"},
+		AllowOriginFunc:  func(origin string) bool { return origin == "https://partner.test" },
+		AllowMethods:     []string{http.MethodGet, http.MethodPost},
+		AllowHeaders:     []string{"Content-Type", "X-CSRF-Token"},
+		ExposeHeaders:    []string{"X-Request-Id"},
+		AllowCredentials: true,
+		MaxAge:           600,
+	})
+	app.GET("/me", func(c *rex.Context) error {
+		return c.String("me")
+	})
+	app.POST("/me", func(c *rex.Context) error {
+		return c.String("updated")
+	})
+	return router
+}
+
+func TestConfigCredentialedRequests(t *testing.T) {
+	router := newPolicyRouter()
+
+	for _, origin := range []string{"https://app.example.com", "https://eu.api.example.org", "https://partner.test"} {
+		req := httptest.NewRequest(http.MethodGet, "/app/me", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK || w.Body.String() != "me" {
+			t.Fatalf("%s: expected 200 me, got %d %q", origin, w.Code, w.Body.String())
+		}
+
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("%s: expected the origin to be echoed, got %q", origin, got)
+		}
+
+		if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Errorf("%s: expected credentials to be allowed", origin)
+		}
+
+		if w.Header().Get("Access-Control-Expose-Headers") != "X-Request-Id" {
+			t.Errorf("%s: expected exposed headers, got %q", origin, w.Header().Get("Access-Control-Expose-Headers"))
+		}
+
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("%s: expected Vary: Origin, got %q", origin, w.Header().Values("Vary"))
+		}
+	}
+}
+
+func TestConfigDisallowedOrigins(t *testing.T) {
+	router := newPolicyRouter()
+
+	origins := []string{
+		"https://evil.com",
+		"https://example.org",           // the wildcard requires a subdomain
+		"https://evil.com/.example.org", // not a subdomain
+		"http://app.example.com",        // wrong scheme
+	}
+
+	for _, origin := range origins {
+		req := httptest.NewRequest(http.MethodGet, "/app/me", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected the request to pass through with 200, got %d", origin, w.Code)
+		}
+
+		for key := range w.Header() {
+			if strings.HasPrefix(key, "Access-Control-") {
+				t.Errorf("%s: expected no CORS headers, got %s", origin, key)
+			}
+		}
+
+		if w.Header().Get("Vary") != "Origin" {
+			t.Errorf("%s: expected Vary: Origin on disallowed responses too", origin)
+		}
+	}
+}
+
+func TestConfigPreflight(t *testing.T) {
+	router := newPolicyRouter()
+
+	req := httptest.NewRequest(http.MethodOptions, "/app/me", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "content-type")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":      "https://app.example.com",
+		"Access-Control-Allow-Methods":     "GET, POST",
+		"Access-Control-Allow-Headers":     "Content-Type, X-CSRF-Token",
+		"Access-Control-Allow-Credentials": "true",
+		"Access-Control-Max-Age":           "600",
+	}
+	for key, value := range expected {
+		if got := w.Header().Get(key); got != value {
+			t.Errorf("expected %s %q, got %q", key, value, got)
+		}
+	}
+
+	vary := strings.Join(w.Header().Values("Vary"), ", ")
+	if vary != "Origin, Access-Control-Request-Method, Access-Control-Request-Headers" {
+		t.Errorf("unexpected Vary: %q", vary)
+	}
+
+	// Public preflights allow any origin and reflect the requested headers.
+	req = httptest.NewRequest(http.MethodOptions, "/public/items", nil)
+	req.Header.Set("Origin", "https://anyone.test")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "x-custom")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", w.Code)
+	}
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Errorf("expected *, got %q", w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	if w.Header().Get("Access-Control-Allow-Headers") != "x-custom" {
+		t.Errorf("expected requested headers to be allowed, got %q", w.Header().Get("Access-Control-Allow-Headers"))
+	}
+
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" || w.Header().Get("Access-Control-Max-Age") != "" {
+		t.Error("expected no credentials or max age for the public policy")
+	}
+
+	// Disallowed preflights get no CORS headers.
+	req = httptest.NewRequest(http.MethodOptions, "/app/me", nil)
+	req.Header.Set("Origin", "https://evil.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if w.Header().Get("Access-Control-Allow-Origin") != "" || w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Error("expected no CORS headers for a disallowed preflight")
+	}
+}
+
+func TestConfigCredentialsWithAnyOrigin(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for credentials with the origin *")
+		}
+	}()
+	cors.WithConfig(cors.Config{AllowOrigins: []string{"*"}, AllowCredentials: true})
+}
@@ -0,0 +1,155 @@
+// Package deadline honors the time budget propagated by internal callers in a request header,
+// e.g "X-Request-Timeout: 250ms", capped by a server-side maximum.
+//
+// The budget becomes the deadline of the request context. Handlers must pass
+// c.Request.Context() to their database and outbound calls for the deadline to stop them,
+// and can use Remaining to return partial results when the budget runs low.
+//
+// Example:
+//
+//	r.Use(deadline.New(5*time.Second, deadline.WithDefault(2*time.Second)))
+//
+//	r.GET("/search", func(c *rex.Context) error {
+//		results := searchPrimary(c.Request.Context())
+//		if deadline.Remaining(c) > 100*time.Millisecond {
+//			results = append(results, searchArchive(c.Request.Context())...)
+//		}
+//		return c.JSON(results)
+//	})
+package deadline
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// ElapsedHeader is set on 504 responses with the milliseconds the server spent on the request.
+var ElapsedHeader = "X-Request-Elapsed"
+
+// Option configures the deadline middleware.
+type Option func(*config)
+
+type config struct {
+	header   string
+	fallback time.Duration
+	max      time.Duration
+}
+
+// WithHeader sets the request header carrying the budget. Default is "X-Request-Timeout".
+// Use "grpc-timeout" for gRPC-style callers.
+func WithHeader(name string) Option {
+	return func(c *config) {
+		c.header = name
+	}
+}
+
+// WithDefault sets the budget of requests without a valid header. Default is the maximum.
+func WithDefault(d time.Duration) Option {
+	return func(c *config) {
+		c.fallback = d
+	}
+}
+
+// New creates a middleware that sets the deadline of the request context from the
+// budget header, capped at max. The header value is either:
+//   - a number of milliseconds, e.g "1500".
+//   - a gRPC timeout, digits followed by one of the units H, M, S, m, u or n, e.g "100m" for 100ms.
+//   - a Go duration, e.g "1.5s".
+//
+// Requests without the header or with an invalid value get the default budget, see WithDefault.
+// An existing deadline of the request context, e.g set by the server or an earlier middleware,
+// is kept if it is earlier.
+//
+// If the deadline expires and the handler returns without writing a response, the middleware
+// returns a 504 error for the router's error handler with the ElapsedHeader set.
+// It panics if max is not positive.
+func New(max time.Duration, options ...Option) rex.Middleware {
+	if max <= 0 {
+		panic("deadline: max must be positive")
+	}
+
+	cfg := &config{header: "X-Request-Timeout", max: max}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	if cfg.fallback <= 0 || cfg.fallback > cfg.max {
+		cfg.fallback = cfg.max
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			budget, ok := Parse(c.GetHeader(cfg.header))
+			if !ok || budget <= 0 {
+				budget = cfg.fallback
+			}
+			budget = min(budget, cfg.max)
+
+			ctx, cancel := context.WithTimeout(c.Request.Context(), budget)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+
+			err := next(c)
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) || c.Written() {
+				return err
+			}
+
+			c.SetHeader(ElapsedHeader, strconv.FormatInt(c.Elapsed().Milliseconds(), 10))
+			return rex.NewError(http.StatusGatewayTimeout, "request deadline exceeded")
+		}
+	}
+}
+
+// grpcUnits are the units of the grpc-timeout header.
+var grpcUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// Parse parses a budget header value, see New for the accepted formats.
+func Parse(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if ms > math.MaxInt64/int64(time.Millisecond) {
+			return math.MaxInt64, true
+		}
+		return time.Duration(ms) * time.Millisecond, true
+	}
+
+	// gRPC allows at most 8 digits, which still overflows a Duration in hours.
+	last := len(value) - 1
+	if unit, ok := grpcUnits[value[last]]; ok && last <= 8 {
+		if n, err := strconv.ParseUint(value[:last], 10, 64); err == nil {
+			if n > uint64(math.MaxInt64/unit) {
+				return math.MaxInt64, true
+			}
+			return time.Duration(n) * unit, true
+		}
+	}
+
+	d, err := time.ParseDuration(value)
+	return d, err == nil
+}
+
+// Remaining returns the time left until the deadline of the request context,
+// or 0 if it has passed. It returns math.MaxInt64 if the request has no deadline.
+func Remaining(c *rex.Context) time.Duration {
+	deadline, ok := c.Request.Context().Deadline()
+	if !ok {
+		return math.MaxInt64
+	}
+	return max(time.Until(deadline), 0)
+}
@@ -0,0 +1,134 @@
+package deadline_test
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/deadline"
+)
+
+// budgetRouter answers the remaining budget of the request, rounded to 100ms.
+func budgetRouter(options ...deadline.Option) *rex.Router {
+	r := rex.NewRouter()
+	r.Use(deadline.New(2*time.Second, options...))
+	r.GET("/", func(c *rex.Context) error {
+		return c.String(deadline.Remaining(c).Round(100 * time.Millisecond).String())
+	})
+	return r
+}
+
+func TestBudget(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"milliseconds", "500", "500ms"},
+		{"duration", "1.5s", "1.5s"},
+		{"grpc", "300m", "300ms"},
+		{"capped", "10s", "2s"},
+		{"grpc overflow", "99999999H", "2s"},
+		{"absent", "", "1s"},
+		{"invalid", "soon", "1s"},
+	}
+
+	r := budgetRouter(deadline.WithDefault(time.Second))
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-Timeout", tt.header)
+			}
+
+			if got := r.Test(req).BodyString(); got != tt.want {
+				t.Errorf("expected a budget of %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCustomHeader(t *testing.T) {
+	r := budgetRouter(deadline.WithHeader("grpc-timeout"))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("grpc-timeout", "1S")
+	if got := r.Test(req).BodyString(); got != "1s" {
+		t.Errorf("expected a budget of 1s, got %s", got)
+	}
+}
+
+func TestExpiredDeadline(t *testing.T) {
+	r := rex.NewRouter()
+	r.Use(deadline.New(time.Second))
+	r.GET("/slow", func(c *rex.Context) error {
+		<-c.Request.Context().Done()
+		return c.Request.Context().Err()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/slow", nil)
+	req.Header.Set("X-Request-Timeout", "50")
+	res := r.Test(req)
+
+	if res.Code != http.StatusGatewayTimeout {
+		t.Fatalf("expected 504, got %d %q", res.Code, res.BodyString())
+	}
+
+	if res.Header(deadline.ElapsedHeader) == "" {
+		t.Error("expected the elapsed time header")
+	}
+}
+
+func TestRemainingDecreases(t *testing.T) {
+	r := rex.NewRouter()
+	r.Use(deadline.New(time.Second))
+	r.GET("/", func(c *rex.Context) error {
+		first := deadline.Remaining(c)
+		time.Sleep(20 * time.Millisecond)
+		second := deadline.Remaining(c)
+
+		if second >= first || first > time.Second {
+			t.Errorf("expected Remaining to decrease from at most 1s, got %s then %s", first, second)
+		}
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestEarlierDeadlineIsKept(t *testing.T) {
+	r := rex.NewRouter()
+	r.Use(deadline.New(time.Second))
+	r.GET("/", func(c *rex.Context) error {
+		if remaining := deadline.Remaining(c); remaining > 100*time.Millisecond {
+			t.Errorf("expected the earlier deadline of the server, got %s", remaining)
+		}
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+}
+
+func TestRemainingWithoutDeadline(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		if deadline.Remaining(c) != math.MaxInt64 {
+			t.Error("expected an unlimited budget without a deadline")
+		}
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+}
+
+func TestParseOverflow(t *testing.T) {
+	for _, value := range []string{"99999999H", "9223372036854775807"} {
+		if d, ok := deadline.Parse(value); !ok || d != math.MaxInt64 {
+			t.Errorf("Parse(%q): expected the maximum duration, got %v %v", value, d, ok)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Package decompress decodes gzip and deflate encoded request bodies
+// for handlers that read c.Request.Body directly.
+package decompress
+
+import (
+	"github.com/abiiranathan/rex"
+)
+
+// Option configures the decompress middleware.
+type Option func(*rex.DecompressLimits)
+
+// WithRatio sets the maximum expansion of the body relative to its Content-Length.
+// Zero disables the ratio check.
+func WithRatio(ratio int64) Option {
+	return func(l *rex.DecompressLimits) {
+		l.Ratio = ratio
+	}
+}
+
+// WithMaxBytes sets the absolute maximum size of the decompressed body.
+// Zero disables the size check.
+func WithMaxBytes(n int64) Option {
+	return func(l *rex.DecompressLimits) {
+		l.MaxBytes = n
+	}
+}
+
+// New returns a middleware that replaces gzip and deflate encoded request bodies
+// with their decompressed stream and removes the Content-Encoding header.
+// Limits default to rex.DefaultDecompressLimits. Reads past the limit fail with
+// rex.ErrDecompressedTooLarge. Invalid encodings are rejected with 400 Bad Request
+// by the router's error handler.
+func New(opts ...Option) rex.Middleware {
+	limits := rex.DefaultDecompressLimits
+	for _, opt := range opts {
+		opt(&limits)
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if err := rex.DecompressBody(c.Request, limits); err != nil {
+				return err
+			}
+			return next(c)
+		}
+	}
+}
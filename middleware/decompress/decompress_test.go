@@ -0,0 +1,93 @@
+package decompress_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/decompress"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func echo(c *rex.Context) error {
+	data, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		if errors.Is(err, rex.ErrDecompressedTooLarge) {
+			return c.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+		return err
+	}
+	return c.String(c.Request.Header.Get("Content-Encoding") + ":" + string(data))
+}
+
+func TestDecompress(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/echo", echo, decompress.New())
+
+	t.Run("gzip", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(gzipped(t, []byte("hello"))))
+		req.Header.Set("Content-Encoding", "gzip")
+		res := r.Test(req)
+		if res.BodyString() != ":hello" {
+			t.Errorf("expected decompressed body without encoding header, got %q", res.BodyString())
+		}
+	})
+
+	t.Run("deflate", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zlib.NewWriter(&buf)
+		zw.Write([]byte("hello"))
+		zw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/echo", &buf)
+		req.Header.Set("Content-Encoding", "deflate")
+		if res := r.Test(req); res.BodyString() != ":hello" {
+			t.Errorf("expected decompressed body, got %q", res.BodyString())
+		}
+	})
+
+	t.Run("identity", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("plain"))
+		if res := r.Test(req); res.BodyString() != ":plain" {
+			t.Errorf("expected body to be untouched, got %q", res.BodyString())
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/echo", strings.NewReader("not gzip"))
+		req.Header.Set("Content-Encoding", "gzip")
+		if res := r.Test(req); res.Status() != http.StatusBadRequest {
+			t.Errorf("expected status 400, got %d", res.Status())
+		}
+	})
+}
+
+func TestDecompressBomb(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/echo", echo, decompress.New(decompress.WithMaxBytes(1<<20)))
+
+	bomb := gzipped(t, bytes.Repeat([]byte{0}, 8<<20))
+	req := httptest.NewRequest(http.MethodPost, "/echo", bytes.NewReader(bomb))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	if res := r.Test(req); res.Status() != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected the read to stop at the limit, got status %d", res.Status())
+	}
+}
@@ -0,0 +1,355 @@
+// Package dedupe suppresses duplicate deliveries of webhooks, which providers like GitHub
+// and Stripe retry aggressively. Unlike the idempotency middleware, which relies on a key
+// sent by the client, the key is derived from attributes of the request.
+//
+// Example:
+//
+//	r.POST("/webhooks/github", handleGitHub, dedupe.New(dedupe.Config{
+//		Keys: []dedupe.KeyFunc{dedupe.Header("X-GitHub-Delivery")},
+//	}))
+//
+//	r.POST("/webhooks/stripe", handleStripe, dedupe.New(dedupe.Config{
+//		Keys:      []dedupe.KeyFunc{dedupe.BodyHash()},
+//		CacheBody: true,
+//	}))
+package dedupe
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/idempotency"
+)
+
+const (
+	// DefaultTTL is how long deliveries are remembered by default.
+	DefaultTTL = 24 * time.Hour
+
+	// DefaultInFlightTTL is how long the in-flight marker of a delivery is kept by default,
+	// so that a crashed request does not block its key forever.
+	DefaultInFlightTTL = time.Minute
+
+	// DefaultMaxBody is the default maximum size of a cached request body and a recorded response body.
+	DefaultMaxBody = 1 << 20
+
+	// HitHeader is set to "hit" on the responses to duplicates.
+	HitHeader = "X-Dedupe"
+)
+
+// ErrBodyNotCached is returned by BodyHash when Config.CacheBody is not set.
+var ErrBodyNotCached = errors.New("dedupe: BodyHash requires Config.CacheBody")
+
+// KeyFunc returns a part of the key of a delivery. body is the request body if
+// Config.CacheBody is set and nil otherwise. An error disables deduplication for the request.
+type KeyFunc func(c *rex.Context, body []byte) (string, error)
+
+// Header returns the value of the request header name, e.g "X-GitHub-Delivery".
+func Header(name string) KeyFunc {
+	return func(c *rex.Context, body []byte) (string, error) {
+		value := c.Request.Header.Get(name)
+		if value == "" {
+			return "", fmt.Errorf("dedupe: missing %s header", name)
+		}
+		return value, nil
+	}
+}
+
+// StripeTimestamp returns the t= timestamp of the Stripe-Signature header.
+// Deliveries signed in the same second share it, combine it with BodyHash.
+func StripeTimestamp() KeyFunc {
+	return func(c *rex.Context, body []byte) (string, error) {
+		for _, part := range strings.Split(c.Request.Header.Get("Stripe-Signature"), ",") {
+			if t, ok := strings.CutPrefix(strings.TrimSpace(part), "t="); ok && t != "" {
+				return t, nil
+			}
+		}
+		return "", errors.New("dedupe: missing timestamp in Stripe-Signature header")
+	}
+}
+
+// BodyHash returns the SHA-256 of the request body. It requires Config.CacheBody.
+func BodyHash() KeyFunc {
+	return func(c *rex.Context, body []byte) (string, error) {
+		if body == nil {
+			return "", ErrBodyNotCached
+		}
+		sum := sha256.Sum256(body)
+		return hex.EncodeToString(sum[:]), nil
+	}
+}
+
+// Config configures the dedupe middleware.
+type Config struct {
+	// Keys derive the key of a delivery, all of them must succeed. Required.
+	Keys []KeyFunc
+
+	// CacheBody reads the request body, up to MaxBody bytes, before the handler runs
+	// so that key functions like BodyHash can use it. The handler reads the same body.
+	CacheBody bool
+
+	// RecordBody stores the response of the first delivery and replays its status,
+	// headers and body to duplicates. By default duplicates get a plain 200 OK.
+	RecordBody bool
+
+	// Store holds the deliveries, it is shared with the idempotency middleware.
+	// The default is an idempotency.MemoryStore, which is not shared between instances.
+	Store idempotency.Store
+
+	// TTL is how long deliveries are remembered. The default is DefaultTTL.
+	TTL time.Duration
+
+	// InFlightTTL is how long the in-flight marker is kept. The default is DefaultInFlightTTL.
+	InFlightTTL time.Duration
+
+	// MaxBody is the maximum size of a cached request body and a recorded response body.
+	// Larger requests are handled without deduplication and larger responses are not recorded.
+	// The default is DefaultMaxBody.
+	MaxBody int
+
+	// Headers are the response headers recorded with RecordBody. The default is idempotency.DefaultHeaders.
+	Headers []string
+
+	// Logger logs the requests handled without deduplication. The default is slog.Default().
+	Logger *slog.Logger
+}
+
+// New creates the dedupe middleware. The first delivery of a key runs the handler and
+// is remembered for TTL, unless it fails with an error or a 5xx status, so that the
+// provider retries it. Duplicates within TTL are answered with HitHeader set, without
+// running the handler. Duplicates of a delivery still in flight get 409 Conflict.
+//
+// Requests whose key can not be derived, e.g because a header is missing, and requests
+// arriving while the store fails, are handled normally and logged as a warning.
+// It panics if no key function is configured.
+func New(config Config) rex.Middleware {
+	if len(config.Keys) == 0 {
+		panic("dedupe: Config.Keys is required")
+	}
+
+	if config.Store == nil {
+		config.Store = idempotency.NewMemoryStore()
+	}
+
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+
+	if config.InFlightTTL <= 0 {
+		config.InFlightTTL = DefaultInFlightTTL
+	}
+
+	if config.MaxBody <= 0 {
+		config.MaxBody = DefaultMaxBody
+	}
+
+	if config.Headers == nil {
+		config.Headers = idempotency.DefaultHeaders
+	}
+
+	if config.Logger == nil {
+		config.Logger = slog.Default()
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			key, err := config.key(c)
+			if err != nil {
+				config.warn(c, "request handled without deduplication", err)
+				return next(c)
+			}
+
+			reserved, err := config.Store.SetNX(key, idempotency.Entry{InFlight: true}, config.InFlightTTL)
+			if err != nil {
+				config.warn(c, "store unavailable, request handled without deduplication", err)
+				return next(c)
+			}
+
+			if reserved {
+				return config.run(c, key, next)
+			}
+
+			entry, found, err := config.Store.Get(key)
+			switch {
+			case err != nil:
+				config.warn(c, "store unavailable, request handled without deduplication", err)
+				return next(c)
+			case !found:
+				// Expired or released after a failure since SetNX.
+				return next(c)
+			case entry.InFlight:
+				c.SetHeader("Retry-After", "1")
+				return rex.NewError(http.StatusConflict, "this delivery is being processed")
+			}
+			return replay(c, entry)
+		}
+	}
+}
+
+func (config *Config) warn(c *rex.Context, msg string, err error) {
+	config.Logger.Warn("dedupe: "+msg, "error", err, "method", c.Request.Method, "path", c.Request.URL.Path)
+}
+
+// key returns the store key of the request, scoped to its route.
+func (config *Config) key(c *rex.Context) (string, error) {
+	var body []byte
+	if config.CacheBody {
+		var err error
+		if body, err = config.cacheBody(c); err != nil {
+			return "", err
+		}
+	}
+
+	parts := []string{c.Request.Method + " " + c.Pattern()}
+	for _, keyFunc := range config.Keys {
+		part, err := keyFunc(c, body)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return "dedupe:" + hex.EncodeToString(sum[:]), nil
+}
+
+// cacheBody reads the request body and replaces it with a reader of the same bytes.
+// Bodies over MaxBody are not cached and the handler reads them in full.
+func (config *Config) cacheBody(c *rex.Context) ([]byte, error) {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return []byte{}, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, int64(config.MaxBody)+1))
+	rest := c.Request.Body
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), rest), rest}
+
+	if err != nil {
+		return nil, fmt.Errorf("dedupe: reading the body: %w", err)
+	}
+
+	if len(body) > config.MaxBody {
+		return nil, fmt.Errorf("dedupe: body exceeds %d bytes", config.MaxBody)
+	}
+	return body, nil
+}
+
+// run executes the handler and remembers the delivery, or releases the key if it failed.
+func (config *Config) run(c *rex.Context, key string, next rex.HandlerFunc) (err error) {
+	rw := &recordWriter{ResponseWriter: c.Response, status: http.StatusOK, max: config.MaxBody, record: config.RecordBody}
+	completed := false
+
+	defer func() {
+		if completed && err == nil && rw.status < http.StatusInternalServerError {
+			entry := idempotency.Entry{Status: rw.status}
+			if rw.record && !rw.overflow {
+				entry = rw.entry(config.Headers)
+			}
+
+			if storeErr := config.Store.Set(key, entry, config.TTL); storeErr == nil {
+				return
+			}
+		}
+		_ = config.Store.Delete(key)
+	}()
+
+	original := c.Response
+	c.Response = rw
+	defer func() { c.Response = original }()
+
+	err = next(c)
+	completed = true
+	return err
+}
+
+// replay answers a duplicate with the recorded response, or a plain 200 OK if it was not recorded.
+func replay(c *rex.Context, entry idempotency.Entry) error {
+	c.SetHeader(HitHeader, "hit")
+	if entry.Header == nil {
+		c.SetHeader("Content-Length", "0")
+		c.WriteHeader(http.StatusOK)
+		return nil
+	}
+
+	for k, v := range entry.Header {
+		c.Response.Header()[k] = slices.Clone(v)
+	}
+	c.SetHeader("Content-Length", strconv.Itoa(len(entry.Body)))
+	c.WriteHeader(entry.Status)
+	_, err := c.Response.Write(entry.Body)
+	return err
+}
+
+// recordWriter passes the response through, recording its status and, if record is set,
+// a copy of the body up to max bytes.
+type recordWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	record      bool
+	max         int
+	buf         bytes.Buffer
+	overflow    bool
+}
+
+func (w *recordWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *recordWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.record && !w.overflow {
+		if w.buf.Len()+len(p) > w.max {
+			w.overflow = true
+			w.buf.Reset()
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *recordWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *recordWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *recordWriter) entry(headers []string) idempotency.Entry {
+	entry := idempotency.Entry{
+		Status: w.status,
+		Header: make(http.Header),
+		Body:   bytes.Clone(w.buf.Bytes()),
+	}
+
+	for _, h := range headers {
+		if v := w.Header().Values(h); len(v) > 0 {
+			entry.Header[http.CanonicalHeaderKey(h)] = slices.Clone(v)
+		}
+	}
+	return entry
+}
@@ -0,0 +1,196 @@
+package dedupe_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/dedupe"
+	"github.com/abiiranathan/rex/middleware/idempotency"
+)
+
+func deliver(r *rex.Router, delivery, body string) *rex.TestResponse {
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	if delivery != "" {
+		req.Header.Set("X-GitHub-Delivery", delivery)
+	}
+	return r.Test(req)
+}
+
+func newWebhookRouter(config dedupe.Config, calls *atomic.Int64) *rex.Router {
+	r := rex.NewRouter()
+	r.POST("/webhooks", func(c *rex.Context) error {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+
+		n := calls.Add(1)
+		c.SetHeader("Location", "/events/1")
+		c.WriteHeader(http.StatusAccepted)
+		return c.JSON(rex.Map{"call": n, "body": string(body)})
+	}, dedupe.New(config))
+	return r
+}
+
+func TestDuplicateDeliveryHandledOnce(t *testing.T) {
+	clock := time.Now()
+	store := idempotency.NewMemoryStore()
+	store.Now = func() time.Time { return clock }
+
+	var calls atomic.Int64
+	r := newWebhookRouter(dedupe.Config{
+		Keys:  []dedupe.KeyFunc{dedupe.Header("X-GitHub-Delivery")},
+		Store: store,
+		TTL:   time.Hour,
+	}, &calls)
+
+	first := deliver(r, "delivery-1", "{}")
+	second := deliver(r, "delivery-1", "{}")
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls.Load())
+	}
+
+	if first.Code != http.StatusAccepted || first.Header(dedupe.HitHeader) != "" {
+		t.Errorf("expected the first delivery to be handled, got %d %q", first.Code, first.Header(dedupe.HitHeader))
+	}
+
+	if second.Code != http.StatusOK || second.Header(dedupe.HitHeader) != "hit" || second.BodyString() != "" {
+		t.Errorf("expected a plain 200 hit, got %d %q %q", second.Code, second.Header(dedupe.HitHeader), second.BodyString())
+	}
+
+	deliver(r, "delivery-2", "{}")
+	if calls.Load() != 2 {
+		t.Errorf("expected a new delivery to be handled, ran %d times", calls.Load())
+	}
+
+	clock = clock.Add(time.Hour + time.Second)
+	deliver(r, "delivery-1", "{}")
+	if calls.Load() != 3 {
+		t.Errorf("expected the delivery to be handled again after the TTL, ran %d times", calls.Load())
+	}
+}
+
+func TestRecordBodyReplaysResponse(t *testing.T) {
+	var calls atomic.Int64
+	r := newWebhookRouter(dedupe.Config{
+		Keys:       []dedupe.KeyFunc{dedupe.Header("X-GitHub-Delivery")},
+		RecordBody: true,
+	}, &calls)
+
+	first := deliver(r, "delivery-1", "{}")
+	second := deliver(r, "delivery-1", "{}")
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls.Load())
+	}
+
+	if second.Code != http.StatusAccepted || second.BodyString() != first.BodyString() {
+		t.Errorf("expected the recorded response, got %d %q", second.Code, second.BodyString())
+	}
+
+	if second.Header("Location") != "/events/1" || second.Header(dedupe.HitHeader) != "hit" {
+		t.Errorf("expected the recorded headers, got %v", second.Result().Header)
+	}
+}
+
+func TestBodyHashKey(t *testing.T) {
+	var calls atomic.Int64
+	r := newWebhookRouter(dedupe.Config{
+		Keys:      []dedupe.KeyFunc{dedupe.BodyHash()},
+		CacheBody: true,
+	}, &calls)
+
+	first := deliver(r, "", `{"id": "evt_1"}`)
+	deliver(r, "", `{"id": "evt_1"}`)
+	deliver(r, "", `{"id": "evt_2"}`)
+
+	if calls.Load() != 2 {
+		t.Errorf("expected one run per distinct body, ran %d times", calls.Load())
+	}
+
+	if !strings.Contains(first.BodyString(), `evt_1`) {
+		t.Errorf("expected the handler to read the cached body, got %q", first.BodyString())
+	}
+}
+
+func TestStripeTimestampKey(t *testing.T) {
+	var calls atomic.Int64
+	r := newWebhookRouter(dedupe.Config{
+		Keys:      []dedupe.KeyFunc{dedupe.StripeTimestamp(), dedupe.BodyHash()},
+		CacheBody: true,
+	}, &calls)
+
+	for _, signature := range []string{"t=1700000000,v1=abc", "t=1700000000,v1=def", "t=1700000001,v1=abc"} {
+		req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(`{"id": "evt_1"}`))
+		req.Header.Set("Stripe-Signature", signature)
+		r.Test(req)
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("expected one run per timestamp, ran %d times", calls.Load())
+	}
+}
+
+func TestMissingKeyFailsOpen(t *testing.T) {
+	var logs bytes.Buffer
+	var calls atomic.Int64
+	r := newWebhookRouter(dedupe.Config{
+		Keys:   []dedupe.KeyFunc{dedupe.Header("X-GitHub-Delivery")},
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	}, &calls)
+
+	deliver(r, "", "{}")
+	res := deliver(r, "", "{}")
+
+	if calls.Load() != 2 || res.Code != http.StatusAccepted {
+		t.Errorf("expected requests without a key to be handled, ran %d times", calls.Load())
+	}
+
+	if !strings.Contains(logs.String(), "level=WARN") || !strings.Contains(logs.String(), "missing X-GitHub-Delivery header") {
+		t.Errorf("expected a warning, got %q", logs.String())
+	}
+}
+
+func TestBodyHashWithoutCacheBodyFailsOpen(t *testing.T) {
+	var logs bytes.Buffer
+	var calls atomic.Int64
+	r := newWebhookRouter(dedupe.Config{
+		Keys:   []dedupe.KeyFunc{dedupe.BodyHash()},
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+	}, &calls)
+
+	deliver(r, "", "{}")
+	deliver(r, "", "{}")
+
+	if calls.Load() != 2 || !strings.Contains(logs.String(), dedupe.ErrBodyNotCached.Error()) {
+		t.Errorf("expected both requests to be handled with a warning, ran %d times, logs %q", calls.Load(), logs.String())
+	}
+}
+
+func TestFailedDeliveryIsRetried(t *testing.T) {
+	var calls atomic.Int64
+	r := rex.NewRouter()
+	r.POST("/webhooks", func(c *rex.Context) error {
+		if calls.Add(1) == 1 {
+			return rex.NewError(http.StatusServiceUnavailable, "database down")
+		}
+		return c.String("ok")
+	}, dedupe.New(dedupe.Config{Keys: []dedupe.KeyFunc{dedupe.Header("X-GitHub-Delivery")}}))
+
+	deliver(r, "delivery-1", "{}")
+	res := deliver(r, "delivery-1", "{}")
+	deliver(r, "delivery-1", "{}")
+
+	if calls.Load() != 2 || res.BodyString() != "ok" {
+		t.Errorf("expected the retry of a failed delivery to be handled once, ran %d times", calls.Load())
+	}
+}
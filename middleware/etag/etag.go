@@ -9,6 +9,7 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strings"
 
 	"github.com/abiiranathan/rex"
 )
@@ -54,7 +55,34 @@ func (e *etagResponseWriter) Status() int {
 	return e.status
 }
 
+// identityHash hashes the uncompressed body written by a compression middleware
+// running inside etag.
+type identityHash struct {
+	hash.Hash
+	used bool
+}
+
+func (h *identityHash) Write(p []byte) (int, error) {
+	h.used = true
+	return h.Hash.Write(p)
+}
+
+// matches reports whether the If-None-Match header value matches etag,
+// using the weak comparison of RFC 9110.
+func matches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
 // Create a new etag middleware.
+// The ETag is computed over the uncompressed body. Responses compressed by a middleware
+// that sets rex.ContentEncodingKey, like brotli.Brotli, get the encoding appended,
+// e.g "abc123-br", whichever of the two middlewares runs first.
 func New(skip ...func(r *http.Request) bool) rex.Middleware {
 	return func(next rex.HandlerFunc) rex.HandlerFunc {
 		return func(c *rex.Context) error {
@@ -83,6 +111,10 @@ func New(skip ...func(r *http.Request) bool) rex.Middleware {
 
 			ew.w = io.MultiWriter(&ew.buf, ew.hash)
 
+			// A compression middleware running inside writes the uncompressed body here.
+			identity := &identityHash{Hash: sha1.New()}
+			c.Set(rex.IdentityWriterKey, identity)
+
 			// Override the response writer
 			// This may cause some incompatibilities where
 			// the Response is assumed to be rex.ResponseWriter
@@ -104,12 +136,20 @@ func New(skip ...func(r *http.Request) bool) rex.Middleware {
 				return err
 			}
 
-			etag := fmt.Sprintf(`"%x"`, ew.hash.Sum(nil))
+			sum := ew.hash.Sum(nil)
+			if identity.used {
+				sum = identity.Sum(nil)
+			}
+
+			etag := fmt.Sprintf(`"%x"`, sum)
+			if encoding, ok := c.Get(rex.ContentEncodingKey); ok && encoding != "" {
+				etag = fmt.Sprintf(`"%x-%s"`, sum, encoding)
+			}
 			c.SetHeader("ETag", etag)
 
 			// Check If-None-Match and If-Match headers and return 304 or 412 if needed
 			ifNoneMatch := c.GetHeader("If-None-Match")
-			if ifNoneMatch == etag {
+			if ifNoneMatch != "" && matches(ifNoneMatch, etag) {
 				return c.WriteHeader(http.StatusNotModified)
 			}
 
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/brotli"
 	"github.com/abiiranathan/rex/middleware/etag"
 )
 
@@ -59,3 +61,65 @@ func TestEtag(t *testing.T) {
 
 	fmt.Println(w.Body.String())
 }
+
+func TestEtagWithCompression(t *testing.T) {
+	body := strings.Repeat("Hello World! ", 100)
+	identity := fmt.Sprintf(`"%x"`, sha1.Sum([]byte(body)))
+
+	orders := map[string][]rex.Middleware{
+		"brotli first": {brotli.Brotli(), etag.New()},
+		"etag first":   {etag.New(), brotli.Brotli()},
+	}
+
+	for name, middlewares := range orders {
+		t.Run(name, func(t *testing.T) {
+			router := rex.NewRouter()
+			router.Use(middlewares...)
+			router.GET("/", func(c *rex.Context) error {
+				return c.String(body)
+			})
+
+			get := func(acceptEncoding, ifNoneMatch string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Header.Set("Accept-Encoding", acceptEncoding)
+				req.Header.Set("If-None-Match", ifNoneMatch)
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, req)
+				return w
+			}
+
+			plain := get("", "")
+			if plain.Header().Get("ETag") != identity || plain.Body.String() != body {
+				t.Errorf("expected the identity ETag %s, got %s", identity, plain.Header().Get("ETag"))
+			}
+
+			compressed := get("br", "")
+			if got := compressed.Header().Get("ETag"); got != strings.TrimSuffix(identity, `"`)+`-br"` {
+				t.Errorf("expected the ETag of the uncompressed body with the encoding, got %s", got)
+			}
+
+			if compressed.Header().Get("Content-Encoding") != "br" || compressed.Body.Len() >= len(body) {
+				t.Errorf("expected a compressed body, got %d bytes", compressed.Body.Len())
+			}
+
+			for _, w := range []*httptest.ResponseRecorder{plain, compressed} {
+				if w.Header().Get("Vary") != "Accept-Encoding" {
+					t.Errorf("expected Vary: Accept-Encoding, got %q", w.Header().Get("Vary"))
+				}
+			}
+
+			if w := get("br", compressed.Header().Get("ETag")); w.Code != http.StatusNotModified {
+				t.Errorf("expected 304 for the same encoding, got %d", w.Code)
+			}
+
+			// A client switching encodings must not reuse the cached compressed body.
+			if w := get("", compressed.Header().Get("ETag")); w.Code != http.StatusOK || w.Body.String() != body {
+				t.Errorf("expected the full identity response, got %d", w.Code)
+			}
+
+			if w := get("br", `"other", W/`+compressed.Header().Get("ETag")); w.Code != http.StatusNotModified {
+				t.Errorf("expected 304 for a list containing the weak ETag, got %d", w.Code)
+			}
+		})
+	}
+}
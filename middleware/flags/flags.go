@@ -0,0 +1,221 @@
+// Package flags evaluates feature flags and A/B tests once per request with sticky assignment.
+// Each request belongs to a unit, the authenticated user or an anonymous visitor identified by
+// a cookie created on the first visit, and a Provider assigns the unit a variant of every flag.
+//
+// Example:
+//
+//	r.Use(flags.New(flags.Config{
+//		Provider: flags.Percentage{
+//			"new-checkout": flags.Rollout(10),
+//			"hero-test":    {{Variant: "a", Percent: 50}, {Variant: "b", Percent: 50}},
+//		},
+//		Flags: []string{"new-checkout", "hero-test"},
+//	}))
+//
+//	r.GET("/checkout", func(c *rex.Context) error {
+//		if flags.Enabled(c, "new-checkout") {
+//			return c.Render("checkout_v2", rex.Map{})
+//		}
+//		return c.Render("checkout", rex.Map{})
+//	})
+//
+// Templates rendered with rex.PassContextToViews(true) see the variants as the "flags" map,
+// e.g {{ if eq (index .flags "hero-test") "b" }}. Use LogArgs as the logger middleware Callback
+// to record them with each request.
+package flags
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"hash/fnv"
+	"log/slog"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// Variants assigned by providers.
+const (
+	Off = ""   // the flag is disabled for the unit
+	On  = "on" // the variant of flags created with Rollout
+)
+
+// Provider assigns variants of flags to units. It must return the same variant for a unit
+// as long as the configuration does not change.
+type Provider interface {
+	Evaluate(flag string, unit string) (variant string)
+}
+
+// Allocation assigns Percent (0-100) of the units to Variant.
+type Allocation struct {
+	Variant string
+	Percent float64
+}
+
+// Rollout returns the allocations enabling a flag for percent of the units.
+func Rollout(percent float64) []Allocation {
+	return []Allocation{{Variant: On, Percent: percent}}
+}
+
+// Percentage is a Provider assigning units to the allocations of each flag by a hash of the
+// flag name and unit, in the order of the allocations. Units past the allocations and
+// unknown flags get Off. Flags are hashed independently, so a unit in the first 10% of one
+// flag is not in the first 10% of every flag.
+type Percentage map[string][]Allocation
+
+// Evaluate implements Provider.
+func (p Percentage) Evaluate(flag string, unit string) string {
+	b := bucket(flag, unit)
+
+	var upper float64
+	for _, allocation := range p[flag] {
+		upper += allocation.Percent * 100
+		if float64(b) < upper {
+			return allocation.Variant
+		}
+	}
+	return Off
+}
+
+// bucket returns the bucket, 0-9999, of the unit for flag.
+func bucket(flag, unit string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(flag))
+	h.Write([]byte{0})
+	h.Write([]byte(unit))
+	return h.Sum64() % 10000
+}
+
+// Config configures the middleware.
+type Config struct {
+	Provider Provider // Required.
+	Flags    []string // Flags evaluated for every request. Others are evaluated when first read.
+
+	// UnitID returns the ID of the authenticated user, e.g from auth.GetAuthState.
+	// Requests without one are identified by the anonymous ID in the cookie.
+	UnitID func(c *rex.Context) (string, bool)
+
+	CookieName   string        // Cookie of the anonymous ID. Default is "rex_unit".
+	CookieMaxAge time.Duration // Default is one year.
+}
+
+// TemplateKey is the key of the variants in the template data.
+const TemplateKey = "flags"
+
+type contextKey struct{}
+
+// state holds the unit and the evaluated flags of a request.
+type state struct {
+	provider Provider
+	unit     string
+	variants map[string]string
+}
+
+func (s *state) variant(flag string) string {
+	v, ok := s.variants[flag]
+	if !ok {
+		v = s.provider.Evaluate(flag, s.unit)
+		s.variants[flag] = v
+	}
+	return v
+}
+
+// New creates the middleware. It panics if config.Provider is nil.
+func New(config Config) rex.Middleware {
+	if config.Provider == nil {
+		panic("flags: the Provider must not be nil")
+	}
+
+	if config.CookieName == "" {
+		config.CookieName = "rex_unit"
+	}
+
+	if config.CookieMaxAge == 0 {
+		config.CookieMaxAge = 365 * 24 * time.Hour
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			s := &state{provider: config.Provider, unit: unitID(c, &config), variants: make(map[string]string)}
+			for _, flag := range config.Flags {
+				s.variant(flag)
+			}
+
+			c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), contextKey{}, s))
+			c.Set(TemplateKey, s.variants)
+			return next(c)
+		}
+	}
+}
+
+// unitID returns the user ID or the anonymous ID of the request, creating the cookie if needed.
+func unitID(c *rex.Context, config *Config) string {
+	if config.UnitID != nil {
+		if id, ok := config.UnitID(c); ok && id != "" {
+			return "user:" + id
+		}
+	}
+
+	if cookie, err := c.Request.Cookie(config.CookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		panic(err)
+	}
+
+	value := hex.EncodeToString(id)
+	http.SetCookie(c.Response, &http.Cookie{
+		Name:     config.CookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(config.CookieMaxAge.Seconds()),
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return value
+}
+
+func from(r *http.Request) (*state, bool) {
+	s, ok := r.Context().Value(contextKey{}).(*state)
+	return s, ok
+}
+
+// Variant returns the variant of flag for the request, Off without the middleware.
+func Variant(c *rex.Context, flag string) string {
+	if s, ok := from(c.Request); ok {
+		return s.variant(flag)
+	}
+	return Off
+}
+
+// Enabled reports whether flag is not Off for the request.
+func Enabled(c *rex.Context, flag string) bool {
+	return Variant(c, flag) != Off
+}
+
+// LogArgs appends the evaluated flags of r to args as a "flags" group.
+// It is a Callback for the logger middleware, which must run before this middleware.
+func LogArgs(r *http.Request, args ...any) []any {
+	s, ok := from(r)
+	if !ok || len(s.variants) == 0 {
+		return args
+	}
+
+	names := make([]string, 0, len(s.variants))
+	for name := range s.variants {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	attrs := make([]slog.Attr, 0, len(names))
+	for _, name := range names {
+		attrs = append(attrs, slog.String(name, s.variants[name]))
+	}
+	return append(args, "flags", slog.GroupValue(attrs...))
+}
@@ -0,0 +1,155 @@
+package flags_test
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/flags"
+	"github.com/abiiranathan/rex/middleware/logger"
+)
+
+var provider = flags.Percentage{
+	"new-checkout": flags.Rollout(10),
+	"hero-test":    {{Variant: "a", Percent: 50}, {Variant: "b", Percent: 50}},
+}
+
+func TestPercentageDistribution(t *testing.T) {
+	const units = 20000
+
+	counts := map[string]int{}
+	for i := 0; i < units; i++ {
+		unit := fmt.Sprintf("unit-%d", i)
+		counts["checkout:"+provider.Evaluate("new-checkout", unit)]++
+		counts["hero:"+provider.Evaluate("hero-test", unit)]++
+	}
+
+	for key, want := range map[string]float64{"checkout:on": 0.10, "hero:a": 0.50, "hero:b": 0.50} {
+		got := float64(counts[key]) / units
+		if math.Abs(got-want) > 0.015 {
+			t.Errorf("%s: expected about %.2f of the units, got %.3f", key, want, got)
+		}
+	}
+
+	if counts["hero:"] != 0 {
+		t.Errorf("expected every unit in a hero variant, got %d off", counts["hero:"])
+	}
+
+	if v := provider.Evaluate("unknown", "unit-1"); v != flags.Off {
+		t.Errorf("expected unknown flags to be off, got %q", v)
+	}
+}
+
+func TestPercentageIsSticky(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		unit := fmt.Sprintf("unit-%d", i)
+		first := provider.Evaluate("hero-test", unit)
+		for j := 0; j < 5; j++ {
+			if got := provider.Evaluate("hero-test", unit); got != first {
+				t.Fatalf("%s: expected %q on every evaluation, got %q", unit, first, got)
+			}
+		}
+	}
+}
+
+func flagRouter(config flags.Config) *rex.Router {
+	r := rex.NewRouter()
+	r.Use(flags.New(config))
+	r.GET("/", func(c *rex.Context) error {
+		return c.String(fmt.Sprintf("%v %s", flags.Enabled(c, "new-checkout"), flags.Variant(c, "hero-test")))
+	})
+	return r
+}
+
+func TestCookieAssignmentIsSticky(t *testing.T) {
+	r := flagRouter(flags.Config{Provider: provider, Flags: []string{"new-checkout", "hero-test"}})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "rex_unit" || !cookies[0].HttpOnly {
+		t.Fatalf("expected the anonymous ID cookie, got %v", cookies)
+	}
+
+	unit := cookies[0].Value
+	want := fmt.Sprintf("%v %s", provider.Evaluate("new-checkout", unit) == flags.On, provider.Evaluate("hero-test", unit))
+	if w.Body.String() != want {
+		t.Errorf("expected %q, got %q", want, w.Body.String())
+	}
+
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(cookies[0])
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != want || len(w.Result().Cookies()) != 0 {
+			t.Errorf("expected the same variants without a new cookie, got %q %v", w.Body.String(), w.Result().Cookies())
+		}
+	}
+}
+
+func TestUnitIDExtractor(t *testing.T) {
+	r := flagRouter(flags.Config{
+		Provider: provider,
+		UnitID: func(c *rex.Context) (string, bool) {
+			id := c.GetHeader("X-User")
+			return id, id != ""
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-User", "42")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if len(w.Result().Cookies()) != 0 {
+		t.Errorf("expected no anonymous cookie for users, got %v", w.Result().Cookies())
+	}
+
+	want := fmt.Sprintf("%v %s", provider.Evaluate("new-checkout", "user:42") == flags.On, provider.Evaluate("hero-test", "user:42"))
+	if w.Body.String() != want {
+		t.Errorf("expected the variants of the user, got %q want %q", w.Body.String(), want)
+	}
+}
+
+func TestTemplateAndLogSeeFlags(t *testing.T) {
+	tmpl := template.Must(template.New("base.html").Parse(
+		`{{ define "base.html" }}{{ .Content }}{{ end }}{{ define "page.html" }}hero={{ index .flags "hero-test" }}{{ end }}`))
+
+	var logs bytes.Buffer
+	r := rex.NewRouter(rex.WithTemplates(tmpl), rex.BaseLayout("base.html"), rex.ContentBlock("Content"), rex.PassContextToViews(true))
+	r.Use(logger.New(&logger.Config{
+		Output:   &logs,
+		Options:  &slog.HandlerOptions{Level: slog.LevelInfo},
+		Callback: flags.LogArgs,
+	}))
+	r.Use(flags.New(flags.Config{
+		Provider: flags.Percentage{"hero-test": {{Variant: "b", Percent: 100}}},
+		Flags:    []string{"hero-test"},
+	}))
+
+	r.GET("/", func(c *rex.Context) error {
+		return c.Render("page.html", rex.Map{})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if !strings.Contains(w.Body.String(), "hero=b") {
+		t.Errorf("expected the template to see the flags, got %q", w.Body.String())
+	}
+
+	if !strings.Contains(logs.String(), "flags.hero-test=b") {
+		t.Errorf("expected the flags in the log, got %q", logs.String())
+	}
+}
@@ -0,0 +1,450 @@
+// Package idempotency replays the stored response of requests retried with the same
+// Idempotency-Key header, so that a retried POST does not repeat its side effects.
+//
+// Example:
+//
+//	r.POST("/payments", createPayment, idempotency.New(idempotency.Config{Required: true}))
+package idempotency
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/auth"
+)
+
+const (
+	// DefaultHeader is the request header holding the idempotency key.
+	DefaultHeader = "Idempotency-Key"
+
+	// DefaultTTL is how long responses are stored by default.
+	DefaultTTL = 24 * time.Hour
+
+	// DefaultInFlightTTL is how long the in-flight marker of a request is kept by default,
+	// so that a crashed request does not block its key forever.
+	DefaultInFlightTTL = time.Minute
+
+	// DefaultMaxBody is the default maximum size of a stored response body.
+	DefaultMaxBody = 1 << 20
+
+	// ReplayedHeader is set to "true" on replayed responses.
+	ReplayedHeader = "Idempotent-Replayed"
+
+	// WarningHeader is set on responses that were too large to be stored.
+	// Retries of these requests run the handler again.
+	WarningHeader = "Idempotency-Warning"
+)
+
+// pollInterval is how often waiting requests check if the original request completed.
+const pollInterval = 20 * time.Millisecond
+
+// DefaultHeaders are the response headers stored and replayed by default.
+var DefaultHeaders = []string{
+	"Cache-Control",
+	"Content-Language",
+	"Content-Type",
+	"ETag",
+	"Last-Modified",
+	"Location",
+}
+
+// Entry is the state of an idempotency key in a Store.
+type Entry struct {
+	InFlight bool        // The original request is still running.
+	Status   int         // Status of the stored response.
+	Header   http.Header // Allowlisted headers of the stored response.
+	Body     []byte      // Body of the stored response.
+}
+
+// Store holds the entries of idempotency keys.
+type Store interface {
+	// Get returns the entry of key, false if there is none or it expired.
+	Get(key string) (Entry, bool, error)
+
+	// SetNX stores entry under key for ttl if there is no entry and reports whether it did.
+	SetNX(key string, entry Entry, ttl time.Duration) (bool, error)
+
+	// Set stores entry under key for ttl, replacing the existing entry.
+	Set(key string, entry Entry, ttl time.Duration) error
+
+	// Delete removes the entry of key.
+	Delete(key string) error
+}
+
+// Config configures the idempotency middleware.
+type Config struct {
+	// Header is the request header holding the key. The default is DefaultHeader.
+	Header string
+
+	// Required rejects requests without a key with 400 Bad Request.
+	// By default they run without idempotency.
+	Required bool
+
+	// Methods are the methods the middleware applies to. The default is POST and PATCH.
+	Methods []string
+
+	// Principal identifies the user of the request so that keys of different users never collide.
+	// The default is the cookie auth state or the JWT payload of the auth package,
+	// falling back to the Authorization header.
+	Principal func(c *rex.Context) string
+
+	// Store holds the entries. The default is a MemoryStore, which is not shared between instances.
+	Store Store
+
+	// TTL is how long responses are stored. The default is DefaultTTL.
+	TTL time.Duration
+
+	// InFlightTTL is how long the in-flight marker is kept. The default is DefaultInFlightTTL.
+	InFlightTTL time.Duration
+
+	// MaxBody is the maximum size of a stored body. Larger responses are sent with
+	// WarningHeader and not stored. The default is DefaultMaxBody.
+	MaxBody int
+
+	// Headers are the response headers stored and replayed. The default is DefaultHeaders.
+	Headers []string
+
+	// Wait makes duplicates of a request still in flight wait for its response.
+	// By default they are rejected with 409 Conflict and Retry-After.
+	Wait bool
+}
+
+func defaultPrincipal(c *rex.Context) string {
+	if state, authenticated := auth.GetAuthState(c); authenticated {
+		return fmt.Sprint(state)
+	}
+
+	if payload := auth.GetPayload(c.Request); payload != nil {
+		return fmt.Sprint(payload)
+	}
+	return c.Request.Header.Get("Authorization")
+}
+
+// New creates the idempotency middleware. The first request with a key runs the handler
+// and its response is stored, unless it fails with an error or a 5xx status, so that it can be retried.
+// Retries within TTL receive the stored status, headers and body with ReplayedHeader set.
+// Keys are scoped to the route and the principal of the request.
+func New(config Config) rex.Middleware {
+	if config.Header == "" {
+		config.Header = DefaultHeader
+	}
+
+	if config.Methods == nil {
+		config.Methods = []string{http.MethodPost, http.MethodPatch}
+	}
+
+	if config.Principal == nil {
+		config.Principal = defaultPrincipal
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	if config.TTL <= 0 {
+		config.TTL = DefaultTTL
+	}
+
+	if config.InFlightTTL <= 0 {
+		config.InFlightTTL = DefaultInFlightTTL
+	}
+
+	if config.MaxBody <= 0 {
+		config.MaxBody = DefaultMaxBody
+	}
+
+	if config.Headers == nil {
+		config.Headers = DefaultHeaders
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if !slices.Contains(config.Methods, c.Request.Method) {
+				return next(c)
+			}
+
+			key := c.Request.Header.Get(config.Header)
+			if key == "" {
+				if config.Required {
+					return rex.NewError(http.StatusBadRequest, config.Header+" header is required")
+				}
+				return next(c)
+			}
+			return config.serve(c, config.scope(c, key), next)
+		}
+	}
+}
+
+// scope returns the store key of key for the route and principal of the request.
+func (config *Config) scope(c *rex.Context, key string) string {
+	sum := sha256.Sum256([]byte(c.Request.Method + " " + c.Pattern() + "\x00" + config.Principal(c) + "\x00" + key))
+	return "idempotency:" + hex.EncodeToString(sum[:])
+}
+
+// serve runs the handler for the first request with key and replays the response for retries.
+func (config *Config) serve(c *rex.Context, key string, next rex.HandlerFunc) error {
+	for {
+		reserved, err := config.Store.SetNX(key, Entry{InFlight: true}, config.InFlightTTL)
+		if err != nil {
+			return rex.NewError(http.StatusServiceUnavailable, "idempotency: store unavailable")
+		}
+
+		if reserved {
+			return config.run(c, key, next)
+		}
+
+		entry, found, err := config.Store.Get(key)
+		if err != nil {
+			return rex.NewError(http.StatusServiceUnavailable, "idempotency: store unavailable")
+		}
+
+		switch {
+		case !found:
+			// The entry expired or was deleted after a failure, try to reserve it again.
+			continue
+		case !entry.InFlight:
+			return replay(c, entry)
+		case !config.Wait:
+			c.SetHeader("Retry-After", "1")
+			return rex.NewError(http.StatusConflict, "a request with this idempotency key is in progress")
+		}
+
+		select {
+		case <-time.After(pollInterval):
+		case <-c.Request.Context().Done():
+			return c.Request.Context().Err()
+		}
+	}
+}
+
+// run executes the handler and stores its response. The key is released if the response
+// can not be stored, so that the request can be retried.
+func (config *Config) run(c *rex.Context, key string, next rex.HandlerFunc) (err error) {
+	cw := &captureWriter{ResponseWriter: c.Response, status: http.StatusOK, max: config.MaxBody}
+	completed := false
+
+	defer func() {
+		if completed && err == nil && !cw.overflow && cw.status < http.StatusInternalServerError {
+			entry := cw.entry(config.Headers)
+			if storeErr := config.Store.Set(key, entry, config.TTL); storeErr == nil {
+				return
+			}
+		}
+
+		_ = config.Store.Delete(key)
+	}()
+
+	original := c.Response
+	c.Response = cw
+	defer func() { c.Response = original }()
+
+	err = next(c)
+	completed = true
+
+	if flushErr := cw.flush(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// replay writes the stored response.
+func replay(c *rex.Context, entry Entry) error {
+	for k, v := range entry.Header {
+		c.Response.Header()[k] = slices.Clone(v)
+	}
+	c.Response.Header().Set(ReplayedHeader, "true")
+	c.Response.Header().Set("Content-Length", strconv.Itoa(len(entry.Body)))
+
+	c.Response.WriteHeader(entry.Status)
+	_, err := c.Response.Write(entry.Body)
+	return err
+}
+
+// captureWriter buffers the response until the handler returns, so that the response
+// can be stored. Bodies over max are passed through with WarningHeader instead.
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	max         int
+	buf         bytes.Buffer
+	overflow    bool
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+
+	w.status = status
+	w.wroteHeader = true
+	if w.overflow {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.overflow {
+		return w.ResponseWriter.Write(p)
+	}
+
+	if w.buf.Len()+len(p) > w.max {
+		w.passThrough(fmt.Sprintf("response not stored, it exceeds %d bytes", w.max))
+		return w.ResponseWriter.Write(p)
+	}
+	return w.buf.Write(p)
+}
+
+// Flush sends the response, streamed responses are not stored.
+func (w *captureWriter) Flush() {
+	if !w.overflow {
+		w.passThrough("streamed response not stored")
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// passThrough sends the buffered response with WarningHeader and stops buffering.
+func (w *captureWriter) passThrough(warning string) {
+	w.overflow = true
+	w.Header().Set(WarningHeader, warning)
+	w.Header().Del("Content-Length")
+
+	if w.wroteHeader {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// flush sends the buffered response once the handler returned.
+func (w *captureWriter) flush() error {
+	if w.overflow || !w.wroteHeader {
+		return nil
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	_, err := w.ResponseWriter.Write(w.buf.Bytes())
+	return err
+}
+
+func (w *captureWriter) entry(headers []string) Entry {
+	entry := Entry{
+		Status: w.status,
+		Header: make(http.Header),
+		Body:   bytes.Clone(w.buf.Bytes()),
+	}
+
+	for _, h := range headers {
+		if v := w.Header().Values(h); len(v) > 0 {
+			entry.Header[http.CanonicalHeaderKey(h)] = slices.Clone(v)
+		}
+	}
+	return entry
+}
+
+// MemoryStore is an in-memory Store. Expired entries are removed as new keys are stored.
+type MemoryStore struct {
+	// Now returns the current time. The default is time.Now.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+type memoryEntry struct {
+	entry   Entry
+	expires time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryEntry)}
+}
+
+func (s *MemoryStore) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+// Get implements Store.
+func (s *MemoryStore) Get(key string) (Entry, bool, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !now.Before(e.expires) {
+		return Entry{}, false, nil
+	}
+	return e.entry, true, nil
+}
+
+// SetNX implements Store.
+func (s *MemoryStore) SetNX(key string, entry Entry, ttl time.Duration) (bool, error) {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, e := range s.entries {
+			if !now.Before(e.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	if e, ok := s.entries[key]; ok && now.Before(e.expires) {
+		return false, nil
+	}
+
+	s.entries[key] = memoryEntry{entry: entry, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Set implements Store.
+func (s *MemoryStore) Set(key string, entry Entry, ttl time.Duration) error {
+	now := s.now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key] = memoryEntry{entry: entry, expires: now.Add(ttl)}
+	return nil
+}
+
+// Delete implements Store.
+func (s *MemoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
@@ -0,0 +1,225 @@
+package idempotency_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/idempotency"
+)
+
+func post(r *rex.Router, path, key, user string) *rex.TestResponse {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{"amount": 100}`))
+	if key != "" {
+		req.Header.Set(idempotency.DefaultHeader, key)
+	}
+
+	if user != "" {
+		req.Header.Set("Authorization", "Bearer "+user)
+	}
+	return r.Test(req)
+}
+
+func newPaymentRouter(config idempotency.Config, calls *atomic.Int64) *rex.Router {
+	r := rex.NewRouter()
+	r.POST("/payments", func(c *rex.Context) error {
+		n := calls.Add(1)
+		c.SetHeader("Location", "/payments/1")
+		c.WriteHeader(http.StatusCreated)
+		return c.JSON(rex.Map{"id": 1, "call": n})
+	}, idempotency.New(config))
+	return r
+}
+
+func TestReplayReturnsStoredResponse(t *testing.T) {
+	var calls atomic.Int64
+	r := newPaymentRouter(idempotency.Config{}, &calls)
+
+	first := post(r, "/payments", "key-1", "alice")
+	second := post(r, "/payments", "key-1", "alice")
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", calls.Load())
+	}
+
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Errorf("expected 201 for both requests, got %d and %d", first.Code, second.Code)
+	}
+
+	if first.BodyString() != second.BodyString() {
+		t.Errorf("expected byte-identical bodies, got %q and %q", first.BodyString(), second.BodyString())
+	}
+
+	if second.Header("Location") != "/payments/1" || second.Header("Content-Type") != "application/json" {
+		t.Errorf("expected the stored headers, got %v", second.Result().Header)
+	}
+
+	if first.Header(idempotency.ReplayedHeader) != "" || second.Header(idempotency.ReplayedHeader) != "true" {
+		t.Errorf("expected only the retry to be marked as replayed")
+	}
+}
+
+func TestKeysAreScoped(t *testing.T) {
+	var calls atomic.Int64
+	r := newPaymentRouter(idempotency.Config{}, &calls)
+
+	post(r, "/payments", "key-1", "alice")
+	post(r, "/payments", "key-1", "bob")
+	post(r, "/payments", "key-2", "alice")
+
+	if calls.Load() != 3 {
+		t.Errorf("expected keys of other users and new keys to run the handler, ran %d times", calls.Load())
+	}
+}
+
+func TestRequiredKey(t *testing.T) {
+	var calls atomic.Int64
+
+	res := post(newPaymentRouter(idempotency.Config{}, &calls), "/payments", "", "alice")
+	if res.Code != http.StatusCreated {
+		t.Errorf("expected requests without a key to run, got %d", res.Code)
+	}
+
+	res = post(newPaymentRouter(idempotency.Config{Required: true}, &calls), "/payments", "", "alice")
+	if res.Code != http.StatusBadRequest || calls.Load() != 1 {
+		t.Errorf("expected 400 for a missing key, got %d", res.Code)
+	}
+}
+
+// slowRouter returns a router whose handler blocks until release is closed.
+func slowRouter(config idempotency.Config, calls *atomic.Int64, started chan<- struct{}, release <-chan struct{}) *rex.Router {
+	r := rex.NewRouter()
+	r.POST("/payments", func(c *rex.Context) error {
+		calls.Add(1)
+		started <- struct{}{}
+		<-release
+		return c.String("charged")
+	}, idempotency.New(config))
+	return r
+}
+
+func TestConcurrentDuplicateConflict(t *testing.T) {
+	var calls atomic.Int64
+	started, release := make(chan struct{}, 2), make(chan struct{})
+	r := slowRouter(idempotency.Config{}, &calls, started, release)
+
+	done := make(chan *rex.TestResponse)
+	go func() { done <- post(r, "/payments", "key-1", "alice") }()
+	<-started
+
+	dup := post(r, "/payments", "key-1", "alice")
+	if dup.Code != http.StatusConflict || dup.Header("Retry-After") == "" {
+		t.Errorf("expected 409 with Retry-After for the in-flight key, got %d %v", dup.Code, dup.Result().Header)
+	}
+
+	close(release)
+	if res := <-done; res.BodyString() != "charged" {
+		t.Errorf("expected the original response, got %q", res.BodyString())
+	}
+
+	if calls.Load() != 1 {
+		t.Errorf("expected the handler to run once, ran %d times", calls.Load())
+	}
+}
+
+func TestConcurrentDuplicateWaits(t *testing.T) {
+	var calls atomic.Int64
+	started, release := make(chan struct{}, 8), make(chan struct{})
+	r := slowRouter(idempotency.Config{Wait: true}, &calls, started, release)
+
+	var wg sync.WaitGroup
+	responses := make([]*rex.TestResponse, 5)
+	for i := range responses {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			responses[i] = post(r, "/payments", "key-1", "alice")
+		}()
+	}
+
+	<-started
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("expected one request to execute, ran %d times", calls.Load())
+	}
+
+	for i, res := range responses {
+		if res.Code != http.StatusOK || res.BodyString() != "charged" {
+			t.Errorf("response %d: expected the original response, got %d %q", i, res.Code, res.BodyString())
+		}
+	}
+}
+
+func TestOversizedResponseNotStored(t *testing.T) {
+	var calls atomic.Int64
+	r := rex.NewRouter()
+	r.POST("/export", func(c *rex.Context) error {
+		calls.Add(1)
+		return c.String(strings.Repeat("x", 64))
+	}, idempotency.New(idempotency.Config{MaxBody: 16}))
+
+	for i := 0; i < 2; i++ {
+		res := post(r, "/export", "key-1", "alice")
+		if res.Body.Len() != 64 {
+			t.Errorf("expected the full body, got %d bytes", res.Body.Len())
+		}
+
+		if res.Header(idempotency.WarningHeader) == "" {
+			t.Errorf("expected the warning header, got %v", res.Result().Header)
+		}
+	}
+
+	if calls.Load() != 2 {
+		t.Errorf("expected the handler to run again, ran %d times", calls.Load())
+	}
+}
+
+func TestFailedRequestCanBeRetried(t *testing.T) {
+	var calls atomic.Int64
+	r := rex.NewRouter()
+	r.POST("/payments", func(c *rex.Context) error {
+		if calls.Add(1) == 1 {
+			return rex.NewError(http.StatusBadGateway, "card processor unavailable")
+		}
+		return c.String("charged")
+	}, idempotency.New(idempotency.Config{}))
+
+	if res := post(r, "/payments", "key-1", "alice"); res.Code != http.StatusBadGateway {
+		t.Fatalf("expected the error, got %d", res.Code)
+	}
+
+	if res := post(r, "/payments", "key-1", "alice"); res.BodyString() != "charged" || calls.Load() != 2 {
+		t.Errorf("expected the retry to run the handler, got %q after %d calls", res.BodyString(), calls.Load())
+	}
+}
+
+func TestMemoryStoreExpiry(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	store := idempotency.NewMemoryStore()
+	store.Now = func() time.Time { return now }
+
+	if ok, _ := store.SetNX("k", idempotency.Entry{InFlight: true}, time.Minute); !ok {
+		t.Fatal("expected the key to be reserved")
+	}
+
+	if ok, _ := store.SetNX("k", idempotency.Entry{InFlight: true}, time.Minute); ok {
+		t.Fatal("expected the key to be taken")
+	}
+
+	now = now.Add(time.Minute)
+	if _, found, _ := store.Get("k"); found {
+		t.Error("expected the entry to expire")
+	}
+
+	if ok, _ := store.SetNX("k", idempotency.Entry{}, time.Minute); !ok {
+		t.Error("expected an expired key to be reserved again")
+	}
+}
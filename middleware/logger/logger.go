@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/abiiranathan/rex"
@@ -24,6 +25,10 @@ const (
 	LOG_IP LogFlags = 1 << iota
 	LOG_LATENCY
 	LOG_USERAGENT
+	LOG_TIMINGS    // Log segments recorded with c.Timing
+	LOG_SIZE       // Log the number of body bytes sent, see c.ResponseSize
+	LOG_ROUTE      // Log the pattern of the matched route
+	LOG_REQUEST_ID // Log the X-Request-ID of the request, see c.RequestID
 )
 
 const StdLogFlags LogFlags = LOG_LATENCY | LOG_IP
@@ -52,6 +57,15 @@ type Config struct {
 	// Callback is a function that can be used to modify the arguments passed to the logger.
 	// Forexample the request_id, user_id etc.
 	Callback func(r *http.Request, args ...any) []any
+
+	// ResponseHeaderAllowlist are the response headers logged in the "response_headers" group,
+	// e.g "Cache-Control". Multiple values are joined with ", " and missing headers are left out.
+	ResponseHeaderAllowlist []string
+
+	// CookieEvents logs a "cookie" event for each cookie set by the response, with its name
+	// and attributes and whether it was set or cleared. Cookie values are never logged.
+	// The events are logged on every request, including the ones left out by LogSampling.
+	CookieEvents bool
 }
 
 // DefaultConfig is the default logger used by the Logger middleware.
@@ -69,7 +83,9 @@ var DefaultConfig = &Config{
 
 // New returns a new Logger middleware with the provided configuration.
 // The logger needs access to status code and thus must apear before middleware wrapping the default
-// response writer (like etags and Brotli)
+// response writer (like etags and Brotli).
+// Fields are named and computed as configured with rex.WithLogFieldNames and rex.WithLogValuer,
+// and the time, level and message keys go through Router.ReplaceLogAttr.
 func New(config *Config) rex.Middleware {
 	if config == nil {
 		config = DefaultConfig
@@ -108,31 +124,51 @@ func (l *Config) Logger(next rex.HandlerFunc) rex.HandlerFunc {
 		err := next(c)
 		latency := time.Since(start).String()
 
-		var logger *slog.Logger
-		switch l.Format {
-		case TextFormat:
-			logger = slog.New(slog.NewTextHandler(l.Output, l.Options))
-		case JSONFormat:
-			logger = slog.New(slog.NewJSONHandler(l.Output, l.Options))
-		default:
-			logger = slog.New(slog.NewTextHandler(l.Output, l.Options))
+		// Successful requests of routes with LogSampling are only logged at the route's rate.
+		if !c.ShouldLog(err) {
+			if l.CookieEvents {
+				l.logCookies(c, l.newLogger(c))
+			}
+			return err
 		}
 
-		args := []any{"status", c.Status()}
+		logger := l.newLogger(c)
+		args := c.AppendLogField(nil, rex.LogStatus, c.Status())
 		if l.Flags&LOG_LATENCY != 0 {
-			args = append(args, "latency", latency)
+			args = c.AppendLogField(args, rex.LogLatency, latency)
+		}
+		args = c.AppendLogField(args, rex.LogMethod, c.Request.Method)
+		args = c.AppendLogField(args, rex.LogPath, c.Request.URL.Path)
+
+		if l.Flags&LOG_ROUTE != 0 {
+			args = c.AppendLogField(args, rex.LogRoute, c.Pattern())
+		}
+
+		if l.Flags&LOG_REQUEST_ID != 0 {
+			args = c.AppendLogField(args, rex.LogRequestID, c.RequestID())
 		}
-		args = append(args, "method", c.Request.Method, "path", c.Request.URL.Path)
 
 		if l.Flags&LOG_IP != 0 {
 			ipAddr, _ := c.IP()
-			args = append(args, "ip", ipAddr)
+			args = c.AppendLogField(args, rex.LogIP, ipAddr)
 		}
 
 		if l.Flags&LOG_USERAGENT != 0 {
 			args = append(args, "user_agent", c.Request.UserAgent())
 		}
 
+		if l.Flags&LOG_SIZE != 0 {
+			args = c.AppendLogField(args, rex.LogBytes, c.ResponseSize())
+		}
+
+		if l.Flags&LOG_TIMINGS != 0 {
+			var timings []any
+			for _, t := range c.Timings() {
+				timings = append(timings, slog.String(t.Name, t.Duration.String()))
+			}
+			args = append(args, slog.Group("timings", timings...))
+		}
+
 		if l.Callback != nil {
 			args = l.Callback(c.Request, args...)
 
@@ -141,7 +177,111 @@ func (l *Config) Logger(next rex.HandlerFunc) rex.HandlerFunc {
 			}
 		}
 
+		if len(l.ResponseHeaderAllowlist) > 0 {
+			args = l.appendResponseHeaders(args, c.Response.Header())
+		}
+
 		logger.Info("", args...)
+
+		if l.CookieEvents {
+			l.logCookies(c, logger)
+		}
 		return err
 	}
 }
+
+// newLogger returns a logger writing to l.Output in l.Format.
+func (l *Config) newLogger(c *rex.Context) *slog.Logger {
+	options := l.handlerOptions(c.Router())
+	switch l.Format {
+	case JSONFormat:
+		return slog.New(slog.NewJSONHandler(l.Output, options))
+	default:
+		return slog.New(slog.NewTextHandler(l.Output, options))
+	}
+}
+
+// appendResponseHeaders appends the values of the allowlisted response headers to args.
+func (l *Config) appendResponseHeaders(args []any, header http.Header) []any {
+	var headers []any
+	for _, name := range l.ResponseHeaderAllowlist {
+		if values := header.Values(name); len(values) > 0 {
+			headers = append(headers, slog.String(strings.ToLower(name), strings.Join(values, ", ")))
+		}
+	}
+
+	if len(headers) == 0 {
+		return args
+	}
+	return append(args, slog.Group("response_headers", headers...))
+}
+
+// logCookies logs an event per Set-Cookie header of the response, without the cookie value.
+// The headers are read once the handler returned, before the router finalizes the response.
+func (l *Config) logCookies(c *rex.Context, logger *slog.Logger) {
+	res := http.Response{Header: http.Header{"Set-Cookie": c.Response.Header().Values("Set-Cookie")}}
+	for _, cookie := range res.Cookies() {
+		action := "set"
+		if cookie.MaxAge < 0 || (!cookie.Expires.IsZero() && cookie.Expires.Before(time.Now())) {
+			action = "cleared"
+		}
+
+		args := []any{
+			"name", cookie.Name,
+			"action", action,
+			"max_age", cookie.MaxAge,
+			"secure", cookie.Secure,
+			"httponly", cookie.HttpOnly,
+			"samesite", sameSiteName(cookie.SameSite),
+		}
+
+		if cookie.Path != "" {
+			args = append(args, "cookie_path", cookie.Path)
+		}
+
+		if cookie.Domain != "" {
+			args = append(args, "domain", cookie.Domain)
+		}
+
+		if !cookie.Expires.IsZero() {
+			args = append(args, "expires", cookie.Expires)
+		}
+
+		args = c.AppendLogField(args, rex.LogMethod, c.Request.Method)
+		args = c.AppendLogField(args, rex.LogPath, c.Request.URL.Path)
+		if l.Flags&LOG_REQUEST_ID != 0 {
+			args = c.AppendLogField(args, rex.LogRequestID, c.RequestID())
+		}
+		logger.Info("cookie", args...)
+	}
+}
+
+func sameSiteName(mode http.SameSite) string {
+	switch mode {
+	case http.SameSiteLaxMode:
+		return "Lax"
+	case http.SameSiteStrictMode:
+		return "Strict"
+	case http.SameSiteNoneMode:
+		return "None"
+	}
+	return ""
+}
+
+// handlerOptions returns the handler options with the ReplaceAttr of router applied
+// after the ReplaceAttr of the options, if any.
+func (l *Config) handlerOptions(router *rex.Router) *slog.HandlerOptions {
+	if router == nil {
+		return l.Options
+	}
+
+	options := *l.Options
+	replace := l.Options.ReplaceAttr
+	options.ReplaceAttr = func(groups []string, a slog.Attr) slog.Attr {
+		if replace != nil {
+			a = replace(groups, a)
+		}
+		return router.ReplaceLogAttr(groups, a)
+	}
+	return &options
+}
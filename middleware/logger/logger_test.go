@@ -0,0 +1,133 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/logger"
+)
+
+func logLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+
+	var lines []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(line), &fields); err != nil {
+			t.Fatalf("invalid JSON log line %q: %v", line, err)
+		}
+		lines = append(lines, fields)
+	}
+	return lines
+}
+
+func TestResponseHeaderAllowlist(t *testing.T) {
+	var logs bytes.Buffer
+	r := rex.NewRouter()
+	r.Use(logger.New(&logger.Config{
+		Output:                  &logs,
+		Format:                  logger.JSONFormat,
+		ResponseHeaderAllowlist: []string{"Cache-Control", "Vary", "X-Missing"},
+	}))
+
+	r.GET("/account", func(c *rex.Context) error {
+		c.SetHeader("Cache-Control", "private, no-store")
+		c.Response.Header().Add("Vary", "Cookie")
+		c.Response.Header().Add("Vary", "Accept-Encoding")
+		c.SetHeader("X-Secret", "s3cr3t")
+		return c.String("ok")
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/account", nil))
+
+	lines := logLines(t, &logs)
+	if len(lines) != 1 {
+		t.Fatalf("expected one log line, got %d", len(lines))
+	}
+
+	headers, _ := lines[0]["response_headers"].(map[string]any)
+	if headers["cache-control"] != "private, no-store" || headers["vary"] != "Cookie, Accept-Encoding" {
+		t.Errorf("expected the allowlisted headers, got %v", lines[0])
+	}
+
+	if _, ok := headers["x-missing"]; ok || strings.Contains(logs.String(), "s3cr3t") {
+		t.Errorf("expected only the allowlisted headers present in the response, got %v", headers)
+	}
+}
+
+func TestCookieEvents(t *testing.T) {
+	var logs bytes.Buffer
+	r := rex.NewRouter()
+	r.Use(logger.New(&logger.Config{Output: &logs, Format: logger.JSONFormat, CookieEvents: true}))
+
+	r.POST("/login", func(c *rex.Context) error {
+		http.SetCookie(c.Response, &http.Cookie{
+			Name: "session", Value: "secret-session-token", Path: "/",
+			MaxAge: 3600, Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode,
+		})
+		http.SetCookie(c.Response, &http.Cookie{Name: "remember", Value: "", MaxAge: -1})
+		return c.String("ok")
+	})
+	r.Test(httptest.NewRequest(http.MethodPost, "/login", nil))
+
+	if strings.Contains(logs.String(), "secret-session-token") {
+		t.Fatalf("expected the cookie value not to be logged, got %q", logs.String())
+	}
+
+	lines := logLines(t, &logs)
+	if len(lines) != 3 {
+		t.Fatalf("expected the request line and two cookie events, got %d", len(lines))
+	}
+
+	session := lines[1]
+	if session["msg"] != "cookie" || session["name"] != "session" || session["action"] != "set" ||
+		session["max_age"] != float64(3600) || session["secure"] != true || session["httponly"] != true ||
+		session["samesite"] != "Lax" || session["cookie_path"] != "/" || session["path"] != "/login" {
+		t.Errorf("unexpected session cookie event %v", session)
+	}
+
+	if cleared := lines[2]; cleared["name"] != "remember" || cleared["action"] != "cleared" {
+		t.Errorf("unexpected cleared cookie event %v", cleared)
+	}
+}
+
+func TestCookieEventsNotSampled(t *testing.T) {
+	var logs bytes.Buffer
+	r := rex.NewRouter()
+	r.Use(logger.New(&logger.Config{Output: &logs, Format: logger.JSONFormat, CookieEvents: true}))
+
+	r.POST("/login", func(c *rex.Context) error {
+		http.SetCookie(c.Response, &http.Cookie{Name: "session", Value: "token", Path: "/"})
+		return c.String("ok")
+	}).LogSampling(0)
+	r.Test(httptest.NewRequest(http.MethodPost, "/login", nil))
+
+	lines := logLines(t, &logs)
+	if len(lines) != 1 {
+		t.Fatalf("expected only the cookie event on a sampled route, got %d lines", len(lines))
+	}
+
+	if lines[0]["msg"] != "cookie" || lines[0]["name"] != "session" {
+		t.Errorf("unexpected cookie event %v", lines[0])
+	}
+}
+
+func TestCookieEventsDisabled(t *testing.T) {
+	var logs bytes.Buffer
+	r := rex.NewRouter()
+	r.Use(logger.New(&logger.Config{Output: &logs, Format: logger.JSONFormat}))
+
+	r.GET("/", func(c *rex.Context) error {
+		http.SetCookie(c.Response, &http.Cookie{Name: "session", Value: "token"})
+		return c.String("ok")
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if lines := logLines(t, &logs); len(lines) != 1 {
+		t.Errorf("expected no cookie events by default, got %d lines", len(lines))
+	}
+}
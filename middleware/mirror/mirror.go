@@ -0,0 +1,333 @@
+// Package mirror copies a sample of requests to a shadow backend and discards its
+// responses, to try a new service with production traffic before cutting over.
+//
+// Example:
+//
+//	shadow, _ := url.Parse("http://orders-v2.internal:8080")
+//	m := mirror.NewMirror(mirror.Config{Target: shadow, Percent: 10})
+//	r.Use(m.Middleware())
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+const (
+	// DefaultTimeout is the default timeout of a mirrored request.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultWorkers is the default number of mirrored requests in flight.
+	DefaultWorkers = 8
+
+	// DefaultMaxBody is the default maximum size of a request body copied to the shadow.
+	DefaultMaxBody = 1 << 20
+)
+
+// ShadowHeader is set to "true" on mirrored requests.
+const ShadowHeader = "X-Shadow"
+
+// hopHeaders are the hop-by-hop headers that are not forwarded to the shadow.
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// Config configures a Mirror.
+type Config struct {
+	// Target is the base URL of the shadow backend. The request path and query are appended to it.
+	Target *url.URL
+
+	// Percent is the percentage of matching requests to mirror, from 0 to 100.
+	Percent float64
+
+	// Methods are the request methods to mirror. The default is GET only.
+	Methods []string
+
+	// Timeout bounds each mirrored request. The default is DefaultTimeout.
+	Timeout time.Duration
+
+	// CopyBody buffers request bodies of up to MaxBody bytes so they can be sent to the shadow too.
+	// The handler still reads the whole body. Requests with larger bodies are not mirrored.
+	// Without CopyBody, requests with a body are not mirrored.
+	CopyBody bool
+
+	// MaxBody is the maximum size of a copied body. The default is DefaultMaxBody.
+	MaxBody int64
+
+	// HeaderAllowlist lists the request headers sent to the shadow.
+	// If empty, all headers except hop-by-hop headers are sent.
+	HeaderAllowlist []string
+
+	// Workers is the number of mirrored requests that can be in flight.
+	// Requests sampled while all workers are busy are dropped. The default is DefaultWorkers.
+	Workers int
+
+	// Client sends the mirrored requests. The default is a client that does not follow redirects.
+	Client *http.Client
+
+	// Rand is the source used to sample requests, e.g rand.New(rand.NewSource(1)) in tests.
+	// The default is the math/rand global source.
+	Rand *rand.Rand
+}
+
+// Stats are the counters of a Mirror.
+type Stats struct {
+	Mirrored  int64 // Requests sent to the shadow.
+	Succeeded int64 // Mirrored requests that got a response below 500.
+	Failed    int64 // Mirrored requests that failed or got a 5xx response.
+	Dropped   int64 // Sampled requests not sent because all workers were busy or the body could not be copied.
+}
+
+// Mirror sends a copy of sampled requests to a shadow backend.
+// Mirroring runs asynchronously and never fails or delays the primary request.
+type Mirror struct {
+	config  Config
+	methods map[string]bool
+	allow   []string
+	sem     chan struct{}
+	wg      sync.WaitGroup
+
+	randMu sync.Mutex
+
+	mirrored, succeeded, failed, dropped atomic.Int64
+}
+
+// NewMirror creates a Mirror. It panics if config.Target is nil.
+func NewMirror(config Config) *Mirror {
+	if config.Target == nil {
+		panic("mirror: Target is required")
+	}
+
+	if len(config.Methods) == 0 {
+		config.Methods = []string{http.MethodGet}
+	}
+
+	if config.Timeout <= 0 {
+		config.Timeout = DefaultTimeout
+	}
+
+	if config.MaxBody <= 0 {
+		config.MaxBody = DefaultMaxBody
+	}
+
+	if config.Workers <= 0 {
+		config.Workers = DefaultWorkers
+	}
+
+	if config.Client == nil {
+		config.Client = &http.Client{
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		}
+	}
+
+	m := &Mirror{
+		config:  config,
+		methods: make(map[string]bool, len(config.Methods)),
+		sem:     make(chan struct{}, config.Workers),
+	}
+
+	for _, method := range config.Methods {
+		m.methods[strings.ToUpper(method)] = true
+	}
+
+	for _, key := range config.HeaderAllowlist {
+		m.allow = append(m.allow, textproto.CanonicalMIMEHeaderKey(key))
+	}
+	return m
+}
+
+// New returns a middleware that mirrors requests as configured.
+// Use NewMirror to read the Stats.
+func New(config Config) rex.Middleware {
+	return NewMirror(config).Middleware()
+}
+
+// Stats returns the current counters.
+func (m *Mirror) Stats() Stats {
+	return Stats{
+		Mirrored:  m.mirrored.Load(),
+		Succeeded: m.succeeded.Load(),
+		Failed:    m.failed.Load(),
+		Dropped:   m.dropped.Load(),
+	}
+}
+
+// Wait blocks until all mirrored requests in flight are done.
+func (m *Mirror) Wait() {
+	m.wg.Wait()
+}
+
+// Middleware returns the mirror middleware.
+func (m *Mirror) Middleware() rex.Middleware {
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if m.methods[c.Request.Method] && m.sample() {
+				m.mirror(c.Request)
+			}
+			return next(c)
+		}
+	}
+}
+
+// sample reports whether the request is part of the mirrored percentage.
+func (m *Mirror) sample() bool {
+	switch {
+	case m.config.Percent <= 0:
+		return false
+	case m.config.Percent >= 100:
+		return true
+	}
+
+	var f float64
+	if m.config.Rand != nil {
+		m.randMu.Lock()
+		f = m.config.Rand.Float64()
+		m.randMu.Unlock()
+	} else {
+		f = rand.Float64()
+	}
+	return f*100 < m.config.Percent
+}
+
+// mirror copies req and sends it to the shadow if a worker is free.
+func (m *Mirror) mirror(req *http.Request) {
+	body, ok := m.copyBody(req)
+	if !ok {
+		m.dropped.Add(1)
+		return
+	}
+
+	select {
+	case m.sem <- struct{}{}:
+	default:
+		m.dropped.Add(1)
+		return
+	}
+
+	// Build the request before the handler runs and may change the original.
+	shadow := m.newRequest(req, body)
+
+	m.mirrored.Add(1)
+	m.wg.Add(1)
+	go func() {
+		defer func() {
+			<-m.sem
+			m.wg.Done()
+		}()
+		m.send(shadow)
+	}()
+}
+
+// copyBody reads the body of req and restores it for the handler.
+// ok is false if the body can not be mirrored.
+func (m *Mirror) copyBody(req *http.Request) (body []byte, ok bool) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, true
+	}
+
+	if !m.config.CopyBody {
+		// A GET with an unknown length may still have an empty body.
+		return nil, req.ContentLength == 0
+	}
+
+	data, err := io.ReadAll(io.LimitReader(req.Body, m.config.MaxBody+1))
+	rest := req.Body
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), rest), rest}
+
+	if err != nil || int64(len(data)) > m.config.MaxBody {
+		return nil, false
+	}
+	return data, true
+}
+
+// newRequest returns the shadow copy of req.
+func (m *Mirror) newRequest(req *http.Request, body []byte) *http.Request {
+	target := *m.config.Target
+	base := strings.TrimSuffix(target.EscapedPath(), "/")
+	target.Path = strings.TrimSuffix(target.Path, "/") + req.URL.Path
+	target.RawPath = base + req.URL.EscapedPath() // Keeps encoded slashes.
+	target.RawQuery = req.URL.RawQuery
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	// The context is set when the request is sent.
+	shadow, _ := http.NewRequest(req.Method, target.String(), reader)
+	shadow.Header = m.copyHeader(req.Header)
+	shadow.Header.Set(ShadowHeader, "true")
+	return shadow
+}
+
+// copyHeader returns the headers of h that are sent to the shadow.
+func (m *Mirror) copyHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	if len(m.allow) > 0 {
+		for _, key := range m.allow {
+			if values, ok := h[key]; ok {
+				out[key] = append([]string(nil), values...)
+			}
+		}
+	} else {
+		for key, values := range h {
+			out[key] = append([]string(nil), values...)
+		}
+	}
+
+	// Headers named in Connection are hop-by-hop as well.
+	for _, value := range h.Values("Connection") {
+		for _, key := range strings.Split(value, ",") {
+			out.Del(strings.TrimSpace(key))
+		}
+	}
+
+	for _, key := range hopHeaders {
+		out.Del(key)
+	}
+	return out
+}
+
+// send performs the shadow request and discards the response.
+func (m *Mirror) send(shadow *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.config.Timeout)
+	defer cancel()
+
+	res, err := m.config.Client.Do(shadow.WithContext(ctx))
+	if err != nil {
+		m.failed.Add(1)
+		return
+	}
+	io.Copy(io.Discard, res.Body)
+	res.Body.Close()
+
+	if res.StatusCode >= http.StatusInternalServerError {
+		m.failed.Add(1)
+		return
+	}
+	m.succeeded.Add(1)
+}
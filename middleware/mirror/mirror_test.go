@@ -0,0 +1,302 @@
+package mirror_test
+
+import (
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/mirror"
+)
+
+// shadowRecord is a request received by the shadow backend.
+type shadowRecord struct {
+	method string
+	path   string
+	body   string
+	header http.Header
+}
+
+// newShadow starts a shadow backend that records the requests it receives.
+func newShadow(t *testing.T) (*url.URL, func() []shadowRecord) {
+	t.Helper()
+
+	var (
+		mu      sync.Mutex
+		records []shadowRecord
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		records = append(records, shadowRecord{r.Method, r.URL.RequestURI(), string(body), r.Header.Clone()})
+		mu.Unlock()
+		w.Write([]byte("shadow"))
+	}))
+	t.Cleanup(srv.Close)
+
+	target, _ := url.Parse(srv.URL)
+	return target, func() []shadowRecord {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]shadowRecord(nil), records...)
+	}
+}
+
+func newRouter(m *mirror.Mirror) *rex.Router {
+	r := rex.NewRouter()
+	r.Use(m.Middleware())
+
+	r.GET("/items", func(c *rex.Context) error {
+		return c.String("primary")
+	})
+
+	r.POST("/items", func(c *rex.Context) error {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			return err
+		}
+		return c.String(string(body))
+	})
+	return r
+}
+
+func TestMirrorSampling(t *testing.T) {
+	target, records := newShadow(t)
+	m := mirror.NewMirror(mirror.Config{
+		Target:  target,
+		Percent: 30,
+		Workers: 1000,
+		Rand:    rand.New(rand.NewSource(1)),
+	})
+	r := newRouter(m)
+
+	const total = 1000
+	for i := 0; i < total; i++ {
+		res := r.Test(httptest.NewRequest(http.MethodGet, "/items", nil))
+		if res.BodyString() != "primary" {
+			t.Fatalf("expected the primary response, got %q", res.BodyString())
+		}
+	}
+	m.Wait()
+
+	got := len(records())
+	if got < 250 || got > 350 {
+		t.Errorf("expected about 30%% of %d requests to be mirrored, got %d", total, got)
+	}
+
+	stats := m.Stats()
+	if stats.Mirrored != int64(got) || stats.Succeeded != int64(got) || stats.Failed != 0 {
+		t.Errorf("unexpected stats %+v for %d mirrored requests", stats, got)
+	}
+}
+
+func TestMirrorHeaders(t *testing.T) {
+	target, records := newShadow(t)
+	target.Path = "/v2"
+	m := mirror.NewMirror(mirror.Config{Target: target, Percent: 100})
+	r := newRouter(m)
+
+	req := httptest.NewRequest(http.MethodGet, "/items?page=2", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Connection", "X-Hop")
+	req.Header.Set("X-Hop", "1")
+	req.Header.Set("Upgrade", "websocket")
+	r.Test(req)
+	m.Wait()
+
+	got := records()
+	if len(got) != 1 {
+		t.Fatalf("expected 1 mirrored request, got %d", len(got))
+	}
+
+	rec := got[0]
+	if rec.path != "/v2/items?page=2" {
+		t.Errorf("expected /v2/items?page=2, got %s", rec.path)
+	}
+
+	if rec.header.Get(mirror.ShadowHeader) != "true" {
+		t.Errorf("expected %s: true, got %q", mirror.ShadowHeader, rec.header.Get(mirror.ShadowHeader))
+	}
+
+	if rec.header.Get("Authorization") != "Bearer token" {
+		t.Errorf("expected the Authorization header to be copied, got %v", rec.header)
+	}
+
+	for _, key := range []string{"X-Hop", "Upgrade"} {
+		if rec.header.Get(key) != "" {
+			t.Errorf("expected hop-by-hop header %s to be stripped", key)
+		}
+	}
+}
+
+func TestMirrorKeepsEncodedSlashes(t *testing.T) {
+	target, records := newShadow(t)
+	target.Path = "/v2"
+	m := mirror.NewMirror(mirror.Config{Target: target, Percent: 100})
+	r := newRouter(m)
+	r.GET("/files/{name}", func(c *rex.Context) error {
+		return c.String(c.Param("name"))
+	})
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/files/reports%2F2024%20q1.pdf", nil))
+	m.Wait()
+
+	got := records()
+	if len(got) != 1 || got[0].path != "/v2/files/reports%2F2024%20q1.pdf" {
+		t.Errorf("expected the escaped path to be mirrored, got %+v", got)
+	}
+}
+
+func TestMirrorHeaderAllowlist(t *testing.T) {
+	target, records := newShadow(t)
+	m := mirror.NewMirror(mirror.Config{Target: target, Percent: 100, HeaderAllowlist: []string{"x-tenant"}})
+	r := newRouter(m)
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("X-Tenant", "acme")
+	r.Test(req)
+	m.Wait()
+
+	rec := records()[0]
+	if rec.header.Get("Authorization") != "" {
+		t.Error("expected headers outside the allowlist to be dropped")
+	}
+
+	if rec.header.Get("X-Tenant") != "acme" {
+		t.Errorf("expected X-Tenant to be copied, got %v", rec.header)
+	}
+}
+
+func TestMirrorPostBody(t *testing.T) {
+	const payload = `{"name":"widget","qty":3}`
+
+	t.Run("GET only by default", func(t *testing.T) {
+		target, records := newShadow(t)
+		m := mirror.NewMirror(mirror.Config{Target: target, Percent: 100})
+		r := newRouter(m)
+
+		res := r.Test(httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(payload)))
+		m.Wait()
+
+		if res.BodyString() != payload {
+			t.Errorf("expected the primary to read the body, got %q", res.BodyString())
+		}
+
+		if n := len(records()); n != 0 {
+			t.Errorf("expected POST not to be mirrored, got %d requests", n)
+		}
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		target, records := newShadow(t)
+		m := mirror.NewMirror(mirror.Config{
+			Target:   target,
+			Percent:  100,
+			Methods:  []string{http.MethodPost},
+			CopyBody: true,
+		})
+		r := newRouter(m)
+
+		res := r.Test(httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(payload)))
+		m.Wait()
+
+		if res.BodyString() != payload {
+			t.Errorf("expected the primary to read the whole body, got %q", res.BodyString())
+		}
+
+		got := records()
+		if len(got) != 1 || got[0].method != http.MethodPost || got[0].body != payload {
+			t.Fatalf("expected the body to be mirrored intact, got %+v", got)
+		}
+	})
+
+	t.Run("body too large", func(t *testing.T) {
+		target, records := newShadow(t)
+		m := mirror.NewMirror(mirror.Config{
+			Target:   target,
+			Percent:  100,
+			Methods:  []string{http.MethodPost},
+			CopyBody: true,
+			MaxBody:  4,
+		})
+		r := newRouter(m)
+
+		res := r.Test(httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(payload)))
+		m.Wait()
+
+		if res.BodyString() != payload {
+			t.Errorf("expected the primary to read the whole body, got %q", res.BodyString())
+		}
+
+		if n := len(records()); n != 0 || m.Stats().Dropped != 1 {
+			t.Errorf("expected the request to be dropped, got %d requests and %+v", n, m.Stats())
+		}
+	})
+}
+
+func TestMirrorUnreachableTarget(t *testing.T) {
+	// A listener that never accepts leaves mirrored requests hanging.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	target, _ := url.Parse("http://" + ln.Addr().String())
+	m := mirror.NewMirror(mirror.Config{Target: target, Percent: 100, Timeout: 300 * time.Millisecond})
+
+	var handled atomic.Int64
+	r := rex.NewRouter()
+	r.Use(m.Middleware())
+	r.GET("/items", func(c *rex.Context) error {
+		handled.Add(1)
+		return c.String("primary")
+	})
+
+	start := time.Now()
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/items", nil))
+	elapsed := time.Since(start)
+
+	if res.Status() != http.StatusOK || res.BodyString() != "primary" {
+		t.Fatalf("expected the primary response, got %d %q", res.Status(), res.BodyString())
+	}
+
+	if elapsed > 100*time.Millisecond {
+		t.Errorf("expected the primary response not to wait for the shadow, took %v", elapsed)
+	}
+
+	m.Wait()
+	if stats := m.Stats(); stats.Failed != 1 || stats.Succeeded != 0 {
+		t.Errorf("expected 1 failed mirror, got %+v", stats)
+	}
+}
+
+func TestMirrorDropsWhenWorkersBusy(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	target, _ := url.Parse(srv.URL)
+	m := mirror.NewMirror(mirror.Config{Target: target, Percent: 100, Workers: 1})
+	r := newRouter(m)
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/items", nil))
+	r.Test(httptest.NewRequest(http.MethodGet, "/items", nil))
+
+	if stats := m.Stats(); stats.Mirrored != 1 || stats.Dropped != 1 {
+		t.Errorf("expected 1 mirrored and 1 dropped request, got %+v", stats)
+	}
+}
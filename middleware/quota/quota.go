@@ -0,0 +1,206 @@
+// Package quota limits the number of requests per API key over one or more windows,
+// e.g a per-minute burst limit together with a daily quota.
+//
+// Example:
+//
+//	r.Use(quota.New(quota.Config{
+//		Limits: []quota.Limit{
+//			{Window: time.Minute, Max: 100},
+//			{Window: 24 * time.Hour, Max: 10000},
+//		},
+//	}))
+package quota
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// Store counts requests in fixed windows.
+type Store interface {
+	// Incr increments the counter of key in the current window and returns the new count
+	// and the time left until the window resets. A new window starts when it expires.
+	Incr(key string, window time.Duration) (count int64, ttl time.Duration, err error)
+}
+
+// Limit allows at most Max requests per Window.
+type Limit struct {
+	Window time.Duration
+	Max    int64
+}
+
+// Config configures the quota middleware.
+type Config struct {
+	// KeyFunc returns the key the quota is counted for.
+	// The default is the X-Api-Key header. Requests with an empty key are not limited.
+	KeyFunc func(c *rex.Context) string
+
+	// Limits are evaluated together, a request is rejected if any of them is exceeded.
+	Limits []Limit
+
+	// Store holds the counters. The default is a MemoryStore, which is not shared between instances.
+	Store Store
+
+	// OnExceeded responds to rejected requests. The default responds with
+	// 429 Too Many Requests and a JSON error. Retry-After is set before it is called.
+	OnExceeded rex.HandlerFunc
+
+	// FailClosed rejects requests with 503 Service Unavailable when the store fails.
+	// By default requests are allowed (fail-open) and the error is logged.
+	FailClosed bool
+}
+
+// usage is the state of a limit after counting the request.
+type usage struct {
+	limit Limit
+	count int64
+	ttl   time.Duration
+}
+
+func (u usage) remaining() int64 {
+	return max(u.limit.Max-u.count, 0)
+}
+
+func defaultKey(c *rex.Context) string {
+	return c.Request.Header.Get("X-Api-Key")
+}
+
+func defaultOnExceeded(c *rex.Context) error {
+	c.SetHeader("Content-Type", "application/json")
+	c.WriteHeader(http.StatusTooManyRequests)
+	return c.JSON(rex.Map{"error": "quota exceeded"})
+}
+
+// New creates the quota middleware. Responses carry X-RateLimit-Limit, X-RateLimit-Remaining
+// and X-RateLimit-Reset (seconds until the window resets) for the strictest limit,
+// the one with the fewest requests remaining. It panics if no limit is configured.
+func New(config Config) rex.Middleware {
+	if len(config.Limits) == 0 {
+		panic("quota: at least one limit is required")
+	}
+
+	for _, limit := range config.Limits {
+		if limit.Window <= 0 || limit.Max < 1 {
+			panic(fmt.Sprintf("quota: invalid limit %+v", limit))
+		}
+	}
+
+	if config.KeyFunc == nil {
+		config.KeyFunc = defaultKey
+	}
+
+	if config.Store == nil {
+		config.Store = NewMemoryStore()
+	}
+
+	if config.OnExceeded == nil {
+		config.OnExceeded = defaultOnExceeded
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			key := config.KeyFunc(c)
+			if key == "" {
+				return next(c)
+			}
+
+			usages := make([]usage, 0, len(config.Limits))
+			for _, limit := range config.Limits {
+				count, ttl, err := config.Store.Incr(key+":"+limit.Window.String(), limit.Window)
+				if err != nil {
+					if config.FailClosed {
+						return rex.NewError(http.StatusServiceUnavailable, "quota: store unavailable")
+					}
+					c.GetLogger().Error("quota: store error, allowing request", "error", err)
+					return next(c)
+				}
+				usages = append(usages, usage{limit: limit, count: count, ttl: ttl})
+			}
+
+			strictest := usages[0]
+			var retryAfter time.Duration
+			for _, u := range usages {
+				if u.remaining() < strictest.remaining() ||
+					(u.remaining() == strictest.remaining() && u.ttl > strictest.ttl) {
+					strictest = u
+				}
+
+				if u.count > u.limit.Max {
+					retryAfter = max(retryAfter, u.ttl)
+				}
+			}
+
+			header := c.Response.Header()
+			header.Set("X-RateLimit-Limit", strconv.FormatInt(strictest.limit.Max, 10))
+			header.Set("X-RateLimit-Remaining", strconv.FormatInt(strictest.remaining(), 10))
+			header.Set("X-RateLimit-Reset", seconds(strictest.ttl))
+
+			if retryAfter > 0 {
+				header.Set("Retry-After", seconds(retryAfter))
+				return config.OnExceeded(c)
+			}
+			return next(c)
+		}
+	}
+}
+
+// seconds formats d as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// MemoryStore is an in-memory Store with fixed windows.
+// Expired windows are removed as new requests are counted.
+type MemoryStore struct {
+	// Now returns the current time. The default is time.Now.
+	Now func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+}
+
+type window struct {
+	count int64
+	reset time.Time
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{windows: make(map[string]*window)}
+}
+
+// Incr implements Store.
+func (s *MemoryStore) Incr(key string, d time.Duration) (int64, time.Duration, error) {
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, w := range s.windows {
+			if !now.Before(w.reset) {
+				delete(s.windows, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	w, ok := s.windows[key]
+	if !ok || !now.Before(w.reset) {
+		w = &window{reset: now.Add(d)}
+		s.windows[key] = w
+	}
+
+	w.count++
+	return w.count, w.reset.Sub(now), nil
+}
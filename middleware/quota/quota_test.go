@@ -0,0 +1,200 @@
+package quota_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/quota"
+)
+
+// clock is a manually advanced time source.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newRouter(config quota.Config) *rex.Router {
+	r := rex.NewRouter()
+	r.Use(quota.New(config))
+	r.GET("/", func(c *rex.Context) error {
+		return c.String("ok")
+	})
+	return r
+}
+
+func request(r *rex.Router, key string) *rex.TestResponse {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if key != "" {
+		req.Header.Set("X-Api-Key", key)
+	}
+	return r.Test(req)
+}
+
+func newClockStore() (*quota.MemoryStore, *clock) {
+	clk := &clock{now: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)}
+	store := quota.NewMemoryStore()
+	store.Now = clk.Now
+	return store, clk
+}
+
+func TestQuotaHeadersAtBoundary(t *testing.T) {
+	store, _ := newClockStore()
+	r := newRouter(quota.Config{Store: store, Limits: []quota.Limit{{Window: time.Minute, Max: 2}}})
+
+	tests := []struct {
+		status    int
+		remaining string
+	}{
+		{http.StatusOK, "1"},
+		{http.StatusOK, "0"},
+		{http.StatusTooManyRequests, "0"},
+	}
+
+	for i, tt := range tests {
+		res := request(r, "key-1")
+		if res.Code != tt.status {
+			t.Fatalf("request %d: expected status %d, got %d", i+1, tt.status, res.Code)
+		}
+
+		if got := res.Header("X-RateLimit-Limit"); got != "2" {
+			t.Errorf("request %d: expected X-RateLimit-Limit 2, got %q", i+1, got)
+		}
+
+		if got := res.Header("X-RateLimit-Remaining"); got != tt.remaining {
+			t.Errorf("request %d: expected X-RateLimit-Remaining %s, got %q", i+1, tt.remaining, got)
+		}
+
+		if got := res.Header("X-RateLimit-Reset"); got != "60" {
+			t.Errorf("request %d: expected X-RateLimit-Reset 60, got %q", i+1, got)
+		}
+	}
+
+	res := request(r, "key-1")
+	if res.Header("Retry-After") != "60" || res.Header("Content-Type") != "application/json" {
+		t.Errorf("expected a JSON 429 with Retry-After, got %v", res.Result().Header)
+	}
+
+	// Keys are counted separately.
+	if res := request(r, "key-2"); res.Code != http.StatusOK {
+		t.Errorf("expected another key to be allowed, got %d", res.Code)
+	}
+
+	// Requests without a key are not limited.
+	if res := request(r, ""); res.Code != http.StatusOK || res.Header("X-RateLimit-Limit") != "" {
+		t.Errorf("expected requests without a key to pass untouched, got %d", res.Code)
+	}
+}
+
+func TestQuotaMultipleWindows(t *testing.T) {
+	store, clk := newClockStore()
+	r := newRouter(quota.Config{
+		Store: store,
+		Limits: []quota.Limit{
+			{Window: time.Minute, Max: 3},
+			{Window: 24 * time.Hour, Max: 5},
+		},
+	})
+
+	// The burst limit is the strictest at first.
+	for i := 0; i < 3; i++ {
+		if res := request(r, "key"); res.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, res.Code)
+		}
+	}
+
+	res := request(r, "key")
+	if res.Code != http.StatusTooManyRequests || res.Header("X-RateLimit-Limit") != "3" {
+		t.Fatalf("expected the minute limit to reject, got %d limit %q", res.Code, res.Header("X-RateLimit-Limit"))
+	}
+
+	if res.Header("Retry-After") != "60" {
+		t.Errorf("expected Retry-After 60, got %q", res.Header("Retry-After"))
+	}
+
+	// A new minute: the daily quota has 1 request left (4 counted so far).
+	clk.Advance(time.Minute)
+	res = request(r, "key")
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status 200 in the next minute, got %d", res.Code)
+	}
+
+	if res.Header("X-RateLimit-Limit") != "5" || res.Header("X-RateLimit-Remaining") != "0" {
+		t.Errorf("expected the daily quota to be the strictest, got limit %q remaining %q",
+			res.Header("X-RateLimit-Limit"), res.Header("X-RateLimit-Remaining"))
+	}
+
+	// The minute limit has room but the daily quota is used up.
+	res = request(r, "key")
+	if res.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the daily quota to reject, got %d", res.Code)
+	}
+
+	wantReset := strconv.Itoa(int((24*time.Hour - time.Minute).Seconds()))
+	if got := res.Header("Retry-After"); got != wantReset {
+		t.Errorf("expected Retry-After %s, got %q", wantReset, got)
+	}
+
+	clk.Advance(24 * time.Hour)
+	if res := request(r, "key"); res.Code != http.StatusOK {
+		t.Errorf("expected the quota to reset after a day, got %d", res.Code)
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Incr(string, time.Duration) (int64, time.Duration, error) {
+	return 0, 0, errors.New("connection refused")
+}
+
+func TestQuotaStoreErrors(t *testing.T) {
+	limits := []quota.Limit{{Window: time.Minute, Max: 1}}
+
+	r := newRouter(quota.Config{Store: failingStore{}, Limits: limits})
+	for i := 0; i < 3; i++ {
+		if res := request(r, "key"); res.Code != http.StatusOK {
+			t.Fatalf("expected fail-open to allow requests, got %d", res.Code)
+		}
+	}
+
+	r = newRouter(quota.Config{Store: failingStore{}, Limits: limits, FailClosed: true})
+	if res := request(r, "key"); res.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected fail-closed to reject with 503, got %d", res.Code)
+	}
+}
+
+func TestQuotaCustomKeyAndHandler(t *testing.T) {
+	store, _ := newClockStore()
+	r := newRouter(quota.Config{
+		Store:   store,
+		Limits:  []quota.Limit{{Window: time.Hour, Max: 1}},
+		KeyFunc: func(c *rex.Context) string { return c.Query("token") },
+		OnExceeded: func(c *rex.Context) error {
+			c.WriteHeader(http.StatusPaymentRequired)
+			return c.String("upgrade your plan")
+		},
+	})
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/?token=abc", nil))
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/?token=abc", nil))
+	if res.Code != http.StatusPaymentRequired || res.Body.String() != "upgrade your plan" {
+		t.Errorf("expected the custom handler, got %d %q", res.Code, res.Body.String())
+	}
+}
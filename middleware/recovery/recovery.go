@@ -2,6 +2,7 @@ package recovery
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"runtime/debug"
@@ -13,29 +14,40 @@ import (
 // If stack trace is true, a stack trace will be logged.
 // If errorHandler is passed, it will be called with the error. No response will be sent to the client.
 // Otherwise the error will be logged and sent with a 500 status code.
+// In rex.Debug mode, the panic is returned to the router's error handler as a *rex.PanicError
+// so that the default error handler renders the debug error page with the stack trace.
 func New(stackTrace bool, errorHandler ...func(err error)) rex.Middleware {
 	return func(next rex.HandlerFunc) rex.HandlerFunc {
-		return func(c *rex.Context) error {
+		return func(c *rex.Context) (err error) {
 			defer func() {
 				if r := recover(); r != nil {
-					err, ok := r.(error)
+					panicErr, ok := r.(error)
 					if !ok {
-						// must be a string
-						err = errors.New(r.(string))
+						if s, isString := r.(string); isString {
+							panicErr = errors.New(s)
+						} else {
+							panicErr = fmt.Errorf("%v", r)
+						}
 					}
 
 					if len(errorHandler) > 0 {
-						errorHandler[0](err)
+						errorHandler[0](panicErr)
+						return
+					}
+
+					if stackTrace {
+						log.Println(string(debug.Stack()))
 					} else {
-						if stackTrace {
-							log.Println(string(debug.Stack()))
-						} else {
-							log.Println(err)
-						}
+						log.Println(panicErr)
+					}
 
-						c.WriteHeader(http.StatusInternalServerError)
-						c.Write([]byte(err.Error()))
+					if rex.Debug {
+						err = rex.NewPanicError(r)
+						return
 					}
+
+					c.WriteHeader(http.StatusInternalServerError)
+					c.Write([]byte(panicErr.Error()))
 				}
 			}()
 
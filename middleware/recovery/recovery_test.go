@@ -0,0 +1,84 @@
+package recovery_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/recovery"
+)
+
+func panickingHandler(c *rex.Context) error {
+	panic("something went wrong")
+}
+
+func TestRecoveryDebugPage(t *testing.T) {
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	r := rex.NewRouter()
+	r.Use(recovery.New(false))
+	r.GET("/boom", panickingHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	req.Header.Set("Authorization", "Bearer secret-token")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "recovery_test.panickingHandler") {
+		t.Errorf("expected the handler stack frame in the debug page, got:\n%s", body)
+	}
+
+	if !strings.Contains(body, `class="frame user"`) {
+		t.Error("expected user frames to be highlighted")
+	}
+
+	if !strings.Contains(body, "something went wrong") {
+		t.Error("expected the panic message in the debug page")
+	}
+
+	if strings.Contains(body, "secret-token") {
+		t.Error("expected the Authorization header to be masked")
+	}
+}
+
+func TestRecoveryProduction(t *testing.T) {
+	r := rex.NewRouter()
+	r.Use(recovery.New(false))
+	r.GET("/boom", panickingHandler)
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", w.Code)
+	}
+
+	if body := w.Body.String(); body != "something went wrong" {
+		t.Errorf("expected terse error body, got %q", body)
+	}
+}
+
+func TestRecoveryErrorHandler(t *testing.T) {
+	var got error
+	r := rex.NewRouter()
+	r.Use(recovery.New(false, func(err error) { got = err }))
+	r.GET("/boom", func(c *rex.Context) error {
+		panic(42)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	if got == nil || got.Error() != "42" {
+		t.Errorf("expected error handler to receive 42, got %v", got)
+	}
+}
@@ -0,0 +1,285 @@
+// Package security grades response security headers against a best-practice baseline.
+//
+// Grade checks a set of headers and ReportHandler replays requests through the
+// router to report the headers each path returns, for verifying a staging setup.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.WithResponseHeaderPolicy(security.Recommended(), []string{"Server", "X-Powered-By"}))
+//	admin := r.Group("/admin", auth.BasicAuth("admin", secret))
+//	admin.GET("/security", security.ReportHandler("/", "/login", "/api/users"))
+package security
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/abiiranathan/rex"
+)
+
+// Severity is the severity of a Finding.
+type Severity string
+
+const (
+	SeverityHigh   Severity = "high"
+	SeverityMedium Severity = "medium"
+	SeverityLow    Severity = "low"
+)
+
+// penalties is the score deducted for a finding of each severity.
+var penalties = map[Severity]int{
+	SeverityHigh:   25,
+	SeverityMedium: 10,
+	SeverityLow:    5,
+}
+
+// MinHSTSMaxAge is the minimum Strict-Transport-Security max-age in seconds (180 days).
+const MinHSTSMaxAge = 180 * 24 * 60 * 60
+
+// graded are the headers reported in Report.Present.
+var graded = []string{
+	"Strict-Transport-Security",
+	"Content-Security-Policy",
+	"X-Frame-Options",
+	"X-Content-Type-Options",
+	"Referrer-Policy",
+	"Permissions-Policy",
+	"Server",
+	"X-Powered-By",
+}
+
+// Finding is a missing or weak security header.
+type Finding struct {
+	Header   string   `json:"header"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+}
+
+// Report is the result of grading response headers.
+type Report struct {
+	Grade    string            `json:"grade"`    // "A" to "F".
+	Score    int               `json:"score"`    // 0 to 100.
+	Present  map[string]string `json:"present"`  // The graded headers that were set.
+	Findings []Finding         `json:"findings"` // Missing or weak headers, most severe first.
+}
+
+// Recommended returns baseline values of the security headers checked by Grade,
+// for use with rex.WithResponseHeaderPolicy. Relax the Content-Security-Policy
+// if the application loads scripts or styles from other origins.
+func Recommended() map[string]string {
+	return map[string]string{
+		"Strict-Transport-Security": "max-age=31536000; includeSubDomains",
+		"Content-Security-Policy":   "default-src 'self'; frame-ancestors 'none'; object-src 'none'",
+		"X-Frame-Options":           "DENY",
+		"X-Content-Type-Options":    "nosniff",
+		"Referrer-Policy":           "strict-origin-when-cross-origin",
+		"Permissions-Policy":        "camera=(), microphone=(), geolocation=()",
+	}
+}
+
+// Grade checks headers against the baseline and returns the findings with a score.
+// Each finding deducts 25 (high), 10 (medium) or 5 (low) points from 100.
+// A score of 90 or more grades "A", 80 "B", 70 "C", 60 "D" and less "F".
+func Grade(headers http.Header) Report {
+	report := Report{Present: make(map[string]string), Findings: []Finding{}}
+	for _, key := range graded {
+		if v := headers.Get(key); v != "" {
+			report.Present[key] = v
+		}
+	}
+
+	add := func(header string, severity Severity, format string, args ...any) {
+		report.Findings = append(report.Findings, Finding{header, severity, fmt.Sprintf(format, args...)})
+	}
+
+	// Strict-Transport-Security
+	if hsts := headers.Get("Strict-Transport-Security"); hsts == "" {
+		add("Strict-Transport-Security", SeverityHigh, "missing, browsers may connect over plain HTTP")
+	} else if maxAge, ok := directiveInt(hsts, "max-age"); !ok || maxAge < MinHSTSMaxAge {
+		add("Strict-Transport-Security", SeverityMedium, "max-age should be at least %d seconds", MinHSTSMaxAge)
+	}
+
+	// Content-Security-Policy
+	csp := headers.Get("Content-Security-Policy")
+	if csp == "" {
+		add("Content-Security-Policy", SeverityHigh, "missing, there is no protection against injected scripts")
+	} else {
+		for _, source := range []string{"'unsafe-inline'", "'unsafe-eval'"} {
+			if scriptSourcesAllow(csp, source) {
+				add("Content-Security-Policy", SeverityMedium, "scripts allow %s", source)
+			}
+		}
+	}
+
+	// X-Frame-Options, superseded by the CSP frame-ancestors directive.
+	switch xfo := strings.ToUpper(headers.Get("X-Frame-Options")); {
+	case xfo == "DENY" || xfo == "SAMEORIGIN":
+	case xfo == "" && hasDirective(csp, "frame-ancestors"):
+	case xfo == "":
+		add("X-Frame-Options", SeverityMedium, "missing and no CSP frame-ancestors, pages can be framed (clickjacking)")
+	default:
+		add("X-Frame-Options", SeverityMedium, "should be DENY or SAMEORIGIN, got %q", headers.Get("X-Frame-Options"))
+	}
+
+	// X-Content-Type-Options
+	if v := headers.Get("X-Content-Type-Options"); !strings.EqualFold(v, "nosniff") {
+		add("X-Content-Type-Options", SeverityMedium, "should be nosniff to disable MIME sniffing")
+	}
+
+	// Referrer-Policy
+	switch v := strings.ToLower(headers.Get("Referrer-Policy")); v {
+	case "":
+		add("Referrer-Policy", SeverityLow, "missing, full URLs may leak to other sites")
+	case "unsafe-url", "no-referrer-when-downgrade":
+		add("Referrer-Policy", SeverityMedium, "%q leaks full URLs to other sites", v)
+	}
+
+	// Permissions-Policy
+	if headers.Get("Permissions-Policy") == "" {
+		add("Permissions-Policy", SeverityLow, "missing, browser features are not restricted")
+	}
+
+	// Information disclosure
+	for _, key := range []string{"Server", "X-Powered-By"} {
+		if v := headers.Get(key); v != "" {
+			add(key, SeverityLow, "discloses the server software %q", v)
+		}
+	}
+
+	report.Score = 100
+	for _, f := range report.Findings {
+		report.Score -= penalties[f.Severity]
+	}
+	report.Score = max(report.Score, 0)
+	report.Grade = letter(report.Score)
+
+	slices.SortStableFunc(report.Findings, func(a, b Finding) int {
+		return severityRank[a.Severity] - severityRank[b.Severity]
+	})
+	return report
+}
+
+// letter converts a score to a grade.
+func letter(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	}
+	return "F"
+}
+
+// severityRank orders findings from the most to the least severe.
+var severityRank = map[Severity]int{SeverityHigh: 0, SeverityMedium: 1, SeverityLow: 2}
+
+// directives splits a header like CSP or HSTS into its directives.
+func directives(header string) []string {
+	var out []string
+	for _, d := range strings.Split(header, ";") {
+		if d = strings.TrimSpace(d); d != "" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// directiveInt returns the integer value of a name=value directive.
+func directiveInt(header, name string) (int, bool) {
+	for _, d := range directives(header) {
+		key, value, found := strings.Cut(d, "=")
+		if found && strings.EqualFold(strings.TrimSpace(key), name) {
+			n, err := strconv.Atoi(strings.Trim(strings.TrimSpace(value), `"`))
+			return n, err == nil
+		}
+	}
+	return 0, false
+}
+
+// hasDirective reports whether the CSP sets the directive.
+func hasDirective(csp, name string) bool {
+	_, ok := cspSources(csp, name)
+	return ok
+}
+
+// cspSources returns the sources of a CSP directive.
+func cspSources(csp, name string) ([]string, bool) {
+	for _, d := range directives(csp) {
+		fields := strings.Fields(d)
+		if strings.EqualFold(fields[0], name) {
+			return fields[1:], true
+		}
+	}
+	return nil, false
+}
+
+// scriptSourcesAllow reports whether the effective script-src of the CSP contains source.
+func scriptSourcesAllow(csp, source string) bool {
+	sources, ok := cspSources(csp, "script-src")
+	if !ok {
+		sources, _ = cspSources(csp, "default-src")
+	}
+
+	for _, s := range sources {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// PathReport is the Report of a path replayed by ReportHandler.
+type PathReport struct {
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+	Report
+}
+
+// auditKey marks the requests replayed by ReportHandler.
+type auditKey struct{}
+
+// ReportHandler returns a handler that replays a GET request for each path through the router
+// and responds with the graded headers of every response as JSON:
+//
+//	{"grade": "B", "paths": [{"path": "/", "status": 200, "grade": "A", ...}]}
+//
+// The overall grade is the worst of the paths. The Cookie and Authorization headers
+// of the report request are copied to the replayed requests so protected pages can be checked.
+// Mount it behind authentication, the report reveals the configuration of the application.
+func ReportHandler(paths ...string) rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		if c.Request.Context().Value(auditKey{}) != nil {
+			return rex.NewError(http.StatusLoopDetected, "security: the report can not audit itself")
+		}
+
+		reports := make([]PathReport, 0, len(paths))
+		overall := "A"
+		for _, path := range paths {
+			req := httptest.NewRequest(http.MethodGet, path, nil)
+			req = req.WithContext(context.WithValue(req.Context(), auditKey{}, true))
+			req.Host = c.Request.Host
+			for _, key := range []string{"Cookie", "Authorization"} {
+				if v := c.Request.Header.Values(key); len(v) > 0 {
+					req.Header[key] = v
+				}
+			}
+
+			res := httptest.NewRecorder()
+			c.Router().ServeHTTP(res, req)
+
+			report := Grade(res.Result().Header)
+			reports = append(reports, PathReport{Path: path, Status: res.Code, Report: report})
+			overall = max(overall, report.Grade)
+		}
+		return c.JSON(rex.Map{"grade": overall, "paths": reports})
+	}
+}
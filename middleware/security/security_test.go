@@ -0,0 +1,155 @@
+package security_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/security"
+)
+
+func TestGradeRecommended(t *testing.T) {
+	headers := make(http.Header)
+	for k, v := range security.Recommended() {
+		headers.Set(k, v)
+	}
+
+	report := security.Grade(headers)
+	if report.Grade != "A" || report.Score != 100 || len(report.Findings) != 0 {
+		t.Errorf("expected a perfect grade, got %+v", report)
+	}
+
+	if report.Present["X-Frame-Options"] != "DENY" {
+		t.Errorf("expected the present headers to be reported, got %v", report.Present)
+	}
+}
+
+func TestGradeMissingHeaders(t *testing.T) {
+	headers := http.Header{"Server": {"nginx/1.25"}}
+	report := security.Grade(headers)
+
+	want := map[string]security.Severity{
+		"Strict-Transport-Security": security.SeverityHigh,
+		"Content-Security-Policy":   security.SeverityHigh,
+		"X-Frame-Options":           security.SeverityMedium,
+		"X-Content-Type-Options":    security.SeverityMedium,
+		"Referrer-Policy":           security.SeverityLow,
+		"Permissions-Policy":        security.SeverityLow,
+		"Server":                    security.SeverityLow,
+	}
+
+	got := make(map[string]security.Severity)
+	for _, f := range report.Findings {
+		got[f.Header] = f.Severity
+	}
+
+	for header, severity := range want {
+		if got[header] != severity {
+			t.Errorf("expected a %s finding for %s, got %q", severity, header, got[header])
+		}
+	}
+
+	if report.Grade != "F" {
+		t.Errorf("expected grade F, got %s (%d)", report.Grade, report.Score)
+	}
+
+	if report.Findings[0].Severity != security.SeverityHigh {
+		t.Errorf("expected the most severe findings first, got %+v", report.Findings)
+	}
+}
+
+func TestGradeWeakHeaders(t *testing.T) {
+	headers := make(http.Header)
+	for k, v := range security.Recommended() {
+		headers.Set(k, v)
+	}
+	headers.Set("Strict-Transport-Security", "max-age=3600")
+	headers.Set("Content-Security-Policy", "default-src 'self'; script-src 'self' 'unsafe-inline'")
+	headers.Del("X-Frame-Options")
+
+	report := security.Grade(headers)
+
+	var messages []string
+	for _, f := range report.Findings {
+		messages = append(messages, f.Header+": "+f.Message)
+	}
+
+	// HSTS max-age, unsafe-inline and X-Frame-Options without frame-ancestors.
+	if len(report.Findings) != 3 || report.Grade != "C" {
+		t.Errorf("expected 3 medium findings and grade C, got %s %v", report.Grade, messages)
+	}
+
+	// frame-ancestors replaces X-Frame-Options.
+	headers.Set("Content-Security-Policy", "default-src 'self'; frame-ancestors 'self'")
+	headers.Set("Strict-Transport-Security", "max-age=31536000")
+	if report := security.Grade(headers); len(report.Findings) != 0 {
+		t.Errorf("expected no findings, got %+v", report.Findings)
+	}
+}
+
+type reportResponse struct {
+	Grade string                `json:"grade"`
+	Paths []security.PathReport `json:"paths"`
+}
+
+func TestReportHandler(t *testing.T) {
+	r := rex.NewRouter()
+
+	secured := r.Group("/app", func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			for k, v := range security.Recommended() {
+				c.SetHeader(k, v)
+			}
+			return next(c)
+		}
+	})
+
+	secured.GET("/home", func(c *rex.Context) error {
+		if c.Request.Header.Get("Authorization") != "Bearer secret" {
+			return c.WriteHeader(http.StatusUnauthorized)
+		}
+		return c.String("home")
+	})
+
+	r.GET("/legacy", func(c *rex.Context) error {
+		return c.String("legacy")
+	})
+
+	r.GET("/security", security.ReportHandler("/app/home", "/legacy", "/security"))
+
+	req := httptest.NewRequest(http.MethodGet, "/security", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	res := r.Test(req)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", res.Code, res.Body.String())
+	}
+
+	var report reportResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Paths) != 3 {
+		t.Fatalf("expected 3 path reports, got %+v", report.Paths)
+	}
+
+	home := report.Paths[0]
+	if home.Path != "/app/home" || home.Status != http.StatusOK || home.Grade != "A" {
+		t.Errorf("expected the secured route to grade A, got %+v", home)
+	}
+
+	legacy := report.Paths[1]
+	if legacy.Grade != "F" || len(legacy.Findings) != 6 {
+		t.Errorf("expected the uncovered route to be flagged for each missing header, got %+v", legacy)
+	}
+
+	if self := report.Paths[2]; self.Status != http.StatusLoopDetected {
+		t.Errorf("expected the report not to audit itself, got status %d", self.Status)
+	}
+
+	if report.Grade != "F" {
+		t.Errorf("expected the overall grade to be the worst, got %s", report.Grade)
+	}
+}
@@ -0,0 +1,217 @@
+// Package singleflight coalesces concurrent identical GET and HEAD requests
+// so that the handler runs once and all waiting clients share its response.
+package singleflight
+
+import (
+	"bytes"
+	"net/http"
+	"sync"
+
+	"github.com/abiiranathan/rex"
+)
+
+// DefaultMaxBody is the default maximum size of a response that can be shared.
+const DefaultMaxBody = 1 << 20
+
+// DefaultHeaders are the response headers copied to coalesced requests.
+var DefaultHeaders = []string{
+	"Cache-Control",
+	"Content-Encoding",
+	"Content-Language",
+	"Content-Type",
+	"ETag",
+	"Expires",
+	"Last-Modified",
+	"Vary",
+}
+
+// Option configures the singleflight middleware.
+type Option func(*group)
+
+// WithKeyFunc sets the function that identifies identical requests.
+// The default key is the method, path and raw query.
+func WithKeyFunc(fn func(c *rex.Context) string) Option {
+	return func(g *group) {
+		g.keyFunc = fn
+	}
+}
+
+// WithMaxBody sets the maximum size of a response that can be shared.
+// Larger responses are not shared and waiting requests run the handler themselves.
+func WithMaxBody(n int) Option {
+	return func(g *group) {
+		g.maxBody = n
+	}
+}
+
+// WithHeaders sets the allowlist of response headers copied to coalesced requests.
+func WithHeaders(headers ...string) Option {
+	return func(g *group) {
+		g.headers = headers
+	}
+}
+
+// response is the captured response of the leading request.
+type response struct {
+	status int
+	header http.Header
+	body   []byte
+}
+
+// call is an in-flight request that others are waiting on.
+type call struct {
+	done chan struct{}
+	res  *response // nil if the response can not be shared
+}
+
+type group struct {
+	keyFunc func(c *rex.Context) string
+	maxBody int
+	headers []string
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+func defaultKey(c *rex.Context) string {
+	return c.Request.Method + " " + c.Request.URL.Path + "?" + c.Request.URL.RawQuery
+}
+
+// New returns a middleware that coalesces concurrent identical GET and HEAD requests.
+// The first request runs the handler while duplicates wait and receive a copy of its
+// status, allowlisted headers and body. Once it completes, new requests run the handler again;
+// nothing is cached. Responses that set cookies, exceed the body limit or fail with an error
+// are never shared.
+func New(opts ...Option) rex.Middleware {
+	g := &group{
+		keyFunc: defaultKey,
+		maxBody: DefaultMaxBody,
+		headers: DefaultHeaders,
+		calls:   make(map[string]*call),
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+				return next(c)
+			}
+
+			key := g.keyFunc(c)
+
+			g.mu.Lock()
+			if cl, ok := g.calls[key]; ok {
+				g.mu.Unlock()
+				return g.wait(c, cl, next)
+			}
+
+			cl := &call{done: make(chan struct{})}
+			g.calls[key] = cl
+			g.mu.Unlock()
+
+			return g.lead(c, key, cl, next)
+		}
+	}
+}
+
+// lead runs the handler for the first request and captures the response.
+func (g *group) lead(c *rex.Context, key string, cl *call, next rex.HandlerFunc) (err error) {
+	cw := &captureWriter{ResponseWriter: c.Response, status: http.StatusOK, max: g.maxBody}
+	completed := false
+
+	defer func() {
+		var res *response
+		if completed && err == nil && !cw.overflow && cw.Header().Get("Set-Cookie") == "" {
+			res = cw.response(g.headers)
+		}
+
+		g.mu.Lock()
+		delete(g.calls, key)
+		cl.res = res
+		g.mu.Unlock()
+		close(cl.done)
+	}()
+
+	original := c.Response
+	c.Response = cw
+	defer func() { c.Response = original }()
+
+	err = next(c)
+	completed = true
+	return err
+}
+
+// wait waits for the leading request and writes its response.
+// If the response can not be shared, the handler runs for this request.
+func (g *group) wait(c *rex.Context, cl *call, next rex.HandlerFunc) error {
+	select {
+	case <-cl.done:
+	case <-c.Request.Context().Done():
+		return c.Request.Context().Err()
+	}
+
+	if cl.res == nil {
+		return next(c)
+	}
+
+	for k, v := range cl.res.header {
+		c.Response.Header()[k] = append([]string(nil), v...)
+	}
+	c.Response.WriteHeader(cl.res.status)
+	_, err := c.Response.Write(cl.res.body)
+	return err
+}
+
+// captureWriter passes the response through while keeping a copy of up to max bytes.
+type captureWriter struct {
+	http.ResponseWriter
+	status   int
+	max      int
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if !w.overflow {
+		if w.buf.Len()+len(p) > w.max {
+			w.overflow = true
+			w.buf = bytes.Buffer{}
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *captureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *captureWriter) response(headers []string) *response {
+	res := &response{
+		status: w.status,
+		header: make(http.Header),
+		body:   bytes.Clone(w.buf.Bytes()),
+	}
+
+	for _, h := range headers {
+		if v := w.Header().Values(h); len(v) > 0 {
+			res.header[http.CanonicalHeaderKey(h)] = append([]string(nil), v...)
+		}
+	}
+	return res
+}
@@ -0,0 +1,145 @@
+package singleflight_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/singleflight"
+)
+
+// runConcurrent sends n identical requests to path. The first request enters
+// the handler before the others are sent, and the handler returns once release is closed.
+func runConcurrent(r *rex.Router, method, path string, n int, started, release chan struct{}) []*rex.TestResponse {
+	responses := make([]*rex.TestResponse, n)
+	var wg sync.WaitGroup
+
+	send := func(i int) {
+		defer wg.Done()
+		responses[i] = r.Test(httptest.NewRequest(method, path, nil))
+	}
+
+	wg.Add(n)
+	go send(0)
+	<-started
+
+	for i := 1; i < n; i++ {
+		go send(i)
+	}
+
+	// Give the duplicates time to join the in-flight call.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	return responses
+}
+
+func TestSingleflightCoalesces(t *testing.T) {
+	var calls atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+
+	r := rex.NewRouter()
+	r.Use(singleflight.New())
+	r.GET("/expensive", func(c *rex.Context) error {
+		if calls.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		c.SetHeader("X-Internal", "secret")
+		c.SetHeader("Cache-Control", "max-age=60")
+		c.WriteHeader(http.StatusAccepted)
+		return c.String("report ready")
+	})
+
+	const n = 20
+	responses := runConcurrent(r, http.MethodGet, "/expensive", n, started, release)
+
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("expected handler to run once, ran %d times", got)
+	}
+
+	for i, res := range responses {
+		if res.Status() != http.StatusAccepted {
+			t.Errorf("response %d: expected status 202, got %d", i, res.Status())
+		}
+
+		if res.BodyString() != "report ready" {
+			t.Errorf("response %d: unexpected body %q", i, res.BodyString())
+		}
+
+		if res.Header("Cache-Control") != "max-age=60" {
+			t.Errorf("response %d: expected allowlisted Cache-Control header", i)
+		}
+
+		if i > 0 && res.Header("X-Internal") != "" {
+			t.Errorf("response %d: header outside the allowlist was shared", i)
+		}
+	}
+
+	// Coalescing ends when the call completes, nothing is cached.
+	r.Test(httptest.NewRequest(http.MethodGet, "/expensive", nil))
+	if got := calls.Load(); got != 2 {
+		t.Errorf("expected a new call after completion, got %d calls", got)
+	}
+}
+
+func TestSingleflightNeverShares(t *testing.T) {
+	tests := []struct {
+		name    string
+		method  string
+		opts    []singleflight.Option
+		handler func(c *rex.Context) error
+	}{
+		{"set-cookie", http.MethodGet, nil, func(c *rex.Context) error {
+			http.SetCookie(c.Response, &http.Cookie{Name: "session", Value: "abc"})
+			return c.String("private")
+		}},
+		{"over cap", http.MethodGet, []singleflight.Option{singleflight.WithMaxBody(4)}, func(c *rex.Context) error {
+			return c.String(strings.Repeat("x", 64))
+		}},
+		{"post", http.MethodPost, nil, func(c *rex.Context) error {
+			return c.String("created")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			started, release := make(chan struct{}), make(chan struct{})
+
+			r := rex.NewRouter()
+			r.Use(singleflight.New(tt.opts...))
+			handler := func(c *rex.Context) error {
+				if calls.Add(1) == 1 {
+					close(started)
+				}
+				<-release
+				return tt.handler(c)
+			}
+
+			if tt.method == http.MethodPost {
+				r.POST("/", handler)
+			} else {
+				r.GET("/", handler)
+			}
+
+			const n = 5
+			responses := runConcurrent(r, tt.method, "/", n, started, release)
+
+			if got := calls.Load(); got != n {
+				t.Errorf("expected every request to run the handler, got %d of %d", got, n)
+			}
+
+			for i, res := range responses {
+				if res.Status() != http.StatusOK {
+					t.Errorf("response %d: expected status 200, got %d", i, res.Status())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+// Package timezone sets the timezone of each request from a header or cookie,
+// so that form and query dates are parsed in the user's timezone.
+//
+// Example:
+//
+//	r.Use(timezone.New())
+//
+//	r.POST("/events", func(c *rex.Context) error {
+//		var event Event
+//		// event.StartsAt is parsed in the timezone sent by the client.
+//		if err := c.BodyParser(&event); err != nil {
+//			return err
+//		}
+//		return c.JSON(event)
+//	})
+package timezone
+
+import (
+	"sync"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+const (
+	// DefaultHeader is the request header read by default.
+	DefaultHeader = "X-Timezone"
+
+	// DefaultCookie is the cookie read by default if the header is not set.
+	DefaultCookie = "tz"
+)
+
+// Option configures the middleware.
+type Option func(*config)
+
+type config struct {
+	header string
+	cookie string
+}
+
+// WithHeader sets the request header holding the IANA timezone name e.g "Africa/Kampala".
+// An empty name disables the header.
+func WithHeader(name string) Option {
+	return func(cfg *config) {
+		cfg.header = name
+	}
+}
+
+// WithCookie sets the cookie holding the IANA timezone name.
+// An empty name disables the cookie.
+func WithCookie(name string) Option {
+	return func(cfg *config) {
+		cfg.cookie = name
+	}
+}
+
+// locations caches loaded locations by name. Only valid names are cached, which bounds
+// the cache by the timezone database; invalid names are looked up again on each request.
+var locations sync.Map // string -> *time.Location
+
+// load returns the location of name, loading it once.
+func load(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// New creates a middleware that reads the timezone from the X-Timezone header or
+// the tz cookie and sets it with c.SetTimezone. Missing or invalid values are ignored,
+// so the request falls back to rex.DefaultTimezone. Invalid values are logged at debug level.
+func New(opts ...Option) rex.Middleware {
+	cfg := config{header: DefaultHeader, cookie: DefaultCookie}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if name := cfg.lookup(c); name != "" {
+				loc, err := load(name)
+				if err != nil {
+					c.GetLogger().Debug("timezone: ignoring invalid timezone", "timezone", name, "error", err)
+				} else {
+					c.SetTimezone(loc)
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+// lookup returns the timezone name sent by the client, the header takes precedence.
+func (cfg config) lookup(c *rex.Context) string {
+	if cfg.header != "" {
+		if name := c.Request.Header.Get(cfg.header); name != "" {
+			return name
+		}
+	}
+
+	if cfg.cookie != "" {
+		if cookie, err := c.Request.Cookie(cfg.cookie); err == nil {
+			return cookie.Value
+		}
+	}
+	return ""
+}
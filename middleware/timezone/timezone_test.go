@@ -0,0 +1,103 @@
+package timezone_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/timezone"
+)
+
+type event struct {
+	StartsAt time.Time `form:"starts_at"`
+}
+
+// newRouter returns a router that responds with the parsed starts_at in UTC.
+func newRouter(opts ...rex.RouterOption) *rex.Router {
+	r := rex.NewRouter(opts...)
+	r.Use(timezone.New())
+	r.POST("/events", func(c *rex.Context) error {
+		var e event
+		if err := c.BodyParser(&e); err != nil {
+			return err
+		}
+		return c.String(e.StartsAt.UTC().Format(time.RFC3339))
+	})
+	return r
+}
+
+func postEvent(r *rex.Router, configure func(*rex.TestRequest)) string {
+	req := rex.NewTestRequest(http.MethodPost, "/events").
+		Form(url.Values{"starts_at": {"2024-03-01T09:00"}})
+	configure(req)
+	return r.Test(req.Build()).Body.String()
+}
+
+func TestTimezoneParsesFormsInRequestTimezone(t *testing.T) {
+	r := newRouter()
+
+	tests := []struct {
+		name      string
+		configure func(*rex.TestRequest)
+		want      string
+	}{
+		{"header", func(req *rex.TestRequest) { req.Header("X-Timezone", "Africa/Kampala") }, "2024-03-01T06:00:00Z"},
+		{"cookie", func(req *rex.TestRequest) {
+			req.Cookie(&http.Cookie{Name: "tz", Value: "America/New_York"})
+		}, "2024-03-01T14:00:00Z"},
+		{"none", func(req *rex.TestRequest) {}, "2024-03-01T09:00:00Z"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := postEvent(r, tt.configure); got != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTimezoneHeaderTakesPrecedence(t *testing.T) {
+	r := newRouter()
+	got := postEvent(r, func(req *rex.TestRequest) {
+		req.Header("X-Timezone", "Africa/Kampala")
+		req.Cookie(&http.Cookie{Name: "tz", Value: "America/New_York"})
+	})
+
+	if want := "2024-03-01T06:00:00Z"; got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestTimezoneInvalidFallsBack(t *testing.T) {
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	r := newRouter(rex.WithLogger(logger))
+
+	got := postEvent(r, func(req *rex.TestRequest) { req.Header("X-Timezone", "Mars/Olympus") })
+	if want := "2024-03-01T09:00:00Z"; got != want {
+		t.Errorf("expected the default timezone, got %s", got)
+	}
+
+	if !strings.Contains(logs.String(), "Mars/Olympus") {
+		t.Errorf("expected the invalid timezone to be logged, got %q", logs.String())
+	}
+}
+
+func TestTimezoneSetsContextTimezone(t *testing.T) {
+	r := rex.NewRouter()
+	r.Use(timezone.New())
+	r.GET("/", func(c *rex.Context) error {
+		return c.String(c.Timezone().String())
+	})
+
+	req := rex.NewTestRequest(http.MethodGet, "/").Header("X-Timezone", "Africa/Kampala").Build()
+	if got := r.Test(req).Body.String(); got != "Africa/Kampala" {
+		t.Errorf("expected Africa/Kampala, got %s", got)
+	}
+}
@@ -0,0 +1,23 @@
+package txn
+
+import (
+	"context"
+	"database/sql"
+)
+
+// sqlManager adapts *sql.DB to Manager.
+type sqlManager struct {
+	db   *sql.DB
+	opts *sql.TxOptions
+}
+
+// SQL returns a Manager that starts transactions on db with opts, which may be nil.
+// The transactions are *sql.Tx values, retrieve them with As[*sql.Tx].
+func SQL(db *sql.DB, opts *sql.TxOptions) Manager {
+	return sqlManager{db: db, opts: opts}
+}
+
+// Begin implements Manager.
+func (m sqlManager) Begin(ctx context.Context) (Tx, error) {
+	return m.db.BeginTx(ctx, m.opts)
+}
@@ -0,0 +1,136 @@
+// Package txn runs each request in a database transaction that is committed
+// when the handler succeeds and rolled back when it fails or panics.
+//
+// Example:
+//
+//	r.Use(recovery.New(false), txn.New(txn.SQL(db, nil)))
+//
+//	r.POST("/orders", func(c *rex.Context) error {
+//		tx := txn.As[*sql.Tx](c)
+//		_, err := tx.ExecContext(c.Request.Context(), "INSERT INTO orders ...")
+//		return err
+//	})
+package txn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/abiiranathan/rex"
+)
+
+// Tx is a transaction started by a Manager.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// Manager starts transactions. See SQL for a *sql.DB adapter.
+type Manager interface {
+	Begin(ctx context.Context) (Tx, error)
+}
+
+type contextKey struct{}
+
+// Key is the context key the transaction is stored under.
+var Key = contextKey{}
+
+// Option configures the middleware.
+type Option func(*config)
+
+type config struct {
+	commitIf func(c *rex.Context, err error) bool
+}
+
+// WithCommitIf sets the predicate that decides whether to commit after the handler returns.
+// The default commits if the handler returned nil and the response status is below 400.
+func WithCommitIf(fn func(c *rex.Context, err error) bool) Option {
+	return func(cfg *config) {
+		cfg.commitIf = fn
+	}
+}
+
+func defaultCommitIf(c *rex.Context, err error) bool {
+	return err == nil && c.Status() < http.StatusBadRequest
+}
+
+// New creates a middleware that begins a transaction with mgr before the handler
+// and commits or rolls it back right before the response status is sent, or after the
+// handler if it wrote nothing, so that a failed commit is never preceded by a success response.
+// Errors returned by a handler that already wrote its response can not roll it back.
+// If the handler panics, the transaction is rolled back and the panic continues, so register
+// recovery before this middleware. A failed Begin or Commit is returned as an error, which
+// the default error handler answers with 500 Internal Server Error, discarding the response
+// of the handler.
+func New(mgr Manager, opts ...Option) rex.Middleware {
+	cfg := config{commitIf: defaultCommitIf}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) (err error) {
+			tx, err := mgr.Begin(c.Request.Context())
+			if err != nil {
+				return fmt.Errorf("txn: begin: %w", err)
+			}
+			c.Set(Key, tx)
+
+			settled := false
+			settle := func(err error) error {
+				settled = true
+				if !cfg.commitIf(c, err) {
+					if rbErr := tx.Rollback(); rbErr != nil {
+						return errors.Join(err, fmt.Errorf("txn: rollback: %w", rbErr))
+					}
+					return err
+				}
+
+				if commitErr := tx.Commit(); commitErr != nil {
+					return errors.Join(err, fmt.Errorf("txn: commit: %w", commitErr))
+				}
+				return err
+			}
+
+			// c.Status reports the status being written in the hook.
+			c.BeforeWriteHeader(func() error {
+				if settled {
+					return nil
+				}
+				return settle(nil)
+			})
+
+			defer func() {
+				if !settled {
+					// The handler panicked.
+					tx.Rollback()
+				}
+			}()
+
+			err = next(c)
+			if settled {
+				return err
+			}
+			return settle(err)
+		}
+	}
+}
+
+// From returns the transaction of the request or nil if the middleware is not used.
+func From(c *rex.Context) Tx {
+	tx, _ := c.GetOrEmpty(Key).(Tx)
+	return tx
+}
+
+// As returns the transaction of the request as T e.g *sql.Tx.
+// It panics if the middleware is not used or the transaction is not a T.
+func As[T any](c *rex.Context) T {
+	tx, ok := c.GetOrEmpty(Key).(T)
+	if !ok {
+		var zero T
+		panic(fmt.Sprintf("txn: transaction is %T, not %T", c.GetOrEmpty(Key), zero))
+	}
+	return tx
+}
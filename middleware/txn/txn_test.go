@@ -0,0 +1,185 @@
+package txn_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/recovery"
+	"github.com/abiiranathan/rex/middleware/txn"
+)
+
+type fakeTx struct {
+	committed, rolledBack bool
+	commitErr             error
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.committed = true
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.rolledBack = true
+	return nil
+}
+
+type fakeManager struct {
+	txs       []*fakeTx
+	beginErr  error
+	commitErr error
+}
+
+func (m *fakeManager) Begin(ctx context.Context) (txn.Tx, error) {
+	if m.beginErr != nil {
+		return nil, m.beginErr
+	}
+	tx := &fakeTx{commitErr: m.commitErr}
+	m.txs = append(m.txs, tx)
+	return tx, nil
+}
+
+func serve(t *testing.T, mgr *fakeManager, handler rex.HandlerFunc, opts ...txn.Option) *httptest.ResponseRecorder {
+	t.Helper()
+
+	r := rex.NewRouter()
+	r.Use(recovery.New(false), txn.New(mgr, opts...))
+	r.GET("/", handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	return w
+}
+
+func TestCommitOnSuccess(t *testing.T) {
+	mgr := &fakeManager{}
+	w := serve(t, mgr, func(c *rex.Context) error {
+		if txn.From(c) == nil || txn.As[*fakeTx](c) != mgr.txs[0] {
+			t.Error("expected the transaction in the context")
+		}
+		return c.String("ok")
+	})
+
+	if w.Code != http.StatusOK || len(mgr.txs) != 1 {
+		t.Fatalf("expected 200 and one transaction, got %d %d", w.Code, len(mgr.txs))
+	}
+
+	if tx := mgr.txs[0]; !tx.committed || tx.rolledBack {
+		t.Errorf("expected commit only, got %+v", tx)
+	}
+}
+
+func TestRollbackOnError(t *testing.T) {
+	mgr := &fakeManager{}
+	serve(t, mgr, func(c *rex.Context) error {
+		return errors.New("insert failed")
+	})
+
+	if tx := mgr.txs[0]; tx.committed || !tx.rolledBack {
+		t.Errorf("expected rollback only, got %+v", tx)
+	}
+
+	// Error statuses written without returning an error roll back too.
+	mgr = &fakeManager{}
+	serve(t, mgr, func(c *rex.Context) error {
+		c.WriteHeader(http.StatusConflict)
+		return nil
+	})
+
+	if tx := mgr.txs[0]; tx.committed || !tx.rolledBack {
+		t.Errorf("expected rollback for a 409, got %+v", tx)
+	}
+}
+
+func TestRollbackOnPanic(t *testing.T) {
+	mgr := &fakeManager{}
+	w := serve(t, mgr, func(c *rex.Context) error {
+		panic("boom")
+	})
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected recovery to respond with 500, got %d", w.Code)
+	}
+
+	if tx := mgr.txs[0]; tx.committed || !tx.rolledBack {
+		t.Errorf("expected rollback after a panic, got %+v", tx)
+	}
+}
+
+func TestCommitAndBeginFailures(t *testing.T) {
+	mgr := &fakeManager{commitErr: errors.New("serialization failure")}
+	w := serve(t, mgr, func(c *rex.Context) error { return nil })
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("expected a failed commit to respond with 500, got %d", w.Code)
+	}
+
+	called := false
+	mgr = &fakeManager{beginErr: errors.New("connection refused")}
+	w = serve(t, mgr, func(c *rex.Context) error {
+		called = true
+		return nil
+	})
+
+	if called || w.Code != http.StatusInternalServerError {
+		t.Errorf("expected the handler to be skipped with 500, got called=%v %d", called, w.Code)
+	}
+}
+
+func TestCommitFailureAfterResponse(t *testing.T) {
+	mgr := &fakeManager{commitErr: errors.New("serialization failure")}
+	w := serve(t, mgr, func(c *rex.Context) error {
+		return c.JSON(rex.Map{"status": "created"})
+	})
+
+	if w.Code != http.StatusInternalServerError || strings.Contains(w.Body.String(), "created") {
+		t.Errorf("expected the failed commit to replace the response with a 500, got %d %q", w.Code, w.Body.String())
+	}
+
+	if cl := w.Header().Get("Content-Length"); cl != "" && cl != strconv.Itoa(w.Body.Len()) {
+		t.Errorf("expected no stale Content-Length, got %q", cl)
+	}
+
+	if tx := mgr.txs[0]; !tx.committed {
+		t.Errorf("expected the commit to be attempted before the response, got %+v", tx)
+	}
+}
+
+func TestCommitBeforeResponse(t *testing.T) {
+	mgr := &fakeManager{}
+	var committed bool
+	w := serve(t, mgr, func(c *rex.Context) error {
+		if err := c.JSON(rex.Map{"ok": true}); err != nil {
+			return err
+		}
+		committed = mgr.txs[0].committed
+		return nil
+	})
+
+	if w.Code != http.StatusOK || !committed {
+		t.Errorf("expected the commit before the body was sent, got %d committed=%v", w.Code, committed)
+	}
+
+	if tx := mgr.txs[0]; tx.rolledBack {
+		t.Errorf("expected no rollback, got %+v", tx)
+	}
+}
+
+func TestWithCommitIf(t *testing.T) {
+	mgr := &fakeManager{}
+	serve(t, mgr, func(c *rex.Context) error {
+		c.WriteHeader(http.StatusAccepted)
+		return nil
+	}, txn.WithCommitIf(func(c *rex.Context, err error) bool {
+		return err == nil && c.Status() == http.StatusOK
+	}))
+
+	if tx := mgr.txs[0]; tx.committed || !tx.rolledBack {
+		t.Errorf("expected the custom predicate to roll back a 202, got %+v", tx)
+	}
+}
@@ -0,0 +1,163 @@
+// Package useragent classifies requests by their User-Agent header, e.g to serve a pre-rendered
+// snapshot to search engine crawlers while browsers get the SPA.
+// It uses a small table of patterns for the major crawlers and browsers, not a full UA database.
+//
+// Example:
+//
+//	r.GET("/products/{id}", spa, useragent.IfBot(prerendered))
+//
+//	r.GET("/", func(c *rex.Context) error {
+//		if useragent.From(c).Mobile {
+//			return c.Render("home_mobile", nil)
+//		}
+//		return c.Render("home", nil)
+//	})
+package useragent
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/abiiranathan/rex"
+)
+
+// OverrideHeader replaces the User-Agent header for classification in rex.Debug mode,
+// so that bot and mobile routes can be tried from a browser, e.g with
+// "X-Debug-User-Agent: Googlebot/2.1". It is ignored otherwise.
+var OverrideHeader = "X-Debug-User-Agent"
+
+// Agent is the classification of a User-Agent.
+type Agent struct {
+	Bot     bool   // a crawler, preview fetcher or other automated client
+	Name    string // e.g "Googlebot", "Chrome" or "" if unknown
+	Version string // major.minor version reported by the agent, "" if unknown
+	Mobile  bool   // a phone or tablet
+}
+
+// pattern matches a known agent. The version is the first submatch, if any.
+type pattern struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// bots are checked before browsers, crawlers often include browser tokens.
+var bots = []pattern{
+	{"Googlebot", regexp.MustCompile(`Googlebot(?:-\w+)?/(\d+(?:\.\d+)?)`)},
+	{"Google-InspectionTool", regexp.MustCompile(`Google-InspectionTool/(\d+(?:\.\d+)?)`)},
+	{"Bingbot", regexp.MustCompile(`(?i)bingbot/(\d+(?:\.\d+)?)`)},
+	{"DuckDuckBot", regexp.MustCompile(`DuckDuckBot(?:-\w+)?/(\d+(?:\.\d+)?)`)},
+	{"YandexBot", regexp.MustCompile(`YandexBot/(\d+(?:\.\d+)?)`)},
+	{"Baiduspider", regexp.MustCompile(`Baiduspider(?:-\w+)?/(\d+(?:\.\d+)?)`)},
+	{"Applebot", regexp.MustCompile(`Applebot/(\d+(?:\.\d+)?)`)},
+	{"facebookexternalhit", regexp.MustCompile(`facebookexternalhit/(\d+(?:\.\d+)?)`)},
+	{"Twitterbot", regexp.MustCompile(`Twitterbot/(\d+(?:\.\d+)?)`)},
+	{"LinkedInBot", regexp.MustCompile(`LinkedInBot/(\d+(?:\.\d+)?)`)},
+	{"Slackbot", regexp.MustCompile(`Slackbot(?:-LinkExpanding)?(?: (\d+(?:\.\d+)?))?`)},
+	{"Discordbot", regexp.MustCompile(`Discordbot/(\d+(?:\.\d+)?)`)},
+	{"GPTBot", regexp.MustCompile(`GPTBot/(\d+(?:\.\d+)?)`)},
+	{"AhrefsBot", regexp.MustCompile(`AhrefsBot/(\d+(?:\.\d+)?)`)},
+	{"SemrushBot", regexp.MustCompile(`SemrushBot(?:-\w+)?/(\d+(?:\.\d+)?)`)},
+}
+
+// genericBot matches other automated clients.
+var genericBot = regexp.MustCompile(`(?i)bot\b|crawl|spider|slurp|headless|curl/|wget/|python-requests|go-http-client`)
+
+// browsers are checked in order, e.g Edge and Opera also send the Chrome token.
+var browsers = []pattern{
+	{"Edge", regexp.MustCompile(`Edg(?:e|A|iOS)?/(\d+(?:\.\d+)?)`)},
+	{"Opera", regexp.MustCompile(`(?:OPR|Opera)/(\d+(?:\.\d+)?)`)},
+	{"Samsung Internet", regexp.MustCompile(`SamsungBrowser/(\d+(?:\.\d+)?)`)},
+	{"Firefox", regexp.MustCompile(`(?:Firefox|FxiOS)/(\d+(?:\.\d+)?)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/(\d+(?:\.\d+)?)`)},
+	{"Safari", regexp.MustCompile(`Version/(\d+(?:\.\d+)?).*Safari/`)},
+}
+
+var mobile = regexp.MustCompile(`Mobi|Android|iPhone|iPad|iPod|Windows Phone`)
+
+// Parse classifies a User-Agent header value.
+func Parse(ua string) Agent {
+	var agent Agent
+	agent.Mobile = mobile.MatchString(ua)
+
+	for _, p := range bots {
+		if m := p.re.FindStringSubmatch(ua); m != nil {
+			agent.Bot, agent.Name = true, p.name
+			if len(m) > 1 {
+				agent.Version = m[1]
+			}
+			return agent
+		}
+	}
+
+	if genericBot.MatchString(ua) {
+		agent.Bot = true
+		return agent
+	}
+
+	for _, p := range browsers {
+		if m := p.re.FindStringSubmatch(ua); m != nil {
+			agent.Name, agent.Version = p.name, m[1]
+			return agent
+		}
+	}
+
+	// Clients that do not identify themselves are not browsers.
+	agent.Bot = strings.TrimSpace(ua) == ""
+	return agent
+}
+
+type contextKey struct{}
+
+// From returns the classification of the request, parsed once per request.
+// It does not require New.
+func From(c *rex.Context) Agent {
+	if agent, ok := c.Get(contextKey{}); ok {
+		return agent.(Agent)
+	}
+
+	ua := c.Request.UserAgent()
+	if rex.Debug {
+		if override := c.GetHeader(OverrideHeader); override != "" {
+			ua = override
+		}
+	}
+
+	agent := Parse(ua)
+	c.Set(contextKey{}, agent)
+	return agent
+}
+
+// New creates a middleware that classifies every request, for handlers and templates
+// reading useragent.From(c).
+func New() rex.Middleware {
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			From(c)
+			return next(c)
+		}
+	}
+}
+
+// If creates a middleware that calls handler instead of the next handler when match
+// returns true for the agent of the request. The response varies by User-Agent.
+func If(match func(Agent) bool, handler rex.HandlerFunc) rex.Middleware {
+	return func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			c.AppendVary("User-Agent")
+			if match(From(c)) {
+				return handler(c)
+			}
+			return next(c)
+		}
+	}
+}
+
+// IfBot creates a middleware that calls handler instead of the next handler for crawlers and other bots.
+func IfBot(handler rex.HandlerFunc) rex.Middleware {
+	return If(func(a Agent) bool { return a.Bot }, handler)
+}
+
+// IfMobile creates a middleware that calls handler instead of the next handler for phones and tablets.
+func IfMobile(handler rex.HandlerFunc) rex.Middleware {
+	return If(func(a Agent) bool { return a.Mobile }, handler)
+}
@@ -0,0 +1,112 @@
+package useragent_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/useragent"
+)
+
+const (
+	googlebot       = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	googlebotMobile = "Mozilla/5.0 (Linux; Android 6.0.1; Nexus 5X Build/MMB29P) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.126 Mobile Safari/537.36 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+	bingbot         = "Mozilla/5.0 AppleWebKit/537.36 (KHTML, like Gecko; compatible; bingbot/2.0; +http://www.bing.com/bingbot.htm) Chrome/116.0.1938.76 Safari/537.36"
+	chrome          = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
+	edge            = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36 Edg/126.0.2592.68"
+	androidChrome   = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.6478.122 Mobile Safari/537.36"
+	iphoneSafari    = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.5 Mobile/15E148 Safari/604.1"
+	ipadChrome      = "Mozilla/5.0 (iPad; CPU OS 17_5 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/126.0.6478.54 Mobile/15E148 Safari/604.1"
+	firefox         = "Mozilla/5.0 (X11; Linux x86_64; rv:127.0) Gecko/20100101 Firefox/127.0"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ua   string
+		want useragent.Agent
+	}{
+		{googlebot, useragent.Agent{Bot: true, Name: "Googlebot", Version: "2.1"}},
+		{googlebotMobile, useragent.Agent{Bot: true, Name: "Googlebot", Version: "2.1", Mobile: true}},
+		{bingbot, useragent.Agent{Bot: true, Name: "Bingbot", Version: "2.0"}},
+		{"curl/8.5.0", useragent.Agent{Bot: true}},
+		{"", useragent.Agent{Bot: true}},
+		{chrome, useragent.Agent{Name: "Chrome", Version: "126.0"}},
+		{edge, useragent.Agent{Name: "Edge", Version: "126.0"}},
+		{firefox, useragent.Agent{Name: "Firefox", Version: "127.0"}},
+		{androidChrome, useragent.Agent{Name: "Chrome", Version: "126.0", Mobile: true}},
+		{iphoneSafari, useragent.Agent{Name: "Safari", Version: "17.5", Mobile: true}},
+		{ipadChrome, useragent.Agent{Name: "Chrome", Version: "126.0", Mobile: true}},
+	}
+
+	for _, tt := range tests {
+		if got := useragent.Parse(tt.ua); got != tt.want {
+			t.Errorf("Parse(%q): expected %+v, got %+v", tt.ua, tt.want, got)
+		}
+	}
+}
+
+func newRouter() *rex.Router {
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		return c.String("spa")
+	}, useragent.IfBot(func(c *rex.Context) error {
+		return c.String("snapshot")
+	}))
+
+	r.GET("/home", func(c *rex.Context) error {
+		return c.String("desktop")
+	}, useragent.IfMobile(func(c *rex.Context) error {
+		return c.String("mobile")
+	}))
+	return r
+}
+
+func get(r *rex.Router, path, ua string, headers ...string) *rex.TestResponse {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("User-Agent", ua)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	return r.Test(req)
+}
+
+func TestIfBot(t *testing.T) {
+	r := newRouter()
+
+	for ua, want := range map[string]string{googlebot: "snapshot", bingbot: "snapshot", chrome: "spa"} {
+		res := get(r, "/", ua)
+		if res.BodyString() != want {
+			t.Errorf("%s: expected %q, got %q", ua, want, res.BodyString())
+		}
+
+		if res.Header("Vary") != "User-Agent" {
+			t.Errorf("expected Vary: User-Agent, got %q", res.Header("Vary"))
+		}
+	}
+}
+
+func TestIfMobile(t *testing.T) {
+	r := newRouter()
+
+	for ua, want := range map[string]string{androidChrome: "mobile", iphoneSafari: "mobile", chrome: "desktop"} {
+		if res := get(r, "/home", ua); res.BodyString() != want {
+			t.Errorf("%s: expected %q, got %q", ua, want, res.BodyString())
+		}
+	}
+}
+
+func TestOverrideHeaderOnlyInDebug(t *testing.T) {
+	r := newRouter()
+
+	if res := get(r, "/", chrome, useragent.OverrideHeader, googlebot); res.BodyString() != "spa" {
+		t.Errorf("expected the override to be ignored, got %q", res.BodyString())
+	}
+
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	if res := get(r, "/", chrome, useragent.OverrideHeader, googlebot); res.BodyString() != "snapshot" {
+		t.Errorf("expected the override in debug mode, got %q", res.BodyString())
+	}
+}
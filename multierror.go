@@ -0,0 +1,191 @@
+package rex
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// Error is an error with an HTTP status. The default error handler responds
+// with the status and message.
+type Error struct {
+	Status  int    // HTTP status code.
+	Message string // Message sent to the client.
+}
+
+// NewError returns an Error with the status and message.
+//
+// Example:
+//
+//	return rex.NewError(http.StatusConflict, "email is already registered")
+func NewError(status int, message string) error {
+	return Error{Status: status, Message: message}
+}
+
+// Error implements the error interface.
+func (e Error) Error() string {
+	return e.Message
+}
+
+// Errors joins the non-nil errors like errors.Join. It returns nil if all errors are nil
+// and the error itself if only one is not nil.
+// The default error handler merges joined validation, form, param and rex.Error errors
+// into a single response, see ErrorItem.
+func Errors(errs ...error) error {
+	var nonNil []error
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	}
+	return errors.Join(nonNil...)
+}
+
+// ErrorItem is one of the errors of a merged error response.
+type ErrorItem struct {
+	Status  int    `json:"status"`          // Status of this error.
+	Type    string `json:"type"`            // "validation", "form", "param" or "error".
+	Field   string `json:"field,omitempty"` // The invalid field or parameter, if any.
+	Message string `json:"message"`         // Human readable message.
+}
+
+// flattenErrors returns the leaves of joined errors, following wrapped chains.
+func flattenErrors(err error) []error {
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		var out []error
+		for _, inner := range e.Unwrap() {
+			out = append(out, flattenErrors(inner)...)
+		}
+		return out
+	case interface{ Unwrap() error }:
+		// Keep known error types whole, only unwrap plain wrappers like fmt.Errorf.
+		if _, known := errorItems(nil, err); known {
+			return []error{err}
+		}
+		if inner := e.Unwrap(); inner != nil {
+			return flattenErrors(inner)
+		}
+	}
+	return []error{err}
+}
+
+// errorItems converts err to response items. ok is false for unknown error types.
+func errorItems(c *Context, err error) (items []ErrorItem, ok bool) {
+	switch e := err.(type) {
+	case validator.ValidationErrors:
+		for _, fe := range e {
+			msg := fe.Error()
+			if c != nil {
+				msg = fe.Translate(c.router.translator)
+			}
+			items = append(items, ErrorItem{
+				Status:  http.StatusUnprocessableEntity,
+				Type:    "validation",
+				Field:   fe.Field(),
+				Message: msg,
+			})
+		}
+		return items, true
+	case FormError:
+		field, msg := e.Field, e.Err.Error()
+		if inner, ok := e.Err.(FormError); ok {
+			field, msg = inner.Field, inner.Err.Error()
+		}
+		return []ErrorItem{{Status: formErrorStatus(e), Type: "form", Field: field, Message: msg}}, true
+	case ParamError:
+		return []ErrorItem{{Status: http.StatusBadRequest, Type: "param", Field: e.Key, Message: e.Error()}}, true
+	case Error:
+		return []ErrorItem{{Status: e.Status, Type: "error", Message: e.Message}}, true
+	}
+	return nil, false
+}
+
+// joinedErrorItems returns the merged items of a joined error.
+// ok is false if err is not a joined error or one of its errors has an unknown type.
+func joinedErrorItems(c *Context, err error) (items []ErrorItem, status int, ok bool) {
+	leaves := flattenErrors(err)
+	if len(leaves) < 2 {
+		return nil, 0, false
+	}
+
+	for _, leaf := range leaves {
+		leafItems, known := errorItems(c, leaf)
+		if !known {
+			return nil, 0, false
+		}
+		items = append(items, leafItems...)
+	}
+	return items, mergedStatus(items), true
+}
+
+// mergedStatus picks the status of a merged response. An explicit rex.Error is the most severe,
+// so the highest of their statuses is used. Otherwise it is 422 if any validation failed
+// and the highest status of the remaining errors if not.
+func mergedStatus(items []ErrorItem) int {
+	explicit, highest, validation := 0, 0, false
+	for _, item := range items {
+		switch item.Type {
+		case "error":
+			explicit = max(explicit, item.Status)
+		case "validation":
+			validation = true
+		}
+		highest = max(highest, item.Status)
+	}
+
+	switch {
+	case explicit > 0:
+		return explicit
+	case validation:
+		return http.StatusUnprocessableEntity
+	}
+	return highest
+}
+
+// HandleJoinedErrors responds with all errors of a joined error.
+// JSON clients receive {"status": 422, "errors": [...ErrorItem]}.
+// HTML clients get the error template with "errors" set to the items if configured,
+// or a list of the messages.
+func HandleJoinedErrors(c *Context, err error, items []ErrorItem, status int) {
+	accept := strings.Split(c.Request.Header.Get("Accept"), ";")[0]
+
+	if accept == "application/json" {
+		c.jsonStatus(status, Map{"status": status, "errors": items})
+		return
+	}
+
+	if c.router.errorTemplate != "" && c.router.template != nil {
+		c.SetHeader("Content-Type", "text/html")
+		c.Response.WriteHeader(status)
+		c.renderTemplate(c.router.errorTemplate, Map{
+			"status":      status,
+			"status_text": http.StatusText(status),
+			"error":       err,
+			"errors":      items,
+		})
+		return
+	}
+
+	var htmlReply strings.Builder
+	htmlReply.WriteString(`<div class="rex_error">`)
+	for _, item := range items {
+		htmlReply.WriteString(`<p class="rex_error_item">`)
+		htmlReply.WriteString(template.HTMLEscapeString(item.Message))
+		htmlReply.WriteString("</p>")
+	}
+	htmlReply.WriteString("</div>")
+
+	c.WriteHeader(status)
+	c.HTML(htmlReply.String())
+}
@@ -0,0 +1,123 @@
+package rex_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/go-playground/validator/v10"
+)
+
+type signup struct {
+	Email string `validate:"required,email"`
+	Name  string `validate:"required"`
+}
+
+func validationError() error {
+	return validator.New().Struct(signup{Email: "not-an-email"})
+}
+
+func TestJoinedErrorsJSON(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/signup", func(c *rex.Context) error {
+		return errors.Join(validationError(), rex.NewError(http.StatusConflict, "email is already registered"))
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/signup", nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusConflict {
+		t.Fatalf("expected 409, got %d: %s", w.Code, w.Body.String())
+	}
+
+	var body struct {
+		Status int             `json:"status"`
+		Errors []rex.ErrorItem `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Status != http.StatusConflict || len(body.Errors) != 3 {
+		t.Fatalf("expected 3 merged errors with status 409, got %+v", body)
+	}
+
+	fields := map[string]rex.ErrorItem{}
+	for _, item := range body.Errors {
+		fields[item.Field] = item
+	}
+
+	if item := fields["Email"]; item.Type != "validation" || item.Status != http.StatusUnprocessableEntity || item.Message == "" {
+		t.Errorf("unexpected email item %+v", item)
+	}
+
+	if item := fields[""]; item.Type != "error" || item.Status != http.StatusConflict || item.Message != "email is already registered" {
+		t.Errorf("unexpected conflict item %+v", item)
+	}
+}
+
+func TestJoinedErrorsStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"validation and param", rex.Errors(validationError(), rex.ParamError{Source: "query", Key: "page", Err: rex.ErrMissingValue}), http.StatusUnprocessableEntity},
+		{"form and param", rex.Errors(rex.FormError{Err: errors.New("too big"), Kind: rex.BodyTooLarge}, rex.ParamError{Source: "query", Key: "page", Err: rex.ErrMissingValue}), http.StatusRequestEntityTooLarge},
+		{"wrapped chain", fmt.Errorf("create: %w", errors.Join(rex.NewError(http.StatusConflict, "a"), rex.NewError(http.StatusForbidden, "b"))), http.StatusConflict},
+		{"unknown errors fall back", errors.Join(validationError(), errors.New("boom")), http.StatusBadRequest},
+		{"single rex.Error", rex.Errors(nil, rex.NewError(http.StatusTeapot, "tea")), http.StatusTeapot},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rex.NewRouter()
+			r.GET("/", func(c *rex.Context) error { return tt.err })
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if rex.Errors(nil, nil) != nil {
+		t.Error("expected nil for only nil errors")
+	}
+}
+
+func TestJoinedErrorsHTML(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		return rex.Errors(validationError(), rex.NewError(http.StatusConflict, "<taken>"))
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), `<p class="rex_error_item">&lt;taken&gt;</p>`) {
+		t.Errorf("expected an escaped list of errors, got %d %q", w.Code, w.Body.String())
+	}
+
+	tmpl := template.Must(template.New("error.html").Parse(
+		`{{.status}}:{{range .errors}}[{{.Type}} {{.Field}}]{{end}}`))
+	r = rex.NewRouter(rex.WithTemplates(tmpl), rex.ErrorTemplate("error.html"), rex.BaseLayout("error.html"))
+	r.GET("/", func(c *rex.Context) error {
+		return rex.Errors(rex.ParamError{Source: "query", Key: "q", Err: rex.ErrMissingValue}, rex.NewError(http.StatusConflict, "x"))
+	})
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Body.String() != "409:[param q][error ]" {
+		t.Errorf("expected the error template to receive the errors, got %q", w.Body.String())
+	}
+}
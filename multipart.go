@@ -0,0 +1,207 @@
+package rex
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+)
+
+// DefaultMultipartMemory is the number of bytes of a multipart body kept in memory
+// when parsing forms. Larger parts are stored in temporary files.
+const DefaultMultipartMemory int64 = 10 << 20 // 10 MB
+
+// WithMultipartMemory sets the maximum number of bytes of a multipart body kept in memory
+// by BodyParser, FormFile and FormFiles. The remainder is stored in temporary files on disk
+// that are removed once the handler returns. The default is DefaultMultipartMemory.
+func WithMultipartMemory(n int64) RouterOption {
+	return func(r *Router) {
+		r.multipartMemory = n
+	}
+}
+
+// ParseMultipartForm parses a multipart/form-data request body keeping at most maxMemory
+// bytes in memory. If maxMemory is omitted, the value set with WithMultipartMemory is used.
+// Forms are parsed once per request, so calling ParseMultipartForm before BodyParser
+// overrides the threshold for that request.
+func (c *Context) ParseMultipartForm(maxMemory ...int64) error {
+	memory := DefaultMultipartMemory
+	if c.router != nil && c.router.multipartMemory > 0 {
+		memory = c.router.multipartMemory
+	}
+
+	if len(maxMemory) > 0 && maxMemory[0] > 0 {
+		memory = maxMemory[0]
+	}
+	return c.Request.ParseMultipartForm(memory)
+}
+
+// removeMultipartFiles deletes temporary files created while parsing a multipart form.
+func (c *Context) removeMultipartFiles() {
+	if c.Request == nil || c.Request.MultipartForm == nil {
+		return
+	}
+
+	if err := c.Request.MultipartForm.RemoveAll(); err != nil && c.router != nil {
+		c.router.logger.Warn("failed to remove multipart temp files", "error", err)
+	}
+}
+
+// PartHandler handles a part of a multipart body streamed by MultipartReader.
+// The part is closed after it returns.
+type PartHandler func(part *multipart.Part) error
+
+// PartHandlers are the handlers of the parts streamed by MultipartReader.
+type PartHandlers struct {
+	Fields  map[string]PartHandler // Handlers by form field name.
+	Default PartHandler            // Handles file parts without a handler in Fields, which are skipped if nil.
+}
+
+// PartTooLargeError is returned by the handlers of PartToWriter for parts larger than their limit.
+// The default error handler answers it with 413 Request Entity Too Large.
+type PartTooLargeError struct {
+	Field    string // Form field name of the part.
+	FileName string // File name of the part, "" if it is not a file.
+	Limit    int64  // The limit in bytes.
+}
+
+// Error implements the error interface.
+func (e PartTooLargeError) Error() string {
+	return fmt.Sprintf("part %q is larger than %d bytes", e.Field, e.Limit)
+}
+
+// PartToWriter returns a handler copying a part to w, e.g an object storage upload.
+// Parts larger than maxBytes return PartTooLargeError after maxBytes have been copied.
+// maxBytes <= 0 means no limit.
+func PartToWriter(w io.Writer, maxBytes int64) PartHandler {
+	return func(part *multipart.Part) error {
+		if maxBytes <= 0 {
+			_, err := io.Copy(w, part)
+			return err
+		}
+
+		if _, err := io.Copy(w, io.LimitReader(part, maxBytes)); err != nil {
+			return err
+		}
+
+		var next [1]byte
+		if n, _ := io.ReadFull(part, next[:]); n > 0 {
+			return PartTooLargeError{Field: part.FormName(), FileName: part.FileName(), Limit: maxBytes}
+		}
+		return nil
+	}
+}
+
+// MultipartReader streams the parts of a multipart/form-data body to handlers, in the order
+// sent by the client, without storing files in memory or temporary files like ParseMultipartForm.
+// Use it for huge uploads piped elsewhere, e.g with PartToWriter.
+//
+// Parts without a handler in handlers.Fields that are not files are collected, up to the size
+// set with WithMultipartMemory in total. Once MultipartReader returns, they are available with
+// c.FormValue and c.Request.MultipartForm and can be bound with c.DecodeForm.
+// Clients must send them before the files if handlers need them.
+//
+// The first error of a handler stops reading and is returned, for the router's error handler.
+// The body can be read only once: MultipartReader and BodyParser, ParseMultipartForm,
+// FormFile or FormFiles are mutually exclusive for a request.
+//
+// Example:
+//
+//	err := c.MultipartReader(rex.PartHandlers{
+//		Fields: map[string]rex.PartHandler{
+//			"video": rex.PartToWriter(bucketWriter, 5<<30),
+//		},
+//	})
+func (c *Context) MultipartReader(handlers PartHandlers) error {
+	if c.ContentType() != ContentTypeMultipartForm {
+		return FormError{
+			Err:  fmt.Errorf("unsupported content type: %s", c.ContentType()),
+			Kind: InvalidContentType,
+		}
+	}
+
+	limits := DefaultDecompressLimits
+	memory := DefaultMultipartMemory
+	if c.router != nil {
+		limits = c.router.decompressLimits
+		if c.router.multipartMemory > 0 {
+			memory = c.router.multipartMemory
+		}
+	}
+
+	if err := DecompressBody(c.Request, limits); err != nil {
+		return err
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return FormError{Err: err, Kind: ParseError}
+	}
+
+	values := make(url.Values)
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return bodyReadError(err, "")
+		}
+
+		name := part.FormName()
+		if handler, ok := handlers.Fields[name]; ok {
+			err = handler(part)
+		} else if part.FileName() == "" {
+			var value []byte
+			value, err = io.ReadAll(io.LimitReader(part, memory+1))
+			if err != nil {
+				err = bodyReadError(err, name)
+			} else if memory -= int64(len(value)); memory < 0 {
+				err = FormError{Err: multipart.ErrMessageTooLarge, Kind: BodyTooLarge, Field: name}
+			} else {
+				values.Add(name, string(value))
+			}
+		} else if handlers.Default != nil {
+			err = handlers.Default(part)
+		}
+
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Expose the fields like ParseMultipartForm.
+	r := c.Request
+	r.MultipartForm = &multipart.Form{Value: values, File: make(map[string][]*multipart.FileHeader)}
+	if err := r.ParseForm(); err != nil {
+		return FormError{Err: err, Kind: ParseError}
+	}
+
+	if r.PostForm == nil {
+		r.PostForm = make(url.Values)
+	}
+
+	for k, v := range values {
+		r.Form[k] = append(r.Form[k], v...)
+		r.PostForm[k] = append(r.PostForm[k], v...)
+	}
+	return nil
+}
+
+// bodyReadError converts an error reading the request body into a FormError.
+func bodyReadError(err error, field string) FormError {
+	fe := FormError{Err: err, Kind: ParseError, Field: field}
+
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		fe.Kind = BodyTooLarge
+	case errors.Is(err, ErrDecompressedTooLarge):
+		fe.Kind = DecompressionFailed
+	}
+	return fe
+}
@@ -0,0 +1,241 @@
+package rex_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestMultipartSpillsToDisk(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	r := rex.NewRouter(rex.WithMultipartMemory(1024))
+	content := bytes.Repeat([]byte("a"), 64<<10)
+
+	type upload struct {
+		Name string `form:"name"`
+	}
+
+	var spilled int
+	r.POST("/upload", func(c *rex.Context) error {
+		var u upload
+		if err := c.BodyParser(&u); err != nil {
+			return err
+		}
+
+		files, err := c.FormFiles("file")
+		if err != nil {
+			return err
+		}
+
+		f, err := files[0].Open()
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		data, err := io.ReadAll(f)
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(data, content) {
+			t.Errorf("uploaded file content mismatch, got %d bytes", len(data))
+		}
+
+		entries, _ := os.ReadDir(tmp)
+		spilled = len(entries)
+		return c.String(u.Name)
+	})
+
+	req := rex.NewTestRequest(http.MethodPost, "/upload").
+		Form(url.Values{"name": {"rex"}}).
+		File("file", "big.txt", content).
+		Build()
+
+	res := r.Test(req)
+	if res.Status() != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", res.Status(), res.BodyString())
+	}
+
+	if res.BodyString() != "rex" {
+		t.Errorf("expected body rex, got %q", res.BodyString())
+	}
+
+	if spilled == 0 {
+		t.Fatalf("expected the file to be stored in a temp file")
+	}
+
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 0 {
+		t.Errorf("expected temp files to be removed, found %d", len(entries))
+	}
+}
+
+func TestMultipartMemoryOverride(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	r := rex.NewRouter(rex.WithMultipartMemory(1024))
+	content := bytes.Repeat([]byte("b"), 4<<10)
+
+	r.POST("/upload", func(c *rex.Context) error {
+		files, err := c.FormFiles("file", 1<<20)
+		if err != nil {
+			return err
+		}
+
+		entries, _ := os.ReadDir(tmp)
+		if len(entries) != 0 {
+			t.Errorf("expected the file to be kept in memory, found %d temp files", len(entries))
+		}
+		return c.String(files[0].Filename)
+	})
+
+	req := rex.NewTestRequest(http.MethodPost, "/upload").File("file", "small.txt", content).Build()
+	res := r.Test(req)
+	if res.Status() != http.StatusOK || res.BodyString() != "small.txt" {
+		t.Fatalf("unexpected response %d: %s", res.Status(), res.BodyString())
+	}
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct{ n int64 }
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+func TestMultipartReaderStreamsParts(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+
+	r := rex.NewRouter(rex.WithMultipartMemory(1024))
+	video := bytes.Repeat([]byte("v"), 256<<10)
+
+	type meta struct {
+		Title string `form:"title"`
+	}
+
+	var streamed countingWriter
+	var skipped []string
+	r.POST("/upload", func(c *rex.Context) error {
+		err := c.MultipartReader(rex.PartHandlers{
+			Fields: map[string]rex.PartHandler{
+				"video": rex.PartToWriter(&streamed, 1<<20),
+			},
+			Default: func(part *multipart.Part) error {
+				skipped = append(skipped, part.FileName())
+				return nil
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		var m meta
+		if err := c.DecodeForm(&m); err != nil {
+			return err
+		}
+
+		if entries, _ := os.ReadDir(tmp); len(entries) != 0 {
+			t.Errorf("expected no temp files, got %d", len(entries))
+		}
+		return c.String(m.Title + "," + c.FormValue("tags"))
+	})
+
+	req := rex.NewTestRequest(http.MethodPost, "/upload").
+		Form(url.Values{"title": {"holiday"}, "tags": {"beach"}}).
+		File("video", "holiday.mp4", video).
+		File("thumb", "thumb.png", []byte("png")).
+		Build()
+
+	res := r.Test(req)
+	if res.Status() != http.StatusOK || res.BodyString() != "holiday,beach" {
+		t.Fatalf("expected the text fields to be captured, got %d %q", res.Status(), res.BodyString())
+	}
+
+	if streamed.n != int64(len(video)) {
+		t.Errorf("expected %d bytes streamed, got %d", len(video), streamed.n)
+	}
+
+	if len(skipped) != 1 || skipped[0] != "thumb.png" {
+		t.Errorf("expected the default handler to get the thumbnail, got %v", skipped)
+	}
+}
+
+func TestMultipartReaderPartTooLarge(t *testing.T) {
+	r := rex.NewRouter()
+
+	var readErr error
+	var streamed countingWriter
+	r.POST("/upload", func(c *rex.Context) error {
+		readErr = c.MultipartReader(rex.PartHandlers{
+			Fields: map[string]rex.PartHandler{"video": rex.PartToWriter(&streamed, 1000)},
+		})
+		return readErr
+	})
+
+	req := rex.NewTestRequest(http.MethodPost, "/upload").
+		File("video", "big.mp4", bytes.Repeat([]byte("v"), 4096)).
+		Build()
+
+	res := r.Test(req)
+
+	var tooLarge rex.PartTooLargeError
+	if !errors.As(readErr, &tooLarge) || tooLarge.Field != "video" || tooLarge.FileName != "big.mp4" || tooLarge.Limit != 1000 {
+		t.Fatalf("expected a PartTooLargeError, got %v", readErr)
+	}
+
+	if streamed.n != 1000 {
+		t.Errorf("expected the copy to stop at the limit, got %d bytes", streamed.n)
+	}
+
+	if res.Status() != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected 413, got %d", res.Status())
+	}
+}
+
+func TestMultipartReaderErrors(t *testing.T) {
+	r := rex.NewRouter(rex.WithMultipartMemory(16))
+
+	var readErr error
+	r.POST("/upload", func(c *rex.Context) error {
+		readErr = c.MultipartReader(rex.PartHandlers{
+			Fields: map[string]rex.PartHandler{
+				"fail": func(part *multipart.Part) error { return errors.New("storage down") },
+			},
+		})
+		return nil
+	})
+
+	r.Test(rex.NewTestRequest(http.MethodPost, "/upload").Form(url.Values{"fail": {"x"}}).File("doc", "doc.txt", nil).Build())
+	if readErr == nil || readErr.Error() != "storage down" {
+		t.Errorf("expected the handler error, got %v", readErr)
+	}
+
+	r.Test(rex.NewTestRequest(http.MethodPost, "/upload").Form(url.Values{"note": {"more than sixteen bytes"}}).File("doc", "doc.txt", nil).Build())
+
+	var fe rex.FormError
+	if !errors.As(readErr, &fe) || fe.Kind != rex.BodyTooLarge || fe.Field != "note" {
+		t.Errorf("expected the text fields to be limited, got %v", readErr)
+	}
+
+	r.Test(rex.NewTestRequest(http.MethodPost, "/upload").JSON(map[string]string{"a": "b"}).Build())
+	if !errors.As(readErr, &fe) || fe.Kind != rex.InvalidContentType {
+		t.Errorf("expected an invalid content type error, got %v", readErr)
+	}
+}
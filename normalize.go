@@ -0,0 +1,127 @@
+package rex
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// PathNormalization configures path normalization enabled with NormalizePaths.
+type PathNormalization struct {
+	// Redirect issues a 301 to the canonical path for GET and HEAD requests.
+	// Other methods (and all methods when false) are rewritten internally.
+	Redirect bool
+
+	// DecodeEscapedSlashes treats %2F in the path as a path separator.
+	// By default encoded slashes are left untouched. It is ignored with AllowEncodedSlashes.
+	DecodeEscapedSlashes bool
+}
+
+type originalPathContextKey struct{}
+
+// NormalizePaths enables path normalization before requests are dispatched.
+// Repeated slashes are collapsed and "." and ".." segments are resolved, so that
+// //admin///users and /a/./b reach /admin/users and /a/b respectively.
+// Requests that traverse above the root are rejected with 400 Bad Request.
+// The query string is never modified.
+// The original escaped path is available with c.OriginalPath().
+func NormalizePaths(enabled bool, options ...PathNormalization) RouterOption {
+	return func(r *Router) {
+		r.normalizePaths = enabled
+		if len(options) > 0 {
+			r.pathNormalization = options[0]
+		}
+	}
+}
+
+// OriginalPath returns the escaped request path as sent by the client
+// before normalization with NormalizePaths.
+func (c *Context) OriginalPath() string {
+	if p, ok := c.Request.Context().Value(originalPathContextKey{}).(string); ok {
+		return p
+	}
+	return c.Request.URL.EscapedPath()
+}
+
+// normalizeRequest returns the request to dispatch after normalization.
+// It returns nil if the response has already been written.
+func (r *Router) normalizeRequest(w http.ResponseWriter, req *http.Request) *http.Request {
+	escaped := req.URL.EscapedPath()
+	canonical, ok := cleanPath(escaped, r.pathNormalization.DecodeEscapedSlashes && !r.allowEncodedSlashes)
+	if !ok {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return nil
+	}
+
+	if canonical == escaped {
+		return req
+	}
+
+	if r.pathNormalization.Redirect && (req.Method == http.MethodGet || req.Method == http.MethodHead) {
+		target := canonical
+		if req.URL.RawQuery != "" {
+			target += "?" + req.URL.RawQuery
+		}
+		http.Redirect(w, req, target, http.StatusMovedPermanently)
+		return nil
+	}
+
+	decoded, err := url.PathUnescape(canonical)
+	if err != nil {
+		http.Error(w, "invalid path", http.StatusBadRequest)
+		return nil
+	}
+
+	ctx := context.WithValue(req.Context(), originalPathContextKey{}, escaped)
+	req = req.WithContext(ctx)
+
+	u := *req.URL
+	u.Path = decoded
+	u.RawPath = canonical
+	req.URL = &u
+	return req
+}
+
+// cleanPath collapses repeated slashes and resolves dot segments in an escaped path.
+// Encoded dots (%2e) are treated as dots. ok is false if the path escapes the root.
+func cleanPath(escaped string, decodeSlashes bool) (string, bool) {
+	if decodeSlashes {
+		escaped = strings.ReplaceAll(escaped, "%2F", "/")
+		escaped = strings.ReplaceAll(escaped, "%2f", "/")
+	}
+
+	segments := strings.Split(escaped, "/")
+	out := make([]string, 0, len(segments))
+	trailing := false
+
+	for i, seg := range segments {
+		last := i == len(segments)-1
+		decoded, err := url.PathUnescape(seg)
+		if err != nil {
+			decoded = seg
+		}
+
+		switch decoded {
+		case "":
+			trailing = last && len(out) > 0
+		case ".":
+			trailing = last && len(out) > 0
+		case "..":
+			if len(out) == 0 {
+				return "", false
+			}
+			out = out[:len(out)-1]
+			trailing = last && len(out) > 0
+		default:
+			out = append(out, seg)
+			trailing = false
+		}
+	}
+
+	p := "/" + strings.Join(out, "/")
+	if trailing {
+		p += "/"
+	}
+	return p, true
+}
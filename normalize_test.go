@@ -0,0 +1,121 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestNormalizePaths(t *testing.T) {
+	r := rex.NewRouter(rex.NormalizePaths(true))
+
+	r.GET("/a/b", func(c *rex.Context) error {
+		return c.String("a/b " + c.OriginalPath())
+	})
+
+	r.GET("/files/{name}", func(c *rex.Context) error {
+		return c.String(c.Param("name"))
+	})
+
+	r.POST("/admin/users", func(c *rex.Context) error {
+		return c.String("created")
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{"clean path", http.MethodGet, "/a/b", http.StatusOK, "a/b /a/b"},
+		{"dot segment", http.MethodGet, "/a/./b", http.StatusOK, "a/b /a/./b"},
+		{"dot dot segment", http.MethodGet, "/a/x/../b", http.StatusOK, "a/b /a/x/../b"},
+		{"encoded dot dot", http.MethodGet, "/a/x/%2e%2e/b", http.StatusOK, "a/b /a/x/%2e%2e/b"},
+		{"traversal above root", http.MethodGet, "/../etc/passwd", http.StatusBadRequest, ""},
+		{"encoded traversal above root", http.MethodGet, "/%2e%2e/etc/passwd", http.StatusBadRequest, ""},
+		{"encoded slash preserved", http.MethodGet, "/files/a%2Fb", http.StatusOK, "a%2Fb"},
+		{"duplicate slashes with encoded slash", http.MethodGet, "//files//a%2Fb", http.StatusOK, "a%2Fb"},
+		{"post duplicate slashes", http.MethodPost, "//admin///users", http.StatusOK, "created"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://example.com"+tt.path, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if tt.body != "" && w.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestNormalizePathsDecodeEscapedSlashes(t *testing.T) {
+	r := rex.NewRouter(rex.NormalizePaths(true, rex.PathNormalization{DecodeEscapedSlashes: true}))
+	r.GET("/files/{name}", func(c *rex.Context) error {
+		return c.String(c.Param("name"))
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/files/a%2Fb", nil))
+	if res.Status() != http.StatusNotFound {
+		t.Errorf("expected 404 when encoded slashes are decoded, got %d", res.Status())
+	}
+}
+
+func TestNormalizePathsRedirect(t *testing.T) {
+	r := rex.NewRouter(rex.NormalizePaths(true, rex.PathNormalization{Redirect: true}))
+	r.GET("/a/b", func(c *rex.Context) error {
+		return c.String("ok")
+	})
+	r.POST("/a/b", func(c *rex.Context) error {
+		return c.String("posted")
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "//a/./b?x=1&y=%2F", nil))
+	if res.Status() != http.StatusMovedPermanently {
+		t.Fatalf("expected 301, got %d", res.Status())
+	}
+
+	if loc := res.Header("Location"); loc != "/a/b?x=1&y=%2F" {
+		t.Errorf("expected canonical location with query, got %q", loc)
+	}
+
+	// Non-GET requests are rewritten internally.
+	res = r.Test(httptest.NewRequest(http.MethodPost, "//a/./b", nil))
+	if res.BodyString() != "posted" {
+		t.Errorf("expected internal rewrite for POST, got %d %q", res.Status(), res.BodyString())
+	}
+}
+
+// Prefix-based auth around the router can't be bypassed with duplicate slashes.
+func TestNormalizePathsPrefixAuth(t *testing.T) {
+	r := rex.NewRouter(rex.NormalizePaths(true))
+
+	r.POST("/admin/users", func(c *rex.Context) error {
+		return c.String("created")
+	}, func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			if strings.HasPrefix(c.Path(), "/admin") && c.GetHeader("Authorization") == "" {
+				c.WriteHeader(http.StatusUnauthorized)
+				return nil
+			}
+			return next(c)
+		}
+	})
+
+	for _, path := range []string{"/admin/users", "//admin/users", "///admin//users", "/x/../admin/users"} {
+		res := r.Test(httptest.NewRequest(http.MethodPost, path, nil))
+		if res.Status() != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", path, res.Status())
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package rex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultOutboundHeaders are the request headers copied to outbound requests
+// unless changed with WithOutboundPropagation.
+var DefaultOutboundHeaders = []string{"X-Request-ID", "Traceparent", "Tracestate"}
+
+// OutboundTimeout is the timeout of outbound requests sent with c.HTTPClient and c.Fetch.
+// It is shorter than the default WriteTimeout of Server so that a slow downstream service
+// fails the request before the connection is closed.
+var OutboundTimeout = 8 * time.Second
+
+// fetchExcerpt is the maximum number of body bytes kept in a FetchError.
+const fetchExcerpt = 512
+
+// WithOutboundPropagation sets the headers of the incoming request copied to outbound
+// requests built with c.NewRequest and c.Fetch, replacing DefaultOutboundHeaders.
+// Only list Authorization if the downstream services are trusted with the credentials.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.WithOutboundPropagation("X-Request-ID", "Traceparent", "Authorization"))
+func WithOutboundPropagation(headers ...string) RouterOption {
+	return func(r *Router) {
+		r.outboundHeaders = headers
+	}
+}
+
+// WithOutboundClient sets the client returned by c.HTTPClient, e.g with a custom transport.
+// Its Timeout is used as is.
+func WithOutboundClient(client *http.Client) RouterOption {
+	return func(r *Router) {
+		r.outboundClient = client
+	}
+}
+
+// HTTPClient returns the client for outbound requests, by default an http.Client
+// with OutboundTimeout. Send requests built with c.NewRequest.
+func (c *Context) HTTPClient() *http.Client {
+	if c.router.outboundClient != nil {
+		return c.router.outboundClient
+	}
+	return &http.Client{Timeout: OutboundTimeout}
+}
+
+// NewRequest returns an outbound request bound to the request context, so it is canceled
+// when the client disconnects. The propagation headers of the incoming request are copied,
+// see WithOutboundPropagation. A request ID set on the response by middleware is used
+// if the request has none.
+//
+// Example:
+//
+//	req, err := c.NewRequest("GET", "http://inventory/items/"+id, nil)
+//	if err != nil {
+//		return err
+//	}
+//	res, err := c.HTTPClient().Do(req)
+func (c *Context) NewRequest(method, url string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(c.Request.Context(), method, url, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range c.router.outboundHeaders {
+		values := c.Request.Header.Values(name)
+		if len(values) == 0 && http.CanonicalHeaderKey(name) == "X-Request-Id" {
+			values = c.Response.Header().Values(name)
+		}
+
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	return req, nil
+}
+
+// FetchError is returned by c.Fetch when the downstream service responds with a non-2xx status.
+type FetchError struct {
+	Method string // Method of the outbound request.
+	URL    string // URL of the outbound request.
+	Status int    // Status of the downstream response.
+	Body   string // The start of the response body.
+}
+
+// Error implements the error interface.
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("rex: %s %s: downstream responded with %d: %q", e.Method, e.URL, e.Status, e.Body)
+}
+
+// Fetch sends in as JSON to the downstream service and decodes the JSON response into out.
+// in and out may be nil to send no body or ignore the response body.
+// The request is built with c.NewRequest and limited to OutboundTimeout.
+// Non-2xx responses return a *FetchError with the status and a body excerpt.
+//
+// Example:
+//
+//	var stock Stock
+//	if err := c.Fetch("GET", "http://inventory/items/"+id, nil, &stock); err != nil {
+//		return err
+//	}
+func (c *Context) Fetch(method, url string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return fmt.Errorf("rex: %s %s: encoding request: %w", method, url, err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := c.NewRequest(method, url, body)
+	if err != nil {
+		return fmt.Errorf("rex: %s %s: %w", method, url, err)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), OutboundTimeout)
+	defer cancel()
+	req = req.WithContext(ctx)
+
+	req.Header.Set("Accept", ContentTypeJSON)
+	if in != nil {
+		req.Header.Set("Content-Type", ContentTypeJSON)
+	}
+
+	res, err := c.HTTPClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("rex: %s %s: %w", method, url, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		excerpt, _ := io.ReadAll(io.LimitReader(res.Body, fetchExcerpt))
+		return &FetchError{
+			Method: method,
+			URL:    url,
+			Status: res.StatusCode,
+			Body:   strings.TrimSpace(string(excerpt)),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return fmt.Errorf("rex: %s %s: decoding response: %w", method, url, err)
+	}
+	return nil
+}
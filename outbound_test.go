@@ -0,0 +1,157 @@
+package rex_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestNewRequestPropagatesHeaders(t *testing.T) {
+	var got http.Header
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Clone()
+	}))
+	defer downstream.Close()
+
+	r := rex.NewRouter(rex.WithOutboundPropagation("X-Request-ID", "Traceparent", "Authorization"))
+	r.GET("/", func(c *rex.Context) error {
+		req, err := c.NewRequest(http.MethodGet, downstream.URL, nil)
+		if err != nil {
+			return err
+		}
+
+		res, err := c.HTTPClient().Do(req)
+		if err != nil {
+			return err
+		}
+		return res.Body.Close()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "req-7")
+	req.Header.Set("Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	req.Header.Set("Authorization", "Bearer token")
+	req.Header.Set("Cookie", "session=secret")
+
+	if res := r.Test(req); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %q", res.Code, res.BodyString())
+	}
+
+	for _, name := range []string{"X-Request-ID", "Traceparent", "Authorization"} {
+		if got.Get(name) != req.Header.Get(name) {
+			t.Errorf("expected %s to be propagated, got %q", name, got.Get(name))
+		}
+	}
+
+	if got.Get("Cookie") != "" {
+		t.Errorf("expected unlisted headers to be dropped, got Cookie %q", got.Get("Cookie"))
+	}
+}
+
+func TestNewRequestUsesResponseRequestID(t *testing.T) {
+	var got string
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		got = req.Header.Get("X-Request-ID")
+	}))
+	defer downstream.Close()
+
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		c.SetHeader("X-Request-ID", "generated-1")
+		return c.Fetch(http.MethodGet, downstream.URL, nil, nil)
+	})
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if got != "generated-1" {
+		t.Errorf("expected the generated request ID, got %q", got)
+	}
+}
+
+func TestFetchJSON(t *testing.T) {
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/fail" {
+			http.Error(w, "inventory offline", http.StatusBadGateway)
+			return
+		}
+
+		var in map[string]int
+		json.NewDecoder(req.Body).Decode(&in)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]int{"total": in["a"] + in["b"]})
+	}))
+	defer downstream.Close()
+
+	r := rex.NewRouter()
+	r.GET("/sum", func(c *rex.Context) error {
+		var out struct{ Total int }
+		if err := c.Fetch(http.MethodPost, downstream.URL+"/sum", rex.Map{"a": 2, "b": 3}, &out); err != nil {
+			return err
+		}
+		return c.JSON(out)
+	})
+
+	r.GET("/fail", func(c *rex.Context) error {
+		err := c.Fetch(http.MethodGet, downstream.URL+"/fail", nil, nil)
+
+		var fetchErr *rex.FetchError
+		if !errors.As(err, &fetchErr) || fetchErr.Status != http.StatusBadGateway || fetchErr.Body != "inventory offline" {
+			t.Errorf("expected a FetchError with the status and body, got %#v", err)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), "502") {
+			t.Errorf("expected the status in the error, got %v", err)
+		}
+		return nil
+	})
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/sum", nil)); !strings.Contains(res.BodyString(), `"Total":5`) {
+		t.Errorf("expected the decoded response, got %d %q", res.Code, res.BodyString())
+	}
+	r.Test(httptest.NewRequest(http.MethodGet, "/fail", nil))
+}
+
+func TestFetchCanceledWithClient(t *testing.T) {
+	started := make(chan struct{})
+	canceled := make(chan struct{})
+	downstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		close(started)
+		select {
+		case <-req.Context().Done():
+			close(canceled)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer downstream.Close()
+
+	var fetchErr error
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		fetchErr = c.Fetch(http.MethodGet, downstream.URL, nil, nil)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	select {
+	case <-canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the downstream request to be canceled")
+	}
+
+	if !errors.Is(fetchErr, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", fetchErr)
+	}
+}
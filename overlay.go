@@ -0,0 +1,199 @@
+package rex
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// OverlayFS returns a file system that serves files from secondary and falls back to primary,
+// e.g embedded assets that can be overridden file by file from a directory on disk.
+// Directories present in both are merged in listings, with the files of secondary taking precedence.
+// Nothing is cached, so files added to or removed from secondary are picked up immediately.
+// For Static and StaticFS minified files, the first layer that has the file or its .min
+// variant decides which one is served, so overriding app.css on disk also replaces a
+// minified app.min.css that is only embedded.
+//
+// Example:
+//
+//	assets := rex.OverlayFS(rex.CreateFileSystem(embedded, "static"), http.Dir("/etc/app/static"))
+//	r.StaticFS("/static", assets, 3600)
+func OverlayFS(primary, secondary http.FileSystem) http.FileSystem {
+	return &overlayFS{layers: []http.FileSystem{secondary, primary}}
+}
+
+// overlayFS looks files up in its layers in order.
+type overlayFS struct {
+	layers []http.FileSystem
+}
+
+// layeredFS is implemented by file systems made of layers, from the highest precedence.
+type layeredFS interface {
+	fsLayers() []http.FileSystem
+}
+
+func (o *overlayFS) fsLayers() []http.FileSystem {
+	return o.layers
+}
+
+// Open opens the file of the first layer that has name. Directories are merged
+// with the directories of the same name in the lower layers.
+func (o *overlayFS) Open(name string) (http.File, error) {
+	// Layers like http.FS reject names with a trailing slash.
+	name = path.Clean("/" + name)
+
+	var dirs []http.File
+	for _, layer := range o.layers {
+		f, err := layer.Open(name)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			closeFiles(dirs)
+			return nil, err
+		}
+
+		stat, err := f.Stat()
+		if err != nil {
+			f.Close()
+			closeFiles(dirs)
+			return nil, err
+		}
+
+		if !stat.IsDir() {
+			if len(dirs) > 0 {
+				// A directory in a higher layer shadows the file.
+				f.Close()
+				continue
+			}
+			return f, nil
+		}
+		dirs = append(dirs, f)
+	}
+
+	switch len(dirs) {
+	case 0:
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	case 1:
+		return dirs[0], nil
+	}
+	return &overlayDir{File: dirs[0], dirs: dirs}, nil
+}
+
+func closeFiles(files []http.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+// overlayDir is a directory present in several layers.
+type overlayDir struct {
+	http.File             // directory of the highest layer, used for Stat
+	dirs      []http.File // the directory in each layer that has it
+	entries   []fs.FileInfo
+	read      bool
+}
+
+// Readdir returns the entries of all layers. Entries of higher layers hide
+// entries of the same name in lower layers.
+func (d *overlayDir) Readdir(count int) ([]fs.FileInfo, error) {
+	if !d.read {
+		d.read = true
+
+		seen := make(map[string]bool)
+		for _, dir := range d.dirs {
+			infos, err := dir.Readdir(-1)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, info := range infos {
+				if !seen[info.Name()] {
+					seen[info.Name()] = true
+					d.entries = append(d.entries, info)
+				}
+			}
+		}
+
+		slices.SortFunc(d.entries, func(a, b fs.FileInfo) int {
+			return strings.Compare(a.Name(), b.Name())
+		})
+	}
+
+	if count <= 0 {
+		entries := d.entries
+		d.entries = nil
+		return entries, nil
+	}
+
+	if len(d.entries) == 0 {
+		return nil, io.EOF
+	}
+
+	n := min(count, len(d.entries))
+	entries := d.entries[:n]
+	d.entries = d.entries[n:]
+	return entries, nil
+}
+
+func (d *overlayDir) Close() error {
+	var errs []error
+	for _, dir := range d.dirs {
+		errs = append(errs, dir.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// layerOf returns the layer of fsys that serves name, or fsys if it is not layered
+// or no layer has the file.
+func layerOf(fsys http.FileSystem, name string) http.FileSystem {
+	layered, ok := fsys.(layeredFS)
+	if !ok {
+		return fsys
+	}
+
+	for _, layer := range layered.fsLayers() {
+		if f, err := layer.Open(name); err == nil {
+			f.Close()
+			return layerOf(layer, name)
+		}
+	}
+	return fsys
+}
+
+// openLayer opens the first of names in the first layer of fsys that has any of them.
+func openLayer(fsys http.FileSystem, names ...string) (http.File, error) {
+	layered, ok := fsys.(layeredFS)
+	if !ok {
+		return openFirst(fsys, names...)
+	}
+
+	for _, layer := range layered.fsLayers() {
+		f, err := openLayer(layer, names...)
+		if err == nil {
+			return f, nil
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: names[len(names)-1], Err: os.ErrNotExist}
+}
+
+// openFirst opens the first of names that exists in fsys.
+func openFirst(fsys http.FileSystem, names ...string) (http.File, error) {
+	var err error
+	for _, name := range names {
+		var f http.File
+		if f, err = fsys.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return nil, err
+}
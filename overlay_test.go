@@ -0,0 +1,197 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/abiiranathan/rex"
+)
+
+// newOverlay returns an overlay of embedded-like files and a disk directory.
+func newOverlay(t *testing.T) (http.FileSystem, string) {
+	t.Helper()
+
+	embedded := fstest.MapFS{
+		"app.css":       {Data: []byte("embedded css")},
+		"app.min.css":   {Data: []byte("embedded min css")},
+		"logo.svg":      {Data: []byte("embedded logo")},
+		"js/app.js":     {Data: []byte("embedded js")},
+		"js/vendor.js":  {Data: []byte("embedded vendor")},
+		"index.html":    {Data: []byte("embedded index")},
+		"index.html.gz": {Data: []byte("embedded gzip index")},
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "js"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return rex.OverlayFS(http.FS(embedded), http.Dir(dir)), dir
+}
+
+func writeFile(t *testing.T, dir, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, filepath.FromSlash(name)), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverlayFSPrecedence(t *testing.T) {
+	assets, dir := newOverlay(t)
+	writeFile(t, dir, "theme.css", "disk theme")
+	writeFile(t, dir, "logo.svg", "disk logo")
+
+	r := rex.NewRouter()
+	r.StaticFS("/static", assets)
+
+	get := func(path string) string {
+		t.Helper()
+		res := r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, res.Code)
+		}
+		return res.BodyString()
+	}
+
+	if body := get("/static/theme.css"); body != "disk theme" {
+		t.Errorf("expected the file only on disk, got %q", body)
+	}
+
+	if body := get("/static/logo.svg"); body != "disk logo" {
+		t.Errorf("expected the disk file to override the embedded one, got %q", body)
+	}
+
+	if body := get("/static/js/app.js"); body != "embedded js" {
+		t.Errorf("expected the embedded file, got %q", body)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "logo.svg")); err != nil {
+		t.Fatal(err)
+	}
+
+	if body := get("/static/logo.svg"); body != "embedded logo" {
+		t.Errorf("expected the embedded file after removing the override, got %q", body)
+	}
+}
+
+func TestOverlayFSStat(t *testing.T) {
+	assets, dir := newOverlay(t)
+	writeFile(t, dir, "js/app.js", "disk js")
+
+	f, err := assets.Open("/js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	stat, err := f.Stat()
+	if err != nil || !stat.IsDir() {
+		t.Fatalf("expected a directory, got %v, %v", stat, err)
+	}
+
+	if _, err := assets.Open("/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestOverlayFSMergedListing(t *testing.T) {
+	assets, dir := newOverlay(t)
+	writeFile(t, dir, "js/app.js", "disk js")
+	writeFile(t, dir, "js/extra.js", "disk extra")
+
+	f, err := assets.Open("/js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	infos, err := f.Readdir(-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, info := range infos {
+		names = append(names, info.Name())
+	}
+
+	if got := strings.Join(names, ","); got != "app.js,extra.js,vendor.js" {
+		t.Errorf("expected the merged entries once each, got %s", got)
+	}
+
+	r := rex.NewRouter(rex.DirListTemplate(""))
+	r.StaticFS("/static", assets)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/static/js/", nil))
+	for _, want := range []string{"app.js", "extra.js", "vendor.js"} {
+		if !strings.Contains(res.BodyString(), want) {
+			t.Errorf("expected the listing to contain %q, got:\n%s", want, res.BodyString())
+		}
+	}
+}
+
+func TestOverlayFSMinified(t *testing.T) {
+	oldMinified := rex.ServeMinified
+	rex.ServeMinified = true
+	defer func() { rex.ServeMinified = oldMinified }()
+
+	assets, dir := newOverlay(t)
+
+	r := rex.NewRouter()
+	r.StaticFS("/static", assets)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	if res.BodyString() != "embedded min css" {
+		t.Errorf("expected the embedded minified file, got %q", res.BodyString())
+	}
+
+	// Overriding the source on disk replaces the stale embedded minified file.
+	writeFile(t, dir, "app.css", "disk css")
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	if res.BodyString() != "disk css" {
+		t.Errorf("expected the disk file, got %q", res.BodyString())
+	}
+
+	writeFile(t, dir, "app.min.css", "disk min css")
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/static/app.css", nil))
+	if res.BodyString() != "disk min css" {
+		t.Errorf("expected the disk minified file, got %q", res.BodyString())
+	}
+}
+
+func TestSPAWatchOverlay(t *testing.T) {
+	assets, dir := newOverlay(t)
+
+	r := rex.NewRouter()
+	r.SPA("/", "index.html", assets, rex.WatchOverlay())
+
+	get := func(acceptEncoding string) *rex.TestResponse {
+		req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+		return r.Test(req)
+	}
+
+	if res := get("gzip"); res.BodyString() != "embedded gzip index" {
+		t.Errorf("expected the embedded precompressed index, got %q", res.BodyString())
+	}
+
+	writeFile(t, dir, "index.html", "disk index")
+
+	// The gzip sidecar of the embedded index must not be served for the disk index.
+	res := get("gzip")
+	if res.BodyString() != "disk index" || res.Header("Content-Encoding") != "" {
+		t.Errorf("expected the uncompressed disk index, got %q %v", res.BodyString(), res.Result().Header)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "index.html")); err != nil {
+		t.Fatal(err)
+	}
+
+	if res := get(""); res.BodyString() != "embedded index" {
+		t.Errorf("expected the embedded index after removing the override, got %q", res.BodyString())
+	}
+}
@@ -0,0 +1,219 @@
+package rex
+
+import (
+	"container/list"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RenderETags enables strong ETags on pages rendered with c.Render for GET and HEAD requests.
+// The ETag is a hash of the rendered page. If it matches If-None-Match,
+// the body is discarded and 304 Not Modified is sent instead.
+// Responses that set cookies or have Cache-Control: no-store are left untouched.
+func RenderETags(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.renderETags = enabled
+	}
+}
+
+// DefaultPageCacheEntries is the number of pages a StaticFor cache keeps by default.
+const DefaultPageCacheEntries = 1000
+
+type cachedPage struct {
+	key     string
+	body    []byte
+	etag    string
+	expires time.Time
+}
+
+// pageCache caches rendered pages by path and query for a fixed ttl.
+// The least recently used page is evicted once it holds maxEntries pages.
+type pageCache struct {
+	ttl         time.Duration
+	maxEntries  int
+	queryParams []string // query parameters of the key, sorted; nil keys on the raw query
+
+	mu    sync.Mutex
+	pages map[string]*list.Element // of *cachedPage
+	lru   list.List                // most recently used first
+}
+
+// PageCacheOption configures the page cache of StaticFor.
+type PageCacheOption func(*pageCache)
+
+// PageCacheMaxEntries sets the number of pages kept, DefaultPageCacheEntries by default.
+// Each distinct path and query is a page, so the limit bounds the memory of the cache
+// however many unique queries clients send.
+func PageCacheMaxEntries(n int) PageCacheOption {
+	return func(pc *pageCache) {
+		pc.maxEntries = n
+	}
+}
+
+// PageCacheQuery keys pages on the query parameters names only, in a fixed order,
+// so that other parameters, like tracking ones, are served the same page.
+// Without it the raw query string is part of the key, with no names the query is ignored.
+func PageCacheQuery(names ...string) PageCacheOption {
+	return func(pc *pageCache) {
+		pc.queryParams = append([]string{}, names...)
+		slices.Sort(pc.queryParams)
+	}
+}
+
+func (pc *pageCache) get(key string) (cachedPage, bool) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	elem, ok := pc.pages[key]
+	if !ok {
+		return cachedPage{}, false
+	}
+
+	page := elem.Value.(*cachedPage)
+	if time.Now().After(page.expires) {
+		pc.lru.Remove(elem)
+		delete(pc.pages, key)
+		return cachedPage{}, false
+	}
+
+	pc.lru.MoveToFront(elem)
+	return *page, true
+}
+
+func (pc *pageCache) set(key string, body []byte, etag string) {
+	page := &cachedPage{key: key, body: body, etag: etag, expires: time.Now().Add(pc.ttl)}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	if elem, ok := pc.pages[key]; ok {
+		elem.Value = page
+		pc.lru.MoveToFront(elem)
+		return
+	}
+
+	pc.pages[key] = pc.lru.PushFront(page)
+	for pc.lru.Len() > pc.maxEntries {
+		oldest := pc.lru.Back()
+		pc.lru.Remove(oldest)
+		delete(pc.pages, oldest.Value.(*cachedPage).key)
+	}
+}
+
+// serve writes cached pages for GET and HEAD requests without calling the handler.
+func (pc *pageCache) serve(next HandlerFunc) HandlerFunc {
+	return func(c *Context) error {
+		if !c.renderCacheable() {
+			return next(c)
+		}
+
+		page, ok := pc.get(pc.key(c.Request))
+		if !ok {
+			return next(c)
+		}
+
+		c.SetHeader("Content-Type", "text/html")
+		return c.writePage(page.body, page.etag)
+	}
+}
+
+// StaticFor caches pages rendered by the route with c.Render for ttl, keyed by path and query.
+// Cached hits skip the handler and template execution entirely, but still run the middleware.
+// Responses that set cookies or have Cache-Control: no-store are not cached.
+// At most DefaultPageCacheEntries pages are kept, see PageCacheMaxEntries and PageCacheQuery.
+//
+// Example:
+//
+//	r.GET("/about", aboutPage).StaticFor(10 * time.Minute)
+//	r.GET("/posts", listPosts).StaticFor(time.Minute, rex.PageCacheQuery("page", "tag"))
+func (rt *Route) StaticFor(ttl time.Duration, options ...PageCacheOption) *Route {
+	rt.pageCache = &pageCache{ttl: ttl, maxEntries: DefaultPageCacheEntries, pages: make(map[string]*list.Element)}
+	for _, option := range options {
+		option(rt.pageCache)
+	}
+	rt.pageCache.maxEntries = max(rt.pageCache.maxEntries, 1)
+	rt.use(rt.pageCache.serve)
+	return rt
+}
+
+// key returns the cache key of req, its path and the raw query or the allowed parameters.
+func (pc *pageCache) key(req *http.Request) string {
+	if pc.queryParams == nil {
+		return req.URL.Path + "?" + req.URL.RawQuery
+	}
+
+	query := req.URL.Query()
+	allowed := make(url.Values, len(pc.queryParams))
+	for _, name := range pc.queryParams {
+		if values, ok := query[name]; ok {
+			allowed[name] = values
+		}
+	}
+	return req.URL.Path + "?" + allowed.Encode()
+}
+
+// renderCacheable reports whether the rendered response may be cached or answered with 304.
+func (c *Context) renderCacheable() bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+
+	if w, ok := c.Response.(*ResponseWriter); ok && w.status != http.StatusOK {
+		return false
+	}
+
+	header := c.Response.Header()
+	if header.Get("Set-Cookie") != "" || strings.Contains(header.Get("Cache-Control"), "no-store") {
+		return false
+	}
+	return true
+}
+
+// writeRendered writes a page rendered by c.Render, storing it in the route page cache
+// and answering conditional requests when enabled.
+func (c *Context) writeRendered(page string) error {
+	rt := c.currentRoute
+	caching := rt != nil && rt.pageCache != nil
+
+	if (!c.router.renderETags && !caching) || !c.renderCacheable() {
+		_, err := io.WriteString(c.Response, page)
+		return err
+	}
+
+	body := []byte(page)
+	etag := contentETag(body)
+
+	if caching {
+		rt.pageCache.set(rt.pageCache.key(c.Request), body, etag)
+	}
+	return c.writePage(body, etag)
+}
+
+// writePage writes body with its ETag if RenderETags is enabled.
+func (c *Context) writePage(body []byte, etag string) error {
+	if c.router.renderETags {
+		c.SetHeader("ETag", etag)
+		if etagMatches(c.Request.Header.Get("If-None-Match"), etag) {
+			return c.WriteHeader(http.StatusNotModified)
+		}
+	}
+
+	_, err := c.Write(body)
+	return err
+}
+
+// etagMatches reports whether the If-None-Match header value matches etag.
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
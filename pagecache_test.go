@@ -0,0 +1,191 @@
+package rex_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func newPageRouter(t *testing.T, renders *atomic.Int32) *rex.Router {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"base.html":  `<main>{{ .Content }}</main>`,
+		"about.html": `<p>About {{ .Name }}{{ count }}</p>`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	funcs := template.FuncMap{
+		"count": func() string {
+			renders.Add(1)
+			return ""
+		},
+	}
+
+	templ := rex.Must(rex.ParseTemplates(dir, funcs))
+	return rex.NewRouter(
+		rex.WithTemplates(templ),
+		rex.BaseLayout("base.html"),
+		rex.ContentBlock("Content"),
+		rex.RenderETags(true),
+	)
+}
+
+func TestRenderETags(t *testing.T) {
+	var renders atomic.Int32
+	r := newPageRouter(t, &renders)
+
+	r.GET("/about", func(c *rex.Context) error {
+		return c.Render("about", rex.Map{"Name": "rex"})
+	})
+
+	r.GET("/private", func(c *rex.Context) error {
+		c.SetHeader("Cache-Control", "no-store")
+		return c.Render("about", rex.Map{"Name": "rex"})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/about", nil))
+	etag := res.Header("ETag")
+	if etag == "" {
+		t.Fatal("expected an ETag header")
+	}
+
+	if res.BodyString() != "<main><p>About rex</p></main>" {
+		t.Fatalf("unexpected body %q", res.BodyString())
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/about", nil)
+	req.Header.Set("If-None-Match", etag)
+	res = r.Test(req)
+
+	if res.Status() != http.StatusNotModified {
+		t.Fatalf("expected status 304, got %d", res.Status())
+	}
+
+	if res.BodyString() != "" {
+		t.Errorf("expected empty body, got %q", res.BodyString())
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/private", nil))
+	if res.Header("ETag") != "" {
+		t.Error("expected no ETag for no-store responses")
+	}
+}
+
+func TestRouteStaticFor(t *testing.T) {
+	var renders atomic.Int32
+	r := newPageRouter(t, &renders)
+
+	r.GET("/about", func(c *rex.Context) error {
+		return c.Render("about", rex.Map{"Name": c.Query("name", "rex")})
+	}).StaticFor(time.Minute)
+
+	r.GET("/session", func(c *rex.Context) error {
+		http.SetCookie(c.Response, &http.Cookie{Name: "session", Value: "1"})
+		return c.Render("about", rex.Map{"Name": "rex"})
+	}).StaticFor(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		res := r.Test(httptest.NewRequest(http.MethodGet, "/about", nil))
+		if res.BodyString() != "<main><p>About rex</p></main>" {
+			t.Fatalf("unexpected body %q", res.BodyString())
+		}
+
+		if res.Header("ETag") == "" {
+			t.Error("expected an ETag on cached responses")
+		}
+	}
+
+	if n := renders.Load(); n != 1 {
+		t.Errorf("expected the template to execute once, executed %d times", n)
+	}
+
+	// The query is part of the cache key.
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/about?name=go", nil))
+	if res.BodyString() != "<main><p>About go</p></main>" {
+		t.Errorf("unexpected body %q", res.BodyString())
+	}
+
+	if n := renders.Load(); n != 2 {
+		t.Errorf("expected a new render for a different query, got %d renders", n)
+	}
+
+	// Responses setting cookies are never cached.
+	renders.Store(0)
+	r.Test(httptest.NewRequest(http.MethodGet, "/session", nil))
+	r.Test(httptest.NewRequest(http.MethodGet, "/session", nil))
+
+	if n := renders.Load(); n != 2 {
+		t.Errorf("expected responses with cookies to bypass the cache, got %d renders", n)
+	}
+}
+
+func TestStaticForEvictsLeastRecentlyUsed(t *testing.T) {
+	var renders atomic.Int32
+	r := newPageRouter(t, &renders)
+
+	r.GET("/about", func(c *rex.Context) error {
+		return c.Render("about", rex.Map{"Name": c.Query("x")})
+	}).StaticFor(time.Minute, rex.PageCacheMaxEntries(2))
+
+	get := func(query string) {
+		r.Test(httptest.NewRequest(http.MethodGet, "/about?"+query, nil))
+	}
+
+	get("x=1")
+	get("x=2")
+	get("x=1") // hit, x=2 is now the least recently used
+	get("x=3") // evicts x=2
+	if n := renders.Load(); n != 3 {
+		t.Fatalf("expected 3 renders, got %d", n)
+	}
+
+	get("x=1")
+	get("x=3")
+	if n := renders.Load(); n != 3 {
+		t.Errorf("expected the recently used pages to stay cached, got %d renders", n)
+	}
+
+	get("x=2")
+	if n := renders.Load(); n != 4 {
+		t.Errorf("expected the evicted page to render again, got %d renders", n)
+	}
+}
+
+func TestStaticForQueryAllowlist(t *testing.T) {
+	var renders atomic.Int32
+	r := newPageRouter(t, &renders)
+
+	r.GET("/posts", func(c *rex.Context) error {
+		return c.Render("about", rex.Map{"Name": c.Query("tag") + c.Query("page")})
+	}).StaticFor(time.Minute, rex.PageCacheQuery("tag", "page"))
+
+	for _, query := range []string{"page=2&tag=go", "tag=go&page=2", "tag=go&page=2&utm_source=mail", "tag=go&page=2&x=1"} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, "/posts?"+query, nil))
+		if res.BodyString() != "<main><p>About go2</p></main>" {
+			t.Fatalf("%s: unexpected body %q", query, res.BodyString())
+		}
+	}
+
+	if n := renders.Load(); n != 1 {
+		t.Errorf("expected other parameters and their order to share the page, got %d renders", n)
+	}
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/posts?tag=go&page=3", nil))
+	if n := renders.Load(); n != 2 {
+		t.Errorf("expected an allowed parameter to change the key, got %d renders", n)
+	}
+}
@@ -0,0 +1,194 @@
+package rex
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrMissingValue is wrapped by ParamError when a required value is not present.
+var ErrMissingValue = errors.New("missing value")
+
+// ParamError is returned by the typed query and form accessors
+// (e.g QueryDurationErr) when a value is missing or can not be parsed.
+// The default error handler responds with 400 Bad Request.
+type ParamError struct {
+	Source string `json:"source"` // "query" or "form"
+	Key    string `json:"key"`    // The query or form key
+	Value  string `json:"value"`  // The raw value
+	Err    error  `json:"-"`      // The underlying parse error
+}
+
+// Error implements the error interface.
+func (e ParamError) Error() string {
+	if errors.Is(e.Err, ErrMissingValue) {
+		return fmt.Sprintf("%s parameter %q is required", e.Source, e.Key)
+	}
+	return fmt.Sprintf("invalid %s parameter %q=%q: %v", e.Source, e.Key, e.Value, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e ParamError) Unwrap() error {
+	return e.Err
+}
+
+func parseParam[T any](source, key, value string, parse func(string) (T, error)) (T, error) {
+	var zero T
+	if value == "" {
+		return zero, ParamError{Source: source, Key: key, Err: ErrMissingValue}
+	}
+
+	v, err := parse(value)
+	if err != nil {
+		return zero, ParamError{Source: source, Key: key, Value: value, Err: err}
+	}
+	return v, nil
+}
+
+func parseTimeLayout(loc *time.Location, layout ...string) func(string) (time.Time, error) {
+	return func(v string) (time.Time, error) {
+		if len(layout) > 0 && layout[0] != "" {
+			return time.ParseInLocation(layout[0], v, loc)
+		}
+		return ParseTime(v, loc)
+	}
+}
+
+// orDefault returns v if err is nil, otherwise the first default or the zero value.
+func orDefault[T any](v T, err error, defaults []T) T {
+	if err != nil && len(defaults) > 0 {
+		return defaults[0]
+	}
+	return v
+}
+
+// QueryDurationErr parses the query value as a time.Duration e.g "30s" or "1h30m".
+func (c *Context) QueryDurationErr(key string) (time.Duration, error) {
+	return parseParam("query", key, c.Query(key), time.ParseDuration)
+}
+
+// QueryDuration returns the query value as a time.Duration.
+// If the value is missing or invalid, it returns the default value.
+func (c *Context) QueryDuration(key string, defaults ...time.Duration) time.Duration {
+	v, err := c.QueryDurationErr(key)
+	return orDefault(v, err, defaults)
+}
+
+// QueryTimeErr parses the query value as a time in the request timezone.
+// If layout is not provided, the formats supported by ParseTime are tried.
+func (c *Context) QueryTimeErr(key string, layout ...string) (time.Time, error) {
+	return parseParam("query", key, c.Query(key), parseTimeLayout(c.Timezone(), layout...))
+}
+
+// QueryTime returns the query value as a time in the request timezone.
+// If the value is missing or invalid, it returns the zero time.
+func (c *Context) QueryTime(key string, layout ...string) time.Time {
+	v, _ := c.QueryTimeErr(key, layout...)
+	return v
+}
+
+// QueryBytesErr parses the query value as a human readable size e.g "10K", "5MB" or "1.5GiB".
+// See ParseBytes for the supported suffixes.
+func (c *Context) QueryBytesErr(key string) (int64, error) {
+	return parseParam("query", key, c.Query(key), ParseBytes)
+}
+
+// QueryBytes returns the query value as a size in bytes.
+// If the value is missing or invalid, it returns the default value.
+func (c *Context) QueryBytes(key string, defaults ...int64) int64 {
+	v, err := c.QueryBytesErr(key)
+	return orDefault(v, err, defaults)
+}
+
+// FormValueDurationErr parses the form value as a time.Duration.
+func (c *Context) FormValueDurationErr(key string) (time.Duration, error) {
+	return parseParam("form", key, c.FormValue(key), time.ParseDuration)
+}
+
+// FormValueDuration returns the form value as a time.Duration.
+// If the value is missing or invalid, it returns the default value.
+func (c *Context) FormValueDuration(key string, defaults ...time.Duration) time.Duration {
+	v, err := c.FormValueDurationErr(key)
+	return orDefault(v, err, defaults)
+}
+
+// FormValueTimeErr parses the form value as a time in the request timezone.
+// If layout is not provided, the formats supported by ParseTime are tried.
+func (c *Context) FormValueTimeErr(key string, layout ...string) (time.Time, error) {
+	return parseParam("form", key, c.FormValue(key), parseTimeLayout(c.Timezone(), layout...))
+}
+
+// FormValueTime returns the form value as a time in the request timezone.
+// If the value is missing or invalid, it returns the zero time.
+func (c *Context) FormValueTime(key string, layout ...string) time.Time {
+	v, _ := c.FormValueTimeErr(key, layout...)
+	return v
+}
+
+// FormValueBytesErr parses the form value as a human readable size.
+func (c *Context) FormValueBytesErr(key string) (int64, error) {
+	return parseParam("form", key, c.FormValue(key), ParseBytes)
+}
+
+// FormValueBytes returns the form value as a size in bytes.
+// If the value is missing or invalid, it returns the default value.
+func (c *Context) FormValueBytes(key string, defaults ...int64) int64 {
+	v, err := c.FormValueBytesErr(key)
+	return orDefault(v, err, defaults)
+}
+
+var byteUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"ki":  1 << 10,
+	"kib": 1 << 10,
+	"mi":  1 << 20,
+	"mib": 1 << 20,
+	"gi":  1 << 30,
+	"gib": 1 << 30,
+	"ti":  1 << 40,
+	"tib": 1 << 40,
+}
+
+// ParseBytes parses a human readable size into bytes.
+// SI suffixes (K, KB, MB, GB, TB) are powers of 1000 and binary suffixes
+// (KiB, MiB, GiB, TiB) are powers of 1024. Suffixes are case-insensitive
+// and fractional values are allowed, e.g "1.5GiB" or "10k".
+func ParseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	number, suffix := s, ""
+	if i >= 0 {
+		number, suffix = s[:i], strings.TrimSpace(s[i:])
+	}
+
+	unit, ok := byteUnits[strings.ToLower(suffix)]
+	if !ok {
+		return 0, fmt.Errorf("unknown size suffix %q", suffix)
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || number == "" {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+
+	size := n * unit
+	if size >= math.MaxInt64 {
+		return 0, fmt.Errorf("size %q overflows int64", s)
+	}
+	return int64(size), nil
+}
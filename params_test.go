@@ -0,0 +1,145 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestParseBytes(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    int64
+		wantErr bool
+	}{
+		{"0", 0, false},
+		{"512", 512, false},
+		{"512B", 512, false},
+		{"10K", 10_000, false},
+		{"10k", 10_000, false},
+		{"10KB", 10_000, false},
+		{"10kb", 10_000, false},
+		{"5MB", 5_000_000, false},
+		{"5mb", 5_000_000, false},
+		{"2G", 2_000_000_000, false},
+		{"1KiB", 1024, false},
+		{"1kib", 1024, false},
+		{"1Ki", 1024, false},
+		{"5MiB", 5 << 20, false},
+		{"1.5GiB", 1610612736, false},
+		{"1.5gib", 1610612736, false},
+		{"0.5KB", 500, false},
+		{"2.5 MB", 2_500_000, false},
+		{" 1TiB ", 1 << 40, false},
+		{"", 0, true},
+		{"MB", 0, true},
+		{"-5MB", 0, true},
+		{"10XB", 0, true},
+		{"1.2.3K", 0, true},
+		{"99999999999TB", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := rex.ParseBytes(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBytes(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("ParseBytes(%q) = %d, want %d", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypedQueryAccessors(t *testing.T) {
+	r := rex.NewRouter()
+
+	r.GET("/", func(c *rex.Context) error {
+		if d := c.QueryDuration("timeout"); d != 30*time.Second {
+			t.Errorf("expected 30s, got %v", d)
+		}
+
+		if d := c.QueryDuration("missing", time.Minute); d != time.Minute {
+			t.Errorf("expected default 1m, got %v", d)
+		}
+
+		since := c.QueryTime("since")
+		if !since.Equal(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("unexpected time %v", since)
+		}
+
+		day := c.QueryTime("day", "02/01/2006")
+		if day.Day() != 15 || day.Month() != time.March {
+			t.Errorf("unexpected day %v", day)
+		}
+
+		if n := c.QueryBytes("max"); n != 10_000_000 {
+			t.Errorf("expected 10MB, got %d", n)
+		}
+
+		if n := c.QueryBytes("bad", 42); n != 42 {
+			t.Errorf("expected default 42, got %d", n)
+		}
+		return c.String("ok")
+	})
+
+	r.GET("/strict", func(c *rex.Context) error {
+		_, err := c.QueryDurationErr("timeout")
+		return err
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/?timeout=30s&since=2024-01-01T00:00:00Z&day=15/03/2024&max=10MB&bad=lots", nil)
+	if res := r.Test(req); res.Status() != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.Status())
+	}
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/strict?timeout=soon", nil))
+	if res.Status() != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", res.Status())
+	}
+
+	if !strings.Contains(res.BodyString(), "timeout") {
+		t.Errorf("expected error to name the key, got %q", res.BodyString())
+	}
+}
+
+func TestTypedFormAccessors(t *testing.T) {
+	r := rex.NewRouter()
+
+	r.POST("/", func(c *rex.Context) error {
+		if d := c.FormValueDuration("ttl"); d != 90*time.Minute {
+			t.Errorf("expected 1h30m, got %v", d)
+		}
+
+		if n := c.FormValueBytes("quota"); n != 1<<30 {
+			t.Errorf("expected 1GiB, got %d", n)
+		}
+
+		_, err := c.FormValueTimeErr("missing")
+		var pe rex.ParamError
+		if !errors.As(err, &pe) || !errors.Is(err, rex.ErrMissingValue) {
+			t.Errorf("expected missing ParamError, got %v", err)
+		}
+
+		if pe.Source != "form" || pe.Key != "missing" {
+			t.Errorf("unexpected ParamError %+v", pe)
+		}
+		return c.String("ok")
+	})
+
+	req := rex.NewTestRequest(http.MethodPost, "/").
+		Form(url.Values{"ttl": {"1h30m"}, "quota": {"1GiB"}}).
+		Build()
+
+	if res := r.Test(req); res.Status() != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.Status())
+	}
+}
@@ -0,0 +1,552 @@
+package rex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSONPatchError is returned by ApplyJSONPatch for an operation that can not be applied.
+// The default error handler responds with 422 Unprocessable Entity.
+type JSONPatchError struct {
+	Index int    `json:"index"` // index of the operation in the patch
+	Op    string `json:"op"`    // e.g "replace"
+	Path  string `json:"path"`  // path of the operation
+	Err   error  `json:"-"`
+}
+
+func (e JSONPatchError) Error() string {
+	return fmt.Sprintf("json patch operation %d (%s %q): %v", e.Index, e.Op, e.Path, e.Err)
+}
+
+func (e JSONPatchError) Unwrap() error {
+	return e.Err
+}
+
+// readPatch reads a patch body of one of the content types.
+func (c *Context) readPatch(contentTypes ...string) ([]byte, error) {
+	contentType := c.ContentType()
+	if !slices.Contains(contentTypes, contentType) {
+		return nil, FormError{
+			Err:  fmt.Errorf("unsupported content type: %s, expected %s", contentType, strings.Join(contentTypes, " or ")),
+			Kind: InvalidContentType,
+		}
+	}
+
+	limits := DefaultDecompressLimits
+	if c.router != nil {
+		limits = c.router.decompressLimits
+	}
+
+	if err := DecompressBody(c.Request, limits); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return nil, jsonFormError(err, body)
+	}
+	return body, nil
+}
+
+// decodeJSON decodes data keeping numbers as json.Number, so that they are not rounded.
+func decodeJSON(data []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var v any
+	if err := decoder.Decode(&v); err != nil {
+		return nil, jsonFormError(err, data)
+	}
+
+	if decoder.More() {
+		return nil, FormError{Err: fmt.Errorf("unexpected data after the JSON value"), Kind: ParseError}
+	}
+	return v, nil
+}
+
+// ApplyMergePatch applies the application/merge-patch+json (RFC 7396) body of the request to
+// target, a pointer to the current state of the resource: objects are merged recursively,
+// null removes a member, resetting the field to its zero value, and other values replace it.
+// Bodies sent as application/json are accepted too. The result is validated like BodyParser.
+//
+// Afterwards c.ChangedFields(target) returns the fields whose keys are in the patch,
+// including those set to null.
+//
+// Example:
+//
+//	user := loadUser(c.Param("id"))
+//	if err := c.ApplyMergePatch(&user); err != nil {
+//		return err
+//	}
+//	db.Select(c.ChangedFields(&user)).Save(&user)
+func (c *Context) ApplyMergePatch(target any) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return FormError{Err: fmt.Errorf("target must be a non-nil pointer"), Kind: InvalidStructPointer}
+	}
+
+	body, err := c.readPatch(ContentTypeMergePatch, ContentTypeJSON)
+	if err != nil {
+		return err
+	}
+
+	patch, err := decodeJSON(body)
+	if err != nil {
+		return err
+	}
+
+	current, err := json.Marshal(target)
+	if err != nil {
+		return err
+	}
+
+	original, err := decodeJSON(current)
+	if err != nil {
+		return err
+	}
+
+	merged, err := json.Marshal(mergePatch(original, patch))
+	if err != nil {
+		return err
+	}
+
+	// Members removed by the patch must not keep their current value.
+	rv.Elem().Set(reflect.Zero(rv.Elem().Type()))
+	if err := json.Unmarshal(merged, target); err != nil {
+		return jsonFormError(err, merged)
+	}
+
+	c.patchedKeys = []string{}
+	if members, ok := patch.(map[string]any); ok {
+		for key := range members {
+			c.patchedKeys = append(c.patchedKeys, key)
+		}
+	}
+	return c.validateBody(target)
+}
+
+// mergePatch returns target with patch applied, see RFC 7396 section 2.
+func mergePatch(target, patch any) any {
+	members, ok := patch.(map[string]any)
+	if !ok {
+		return patch
+	}
+
+	object, ok := target.(map[string]any)
+	if !ok {
+		object = make(map[string]any)
+	}
+
+	for key, value := range members {
+		if value == nil {
+			delete(object, key)
+		} else {
+			object[key] = mergePatch(object[key], value)
+		}
+	}
+	return object
+}
+
+// patchedField reports whether the merge patch applied with ApplyMergePatch has a key
+// for field. Keys match JSON names case-insensitively like encoding/json.
+func (c *Context) patchedField(field reflect.StructField) bool {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return false
+	}
+
+	if name == "" {
+		name = field.Name
+	}
+
+	for _, key := range c.patchedKeys {
+		if strings.EqualFold(key, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonPatchOperation is an operation of a JSON Patch document.
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  *string         `json:"path"`
+	From  *string         `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// ApplyJSONPatch applies the application/json-patch+json (RFC 6902) body of the request
+// to the JSON document doc and returns the patched document. The operations add, remove,
+// replace, move, copy and test are applied in order and the patch is atomic: if an
+// operation fails, a JSONPatchError with its index is returned and doc is unchanged.
+// Bodies sent as application/json are accepted too.
+//
+// Example:
+//
+//	patched, err := c.ApplyJSONPatch(current)
+//	if err != nil {
+//		return err // 422 for failed operations
+//	}
+//	json.Unmarshal(patched, &settings)
+func (c *Context) ApplyJSONPatch(doc []byte) ([]byte, error) {
+	body, err := c.readPatch(ContentTypeJSONPatch, ContentTypeJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	var operations []jsonPatchOperation
+	if err := json.Unmarshal(body, &operations); err != nil {
+		return nil, jsonFormError(fmt.Errorf("a JSON patch must be an array of operations: %w", err), body)
+	}
+
+	document, err := decodeJSON(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, operation := range operations {
+		path := ""
+		if operation.Path != nil {
+			path = *operation.Path
+		}
+
+		document, err = applyOperation(document, operation)
+		if err != nil {
+			return nil, JSONPatchError{Index: i, Op: operation.Op, Path: path, Err: err}
+		}
+	}
+	return json.Marshal(document)
+}
+
+// applyOperation returns document with a JSON patch operation applied.
+func applyOperation(document any, operation jsonPatchOperation) (any, error) {
+	if operation.Path == nil {
+		return nil, fmt.Errorf("missing path")
+	}
+
+	path, err := parsePointer(*operation.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+	switch operation.Op {
+	case "add", "replace", "test":
+		if len(operation.Value) == 0 {
+			return nil, fmt.Errorf("missing value")
+		}
+
+		if value, err = decodeJSON(operation.Value); err != nil {
+			return nil, fmt.Errorf("invalid value: %w", err)
+		}
+	case "move", "copy":
+		if operation.From == nil {
+			return nil, fmt.Errorf("missing from")
+		}
+
+		from, err := parsePointer(*operation.From)
+		if err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+
+		if value, err = pointerGet(document, from); err != nil {
+			return nil, fmt.Errorf("from: %w", err)
+		}
+
+		if operation.Op == "copy" {
+			value = deepCopyJSON(value)
+			break
+		}
+
+		if len(path) > len(from) && isPrefix(from, path) {
+			return nil, fmt.Errorf("cannot move %q into itself", *operation.From)
+		}
+
+		if document, err = pointerRemove(document, from); err != nil {
+			return nil, err
+		}
+	case "remove":
+	default:
+		return nil, fmt.Errorf("unknown operation %q", operation.Op)
+	}
+
+	switch operation.Op {
+	case "add", "move", "copy":
+		return pointerAdd(document, path, value)
+	case "remove":
+		return pointerRemove(document, path)
+	case "replace":
+		if _, err := pointerGet(document, path); err != nil {
+			return nil, err
+		}
+		return pointerReplace(document, path, value)
+	}
+
+	// test
+	current, err := pointerGet(document, path)
+	if err != nil {
+		return nil, err
+	}
+
+	if !equalJSON(current, value) {
+		return nil, fmt.Errorf("test failed: the value is %s, expected %s", mustMarshal(current), operation.Value)
+	}
+	return document, nil
+}
+
+// parsePointer splits a JSON pointer (RFC 6901) into unescaped reference tokens.
+// The empty pointer refers to the whole document and returns no tokens.
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid path %q: must be empty or start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid path %q: ~ must be followed by 0 or 1", pointer)
+			}
+		}
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+func isPrefix(prefix, tokens []string) bool {
+	for i := range prefix {
+		if prefix[i] != tokens[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// arrayIndex parses the index token of an array of length n. Indexes have no leading zeros.
+// With appendable, "-" and n refer to the position after the last element.
+func arrayIndex(token string, n int, appendable bool) (int, error) {
+	limit := n
+	if appendable {
+		limit = n + 1
+		if token == "-" {
+			return n, nil
+		}
+	}
+
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') || token[0] == '+' {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+
+	if index >= limit {
+		return 0, fmt.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+// pointerGet returns the value at tokens in document.
+func pointerGet(document any, tokens []string) (any, error) {
+	node := document
+	for _, token := range tokens {
+		switch container := node.(type) {
+		case map[string]any:
+			value, ok := container[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			node = value
+		case []any:
+			index, err := arrayIndex(token, len(container), false)
+			if err != nil {
+				return nil, err
+			}
+			node = container[index]
+		default:
+			return nil, fmt.Errorf("cannot reference %q in a %s", token, jsonKind(node))
+		}
+	}
+	return node, nil
+}
+
+// pointerUpdate returns document with the container of the last token replaced by fn.
+func pointerUpdate(document any, tokens []string, fn func(container any, token string) (any, error)) (any, error) {
+	if len(tokens) == 1 {
+		return fn(document, tokens[0])
+	}
+
+	child, err := pointerGet(document, tokens[:1])
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := pointerUpdate(child, tokens[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch container := document.(type) {
+	case map[string]any:
+		container[tokens[0]] = updated
+	case []any:
+		index, _ := arrayIndex(tokens[0], len(container), false)
+		container[index] = updated
+	}
+	return document, nil
+}
+
+func pointerAdd(document any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return pointerUpdate(document, tokens, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = value
+			return c, nil
+		case []any:
+			index, err := arrayIndex(token, len(c), true)
+			if err != nil {
+				return nil, err
+			}
+			c = append(c, nil)
+			copy(c[index+1:], c[index:])
+			c[index] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("cannot add %q to a %s", token, jsonKind(container))
+	})
+}
+
+func pointerRemove(document any, tokens []string) (any, error) {
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("cannot remove the whole document")
+	}
+
+	return pointerUpdate(document, tokens, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			if _, ok := c[token]; !ok {
+				return nil, fmt.Errorf("member %q does not exist", token)
+			}
+			delete(c, token)
+			return c, nil
+		case []any:
+			index, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(c[:index], c[index+1:]...), nil
+		}
+		return nil, fmt.Errorf("cannot remove %q from a %s", token, jsonKind(container))
+	})
+}
+
+func pointerReplace(document any, tokens []string, value any) (any, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+
+	return pointerUpdate(document, tokens, func(container any, token string) (any, error) {
+		switch c := container.(type) {
+		case map[string]any:
+			c[token] = value
+			return c, nil
+		case []any:
+			index, err := arrayIndex(token, len(c), false)
+			if err != nil {
+				return nil, err
+			}
+			c[index] = value
+			return c, nil
+		}
+		return nil, fmt.Errorf("cannot replace %q in a %s", token, jsonKind(container))
+	})
+}
+
+func deepCopyJSON(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		object := make(map[string]any, len(v))
+		for key, member := range v {
+			object[key] = deepCopyJSON(member)
+		}
+		return object
+	case []any:
+		array := make([]any, len(v))
+		for i, element := range v {
+			array[i] = deepCopyJSON(element)
+		}
+		return array
+	}
+	return value
+}
+
+// equalJSON compares decoded JSON values, numbers by value e.g 1 and 1.0 are equal.
+func equalJSON(a, b any) bool {
+	switch x := a.(type) {
+	case json.Number:
+		y, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		fx, errX := x.Float64()
+		fy, errY := y.Float64()
+		return errX == nil && errY == nil && fx == fy
+	case map[string]any:
+		y, ok := b.(map[string]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for key, value := range x {
+			other, ok := y[key]
+			if !ok || !equalJSON(value, other) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		y, ok := b.([]any)
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !equalJSON(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func jsonKind(value any) string {
+	switch value.(type) {
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case json.Number:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+func mustMarshal(value any) []byte {
+	data, _ := json.Marshal(value)
+	return data
+}
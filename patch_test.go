@@ -0,0 +1,169 @@
+package rex_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+type patchedUser struct {
+	Name    string            `json:"name"`
+	Email   *string           `json:"email"`
+	Age     int               `json:"age" validate:"gte=0"`
+	Profile map[string]string `json:"profile"`
+}
+
+func patchRequest(contentType, body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPatch, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	return req
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	r := rex.NewRouter()
+
+	email := "ann@example.com"
+	user := patchedUser{Name: "Ann", Email: &email, Age: 30, Profile: map[string]string{"city": "Kampala", "lang": "en"}}
+
+	var changed []string
+	r.PATCH("/", func(c *rex.Context) error {
+		if err := c.ApplyMergePatch(&user); err != nil {
+			return err
+		}
+		changed = c.ChangedFields(&user)
+		return nil
+	})
+
+	body := `{"email": null, "age": 31, "profile": {"lang": null, "tz": "EAT"}}`
+	if res := r.Test(patchRequest(rex.ContentTypeMergePatch, body)); res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %q", res.Code, res.BodyString())
+	}
+
+	want := patchedUser{Name: "Ann", Age: 31, Profile: map[string]string{"city": "Kampala", "tz": "EAT"}}
+	if !reflect.DeepEqual(user, want) {
+		t.Errorf("expected %+v, got %+v", want, user)
+	}
+
+	if !reflect.DeepEqual(changed, []string{"Email", "Age", "Profile"}) {
+		t.Errorf("expected the patched fields, got %v", changed)
+	}
+
+	// The result is validated.
+	if res := r.Test(patchRequest(rex.ContentTypeMergePatch, `{"age": -1}`)); res.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid result to be rejected, got %d", res.Code)
+	}
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	doc := []byte(`{"name": "Ann", "tags": ["a", "b"], "address": {"city": "Kampala"}, "score": 1}`)
+
+	tests := []struct {
+		name  string
+		patch string
+		want  string
+	}{
+		{"add member", `[{"op": "add", "path": "/email", "value": "ann@example.com"}]`,
+			`{"address":{"city":"Kampala"},"email":"ann@example.com","name":"Ann","score":1,"tags":["a","b"]}`},
+		{"insert and append", `[{"op": "add", "path": "/tags/0", "value": "z"}, {"op": "add", "path": "/tags/-", "value": "c"}]`,
+			`{"address":{"city":"Kampala"},"name":"Ann","score":1,"tags":["z","a","b","c"]}`},
+		{"remove", `[{"op": "remove", "path": "/tags/1"}, {"op": "remove", "path": "/address"}]`,
+			`{"name":"Ann","score":1,"tags":["a"]}`},
+		{"replace", `[{"op": "replace", "path": "/address/city", "value": "Gulu"}]`,
+			`{"address":{"city":"Gulu"},"name":"Ann","score":1,"tags":["a","b"]}`},
+		{"move", `[{"op": "move", "from": "/address/city", "path": "/city"}]`,
+			`{"address":{},"city":"Kampala","name":"Ann","score":1,"tags":["a","b"]}`},
+		{"copy", `[{"op": "copy", "from": "/tags", "path": "/labels"}, {"op": "add", "path": "/labels/-", "value": "c"}]`,
+			`{"address":{"city":"Kampala"},"labels":["a","b","c"],"name":"Ann","score":1,"tags":["a","b"]}`},
+		{"test", `[{"op": "test", "path": "/score", "value": 1.0}, {"op": "test", "path": "/tags", "value": ["a", "b"]}]`,
+			`{"address":{"city":"Kampala"},"name":"Ann","score":1,"tags":["a","b"]}`},
+		{"escaped path", `[{"op": "add", "path": "/a~1b~0c", "value": true}]`,
+			`{"a/b~c":true,"address":{"city":"Kampala"},"name":"Ann","score":1,"tags":["a","b"]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rex.NewRouter()
+			r.PATCH("/", func(c *rex.Context) error {
+				patched, err := c.ApplyJSONPatch(doc)
+				if err != nil {
+					return err
+				}
+				return c.Send(patched)
+			})
+
+			res := r.Test(patchRequest(rex.ContentTypeJSONPatch, tt.patch))
+			if res.Code != http.StatusOK || res.BodyString() != tt.want {
+				t.Errorf("expected %s, got %d %s", tt.want, res.Code, res.BodyString())
+			}
+		})
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	doc := []byte(`{"name": "Ann", "tags": ["a"]}`)
+
+	tests := []struct {
+		name  string
+		patch string
+		index int
+	}{
+		{"failed test", `[{"op": "replace", "path": "/name", "value": "Bob"}, {"op": "test", "path": "/name", "value": "Ann"}]`, 1},
+		{"missing member", `[{"op": "remove", "path": "/email"}]`, 0},
+		{"relative path", `[{"op": "add", "path": "name", "value": 1}]`, 0},
+		{"invalid escape", `[{"op": "add", "path": "/a~2", "value": 1}]`, 0},
+		{"index out of range", `[{"op": "add", "path": "/tags/5", "value": "b"}]`, 0},
+		{"leading zero", `[{"op": "replace", "path": "/tags/00", "value": "b"}]`, 0},
+		{"move into itself", `[{"op": "move", "from": "/tags", "path": "/tags/0"}]`, 0},
+		{"unknown op", `[{"op": "add", "path": "/x", "value": 1}, {"op": "merge", "path": "/x"}]`, 1},
+		{"missing value", `[{"op": "replace", "path": "/name"}]`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := rex.NewRouter()
+
+			var patchErr rex.JSONPatchError
+			r.PATCH("/", func(c *rex.Context) error {
+				_, err := c.ApplyJSONPatch(doc)
+				errors.As(err, &patchErr)
+				return err
+			})
+
+			req := patchRequest(rex.ContentTypeJSONPatch, tt.patch)
+			req.Header.Set("Accept", "application/json")
+			res := r.Test(req)
+
+			if res.Code != http.StatusUnprocessableEntity || patchErr.Index != tt.index {
+				t.Fatalf("expected 422 for operation %d, got %d %s", tt.index, res.Code, res.BodyString())
+			}
+
+			var body struct {
+				Index int `json:"index"`
+			}
+			if err := json.Unmarshal(res.Body.Bytes(), &body); err != nil || body.Index != tt.index {
+				t.Errorf("expected the operation index in the response, got %s", res.BodyString())
+			}
+		})
+	}
+}
+
+func TestBodyParserRejectsPatches(t *testing.T) {
+	r := rex.NewRouter()
+	r.PATCH("/", func(c *rex.Context) error {
+		var user patchedUser
+		return c.BodyParser(&user)
+	})
+
+	for _, contentType := range []string{rex.ContentTypeMergePatch, rex.ContentTypeJSONPatch} {
+		res := r.Test(patchRequest(contentType, `{}`))
+		if res.Code != http.StatusUnsupportedMediaType || !strings.Contains(res.BodyString(), "ApplyMergePatch") {
+			t.Errorf("%s: expected 415 pointing to the patch helpers, got %d %q", contentType, res.Code, res.BodyString())
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package rex
+
+import (
+	"net/url"
+	"strings"
+)
+
+// AllowEncodedSlashes makes c.Param decode encoded slashes (%2F) in path parameters,
+// so that a single-segment parameter can hold a path: "/files/reports%2F2024%2Fq1.pdf"
+// on "/files/{name}" gives c.Param("name") "reports/2024/q1.pdf". Encoded slashes are
+// also kept when NormalizePaths is set with DecodeEscapedSlashes.
+//
+// By default encoded slashes stay "%2F" in the otherwise decoded value of c.Param,
+// so that "..%2F..%2Fetc" on "/files/{name}" is never read as a path. The values of
+// catch-all parameters like {path...} use "/" for the separators of the path either way.
+//
+// Go's ServeMux matches the escaped path segment by segment: an encoded slash never
+// separates segments, and paths with repeated slashes or dot segments are redirected
+// to their clean form before they are matched, while encoded dots (%2E) are not cleaned.
+func AllowEncodedSlashes(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.allowEncodedSlashes = enabled
+	}
+}
+
+// ParamRaw returns the path parameter name as it appears in the escaped request path,
+// e.g "q1%20report%2Fdraft.pdf". For a catch-all parameter it is the escaped remainder of the path.
+// Parameters set with RedirectOptions.Params are returned unchanged.
+func (c *Context) ParamRaw(name string) string {
+	c.checkReleased()
+	if raw, ok := c.rawParam(name); ok {
+		return raw
+	}
+
+	if opts, ok := c.redirectOptions(); ok {
+		return opts.Params[name]
+	}
+	return ""
+}
+
+// PathEscapeParam escapes v for a single-segment path parameter like {name}.
+// Slashes are encoded as %2F, so they need AllowEncodedSlashes to be decoded by c.Param.
+//
+//	"/files/" + rex.PathEscapeParam("reports/2024/q1.pdf") // "/files/reports%2F2024%2Fq1.pdf"
+func PathEscapeParam(v string) string {
+	return url.PathEscape(v)
+}
+
+// PathEscapeWildcard escapes v for a catch-all parameter like {path...}.
+// Slashes are kept as the separators of the path and each segment is escaped.
+//
+//	"/docs/" + rex.PathEscapeWildcard("guides/intro & setup.md") // "/docs/guides/intro%20&%20setup.md"
+func PathEscapeWildcard(v string) string {
+	segments := strings.Split(v, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// rawParam returns the escaped path segments matched by the placeholder name of the route
+// pattern. ok is false if the pattern has no such placeholder.
+func (c *Context) rawParam(name string) (raw string, ok bool) {
+	if c.currentRoute == nil {
+		return "", false
+	}
+
+	pattern := c.currentRoute.pattern
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:] // Strip the host.
+	}
+
+	segments := strings.Split(c.Request.URL.EscapedPath(), "/")
+	for i, segment := range strings.Split(pattern, "/") {
+		if !strings.HasPrefix(segment, "{") || !strings.HasSuffix(segment, "}") {
+			continue
+		}
+
+		param, wildcard := strings.CutSuffix(segment[1:len(segment)-1], "...")
+		switch {
+		case param != name:
+			continue
+		case i >= len(segments):
+			return "", true // Empty remainder, e.g "/files/" on "/files/{path...}".
+		case wildcard:
+			return strings.Join(segments[i:], "/"), true
+		}
+		return segments[i], true
+	}
+	return "", false
+}
+
+// hasEncodedSlash reports whether the escaped path contains %2F.
+func hasEncodedSlash(escaped string) bool {
+	return strings.Contains(escaped, "%2F") || strings.Contains(escaped, "%2f")
+}
+
+// decodeParam unescapes a raw parameter except for its encoded slashes, which stay "%2F".
+// Invalid escapes are kept like ServeMux does.
+func decodeParam(raw string) string {
+	parts := strings.Split(strings.ReplaceAll(raw, "%2f", "%2F"), "%2F")
+	for i, part := range parts {
+		if decoded, err := url.PathUnescape(part); err == nil {
+			parts[i] = decoded
+		}
+	}
+	return strings.Join(parts, "%2F")
+}
@@ -0,0 +1,121 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func newParamRouter(options ...rex.RouterOption) *rex.Router {
+	r := rex.NewRouter(options...)
+	r.GET("/files/{name}", func(c *rex.Context) error {
+		return c.String(c.Param("name") + " " + c.ParamRaw("name"))
+	})
+	r.GET("/docs/{path...}", func(c *rex.Context) error {
+		return c.String(c.Param("path") + " " + c.ParamRaw("path"))
+	})
+	return r
+}
+
+func TestEncodedSlashRoundTrip(t *testing.T) {
+	r := newParamRouter(rex.AllowEncodedSlashes(true))
+
+	tests := []struct {
+		target string
+		body   string
+	}{
+		{"/files/" + rex.PathEscapeParam("reports/2024/q1.pdf"), "reports/2024/q1.pdf reports%2F2024%2Fq1.pdf"},
+		{"/docs/" + rex.PathEscapeWildcard("guides/intro & setup.md"), "guides/intro & setup.md guides/intro%20&%20setup.md"},
+		{"/docs/" + rex.PathEscapeWildcard("a/b") + "%2Fc", "a/b/c a/b%2Fc"},
+	}
+
+	for _, tt := range tests {
+		res := r.Test(httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if res.Code != http.StatusOK || res.BodyString() != tt.body {
+			t.Errorf("%s: expected %q, got %d %q", tt.target, tt.body, res.Code, res.BodyString())
+		}
+	}
+}
+
+func TestEncodedSlashesKeptByDefault(t *testing.T) {
+	r := newParamRouter()
+
+	tests := []struct {
+		target string
+		body   string
+	}{
+		{"/files/reports%2F2024%2Fq1.pdf", "reports%2F2024%2Fq1.pdf reports%2F2024%2Fq1.pdf"},
+		{"/files/..%2F..%2Fetc%2Fpasswd", "..%2F..%2Fetc%2Fpasswd ..%2F..%2Fetc%2Fpasswd"},
+		{"/files/caf%C3%A9%2fmenu%20v2", "café%2Fmenu v2 caf%C3%A9%2fmenu%20v2"},
+		{"/docs/a%2Fb/c%20d", "a%2Fb/c d a%2Fb/c%20d"},
+		{"/files/plain%20name", "plain name plain%20name"},
+	}
+
+	for _, tt := range tests {
+		res := r.Test(httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if res.Code != http.StatusOK || res.BodyString() != tt.body {
+			t.Errorf("%s: expected %q, got %d %q", tt.target, tt.body, res.Code, res.BodyString())
+		}
+	}
+}
+
+func TestParamPlusAndUnicode(t *testing.T) {
+	r := newParamRouter()
+
+	tests := []struct {
+		target string
+		body   string
+	}{
+		{"/files/a+b", "a+b a+b"},
+		{"/files/a%2Bb", "a+b a%2Bb"},
+		{"/files/" + rex.PathEscapeParam("résumé 2024.pdf"), "résumé 2024.pdf r%C3%A9sum%C3%A9%202024.pdf"},
+		{"/files/" + rex.PathEscapeParam("日本語"), "日本語 %E6%97%A5%E6%9C%AC%E8%AA%9E"},
+		{"/docs/" + rex.PathEscapeWildcard("c++/über.md"), "c++/über.md c++/%C3%BCber.md"},
+	}
+
+	for _, tt := range tests {
+		res := r.Test(httptest.NewRequest(http.MethodGet, tt.target, nil))
+		if res.Code != http.StatusOK || res.BodyString() != tt.body {
+			t.Errorf("%s: expected %q, got %d %q", tt.target, tt.body, res.Code, res.BodyString())
+		}
+	}
+}
+
+func TestAllowEncodedSlashesWithNormalization(t *testing.T) {
+	r := newParamRouter(
+		rex.AllowEncodedSlashes(true),
+		rex.NormalizePaths(true, rex.PathNormalization{DecodeEscapedSlashes: true}),
+	)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "//files//a%2Fb", nil))
+	if res.Code != http.StatusOK || res.BodyString() != "a/b a%2Fb" {
+		t.Errorf("expected the encoded slash to be kept, got %d %q", res.Code, res.BodyString())
+	}
+}
+
+func TestParamRawRedirectParams(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/target", func(c *rex.Context) error {
+		return c.String(c.ParamRaw("id") + "|" + c.ParamRaw("missing"))
+	})
+	r.GET("/source", func(c *rex.Context) error {
+		return c.RedirectRoute("/target", rex.RedirectOptions{Params: map[string]string{"id": "a b"}})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/source", nil))
+	if res.BodyString() != "a b|" {
+		t.Errorf("expected the redirect param unchanged, got %q", res.BodyString())
+	}
+}
+
+func TestRedirectsKeepEncodedSlashes(t *testing.T) {
+	r := rex.NewRouter()
+	r.Redirects(map[string]string{"/blog/{slug}": "/articles/{slug}"})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/blog/a%2Fb%20c", nil))
+	if res.Code != http.StatusMovedPermanently || res.Header("Location") != "/articles/a%2Fb%20c" {
+		t.Errorf("expected the escaped slug in the target, got %d %q", res.Code, res.Header("Location"))
+	}
+}
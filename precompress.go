@@ -0,0 +1,259 @@
+package rex
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// PrecompressMaxSize is the size in bytes of the largest file loaded by PrecompressAssets.
+// Larger files are served from the underlying file system.
+var PrecompressMaxSize int64 = 512 << 10
+
+// PrecompressAssets loads the files of fsys matching any of patterns, e.g "*.css" or "img/logo.svg",
+// into memory with their brotli and gzip variants and a strong ETag. A pattern matches the path
+// of a file or its base name, see path.Match. Without patterns, all files are loaded.
+// It panics if a pattern is malformed or a file can not be read.
+//
+// Mounted with StaticFS, loaded files are served from memory with the encoding negotiated
+// from Accept-Encoding, and compression middleware skips them. Conditional requests are answered
+// with 304 Not Modified and range requests get the uncompressed file. Everything else is served
+// from fsys. Use it for a handful of small, hot embedded files.
+//
+// Example:
+//
+//	r.StaticFS("/static", rex.PrecompressAssets(embedded, "*.css", "*.js", "*.woff2"), 3600)
+func PrecompressAssets(fsys fs.FS, patterns ...string) http.FileSystem {
+	p := &precompressedFS{FileSystem: http.FS(fsys), assets: make(map[string]*precompressedAsset)}
+
+	err := fs.WalkDir(fsys, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		matched, err := matchAny(patterns, name)
+		if err != nil || !matched {
+			return err
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if info.Size() > PrecompressMaxSize {
+			return nil
+		}
+
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+
+		asset := newPrecompressedAsset(info, data)
+		p.assets["/"+name] = asset
+		p.size += int64(len(data))
+		for _, variant := range asset.variants {
+			p.size += int64(len(variant.data))
+		}
+		return nil
+	})
+
+	if err != nil {
+		panic(fmt.Sprintf("rex: PrecompressAssets: %v", err))
+	}
+	return p
+}
+
+// matchAny reports whether name or its base name matches one of patterns, or patterns is empty.
+func matchAny(patterns []string, name string) (bool, error) {
+	if len(patterns) == 0 {
+		return true, nil
+	}
+
+	for _, pattern := range patterns {
+		for _, candidate := range []string{name, path.Base(name)} {
+			if matched, err := path.Match(pattern, candidate); err != nil || matched {
+				return matched, err
+			}
+		}
+	}
+	return false, nil
+}
+
+// precompressedFS serves the files loaded by PrecompressAssets from memory.
+type precompressedFS struct {
+	http.FileSystem                                // the underlying file system
+	assets          map[string]*precompressedAsset // keyed by the cleaned path, e.g "/css/app.css"
+	size            int64                          // bytes loaded, including the compressed variants
+}
+
+type precompressedAsset struct {
+	info        fs.FileInfo
+	data        []byte
+	contentType string
+	etag        string
+	variants    []precompressedVariant // in order of preference
+}
+
+type precompressedVariant struct {
+	encoding string
+	data     []byte
+	etag     string
+}
+
+func newPrecompressedAsset(info fs.FileInfo, data []byte) *precompressedAsset {
+	asset := &precompressedAsset{info: info, data: data, etag: contentETag(data)}
+
+	asset.contentType = mime.TypeByExtension(filepath.Ext(info.Name()))
+	if asset.contentType == "" {
+		asset.contentType = http.DetectContentType(data)
+	}
+
+	encoders := []struct {
+		encoding string
+		writer   func(io.Writer) io.WriteCloser
+	}{
+		{"br", func(w io.Writer) io.WriteCloser { return brotli.NewWriterLevel(w, brotli.BestCompression) }},
+		{"gzip", func(w io.Writer) io.WriteCloser { zw, _ := gzip.NewWriterLevel(w, gzip.BestCompression); return zw }},
+	}
+
+	for _, encoder := range encoders {
+		var buf bytes.Buffer
+		w := encoder.writer(&buf)
+		w.Write(data)
+		w.Close()
+
+		// Already compressed formats like images and fonts do not shrink.
+		if buf.Len() < len(data) {
+			asset.variants = append(asset.variants, precompressedVariant{
+				encoding: encoder.encoding,
+				data:     buf.Bytes(),
+				etag:     strings.TrimSuffix(asset.etag, `"`) + "-" + encoder.encoding + `"`,
+			})
+		}
+	}
+	return asset
+}
+
+// Open opens loaded files from memory and other files from the underlying file system.
+func (p *precompressedFS) Open(name string) (http.File, error) {
+	if asset, ok := p.assets[path.Clean("/"+name)]; ok {
+		return &memoryFile{Reader: bytes.NewReader(asset.data), info: asset.info}, nil
+	}
+	return p.FileSystem.Open(name)
+}
+
+// asset returns the loaded file served for name, honoring ServeMinified and ServeDotfiles.
+func (p *precompressedFS) asset(name string) (*precompressedAsset, bool) {
+	if !ServeDotfiles && hasDotSegment(name) {
+		return nil, false
+	}
+
+	if ext := path.Ext(name); ServeMinified && slices.Contains(MinExtensions, ext) {
+		if asset, ok := p.assets[strings.TrimSuffix(name, ext)+".min"+ext]; ok {
+			return asset, true
+		}
+
+		// The minified file is served from fsys if it exists there.
+		if f, err := p.FileSystem.Open(strings.TrimSuffix(name, ext) + ".min" + ext); err == nil {
+			f.Close()
+			return nil, false
+		}
+	}
+
+	asset, ok := p.assets[name]
+	return asset, ok
+}
+
+// serve writes the loaded file for name, the cleaned path in the mount, and reports
+// whether it was loaded.
+func (p *precompressedFS) serve(c *Context, name string) bool {
+	asset, ok := p.asset(name)
+	if !ok {
+		return false
+	}
+
+	c.AppendVary("Accept-Encoding")
+	c.SetHeader("Content-Type", asset.contentType)
+
+	data, etag := asset.data, asset.etag
+	if c.Request.Header.Get("Range") == "" {
+		if variant, ok := asset.negotiate(c.Request.Header.Get("Accept-Encoding")); ok {
+			data, etag = variant.data, variant.etag
+			c.SetHeader("Content-Encoding", variant.encoding)
+			c.Set(ContentEncodingKey, variant.encoding)
+		}
+	}
+
+	c.SetHeader("ETag", etag)
+	http.ServeContent(c.Response, c.Request, name, asset.info.ModTime(), bytes.NewReader(data))
+	return true
+}
+
+// negotiate returns the preferred variant accepted by the Accept-Encoding header.
+func (a *precompressedAsset) negotiate(acceptEncoding string) (precompressedVariant, bool) {
+	var best precompressedVariant
+	bestQuality := 0.0
+	for _, variant := range a.variants {
+		if q := encodingQuality(acceptEncoding, variant.encoding); q > bestQuality {
+			best, bestQuality = variant, q
+		}
+	}
+	return best, bestQuality > 0
+}
+
+// encodingQuality returns the q value of encoding in an Accept-Encoding header, 0 if not acceptable.
+func encodingQuality(header, encoding string) float64 {
+	quality, wildcard := -1.0, -1.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case encoding:
+			quality = q
+		case "*":
+			wildcard = q
+		}
+	}
+
+	if quality >= 0 {
+		return quality
+	}
+	return max(wildcard, 0)
+}
+
+// memoryFile is a loaded file opened with precompressedFS.Open.
+type memoryFile struct {
+	*bytes.Reader
+	info fs.FileInfo
+}
+
+func (f *memoryFile) Close() error {
+	return nil
+}
+
+func (f *memoryFile) Readdir(count int) ([]fs.FileInfo, error) {
+	return nil, fmt.Errorf("%s is not a directory", f.info.Name())
+}
+
+func (f *memoryFile) Stat() (fs.FileInfo, error) {
+	return f.info, nil
+}
@@ -0,0 +1,155 @@
+package rex_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"testing/fstest"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/brotli"
+	andybrotli "github.com/andybalholm/brotli"
+)
+
+// countingFS counts the files opened in the wrapped file system.
+type countingFS struct {
+	fs.FS
+	opens atomic.Int64
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.opens.Add(1)
+	return c.FS.Open(name)
+}
+
+func precompressRouter(t *testing.T) (*rex.Router, *countingFS) {
+	t.Helper()
+
+	css := strings.Repeat("body { color: red; }\n", 200)
+	counting := &countingFS{FS: fstest.MapFS{
+		"css/app.css":  {Data: []byte(css)},
+		"img/logo.png": {Data: []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}},
+		"readme.txt":   {Data: []byte("not preloaded")},
+	}}
+
+	r := rex.NewRouter()
+	r.Use(brotli.Brotli())
+	r.StaticFS("/static", rex.PrecompressAssets(counting, "*.css", "img/*"), 3600)
+	return r, counting
+}
+
+func getStatic(r *rex.Router, target string, headers map[string]string) *rex.TestResponse {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return r.Test(req)
+}
+
+func TestPrecompressServesFromMemory(t *testing.T) {
+	r, counting := precompressRouter(t)
+	counting.opens.Store(0)
+
+	for i := 0; i < 2; i++ {
+		res := getStatic(r, "/static/css/app.css", nil)
+		if res.Code != http.StatusOK || !strings.HasPrefix(res.BodyString(), "body { color: red; }") {
+			t.Fatalf("expected the stylesheet, got %d %q", res.Code, res.BodyString())
+		}
+
+		if res.Header("Cache-Control") != "public, max-age=3600" || !strings.HasPrefix(res.Header("Content-Type"), "text/css") {
+			t.Errorf("unexpected headers %v", res.Result().Header)
+		}
+	}
+
+	if n := counting.opens.Load(); n != 0 {
+		t.Errorf("expected preloaded files to be served from memory, the FS was opened %d times", n)
+	}
+
+	// Other files fall through to the file system.
+	if res := getStatic(r, "/static/readme.txt", nil); res.BodyString() != "not preloaded" || counting.opens.Load() == 0 {
+		t.Errorf("expected the file system to serve other files, got %q", res.BodyString())
+	}
+}
+
+func TestPrecompressNegotiation(t *testing.T) {
+	r, _ := precompressRouter(t)
+	want := strings.Repeat("body { color: red; }\n", 200)
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"br":   func(r io.Reader) (io.Reader, error) { return andybrotli.NewReader(r), nil },
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"":     func(r io.Reader) (io.Reader, error) { return r, nil },
+	}
+
+	tests := []struct {
+		acceptEncoding string
+		encoding       string
+	}{
+		{"gzip, deflate, br", "br"},
+		{"gzip", "gzip"},
+		{"br;q=0, gzip;q=0.5", "gzip"},
+		{"identity", ""},
+		{"", ""},
+	}
+
+	var etags []string
+	for _, tt := range tests {
+		res := getStatic(r, "/static/css/app.css", map[string]string{"Accept-Encoding": tt.acceptEncoding})
+		if got := res.Header("Content-Encoding"); got != tt.encoding {
+			t.Errorf("%q: expected encoding %q, got %q", tt.acceptEncoding, tt.encoding, got)
+			continue
+		}
+
+		if res.Header("Vary") != "Accept-Encoding" {
+			t.Errorf("%q: expected Vary: Accept-Encoding once, got %q", tt.acceptEncoding, res.Header("Vary"))
+		}
+
+		body, err := decoders[tt.encoding](bytes.NewReader(res.Body.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, _ := io.ReadAll(body)
+		if string(data) != want {
+			t.Errorf("%q: unexpected body after decoding", tt.acceptEncoding)
+		}
+		etags = append(etags, res.Header("ETag"))
+	}
+
+	if etags[0] == etags[1] || etags[1] == etags[3] {
+		t.Errorf("expected each encoding to have its own ETag, got %v", etags)
+	}
+
+	// Images are not compressed.
+	if res := getStatic(r, "/static/img/logo.png", map[string]string{"Accept-Encoding": "br"}); res.Header("Content-Encoding") != "" {
+		t.Errorf("expected the image to be served uncompressed, got %q", res.Header("Content-Encoding"))
+	}
+
+	// Files served from the file system are still compressed by the middleware.
+	if res := getStatic(r, "/static/readme.txt", map[string]string{"Accept-Encoding": "br"}); res.Header("Content-Encoding") != "br" {
+		t.Errorf("expected the middleware to compress other files, got %q", res.Header("Content-Encoding"))
+	}
+}
+
+func TestPrecompressConditionalAndRange(t *testing.T) {
+	r, _ := precompressRouter(t)
+
+	first := getStatic(r, "/static/css/app.css", map[string]string{"Accept-Encoding": "br"})
+	etag := first.Header("ETag")
+
+	res := getStatic(r, "/static/css/app.css", map[string]string{"Accept-Encoding": "br", "If-None-Match": etag})
+	if res.Code != http.StatusNotModified || res.Body.Len() != 0 {
+		t.Errorf("expected 304 for a matching ETag, got %d", res.Code)
+	}
+
+	res = getStatic(r, "/static/css/app.css", map[string]string{"Accept-Encoding": "br", "Range": "bytes=0-3"})
+	if res.Code != http.StatusPartialContent || res.BodyString() != "body" || res.Header("Content-Encoding") != "" {
+		t.Errorf("expected the range of the uncompressed file, got %d %q %q", res.Code, res.BodyString(), res.Header("Content-Encoding"))
+	}
+}
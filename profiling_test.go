@@ -0,0 +1,99 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func profilingGuard(next rex.HandlerFunc) rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		if c.Request.Header.Get("X-Admin-Token") != "secret" {
+			return c.WriteHeader(http.StatusUnauthorized)
+		}
+		return next(c)
+	}
+}
+
+func profilingRequest(method, target string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("X-Admin-Token", "secret")
+	return req
+}
+
+func TestEnableProfiling(t *testing.T) {
+	r := rex.NewRouter()
+	r.EnableProfiling("/admin", profilingGuard)
+
+	res := r.Test(profilingRequest(http.MethodGet, "/admin/debug/pprof/"))
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "goroutine") {
+		t.Fatalf("expected the pprof index, got %d %q", res.Code, res.Body.String())
+	}
+
+	res = r.Test(profilingRequest(http.MethodGet, "/admin/debug/pprof/goroutine?debug=1"))
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "goroutine profile") {
+		t.Errorf("expected the goroutine profile, got %d", res.Code)
+	}
+
+	res = r.Test(profilingRequest(http.MethodGet, "/admin/debug/vars"))
+	if res.Code != http.StatusOK || !strings.Contains(res.Body.String(), "memstats") {
+		t.Errorf("expected expvar output, got %d", res.Code)
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/admin/debug/pprof/", nil))
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("expected the guard to reject the request, got %d", res.Code)
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/admin/debug/vars", nil))
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("expected the guard to protect expvar, got %d", res.Code)
+	}
+}
+
+func TestEnableProfilingCPUProfile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("takes a second")
+	}
+
+	r := rex.NewRouter(rex.WithMaxResponseBytes(16))
+	r.EnableProfiling("", profilingGuard)
+
+	res := r.Test(profilingRequest(http.MethodGet, "/debug/pprof/profile?seconds=1"))
+	if res.Code != http.StatusOK || res.Body.Len() == 0 {
+		t.Errorf("expected a CPU profile, got %d with %d bytes", res.Code, res.Body.Len())
+	}
+}
+
+func TestEnableProfilingRoutes(t *testing.T) {
+	r := rex.NewRouter()
+	r.EnableProfiling("/admin", profilingGuard)
+
+	registered := make(map[string]bool)
+	for _, route := range r.RegisteredRoutes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+
+	for _, route := range []string{
+		"GET /admin/debug/pprof/{$}",
+		"GET /admin/debug/pprof/{name}",
+		"POST /admin/debug/pprof/symbol",
+		"GET /admin/debug/vars",
+	} {
+		if !registered[route] {
+			t.Errorf("expected %s in the registered routes, got %v", route, registered)
+		}
+	}
+}
+
+func TestEnableProfilingRequiresGuard(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic without a guard")
+		}
+	}()
+	rex.NewRouter().EnableProfiling("", nil)
+}
@@ -0,0 +1,94 @@
+package rex
+
+import (
+	"net/url"
+	"strings"
+)
+
+// queryValues returns the parsed query of the request. The query is parsed once
+// and cached until a middleware replaces the URL. The values must not be modified.
+func (c *Context) queryValues() url.Values {
+	raw := c.Request.URL.RawQuery
+	if c.query == nil || c.queryRaw != raw {
+		c.query = c.Request.URL.Query()
+		c.queryRaw = raw
+	}
+	return c.query
+}
+
+// ForEachQuery calls fn with each value of the query parameter key, in order, until fn returns false.
+// Unless the query was already parsed by another accessor, it scans the raw query
+// without allocating the map of all parameters, for huge query strings like
+// thousands of ids=... repetitions. Like Query, it falls back to the redirect options
+// if the query has no key.
+//
+// Example:
+//
+//	var ids []int
+//	c.ForEachQuery("ids", func(v string) bool {
+//		if id, err := strconv.Atoi(v); err == nil {
+//			ids = append(ids, id)
+//		}
+//		return true
+//	})
+func (c *Context) ForEachQuery(key string, fn func(value string) bool) {
+	c.checkReleased()
+
+	found := false
+	if c.query != nil && c.queryRaw == c.Request.URL.RawQuery {
+		for _, v := range c.query[key] {
+			found = true
+			if !fn(v) {
+				return
+			}
+		}
+	} else {
+		found = scanQuery(c.Request.URL.RawQuery, key, fn)
+	}
+
+	if !found {
+		if opts, ok := c.redirectOptions(); ok {
+			if v, ok := opts.QueryParams[key]; ok {
+				fn(v)
+			}
+		}
+	}
+}
+
+// scanQuery calls fn with the values of key in the raw query, skipping pairs that
+// url.ParseQuery rejects. It reports whether key was found.
+func scanQuery(query, key string, fn func(value string) bool) bool {
+	found := false
+	for query != "" {
+		var pair string
+		pair, query, _ = strings.Cut(query, "&")
+		if pair == "" || strings.Contains(pair, ";") {
+			continue
+		}
+
+		k, v, _ := strings.Cut(pair, "=")
+		if strings.ContainsAny(k, "%+") {
+			var err error
+			if k, err = url.QueryUnescape(k); err != nil {
+				continue
+			}
+		}
+
+		if k != key {
+			continue
+		}
+
+		if strings.ContainsAny(v, "%+") {
+			var err error
+			if v, err = url.QueryUnescape(v); err != nil {
+				continue
+			}
+		}
+
+		found = true
+		if !fn(v) {
+			return true
+		}
+	}
+	return found
+}
@@ -0,0 +1,161 @@
+package rex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestScanQueryMatchesParseQuery(t *testing.T) {
+	queries := []string{
+		"ids=1&ids=2&name=ann",
+		"ids=1&&ids=&other=3",
+		"i%64s=a%20b&ids=c+d&ids",
+		"ids=1;ids=2&ids=3",
+		"ids=%zz&ids=4&%zz=5",
+		"",
+	}
+
+	for _, query := range queries {
+		parsed, _ := url.ParseQuery(query)
+
+		var got []string
+		scanQuery(query, "ids", func(v string) bool {
+			got = append(got, v)
+			return true
+		})
+
+		if !slices.Equal(got, parsed["ids"]) {
+			t.Errorf("%q: expected %q, got %q", query, parsed["ids"], got)
+		}
+	}
+}
+
+func TestForEachQuery(t *testing.T) {
+	r := NewRouter()
+	r.GET("/items", func(c *Context) error {
+		var ids []string
+		c.ForEachQuery("ids", func(v string) bool {
+			ids = append(ids, v)
+			return len(ids) < 2
+		})
+
+		if !slices.Equal(ids, []string{"1", "2"}) {
+			t.Errorf("expected the first two values, got %q", ids)
+		}
+
+		// The cached query gives the same values.
+		if c.Query("ids") != "1" {
+			t.Errorf("expected the first value, got %q", c.Query("ids"))
+		}
+
+		ids = nil
+		c.ForEachQuery("ids", func(v string) bool {
+			ids = append(ids, v)
+			return true
+		})
+
+		if !slices.Equal(ids, []string{"1", "2", "3"}) {
+			t.Errorf("expected all values, got %q", ids)
+		}
+		return nil
+	})
+	r.Test(httptest.NewRequest(http.MethodGet, "/items?ids=1&ids=2&ids=3", nil))
+}
+
+func TestQueryCacheFollowsURL(t *testing.T) {
+	r := NewRouter()
+	rewrite := func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			if c.Query("lang") != "en" {
+				t.Errorf("expected the original query, got %q", c.Query("lang"))
+			}
+
+			req := c.Request.Clone(c.Request.Context())
+			req.URL.RawQuery = "lang=fr"
+			c.Request = req
+			return next(c)
+		}
+	}
+
+	r.GET("/", func(c *Context) error {
+		return c.String(c.Query("lang"))
+	}, rewrite)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/?lang=en", nil))
+	if res.BodyString() != "fr" {
+		t.Errorf("expected the query of the replaced URL, got %q", res.BodyString())
+	}
+}
+
+func TestForEachQueryRedirectFallback(t *testing.T) {
+	r := NewRouter()
+	r.GET("/start", func(c *Context) error {
+		return c.RedirectRoute("/target", RedirectOptions{
+			Status:      http.StatusFound,
+			QueryParams: map[string]string{"tab": "billing"},
+		})
+	})
+
+	var got []string
+	r.GET("/target", func(c *Context) error {
+		c.ForEachQuery("tab", func(v string) bool {
+			got = append(got, v)
+			return true
+		})
+		return nil
+	})
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/start", nil))
+	if !slices.Equal(got, []string{"billing"}) {
+		t.Errorf("expected the redirect query param, got %q", got)
+	}
+}
+
+// hugeQuery returns a query with n ids values.
+func hugeQuery(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString("ids=")
+		b.WriteString(strconv.Itoa(i))
+	}
+	return b.String() + "&page=2&sort=name"
+}
+
+func BenchmarkQueryAccessors(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/?"+hugeQuery(10000), nil)
+	r := NewRouter()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := r.InitContext(httptest.NewRecorder(), req)
+		_ = c.Query("page")
+		_ = c.QueryInt("page")
+		_ = c.Query("sort")
+		_ = c.Query("missing")
+		r.PutContext(c)
+	}
+}
+
+func BenchmarkForEachQuery(b *testing.B) {
+	req := httptest.NewRequest(http.MethodGet, "/?"+hugeQuery(10000), nil)
+	r := NewRouter()
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c := r.InitContext(httptest.NewRecorder(), req)
+		sum := 0
+		c.ForEachQuery("ids", func(v string) bool {
+			sum += len(v)
+			return true
+		})
+		r.PutContext(c)
+	}
+}
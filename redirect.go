@@ -0,0 +1,237 @@
+package rex
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnsafeRedirect is returned by SafeRedirect when the target URL points to a foreign host.
+var ErrUnsafeRedirect = errors.New("rex: refusing to redirect to a foreign host")
+
+// WithRedirectHosts allows SafeRedirect to redirect to absolute URLs on the given hosts.
+// Hosts are matched against the URL host including the port if any.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.WithRedirectHosts("accounts.example.com"))
+func WithRedirectHosts(hosts ...string) RouterOption {
+	return func(r *Router) {
+		r.redirectHosts = append(r.redirectHosts, hosts...)
+	}
+}
+
+// WithFlashKey sets the HMAC key used to sign flash message cookies.
+// By default a random key is generated when the router is created, which means
+// flashes do not survive restarts or work across multiple instances.
+func WithFlashKey(key []byte) RouterOption {
+	return func(r *Router) {
+		r.flashKey = key
+	}
+}
+
+// localTarget returns the path, query and fragment of target if it is relative
+// or points to the request host. ok is false for foreign or malformed URLs.
+func (c *Context) localTarget(target string, hosts ...string) (string, bool) {
+	// Browsers treat backslashes like forward slashes, "/\evil.com" is protocol relative.
+	if target == "" || strings.Contains(target, "\\") {
+		return "", false
+	}
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", false
+	}
+
+	if u.Scheme == "" && u.Host == "" {
+		if !strings.HasPrefix(u.Path, "/") || strings.HasPrefix(target, "//") {
+			return "", false
+		}
+		return target, true
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", false
+	}
+
+	if u.Host == c.Request.Host {
+		local := u.RequestURI()
+		if u.Fragment != "" {
+			local += "#" + u.Fragment
+		}
+
+		// Targets are relative to the base path, like the paths passed to c.Redirect.
+		return c.trimBasePath(local)
+	}
+
+	if slices.Contains(hosts, u.Host) {
+		return target, true
+	}
+	return "", false
+}
+
+// RedirectBack redirects to the page in the Referer header if it is same-origin.
+// Otherwise it redirects to fallback. This prevents open redirects through crafted Referer headers.
+// Default status code is 303 (http.StatusSeeOther).
+func (c *Context) RedirectBack(fallback string, status ...int) error {
+	if target, ok := c.localTarget(c.Request.Referer()); ok {
+		return c.Redirect(target, status...)
+	}
+	return c.Redirect(fallback, status...)
+}
+
+// SafeRedirect redirects to target only if it is relative, same-origin or on a host
+// allowed with the WithRedirectHosts option. Otherwise ErrUnsafeRedirect is returned
+// and nothing is written. Default status code is 303 (http.StatusSeeOther).
+func (c *Context) SafeRedirect(target string, status ...int) error {
+	local, ok := c.localTarget(target, c.router.redirectHosts...)
+	if !ok {
+		return ErrUnsafeRedirect
+	}
+	return c.Redirect(local, status...)
+}
+
+// Flash is a one-time message shown after a redirect.
+type Flash struct {
+	Level   string `json:"level"`   // e.g success, info, warning, error
+	Message string `json:"message"` // The message
+}
+
+const (
+	flashCookieName = "rex_flash"
+	flashLocalsKey  = "rex_flashes"
+	flashMaxAge     = 60 // seconds a flash cookie is valid
+)
+
+// RedirectWithFlash stores a flash message in a short-lived signed cookie and redirects to url.
+// The message is available through c.Flashes() on the next request only.
+// It is also passed to views rendered with c.Render as "flashes".
+func (c *Context) RedirectWithFlash(url, level, message string, status ...int) error {
+	flashes := append(c.pendingFlashes(), Flash{Level: level, Message: message})
+	value, err := c.router.encodeFlashes(flashes, time.Now())
+	if err != nil {
+		return err
+	}
+
+	http.SetCookie(c.Response, &http.Cookie{
+		Name:     flashCookieName,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   flashMaxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return c.Redirect(url, status...)
+}
+
+// Flashes returns the flash messages set by RedirectWithFlash on the previous request.
+// The flashes are consumed: the cookie is cleared so they are shown exactly once.
+// Calling Flashes more than once in the same request returns the same messages.
+func (c *Context) Flashes() []Flash {
+	if flashes, ok := c.Get(flashLocalsKey); ok {
+		return flashes.([]Flash)
+	}
+
+	flashes := c.pendingFlashes()
+	if flashes != nil {
+		http.SetCookie(c.Response, &http.Cookie{
+			Name:     flashCookieName,
+			Value:    "",
+			Path:     "/",
+			MaxAge:   -1,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	c.mu.Lock()
+	c.locals[flashLocalsKey] = flashes
+	c.mu.Unlock()
+	return flashes
+}
+
+// pendingFlashes decodes the flash cookie sent with the request.
+// Tampered, malformed or expired cookies are ignored.
+func (c *Context) pendingFlashes() []Flash {
+	cookie, err := c.Request.Cookie(flashCookieName)
+	if err != nil || cookie.Value == "" {
+		return nil
+	}
+	return c.router.decodeFlashes(cookie.Value, time.Now())
+}
+
+func (r *Router) flashMAC(payload string) string {
+	mac := hmac.New(sha256.New, r.flashKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// encodeFlashes returns the cookie value "issued.payload.mac" of flashes, where issued
+// is the Unix time the value was created at. The issue time is signed with the payload.
+func (r *Router) encodeFlashes(flashes []Flash, issued time.Time) (string, error) {
+	data, err := json.Marshal(flashes)
+	if err != nil {
+		return "", err
+	}
+	payload := strconv.FormatInt(issued.Unix(), 10) + "." + base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + r.flashMAC(payload), nil
+}
+
+// decodeFlashes returns the flashes of a cookie value created by encodeFlashes.
+// Values issued more than flashMaxAge seconds before now are rejected, so that a
+// captured cookie cannot be replayed after the browser has dropped it.
+func (r *Router) decodeFlashes(value string, now time.Time) []Flash {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return nil
+	}
+
+	payload, sig := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(r.flashMAC(payload))) {
+		return nil
+	}
+
+	issuedAt, encoded, ok := strings.Cut(payload, ".")
+	if !ok {
+		return nil
+	}
+
+	issued, err := strconv.ParseInt(issuedAt, 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	if age := now.Unix() - issued; age < -flashMaxAge || age > flashMaxAge {
+		return nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil
+	}
+
+	var flashes []Flash
+	if err := json.Unmarshal(data, &flashes); err != nil {
+		return nil
+	}
+	return flashes
+}
+
+// randomKey returns n cryptographically random bytes.
+func randomKey(n int) []byte {
+	key := make([]byte, n)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}
@@ -0,0 +1,183 @@
+package rex_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestRedirectBack(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/save", func(c *rex.Context) error {
+		return c.RedirectBack("/home")
+	})
+
+	tests := []struct {
+		name     string
+		referer  string
+		expected string
+	}{
+		{"same origin", "http://example.com/items?page=2", "/items?page=2"},
+		{"relative", "/items", "/items"},
+		{"no referer", "", "/home"},
+		{"attacker domain", "https://evil.com/phish", "/home"},
+		{"attacker subdomain trick", "http://example.com.evil.com/", "/home"},
+		{"protocol relative", "//evil.com/phish", "/home"},
+		{"backslash trick", "/\\evil.com", "/home"},
+		{"javascript scheme", "javascript:alert(1)", "/home"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "http://example.com/save", nil)
+			if tt.referer != "" {
+				req.Header.Set("Referer", tt.referer)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusSeeOther {
+				t.Fatalf("expected status 303, got %d", w.Code)
+			}
+
+			if loc := w.Header().Get("Location"); loc != tt.expected {
+				t.Errorf("expected Location %q, got %q", tt.expected, loc)
+			}
+		})
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	r := rex.NewRouter(rex.WithRedirectHosts("accounts.example.com"))
+	r.GET("/go", func(c *rex.Context) error {
+		if err := c.SafeRedirect(c.Query("to")); err != nil {
+			c.WriteHeader(http.StatusBadRequest)
+			return c.String(err.Error())
+		}
+		return nil
+	})
+
+	tests := []struct {
+		to     string
+		status int
+	}{
+		{"/dashboard", http.StatusSeeOther},
+		{"http://example.com/dashboard", http.StatusSeeOther},
+		{"https://accounts.example.com/login", http.StatusSeeOther},
+		{"https://evil.com", http.StatusBadRequest},
+		{"//evil.com", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "http://example.com/go?to="+tt.to, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d", tt.to, tt.status, w.Code)
+		}
+	}
+}
+
+func TestRedirectWithFlash(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/save", func(c *rex.Context) error {
+		return c.RedirectWithFlash("/items", "success", "Item saved")
+	})
+
+	r.GET("/items", func(c *rex.Context) error {
+		flashes := c.Flashes()
+		if len(flashes) == 0 {
+			return c.String("none")
+		}
+		return c.String(flashes[0].Level + ":" + flashes[0].Message)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/save", nil))
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("expected status 303, got %d", w.Code)
+	}
+
+	cookies := w.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected 1 flash cookie, got %d", len(cookies))
+	}
+	flashCookie := cookies[0]
+
+	// First hop: the flash is displayed and cleared.
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.AddCookie(flashCookie)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Body.String() != "success:Item saved" {
+		t.Fatalf("expected flash message, got %q", w.Body.String())
+	}
+
+	cleared := w.Result().Cookies()
+	if len(cleared) != 1 || cleared[0].MaxAge >= 0 {
+		t.Fatalf("expected the flash cookie to be cleared, got %v", cleared)
+	}
+
+	// Second hop: the browser has dropped the cookie.
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if w.Body.String() != "none" {
+		t.Errorf("expected no flash on second hop, got %q", w.Body.String())
+	}
+
+	// Tampered cookies are ignored.
+	req = httptest.NewRequest(http.MethodGet, "/items", nil)
+	req.AddCookie(&http.Cookie{Name: flashCookie.Name, Value: "W3sibGV2ZWwiOiJ4In1d.forged"})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "none" {
+		t.Errorf("expected tampered flash to be ignored, got %q", w.Body.String())
+	}
+}
+
+func TestExpiredFlashIgnored(t *testing.T) {
+	key := []byte("flash-test-key")
+	r := rex.NewRouter(rex.WithFlashKey(key))
+	r.GET("/items", func(c *rex.Context) error {
+		return c.String(strconv.Itoa(len(c.Flashes())))
+	})
+
+	sign := func(issued time.Time) string {
+		payload := strconv.FormatInt(issued.Unix(), 10) + "." +
+			base64.RawURLEncoding.EncodeToString([]byte(`[{"level":"success","message":"Item saved"}]`))
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(payload))
+		return payload + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name     string
+		issued   time.Time
+		expected string
+	}{
+		{"fresh", time.Now(), "1"},
+		{"expired", time.Now().Add(-10 * time.Minute), "0"},
+		{"future", time.Now().Add(10 * time.Minute), "0"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.AddCookie(&http.Cookie{Name: "rex_flash", Value: sign(tt.issued)})
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Body.String() != tt.expected {
+			t.Errorf("%s: expected %s flashes, got %s", tt.name, tt.expected, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,254 @@
+package rex
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// redirectPlaceholder matches the {name} and {name...} placeholders of a redirect target.
+var redirectPlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+
+// Redirects registers a GET route for each source pattern of rules redirecting to its target
+// with status, 301 Moved Permanently by default. HEAD requests are served like for other GET routes.
+//
+// Targets use the placeholders of the source, including the remainder of a wildcard,
+// and the query string of the request is preserved:
+//
+//	r.Redirects(map[string]string{
+//		"/blog/{slug}":        "/articles/{slug}",
+//		"/old-docs/{rest...}": "/docs/{rest...}",
+//		"/about-us":           "https://example.com/about",
+//	})
+//
+// It panics if a target uses a placeholder missing from its source.
+// Sources conflicting with registered routes, including patterns that only differ in
+// the names of their placeholders, are reported like duplicate routes, see MustRegister.
+func (r *Router) Redirects(rules map[string]string, status ...int) {
+	code := http.StatusMovedPermanently
+	if len(status) > 0 {
+		code = status[0]
+	}
+
+	location := callerLocation()
+	for _, source := range sortedKeys(rules) {
+		r.redirect(&redirectRule{source: source, target: rules[source], status: code}, location)
+	}
+}
+
+// RedirectsFromFile registers the redirects of the JSON or CSV file name in fsys, like Redirects.
+// JSON files hold an object mapping sources to targets. CSV files have a source and a target
+// per record, an optional header and lines starting with "#" are skipped.
+//
+// In Debug mode the file is read again on every redirect, so that changed targets apply without
+// a restart. Sources removed from the file answer 404 Not Found and new sources need a restart.
+func (r *Router) RedirectsFromFile(fsys fs.FS, name string, status ...int) error {
+	rules, err := readRedirects(fsys, name)
+	if err != nil {
+		return err
+	}
+
+	code := http.StatusMovedPermanently
+	if len(status) > 0 {
+		code = status[0]
+	}
+
+	location := callerLocation()
+	file := &redirectFile{fsys: fsys, name: name, rules: rules}
+	for _, source := range sortedKeys(rules) {
+		r.redirect(&redirectRule{source: source, target: rules[source], status: code, file: file}, location)
+	}
+	return nil
+}
+
+// redirectRule is a redirect registered with Redirects or RedirectsFromFile.
+type redirectRule struct {
+	source string
+	target string
+	status int
+	params []string      // placeholders of the source
+	file   *redirectFile // file the rule was loaded from, nil for Redirects
+}
+
+// redirectFile is a redirects file reloaded in Debug mode.
+type redirectFile struct {
+	fsys fs.FS
+	name string
+
+	mu    sync.Mutex
+	rules map[string]string
+}
+
+// target returns the current target of source, reloading the file. The loaded rules are kept if reading fails.
+func (f *redirectFile) target(c *Context, source string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	rules, err := readRedirects(f.fsys, f.name)
+	if err != nil {
+		c.router.logger.Error("failed to reload redirects", "error", err, "file", f.name)
+	} else {
+		f.rules = rules
+	}
+
+	target, ok := f.rules[source]
+	return target, ok
+}
+
+// redirect validates rule and registers its route.
+func (r *Router) redirect(rule *redirectRule, location string) {
+	params, err := patternParams(rule.source)
+	if err != nil {
+		panic(fmt.Sprintf("rex: redirect %q: %v", rule.source, err))
+	}
+	rule.params = params
+
+	if err := rule.checkTarget(rule.target); err != nil {
+		panic(err.Error())
+	}
+
+	name := fmt.Sprintf("redirect %q -> %q", rule.source, rule.target)
+	if rule.file != nil {
+		name += " (" + rule.file.name + ")"
+	}
+
+	methods := []string{http.MethodGet}
+	if !r.autoHEAD {
+		methods = append(methods, http.MethodHead)
+	}
+
+	for _, method := range methods {
+		pattern := normalizePattern(rule.source, false)
+		if existing := r.conflictingRoute(method, pattern); existing != nil {
+			r.registrationFailed(fmt.Errorf("rex: duplicate route %q: %s registered at %s conflicts with %s registered at %s",
+				method+" "+pattern, name, location, existing.handlerName(), existing.location))
+			continue
+		}
+
+		rt := r.handle(method, rule.source, rule.serve, false)
+		rt.name = name
+		rt.location = location
+	}
+}
+
+// checkTarget returns an error if target uses placeholders missing from the source.
+func (rule *redirectRule) checkTarget(target string) error {
+	for _, m := range redirectPlaceholder.FindAllStringSubmatch(target, -1) {
+		if name := strings.TrimSuffix(m[1], "..."); !slices.Contains(rule.params, name) {
+			return fmt.Errorf("rex: redirect %q -> %q: the target uses {%s} but the source has no such placeholder",
+				rule.source, target, m[1])
+		}
+	}
+	return nil
+}
+
+// serve redirects the request to the target of the rule.
+func (rule *redirectRule) serve(c *Context) error {
+	target := rule.target
+	if rule.file != nil && Debug {
+		var ok bool
+		if target, ok = rule.file.target(c, rule.source); !ok {
+			return ErrNotFound
+		}
+
+		if err := rule.checkTarget(target); err != nil {
+			return err
+		}
+	}
+
+	// The escaped values are used as is, so that encoded slashes stay encoded.
+	target = redirectPlaceholder.ReplaceAllStringFunc(target, func(placeholder string) string {
+		return c.ParamRaw(strings.TrimSuffix(placeholder[1:len(placeholder)-1], "..."))
+	})
+
+	if query := c.Request.URL.RawQuery; query != "" {
+		if strings.Contains(target, "?") {
+			target += "&" + query
+		} else {
+			target += "?" + query
+		}
+	}
+	return c.Redirect(target, rule.status)
+}
+
+// conflictingRoute returns the route registered for method with the same pattern,
+// ignoring the names of the placeholders, or nil. Automatic routes are replaced and do not conflict.
+func (r *Router) conflictingRoute(method, pattern string) *Route {
+	shape := patternShape(pattern)
+	for _, route := range r.routes {
+		if route.method == method && !route.automatic && patternShape(route.pattern) == shape {
+			return route
+		}
+	}
+	return nil
+}
+
+// patternShape returns pattern with the names of its placeholders removed.
+func patternShape(pattern string) string {
+	return redirectPlaceholder.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		switch {
+		case placeholder == "{$}":
+			return placeholder
+		case strings.HasSuffix(placeholder, "...}"):
+			return "{...}"
+		}
+		return "{}"
+	})
+}
+
+// readRedirects reads the rules of a JSON or CSV redirects file.
+func readRedirects(fsys fs.FS, name string) (map[string]string, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, fmt.Errorf("rex: reading redirects: %w", err)
+	}
+
+	rules := make(map[string]string)
+	switch strings.ToLower(path.Ext(name)) {
+	case ".json":
+		if err := json.Unmarshal(data, &rules); err != nil {
+			return nil, fmt.Errorf("rex: parsing redirects %s: %w", name, err)
+		}
+	case ".csv":
+		reader := csv.NewReader(strings.NewReader(string(data)))
+		reader.Comment = '#'
+		reader.FieldsPerRecord = 2
+		reader.TrimLeadingSpace = true
+
+		records, err := reader.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("rex: parsing redirects %s: %w", name, err)
+		}
+
+		for i, record := range records {
+			source, target := strings.TrimSpace(record[0]), strings.TrimSpace(record[1])
+			if i == 0 && !strings.HasPrefix(source, "/") {
+				continue // header
+			}
+
+			if _, ok := rules[source]; ok {
+				return nil, fmt.Errorf("rex: parsing redirects %s: duplicate source %q in record %d", name, source, i+1)
+			}
+			rules[source] = target
+		}
+	default:
+		return nil, fmt.Errorf("rex: redirects file %s must be .json or .csv", name)
+	}
+	return rules, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
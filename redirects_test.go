@@ -0,0 +1,136 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestRedirects(t *testing.T) {
+	r := rex.NewRouter()
+	r.Redirects(map[string]string{
+		"/blog/{slug}":        "/articles/{slug}",
+		"/old-docs/{rest...}": "/docs/{rest...}",
+		"/about-us":           "https://example.com/about?from=legacy",
+	})
+
+	tests := []struct {
+		path     string
+		location string
+	}{
+		{"/blog/hello-world", "/articles/hello-world"},
+		{"/blog/caf%C3%A9", "/articles/caf%C3%A9"},
+		{"/old-docs/guide/install?lang=go&v=2", "/docs/guide/install?lang=go&v=2"},
+		{"/about-us?ref=footer", "https://example.com/about?from=legacy&ref=footer"},
+	}
+
+	for _, tt := range tests {
+		res := r.Test(httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if res.Code != http.StatusMovedPermanently || res.Header("Location") != tt.location {
+			t.Errorf("%s: expected 301 to %s, got %d %q", tt.path, tt.location, res.Code, res.Header("Location"))
+		}
+	}
+
+	if res := r.Test(httptest.NewRequest(http.MethodHead, "/blog/x", nil)); res.Code != http.StatusMovedPermanently {
+		t.Errorf("expected HEAD requests to be redirected, got %d", res.Code)
+	}
+}
+
+func TestRedirectsStatus(t *testing.T) {
+	r := rex.NewRouter()
+	r.Redirects(map[string]string{"/promo": "/sale"}, http.StatusFound)
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/promo", nil)); res.Code != http.StatusFound {
+		t.Errorf("expected 302, got %d", res.Code)
+	}
+}
+
+func TestRedirectsConflict(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/blog/{id}", showPost)
+
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, `redirect "/blog/{slug}" -> "/articles/{slug}"`) || !strings.Contains(msg, "showPost") {
+			t.Errorf("expected a panic naming both registrants, got %q", msg)
+		}
+	}()
+	r.Redirects(map[string]string{"/blog/{slug}": "/articles/{slug}"})
+}
+
+func TestRouteConflictingWithRedirect(t *testing.T) {
+	r := rex.NewRouter()
+	r.Redirects(map[string]string{"/blog": "/articles"})
+
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, `redirect "/blog" -> "/articles"`) || !strings.Contains(msg, "showPost") {
+			t.Errorf("expected a panic naming both registrants, got %q", msg)
+		}
+	}()
+	r.GET("/blog", showPost)
+}
+
+func TestRedirectsUnknownPlaceholder(t *testing.T) {
+	defer func() {
+		if msg, _ := recover().(string); !strings.Contains(msg, "{id}") {
+			t.Errorf("expected a panic naming the placeholder, got %q", msg)
+		}
+	}()
+	rex.NewRouter().Redirects(map[string]string{"/blog/{slug}": "/articles/{id}"})
+}
+
+func TestRedirectsFromFile(t *testing.T) {
+	fsys := fstest.MapFS{
+		"redirects.csv":  {Data: []byte("source,target\n# moved in 2024\n/team,/about/team\n/jobs/{id},/careers/{id}\n")},
+		"redirects.json": {Data: []byte(`{"/pricing": "/plans"}`)},
+	}
+
+	r := rex.NewRouter()
+	if err := r.RedirectsFromFile(fsys, "redirects.csv"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.RedirectsFromFile(fsys, "redirects.json", http.StatusTemporaryRedirect); err != nil {
+		t.Fatal(err)
+	}
+
+	for path, want := range map[string]string{"/team": "/about/team", "/jobs/7": "/careers/7", "/pricing": "/plans"} {
+		if res := r.Test(httptest.NewRequest(http.MethodGet, path, nil)); res.Header("Location") != want {
+			t.Errorf("%s: expected a redirect to %s, got %d %q", path, want, res.Code, res.Header("Location"))
+		}
+	}
+
+	if err := r.RedirectsFromFile(fsys, "missing.csv"); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestRedirectsFromFileReloadsInDebug(t *testing.T) {
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	fsys := fstest.MapFS{"redirects.json": {Data: []byte(`{"/team": "/about/team", "/jobs": "/careers"}`)}}
+	r := rex.NewRouter()
+	if err := r.RedirectsFromFile(fsys, "redirects.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys["redirects.json"] = &fstest.MapFile{Data: []byte(`{"/team": "/people"}`)}
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/team", nil)); res.Header("Location") != "/people" {
+		t.Errorf("expected the changed target, got %q", res.Header("Location"))
+	}
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/jobs", nil)); res.Code != http.StatusNotFound {
+		t.Errorf("expected a removed source to answer 404, got %d", res.Code)
+	}
+}
+
+func showPost(c *rex.Context) error {
+	return c.String("post")
+}
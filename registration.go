@@ -0,0 +1,111 @@
+package rex
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strings"
+	"unicode"
+)
+
+// MustRegister controls how invalid route registrations are reported.
+// If true (the default), registering a duplicate route panics.
+// If false, the route is skipped, the error is logged and returned by r.RegistrationError().
+var MustRegister = true
+
+// callerLocation returns the file:line of the first caller outside the Router and Group methods.
+func callerLocation() string {
+	pcs := make([]uintptr, 16)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+
+	for {
+		frame, more := frames.Next()
+		if !isRegistrationFrame(frame.Function) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+func isRegistrationFrame(function string) bool {
+	const pkg = "github.com/abiiranathan/rex."
+	if !strings.HasPrefix(function, pkg) {
+		return false
+	}
+
+	name := strings.TrimPrefix(function, pkg)
+	return strings.HasPrefix(name, "(*Router).") || strings.HasPrefix(name, "(*Group).")
+}
+
+// validGroupPrefix normalizes a group prefix. It must start with "/" and
+// may not contain whitespace. Trailing slashes are removed.
+func validGroupPrefix(prefix string) string {
+	if !strings.HasPrefix(prefix, "/") {
+		panic(fmt.Sprintf("rex: invalid group prefix %q: must start with \"/\"", prefix))
+	}
+
+	if strings.IndexFunc(prefix, unicode.IsSpace) >= 0 {
+		panic(fmt.Sprintf("rex: invalid group prefix %q: must not contain whitespace", prefix))
+	}
+	return strings.TrimRight(prefix, "/")
+}
+
+// checkDuplicate reports an error if routePattern is already registered.
+// It returns false if the route must not be registered.
+func (r *Router) checkDuplicate(routePattern string, handler HandlerFunc, location string) bool {
+	existing, ok := r.routes[routePattern]
+	if !ok {
+		return true
+	}
+
+	r.registrationFailed(fmt.Errorf("rex: duplicate route %q: %s registered at %s conflicts with %s registered at %s",
+		routePattern, getFuncName(handler), location, existing.handlerName(), existing.location))
+	return false
+}
+
+// registrationFailed panics with err if MustRegister is set, otherwise it logs err
+// and records it for RegistrationError.
+func (r *Router) registrationFailed(err error) {
+	if MustRegister {
+		panic(err.Error())
+	}
+
+	r.logger.Error("route registration failed", "error", err)
+	r.registrationErrs = append(r.registrationErrs, err)
+}
+
+// warnStaticOverlap logs a warning when a static mount overlaps earlier routes.
+// Routes under the mount take precedence over the files they shadow.
+func (r *Router) warnStaticOverlap(prefix, location string) {
+	for _, route := range r.routes {
+		if route.method != http.MethodGet || !strings.HasPrefix(route.pattern, prefix) || route.pattern == prefix {
+			continue
+		}
+
+		kind := "route"
+		if route.static {
+			kind = "static mount"
+		}
+		r.logger.Warn("static mount overlaps an existing "+kind,
+			"mount", prefix, "mount_location", location,
+			"pattern", route.pattern, "location", route.location)
+	}
+
+	for _, route := range r.routes {
+		if route.static && route.pattern != prefix && strings.HasPrefix(prefix, route.pattern) {
+			r.logger.Warn("static mount shadows files of an earlier static mount",
+				"mount", prefix, "mount_location", location,
+				"shadowed", route.pattern, "location", route.location)
+		}
+	}
+}
+
+// RegistrationError returns the route registration errors collected when MustRegister is false.
+func (r *Router) RegistrationError() error {
+	return errors.Join(r.registrationErrs...)
+}
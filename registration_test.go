@@ -0,0 +1,112 @@
+package rex
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func listUsers(c *Context) error  { return c.String("list") }
+func listPeople(c *Context) error { return c.String("people") }
+
+func expectPanic(t *testing.T, fn func()) string {
+	t.Helper()
+	var msg string
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				msg, _ = r.(string)
+			}
+		}()
+		fn()
+	}()
+
+	if msg == "" {
+		t.Fatal("expected a panic")
+	}
+	return msg
+}
+
+func TestDuplicateRoutePanics(t *testing.T) {
+	r := NewRouter()
+	r.GET("/users", listUsers)
+
+	msg := expectPanic(t, func() {
+		r.GET("/users", listPeople)
+	})
+
+	for _, want := range []string{"GET /users", "listUsers", "listPeople", "registration_test.go:"} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected panic message to contain %q, got %q", want, msg)
+		}
+	}
+
+	// Group routes resolve to the same pattern.
+	api := r.Group("/api")
+	api.GET("/users", listUsers)
+	expectPanic(t, func() {
+		r.GET("/api/users", listPeople)
+	})
+}
+
+func TestDuplicateRouteWithoutMustRegister(t *testing.T) {
+	MustRegister = false
+	defer func() { MustRegister = true }()
+
+	r := NewRouter()
+	r.GET("/users", listUsers)
+	r.GET("/users", listPeople)
+
+	err := r.RegistrationError()
+	if err == nil || !strings.Contains(err.Error(), "listPeople") {
+		t.Fatalf("expected registration error, got %v", err)
+	}
+
+	// The first registration is kept.
+	res := r.Test(NewTestRequest(http.MethodGet, "/users").Build())
+	if res.BodyString() != "list" {
+		t.Errorf("expected first handler to be kept, got %q", res.BodyString())
+	}
+}
+
+func TestGroupPrefixValidation(t *testing.T) {
+	r := NewRouter()
+
+	for _, prefix := range []string{"admin", "", "/ad min", "/admin\t"} {
+		msg := expectPanic(t, func() { r.Group(prefix) })
+		if !strings.Contains(msg, "invalid group prefix") {
+			t.Errorf("prefix %q: unexpected panic %q", prefix, msg)
+		}
+	}
+
+	expectPanic(t, func() { r.Group("/admin").Group("users") })
+
+	// Trailing slashes are normalized.
+	admin := r.Group("/admin/")
+	admin.GET("/home", listUsers)
+
+	res := r.Test(NewTestRequest(http.MethodGet, "/admin/home").Build())
+	if res.Status() != http.StatusOK {
+		t.Errorf("expected status 200, got %d", res.Status())
+	}
+}
+
+func TestStaticOverlapWarning(t *testing.T) {
+	var logs bytes.Buffer
+	r := NewRouter(WithLogger(slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelWarn}))))
+
+	r.GET("/static/app.js", listUsers)
+	r.Static("/static", t.TempDir())
+	r.Static("/static/img", t.TempDir())
+
+	out := logs.String()
+	if !strings.Contains(out, "overlaps an existing route") {
+		t.Errorf("expected route overlap warning, got %q", out)
+	}
+
+	if !strings.Contains(out, "shadows files of an earlier static mount") {
+		t.Errorf("expected static mount warning, got %q", out)
+	}
+}
@@ -0,0 +1,66 @@
+package rex
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// LayoutSuffix is appended to the base layout name in render hooks, so the layout
+// and the page are measured separately e.g "base.html#layout" and "home.html".
+const LayoutSuffix = "#layout"
+
+// OnRenderStart registers fn to run before each template execution of Render and ExecuteTemplate.
+// Hooks run in registration order. Panics in hooks are recovered and logged.
+func OnRenderStart(fn func(c *Context, name string)) RouterOption {
+	return func(r *Router) {
+		r.renderStart = append(r.renderStart, fn)
+	}
+}
+
+// OnRenderEnd registers fn to run after each template execution of Render and ExecuteTemplate
+// with the duration and the execution error, if any. With a base layout, Render executes the
+// page and then the layout, reported as the layout name with LayoutSuffix.
+// Hooks run in registration order. Panics in hooks are recovered and logged.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.OnRenderEnd(func(c *rex.Context, name string, d time.Duration, err error) {
+//		renderDuration.WithLabelValues(name).Observe(d.Seconds())
+//	}))
+func OnRenderEnd(fn func(c *Context, name string, d time.Duration, err error)) RouterOption {
+	return func(r *Router) {
+		r.renderEnd = append(r.renderEnd, fn)
+	}
+}
+
+// executeTemplate executes the template name and runs the render hooks around it
+// with hookName.
+func (c *Context) executeTemplate(w io.Writer, name, hookName string, data Map) error {
+	if len(c.router.renderStart) == 0 && len(c.router.renderEnd) == 0 {
+		return c.router.template.ExecuteTemplate(w, name, data)
+	}
+
+	for _, hook := range c.router.renderStart {
+		c.runRenderHook(hookName, func() { hook(c, hookName) })
+	}
+
+	start := time.Now()
+	err := c.router.template.ExecuteTemplate(w, name, data)
+	elapsed := time.Since(start)
+
+	for _, hook := range c.router.renderEnd {
+		c.runRenderHook(hookName, func() { hook(c, hookName, elapsed, err) })
+	}
+	return err
+}
+
+// runRenderHook calls fn, recovering and logging a panic.
+func (c *Context) runRenderHook(name string, fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.router.logger.Error("panic in render hook", "template", name, "panic", fmt.Sprint(r))
+		}
+	}()
+	fn()
+}
@@ -0,0 +1,171 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// renderEvent is a call of a render hook.
+type renderEvent struct {
+	hook string
+	name string
+	d    time.Duration
+	err  error
+}
+
+type renderRecorder struct {
+	mu     sync.Mutex
+	events []renderEvent
+}
+
+func (r *renderRecorder) start(c *rex.Context, name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, renderEvent{hook: "start", name: name})
+}
+
+func (r *renderRecorder) end(c *rex.Context, name string, d time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, renderEvent{hook: "end", name: name, d: d, err: err})
+}
+
+func newHookRouter(t testing.TB, options ...rex.RouterOption) *rex.Router {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"base.html":   `<main>{{ .Content }}</main>`,
+		"home.html":   `<p>{{ .Title }}</p>`,
+		"broken.html": `<p>{{ .Title.Missing }}</p>`,
+		"plain.html":  `plain`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	options = append([]rex.RouterOption{
+		rex.WithTemplates(rex.Must(rex.ParseTemplates(dir, nil))),
+		rex.BaseLayout("base.html"),
+		rex.ContentBlock("Content"),
+	}, options...)
+
+	r := rex.NewRouter(options...)
+	r.GET("/home", func(c *rex.Context) error {
+		return c.Render("home", rex.Map{"Title": "Home"})
+	})
+
+	r.GET("/broken", func(c *rex.Context) error {
+		return c.Render("broken", rex.Map{"Title": "Home"})
+	})
+
+	r.GET("/plain", func(c *rex.Context) error {
+		return c.ExecuteTemplate("plain.html", rex.Map{})
+	})
+	return r
+}
+
+func TestRenderHooks(t *testing.T) {
+	rec := &renderRecorder{}
+	r := newHookRouter(t, rex.OnRenderStart(rec.start), rex.OnRenderEnd(rec.end))
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/home", nil))
+	if res.Code != http.StatusOK || res.Body.String() != "<main><p>Home</p></main>" {
+		t.Fatalf("unexpected response %d %q", res.Code, res.Body.String())
+	}
+
+	want := []renderEvent{
+		{hook: "start", name: "home.html"},
+		{hook: "end", name: "home.html"},
+		{hook: "start", name: "base.html" + rex.LayoutSuffix},
+		{hook: "end", name: "base.html" + rex.LayoutSuffix},
+	}
+
+	if len(rec.events) != len(want) {
+		t.Fatalf("expected %d events, got %+v", len(want), rec.events)
+	}
+
+	for i, ev := range rec.events {
+		if ev.hook != want[i].hook || ev.name != want[i].name {
+			t.Errorf("event %d: expected %s %s, got %s %s", i, want[i].hook, want[i].name, ev.hook, ev.name)
+		}
+
+		if ev.hook == "end" && (ev.d <= 0 || ev.err != nil) {
+			t.Errorf("event %d: expected a duration and no error, got %v %v", i, ev.d, ev.err)
+		}
+	}
+
+	rec.events = nil
+	r.Test(httptest.NewRequest(http.MethodGet, "/plain", nil))
+	if len(rec.events) != 2 || rec.events[1].name != "plain.html" {
+		t.Errorf("expected hooks for ExecuteTemplate, got %+v", rec.events)
+	}
+}
+
+func TestRenderHooksError(t *testing.T) {
+	rec := &renderRecorder{}
+	r := newHookRouter(t, rex.OnRenderEnd(rec.end))
+
+	r.Test(httptest.NewRequest(http.MethodGet, "/broken", nil))
+	if len(rec.events) != 1 {
+		t.Fatalf("expected the layout not to be rendered after an error, got %+v", rec.events)
+	}
+
+	ev := rec.events[0]
+	if ev.name != "broken.html" || ev.err == nil || !strings.Contains(ev.err.Error(), "Missing") {
+		t.Errorf("expected the execution error to be passed, got %+v", ev)
+	}
+}
+
+func TestRenderHooksOrderAndPanics(t *testing.T) {
+	var order []string
+	r := newHookRouter(t,
+		rex.OnRenderEnd(func(c *rex.Context, name string, d time.Duration, err error) {
+			order = append(order, "first "+name)
+			panic(errors.New("hook failed"))
+		}),
+		rex.OnRenderEnd(func(c *rex.Context, name string, d time.Duration, err error) {
+			order = append(order, "second "+name)
+		}),
+	)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/plain", nil))
+	if res.Code != http.StatusOK || res.Body.String() != "plain" {
+		t.Fatalf("expected a panicking hook not to affect the response, got %d %q", res.Code, res.Body.String())
+	}
+
+	if strings.Join(order, ",") != "first plain.html,second plain.html" {
+		t.Errorf("expected hooks in registration order, got %v", order)
+	}
+}
+
+func benchmarkRender(b *testing.B, options ...rex.RouterOption) {
+	r := newHookRouter(b, options...)
+	req := httptest.NewRequest(http.MethodGet, "/home", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+}
+
+func BenchmarkRenderWithoutHooks(b *testing.B) {
+	benchmarkRender(b)
+}
+
+func BenchmarkRenderWithHooks(b *testing.B) {
+	benchmarkRender(b, rex.OnRenderEnd(func(*rex.Context, string, time.Duration, error) {}))
+}
@@ -0,0 +1,140 @@
+package rex
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// ResponseBuilder collects the status, headers and cookies of a response and sends them
+// with the body in the correct order: headers and cookies first, then the status, then the body.
+// Create one with c.Respond and finish it with exactly one of JSON, XML, Text, HTML, Render or NoContent.
+type ResponseBuilder struct {
+	c       *Context
+	status  int
+	header  http.Header
+	cookies []*http.Cookie
+	sent    string // the terminal method that sent the response, "" if not sent
+}
+
+// Respond returns a builder for a response, avoiding ordering mistakes like
+// setting a header after the status was written.
+//
+// Example:
+//
+//	return c.Respond().Status(http.StatusCreated).Header("Location", "/users/1").JSON(user)
+func (c *Context) Respond() *ResponseBuilder {
+	return &ResponseBuilder{c: c, header: make(http.Header)}
+}
+
+// Status sets the status code of the response. The default is 200 OK.
+func (b *ResponseBuilder) Status(status int) *ResponseBuilder {
+	b.status = status
+	return b
+}
+
+// Header adds a value to the header key. The values replace the header of the same key
+// set on the response, including the Content-Type set by the terminal method.
+func (b *ResponseBuilder) Header(key, value string) *ResponseBuilder {
+	b.header.Add(key, value)
+	return b
+}
+
+// Cookie adds a Set-Cookie header sending cookie.
+func (b *ResponseBuilder) Cookie(cookie *http.Cookie) *ResponseBuilder {
+	b.cookies = append(b.cookies, cookie)
+	return b
+}
+
+// JSON sends data as JSON, see Context.JSON.
+func (b *ResponseBuilder) JSON(data any) error {
+	return b.send("JSON", func() error { return b.c.JSON(data) })
+}
+
+// XML sends data as XML, see Context.XML.
+func (b *ResponseBuilder) XML(data any) error {
+	return b.send("XML", func() error { return b.c.XML(data) })
+}
+
+// Text sends text as text/plain, see Context.String.
+func (b *ResponseBuilder) Text(text string) error {
+	return b.send("Text", func() error { return b.c.String(text) })
+}
+
+// HTML sends html as text/html, see Context.HTML.
+func (b *ResponseBuilder) HTML(html string) error {
+	return b.send("HTML", func() error { return b.c.HTML(html) })
+}
+
+// Render renders the template name with data, see Context.Render.
+func (b *ResponseBuilder) Render(name string, data Map) error {
+	return b.send("Render", func() error { return b.c.Render(name, data) })
+}
+
+// NoContent sends the headers without a body, with status 204 No Content unless
+// another status was set with Status.
+func (b *ResponseBuilder) NoContent() error {
+	if b.status == 0 {
+		b.status = http.StatusNoContent
+	}
+	return b.send("NoContent", func() error {
+		b.c.Response.WriteHeader(b.status)
+		return nil
+	})
+}
+
+// send calls write with a writer that applies the headers and status right before the
+// body, so that they win over the defaults of write, e.g the Content-Type of JSON.
+func (b *ResponseBuilder) send(terminal string, write func() error) error {
+	if b.sent != "" {
+		return fmt.Errorf("rex: Respond().%s called after %s already sent the response", terminal, b.sent)
+	}
+	b.sent = terminal
+
+	if b.c.rw != nil && b.c.rw.WroteHeader() {
+		return fmt.Errorf("rex: Respond().%s called after the response header was written", terminal)
+	}
+
+	w := b.c.Response
+	b.c.Response = &builderWriter{ResponseWriter: w, builder: b}
+	defer func() { b.c.Response = w }()
+	return write()
+}
+
+// builderWriter applies the headers, cookies and status of a ResponseBuilder
+// when the status is written, explicitly or by the first write.
+type builderWriter struct {
+	http.ResponseWriter
+	builder *ResponseBuilder
+	applied bool
+}
+
+func (w *builderWriter) WriteHeader(status int) {
+	if !w.applied {
+		w.applied = true
+
+		header := w.Header()
+		for key, values := range w.builder.header {
+			header[key] = values
+		}
+
+		for _, cookie := range w.builder.cookies {
+			http.SetCookie(w.ResponseWriter, cookie)
+		}
+
+		if w.builder.status != 0 {
+			status = w.builder.status
+		}
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *builderWriter) Write(b []byte) (int, error) {
+	if !w.applied {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *builderWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
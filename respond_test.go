@@ -0,0 +1,131 @@
+package rex_test
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestRespondTerminals(t *testing.T) {
+	tmpl := template.Must(template.New("base.html").Parse(`<main>{{.Content}}</main>`))
+	template.Must(tmpl.New("user.html").Parse(`<p>{{.name}}</p>`))
+	r := rex.NewRouter(rex.WithTemplates(tmpl), rex.BaseLayout("base.html"), rex.ContentBlock("Content"))
+
+	type user struct {
+		Name string `json:"name" xml:"name"`
+	}
+
+	terminals := map[string]func(b *rex.ResponseBuilder) error{
+		"/json":   func(b *rex.ResponseBuilder) error { return b.JSON(user{"ann"}) },
+		"/xml":    func(b *rex.ResponseBuilder) error { return b.XML(user{"ann"}) },
+		"/text":   func(b *rex.ResponseBuilder) error { return b.Text("ann") },
+		"/html":   func(b *rex.ResponseBuilder) error { return b.HTML("<p>ann</p>") },
+		"/render": func(b *rex.ResponseBuilder) error { return b.Render("user.html", rex.Map{"name": "ann"}) },
+	}
+
+	for path, terminal := range terminals {
+		r.POST(path, func(c *rex.Context) error {
+			return terminal(c.Respond().
+				Status(http.StatusCreated).
+				Header("Location", "/users/1").
+				Cookie(&http.Cookie{Name: "seen", Value: "1"}))
+		})
+	}
+
+	contentTypes := map[string]string{
+		"/json":   "application/json",
+		"/xml":    "application/xml",
+		"/text":   "text/plain",
+		"/html":   "text/html",
+		"/render": "text/html",
+	}
+
+	for path, contentType := range contentTypes {
+		res := r.Test(httptest.NewRequest(http.MethodPost, path, nil))
+		if res.Code != http.StatusCreated {
+			t.Errorf("%s: expected status 201, got %d", path, res.Code)
+		}
+
+		if res.Header("Location") != "/users/1" || !strings.HasPrefix(res.Header("Set-Cookie"), "seen=1") {
+			t.Errorf("%s: expected the header and cookie, got %v", path, res.Result().Header)
+		}
+
+		if got := res.Header("Content-Type"); !strings.HasPrefix(got, contentType) {
+			t.Errorf("%s: expected Content-Type %s, got %q", path, contentType, got)
+		}
+
+		if !strings.Contains(res.BodyString(), "ann") {
+			t.Errorf("%s: unexpected body %q", path, res.BodyString())
+		}
+	}
+}
+
+func TestRespondHeaderOverridesContentType(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/problem", func(c *rex.Context) error {
+		return c.Respond().
+			Status(http.StatusBadRequest).
+			Header("Content-Type", "application/problem+json").
+			JSON(rex.Map{"title": "invalid"})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/problem", nil))
+	if res.Code != http.StatusBadRequest || res.Header("Content-Type") != "application/problem+json" {
+		t.Errorf("expected 400 with the builder Content-Type, got %d %q", res.Code, res.Header("Content-Type"))
+	}
+}
+
+func TestRespondNoContent(t *testing.T) {
+	r := rex.NewRouter()
+	r.DELETE("/users/1", func(c *rex.Context) error {
+		return c.Respond().Header("X-Deleted", "1").NoContent()
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodDelete, "/users/1", nil))
+	if res.Code != http.StatusNoContent || res.Header("X-Deleted") != "1" || res.Body.Len() != 0 {
+		t.Errorf("expected an empty 204 with the header, got %d %v %q", res.Code, res.Result().Header, res.BodyString())
+	}
+}
+
+func TestRespondTwice(t *testing.T) {
+	r := rex.NewRouter()
+
+	var second error
+	r.GET("/", func(c *rex.Context) error {
+		b := c.Respond().Status(http.StatusAccepted)
+		if err := b.Text("first"); err != nil {
+			return err
+		}
+		second = b.JSON(rex.Map{"second": true})
+		return nil
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.Code != http.StatusAccepted || res.BodyString() != "first" {
+		t.Errorf("expected only the first response, got %d %q", res.Code, res.BodyString())
+	}
+
+	if second == nil || !strings.Contains(second.Error(), "JSON called after Text") {
+		t.Errorf("expected a descriptive error for the second terminal, got %v", second)
+	}
+}
+
+func TestRespondAfterWrite(t *testing.T) {
+	r := rex.NewRouter()
+
+	var err error
+	r.GET("/", func(c *rex.Context) error {
+		c.String("written")
+		err = c.Respond().Status(http.StatusCreated).Text("late")
+		return nil
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err == nil || res.BodyString() != "written" {
+		t.Errorf("expected an error and the first body, got %v %q", err, res.BodyString())
+	}
+}
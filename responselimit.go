@@ -0,0 +1,87 @@
+package rex
+
+import (
+	"io"
+	"net/http"
+)
+
+// MetaMaxResponseBytes is the route metadata key overriding WithMaxResponseBytes for a route.
+// Set it with route.MaxResponseBytes(n).
+const MetaMaxResponseBytes = "rex.max_response_bytes"
+
+// ErrResponseTooLarge is returned by writes that exceed the response size limit.
+// If nothing was sent yet, the error handler responds with it: 500 "response too large".
+var ErrResponseTooLarge = Error{Status: http.StatusInternalServerError, Message: "response too large"}
+
+// WithMaxResponseBytes limits the body of every response to n bytes, to stop a bug from
+// serializing a huge result into memory and the network. Writes past the limit fail with
+// ErrResponseTooLarge. If the header was not written yet, the response is replaced with
+// a 500 from the error handler, otherwise the connection is closed to abort the response.
+// Both cases are logged with the route pattern and byte count.
+// Streaming routes like downloads and server-sent events opt out with route.MaxResponseBytes(0).
+// n <= 0 disables the limit, which is the default.
+func WithMaxResponseBytes(n int64) RouterOption {
+	return func(r *Router) {
+		r.maxResponseBytes = n
+	}
+}
+
+// MaxResponseBytes overrides the WithMaxResponseBytes limit for the route.
+// n <= 0 disables the limit for the route.
+//
+// Example:
+//
+//	r.GET("/exports/{id}", downloadExport).MaxResponseBytes(0)
+func (rt *Route) MaxResponseBytes(n int64) *Route {
+	return rt.Meta(MetaMaxResponseBytes, n)
+}
+
+// responseLimit returns the response size limit of the route.
+func (rt *Route) responseLimit(routerLimit int64) int64 {
+	if v, ok := rt.GetMeta(MetaMaxResponseBytes); ok {
+		if n, ok := v.(int64); ok {
+			return n
+		}
+	}
+	return routerLimit
+}
+
+// exceedsLimit reports whether writing n more bytes exceeds the limit and records it.
+func (w *ResponseWriter) exceedsLimit(n int) bool {
+	if w.limit <= 0 {
+		return false
+	}
+
+	if w.exceeded || int64(w.size+n) > w.limit {
+		w.exceeded = true
+		w.attempted = max(w.attempted, int64(w.size+n))
+		return true
+	}
+	return false
+}
+
+// limitedReadFrom copies r through Write so the limit is enforced.
+func (w *ResponseWriter) limitedReadFrom(r io.Reader) (int64, error) {
+	// Hide ReadFrom from io.Copy to avoid recursion.
+	return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+// abortOversized handles a response that exceeded its size limit.
+// It returns ErrResponseTooLarge for the error handler if nothing was sent,
+// and aborts the connection otherwise.
+func (r *Router) abortOversized(c *Context) error {
+	r.logger.Error("response too large",
+		"pattern", c.Pattern(), "path", c.Request.URL.Path,
+		"limit", c.rw.limit, "bytes", c.rw.attempted, "written", c.rw.size)
+
+	if !c.rw.statusSent {
+		// Headers like Content-Length describe the discarded body.
+		c.rw.Header().Del("Content-Length")
+		// Let the error handler write its response.
+		c.rw.limit, c.rw.exceeded = 0, false
+		return ErrResponseTooLarge
+	}
+
+	// Closes the connection so the client sees an incomplete response.
+	panic(http.ErrAbortHandler)
+}
@@ -0,0 +1,134 @@
+package rex_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func newLimitedRouter(logs *bytes.Buffer) *rex.Router {
+	logger := slog.New(slog.NewTextHandler(logs, nil))
+	r := rex.NewRouter(rex.WithMaxResponseBytes(64), rex.WithLogger(logger))
+
+	r.GET("/small", func(c *rex.Context) error {
+		return c.String("ok")
+	})
+
+	r.GET("/table", func(c *rex.Context) error {
+		rows := make([]string, 100)
+		for i := range rows {
+			rows[i] = "row"
+		}
+		return c.JSON(rows)
+	})
+
+	r.GET("/stream", func(c *rex.Context) error {
+		for i := 0; i < 10; i++ {
+			if _, err := c.Response.Write(bytes.Repeat([]byte("x"), 16)); err != nil {
+				return err
+			}
+			c.Response.(http.Flusher).Flush()
+		}
+		return nil
+	})
+
+	r.GET("/download", func(c *rex.Context) error {
+		_, err := io.Copy(c.Response, strings.NewReader(strings.Repeat("d", 1024)))
+		return err
+	}).MaxResponseBytes(0)
+
+	r.GET("/report", func(c *rex.Context) error {
+		return c.String(strings.Repeat("r", 100))
+	}).MaxResponseBytes(128)
+	return r
+}
+
+func TestMaxResponseBytesReplacesResponse(t *testing.T) {
+	var logs bytes.Buffer
+	r := newLimitedRouter(&logs)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/table", nil))
+	if res.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status 500, got %d", res.Code)
+	}
+
+	if !strings.Contains(res.Body.String(), "response too large") || strings.Contains(res.Body.String(), "row") {
+		t.Errorf("expected the body to be replaced, got %q", res.Body.String())
+	}
+
+	if cl := res.Header("Content-Length"); cl != "" && cl != strconv.Itoa(res.Body.Len()) {
+		t.Errorf("expected no stale Content-Length, got %q", cl)
+	}
+
+	if !strings.Contains(logs.String(), "pattern=/table") {
+		t.Errorf("expected the route to be logged, got %q", logs.String())
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/small", nil))
+	if res.Code != http.StatusOK || res.Body.String() != "ok" {
+		t.Errorf("expected small responses to pass, got %d %q", res.Code, res.Body.String())
+	}
+}
+
+func TestMaxResponseBytesAbortsStream(t *testing.T) {
+	var logs bytes.Buffer
+	srv := httptest.NewServer(newLimitedRouter(&logs))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err == nil {
+		t.Errorf("expected the stream to be cut off, read %d bytes", len(body))
+	}
+
+	if len(body) > 64 {
+		t.Errorf("expected at most 64 bytes, got %d", len(body))
+	}
+
+	// Close waits for the handler, which logs from the server goroutine.
+	srv.Close()
+	if !strings.Contains(logs.String(), "pattern=/stream") || !strings.Contains(logs.String(), "bytes=80") {
+		t.Errorf("expected the route and byte count to be logged, got %q", logs.String())
+	}
+}
+
+func TestMaxResponseBytesRouteOverride(t *testing.T) {
+	var logs bytes.Buffer
+	r := newLimitedRouter(&logs)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/download", nil))
+	if res.Code != http.StatusOK || res.Body.Len() != 1024 {
+		t.Errorf("expected the opted-out route to stream 1024 bytes, got %d with %d bytes", res.Code, res.Body.Len())
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/report", nil))
+	if res.Code != http.StatusOK || res.Body.Len() != 100 {
+		t.Errorf("expected the route limit to allow 100 bytes, got %d with %d bytes", res.Code, res.Body.Len())
+	}
+
+	if logs.Len() != 0 {
+		t.Errorf("expected nothing to be logged, got %q", logs.String())
+	}
+}
+
+func TestMaxResponseBytesHead(t *testing.T) {
+	var logs bytes.Buffer
+	r := newLimitedRouter(&logs)
+
+	res := r.Test(httptest.NewRequest(http.MethodHead, "/table", nil))
+	if res.Code != http.StatusOK {
+		t.Errorf("expected HEAD requests not to count the skipped body, got %d", res.Code)
+	}
+}
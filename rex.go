@@ -24,13 +24,20 @@ a standard function that wraps rex.Handler.
 package rex
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"log/slog"
+	"math/rand"
 	"net/http"
+	"net/netip"
 	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
@@ -62,6 +69,25 @@ var (
 
 	// MinExtensions is the slice of file extensions for which minified files are served.
 	MinExtensions = []string{".js", ".css"}
+
+	// ServeDotfiles when set to true, Static and StaticFS serve files and directories
+	// whose name starts with a dot like .env and .git. These are denied by default.
+	ServeDotfiles = false
+
+	// AllowSymlinksOutsideRoot when set to true, Static serves files reached through
+	// symlinks that point outside the static directory.
+	AllowSymlinksOutsideRoot = false
+
+	// DisableDirListing when set to true, Static and StaticFS respond with 404 Not Found
+	// for directories without an index.html instead of listing their contents.
+	DisableDirListing = false
+
+	// Debug enables development checks. When true, contexts returned to the pool
+	// are poisoned so that use after the request completes panics loudly, and
+	// the default error handler renders a detailed error page with the stack trace
+	// and request dump for 500 errors. The stack of the first write of each response
+	// is recorded for c.AssertNotWritten. Never enable in production.
+	Debug = false
 )
 
 // HandlerFunc is the signature for route handlers that can return errors
@@ -94,6 +120,7 @@ func (r *Router) ToHTTPHandler(h HandlerFunc) http.Handler {
 		if err := h(ctx); err != nil {
 			r.errorHandler(ctx, err)
 		}
+		ctx.runFinished()
 	})
 }
 
@@ -125,13 +152,14 @@ func (router *Router) WrapMiddleware(middleware func(http.Handler) http.Handler)
 // Router is the main router structure
 type Router struct {
 	mux               *http.ServeMux        // http.ServeMux
-	routes            map[string]route      // map of routes
+	routes            map[string]*Route     // map of routes
 	globalMiddlewares []Middleware          // global middlewares
 	errorHandler      func(*Context, error) // centralized error handler
 
 	// Configuration for templates
 	viewsFs            fs.FS              // Views embed.FS(Alternative to views if set)
 	template           *template.Template // All parsed templates
+	templateInfo       *templateSetInfo   // Partials and files of a set parsed by ParseTemplates
 	baseLayout         string             // Base layout for the templates(default is "")
 	contentBlock       string             // Content block for the templates(default is "Content")
 	errorTemplate      string             // Error template. Passed "error", "status", "status_text" in its context.
@@ -143,6 +171,7 @@ type Router struct {
 	// Handler for 404 not found errors. Note that when this is called,
 	// The request parameters are not available, since they are populated by the http.ServeMux
 	// when the request is matched to a route. So calling r.PathValue() will return "".
+	// The default error handler calls it for ErrNotFound, including files missing in static mounts.
 	NotFoundHandler http.Handler
 
 	// Validator instance
@@ -151,14 +180,198 @@ type Router struct {
 	// universal translator
 	translator ut.Translator
 
+	// Formats validation errors for HandleValidationErrors
+	validationErrorFormatter ValidationErrorFormatter
+
+	// Codecs registered with RegisterCodec, by content type
+	codecs map[string]Codec
+
 	// Logger
 	logger *slog.Logger
+
+	// Hosts allowed as SafeRedirect targets in addition to the request host.
+	redirectHosts []string
+
+	// HMAC key for signing flash message cookies.
+	flashKey []byte
+
+	// Base path set with WithBasePath and proxies trusted with TrustForwardedPrefix.
+	basePath       string
+	trustedProxies []netip.Prefix
+
+	// Path normalization settings. See NormalizePaths.
+	normalizePaths    bool
+	pathNormalization PathNormalization
+	serverTiming      bool
+	assets            *assetManifest
+	viewUserFunc      func(c *Context) any
+	registrationErrs  []error
+	renderETags       bool
+	multipartMemory   int64
+	dirListing        bool
+	dirListTemplate   string
+	decompressLimits  DecompressLimits
+	headerPolicy      *headerPolicy
+
+	templateMissingKey string
+	maxResponseBytes   int64 // Response size limit, 0 if unlimited
+
+	// Outbound requests built with c.NewRequest and c.Fetch.
+	outboundHeaders []string
+	outboundClient  *http.Client
+
+	// Source of LogSampling draws set with WithLogSamplingRand, guarded by samplingMu.
+	samplingRand *rand.Rand
+	samplingMu   sync.Mutex
+
+	// Hooks registered with OnErrorRateExceeded.
+	errorRateHooks []errorRateHook
+
+	// Event bus returned by Events.
+	events *EventBus
+
+	// Set by Freeze, registering routes afterwards panics.
+	frozen bool
+
+	// Uploads tracked with TrackUploadProgress.
+	uploads *uploadRegistry
+
+	// Build information set with WithBuildInfo, served by AdminHandler.
+	buildInfo BuildInfo
+
+	// JSON response settings, see JSONNoSniff and JSONPrefix.
+	jsonNoSniff bool
+	jsonPrefix  []byte
+
+	// Hooks registered with OnRenderStart and OnRenderEnd.
+	renderStart []func(c *Context, name string)
+	renderEnd   []func(c *Context, name string, d time.Duration, err error)
+
+	// GET routes also serve HEAD requests, see AutoHEAD.
+	autoHEAD bool
+
+	// Reading the body sends 100 Continue, see AutoContinue.
+	autoContinue bool
+
+	// Encoded slashes in path parameters are decoded, see AllowEncodedSlashes.
+	allowEncodedSlashes bool
+
+	// Log field names, valuers and time format, see WithLogFieldNames.
+	logFieldNames   map[LogField]string
+	logValuers      map[LogField]LogValuer
+	logTimeLayout   string
+	logTimeLocation *time.Location
+}
+
+// Route is a registered route. It is returned by the route registration methods
+// and can be used to attach metadata to the route.
+type Route struct {
+	method      string         // http method
+	pattern     string         // pattern without the method
+	prefix      string         // method + pattern
+	handler     HandlerFunc    // handler function as registered
+	name        string         // describes handlers that are not plain functions, like redirects
+	group       string         // prefix of the group the route was registered on
+	final       HandlerFunc    // handler wrapped with all middlewares
+	middlewares []Middleware   // middlewares for the route
+	meta        map[string]any // route metadata
+	static      bool           // static file mount
+	location    string         // file:line where the route was registered
+	chain       []Middleware   // global and route middlewares applied at registration
+	inner       []Middleware   // middlewares added with the route builder, innermost
+	pageCache   *pageCache     // rendered page cache enabled with StaticFor
+	stats       *routeStats    // error counters, see ErrorStats
+	automatic   bool           // registered by the router, replaced by an application route
+}
+
+// MetaSkipCompression is the route metadata key that tells compression middleware
+// to leave the response untouched. Set it with route.Meta(rex.MetaSkipCompression, true),
+// or to a func(*rex.Context) bool deciding per request. Check it with SkipCompression.
+const MetaSkipCompression = "rex.skip_compression"
+
+// SkipCompression reports whether the MetaSkipCompression metadata of the route
+// tells compression middleware to leave the response of the request untouched.
+func (c *Context) SkipCompression() bool {
+	switch skip, _ := c.RouteMeta(MetaSkipCompression); skip := skip.(type) {
+	case bool:
+		return skip
+	case func(*Context) bool:
+		return skip(c)
+	}
+	return false
+}
+
+// Context keys coordinating compression and ETag middleware, in either order.
+const (
+	// ContentEncodingKey is set with c.Set by compression middleware to the content coding
+	// applied to the response, e.g "br". The etag middleware appends it to the ETag
+	// so that each encoding of a resource has its own validator, e.g "abc123-br".
+	ContentEncodingKey = "rex.content_encoding"
+
+	// IdentityWriterKey is set with c.Set to an io.Writer by middleware that needs the
+	// uncompressed response body, like etag. Compression middleware running inside it
+	// writes the body to it before encoding.
+	IdentityWriterKey = "rex.identity_writer"
+)
+
+// Meta attaches a metadata value to the route.
+// Middleware can read it with c.RouteMeta(key).
+func (rt *Route) Meta(key string, value any) *Route {
+	if rt.meta == nil {
+		rt.meta = make(map[string]any)
+	}
+	rt.meta[key] = value
+	return rt
+}
+
+// handlerName returns the name of the route handler for registration errors and route listings.
+func (rt *Route) handlerName() string {
+	if rt.name != "" {
+		return rt.name
+	}
+	return getFuncName(rt.handler)
+}
+
+// Method returns the http method of the route.
+func (rt *Route) Method() string {
+	return rt.method
+}
+
+// Pattern returns the registered pattern of the route without the method.
+func (rt *Route) Pattern() string {
+	return rt.pattern
 }
 
-type route struct {
-	prefix      string       // method + pattern
-	handler     HandlerFunc  // handler function
-	middlewares []Middleware // middlewares for the route
+// Middleware adds middlewares to the route, closest to the handler.
+// They run after the global, group and route registration middlewares, see Router.Use.
+func (rt *Route) Middleware(middlewares ...Middleware) *Route {
+	rt.use(middlewares...)
+	return rt
+}
+
+// use adds middleware after the registration-time chain, closest to the handler.
+func (rt *Route) use(middlewares ...Middleware) {
+	rt.inner = append(rt.inner, middlewares...)
+	rt.build()
+}
+
+// build wraps the handler with the inner middlewares, then the registration-time chain.
+func (rt *Route) build() {
+	final := rt.handler
+	for i := len(rt.inner) - 1; i >= 0; i-- {
+		final = wrapMiddleware(rt.inner[i], final)
+	}
+
+	for i := len(rt.chain) - 1; i >= 0; i-- {
+		final = wrapMiddleware(rt.chain[i], final)
+	}
+	rt.final = final
+}
+
+// GetMeta returns the metadata value stored under key.
+func (rt *Route) GetMeta(key string) (any, bool) {
+	value, ok := rt.meta[key]
+	return value, ok
 }
 
 // Router option a function option for configuring the router.
@@ -183,14 +396,15 @@ func (c *Context) GetLogger() *slog.Logger {
 // defaultErrorHandler is the default error handler for the router.
 // It handles errors centrally and logs and writes the error to the response.
 // The logger can be replaced with a custom logger using WithLogger option.
-// It also handles validation errors and form errors.
+// It also handles validation errors, form errors and joined errors (see Errors).
 // The default error handler can be replaced with a custom error handler using SetErrorHandler.
 func defaultErrorHandler(ctx *Context, err error) {
 	defer func() {
 		// Log the error on exit to ensure that the correct status code is set.
-		ctx.router.logger.Debug("ERROR", "error", err, "status",
-			ctx.Response.(*ResponseWriter).Status(),
-			"path", ctx.Request.URL.Path)
+		if !ctx.ShouldLog(err) {
+			return
+		}
+		ctx.router.logger.Debug("ERROR", ctx.requestLogArgs(err)...)
 	}()
 
 	// We must return early if there is no error.
@@ -198,16 +412,90 @@ func defaultErrorHandler(ctx *Context, err error) {
 		return
 	}
 
-	if ve, ok := err.(validator.ValidationErrors); ok {
+	// Nobody is left to read the response.
+	if errors.Is(err, ErrClientGone) {
+		return
+	}
+
+	// The status or body has already been sent, writing an error response would corrupt it.
+	if ctx.Written() || ctx.rw.statusSent {
+		ctx.router.logger.Error("error after response was written", ctx.requestLogArgs(err)...)
+		return
+	}
+
+	// HEAD requests get the status and headers of the error response without the body,
+	// like statuses without a body such as 204 and 304.
+	if ctx.Request.Method == http.MethodHead {
+		ctx.rw.skipBody = true
+		ctx.rw.onBeforeWrite(func() {
+			ctx.rw.Header().Del("Content-Length")
+		})
+	}
+
+	if items, status, ok := joinedErrorItems(ctx, err); ok {
+		HandleJoinedErrors(ctx, err, items, status)
+		return
+	}
+
+	var ve validator.ValidationErrors
+	if errors.As(err, &ve) {
 		HandleValidationErrors(ctx, ve)
 		return
 	}
 
-	if fe, ok := err.(FormError); ok {
+	var fe FormError
+	if errors.As(err, &fe) {
 		HandleFormErrors(ctx, fe)
 		return
 	}
 
+	var pe ParamError
+	if errors.As(err, &pe) {
+		HandleParamErrors(ctx, pe)
+		return
+	}
+
+	var jpe JSONPatchError
+	if errors.As(err, &jpe) {
+		HandleJSONPatchErrors(ctx, jpe)
+		return
+	}
+
+	var ptl PartTooLargeError
+	if errors.As(err, &ptl) {
+		HandleFormErrors(ctx, FormError{Err: ptl, Kind: BodyTooLarge, Field: ptl.Field})
+		return
+	}
+
+	var re Error
+	if errors.As(err, &re) {
+		ctx.WriteHeader(re.Status)
+		ctx.Write([]byte(re.Message))
+		return
+	}
+
+	var le LoadError
+	if errors.Is(err, ErrNotFound) || (errors.As(err, &le) && le.Status() == http.StatusNotFound) {
+		if ctx.router.NotFoundHandler != nil {
+			ctx.router.NotFoundHandler.ServeHTTP(ctx.Response, ctx.Request)
+			return
+		}
+		ctx.WriteHeader(http.StatusNotFound)
+		ctx.Write([]byte(http.StatusText(http.StatusNotFound)))
+		return
+	}
+
+	if errors.Is(err, ErrInvalidSignature) || errors.Is(err, ErrSignatureExpired) {
+		HandleSignatureErrors(ctx, err)
+		return
+	}
+
+	// Never reached in production, the debug page exposes request internals.
+	if Debug {
+		ctx.renderDebugError(err)
+		return
+	}
+
 	ctx.WriteHeader(http.StatusInternalServerError)
 	ctx.Write([]byte(err.Error()))
 }
@@ -221,7 +509,7 @@ func defaultErrorHandler(ctx *Context, err error) {
 func NewRouter(options ...RouterOption) *Router {
 	r := &Router{
 		mux:                http.NewServeMux(),
-		routes:             make(map[string]route),
+		routes:             make(map[string]*Route),
 		passContextToViews: false,
 		baseLayout:         "",
 		contentBlock:       contentBlock,
@@ -230,14 +518,25 @@ func NewRouter(options ...RouterOption) *Router {
 		groups:             make(map[string]*Group),
 		globalMiddlewares:  []Middleware{},
 		validator:          validator.New(validator.WithRequiredStructEnabled()),
-		logger: slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
-			AddSource: false,
-			Level:     slog.LevelError,
-		})),
 
 		// Global error handler function.
-		errorHandler: defaultErrorHandler,
+		errorHandler:     defaultErrorHandler,
+		flashKey:         randomKey(32),
+		multipartMemory:  DefaultMultipartMemory,
+		decompressLimits: DefaultDecompressLimits,
+		outboundHeaders:  DefaultOutboundHeaders,
+		autoHEAD:         true,
+		autoContinue:     true,
+
+		validationErrorFormatter: DefaultValidationErrorFormatter,
 	}
+	r.logger = slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{
+		AddSource:   false,
+		Level:       slog.LevelError,
+		ReplaceAttr: r.ReplaceLogAttr,
+	}))
+	r.events = newEventBus(r)
+	r.uploads = newUploadRegistry()
 
 	// Create translator
 	en := en.New()
@@ -248,9 +547,14 @@ func NewRouter(options ...RouterOption) *Router {
 	en_translations.RegisterDefaultTranslations(r.validator, trans)
 	r.translator = trans
 
+	// Report json or form tag names in validation errors
+	r.validator.RegisterTagNameFunc(validationTagName)
+
 	for _, option := range options {
 		option(r)
 	}
+
+	r.bindTemplateFuncs()
 	return r
 }
 
@@ -277,7 +581,16 @@ func (r *Router) SetErrorHandler(handler func(*Context, error)) {
 	r.errorHandler = handler
 }
 
-// Global middleware
+// Use adds global middlewares, applied to the routes registered afterwards.
+//
+// Middlewares run in this order, each wrapping the ones after it:
+//   - global middlewares, in the order of the calls to Use.
+//   - group middlewares, from the outermost group to the innermost.
+//   - middlewares passed when registering the route, e.g r.GET(path, handler, m1, m2).
+//   - middlewares added with the route builder, e.g r.GET(path, handler).Middleware(m3).
+//
+// In Debug mode, a panic in a middleware is logged with the name of the middleware,
+// then rethrown to the recovery middleware.
 func (r *Router) Use(middlewares ...Middleware) {
 	r.globalMiddlewares = append(r.globalMiddlewares, middlewares...)
 }
@@ -297,8 +610,18 @@ func (r *Router) getContext() *Context {
 }
 
 // Put the context back in the pool.
+// In Debug mode, the context is poisoned instead and never reused, so that any
+// later use (e.g from a goroutine that outlived the request) panics.
 func (r *Router) PutContext(c *Context) {
+	if c.rw != nil {
+		c.rw.writeTrailers()
+	}
+	c.removeMultipartFiles()
 	c.reset()
+	if Debug {
+		c.released = true
+		return
+	}
 	ctxPool.Put(c)
 }
 
@@ -306,11 +629,20 @@ func (r *Router) PutContext(c *Context) {
 func (r *Router) InitContext(w http.ResponseWriter, req *http.Request) *Context {
 	c := r.getContext()
 	c.Request = req
-	c.Response = &ResponseWriter{
+	c.rw = &ResponseWriter{
 		writer: w,
 		status: http.StatusOK,
 	}
+	c.Response = c.rw
 	c.router = r
+	c.startTime = time.Now()
+	if r.serverTiming {
+		c.installServerTiming()
+	}
+
+	if r.headerPolicy != nil {
+		c.applyHeaderPolicy(r.headerPolicy)
+	}
 	return c
 }
 
@@ -318,65 +650,122 @@ func (r *Router) InitContext(w http.ResponseWriter, req *http.Request) *Context
 func (c *Context) reset() {
 	c.Request = nil
 	c.Response = nil
+	c.rw = nil
 	c.router = nil
+	c.currentRoute = nil
+	c.timings = nil
+	c.timingsSent = false
+	c.timezone = nil
+	c.logSample = 0
+	c.onFinished = nil
+	c.query = nil
+	c.queryRaw = ""
+	c.patchedKeys = nil
+	c.panicReported = false
+	c.continueGate = nil
 	c.locals = make(map[any]any)
 }
 
-// handle registers a new route with the given path and handler
-func (r *Router) handle(method, pattern string, handler HandlerFunc, is_static bool, middlewares ...Middleware) {
-	if StrictHome && pattern == "/" {
+// normalizePattern applies StrictHome and NoTrailingSlash to a route pattern.
+func normalizePattern(pattern string, isStatic bool) string {
+	// Static mounts at "/" match every path except the home route, which is "/{$}".
+	if StrictHome && pattern == "/" && !isStatic {
 		pattern = pattern + "{$}" // Match only the root pattern
 	}
 
 	// remove trailing slashes if not a static route
-	if !is_static {
+	if !isStatic {
 		if NoTrailingSlash && pattern != "/" {
 			pattern = strings.TrimSuffix(pattern, "/")
 		}
 	}
+	return pattern
+}
 
-	// Combine global and route-specific middlewares
-	allMiddleware := append(r.globalMiddlewares, middlewares...)
+// handle registers a new route with the given path and handler
+func (r *Router) handle(method, pattern string, handler HandlerFunc, is_static bool, middlewares ...Middleware) *Route {
+	r.checkFrozen(method + " " + pattern)
+	pattern = normalizePattern(pattern, is_static)
 
-	// Chain all middleware
-	final := handler
-	for i := len(allMiddleware) - 1; i >= 0; i-- {
-		final = allMiddleware[i](final)
-	}
+	// Combine global and route-specific middlewares into a new slice. Appending to
+	// r.globalMiddlewares could share its backing array between routes.
+	allMiddleware := slices.Concat(r.globalMiddlewares, middlewares)
 
 	// Store the route
 	routePattern := method + " " + pattern
-	r.routes[routePattern] = route{
+	rt := &Route{
+		method:      method,
+		pattern:     pattern,
 		prefix:      routePattern,
-		handler:     final,
-		middlewares: middlewares,
+		handler:     handler,
+		middlewares: slices.Clone(middlewares),
+		static:      is_static,
+		location:    callerLocation(),
+		chain:       allMiddleware,
+		stats:       newRouteStats(len(r.errorRateHooks)),
 	}
+	rt.build()
+
+	// Routes added by the router, like the redirect of a bare static prefix, yield to the
+	// application. The mux already serves the existing route, so it takes the new one over.
+	if existing, ok := r.routes[routePattern]; ok && existing.automatic {
+		*existing = *rt
+		return existing
+	}
+
+	if !r.checkDuplicate(routePattern, handler, rt.location) {
+		return rt
+	}
+
+	if is_static {
+		r.warnStaticOverlap(pattern, rt.location)
+	}
+	r.routes[routePattern] = rt
 
 	r.mux.HandleFunc(routePattern, func(w http.ResponseWriter, req *http.Request) {
 		start := time.Now()
 
 		ctx := r.InitContext(w, req)
 		defer r.PutContext(ctx)
+		ctx.currentRoute = rt
+		ctx.startTime = start
+		if !r.autoContinue {
+			ctx.gateContinue()
+		}
 
-		var skipBody bool
 		if req.Method != method {
 			// Allow HEAD requests for GET routes as this is allowed by the new Go 1.22 router.
-			allowed := method == http.MethodGet && req.Method == http.MethodHead
+			allowed := r.autoHEAD && method == http.MethodGet && req.Method == http.MethodHead
 			if !allowed {
 				ctx.WriteHeader(http.StatusMethodNotAllowed)
 				r.errorHandler(ctx, fmt.Errorf("method not allowed"))
+				ctx.runFinished()
 				return
 			}
 
 			// Skip the body for HEAD requests
-			skipBody = true
+			ctx.rw.skipBody = true
 		}
 
-		// Router logic
-		ctx.Response.(*ResponseWriter).skipBody = skipBody
+		ctx.rw.limit = rt.responseLimit(r.maxResponseBytes)
+
+		rt.stats.inFlight.Add(1)
+		defer rt.stats.inFlight.Add(-1)
 
 		// Execute the handler and handle any errors
-		err := final(ctx)
+		err := rt.final(ctx)
+		if ctx.rw.exceeded {
+			err = r.abortOversized(ctx)
+		}
+
+		if headerErr := ctx.rw.headerErr; headerErr != nil {
+			// A BeforeWriteHeader hook rejected the response, the error handler answers instead.
+			ctx.rw.headerErr = nil
+			ctx.rw.Header().Del("Content-Length")
+			if !errors.Is(err, headerErr) {
+				err = errors.Join(headerErr, err)
+			}
+		}
 
 		end := time.Now()
 
@@ -388,52 +777,72 @@ func (r *Router) handle(method, pattern string, handler HandlerFunc, is_static b
 		// e.g. errors that occur in the middleware.
 		// Also logging should be done in the errorHandler because the correct status code is set there.
 		r.errorHandler(ctx, err)
+		r.recordResult(rt, ctx, err)
+		ctx.runFinished()
 	})
+	return rt
 }
 
-// Common HTTP method handlers
-func (r *Router) GET(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodGet, pattern, handler, false, middlewares...)
+// Common HTTP method handlers.
+//
+// Patterns follow http.ServeMux, including a trailing catch-all parameter matching the rest
+// of the path, e.g POST "/webhooks/{path...}" for a receiver that dispatches on c.Param("path").
+// A catch-all handler can return ErrNotFound for paths it does not handle, so that the router's
+// 404 or NotFoundHandler is rendered. Catch-all patterns are listed as registered by RegisteredRoutes,
+// and c.RedirectRoute passes them the parameter set in RedirectOptions.Params.
+func (r *Router) GET(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodGet, pattern, handler, false, middlewares...)
 }
 
-func (r *Router) POST(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodPost, pattern, handler, false, middlewares...)
+func (r *Router) POST(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodPost, pattern, handler, false, middlewares...)
 }
 
-func (r *Router) PUT(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodPut, pattern, handler, false, middlewares...)
+func (r *Router) PUT(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodPut, pattern, handler, false, middlewares...)
 }
 
-func (r *Router) PATCH(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodPatch, pattern, handler, false, middlewares...)
+func (r *Router) PATCH(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodPatch, pattern, handler, false, middlewares...)
 }
 
-func (r *Router) DELETE(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodDelete, pattern, handler, false, middlewares...)
+func (r *Router) DELETE(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodDelete, pattern, handler, false, middlewares...)
 }
 
 // OPTIONS. This may not be necessary as registering GET request automatically registers OPTIONS.
-func (r *Router) OPTIONS(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodOptions, pattern, handler, false, middlewares...)
+func (r *Router) OPTIONS(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodOptions, pattern, handler, false, middlewares...)
 }
 
 // HEAD request.
-func (r *Router) HEAD(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodHead, pattern, handler, false, middlewares...)
+func (r *Router) HEAD(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodHead, pattern, handler, false, middlewares...)
 }
 
 // TRACE http request.
-func (r *Router) TRACE(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodTrace, pattern, handler, false, middlewares...)
+func (r *Router) TRACE(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodTrace, pattern, handler, false, middlewares...)
 }
 
 // CONNECT http request.
-func (r *Router) CONNECT(pattern string, handler HandlerFunc, middlewares ...Middleware) {
-	r.handle(http.MethodConnect, pattern, handler, false, middlewares...)
+func (r *Router) CONNECT(pattern string, handler HandlerFunc, middlewares ...Middleware) *Route {
+	return r.handle(http.MethodConnect, pattern, handler, false, middlewares...)
 }
 
 // ServeHTTP implements the http.Handler interface
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if r.normalizePaths {
+		if req = r.normalizeRequest(w, req); req == nil {
+			return
+		}
+	}
+
+	if r.basePath != "" || len(r.trustedProxies) > 0 {
+		if req = r.applyBasePath(w, req); req == nil {
+			return
+		}
+	}
 	r.mux.ServeHTTP(w, req)
 }
 
@@ -453,9 +862,88 @@ func (r *Router) chain(middlewares []Middleware, handler HandlerFunc) HandlerFun
 	return wrapped
 }
 
-func staticHandler(prefix, dir string, cacheDuration int) http.HandlerFunc {
-	return func(w http.ResponseWriter, req *http.Request) {
-		path := filepath.Join(dir, strings.TrimPrefix(req.URL.Path, prefix))
+// wrapMiddleware applies m to next. In Debug mode, m is wrapped to report its panics.
+func wrapMiddleware(m Middleware, next HandlerFunc) HandlerFunc {
+	if !Debug {
+		return m(next)
+	}
+	return debugMiddleware(m, next)
+}
+
+// debugMiddleware applies m to next and logs a panic raised in m itself with the name
+// of the middleware before rethrowing it. Panics of next are marked as reported,
+// so that only the middleware the panic started in is named.
+func debugMiddleware(m Middleware, next HandlerFunc) HandlerFunc {
+	name := getFuncName(m)
+
+	handler := m(func(c *Context) error {
+		defer func() {
+			if p := recover(); p != nil {
+				c.panicReported = true
+				panic(p)
+			}
+		}()
+		return next(c)
+	})
+
+	return func(c *Context) error {
+		defer func() {
+			if p := recover(); p != nil {
+				if !c.panicReported {
+					c.panicReported = true
+					c.router.logger.Error("panic in middleware", "middleware", name, "panic", p,
+						"method", c.Request.Method, "path", c.Request.URL.Path)
+				}
+				panic(p)
+			}
+		}()
+		return handler(c)
+	}
+}
+
+// staticHandler serves the files of dir at prefix. Missing files return ErrNotFound,
+// so that the router's error handler renders the 404.
+func staticHandler(prefix, dir string, cacheDuration int, lister *dirLister) HandlerFunc {
+	root := newStaticRoot(dir)
+	rootFS := http.Dir(root.dir)
+
+	return func(c *Context) error {
+		w, req := c.Response, c.Request
+		path, status := root.resolve(strings.TrimPrefix(req.URL.Path, prefix))
+		if status == http.StatusNotFound {
+			return ErrNotFound
+		} else if status != 0 {
+			return NewError(status, http.StatusText(status))
+		}
+
+		stat, err := os.Stat(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			return ErrNotFound
+		}
+
+		if err == nil && stat.IsDir() {
+			fsys := http.FileSystem(rootFS)
+			if !ServeDotfiles {
+				fsys = dotfileFS{rootFS}
+			}
+
+			name := "/" + strings.Trim(filepath.ToSlash(strings.TrimPrefix(path, root.dir)), "/")
+			if lister.serve(w, req, fsys, name) {
+				return nil
+			}
+
+			// The listings of http.ServeFile would show the dotfiles.
+			if strings.HasSuffix(req.URL.Path, "/") && name != "/" {
+				name += "/"
+			}
+			dirReq := *req
+			dirURL := *req.URL
+			dirURL.Path = name
+			dirReq.URL = &dirURL
+			http.FileServer(fsys).ServeHTTP(w, &dirReq)
+			return nil
+		}
+
 		ext := filepath.Ext(path)
 
 		setCacheHeaders := func() {
@@ -466,10 +954,8 @@ func staticHandler(prefix, dir string, cacheDuration int) http.HandlerFunc {
 		}
 
 		if ServeMinified && slices.Contains(MinExtensions, ext) {
-			stat, err := os.Stat(path)
 			if err != nil || stat.IsDir() {
-				http.NotFound(w, req)
-				return
+				return ErrNotFound
 			}
 
 			// TODO: Allow user to customize the minified extension based on the file type
@@ -480,25 +966,40 @@ func staticHandler(prefix, dir string, cacheDuration int) http.HandlerFunc {
 			// Check for the minified version of the file
 			stat, err = os.Stat(minifiedPath)
 			if err == nil && !stat.IsDir() {
-				http.ServeFile(w, req, minifiedPath)
 				setCacheHeaders()
-				return
+				http.ServeFile(w, req, minifiedPath)
+				return nil
 			}
 		}
 
 		setCacheHeaders()
 
 		http.ServeFile(w, req, path)
+		return nil
 	}
-
 }
 
 // Serve static assests at prefix in the directory dir.
 // e.g r.Static("/static", "static").
 // This method will strip the prefix from the URL path.
+//
+// Static mounts, including StaticFS and SPA in groups, match as follows:
+//   - "/static/" and every path under it are served from the mount.
+//   - "/static" redirects to "/static/" with 301 Moved Permanently, unless a GET "/static"
+//     route was registered before the mount.
+//   - a mount at "/" matches every path not matched by another route. With StrictHome,
+//     a GET "/" route only matches "/" and coexists with the mount.
+//   - missing files return ErrNotFound to the error handler, so the router's 404 page
+//     (or NotFoundHandler) is rendered.
+//
 // To serve minified assets(JS and CSS) if present, call rex.ServeMinifiedAssetsIfPresent=true.
 // To enable caching, provide maxAge seconds for cache duration.
 func (r *Router) Static(prefix, dir string, maxAge ...int) {
+	r.static(prefix, dir, maxAge)
+}
+
+// static registers a Static mount with the middlewares, which run after the global middleware.
+func (r *Router) static(prefix, dir string, maxAge []int, middlewares ...Middleware) {
 	if !strings.HasSuffix(prefix, "/") {
 		prefix = prefix + "/"
 	}
@@ -508,22 +1009,29 @@ func (r *Router) Static(prefix, dir string, maxAge ...int) {
 		cacheDuration = maxAge[0]
 	}
 
-	handler := r.WrapHandler(staticHandler(prefix, dir, cacheDuration))
-	r.handle(http.MethodGet, prefix, handler, true)
+	handler := staticHandler(prefix, dir, cacheDuration, r.dirLister(""))
+	r.handle(http.MethodGet, prefix, handler, true, middlewares...)
+	r.redirectBarePrefix(prefix)
 }
 
 // Wrapper around http.ServeFile but applies global middleware to the handler.
 func (r *Router) File(path, file string) {
-	var hf http.HandlerFunc = func(w http.ResponseWriter, req *http.Request) {
+	r.GET(path, r.WrapHandler(fileHandler(file)))
+}
+
+// FileFS serves the file at path in the file system fs at the route prefix.
+func (r *Router) FileFS(fs http.FileSystem, prefix, path string) {
+	r.GET(prefix, r.WrapHandler(fileFSHandler(fs, path)))
+}
+
+func fileHandler(file string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
 		http.ServeFile(w, req, file)
 	}
-
-	handler := r.chain(r.globalMiddlewares, r.WrapHandler(hf))
-	r.GET(path, handler)
 }
 
-func (r *Router) FileFS(fs http.FileSystem, prefix, path string) {
-	r.GET(prefix, r.WrapHandler(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+func fileFSHandler(fs http.FileSystem, path string) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
 		f, err := fs.Open(path)
 		if err != nil {
 			http.NotFound(w, req)
@@ -539,7 +1047,7 @@ func (r *Router) FileFS(fs http.FileSystem, prefix, path string) {
 
 		w.WriteHeader(http.StatusOK)
 		http.ServeContent(w, req, path, stat.ModTime(), f)
-	})))
+	}
 }
 
 // Serve favicon.ico from the file system fs at path.
@@ -576,6 +1084,55 @@ func (r *Router) FaviconFS(fs http.FileSystem, path string) {
 	r.GET("/favicon.ico", r.WrapHandler(handler))
 }
 
+// Favicon serves favicon.ico from the given bytes.
+// This is useful for apps without a file system that embed the icon directly.
+// The default content type is "image/x-icon".
+func (r *Router) Favicon(data []byte, contentType string) *Route {
+	if contentType == "" {
+		contentType = "image/x-icon"
+	}
+
+	etag := contentETag(data)
+	return r.GET("/favicon.ico", func(c *Context) error {
+		return serveBytes(c, "favicon.ico", contentType, "public, max-age=31536000", etag, data)
+	}).Meta(MetaSkipCompression, true)
+}
+
+// Robots serves content at /robots.txt as text/plain.
+func (r *Router) Robots(content string) *Route {
+	data := []byte(content)
+	etag := contentETag(data)
+	return r.GET("/robots.txt", func(c *Context) error {
+		return serveBytes(c, "robots.txt", "text/plain; charset=utf-8", "public, max-age=86400", etag, data)
+	}).Meta(MetaSkipCompression, true)
+}
+
+// SecurityTxt serves content at /.well-known/security.txt as text/plain.
+// See https://securitytxt.org for the format.
+func (r *Router) SecurityTxt(content string) *Route {
+	data := []byte(content)
+	etag := contentETag(data)
+	return r.GET("/.well-known/security.txt", func(c *Context) error {
+		return serveBytes(c, "security.txt", "text/plain; charset=utf-8", "public, max-age=86400", etag, data)
+	}).Meta(MetaSkipCompression, true)
+}
+
+// contentETag returns a strong ETag computed from the sha256 hash of data.
+func contentETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// serveBytes writes data with the given headers.
+// Conditional and range requests are handled by http.ServeContent.
+func serveBytes(c *Context, name, contentType, cacheControl, etag string, data []byte) error {
+	c.SetHeader("Content-Type", contentType)
+	c.SetHeader("Cache-Control", cacheControl)
+	c.SetHeader("ETag", etag)
+	http.ServeContent(c.Response, c.Request, name, time.Time{}, bytes.NewReader(data))
+	return nil
+}
+
 type minifiedFS struct {
 	http.FileSystem
 }
@@ -583,11 +1140,11 @@ type minifiedFS struct {
 func (mfs *minifiedFS) Open(name string) (http.File, error) {
 	ext := filepath.Ext(name)
 
-	if slices.Contains(MinExtensions, ext) {
+	if ServeMinified && slices.Contains(MinExtensions, ext) {
 		minifiedName := strings.TrimSuffix(name, filepath.Ext(name)) + ".min" + filepath.Ext(name)
-		if f, err := mfs.FileSystem.Open(minifiedName); err == nil {
-			return f, nil
-		}
+
+		// With OverlayFS, the first layer that has either file decides.
+		return openLayer(mfs.FileSystem, minifiedName, name)
 	}
 
 	// serve the original file
@@ -600,7 +1157,14 @@ func (mfs *minifiedFS) Open(name string) (http.File, error) {
 //	app.StaticFS("/static", rex.CreateFileSystem(embedfs, "static"), 3600)
 //
 // To enable caching, provide maxAge seconds for cache duration.
+// Files are opened on every request and never cached by the router,
+// see OverlayFS for embedded assets with overrides on disk.
 func (r *Router) StaticFS(prefix string, fs http.FileSystem, maxAge ...int) {
+	r.staticFS(prefix, fs, maxAge)
+}
+
+// staticFS registers a StaticFS mount with the middlewares, which run after the global middleware.
+func (r *Router) staticFS(prefix string, fs http.FileSystem, maxAge []int, middlewares ...Middleware) {
 	if !strings.HasSuffix(prefix, "/") {
 		prefix = prefix + "/"
 	}
@@ -610,22 +1174,106 @@ func (r *Router) StaticFS(prefix string, fs http.FileSystem, maxAge ...int) {
 		cacheDuration = maxAge[0]
 	}
 
-	if ServeMinified {
-		fs = &minifiedFS{fs}
+	// Files loaded with PrecompressAssets are served from memory.
+	precompressed, _ := fs.(*precompressedFS)
+	if precompressed != nil {
+		r.logger.Info("preloaded static assets", "prefix", prefix,
+			"files", len(precompressed.assets), "bytes", precompressed.size)
 	}
 
-	// Create file server for the http.FileSystem
-	var handler http.HandlerFunc = func(w http.ResponseWriter, r *http.Request) {
-		if cacheDuration > 0 {
+	// Like Static, ServeMinified is checked on each request.
+	fs = &minifiedFS{fs}
+
+	if !ServeDotfiles {
+		fs = dotfileFS{fs}
+	}
+
+	lister := r.dirLister(prefix)
+	fileServer := http.FileServer(fs)
+
+	// name is the path relative to the mount.
+	setCacheControl := func(header http.Header, name string) {
+		if r.assets != nil && r.assets.isHashed(name, prefix+name) {
+			header.Set("Cache-Control", immutableCacheControl)
+		} else if cacheDuration > 0 {
 			// Set cache control headers with the specified maxAge
-			w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(cacheDuration))
+			header.Set("Cache-Control", "public, max-age="+strconv.Itoa(cacheDuration))
 		}
-		http.FileServer(fs).ServeHTTP(w, r)
+	}
+
+	// Create file server for the http.FileSystem
+	var handler http.HandlerFunc = func(w http.ResponseWriter, req *http.Request) {
+		if f, err := fs.Open(req.URL.Path); err == nil {
+			stat, err := f.Stat()
+			f.Close()
+			if err == nil && stat.IsDir() && lister.serve(w, req, fs, req.URL.Path) {
+				return
+			}
+		}
+
+		setCacheControl(w.Header(), req.URL.Path)
+		fileServer.ServeHTTP(w, req)
 	}
 
 	// Apply global middleware
-	finalHandler := r.WrapHandler(http.StripPrefix(prefix, handler))
-	r.handle(http.MethodGet, prefix, finalHandler, true)
+	serve := r.WrapHandler(http.StripPrefix(prefix, handler))
+	finalHandler := func(c *Context) error {
+		// Misses return ErrNotFound, so that the router's error handler renders the 404.
+		name := path.Clean("/" + strings.TrimPrefix(c.Request.URL.Path, prefix))
+		f, err := fs.Open(name)
+		if errors.Is(err, os.ErrNotExist) {
+			return ErrNotFound
+		} else if err == nil {
+			f.Close()
+		}
+
+		if precompressed != nil {
+			if _, ok := precompressed.asset(name); ok {
+				setCacheControl(c.Response.Header(), strings.TrimPrefix(c.Request.URL.Path, prefix))
+				precompressed.serve(c, name)
+				return nil
+			}
+		}
+		return serve(c)
+	}
+
+	route := r.handle(http.MethodGet, prefix, finalHandler, true, middlewares...)
+	if precompressed != nil {
+		route.Meta(MetaSkipCompression, func(c *Context) bool {
+			_, ok := precompressed.asset(path.Clean("/" + strings.TrimPrefix(c.Request.URL.Path, prefix)))
+			return ok
+		})
+	}
+	r.redirectBarePrefix(prefix)
+}
+
+// redirectBarePrefix registers a route redirecting prefix without its trailing slash,
+// e.g "/static", to the mount at "/static/" with 301 Moved Permanently.
+// A route already registered for the bare prefix is kept, and one registered later replaces it.
+func (r *Router) redirectBarePrefix(prefix string) {
+	bare := strings.TrimSuffix(prefix, "/")
+	if bare == "" || strings.Contains(bare, "{") {
+		return
+	}
+
+	if _, ok := r.routes[http.MethodGet+" "+bare]; ok {
+		return
+	}
+
+	rt := r.handle(http.MethodGet, bare, func(c *Context) error {
+		target := prefix
+		if c.Request.URL.RawQuery != "" {
+			target += "?" + c.Request.URL.RawQuery
+		}
+		return c.Redirect(target, http.StatusMovedPermanently)
+	}, false)
+	rt.name = fmt.Sprintf("redirect %q -> %q", bare, prefix)
+	rt.automatic = true
+}
+
+// StaticFs is an alias for StaticFS.
+func (r *Router) StaticFs(prefix string, fs http.FileSystem, maxAge ...int) {
+	r.StaticFS(prefix, fs, maxAge...)
 }
 
 type RedirectOptions struct {
@@ -678,7 +1326,7 @@ func (c *Context) RedirectRoute(pathname string, options ...RedirectOptions) err
 		parts := strings.Split(route.prefix, " ")
 		name := strings.TrimSpace(parts[1])
 		if name == pathname {
-			handler = route.handler
+			handler = route.final
 			break
 		}
 	}
@@ -714,7 +1362,7 @@ func (r *Router) RegisteredRoutes() []RouteInfo {
 	var routes []RouteInfo
 	for _, route := range r.routes {
 		parts := strings.SplitN(route.prefix, " ", 2)
-		routes = append(routes, RouteInfo{Method: parts[0], Path: parts[1], Handler: getFuncName(route.handler)})
+		routes = append(routes, RouteInfo{Method: parts[0], Path: parts[1], Handler: route.handlerName()})
 	}
 	return routes
 }
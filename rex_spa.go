@@ -2,11 +2,15 @@ package rex
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
+	"net/url"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"time"
 )
 
@@ -16,10 +20,24 @@ type SPAOption func(*spaHandler)
 type spaHandler struct {
 	indexContent     []byte
 	indexModTime     time.Time
+	indexEncoded     map[string][]byte // precompressed index by content encoding
 	cacheControl     string
 	skipFunc         func(r *http.Request) bool
 	responseModifier http.HandlerFunc
+	transforms       []func(c *Context, index []byte) []byte
 	fileServer       http.Handler
+	stripPrefix      string
+	frontend         http.FileSystem
+	index            string
+	watch            bool // reload the index on every request, see WatchOverlay
+}
+
+// precompressedEncodings are the index sidecar encodings in order of preference.
+var precompressedEncodings = []struct {
+	encoding, ext string
+}{
+	{"br", ".br"},
+	{"gzip", ".gz"},
 }
 
 // WithCacheControl sets the Cache-Control header for the index file.
@@ -43,30 +61,138 @@ func WithResponseModifier(responseModifier http.HandlerFunc) SPAOption {
 	}
 }
 
-// newSPAHandler creates and initializes a new SPA handler
-func newSPAHandler(frontend http.FileSystem, index string, options ...SPAOption) (*spaHandler, error) {
-	// Pre-load index file
-	indexContent, modTime, err := loadIndexFile(frontend, index)
-	if err != nil {
-		return nil, fmt.Errorf("failed to load index file: %w", err)
+// WithIndexTransform registers a function that rewrites the index file before it is sent.
+// It is called on every request for the index with a copy of the original content and must
+// not modify assets. Transforms run in the order they were registered.
+// Precompressed index sidecars are not used when the index is transformed.
+func WithIndexTransform(transform func(c *Context, index []byte) []byte) SPAOption {
+	return func(h *spaHandler) {
+		h.transforms = append(h.transforms, transform)
+	}
+}
+
+// WatchOverlay reloads the index file on every request instead of loading it once,
+// so that changes to the disk layer of an OverlayFS, or any other file system,
+// show up without a restart. Other files are never cached. Use it in development.
+func WatchOverlay() SPAOption {
+	return func(h *spaHandler) {
+		h.watch = true
+	}
+}
+
+// jsIdentifier matches a valid JavaScript global variable name.
+var jsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// InjectGlobals injects the JSON encoding of data(c) into the index file as
+// <script>window.varName = {...}</script> right before </head>, or at the start
+// of the document if there is no head. This provides runtime configuration
+// like API base URLs and feature flags without rebuilding the frontend.
+// The JSON is escaped so that it cannot terminate the script element.
+// It panics if varName is not a valid JavaScript identifier.
+//
+// Example:
+//
+//	r.SPA("/", "index.html", fs, rex.InjectGlobals("APP_CONFIG", func(c *rex.Context) any {
+//		return map[string]any{"apiBase": os.Getenv("API_BASE")}
+//	}))
+func InjectGlobals(varName string, data func(c *Context) any) SPAOption {
+	if !jsIdentifier.MatchString(varName) {
+		panic(fmt.Sprintf("rex: invalid JavaScript identifier %q", varName))
 	}
 
+	return WithIndexTransform(func(c *Context, index []byte) []byte {
+		// json.Marshal escapes <, > and & so the payload cannot close the script tag.
+		payload, err := json.Marshal(data(c))
+		if err != nil {
+			c.router.logger.Error("failed to encode SPA globals", "error", err, "var", varName)
+			return index
+		}
+
+		script := make([]byte, 0, len(payload)+len(varName)+40)
+		script = append(script, "<script>window."...)
+		script = append(script, varName...)
+		script = append(script, " = "...)
+		script = append(script, payload...)
+		script = append(script, ";</script>"...)
+		return injectBeforeHead(index, script)
+	})
+}
+
+// injectBeforeHead inserts snippet before the first </head> tag, or at the start of index.
+func injectBeforeHead(index, snippet []byte) []byte {
+	pos := bytes.Index(bytes.ToLower(index), []byte("</head>"))
+	if pos < 0 {
+		pos = 0
+	}
+
+	out := make([]byte, 0, len(index)+len(snippet))
+	out = append(out, index[:pos]...)
+	out = append(out, snippet...)
+	return append(out, index[pos:]...)
+}
+
+// newSPAHandler creates and initializes a new SPA handler
+func newSPAHandler(frontend http.FileSystem, index string, options ...SPAOption) (*spaHandler, error) {
 	spa := &spaHandler{
-		indexContent:     indexContent,
-		indexModTime:     modTime,
 		cacheControl:     "",
 		skipFunc:         nil,
 		responseModifier: nil,
 		fileServer:       http.FileServer(frontend),
+		frontend:         frontend,
+		index:            index,
 	}
 
 	// Apply options
 	for _, opt := range options {
 		opt(spa)
 	}
+
+	// Pre-load index file
+	var err error
+	spa.indexContent, spa.indexModTime, spa.indexEncoded, err = spa.loadIndex()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load index file: %w", err)
+	}
 	return spa, nil
 }
 
+// loadIndex reads the index file and its precompressed sidecars.
+// With OverlayFS, sidecars are only read from the layer that serves the index,
+// so that an overridden index is not replaced by a stale compressed copy.
+func (h *spaHandler) loadIndex() (content []byte, modTime time.Time, encoded map[string][]byte, err error) {
+	content, modTime, err = loadIndexFile(h.frontend, h.index)
+	if err != nil {
+		return nil, time.Time{}, nil, err
+	}
+
+	// Precompressed sidecars are only valid for the untransformed index.
+	if len(h.transforms) == 0 {
+		layer := layerOf(h.frontend, h.index)
+		for _, pc := range precompressedEncodings {
+			if sidecar, _, err := loadIndexFile(layer, h.index+pc.ext); err == nil {
+				if encoded == nil {
+					encoded = make(map[string][]byte)
+				}
+				encoded[pc.encoding] = sidecar
+			}
+		}
+	}
+	return content, modTime, encoded, nil
+}
+
+// currentIndex returns the index, reloaded if the handler watches the file system.
+// The loaded index is used if reloading fails.
+func (h *spaHandler) currentIndex(c *Context) ([]byte, time.Time, map[string][]byte) {
+	if h.watch {
+		content, modTime, encoded, err := h.loadIndex()
+		if err == nil {
+			return content, modTime, encoded
+		}
+		c.router.logger.Error("failed to reload SPA index", "error", err, "index", h.index)
+	}
+	return h.indexContent, h.indexModTime, h.indexEncoded
+}
+
 // loadIndexFile reads the index file content and modification time
 func loadIndexFile(fs http.FileSystem, indexPath string) ([]byte, time.Time, error) {
 	f, err := fs.Open(indexPath)
@@ -88,26 +214,82 @@ func loadIndexFile(fs http.FileSystem, indexPath string) ([]byte, time.Time, err
 	return content, stat.ModTime(), nil
 }
 
-// ServeHTTP handles the actual request
-func (h *spaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+// serve handles the actual request
+func (h *spaHandler) serve(c *Context) error {
+	w, r := c.Response, c.Request
+	if h.stripPrefix != "" {
+		r = stripRequestPrefix(r, h.stripPrefix)
+		if r == nil {
+			http.NotFound(w, c.Request)
+			return nil
+		}
+		c.Request = r
+	}
+
 	if h.skipFunc != nil && h.skipFunc(r) {
 		http.NotFound(w, r)
-		return
+		return nil
 	}
 
 	// If path has an extension, try serving as static file first
 	if ext := filepath.Ext(r.URL.Path); ext != "" {
 		h.fileServer.ServeHTTP(w, r)
-		return
+		return nil
 	}
 
 	// Serve index.html for SPA routes
-	h.serveIndex(w, r)
+	h.serveIndex(c)
+	return nil
+}
+
+// skipCompression reports whether the request is answered with the precompressed index,
+// which compression middleware must leave untouched. Assets are compressed as usual.
+func (h *spaHandler) skipCompression(c *Context) bool {
+	if len(h.transforms) > 0 {
+		return false
+	}
+
+	r := c.Request
+	if h.stripPrefix != "" {
+		if r = stripRequestPrefix(r, h.stripPrefix); r == nil {
+			return false
+		}
+	}
+
+	if (h.skipFunc != nil && h.skipFunc(r)) || filepath.Ext(r.URL.Path) != "" {
+		return false
+	}
+
+	for _, pc := range precompressedEncodings {
+		if _, ok := h.indexEncoded[pc.encoding]; ok && acceptsEncoding(r.Header.Get("Accept-Encoding"), pc.encoding) {
+			return true
+		}
+	}
+	return false
 }
 
-// serveIndex serves the pre-loaded index file
-func (h *spaHandler) serveIndex(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "text/html")
+// stripRequestPrefix returns a shallow copy of r with prefix removed from the path
+// like http.StripPrefix. It returns nil if the path does not have the prefix.
+func stripRequestPrefix(r *http.Request, prefix string) *http.Request {
+	p := strings.TrimPrefix(r.URL.Path, prefix)
+	rp := strings.TrimPrefix(r.URL.RawPath, prefix)
+	if len(p) == len(r.URL.Path) && (r.URL.RawPath == "" || len(rp) == len(r.URL.RawPath)) {
+		return nil
+	}
+
+	r2 := new(http.Request)
+	*r2 = *r
+	r2.URL = new(url.URL)
+	*r2.URL = *r.URL
+	r2.URL.Path = p
+	r2.URL.RawPath = rp
+	return r2
+}
+
+// serveIndex serves the pre-loaded index file with the status 200 OK.
+func (h *spaHandler) serveIndex(c *Context) {
+	w, r := c.Response, c.Request
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	if h.cacheControl != "" {
 		w.Header().Set("Cache-Control", h.cacheControl)
 	}
@@ -116,7 +298,45 @@ func (h *spaHandler) serveIndex(w http.ResponseWriter, r *http.Request) {
 		h.responseModifier(w, r)
 	}
 
-	http.ServeContent(w, r, "index.html", h.indexModTime, bytes.NewReader(h.indexContent))
+	content, modTime, indexEncoded := h.currentIndex(c)
+	if len(h.transforms) > 0 {
+		content := bytes.Clone(content)
+		for _, transform := range h.transforms {
+			content = transform(c, content)
+		}
+
+		// The content may differ per request, don't let clients revalidate by date.
+		http.ServeContent(w, r, "index.html", time.Time{}, bytes.NewReader(content))
+		return
+	}
+
+	if len(indexEncoded) > 0 {
+		w.Header().Add("Vary", "Accept-Encoding")
+		for _, pc := range precompressedEncodings {
+			encoded, ok := indexEncoded[pc.encoding]
+			if ok && acceptsEncoding(r.Header.Get("Accept-Encoding"), pc.encoding) {
+				w.Header().Set("Content-Encoding", pc.encoding)
+				content = encoded
+				break
+			}
+		}
+	}
+
+	http.ServeContent(w, r, "index.html", modTime, bytes.NewReader(content))
+}
+
+// acceptsEncoding reports whether the Accept-Encoding header value allows encoding.
+func acceptsEncoding(header, encoding string) bool {
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if !strings.EqualFold(strings.TrimSpace(name), encoding) {
+			continue
+		}
+
+		q := strings.ReplaceAll(strings.TrimSpace(params), " ", "")
+		return q != "q=0" && q != "q=0.0" && q != "q=0.00" && q != "q=0.000"
+	}
+	return false
 }
 
 // SPA serves a single page application (SPA) with the given index file.
@@ -132,8 +352,10 @@ func (r *Router) SPA(pattern string, index string, frontend http.FileSystem, opt
 		panic(fmt.Errorf("failed to create SPA handler: %w", err))
 	}
 
-	// Apply global middleware
-	r.mux.Handle(fmt.Sprintf("GET %s", pattern), handler)
+	rt := r.handle(http.MethodGet, pattern, handler.serve, true)
+	if len(handler.indexEncoded) > 0 {
+		rt.Meta(MetaSkipCompression, handler.skipCompression)
+	}
 }
 
 // Creates a new http.FileSystem from the fs.FS (e.g embed.FS) with the root directory.
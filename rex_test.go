@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"embed"
-	"encoding/json"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -14,6 +13,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -173,23 +173,16 @@ func TestRouterJSONData(t *testing.T) {
 		Age:  23,
 	}
 
-	body, err := json.Marshal(u)
-	if err != nil {
-		t.Error(err)
-	}
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest("POST", "/json", bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-
-	r.ServeHTTP(w, req)
+	res := r.Test(rex.NewTestRequest("POST", "/json").JSON(u).Build())
 
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	if res.Status() != http.StatusOK {
+		t.Errorf("expected status 200, got %d", res.Status())
 	}
 
 	var u2 User
-	json.NewDecoder(w.Body).Decode(&u2)
+	if err := res.JSON(&u2); err != nil {
+		t.Fatal(err)
+	}
 
 	if !reflect.DeepEqual(u, u2) {
 		t.Errorf("expected %v, got %v", u, u2)
@@ -213,23 +206,16 @@ func TestBodyParserDerivedTypes(t *testing.T) {
 		Age:  23,
 	}
 
-	body, err := json.Marshal(u)
-	if err != nil {
-		t.Error(err)
-	}
-
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest("POST", "/json", bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-
-	r.ServeHTTP(w, req)
+	res := r.Test(rex.NewTestRequest("POST", "/json").JSON(u).Build())
 
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	if res.Status() != http.StatusOK {
+		t.Errorf("expected status 200, got %d", res.Status())
 	}
 
 	var u2 User
-	json.NewDecoder(w.Body).Decode(&u2)
+	if err := res.JSON(&u2); err != nil {
+		t.Fatal(err)
+	}
 
 	if !reflect.DeepEqual(u, u2) {
 		t.Errorf("expected %v, got %v", u, u2)
@@ -798,16 +784,14 @@ func TestRouterQuery(t *testing.T) {
 		return c.String(c.Query("name", "default"))
 	})
 
-	w := httptest.NewRecorder()
-	req := httptest.NewRequest("GET", "/query?name=abiira", nil)
-	r.ServeHTTP(w, req)
+	res := r.Test(rex.NewTestRequest("GET", "/query").Query("name", "abiira").Build())
 
-	if w.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", w.Code)
+	if res.Status() != http.StatusOK {
+		t.Errorf("expected status 200, got %d", res.Status())
 	}
 
-	if w.Body.String() != "abiira" {
-		t.Errorf("expected abiira, got %s", w.Body.String())
+	if res.BodyString() != "abiira" {
+		t.Errorf("expected abiira, got %s", res.BodyString())
 	}
 }
 
@@ -1211,7 +1195,7 @@ func TestSPAHandler(t *testing.T) {
 	r.ServeHTTP(w, req)
 
 	if w.Code != http.StatusOK {
-		t.Fatalf("expected status 202, got %d", w.Code)
+		t.Fatalf("expected status 200, got %d", w.Code)
 	}
 
 	// we expect content of index.html
@@ -1249,6 +1233,130 @@ func TestSPAHandler(t *testing.T) {
 
 }
 
+func TestSPAInjectGlobals(t *testing.T) {
+	temp := t.TempDir()
+	index := `<html><head><title>App</title></head><body><script src="/app.js"></script></body></html>`
+	os.WriteFile(filepath.Join(temp, "index.html"), []byte(index), 0644)
+	os.WriteFile(filepath.Join(temp, "app.js"), []byte(`console.log("</head>")`), 0644)
+
+	r := rex.NewRouter()
+	r.SPA("/", "index.html", http.Dir(temp), rex.InjectGlobals("APP_CONFIG", func(c *rex.Context) any {
+		return map[string]string{
+			"apiBase": "https://api.example.com",
+			"path":    c.Request.URL.Path,
+			"evil":    "</script><script>alert(1)</script>",
+		}
+	}))
+
+	for i := 0; i < 2; i++ {
+		res := r.Test(httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+		if res.Status() != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", res.Status())
+		}
+
+		body := res.BodyString()
+		if n := strings.Count(body, "window.APP_CONFIG = "); n != 1 {
+			t.Fatalf("expected globals to be injected exactly once, got %d in %s", n, body)
+		}
+
+		if strings.Count(body, "</script>") != 2 || strings.Contains(body, "<script>alert(1)") {
+			t.Errorf("expected injected JSON to be escaped, got %s", body)
+		}
+
+		if !strings.Contains(body, `"path":"/dashboard"`) {
+			t.Errorf("expected per-request data, got %s", body)
+		}
+
+		if strings.Index(body, "window.APP_CONFIG") > strings.Index(body, "</head>") {
+			t.Errorf("expected globals before </head>, got %s", body)
+		}
+	}
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/app.js", nil))
+	if res.BodyString() != `console.log("</head>")` {
+		t.Errorf("expected assets to be untouched, got %q", res.BodyString())
+	}
+}
+
+func TestSPAInjectGlobalsInvalidName(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected InjectGlobals to panic for an invalid identifier")
+		}
+	}()
+	rex.InjectGlobals("a.b;alert(1)", func(c *rex.Context) any { return nil })
+}
+
+func TestSPAPrecompressedIndex(t *testing.T) {
+	temp := t.TempDir()
+	os.WriteFile(filepath.Join(temp, "index.html"), []byte("<html>plain</html>"), 0644)
+	os.WriteFile(filepath.Join(temp, "index.html.br"), []byte("brotli-bytes"), 0644)
+	os.WriteFile(filepath.Join(temp, "index.html.gz"), []byte("gzip-bytes"), 0644)
+
+	r := rex.NewRouter()
+	r.SPA("/", "index.html", http.Dir(temp))
+
+	tests := []struct {
+		accept, encoding, body string
+	}{
+		{"", "", "<html>plain</html>"},
+		{"gzip", "gzip", "gzip-bytes"},
+		{"gzip, br", "br", "brotli-bytes"},
+		{"br;q=0, gzip", "gzip", "gzip-bytes"},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept-Encoding", tt.accept)
+		}
+
+		res := r.Test(req)
+		if res.Status() != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", res.Status())
+		}
+
+		if res.Header("Content-Encoding") != tt.encoding || res.BodyString() != tt.body {
+			t.Errorf("Accept-Encoding %q: got encoding %q body %q", tt.accept, res.Header("Content-Encoding"), res.BodyString())
+		}
+
+		if res.Header("Content-Type") != "text/html; charset=utf-8" || res.Header("Vary") != "Accept-Encoding" {
+			t.Errorf("unexpected headers %v", res.Result().Header)
+		}
+	}
+}
+
+func TestSPARegisteredAsRoute(t *testing.T) {
+	temp := t.TempDir()
+	os.WriteFile(filepath.Join(temp, "index.html"), []byte("<html>plain</html>"), 0644)
+	os.WriteFile(filepath.Join(temp, "index.html.gz"), []byte("gzip-bytes"), 0644)
+	os.WriteFile(filepath.Join(temp, "app.js"), []byte("console.log(1)"), 0644)
+
+	skipped := map[string]bool{}
+	r := rex.NewRouter()
+	r.Use(func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			skipped[c.Request.URL.Path] = c.SkipCompression()
+			return next(c)
+		}
+	})
+	r.SPA("/", "index.html", http.Dir(temp))
+
+	if !slices.ContainsFunc(r.RegisteredRoutes(), func(info rex.RouteInfo) bool { return info.Method == "GET" && info.Path == "/" }) {
+		t.Errorf("expected the SPA route to be registered, got %v", r.RegisteredRoutes())
+	}
+
+	for _, path := range []string{"/users/1", "/app.js"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		r.Test(req)
+	}
+
+	if !skipped["/users/1"] || skipped["/app.js"] {
+		t.Errorf("expected the global middleware to run and only the precompressed index to skip compression, got %v", skipped)
+	}
+}
+
 //go:embed cmd/server/templates
 var templates embed.FS
 
@@ -1323,3 +1431,77 @@ func TestRequestsPerSecond(t *testing.T) {
 	t.Logf("Memory allocations: %d", result.AllocsPerOp())
 	t.Logf("Bytes allocated per op: %d", result.AllocedBytesPerOp())
 }
+
+func TestRouterRobotsSecurityTxtFavicon(t *testing.T) {
+	r := rex.NewRouter()
+	r.Robots("User-agent: *\nDisallow: /admin\n")
+	r.SecurityTxt("Contact: mailto:security@example.com\n")
+	r.Favicon([]byte("icon-bytes"), "")
+
+	tests := []struct {
+		path        string
+		contentType string
+		body        string
+	}{
+		{"/robots.txt", "text/plain; charset=utf-8", "User-agent: *\nDisallow: /admin\n"},
+		{"/.well-known/security.txt", "text/plain; charset=utf-8", "Contact: mailto:security@example.com\n"},
+		{"/favicon.ico", "image/x-icon", "icon-bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected status 200, got %d", w.Code)
+			}
+
+			if w.Body.String() != tt.body {
+				t.Errorf("expected body %q, got %q", tt.body, w.Body.String())
+			}
+
+			if ct := w.Header().Get("Content-Type"); ct != tt.contentType {
+				t.Errorf("expected content type %q, got %q", tt.contentType, ct)
+			}
+
+			if !strings.Contains(w.Header().Get("Cache-Control"), "max-age=") {
+				t.Errorf("expected a cache-control max-age, got %q", w.Header().Get("Cache-Control"))
+			}
+
+			etag := w.Header().Get("ETag")
+			if etag == "" {
+				t.Fatal("expected an ETag header")
+			}
+
+			// Conditional request with the ETag gets a 304.
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusNotModified {
+				t.Errorf("expected status 304, got %d", w.Code)
+			}
+
+			if w.Body.Len() != 0 {
+				t.Errorf("expected empty body for 304, got %q", w.Body.String())
+			}
+		})
+	}
+
+	names := map[string]string{}
+	for _, route := range r.RegisteredRoutes() {
+		names[route.Path] = route.Handler
+	}
+
+	for path, want := range map[string]string{
+		"/robots.txt":               "Robots",
+		"/.well-known/security.txt": "SecurityTxt",
+		"/favicon.ico":              "Favicon",
+	} {
+		if !strings.Contains(names[path], want) {
+			t.Errorf("expected handler name for %s to contain %q, got %q", path, want, names[path])
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package rexgolden compares response bodies against golden files in testdata.
+//
+// Run the tests with -update to write the current output to the golden files:
+//
+//	go test ./... -update
+//
+// Example:
+//
+//	res := r.Test(rex.NewTestRequest("GET", "/").Build())
+//	rexgolden.MatchResponse(t, "home", res, rexgolden.HTML())
+package rexgolden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"golang.org/x/net/html"
+)
+
+const updateFlag = "update"
+
+func init() {
+	// Share the flag with other golden file helpers in the same test binary.
+	if flag.Lookup(updateFlag) == nil {
+		flag.Bool(updateFlag, false, "update golden files in testdata")
+	}
+}
+
+// updating reports whether the -update flag is set.
+func updating() bool {
+	f := flag.Lookup(updateFlag)
+	return f != nil && f.Value.String() == "true"
+}
+
+type mode int
+
+const (
+	modeExact mode = iota
+	modeHTML
+	modeJSON
+)
+
+type config struct {
+	dir  string
+	mode mode
+}
+
+// Option configures Match.
+type Option func(*config)
+
+// HTML compares bodies after normalizing insignificant whitespace and attribute order,
+// so formatting-only template changes do not fail the test.
+func HTML() Option {
+	return func(c *config) {
+		c.mode = modeHTML
+	}
+}
+
+// JSON compares bodies semantically: object key order and formatting are ignored.
+func JSON() Option {
+	return func(c *config) {
+		c.mode = modeJSON
+	}
+}
+
+// Dir sets the directory of the golden files. The default is "testdata".
+func Dir(dir string) Option {
+	return func(c *config) {
+		c.dir = dir
+	}
+}
+
+// Match compares got with the golden file <dir>/<name>.golden and fails the test with
+// a diff if they differ. With -update, got is written to the golden file instead.
+func Match(t testing.TB, name string, got []byte, options ...Option) {
+	t.Helper()
+
+	cfg := config{dir: "testdata"}
+	for _, opt := range options {
+		opt(&cfg)
+	}
+
+	path := filepath.Join(cfg.dir, name+".golden")
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("rexgolden: %v", err)
+		}
+
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("rexgolden: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("rexgolden: %v (run with -%s to create it)", err, updateFlag)
+	}
+
+	var wantText, gotText string
+	switch cfg.mode {
+	case modeHTML:
+		wantText, gotText = NormalizeHTML(want), NormalizeHTML(got)
+	case modeJSON:
+		var wantValue, gotValue any
+		if err := json.Unmarshal(want, &wantValue); err != nil {
+			t.Fatalf("rexgolden: invalid JSON in %s: %v", path, err)
+		}
+
+		if err := json.Unmarshal(got, &gotValue); err != nil {
+			t.Fatalf("rexgolden: response is not valid JSON: %v\n%s", err, got)
+		}
+
+		if reflect.DeepEqual(wantValue, gotValue) {
+			return
+		}
+		wantText, gotText = indentJSON(wantValue), indentJSON(gotValue)
+	default:
+		wantText, gotText = string(want), string(got)
+	}
+
+	if wantText != gotText {
+		t.Errorf("rexgolden: %s does not match (-want +got):\n%s", path, Diff(wantText, gotText))
+	}
+}
+
+// MatchResponse matches the body of a response recorded with Router.Test.
+func MatchResponse(t testing.TB, name string, res *rex.TestResponse, options ...Option) {
+	t.Helper()
+	Match(t, name, res.Body.Bytes(), options...)
+}
+
+func indentJSON(v any) string {
+	// Map keys are sorted by encoding/json, which makes the output stable.
+	data, _ := json.MarshalIndent(v, "", "  ")
+	return string(data)
+}
+
+// NormalizeHTML returns the HTML document with one token per line, attributes sorted
+// by name and runs of whitespace in text collapsed to a single space.
+// Whitespace-only text is dropped, except inside pre and textarea elements
+// where text is kept verbatim.
+func NormalizeHTML(data []byte) string {
+	z := html.NewTokenizer(bytes.NewReader(data))
+
+	var lines []string
+	preserve := 0
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+
+		token := z.Token()
+		switch tt {
+		case html.TextToken:
+			text := token.Data
+			if preserve == 0 {
+				text = strings.Join(strings.Fields(text), " ")
+				if text == "" {
+					continue
+				}
+			}
+			lines = append(lines, html.EscapeString(text))
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if tt == html.StartTagToken && isPreformatted(token.Data) {
+				preserve++
+			}
+
+			slices.SortFunc(token.Attr, func(a, b html.Attribute) int {
+				return strings.Compare(a.Namespace+":"+a.Key, b.Namespace+":"+b.Key)
+			})
+			lines = append(lines, token.String())
+		case html.EndTagToken:
+			if isPreformatted(token.Data) && preserve > 0 {
+				preserve--
+			}
+			lines = append(lines, token.String())
+		default:
+			lines = append(lines, token.String())
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func isPreformatted(tag string) bool {
+	return tag == "pre" || tag == "textarea"
+}
+
+// Diff returns a line diff of want and got. Removed lines start with "-",
+// added lines with "+" and unchanged lines with a space.
+func Diff(want, got string) string {
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out strings.Builder
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&out, "  %s\n", a[i])
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(&out, "- %s\n", a[i])
+			i++
+		default:
+			fmt.Fprintf(&out, "+ %s\n", b[j])
+			j++
+		}
+	}
+	return out.String()
+}
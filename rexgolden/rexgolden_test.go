@@ -0,0 +1,119 @@
+package rexgolden
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+// recorder captures the first failure instead of failing the test.
+type recorder struct {
+	testing.TB
+	failed bool
+	msg    string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	if !r.failed {
+		r.failed = true
+		r.msg = fmt.Sprintf(format, args...)
+	}
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func setUpdate(t *testing.T, value bool) {
+	t.Helper()
+	old := flag.Lookup(updateFlag).Value.String()
+	flag.Set(updateFlag, fmt.Sprint(value))
+	t.Cleanup(func() { flag.Set(updateFlag, old) })
+}
+
+func TestNormalizeHTML(t *testing.T) {
+	a := `<div class="card"   id="x">
+		<p>Hello,
+		   world</p>
+	</div>`
+	b := `<div id="x" class="card"><p>Hello, world</p></div>`
+
+	if NormalizeHTML([]byte(a)) != NormalizeHTML([]byte(b)) {
+		t.Errorf("expected equal normalized HTML:\n%s\n---\n%s", NormalizeHTML([]byte(a)), NormalizeHTML([]byte(b)))
+	}
+
+	c := `<div id="x" class="card"><p>Hello, there</p></div>`
+	if NormalizeHTML([]byte(a)) == NormalizeHTML([]byte(c)) {
+		t.Error("expected text changes to be significant")
+	}
+
+	// Whitespace inside pre is significant.
+	pre1 := "<pre>a  b\n c</pre>"
+	pre2 := "<pre>a b c</pre>"
+	if NormalizeHTML([]byte(pre1)) == NormalizeHTML([]byte(pre2)) {
+		t.Error("expected whitespace inside pre to be preserved")
+	}
+}
+
+func TestMatchUpdateFlow(t *testing.T) {
+	dir := t.TempDir()
+	body := []byte(`<ul><li  class="a" id="1">One</li></ul>`)
+
+	rec := &recorder{TB: t}
+	Match(rec, "list", body, Dir(dir))
+	if !rec.failed || !strings.Contains(rec.msg, "-update") {
+		t.Fatalf("expected missing golden to fail with a hint, got %q", rec.msg)
+	}
+
+	setUpdate(t, true)
+	Match(t, "list", body, Dir(dir))
+
+	data, err := os.ReadFile(filepath.Join(dir, "list.golden"))
+	if err != nil || string(data) != string(body) {
+		t.Fatalf("expected golden file to be written, got %q (%v)", data, err)
+	}
+
+	setUpdate(t, false)
+	Match(t, "list", body, Dir(dir))
+	Match(t, "list", []byte("<ul>\n  <li id=\"1\" class=\"a\">One</li>\n</ul>"), Dir(dir), HTML())
+
+	rec = &recorder{TB: t}
+	Match(rec, "list", []byte(`<ul><li class="a" id="1">Two</li></ul>`), Dir(dir), HTML())
+	if !rec.failed || !strings.Contains(rec.msg, "- One") || !strings.Contains(rec.msg, "+ Two") {
+		t.Errorf("expected a diff of the changed text, got %q", rec.msg)
+	}
+}
+
+func TestMatchJSON(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "user.golden"), []byte(`{"name":"rex","tags":["a","b"],"age":3}`), 0644)
+
+	Match(t, "user", []byte("{\n  \"age\": 3,\n  \"tags\": [\"a\", \"b\"],\n  \"name\": \"rex\"\n}"), Dir(dir), JSON())
+
+	rec := &recorder{TB: t}
+	Match(rec, "user", []byte(`{"name":"rex","tags":["a","c"],"age":3}`), Dir(dir), JSON())
+	if !rec.failed || !strings.Contains(rec.msg, `-     "b"`) || !strings.Contains(rec.msg, `+     "c"`) {
+		t.Errorf("expected a readable JSON diff, got %q", rec.msg)
+	}
+}
+
+func TestMatchResponse(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "hello.golden"), []byte(`{"hello":"world"}`), 0644)
+
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		return c.JSON(map[string]string{"hello": "world"})
+	})
+
+	res := r.Test(rex.NewTestRequest(http.MethodGet, "/").Build())
+	MatchResponse(t, "hello", res, Dir(dir), JSON())
+}
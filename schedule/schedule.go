@@ -0,0 +1,288 @@
+// Package schedule runs periodic jobs, like cleanups and report emails, alongside a rex.Server.
+// Jobs start when the server starts serving and are stopped, and waited for, when it shuts down.
+//
+// Example:
+//
+//	sched := schedule.New(logger)
+//	sched.Every(10*time.Minute, "cleanup", deleteExpiredSessions)
+//	sched.Daily("03:00", "report", sendDailyReport)
+//
+//	srv := rex.NewServer(":8080", r)
+//	sched.Attach(srv)
+//	r.GET("/ops/jobs", sched.StatusHandler())
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// JobFunc is the function of a job. ctx is canceled when the scheduler stops.
+type JobFunc func(ctx context.Context) error
+
+// JobStatus is the state of a job returned by Status.
+type JobStatus struct {
+	Name         string        `json:"name"`
+	Schedule     string        `json:"schedule"`      // e.g "every 10m0s" or "daily at 03:00"
+	Running      bool          `json:"running"`       // a run is in progress
+	Runs         int           `json:"runs"`          // completed runs
+	Failures     int           `json:"failures"`      // runs that returned an error or panicked
+	Skipped      int           `json:"skipped"`       // runs skipped because the previous run was still active
+	LastStart    time.Time     `json:"last_start"`    // zero if the job never ran
+	LastDuration time.Duration `json:"last_duration"` // duration of the last completed run
+	LastError    string        `json:"last_error,omitempty"`
+	NextRun      time.Time     `json:"next_run"` // zero if the scheduler is not running
+}
+
+// Option configures a Scheduler.
+type Option func(*Scheduler)
+
+// WithLocation sets the time zone of Daily jobs. The default is time.Local.
+func WithLocation(loc *time.Location) Option {
+	return func(s *Scheduler) {
+		s.location = loc
+	}
+}
+
+// WithJitter delays the first run of Every jobs by a random duration up to d,
+// so that instances started together do not run their jobs at the same time.
+func WithJitter(d time.Duration) Option {
+	return func(s *Scheduler) {
+		s.jitter = d
+	}
+}
+
+// Scheduler runs jobs on their schedules. Register jobs before calling Start.
+type Scheduler struct {
+	logger   *slog.Logger
+	location *time.Location
+	jitter   time.Duration
+
+	mu      sync.Mutex
+	jobs    []*job
+	ctx     context.Context // canceled by Stop, nil until Start
+	cancel  context.CancelFunc
+	running sync.WaitGroup // job loops and runs
+}
+
+// job is a registered job and its status, guarded by the scheduler mutex.
+type job struct {
+	status JobStatus
+	next   func(now time.Time) time.Time
+	fn     JobFunc
+}
+
+// New creates a scheduler logging job failures to logger, or slog.Default() if logger is nil.
+func New(logger *slog.Logger, options ...Option) *Scheduler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	s := &Scheduler{logger: logger, location: time.Local}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Every registers a job running fn every interval, the first run one interval after Start.
+// A run is skipped if the previous run is still active.
+func (s *Scheduler) Every(interval time.Duration, name string, fn JobFunc) {
+	if interval <= 0 {
+		panic(fmt.Sprintf("schedule: job %q: interval must be positive", name))
+	}
+
+	first := true
+	s.add(name, fmt.Sprintf("every %s", interval), fn, func(now time.Time) time.Time {
+		next := now.Add(interval)
+		if first && s.jitter > 0 {
+			next = next.Add(rand.N(s.jitter))
+		}
+		first = false
+		return next
+	})
+}
+
+// Daily registers a job running fn every day at the given "15:04" time in the
+// location of the scheduler, see WithLocation. It panics if at is not a valid time.
+func (s *Scheduler) Daily(at string, name string, fn JobFunc) {
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		panic(fmt.Sprintf("schedule: job %q: invalid daily time %q, expected HH:MM", name, at))
+	}
+
+	s.add(name, "daily at "+at, fn, func(now time.Time) time.Time {
+		return nextDaily(now, t.Hour(), t.Minute(), s.location)
+	})
+}
+
+// nextDaily returns the first time after now at hour:minute in loc.
+func nextDaily(now time.Time, hour, minute int, loc *time.Location) time.Time {
+	local := now.In(loc)
+	next := time.Date(local.Year(), local.Month(), local.Day(), hour, minute, 0, 0, loc)
+	if !next.After(now) {
+		next = time.Date(local.Year(), local.Month(), local.Day()+1, hour, minute, 0, 0, loc)
+	}
+	return next
+}
+
+func (s *Scheduler) add(name, schedule string, fn JobFunc, next func(time.Time) time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil {
+		panic(fmt.Sprintf("schedule: cannot add job %q, the scheduler is running", name))
+	}
+	s.jobs = append(s.jobs, &job{status: JobStatus{Name: name, Schedule: schedule}, next: next, fn: fn})
+}
+
+// Start starts running the jobs. It does nothing if the scheduler was already started.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ctx != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx, s.cancel = ctx, cancel
+
+	for _, j := range s.jobs {
+		j.status.NextRun = j.next(time.Now())
+		s.running.Add(1)
+		go s.loop(ctx, j)
+	}
+}
+
+// Stop cancels the context of the running jobs and waits for them to return or ctx to be done.
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Attach starts the scheduler when srv starts serving and stops it during srv.ShutdownContext.
+func (s *Scheduler) Attach(srv *rex.Server) {
+	srv.OnStartup(s.Start)
+	srv.OnShutdown(s.Stop)
+}
+
+// loop waits for the next run of j until ctx is canceled.
+func (s *Scheduler) loop(ctx context.Context, j *job) {
+	defer s.running.Done()
+
+	for {
+		s.mu.Lock()
+		wait := time.Until(j.status.NextRun)
+		s.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.mu.Lock()
+		start := time.Now()
+		j.status.NextRun = j.next(start)
+		if j.status.Running {
+			j.status.Skipped++
+			s.mu.Unlock()
+			s.logger.Warn("skipping job run, the previous run is still active", "job", j.status.Name)
+			continue
+		}
+
+		j.status.Running = true
+		j.status.LastStart = start
+		s.running.Add(1)
+		s.mu.Unlock()
+
+		go s.run(ctx, j, start)
+	}
+}
+
+// run calls the job function, recovering a panic, and records the result.
+func (s *Scheduler) run(ctx context.Context, j *job, start time.Time) {
+	defer s.running.Done()
+
+	err := s.call(ctx, j)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j.status.Running = false
+	j.status.Runs++
+	j.status.LastDuration = time.Since(start)
+	j.status.LastError = ""
+	if err != nil {
+		j.status.Failures++
+		j.status.LastError = err.Error()
+		s.logger.Error("job failed", "job", j.status.Name, "error", err)
+	}
+}
+
+func (s *Scheduler) call(ctx context.Context, j *job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+			s.logger.Error("panic in job", "job", j.status.Name, "stack", string(debug.Stack()))
+		}
+	}()
+	return j.fn(ctx)
+}
+
+// Status returns the status of the jobs sorted by name.
+func (s *Scheduler) Status() []JobStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		status := j.status
+		if s.ctx == nil || s.ctx.Err() != nil {
+			status.NextRun = time.Time{}
+		}
+		statuses = append(statuses, status)
+	}
+
+	slices.SortFunc(statuses, func(a, b JobStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return statuses
+}
+
+// StatusHandler returns a handler answering Status as JSON, for an ops endpoint.
+// Protect it like other internal routes.
+func (s *Scheduler) StatusHandler() rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		c.SetHeader("Cache-Control", "no-store")
+		return c.JSON(s.Status())
+	}
+}
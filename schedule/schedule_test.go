@@ -0,0 +1,188 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/abiiranathan/rex"
+)
+
+var discard = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+func stop(t *testing.T, s *Scheduler) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOverlappingRunsAreSkipped(t *testing.T) {
+	s := New(discard)
+
+	var active, maxActive atomic.Int64
+	s.Every(10*time.Millisecond, "slow", func(ctx context.Context) error {
+		n := active.Add(1)
+		defer active.Add(-1)
+		if n > maxActive.Load() {
+			maxActive.Store(n)
+		}
+		time.Sleep(45 * time.Millisecond)
+		return nil
+	})
+
+	s.Start()
+	time.Sleep(150 * time.Millisecond)
+	stop(t, s)
+
+	status := s.Status()[0]
+	if maxActive.Load() != 1 {
+		t.Errorf("expected runs not to overlap, got %d concurrent runs", maxActive.Load())
+	}
+
+	if status.Runs == 0 || status.Skipped == 0 {
+		t.Errorf("expected completed and skipped runs, got %+v", status)
+	}
+}
+
+func TestFailuresAndPanicsAreRecorded(t *testing.T) {
+	s := New(discard)
+
+	var calls atomic.Int64
+	s.Every(5*time.Millisecond, "flaky", func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			panic("nil map")
+		}
+		return errors.New("smtp unavailable")
+	})
+
+	s.Start()
+	time.Sleep(30 * time.Millisecond)
+	stop(t, s)
+
+	status := s.Status()[0]
+	if status.Failures != status.Runs || status.Runs < 2 || status.LastError != "smtp unavailable" {
+		t.Errorf("expected every run to fail, got %+v", status)
+	}
+
+	if !status.NextRun.IsZero() {
+		t.Errorf("expected no next run once stopped, got %s", status.NextRun)
+	}
+}
+
+func TestShutdownCancelsRunningJob(t *testing.T) {
+	s := New(discard)
+
+	started := make(chan struct{})
+	var canceled atomic.Bool
+	s.Every(5*time.Millisecond, "export", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		time.Sleep(20 * time.Millisecond) // cleanup after cancellation
+		canceled.Store(true)
+		return ctx.Err()
+	})
+
+	srv := rex.NewServer("", rex.NewRouter())
+	s.Attach(srv)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the job to start with the server")
+	}
+
+	if err := srv.ShutdownContext(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !canceled.Load() {
+		t.Error("expected shutdown to cancel the job and wait for it")
+	}
+}
+
+func TestNextDaily(t *testing.T) {
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		loc  *time.Location
+		want time.Time
+	}{
+		{
+			name: "later today in the location",
+			now:  time.Date(2024, 5, 1, 17, 0, 0, 0, time.UTC), // 02:00 in Tokyo on May 2
+			loc:  tokyo,
+			want: time.Date(2024, 5, 2, 3, 0, 0, 0, tokyo),
+		},
+		{
+			name: "tomorrow when the time passed",
+			now:  time.Date(2024, 5, 1, 19, 0, 0, 0, time.UTC), // 04:00 in Tokyo on May 2
+			loc:  tokyo,
+			want: time.Date(2024, 5, 3, 3, 0, 0, 0, tokyo),
+		},
+		{
+			name: "exactly at the time",
+			now:  time.Date(2024, 5, 2, 3, 0, 0, 0, tokyo),
+			loc:  tokyo,
+			want: time.Date(2024, 5, 3, 3, 0, 0, 0, tokyo),
+		},
+		{
+			name: "across a daylight saving change",
+			now:  time.Date(2024, 3, 9, 12, 0, 0, 0, newYork),
+			loc:  newYork,
+			want: time.Date(2024, 3, 10, 3, 0, 0, 0, newYork), // 23 hours later
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextDaily(tt.now, 3, 0, tt.loc); !got.Equal(tt.want) {
+				t.Errorf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	s := New(discard, WithLocation(time.UTC))
+	s.Daily("03:00", "report", func(ctx context.Context) error { return nil })
+	s.Every(time.Hour, "cleanup", func(ctx context.Context) error { return nil })
+
+	r := rex.NewRouter()
+	r.GET("/ops/jobs", s.StatusHandler())
+
+	res := r.Test(httptest.NewRequest("GET", "/ops/jobs", nil))
+	var statuses []JobStatus
+	if err := res.JSON(&statuses); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(statuses) != 2 || statuses[0].Name != "cleanup" || statuses[1].Schedule != "daily at 03:00" {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+}
@@ -0,0 +1,143 @@
+package rex
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// selfTestExcerpt is the maximum number of body bytes shown for a failing spec.
+const selfTestExcerpt = 200
+
+// SelfTestSpec is a synthetic request executed by SelfTest.
+type SelfTestSpec struct {
+	Name         string      // Name shown in failures. Defaults to "METHOD path".
+	Method       string      // Http method. Defaults to GET.
+	Path         string      // Request path with the query, e.g "/users?page=1".
+	Headers      http.Header // Request headers.
+	Body         string      // Request body.
+	WantStatus   int         // Expected status.
+	WantStatusIn []int       // Expected statuses, used if WantStatus is 0.
+}
+
+// name returns the name of the spec.
+func (s SelfTestSpec) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.method() + " " + s.Path
+}
+
+func (s SelfTestSpec) method() string {
+	if s.Method == "" {
+		return http.MethodGet
+	}
+	return s.Method
+}
+
+// accepts reports whether status is expected. Without an expected status,
+// anything below 500 is accepted.
+func (s SelfTestSpec) accepts(status int) bool {
+	switch {
+	case s.WantStatus != 0:
+		return status == s.WantStatus
+	case len(s.WantStatusIn) > 0:
+		return slices.Contains(s.WantStatusIn, status)
+	}
+	return status < http.StatusInternalServerError
+}
+
+// want describes the expected status for failures.
+func (s SelfTestSpec) want() string {
+	switch {
+	case s.WantStatus != 0:
+		return strconv.Itoa(s.WantStatus)
+	case len(s.WantStatusIn) > 0:
+		statuses := make([]string, len(s.WantStatusIn))
+		for i, status := range s.WantStatusIn {
+			statuses[i] = strconv.Itoa(status)
+		}
+		return "one of " + strings.Join(statuses, ", ")
+	}
+	return "<500"
+}
+
+// SelfTest executes the specs in-process against the router with all middleware and
+// returns an error listing every spec that got an unexpected status or panicked.
+// Call it after registering routes and before the server starts accepting traffic.
+// It also warms up the templates rendered by the routes.
+//
+// Example:
+//
+//	specs := append(r.SelfTestGETRoutes(), rex.SelfTestSpec{
+//		Method: "POST", Path: "/login", WantStatus: http.StatusUnprocessableEntity,
+//	})
+//	if err := r.SelfTest(specs); err != nil {
+//		log.Fatal(err)
+//	}
+func (r *Router) SelfTest(specs []SelfTestSpec) error {
+	var failures []string
+	for _, spec := range specs {
+		if failure := r.runSelfTest(spec); failure != "" {
+			failures = append(failures, fmt.Sprintf("%s: %s", spec.name(), failure))
+		}
+	}
+
+	if len(failures) > 0 {
+		return fmt.Errorf("rex: self-test failed for %d of %d specs:\n\t%s",
+			len(failures), len(specs), strings.Join(failures, "\n\t"))
+	}
+	return nil
+}
+
+// runSelfTest executes spec and returns why it failed or "" if it passed.
+func (r *Router) runSelfTest(spec SelfTestSpec) (failure string) {
+	defer func() {
+		if err := recover(); err != nil {
+			failure = fmt.Sprintf("panic: %v", err)
+		}
+	}()
+
+	req := httptest.NewRequest(spec.method(), spec.Path, strings.NewReader(spec.Body))
+	for key, values := range spec.Headers {
+		req.Header[key] = values
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if spec.accepts(w.Code) {
+		return ""
+	}
+
+	body := strings.TrimSpace(w.Body.String())
+	if len(body) > selfTestExcerpt {
+		body = body[:selfTestExcerpt] + "..."
+	}
+	return fmt.Sprintf("got status %d, want %s: %q", w.Code, spec.want(), body)
+}
+
+// SelfTestGETRoutes returns specs for all GET routes without path parameters,
+// sorted by path, expecting a status below 500. Static file mounts are skipped.
+func (r *Router) SelfTestGETRoutes() []SelfTestSpec {
+	var specs []SelfTestSpec
+	for _, route := range r.routes {
+		if route.method != http.MethodGet || route.static {
+			continue
+		}
+
+		path := strings.TrimSuffix(route.pattern, "{$}")
+		if !strings.HasPrefix(path, "/") || strings.Contains(path, "{") {
+			continue
+		}
+		specs = append(specs, SelfTestSpec{Path: path})
+	}
+
+	slices.SortFunc(specs, func(a, b SelfTestSpec) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	return specs
+}
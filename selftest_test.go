@@ -0,0 +1,103 @@
+package rex_test
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func newSelfTestRouter() *rex.Router {
+	r := rex.NewRouter()
+	r.GET("/{$}", func(c *rex.Context) error {
+		return c.String("home")
+	})
+
+	r.GET("/about", func(c *rex.Context) error {
+		return c.String("about")
+	})
+
+	r.GET("/users/{id}", func(c *rex.Context) error {
+		return c.String(c.Param("id"))
+	})
+
+	r.POST("/login", func(c *rex.Context) error {
+		if c.Request.Header.Get("Content-Type") != "application/json" {
+			return rex.NewError(http.StatusUnsupportedMediaType, "expected JSON")
+		}
+		return c.String("ok")
+	})
+	return r
+}
+
+func TestSelfTestPasses(t *testing.T) {
+	r := newSelfTestRouter()
+
+	specs := append(r.SelfTestGETRoutes(), rex.SelfTestSpec{
+		Method:     http.MethodPost,
+		Path:       "/login",
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       `{"user":"admin"}`,
+		WantStatus: http.StatusOK,
+	}, rex.SelfTestSpec{
+		Method:       http.MethodPost,
+		Path:         "/login",
+		WantStatusIn: []int{http.StatusBadRequest, http.StatusUnsupportedMediaType},
+	})
+
+	if err := r.SelfTest(specs); err != nil {
+		t.Fatalf("expected the self-test to pass, got %v", err)
+	}
+}
+
+func TestSelfTestReportsFailures(t *testing.T) {
+	r := newSelfTestRouter()
+	r.GET("/broken", func(c *rex.Context) error {
+		return errors.New("template dashboard.html is missing")
+	})
+
+	r.GET("/panics", func(c *rex.Context) error {
+		panic("nil map")
+	})
+
+	err := r.SelfTest(append(r.SelfTestGETRoutes(), rex.SelfTestSpec{
+		Name:       "login page",
+		Method:     http.MethodPost,
+		Path:       "/login",
+		WantStatus: http.StatusOK,
+	}))
+	if err == nil {
+		t.Fatal("expected the self-test to fail")
+	}
+
+	msg := err.Error()
+	for _, want := range []string{
+		"3 of 5 specs",
+		`GET /broken: got status 500, want <500: "template dashboard.html is missing"`,
+		"GET /panics: panic: nil map",
+		"login page: got status 415, want 200",
+	} {
+		if !strings.Contains(msg, want) {
+			t.Errorf("expected %q in the error, got:\n%s", want, msg)
+		}
+	}
+
+	if strings.Contains(msg, "/about") {
+		t.Errorf("expected passing specs to be left out, got:\n%s", msg)
+	}
+}
+
+func TestSelfTestGETRoutes(t *testing.T) {
+	r := newSelfTestRouter()
+
+	var paths []string
+	for _, spec := range r.SelfTestGETRoutes() {
+		paths = append(paths, spec.Path)
+	}
+
+	if got := strings.Join(paths, " "); got != "/ /about" {
+		t.Errorf("expected the parameterless GET routes, got %q", got)
+	}
+}
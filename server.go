@@ -3,10 +3,12 @@ package rex
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"time"
 
 	"golang.org/x/net/http2"
@@ -14,8 +16,25 @@ import (
 
 // Wrapper around the standard http.Server.
 // Adds easy graceful shutdown, functional options for customizing the server, and HTTP/2 support.
+//
+// ListenAndServe and ListenAndServeTLS both return http.ErrServerClosed after a graceful
+// shutdown. Wrap their result with IgnoreServerClosed to treat it as a clean exit.
 type Server struct {
 	*http.Server
+
+	// Answers ACME HTTP-01 challenges when WithAutocert is used.
+	challengeServer *http.Server
+
+	// Connection states and the shutdown notification for handlers.
+	conns          *connTracker
+	shutdownCtx    context.Context
+	shutdownCancel context.CancelFunc
+	gracePeriod    time.Duration
+
+	// Hooks registered with OnStartup and OnShutdown.
+	startupHooks  []func()
+	startOnce     sync.Once
+	shutdownHooks []func(ctx context.Context) error
 }
 
 // Option for configuring the server.
@@ -24,7 +43,7 @@ type ServerOption func(*Server)
 // Create a new Server instance with HTTP/2 support.
 func NewServer(addr string, handler http.Handler, options ...ServerOption) *Server {
 	server := &Server{
-		&http.Server{
+		Server: &http.Server{
 			Addr:         addr,
 			Handler:      handler,
 			ReadTimeout:  5 * time.Second,
@@ -38,6 +57,7 @@ func NewServer(addr string, handler http.Handler, options ...ServerOption) *Serv
 
 	// Explicitly enable HTTP/2
 	http2.ConfigureServer(server.Server, &http2.Server{})
+	server.trackConnections()
 
 	for _, option := range options {
 		option(server)
@@ -47,6 +67,9 @@ func NewServer(addr string, handler http.Handler, options ...ServerOption) *Serv
 
 // Gracefully shuts down the server. The default timeout is 5 seconds
 // to wait for pending connections.
+// Shutdown blocks until an interrupt signal (os.Interrupt) is received.
+// Use ShutdownContext to shut down programmatically or ShutdownOnSignals to
+// compose with your own signal handling.
 func (s *Server) Shutdown(timeout ...time.Duration) {
 	var t time.Duration
 	if len(timeout) > 0 {
@@ -55,15 +78,108 @@ func (s *Server) Shutdown(timeout ...time.Duration) {
 		t = 5 * time.Second
 	}
 
+	if err := <-s.ShutdownOnSignals(t, os.Interrupt); err != nil {
+		log.Fatalf("Could not gracefully shutdown the server: %v\n", err)
+	}
+}
+
+// ShutdownContext gracefully shuts down the server without interrupting active connections.
+// It closes ShutdownChannel for handlers and waits for pending requests until ctx is done.
+// With WithShutdownGracePeriod, connections still open after the grace period are closed.
+// The OnShutdown hooks run next, then if the handler is a *Router, the events emitted
+// with c.Emit are delivered before it returns. See http.Server.Shutdown.
+func (s *Server) ShutdownContext(ctx context.Context) error {
+	if s.shutdownCancel != nil {
+		s.shutdownCancel()
+	}
+
+	if s.gracePeriod > 0 {
+		timer := time.AfterFunc(s.gracePeriod, func() { s.Server.Close() })
+		defer timer.Stop()
+	}
+
+	err := s.Server.Shutdown(ctx)
+	if errors.Is(err, http.ErrServerClosed) {
+		err = nil
+	}
+
+	for _, hook := range s.shutdownHooks {
+		if hookErr := hook(ctx); err == nil {
+			err = hookErr
+		}
+	}
+
+	if router, ok := s.Handler.(*Router); ok {
+		if drainErr := router.events.Close(ctx); err == nil {
+			err = drainErr
+		}
+	}
+	return err
+}
+
+// OnStartup registers fn to run once when the server starts serving, after its listener is open.
+// Register hooks before calling ListenAndServe. The hooks run in order of registration
+// and should return quickly, start goroutines for long running work.
+func (s *Server) OnStartup(fn func()) {
+	s.startupHooks = append(s.startupHooks, fn)
+}
+
+// OnShutdown registers fn to run during ShutdownContext after pending requests completed,
+// e.g to stop background workers. fn gets the shutdown context and should return when it is done.
+// The hooks run in order of registration and the first error is returned by ShutdownContext.
+func (s *Server) OnShutdown(fn func(ctx context.Context) error) {
+	s.shutdownHooks = append(s.shutdownHooks, fn)
+}
+
+// runStartupHooks calls the OnStartup hooks the first time the server serves.
+func (s *Server) runStartupHooks() {
+	s.startOnce.Do(func() {
+		for _, hook := range s.startupHooks {
+			hook()
+		}
+	})
+}
+
+// ShutdownOnSignals shuts down the server with the given timeout when one of sigs is received.
+// If no signals are given, os.Interrupt is used. It returns immediately; the returned channel
+// receives the result of the shutdown and is then closed.
+//
+// Example:
+//
+//	done := srv.ShutdownOnSignals(10*time.Second, os.Interrupt, syscall.SIGTERM)
+//	if err := rex.IgnoreServerClosed(srv.ListenAndServe()); err != nil {
+//		log.Fatal(err)
+//	}
+//	<-done
+func (s *Server) ShutdownOnSignals(timeout time.Duration, sigs ...os.Signal) <-chan error {
+	if len(sigs) == 0 {
+		sigs = []os.Signal{os.Interrupt}
+	}
+
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt)
-	<-quit
-	ctx, cancel := context.WithTimeout(context.Background(), t)
-	defer cancel()
+	signal.Notify(quit, sigs...)
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(done)
+		<-quit
+		signal.Stop(quit)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		done <- s.ShutdownContext(ctx)
+	}()
+	return done
+}
 
-	if err := s.Server.Shutdown(ctx); err != nil && err != http.ErrServerClosed {
-		log.Fatalf("Could not gracefully shutdown the server: %v\n", err)
+// IgnoreServerClosed returns nil if err is http.ErrServerClosed.
+// ListenAndServe and ListenAndServeTLS return http.ErrServerClosed once the server
+// is shut down, which is the expected outcome of a graceful shutdown.
+func IgnoreServerClosed(err error) error {
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
 	}
+	return err
 }
 
 func WithReadTimeout(d time.Duration) ServerOption {
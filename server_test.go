@@ -1,6 +1,7 @@
 package rex
 
 import (
+	"context"
 	"crypto/ecdsa"
 	"crypto/elliptic"
 	"crypto/rand"
@@ -8,13 +9,14 @@ import (
 	"crypto/x509"
 	"crypto/x509/pkix"
 	"encoding/pem"
+	"errors"
+	"io"
 	"math/big"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
 	"slices"
-	"syscall"
 	"testing"
 	"time"
 
@@ -135,25 +137,123 @@ func TestServerOptions(t *testing.T) {
 }
 
 func TestServerShutdown(t *testing.T) {
-	server := NewServer(":0", &TestHandler{})
+	r := NewRouter()
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) error {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		return c.String("done")
+	})
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
 
-	// Start server in goroutine
+	server := NewServer(ln.Addr().String(), r)
+	serveErr := make(chan error, 1)
 	go func() {
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
-			t.Errorf("Expected ErrServerClosed, got %v", err)
-		}
+		serveErr <- server.Serve(ln)
 	}()
 
-	// Allow server to start
-	time.Sleep(100 * time.Millisecond)
-
-	// Trigger shutdown
+	// A request in flight when shutdown starts must complete.
+	type result struct {
+		body string
+		err  error
+	}
+	res := make(chan result, 1)
 	go func() {
-		time.Sleep(100 * time.Millisecond)
-		syscall.Kill(syscall.Getpid(), syscall.SIGINT)
+		resp, err := http.Get("http://" + ln.Addr().String() + "/slow")
+		if err != nil {
+			res <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		res <- result{string(b), err}
 	}()
 
-	server.Shutdown(2 * time.Second)
+	<-started
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := server.ShutdownContext(ctx); err != nil {
+		t.Fatalf("ShutdownContext: %v", err)
+	}
+
+	if err := <-serveErr; err != http.ErrServerClosed {
+		t.Errorf("expected ErrServerClosed, got %v", err)
+	}
+
+	if err := IgnoreServerClosed(http.ErrServerClosed); err != nil {
+		t.Errorf("expected IgnoreServerClosed to return nil, got %v", err)
+	}
+
+	got := <-res
+	if got.err != nil || got.body != "done" {
+		t.Errorf("expected in-flight request to complete, got %q, %v", got.body, got.err)
+	}
+}
+
+func TestServerShutdownContextExpired(t *testing.T) {
+	r := NewRouter()
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	r.GET("/hang", func(c *Context) error {
+		close(started)
+		<-unblock
+		return nil
+	})
+	defer close(unblock)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(ln.Addr().String(), r)
+	go server.Serve(ln)
+	go http.Get("http://" + ln.Addr().String() + "/hang")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := server.ShutdownContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestServerLifecycleHooks(t *testing.T) {
+	server := NewServer("", NewRouter())
+
+	var order []string
+	started := make(chan struct{})
+	server.OnStartup(func() {
+		order = append(order, "startup")
+		close(started)
+	})
+
+	shutdownErr := errors.New("worker did not stop")
+	server.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "shutdown 1")
+		return shutdownErr
+	})
+	server.OnShutdown(func(ctx context.Context) error {
+		order = append(order, "shutdown 2")
+		return nil
+	})
+
+	startServer(t, server)
+	<-started
+
+	if err := server.ShutdownContext(context.Background()); err != shutdownErr {
+		t.Errorf("expected the hook error, got %v", err)
+	}
+
+	if !slices.Equal(order, []string{"startup", "shutdown 1", "shutdown 2"}) {
+		t.Errorf("unexpected hook order %v", order)
+	}
 }
 
 // CertConfig holds configuration for certificate generation
@@ -341,3 +441,103 @@ func TestWriteCertFiles(t *testing.T) {
 		t.Errorf("Expected 1 certificate, got %d", len(config.Certificates))
 	}
 }
+
+func TestWithAutoReloadCerts(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+
+	writeCert := func(org string, modTime time.Time) {
+		t.Helper()
+		config := DefaultCertConfig()
+		config.Organization = org
+
+		certPEM, keyPEM, err := GenerateCert(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := WriteCertFiles(certPEM, keyPEM, certPath, keyPath); err != nil {
+			t.Fatal(err)
+		}
+
+		// Guarantee a different mtime even on file systems with coarse timestamps.
+		os.Chtimes(certPath, modTime, modTime)
+		os.Chtimes(keyPath, modTime, modTime)
+	}
+
+	writeCert("First Org", time.Now().Add(-time.Hour))
+
+	server := NewServer("127.0.0.1:0", &TestHandler{}, WithAutoReloadCerts(certPath, keyPath, 10*time.Millisecond))
+	if !slices.Contains(server.TLSConfig.NextProtos, "h2") {
+		t.Error("expected HTTP/2 to remain enabled")
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go server.ServeTLS(ln, "", "")
+	defer server.ShutdownContext(context.Background())
+
+	presented := func() string {
+		t.Helper()
+		conn, err := tls.Dial("tcp", ln.Addr().String(), &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.Organization[0]
+	}
+
+	if org := presented(); org != "First Org" {
+		t.Fatalf("expected First Org, got %s", org)
+	}
+
+	writeCert("Second Org", time.Now())
+	time.Sleep(20 * time.Millisecond)
+
+	// The first handshake after the interval triggers the reload.
+	deadline := time.Now().Add(2 * time.Second)
+	for presented() != "Second Org" {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the new certificate to be presented after the files changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// A broken key pair keeps the previous certificate.
+	os.WriteFile(keyPath, []byte("garbage"), 0600)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(keyPath, later, later)
+	time.Sleep(20 * time.Millisecond)
+
+	if org := presented(); org != "Second Org" {
+		t.Errorf("expected the previous certificate after a failed reload, got %s", org)
+	}
+}
+
+func TestWithAutocert(t *testing.T) {
+	server := NewServer(":0", &TestHandler{}, WithAutocert([]string{"example.com"}, t.TempDir()))
+
+	if server.TLSConfig.GetCertificate == nil {
+		t.Fatal("expected GetCertificate to be set")
+	}
+
+	for _, proto := range []string{"h2", "http/1.1", "acme-tls/1"} {
+		if !slices.Contains(server.TLSConfig.NextProtos, proto) {
+			t.Errorf("expected %s in NextProtos, got %v", proto, server.TLSConfig.NextProtos)
+		}
+	}
+
+	if server.challengeServer == nil || server.challengeServer.Addr != ":80" {
+		t.Error("expected the HTTP-01 challenge server to be configured on :80")
+	}
+
+	// Hosts outside the policy are refused without contacting the ACME server.
+	_, err := server.TLSConfig.GetCertificate(&tls.ClientHelloInfo{ServerName: "evil.com"})
+	if err == nil {
+		t.Error("expected a host outside the policy to be rejected")
+	}
+}
@@ -0,0 +1,126 @@
+package rex
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+type shutdownContextKey struct{}
+
+// ConnStats is the number of connections of a Server by state.
+// See http.ConnState for the meaning of each state.
+type ConnStats struct {
+	New      int // Connections that have not yet sent a request.
+	Active   int // Connections serving a request.
+	Idle     int // Keep-alive connections waiting for the next request.
+	Hijacked int // Connections taken over by a handler since the server started.
+}
+
+// Open returns the number of connections still managed by the server.
+func (s ConnStats) Open() int {
+	return s.New + s.Active + s.Idle
+}
+
+// connTracker records the state of every open connection.
+type connTracker struct {
+	mu       sync.Mutex
+	states   map[net.Conn]http.ConnState
+	hijacked int
+}
+
+func (t *connTracker) track(conn net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	switch state {
+	case http.StateClosed:
+		delete(t.states, conn)
+	case http.StateHijacked:
+		// The server never reports hijacked connections as closed.
+		delete(t.states, conn)
+		t.hijacked++
+	default:
+		t.states[conn] = state
+	}
+}
+
+func (t *connTracker) stats() ConnStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	stats := ConnStats{Hijacked: t.hijacked}
+	for _, state := range t.states {
+		switch state {
+		case http.StateNew:
+			stats.New++
+		case http.StateActive:
+			stats.Active++
+		case http.StateIdle:
+			stats.Idle++
+		}
+	}
+	return stats
+}
+
+// trackConnections installs the ConnState callback and the base context that
+// carries the shutdown notification to handlers and runs the OnStartup hooks.
+func (s *Server) trackConnections() {
+	s.conns = &connTracker{states: make(map[net.Conn]http.ConnState)}
+	s.shutdownCtx, s.shutdownCancel = context.WithCancel(context.Background())
+
+	s.Server.ConnState = func(conn net.Conn, state http.ConnState) {
+		s.conns.track(conn, state)
+	}
+
+	// BaseContext is called when Serve starts, after the listener is open.
+	s.Server.BaseContext = func(net.Listener) context.Context {
+		s.runStartupHooks()
+		return context.WithValue(context.Background(), shutdownContextKey{}, s.shutdownCtx.Done())
+	}
+
+	// Notify handlers when http.Server.Shutdown is called directly as well.
+	s.RegisterOnShutdown(s.shutdownCancel)
+}
+
+// ConnectionStats returns the number of open connections by state.
+func (s *Server) ConnectionStats() ConnStats {
+	if s.conns == nil {
+		return ConnStats{}
+	}
+	return s.conns.stats()
+}
+
+// WithShutdownGracePeriod force-closes connections still open d after the shutdown
+// started, for handlers that do not return when ShutdownChannel is closed.
+// The shutdown still returns early if its context is done first.
+func WithShutdownGracePeriod(d time.Duration) ServerOption {
+	return func(s *Server) {
+		s.gracePeriod = d
+	}
+}
+
+// ShutdownChannel returns a channel that is closed when the Server serving the request
+// starts shutting down. Long-lived handlers like server-sent event streams should select
+// on it and return, since the server waits for active requests before it exits.
+// It returns nil (blocking forever) for requests not served by a rex.Server.
+//
+// Example:
+//
+//	for {
+//		select {
+//		case <-rex.ShutdownChannel(c):
+//			return nil
+//		case <-c.Request.Context().Done():
+//			return nil
+//		case msg := <-messages:
+//			fmt.Fprintf(c.Response, "data: %s\n\n", msg)
+//			c.Response.(http.Flusher).Flush()
+//		}
+//	}
+func ShutdownChannel(c *Context) <-chan struct{} {
+	done, _ := c.Request.Context().Value(shutdownContextKey{}).(<-chan struct{})
+	return done
+}
@@ -0,0 +1,140 @@
+package rex
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer serves srv on a random port and returns its base URL.
+func startServer(t *testing.T, srv *Server) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	return "http://" + ln.Addr().String()
+}
+
+// openStream starts a request to url and waits for the first event.
+func openStream(t *testing.T, url string) *http.Response {
+	t.Helper()
+
+	res, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "data:") {
+		t.Fatalf("expected an event, got %q (%v)", line, err)
+	}
+	return res
+}
+
+func TestShutdownChannelEndsStreams(t *testing.T) {
+	returned := make(chan struct{})
+
+	r := NewRouter()
+	r.GET("/events", func(c *Context) error {
+		defer close(returned)
+		c.SetHeader("Content-Type", ContentTypeEventStream)
+
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+
+		for i := 0; ; i++ {
+			select {
+			case <-ShutdownChannel(c):
+				return nil
+			case <-c.Request.Context().Done():
+				return nil
+			case <-ticker.C:
+				fmt.Fprintf(c.Response, "data: %d\n\n", i)
+				c.Response.(http.Flusher).Flush()
+			}
+		}
+	})
+
+	srv := NewServer("", r)
+	url := startServer(t, srv)
+	openStream(t, url+"/events")
+
+	if stats := srv.ConnectionStats(); stats.Active != 1 {
+		t.Errorf("expected 1 active connection, got %+v", stats)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := srv.ShutdownContext(ctx); err != nil {
+		t.Fatalf("unexpected shutdown error: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the stream to end promptly, shutdown took %v", elapsed)
+	}
+
+	select {
+	case <-returned:
+	default:
+		t.Error("expected the handler to return on shutdown")
+	}
+}
+
+func TestShutdownGracePeriodClosesConnections(t *testing.T) {
+	r := NewRouter()
+	r.GET("/events", func(c *Context) error {
+		c.SetHeader("Content-Type", ContentTypeEventStream)
+		fmt.Fprint(c.Response, "data: hello\n\n")
+		c.Response.(http.Flusher).Flush()
+
+		// Ignores the shutdown notification.
+		<-c.Request.Context().Done()
+		return nil
+	})
+
+	srv := NewServer("", r, WithShutdownGracePeriod(100*time.Millisecond))
+	url := startServer(t, srv)
+	openStream(t, url+"/events")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	start := time.Now()
+	srv.ShutdownContext(ctx)
+
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("expected connections to be closed after the grace period, shutdown took %v", elapsed)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for srv.ConnectionStats().Open() != 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected no open connections, got %+v", srv.ConnectionStats())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestShutdownChannelOutsideServer(t *testing.T) {
+	r := NewRouter()
+	r.GET("/", func(c *Context) error {
+		if ShutdownChannel(c) != nil {
+			t.Error("expected a nil channel outside a rex.Server")
+		}
+		return nil
+	})
+	r.Test(NewTestRequest(http.MethodGet, "/").Build())
+}
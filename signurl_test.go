@@ -0,0 +1,92 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestSignedURL(t *testing.T) {
+	oldKey := []byte("old-signing-key")
+	newKey := []byte("new-signing-key")
+
+	r := rex.NewRouter()
+	r.GET("/files/{id}", func(c *rex.Context) error {
+		return c.String("file " + c.Param("id") + " " + c.Query("name"))
+	}, rex.VerifySignedURL(newKey, oldKey))
+
+	get := func(target string) *rex.TestResponse {
+		return r.Test(httptest.NewRequest(http.MethodGet, target, nil))
+	}
+
+	link := rex.SignURL(newKey, "/files/42", url.Values{"name": {"report.pdf"}}, time.Hour)
+	if res := get(link); res.Status() != http.StatusOK || res.BodyString() != "file 42 report.pdf" {
+		t.Fatalf("expected valid link to work, got %d: %s", res.Status(), res.BodyString())
+	}
+
+	t.Run("rotated key", func(t *testing.T) {
+		link := rex.SignURL(oldKey, "/files/42", nil, time.Hour)
+		if res := get(link); res.Status() != http.StatusOK {
+			t.Errorf("expected link signed with old key to work, got %d", res.Status())
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		link := rex.SignURL([]byte("other"), "/files/42", nil, time.Hour)
+		if res := get(link); res.Status() != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", res.Status())
+		}
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		link := rex.SignURL(newKey, "/files/42", nil, -time.Minute)
+		if res := get(link); res.Status() != http.StatusGone {
+			t.Errorf("expected 410, got %d", res.Status())
+		}
+	})
+
+	t.Run("missing signature", func(t *testing.T) {
+		if res := get("/files/42?name=report.pdf"); res.Status() != http.StatusForbidden {
+			t.Errorf("expected 403, got %d", res.Status())
+		}
+	})
+
+	tampered := map[string]string{
+		"path":        strings.Replace(link, "/files/42", "/files/43", 1),
+		"param":       strings.Replace(link, "report.pdf", "secret.pdf", 1),
+		"expiry":      strings.Replace(link, "exp=", "exp=9", 1),
+		"added param": link + "&admin=1",
+	}
+
+	for name, target := range tampered {
+		t.Run("tampered "+name, func(t *testing.T) {
+			if res := get(target); res.Status() != http.StatusForbidden {
+				t.Errorf("expected 403 for %s, got %d", target, res.Status())
+			}
+		})
+	}
+}
+
+func TestSignURLStableParams(t *testing.T) {
+	key := []byte("key")
+	r := rex.NewRouter()
+	r.GET("/dl", func(c *rex.Context) error {
+		return c.VerifySignature(key)
+	})
+
+	// Parameters in the path and in params are both signed, in any order.
+	link := rex.SignURL(key, "/dl?b=2", url.Values{"a": {"1"}}, time.Hour)
+	u, _ := url.Parse(link)
+	q := u.Query()
+
+	reordered := "/dl?sig=" + url.QueryEscape(q.Get("sig")) + "&exp=" + q.Get("exp") + "&b=2&a=1"
+	res := r.Test(httptest.NewRequest(http.MethodGet, reordered, nil))
+	if res.Status() != http.StatusOK {
+		t.Errorf("expected reordered params to verify, got %d", res.Status())
+	}
+}
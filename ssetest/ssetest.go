@@ -0,0 +1,339 @@
+// Package ssetest parses server-sent event streams in tests, so that assertions are made
+// on events instead of raw bodies.
+//
+// ParseEvents parses a complete body, e.g from r.Test. Subscribe runs a handler against a
+// live stream and returns its events one at a time, skipping keepalive comments.
+//
+// Example:
+//
+//	events, err := ssetest.ParseEvents(res.Body)
+//
+//	sub := ssetest.Subscribe(t, r, "/events")
+//	ev, err := sub.Next(time.Second)
+//	if err != nil || ev.Event != "ready" {
+//		t.Fatalf("expected the ready event, got %+v, %v", ev, err)
+//	}
+package ssetest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// ErrTimeout is returned by Subscription.Next when no event arrives in time.
+var ErrTimeout = errors.New("ssetest: timed out waiting for an event")
+
+// Event is a parsed server-sent event.
+type Event struct {
+	ID       string        // value of the id field, "" if not set
+	Event    string        // value of the event field, "" for the default "message" type
+	Data     string        // data fields joined with "\n"
+	Retry    time.Duration // value of the retry field, 0 if not set
+	Comments []string      // comment lines without the leading ":" and space
+}
+
+// IsComment reports whether the event only has comments, like a keepalive.
+func (e Event) IsComment() bool {
+	return len(e.Comments) > 0 && e.ID == "" && e.Event == "" && e.Data == "" && e.Retry == 0
+}
+
+// Decoder reads events from a stream as they arrive.
+type Decoder struct {
+	r       *bufio.Reader
+	started bool // the byte order mark was checked
+	skipLF  bool // the previous line ended with "\r", a following "\n" belongs to it
+}
+
+// NewDecoder returns a decoder reading events from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return &Decoder{r: bufio.NewReader(r)}
+}
+
+// Next returns the next event. Lines are terminated by "\r\n", "\n" or "\r" and a leading
+// byte order mark is ignored. Blocks with no fields, and an incomplete block at the end of
+// the stream, are not events. It returns io.EOF at the end of the stream.
+func (d *Decoder) Next() (Event, error) {
+	var ev Event
+	var data []string
+	var seen bool
+
+	for {
+		line, err := d.readLine()
+		if err != nil {
+			return Event{}, err
+		}
+
+		if line == "" {
+			if !seen {
+				continue
+			}
+			ev.Data = strings.Join(data, "\n")
+			return ev, nil
+		}
+
+		if comment, ok := strings.CutPrefix(line, ":"); ok {
+			ev.Comments = append(ev.Comments, strings.TrimPrefix(comment, " "))
+			seen = true
+			continue
+		}
+
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "id":
+			if strings.ContainsRune(value, 0) {
+				continue // Ignored like browsers do.
+			}
+			ev.ID = value
+		case "event":
+			ev.Event = value
+		case "data":
+			data = append(data, value)
+		case "retry":
+			ms, err := strconv.Atoi(value)
+			if err != nil || ms < 0 {
+				continue // Ignored like browsers do.
+			}
+			ev.Retry = time.Duration(ms) * time.Millisecond
+		default:
+			continue
+		}
+		seen = true
+	}
+}
+
+// readLine returns the next line without its terminator.
+func (d *Decoder) readLine() (string, error) {
+	if !d.started {
+		d.started = true
+		if bom, err := d.r.Peek(3); err == nil && bytes.Equal(bom, []byte("\xEF\xBB\xBF")) {
+			d.r.Discard(3)
+		}
+	}
+
+	var line []byte
+	for {
+		b, err := d.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", err
+		}
+
+		skipLF := d.skipLF
+		d.skipLF = false
+		switch {
+		case b == '\n' && skipLF && len(line) == 0:
+			continue
+		case b == '\n':
+			return string(line), nil
+		case b == '\r':
+			// Do not wait for the next byte of a live stream to find out if it is "\n".
+			d.skipLF = true
+			return string(line), nil
+		}
+		line = append(line, b)
+	}
+}
+
+// ParseEvents parses a complete event stream body, including comment-only events.
+func ParseEvents(r io.Reader) ([]Event, error) {
+	d := NewDecoder(r)
+
+	var events []Event
+	for {
+		ev, err := d.Next()
+		if err == io.EOF {
+			return events, nil
+		}
+
+		if err != nil {
+			return events, err
+		}
+		events = append(events, ev)
+	}
+}
+
+// Option configures a Subscription.
+type Option func(*Subscription)
+
+// KeepComments makes Next return comment-only events, like keepalives, instead of skipping them.
+func KeepComments() Option {
+	return func(s *Subscription) {
+		s.keepComments = true
+	}
+}
+
+// Subscription is a live event stream of a handler running in the background.
+// It is stopped, and the handler waited for, when the test ends.
+type Subscription struct {
+	keepComments bool
+	w            *pipeWriter
+	events       chan Event
+	err          error // error ending the stream, read after events is closed
+	done         chan struct{}
+}
+
+// Subscribe sends a GET request for path, accepting text/event-stream, to h.
+// See SubscribeRequest.
+func Subscribe(t testing.TB, h http.Handler, path string, options ...Option) *Subscription {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Accept", rex.ContentTypeEventStream)
+	return SubscribeRequest(t, h, req, options...)
+}
+
+// SubscribeFunc subscribes to the stream of handler, registered at "/" on a new router.
+// See SubscribeRequest.
+func SubscribeFunc(t testing.TB, handler rex.HandlerFunc, options ...Option) *Subscription {
+	t.Helper()
+	r := rex.NewRouter()
+	r.GET("/", handler)
+	return Subscribe(t, r, "/", options...)
+}
+
+// SubscribeRequest serves req with h in a goroutine, writing the response to a pipe
+// read by Next. Set headers like Last-Event-ID on req. The request context is canceled
+// when the test ends and the handler must return then.
+func SubscribeRequest(t testing.TB, h http.Handler, req *http.Request, options ...Option) *Subscription {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	pr, pw := io.Pipe()
+
+	s := &Subscription{
+		w:      newPipeWriter(pw),
+		events: make(chan Event),
+		done:   make(chan struct{}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		defer pw.Close()
+		defer s.w.headerDone()
+		h.ServeHTTP(s.w, req.WithContext(ctx))
+	}()
+
+	go s.read(ctx, NewDecoder(pr))
+
+	t.Cleanup(func() {
+		cancel()
+		pr.Close() // Unblocks writes of a handler ignoring the context.
+		<-served
+		<-s.done
+	})
+	return s
+}
+
+// read sends the decoded events until the stream ends or ctx is canceled.
+func (s *Subscription) read(ctx context.Context, d *Decoder) {
+	defer close(s.done)
+	defer close(s.events)
+
+	for {
+		ev, err := d.Next()
+		if err != nil {
+			s.err = err
+			return
+		}
+
+		if ev.IsComment() && !s.keepComments {
+			continue
+		}
+
+		select {
+		case s.events <- ev:
+		case <-ctx.Done():
+			s.err = ctx.Err()
+			return
+		}
+	}
+}
+
+// Next returns the next event, waiting up to timeout. It returns ErrTimeout if none
+// arrives in time and io.EOF once the handler returned and all events were read.
+func (s *Subscription) Next(timeout time.Duration) (Event, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case ev, ok := <-s.events:
+		if !ok {
+			if s.err == io.ErrClosedPipe {
+				return Event{}, io.EOF
+			}
+			return Event{}, s.err
+		}
+		return ev, nil
+	case <-timer.C:
+		return Event{}, ErrTimeout
+	}
+}
+
+// Status returns the response status, waiting until the handler writes the header or returns.
+func (s *Subscription) Status() int {
+	<-s.w.headerWritten
+	return s.w.status
+}
+
+// Header returns the response headers, waiting until the handler writes the header or returns.
+func (s *Subscription) Header() http.Header {
+	<-s.w.headerWritten
+	return s.w.written
+}
+
+// pipeWriter is a ResponseWriter writing the body to a pipe.
+type pipeWriter struct {
+	header        http.Header
+	pipe          *io.PipeWriter
+	once          sync.Once
+	headerWritten chan struct{} // closed once status and written are set
+	status        int
+	written       http.Header // copy of header when it was written
+}
+
+func newPipeWriter(pipe *io.PipeWriter) *pipeWriter {
+	return &pipeWriter{header: make(http.Header), pipe: pipe, headerWritten: make(chan struct{})}
+}
+
+func (w *pipeWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *pipeWriter) WriteHeader(status int) {
+	w.once.Do(func() {
+		w.status = status
+		w.written = w.header.Clone()
+		close(w.headerWritten)
+	})
+}
+
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	w.WriteHeader(http.StatusOK)
+	return w.pipe.Write(p)
+}
+
+// Flush does nothing, writes go to the pipe unbuffered.
+func (w *pipeWriter) Flush() {}
+
+// headerDone records a 200 OK for handlers returning without writing.
+func (w *pipeWriter) headerDone() {
+	w.WriteHeader(http.StatusOK)
+}
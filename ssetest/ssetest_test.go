@@ -0,0 +1,176 @@
+package ssetest_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/ssetest"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []ssetest.Event
+	}{
+		{
+			name: "fields",
+			body: "id: 7\nevent: update\nretry: 1500\ndata: hello\n\n",
+			want: []ssetest.Event{{ID: "7", Event: "update", Data: "hello", Retry: 1500 * time.Millisecond}},
+		},
+		{
+			name: "multiline data",
+			body: "data: line one\ndata:line two\ndata\ndata:  indented\n\n",
+			want: []ssetest.Event{{Data: "line one\nline two\n\n indented"}},
+		},
+		{
+			name: "byte order mark",
+			body: "\xEF\xBB\xBFdata: first\n\ndata: second\n\n",
+			want: []ssetest.Event{{Data: "first"}, {Data: "second"}},
+		},
+		{
+			name: "CRLF line endings",
+			body: "event: a\r\ndata: 1\r\n\r\nevent: b\r\ndata: 2\r\n\r\n",
+			want: []ssetest.Event{{Event: "a", Data: "1"}, {Event: "b", Data: "2"}},
+		},
+		{
+			name: "CR line endings",
+			body: "data: 1\r\rdata: 2\r\r",
+			want: []ssetest.Event{{Data: "1"}, {Data: "2"}},
+		},
+		{
+			name: "comments",
+			body: ": keepalive\n\n:no space\ndata: x\n\n",
+			want: []ssetest.Event{{Comments: []string{"keepalive"}}, {Comments: []string{"no space"}, Data: "x"}},
+		},
+		{
+			name: "ignored fields",
+			body: "unknown: 1\nretry: soon\nid: a\x00b\n\n\n\ndata: kept\n\n",
+			want: []ssetest.Event{{Data: "kept"}},
+		},
+		{
+			name: "empty data",
+			body: "data\n\n",
+			want: []ssetest.Event{{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := ssetest.ParseEvents(strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(events, tt.want) {
+				t.Errorf("expected %+v, got %+v", tt.want, events)
+			}
+		})
+	}
+}
+
+func TestParseEventsIncomplete(t *testing.T) {
+	events, err := ssetest.ParseEvents(strings.NewReader("data: done\n\ndata: cut"))
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+
+	if len(events) != 1 || events[0].Data != "done" {
+		t.Errorf("expected the complete event, got %+v", events)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	next := make(chan struct{})
+	r := rex.NewRouter()
+	r.GET("/events", func(c *rex.Context) error {
+		c.SetHeader("Content-Type", rex.ContentTypeEventStream)
+		w := c.StreamingWriter(rex.FlushEvery(1))
+
+		for i := 1; i <= 3; i++ {
+			select {
+			case <-next:
+			case <-c.Request.Context().Done():
+				return nil
+			}
+			fmt.Fprintf(w, ": keepalive\n\nid: %d\ndata: tick %d\n\n", i, i)
+		}
+		return nil
+	})
+
+	sub := ssetest.Subscribe(t, r, "/events")
+	if _, err := sub.Next(20 * time.Millisecond); !errors.Is(err, ssetest.ErrTimeout) {
+		t.Fatalf("expected a timeout before the first event, got %v", err)
+	}
+
+	for i := 1; i <= 3; i++ {
+		next <- struct{}{}
+		ev, err := sub.Next(time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if ev.ID != fmt.Sprint(i) || ev.Data != fmt.Sprintf("tick %d", i) {
+			t.Errorf("expected event %d, got %+v", i, ev)
+		}
+	}
+
+	if sub.Status() != http.StatusOK || sub.Header().Get("Content-Type") != rex.ContentTypeEventStream {
+		t.Errorf("expected a 200 event stream, got %d %q", sub.Status(), sub.Header().Get("Content-Type"))
+	}
+
+	if _, err := sub.Next(time.Second); err != io.EOF {
+		t.Errorf("expected io.EOF once the handler returned, got %v", err)
+	}
+}
+
+func TestSubscribeKeepComments(t *testing.T) {
+	sub := ssetest.SubscribeFunc(t, func(c *rex.Context) error {
+		_, err := io.WriteString(c.Response, ": keepalive\n\ndata: x\n\n")
+		return err
+	}, ssetest.KeepComments())
+
+	ev, err := sub.Next(time.Second)
+	if err != nil || !ev.IsComment() || ev.Comments[0] != "keepalive" {
+		t.Fatalf("expected the keepalive comment, got %+v, %v", ev, err)
+	}
+
+	if ev, err := sub.Next(time.Second); err != nil || ev.Data != "x" {
+		t.Errorf("expected the data event, got %+v, %v", ev, err)
+	}
+}
+
+func TestSubscribeRequestStopsHandler(t *testing.T) {
+	stopped := make(chan struct{})
+	r := rex.NewRouter()
+	r.GET("/events", func(c *rex.Context) error {
+		defer close(stopped)
+		fmt.Fprintf(c.Response, "id: %s\ndata: resumed\n\n", c.GetHeader("Last-Event-ID"))
+		<-c.Request.Context().Done()
+		return nil
+	})
+
+	t.Run("subscription", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/events", nil)
+		req.Header.Set("Last-Event-ID", "41")
+		sub := ssetest.SubscribeRequest(t, r, req)
+
+		if ev, err := sub.Next(time.Second); err != nil || ev.ID != "41" {
+			t.Errorf("expected the Last-Event-ID to reach the handler, got %+v, %v", ev, err)
+		}
+	})
+
+	select {
+	case <-stopped:
+	default:
+		t.Error("expected the handler to return when the test ended")
+	}
+}
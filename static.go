@@ -0,0 +1,130 @@
+package rex
+
+import (
+	"io/fs"
+	"net/http"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// staticRoot is the resolved root directory of a Static mount.
+type staticRoot struct {
+	dir      string // absolute root directory
+	resolved string // root directory with symlinks evaluated
+}
+
+func newStaticRoot(dir string) staticRoot {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		abs = filepath.Clean(dir)
+	}
+
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		resolved = abs
+	}
+	return staticRoot{dir: abs, resolved: resolved}
+}
+
+// resolve maps the decoded url path (with the mount prefix removed) to a file on disk.
+// It returns the file path and 0, or an http status code if the request must be rejected.
+// Containment violations return 404 to avoid revealing anything about the file system.
+func (root staticRoot) resolve(urlPath string) (string, int) {
+	if !validPathBytes(urlPath) {
+		return "", http.StatusBadRequest
+	}
+
+	if slices.Contains(strings.Split(urlPath, "/"), "..") {
+		return "", http.StatusNotFound
+	}
+
+	cleaned := path.Clean("/" + urlPath)
+	if !ServeDotfiles && hasDotSegment(cleaned) {
+		return "", http.StatusNotFound
+	}
+
+	name := filepath.Join(root.dir, filepath.FromSlash(cleaned))
+	if !withinDir(root.dir, name) {
+		return "", http.StatusNotFound
+	}
+
+	if !AllowSymlinksOutsideRoot {
+		resolved, err := filepath.EvalSymlinks(name)
+		if err == nil && !withinDir(root.resolved, resolved) {
+			return "", http.StatusNotFound
+		}
+	}
+	return name, 0
+}
+
+// withinDir reports whether name is dir or a descendant of dir.
+func withinDir(dir, name string) bool {
+	rel, err := filepath.Rel(dir, name)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// validPathBytes reports whether p is valid UTF-8 without NUL or other control characters.
+func validPathBytes(p string) bool {
+	if !utf8.ValidString(p) {
+		return false
+	}
+
+	for i := 0; i < len(p); i++ {
+		if p[i] < 0x20 || p[i] == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// hasDotSegment reports whether any segment of the slash separated path p starts with a dot.
+func hasDotSegment(p string) bool {
+	for _, seg := range strings.Split(p, "/") {
+		if strings.HasPrefix(seg, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// dotfileFS hides files and directories whose name starts with a dot.
+type dotfileFS struct {
+	http.FileSystem
+}
+
+func (dfs dotfileFS) Open(name string) (http.File, error) {
+	if hasDotSegment(name) {
+		return nil, fs.ErrNotExist
+	}
+
+	f, err := dfs.FileSystem.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return dotfileFile{f}, nil
+}
+
+// dotfileFile leaves the hidden entries out of directory listings.
+type dotfileFile struct {
+	http.File
+}
+
+func (f dotfileFile) Readdir(count int) ([]fs.FileInfo, error) {
+	for {
+		infos, err := f.File.Readdir(count)
+		infos = slices.DeleteFunc(infos, func(info fs.FileInfo) bool {
+			return strings.HasPrefix(info.Name(), ".")
+		})
+
+		// A batch of only dotfiles is not the end of the directory.
+		if len(infos) > 0 || count <= 0 || err != nil {
+			return infos, err
+		}
+	}
+}
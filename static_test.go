@@ -0,0 +1,246 @@
+package rex
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupStaticDirs(t *testing.T) (root, outside string) {
+	t.Helper()
+	base := t.TempDir()
+	root = filepath.Join(base, "public")
+	outside = filepath.Join(base, "private")
+
+	for _, dir := range []string{root, outside, filepath.Join(root, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		filepath.Join(root, "app.js"):         "app",
+		filepath.Join(root, ".env"):           "SECRET=1",
+		filepath.Join(root, ".git", "config"): "git",
+		filepath.Join(outside, "secret.txt"):  "top secret",
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root, outside
+}
+
+func TestStaticHandlerContainment(t *testing.T) {
+	root, outside := setupStaticDirs(t)
+
+	if err := os.Symlink(outside, filepath.Join(root, "escape")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	r := NewRouter()
+	staticHandler := staticHandler("/static/", root, 0, nil)
+	handler := func(w http.ResponseWriter, req *http.Request) {
+		c := r.InitContext(w, req)
+		defer r.PutContext(c)
+		if err := staticHandler(c); err != nil {
+			r.errorHandler(c, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		path   string // decoded URL path as seen by the handler
+		status int
+	}{
+		{"regular file", "/static/app.js", http.StatusOK},
+		{"decoded traversal", "/static/../private/secret.txt", http.StatusNotFound},
+		{"symlink outside root", "/static/escape/secret.txt", http.StatusNotFound},
+		{"dotfile", "/static/.env", http.StatusNotFound},
+		{"dot directory", "/static/.git/config", http.StatusNotFound},
+		{"nul byte", "/static/app.js\x00.png", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+			req.URL.Path = tt.path
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d", tt.status, w.Code)
+			}
+
+			if strings.Contains(w.Body.String(), "secret") || strings.Contains(w.Body.String(), "SECRET") {
+				t.Errorf("response leaked protected content: %q", w.Body.String())
+			}
+		})
+	}
+
+	// Opt-outs
+	AllowSymlinksOutsideRoot = true
+	ServeDotfiles = true
+	defer func() {
+		AllowSymlinksOutsideRoot = false
+		ServeDotfiles = false
+	}()
+
+	for _, p := range []string{"/static/escape/secret.txt", "/static/.env"} {
+		req := httptest.NewRequest(http.MethodGet, "/static/app.js", nil)
+		req.URL.Path = p
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: expected status 200 with opt-out, got %d", p, w.Code)
+		}
+	}
+}
+
+func TestStaticListingHidesDotfiles(t *testing.T) {
+	root, _ := setupStaticDirs(t)
+
+	r := NewRouter()
+	r.Static("/static", root)
+	r.StaticFS("/assets", http.Dir(root))
+
+	for _, p := range []string{"/static/", "/assets/"} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, p, nil))
+		if res.Code != http.StatusOK {
+			t.Fatalf("%s: expected a directory listing, got %d", p, res.Code)
+		}
+
+		body := res.BodyString()
+		if !strings.Contains(body, "app.js") {
+			t.Errorf("%s: expected app.js in the listing, got %q", p, body)
+		}
+
+		if strings.Contains(body, ".env") || strings.Contains(body, ".git") {
+			t.Errorf("%s: expected the dotfiles to be hidden from the listing, got %q", p, body)
+		}
+	}
+}
+
+func TestRouterStaticEncodedTraversal(t *testing.T) {
+	root, _ := setupStaticDirs(t)
+
+	r := NewRouter()
+	r.Static("/static", root)
+	r.StaticFS("/assets", http.Dir(root))
+
+	for _, target := range []string{
+		"/static/%2e%2e%2fprivate%2fsecret.txt",
+		"/static/..%2fprivate/secret.txt",
+		"/static/%2e%2e/private/secret.txt",
+		"/static/.env",
+		"/assets/.env",
+		"/assets/.git/config",
+	} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, target, nil))
+
+		if w.Code == http.StatusOK {
+			t.Errorf("%s: expected request to be rejected, got 200", target)
+		}
+
+		if strings.Contains(w.Body.String(), "secret") || strings.Contains(w.Body.String(), "SECRET") {
+			t.Errorf("%s: response leaked protected content", target)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/assets/app.js", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("expected status 200 for StaticFS file, got %d", w.Code)
+	}
+}
+
+func TestStaticBarePrefixRedirects(t *testing.T) {
+	root, _ := setupStaticDirs(t)
+
+	r := NewRouter()
+	r.Static("/static", root)
+	r.StaticFS("/assets", http.Dir(root))
+
+	for _, prefix := range []string{"/static", "/assets"} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, prefix+"?v=2", nil))
+		if res.Code != http.StatusMovedPermanently || res.Header("Location") != prefix+"/?v=2" {
+			t.Errorf("%s: expected a 301 redirect to the mount, got %d %q", prefix, res.Code, res.Header("Location"))
+		}
+
+		if res := r.Test(httptest.NewRequest(http.MethodGet, prefix+"/app.js", nil)); res.BodyString() != "app" {
+			t.Errorf("%s: expected the file, got %d %q", prefix, res.Code, res.BodyString())
+		}
+	}
+}
+
+func TestStaticBarePrefixReplacedByRoute(t *testing.T) {
+	root, _ := setupStaticDirs(t)
+
+	r := NewRouter()
+	r.Static("/static", root)
+	r.GET("/static", func(c *Context) error {
+		return c.String("listing")
+	})
+
+	if err := r.RegistrationError(); err != nil {
+		t.Fatalf("expected the route to replace the redirect, got %v", err)
+	}
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/static", nil)); res.Code != http.StatusOK || res.BodyString() != "listing" {
+		t.Errorf("expected the application route, got %d %q", res.Code, res.BodyString())
+	}
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/static/app.js", nil)); res.BodyString() != "app" {
+		t.Errorf("expected the file, got %d %q", res.Code, res.BodyString())
+	}
+
+	r.StaticFS("/assets", http.Dir(root))
+	r.Redirects(map[string]string{"/assets": "/assets/app.js"}, http.StatusFound)
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/assets", nil)); res.Header("Location") != "/assets/app.js" {
+		t.Errorf("expected the redirect rule to replace the automatic redirect, got %d %q", res.Code, res.Header("Location"))
+	}
+}
+
+func TestStaticRootMountWithHomeRoute(t *testing.T) {
+	root, _ := setupStaticDirs(t)
+
+	r := NewRouter()
+	r.GET("/", func(c *Context) error {
+		return c.String("home")
+	})
+	r.StaticFS("/", http.Dir(root))
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil)); res.BodyString() != "home" {
+		t.Errorf("expected the home route, got %q", res.BodyString())
+	}
+
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/app.js", nil)); res.BodyString() != "app" {
+		t.Errorf("expected the file from the root mount, got %d %q", res.Code, res.BodyString())
+	}
+}
+
+func TestStaticMissRendersRouterNotFound(t *testing.T) {
+	root, _ := setupStaticDirs(t)
+
+	r := NewRouter()
+	r.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom 404"))
+	})
+	r.Static("/static", root)
+	r.StaticFS("/assets", http.Dir(root))
+
+	for _, path := range []string{"/static/missing.js", "/assets/missing.js", "/static/.env", "/assets/.env"} {
+		res := r.Test(httptest.NewRequest(http.MethodGet, path, nil))
+		if res.Code != http.StatusNotFound || res.BodyString() != "custom 404" {
+			t.Errorf("%s: expected the router's 404, got %d %q", path, res.Code, res.BodyString())
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package rex
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// ErrClientGone is returned by StreamingWriter once the request context is done,
+// usually because the client disconnected. The default error handler does not answer it.
+var ErrClientGone = errors.New("rex: client gone")
+
+var (
+	// StreamFlushBytes is the default number of bytes a StreamingWriter buffers before flushing.
+	StreamFlushBytes = 32 << 10
+
+	// StreamFlushInterval is the default maximum time a StreamingWriter waits between flushes,
+	// checked on each write.
+	StreamFlushInterval = 200 * time.Millisecond
+)
+
+// StreamOption configures a StreamingWriter.
+type StreamOption func(*StreamingWriter)
+
+// FlushEvery flushes the stream once n bytes were written since the last flush.
+// n <= 0 disables flushing by size.
+func FlushEvery(n int) StreamOption {
+	return func(w *StreamingWriter) {
+		w.flushBytes = n
+	}
+}
+
+// FlushInterval flushes the stream on the first write d after the last flush.
+// d <= 0 disables flushing by time.
+func FlushInterval(d time.Duration) StreamOption {
+	return func(w *StreamingWriter) {
+		w.flushInterval = d
+	}
+}
+
+// StreamingWriter writes a long response, like a large export, that stops when the client goes away.
+// Create it with c.StreamingWriter.
+type StreamingWriter struct {
+	c             *Context
+	flushBytes    int
+	flushInterval time.Duration
+
+	pending   int // bytes written since the last flush
+	lastFlush time.Time
+	written   int64
+	flushes   int
+}
+
+// StreamingWriter returns a writer for the response body that returns ErrClientGone
+// from Write once the request context is done, so that export loops stop instead of
+// querying the database for a client that is gone. Writes are flushed to the client every
+// StreamFlushBytes bytes or StreamFlushInterval, see FlushEvery and FlushInterval.
+// Call Flush after the last write.
+//
+// Example:
+//
+//	w := c.StreamingWriter()
+//	csvw := csv.NewWriter(w)
+//	for rows.Next() {
+//		// ...
+//		if err := csvw.Write(record); err != nil {
+//			return err // ErrClientGone if the client disconnected
+//		}
+//	}
+//	csvw.Flush()
+//	return w.Flush()
+func (c *Context) StreamingWriter(options ...StreamOption) *StreamingWriter {
+	c.checkReleased()
+
+	w := &StreamingWriter{
+		c:             c,
+		flushBytes:    StreamFlushBytes,
+		flushInterval: StreamFlushInterval,
+		lastFlush:     time.Now(),
+	}
+
+	for _, option := range options {
+		option(w)
+	}
+	return w
+}
+
+// Write writes p to the response, flushing it when the size or interval threshold is reached.
+// It returns ErrClientGone without writing if the request context is done.
+func (w *StreamingWriter) Write(p []byte) (int, error) {
+	if w.c.Request.Context().Err() != nil {
+		return 0, ErrClientGone
+	}
+
+	n, err := w.c.Response.Write(p)
+	w.written += int64(n)
+	w.pending += n
+	if err != nil {
+		if w.c.Request.Context().Err() != nil {
+			return n, ErrClientGone
+		}
+		return n, err
+	}
+
+	if (w.flushBytes > 0 && w.pending >= w.flushBytes) ||
+		(w.flushInterval > 0 && time.Since(w.lastFlush) >= w.flushInterval) {
+		return n, w.Flush()
+	}
+	return n, nil
+}
+
+// Flush sends the buffered data to the client.
+// It returns ErrClientGone if the request context is done.
+func (w *StreamingWriter) Flush() error {
+	if w.c.Request.Context().Err() != nil {
+		return ErrClientGone
+	}
+
+	if f, ok := w.c.Response.(http.Flusher); ok {
+		f.Flush()
+	}
+
+	w.pending = 0
+	w.lastFlush = time.Now()
+	w.flushes++
+	return nil
+}
+
+// Written returns the number of bytes written to the response.
+func (w *StreamingWriter) Written() int64 {
+	return w.written
+}
+
+// Flushes returns the number of flushes.
+func (w *StreamingWriter) Flushes() int {
+	return w.flushes
+}
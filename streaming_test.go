@@ -0,0 +1,104 @@
+package rex_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// flushRecorder counts the flushes reaching the connection.
+type flushRecorder struct {
+	*httptest.ResponseRecorder
+	flushes int
+}
+
+func (f *flushRecorder) Flush() {
+	f.flushes++
+	f.ResponseRecorder.Flush()
+}
+
+func TestStreamingWriterStopsWhenClientGone(t *testing.T) {
+	r := rex.NewRouter()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var rows int
+	var streamErr error
+	r.GET("/export", func(c *rex.Context) error {
+		w := c.StreamingWriter()
+		for i := 0; i < 1_000_000; i++ {
+			if i == 5 {
+				cancel() // the client disconnects
+			}
+
+			if _, err := w.Write([]byte("row\n")); err != nil {
+				streamErr = err
+				return err
+			}
+			rows++
+		}
+		return w.Flush()
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/export", nil).WithContext(ctx)
+	res := r.Test(req)
+
+	if !errors.Is(streamErr, rex.ErrClientGone) || rows != 5 {
+		t.Fatalf("expected the loop to stop after 5 rows with ErrClientGone, got %d rows and %v", rows, streamErr)
+	}
+
+	if res.BodyString() != strings.Repeat("row\n", 5) {
+		t.Errorf("expected no error response to be appended, got %q", res.BodyString())
+	}
+}
+
+func TestStreamingWriterFlushCadence(t *testing.T) {
+	r := rex.NewRouter()
+
+	var written int64
+	var size int
+	r.GET("/by-size", func(c *rex.Context) error {
+		w := c.StreamingWriter(rex.FlushEvery(250), rex.FlushInterval(0))
+		for i := 0; i < 10; i++ {
+			if _, err := w.Write(make([]byte, 100)); err != nil {
+				return err
+			}
+		}
+		written, size = w.Written(), c.ResponseSize()
+		return w.Flush()
+	})
+
+	r.GET("/by-time", func(c *rex.Context) error {
+		w := c.StreamingWriter(rex.FlushEvery(0), rex.FlushInterval(10*time.Millisecond))
+		w.Write([]byte("a"))
+		w.Write([]byte("b"))
+		time.Sleep(15 * time.Millisecond)
+		w.Write([]byte("c"))
+		return nil
+	})
+
+	rec := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/by-size", nil))
+
+	// Flushed after 300, 600 and 900 bytes, then by the final Flush.
+	if rec.flushes != 4 {
+		t.Errorf("expected 4 flushes, got %d", rec.flushes)
+	}
+
+	if written != 1000 || size != 1000 || rec.Body.Len() != 1000 {
+		t.Errorf("expected 1000 bytes, got %d written, size %d and a %d byte body", written, size, rec.Body.Len())
+	}
+
+	rec = &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/by-time", nil))
+	if rec.flushes != 1 || rec.Body.String() != "abc" {
+		t.Errorf("expected one flush after the interval, got %d and %q", rec.flushes, rec.Body.String())
+	}
+}
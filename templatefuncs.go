@@ -0,0 +1,221 @@
+package rex
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TemplateFuncs returns the builtin template functions added by ParseTemplates
+// and ParseTemplatesFS. Add them to templates parsed by other means before parsing:
+//
+//	t := template.Must(template.New("").Funcs(rex.TemplateFuncs()).ParseGlob("views/*.html"))
+//
+// The functions are:
+//
+//	asset        "css/app.css" -> fingerprinted path, see WithAssetManifest
+//	url          "/login" -> path with the base path, see WithBasePath
+//	formatDate   .CreatedAt "02 Jan 2006" -> date in DefaultTimezone
+//	formatNumber 1234567.89 -> "1,234,567.89", an optional second argument sets the decimals
+//	timeago      .CreatedAt -> "5 minutes ago" or "in 2 days"
+//	dict         "Title" .Title "Count" 3 -> map[string]any
+//	include      "partials/card.html" (dict "Title" .Title) -> rendered template
+//	iter         .Rows -> channel of a TemplateIter for the range action
+//	flush        flushes the response of RenderStream, nothing for other renders
+func TemplateFuncs() template.FuncMap {
+	return builtinFuncs()
+}
+
+// toTime converts time.Time and *time.Time values. ok is false for other types and nil pointers.
+func toTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, true
+	case *time.Time:
+		if t != nil {
+			return *t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// formatDate formats t with layout in DefaultTimezone.
+// Zero times format as an empty string. The default layout is "2006-01-02".
+func formatDate(v any, layout ...string) (string, error) {
+	t, ok := toTime(v)
+	if !ok {
+		if v == nil {
+			return "", nil
+		}
+		return "", fmt.Errorf("formatDate: expected time.Time, got %T", v)
+	}
+
+	if t.IsZero() {
+		return "", nil
+	}
+
+	format := "2006-01-02"
+	if len(layout) > 0 {
+		format = layout[0]
+	}
+	return t.In(DefaultTimezone).Format(format), nil
+}
+
+// formatNumber formats a number with comma thousand separators.
+// Floats keep their shortest representation unless decimals is given.
+func formatNumber(v any, decimals ...int) (string, error) {
+	rv := reflect.ValueOf(v)
+
+	var s string
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s = strconv.FormatInt(rv.Int(), 10)
+		if len(decimals) > 0 {
+			s = strconv.FormatFloat(float64(rv.Int()), 'f', decimals[0], 64)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s = strconv.FormatUint(rv.Uint(), 10)
+		if len(decimals) > 0 {
+			s = strconv.FormatFloat(float64(rv.Uint()), 'f', decimals[0], 64)
+		}
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+
+		precision := -1
+		if len(decimals) > 0 {
+			precision = decimals[0]
+		}
+		s = strconv.FormatFloat(f, 'f', precision, 64)
+	default:
+		return "", fmt.Errorf("formatNumber: expected a number, got %T", v)
+	}
+	return groupThousands(s), nil
+}
+
+// groupThousands inserts commas in the integer part of the decimal number s.
+func groupThousands(s string) string {
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+
+	intPart, frac, hasFrac := strings.Cut(s, ".")
+
+	var b strings.Builder
+	b.WriteString(sign)
+	for i, digit := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+
+	if hasFrac {
+		b.WriteByte('.')
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// timeago describes t relative to now, e.g "3 hours ago" or "in 2 days".
+func timeago(v any) (string, error) {
+	t, ok := toTime(v)
+	if !ok {
+		return "", fmt.Errorf("timeago: expected time.Time, got %T", v)
+	}
+
+	if t.IsZero() {
+		return "", nil
+	}
+	return relativeTime(t, time.Now()), nil
+}
+
+// relativeTime describes t relative to now in the largest whole unit.
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	if d < time.Minute {
+		return "just now"
+	}
+
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"week", 7 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+
+	for _, unit := range units {
+		n := int64(d / unit.size)
+		if n < 1 {
+			continue
+		}
+
+		phrase := fmt.Sprintf("%d %s", n, unit.name)
+		if n > 1 {
+			phrase += "s"
+		}
+
+		if future {
+			return "in " + phrase
+		}
+		return phrase + " ago"
+	}
+	return "just now"
+}
+
+// dict builds a map from alternating string keys and values.
+func dict(pairs ...any) (map[string]any, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict: expected an even number of arguments, got %d", len(pairs))
+	}
+
+	m := make(map[string]any, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict: key at position %d must be a string, got %T", i, pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+	return m, nil
+}
+
+// bindInclude binds the include function to the template set of t.
+func bindInclude(t *template.Template) {
+	t.Funcs(template.FuncMap{
+		"include": func(name string, data ...any) (template.HTML, error) {
+			var value any
+			if len(data) > 0 {
+				value = data[0]
+			}
+
+			var b strings.Builder
+			if err := t.ExecuteTemplate(&b, name, value); err != nil {
+				return "", fmt.Errorf("include %q: %w", name, err)
+			}
+			return template.HTML(b.String()), nil
+		},
+	})
+}
+
+// includeUnbound is the placeholder include function used while parsing.
+func includeUnbound(name string, data ...any) (template.HTML, error) {
+	return "", fmt.Errorf("include %q: template set is not bound, parse with rex.ParseTemplates or pass it to rex.WithTemplates", name)
+}
@@ -0,0 +1,120 @@
+package rex
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/page.html": {Data: []byte(
+			`{{formatDate .When "02 Jan 2006 15:04"}}|{{formatDate .Zero}}|{{formatNumber 1234567.89}}|` +
+				`{{formatNumber .Count}}|{{formatNumber -9876.5 2}}|{{timeago .Past}}|{{timeago .Future}}|` +
+				`{{include "views/partials/card.html" (dict "Title" .Title "Inner" (dict "Name" "nested"))}}`)},
+		"views/partials/card.html": {Data: []byte(`<div>{{.Title}}:{{include "views/partials/name.html" .Inner}}</div>`)},
+		"views/partials/name.html": {Data: []byte(`<b>{{.Name}}</b>`)},
+		"views/broken.html":        {Data: []byte(`{{include "views/partials/bad.html" (dict "Items" .Items)}}`)},
+		"views/partials/bad.html":  {Data: []byte(`{{index .Items 3}}`)},
+		"views/baddict.html":       {Data: []byte(`{{dict "a"}}`)},
+	}
+
+	tmpl, err := ParseTemplatesFS(fsys, "views", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldTZ := DefaultTimezone
+	DefaultTimezone = time.FixedZone("EAT", 3*60*60)
+	defer func() { DefaultTimezone = oldTZ }()
+
+	var b strings.Builder
+	err = tmpl.ExecuteTemplate(&b, "views/page.html", map[string]any{
+		"When":   time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC),
+		"Zero":   time.Time{},
+		"Count":  1000000,
+		"Past":   time.Now().Add(-3 * time.Hour),
+		"Future": time.Now().Add(49 * time.Hour),
+		"Title":  "<Card>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "05 Mar 2024 12:30||1,234,567.89|1,000,000|-9,876.50|3 hours ago|in 2 days|" +
+		"<div>&lt;Card&gt;:<b>nested</b></div>"
+	if b.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, b.String())
+	}
+
+	err = tmpl.ExecuteTemplate(&b, "views/broken.html", map[string]any{"Items": []int{1}})
+	if err == nil || !strings.Contains(err.Error(), `include "views/partials/bad.html"`) {
+		t.Errorf("expected include error with the inner template name, got %v", err)
+	}
+
+	if err := tmpl.ExecuteTemplate(&b, "views/baddict.html", nil); err == nil {
+		t.Error("expected dict with an odd number of arguments to fail")
+	}
+}
+
+func TestTemplateFuncsRouter(t *testing.T) {
+	// Templates parsed outside ParseTemplates get include bound by the router.
+	tmpl := template.Must(template.New("page.html").Funcs(TemplateFuncs()).Parse(`{{include "card" (dict "N" 2)}}`))
+	template.Must(tmpl.New("card").Parse(`card {{formatNumber .N}}`))
+
+	r := NewRouter(WithTemplates(tmpl))
+
+	var b strings.Builder
+	if err := r.template.ExecuteTemplate(&b, "page.html", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != "card 2" {
+		t.Errorf("expected card 2, got %q", b.String())
+	}
+}
+
+func TestTemplateFuncsCustomInclude(t *testing.T) {
+	fsys := fstest.MapFS{"views/page.html": {Data: []byte(`{{include "card"}}`)}}
+	funcs := template.FuncMap{"include": func(name string) string { return "custom " + name }}
+
+	tmpl, err := ParseTemplatesFS(fsys, "views", funcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter(WithTemplates(tmpl))
+
+	var b strings.Builder
+	if err := r.template.ExecuteTemplate(&b, "views/page.html", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if b.String() != "custom card" {
+		t.Errorf("expected the user include to be kept, got %q", b.String())
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		offset time.Duration
+		want   string
+	}{
+		{-10 * time.Second, "just now"},
+		{-time.Minute, "1 minute ago"},
+		{-90 * time.Minute, "1 hour ago"},
+		{-8 * 24 * time.Hour, "1 week ago"},
+		{-65 * 24 * time.Hour, "2 months ago"},
+		{-800 * 24 * time.Hour, "2 years ago"},
+		{5 * time.Minute, "in 5 minutes"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeTime(now.Add(tt.offset), now); got != tt.want {
+			t.Errorf("relativeTime(%v) = %q, want %q", tt.offset, got, tt.want)
+		}
+	}
+}
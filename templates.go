@@ -3,11 +3,10 @@ package rex
 import (
 	"fmt"
 	"html/template"
-	"io"
 	"io/fs"
 	"net/http"
-	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 )
@@ -48,7 +47,8 @@ func ContentBlock(contentBlock string) RouterOption {
 }
 
 // PassContextToViews enables or disables passing the router context to views.
-// If enabled, the router context will be available as a variable named "ctx" in the views.
+// If enabled, the context locals are available in the views and a ViewRequest
+// with the path, method, query, CSRF token, flashes and user is available as {{ .Request }}.
 // This allows views to access information about the request and the router.
 // The default value is `false`.
 //
@@ -61,6 +61,25 @@ func PassContextToViews(passContextToViews bool) RouterOption {
 	}
 }
 
+// TemplateMissingKey sets how templates handle map keys missing from their data
+// with Option("missingkey=mode"). mode is "default", "invalid", "zero" or "error".
+// With "error", rendering fails and in Debug mode the error page shows the failing line.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.WithTemplates(t), rex.TemplateMissingKey("error"))
+func TemplateMissingKey(mode string) RouterOption {
+	switch mode {
+	case "default", "invalid", "zero", "error":
+	default:
+		panic(fmt.Sprintf("rex: invalid missingkey mode %q", mode))
+	}
+
+	return func(r *Router) {
+		r.templateMissingKey = mode
+	}
+}
+
 // WithTemplates sets the template for the router.
 // This template will be used to render views.
 //
@@ -71,6 +90,7 @@ func PassContextToViews(passContextToViews bool) RouterOption {
 func WithTemplates(t *template.Template) RouterOption {
 	return func(r *Router) {
 		r.template = t
+		r.templateInfo = templateInfo(t)
 	}
 }
 
@@ -104,6 +124,46 @@ func (c *Context) RenderError(w http.ResponseWriter, err error, status ...int) e
 	return c.renderErrorTemplate(err, status...)
 }
 
+// TemplateError is returned when executing a template fails.
+// It records the template name and the keys of the data passed to it.
+type TemplateError struct {
+	Name string   // Name of the template that failed.
+	Keys []string // Sorted keys of the template data.
+	Err  error    // The underlying execution error.
+
+	// In Debug mode, the file and line the error occurred at and the surrounding source,
+	// for templates parsed with ParseTemplates or ParseTemplatesFS.
+	File   string
+	Line   int
+	Source []SourceLine
+}
+
+func newTemplateError(t *template.Template, info *templateSetInfo, name string, data Map, err error) TemplateError {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+
+	te := TemplateError{Name: name, Keys: keys, Err: err}
+	if Debug {
+		if file, line, source, ok := templateErrorSource(t, info, err); ok {
+			te.File, te.Line, te.Source = file, line, source
+		}
+	}
+	return te
+}
+
+// Error implements the error interface.
+func (e TemplateError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying execution error.
+func (e TemplateError) Unwrap() error {
+	return e.Err
+}
+
 // builderPool is a pool of strings.Builder to avoid allocations.
 var builderPool = sync.Pool{
 	New: func() interface{} {
@@ -125,27 +185,37 @@ func (c *Context) renderTemplate(name string, data Map) error {
 		name += ".html"
 	}
 
-	// Execute the template into the pooled builder
-	if err := c.router.template.ExecuteTemplate(builder, name, data); err != nil {
+	if err := checkRenderable(c.router.templateInfo, name); err != nil {
 		return err
 	}
 
+	// Execute the template into the pooled builder
+	if err := c.executeTemplate(builder, name, name, data); err != nil {
+		stopTemplateIters(data)
+		return newTemplateError(c.router.template, c.router.templateInfo, name, data, err)
+	}
+
 	// Update the data map with the rendered content
-	data[c.router.contentBlock] = template.HTML(builder.String())
+	data[c.router.contentBlock] = template.HTML(stripFlushMarkers(builder.String()))
 
 	// Reset the builder for reuse
 	builder.Reset()
 
 	// Execute the base template
-	if err := c.router.template.ExecuteTemplate(builder, c.router.baseLayout, data); err != nil {
-		return err
+	err := c.executeTemplate(builder, c.router.baseLayout, c.router.baseLayout+LayoutSuffix, data)
+	stopErr := stopTemplateIters(data)
+	if err != nil {
+		return newTemplateError(c.router.template, c.router.templateInfo, c.router.baseLayout, data, err)
+	}
+
+	if stopErr != nil {
+		return newTemplateError(c.router.template, c.router.templateInfo, name, data, stopErr)
 	}
 
 	c.SetHeader("Content-Type", "text/html")
 
 	// Write the final content
-	_, err := io.WriteString(c.Response, builder.String())
-	return err
+	return c.writeRendered(stripFlushMarkers(builder.String()))
 }
 
 // Render the template tmpl with the data. If no template is configured, Render will panic.
@@ -157,13 +227,35 @@ func (c *Context) Render(name string, data Map) error {
 		return fmt.Errorf("no template is configured")
 	}
 
+	if err := c.prepareViewData(data); err != nil {
+		return err
+	}
+	return c.renderTemplate(name, data)
+}
+
+// prepareViewData adds the locals, the view request, the timezone and the flashes to the data of Render.
+func (c *Context) prepareViewData(data Map) error {
 	// pass the request context to the views
 	if c.router.passContextToViews && c.router.baseLayout != "" && c.router.contentBlock != "" {
 		for k, v := range c.locals {
 			data[fmt.Sprintf("%v", k)] = v
 		}
 	}
-	return c.renderTemplate(name, data)
+
+	if c.router.passContextToViews {
+		if err := c.injectViewRequest(data); err != nil {
+			return err
+		}
+		c.injectTimezone(data)
+	}
+
+	// expose flash messages from RedirectWithFlash
+	if _, ok := data["flashes"]; !ok {
+		if flashes := c.Flashes(); flashes != nil {
+			data["flashes"] = flashes
+		}
+	}
+	return nil
 }
 
 // Execute a standalone template without a layout.
@@ -177,8 +269,26 @@ func (c *Context) ExecuteTemplate(name string, data Map) error {
 		for k, v := range c.locals {
 			data[fmt.Sprintf("%v", k)] = v
 		}
+
+		if err := c.injectViewRequest(data); err != nil {
+			return err
+		}
+		c.injectTimezone(data)
 	}
-	return c.router.template.ExecuteTemplate(c.Response, name, data)
+
+	if err := checkRenderable(c.router.templateInfo, name); err != nil {
+		return err
+	}
+
+	err := c.executeTemplate(&flushMarkerWriter{w: c.Response}, name, name, data)
+	if stopErr := stopTemplateIters(data); err == nil {
+		err = stopErr
+	}
+
+	if err != nil {
+		return newTemplateError(c.router.template, c.router.templateInfo, name, data, err)
+	}
+	return nil
 }
 
 // Template returns the template passed to the router.
@@ -199,42 +309,24 @@ func (c *Context) LookupTemplate(name string) (*template.Template, error) {
 
 // ParseTemplates recursively parses all the templates in the given directory and returns a template.
 // The funcMap is applied to all the templates. The suffix is used to filter the files.
+// The builtin funcs like "asset", "formatDate" and "include" are always available, see TemplateFuncs.
+// An "include" func in funcMap replaces the builtin one.
+// Each file is named by its path relative to rootDir e.g "users/list.html".
 // The default suffix is ".html".
 // If you have a file system, you can use ParseTemplatesFS instead.
+// Use ParseTemplatesWith to exclude files or declare a partials directory.
 func ParseTemplates(rootDir string, funcMap template.FuncMap, suffix ...string) (*template.Template, error) {
-	ext := ".html"
+	opts := TemplateOptions{}
 	if len(suffix) > 0 {
-		ext = suffix[0]
+		opts.Suffix = suffix[0]
 	}
-
-	cleanRoot := filepath.Clean(rootDir)
-	pfx := len(cleanRoot) + 1
-	root := template.New("")
-
-	err := filepath.WalkDir(cleanRoot, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !d.IsDir() && strings.HasSuffix(path, ext) {
-			b, err := os.ReadFile(path)
-			if err != nil {
-				return err
-			}
-
-			t := root.New(path[pfx:]).Funcs(funcMap)
-			_, err = t.Parse(string(b))
-			return err
-		}
-		return nil
-	})
-
-	return root, err
+	return ParseTemplatesWith(rootDir, funcMap, opts)
 }
 
 // ParseTemplatesFS parses all templates in a directory recursively from a given filesystem.
 // It uses the specified `funcMap` to define custom template functions.
 // The `suffix` argument can be used to specify a different file extension for the templates.
+// Each file is named by its path including rootDir e.g "templates/users/list.html".
 // The default file extension is ".html".
 //
 // Example:
@@ -250,37 +342,11 @@ func ParseTemplates(rootDir string, funcMap template.FuncMap, suffix ...string)
 //
 //		 r := rex.NewRouter(rex.WithTemplates(t))
 func ParseTemplatesFS(root fs.FS, rootDir string, funcMap template.FuncMap, suffix ...string) (*template.Template, error) {
-	ext := ".html"
+	opts := TemplateOptions{}
 	if len(suffix) > 0 {
-		ext = suffix[0]
+		opts.Suffix = suffix[0]
 	}
-
-	pfx := len(rootDir) + 1  // +1 for the trailing slash
-	tmpl := template.New("") // Create a new template
-
-	err := fs.WalkDir(root, rootDir, func(path string, d fs.DirEntry, err error) error {
-		if err != nil {
-			return err
-		}
-
-		if !d.IsDir() && strings.HasSuffix(path, ext) {
-			if d != nil && d.IsDir() {
-				return nil
-			}
-
-			b, err := fs.ReadFile(root, path)
-			if err != nil {
-				return err
-			}
-
-			t := tmpl.New(rootDir + "/" + path[pfx:]).Funcs(funcMap)
-			_, err = t.Parse(string(b))
-
-			return err
-		}
-		return nil
-	})
-	return tmpl, err
+	return ParseTemplatesFSWith(root, rootDir, funcMap, opts)
 }
 
 // Must unwraps the value and panics if the error is not nil.
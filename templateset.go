@@ -0,0 +1,349 @@
+package rex
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// TemplateOptions configures ParseTemplatesWith and ParseTemplatesFSWith.
+type TemplateOptions struct {
+	// Suffix is the file extension of templates. The default is ".html".
+	Suffix string
+
+	// Exclude lists path.Match patterns of files and directories to skip,
+	// relative to the root directory e.g "emails" or "drafts/*.html".
+	// A matching directory is skipped with everything in it.
+	Exclude []string
+
+	// Partials is a directory relative to the root directory whose files only hold
+	// templates used by other templates, e.g {{define "partials/nav"}} blocks.
+	// They are parsed into the set, but Render and ExecuteTemplate refuse to render the files directly.
+	Partials string
+}
+
+// templateSetInfo records what ParseTemplates knows about a template set.
+type templateSetInfo struct {
+	partials map[string]bool   // names of partial files
+	fsys     fs.FS             // file system the set was parsed from
+	files    map[string]string // file template name -> path in fsys, read for debug snippets
+
+	customInclude bool // the funcMap replaced the builtin include, which is not bound
+	customURL     bool // the funcMap replaced the builtin url, which is not bound to the base path
+	customAsset   bool // the funcMap replaced the builtin asset, which is not bound to the manifest
+}
+
+// parsedSets holds the info of the template sets parsed by ParseTemplates and ParseTemplatesFS
+// for WithTemplates, which stores it on the router. Entries are keyed by the address of the set
+// so that they do not keep it alive.
+var (
+	parsedSetsMu sync.Mutex
+	parsedSets   = make(map[uintptr]*templateSetInfo)
+)
+
+// parsedSetRef removes the entry of a template set from parsedSets once the set is collected.
+// A set references itself, so a finalizer on it would never run; the set holds the ref
+// through a template func instead.
+type parsedSetRef struct {
+	key  uintptr
+	info *templateSetInfo
+}
+
+func storeTemplateInfo(t *template.Template, info *templateSetInfo) {
+	ref := &parsedSetRef{key: reflect.ValueOf(t).Pointer(), info: info}
+
+	parsedSetsMu.Lock()
+	parsedSets[ref.key] = info
+	parsedSetsMu.Unlock()
+
+	runtime.SetFinalizer(ref, func(ref *parsedSetRef) {
+		parsedSetsMu.Lock()
+		defer parsedSetsMu.Unlock()
+
+		// The address may already belong to a newer set.
+		if parsedSets[ref.key] == ref.info {
+			delete(parsedSets, ref.key)
+		}
+	})
+	t.Funcs(template.FuncMap{"_rexTemplateSet": func() *parsedSetRef { return ref }})
+}
+
+// templateInfo returns the info of the template set t or nil if it was parsed by other means.
+func templateInfo(t *template.Template) *templateSetInfo {
+	if t == nil {
+		return nil
+	}
+
+	parsedSetsMu.Lock()
+	defer parsedSetsMu.Unlock()
+	return parsedSets[reflect.ValueOf(t).Pointer()]
+}
+
+// ParseTemplatesWith is like ParseTemplates with options to exclude files and declare partials.
+//
+// Example:
+//
+//	t, err := rex.ParseTemplatesWith("views", nil, rex.TemplateOptions{
+//		Exclude:  []string{"emails"},
+//		Partials: "partials",
+//	})
+func ParseTemplatesWith(rootDir string, funcMap template.FuncMap, opts TemplateOptions) (*template.Template, error) {
+	cleanRoot := filepath.Clean(rootDir)
+	return parseTemplateTree(os.DirFS(cleanRoot), ".", "", funcMap, opts)
+}
+
+// ParseTemplatesFSWith is like ParseTemplatesFS with options to exclude files and declare partials.
+// Exclude and Partials are relative to rootDir.
+func ParseTemplatesFSWith(root fs.FS, rootDir string, funcMap template.FuncMap, opts TemplateOptions) (*template.Template, error) {
+	return parseTemplateTree(root, rootDir, rootDir+"/", funcMap, opts)
+}
+
+// parseTemplateTree parses the templates below dir in fsys, naming them namePrefix + relative path.
+func parseTemplateTree(fsys fs.FS, dir, namePrefix string, funcMap template.FuncMap, opts TemplateOptions) (*template.Template, error) {
+	ext := opts.Suffix
+	if ext == "" {
+		ext = ".html"
+	}
+
+	partialsDir := strings.Trim(path.Clean("/"+opts.Partials), "/")
+	info := &templateSetInfo{partials: make(map[string]bool), fsys: fsys, files: make(map[string]string)}
+	tmpl := template.New("")
+
+	err := fs.WalkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, dir), "/")
+		if dir == "." {
+			rel = p
+		}
+
+		if rel != "." && rel != "" && excludedTemplate(rel, opts.Exclude) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		if d.IsDir() || !strings.HasSuffix(p, ext) {
+			return nil
+		}
+
+		b, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+
+		name := namePrefix + rel
+		if partialsDir != "" && strings.HasPrefix(rel, partialsDir+"/") {
+			info.partials[name] = true
+		}
+		info.files[name] = p
+
+		_, err = tmpl.New(name).Funcs(withBuiltinFuncs(funcMap)).Parse(string(b))
+		return err
+	})
+
+	_, info.customInclude = funcMap["include"]
+	_, info.customURL = funcMap["url"]
+	_, info.customAsset = funcMap["asset"]
+	storeTemplateInfo(tmpl, info)
+
+	if !info.customInclude {
+		bindInclude(tmpl)
+	}
+	return tmpl, err
+}
+
+// excludedTemplate reports whether the relative path matches one of the patterns.
+func excludedTemplate(rel string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.Trim(pattern, "/"), rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkRenderable returns an error if name is a partial file of the template set of info.
+func checkRenderable(info *templateSetInfo, name string) error {
+	if info == nil || !info.partials[name] {
+		return nil
+	}
+	return fmt.Errorf("rex: template %q is a partial and cannot be rendered directly, "+
+		"use it from a page with {{template}} or include", name)
+}
+
+// DefinedTemplateNames returns the sorted names of all templates in the router's template set,
+// including the blocks declared with {{define}}. It returns nil if no template is configured.
+func (r *Router) DefinedTemplateNames() []string {
+	if r.template == nil {
+		return nil
+	}
+
+	var names []string
+	for _, t := range r.template.Templates() {
+		if t.Name() != "" {
+			names = append(names, t.Name())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ValidateTemplates returns an error listing the templates that are not defined.
+// Names without an extension are looked up with ".html" like in Render.
+// The base layout and error template are always checked if configured.
+// Call it at startup to fail fast on a missing or misspelled template name.
+//
+// Example:
+//
+//	if err := r.ValidateTemplates("home", "users/list", "partials/nav"); err != nil {
+//		log.Fatal(err)
+//	}
+func (r *Router) ValidateTemplates(requiredNames ...string) error {
+	if r.template == nil {
+		return fmt.Errorf("rex: no template is configured")
+	}
+
+	names := slices.Clone(requiredNames)
+	if r.baseLayout != "" {
+		names = append(names, r.baseLayout)
+	}
+
+	if r.errorTemplate != "" {
+		names = append(names, r.errorTemplate)
+	}
+
+	defined := r.DefinedTemplateNames()
+
+	var missing []string
+	for _, name := range names {
+		if r.template.Lookup(name) != nil {
+			continue
+		}
+
+		if filepath.Ext(name) == "" && r.template.Lookup(name+".html") != nil {
+			continue
+		}
+
+		msg := fmt.Sprintf("%q", name)
+		if suggestion := closestName(name, defined); suggestion != "" {
+			msg += fmt.Sprintf(" (did you mean %q?)", suggestion)
+		}
+		missing = append(missing, msg)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("rex: undefined templates: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// closestName returns the candidate nearest to name if it is close enough to be a typo.
+func closestName(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/3+1
+	for _, candidate := range candidates {
+		for _, c := range []string{candidate, strings.TrimSuffix(candidate, ".html")} {
+			if d := editDistance(name, c); d < bestDistance {
+				best, bestDistance = candidate, d
+			}
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
+
+// templateContextLines is the number of source lines shown before and after
+// the failing line of a template in Debug mode.
+const templateContextLines = 3
+
+// SourceLine is a line of template source shown for a TemplateError in Debug mode.
+type SourceLine struct {
+	Number  int    `json:"number"`
+	Text    string `json:"text"`
+	Current bool   `json:"current"` // The line the error occurred on.
+}
+
+// templateLocation matches the "template: name:line:col:" location of execution errors
+// and the "html/template:name:line:col:" location of escaping errors.
+var templateLocation = regexp.MustCompile(`template: ?([^\s:]+):(\d+)(?::\d+)?:`)
+
+// templateErrorSource returns the template name, line and surrounding source of the
+// innermost location in a template error of the set t. ok is false if the source is unknown.
+// The source is read from the file system the set was parsed from, it is not kept in memory.
+func templateErrorSource(t *template.Template, info *templateSetInfo, err error) (name string, line int, source []SourceLine, ok bool) {
+	if info == nil {
+		return "", 0, nil, false
+	}
+
+	matches := templateLocation.FindAllStringSubmatch(err.Error(), -1)
+	if len(matches) == 0 {
+		return "", 0, nil, false
+	}
+
+	// Errors from include nest the included template last.
+	last := matches[len(matches)-1]
+	name = last[1]
+	line, _ = strconv.Atoi(last[2])
+
+	// Errors may name a {{define}} block, which belongs to the file it was parsed from.
+	if _, found := info.files[name]; !found && t != nil {
+		if defined := t.Lookup(name); defined != nil && defined.Tree != nil {
+			name = defined.Tree.ParseName
+		}
+	}
+
+	file, found := info.files[name]
+	if !found || line < 1 {
+		return "", 0, nil, false
+	}
+
+	text, readErr := fs.ReadFile(info.fsys, file)
+	if readErr != nil {
+		return "", 0, nil, false
+	}
+
+	lines := strings.Split(string(text), "\n")
+	if line > len(lines) {
+		return "", 0, nil, false
+	}
+
+	start, end := max(1, line-templateContextLines), min(len(lines), line+templateContextLines)
+	for n := start; n <= end; n++ {
+		source = append(source, SourceLine{Number: n, Text: lines[n-1], Current: n == line})
+	}
+	return name, line, source, true
+}
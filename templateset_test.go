@@ -0,0 +1,281 @@
+package rex
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func templateSetFS() fstest.MapFS {
+	return fstest.MapFS{
+		"views/home.html":            {Data: []byte(`<main>{{template "partials/nav" .}}home</main>`)},
+		"views/users/list.html":      {Data: []byte(`users`)},
+		"views/partials/shared.html": {Data: []byte(`{{define "partials/nav"}}<nav>{{.title}}</nav>{{end}}{{define "partials/footer"}}<footer></footer>{{end}}`)},
+		"views/emails/welcome.html":  {Data: []byte(`{{.Name | printf "%s"}} {{ broken`)},
+		"views/drafts/old.html":      {Data: []byte(`draft`)},
+		"views/drafts/keep.txt":      {Data: []byte(`not a template`)},
+	}
+}
+
+func TestParseTemplatesExcludeAndPartials(t *testing.T) {
+	tmpl, err := ParseTemplatesFSWith(templateSetFS(), "views", nil, TemplateOptions{
+		Exclude:  []string{"emails", "drafts/*.html"},
+		Partials: "partials",
+	})
+	if err != nil {
+		t.Fatalf("expected excluded files not to be parsed, got %v", err)
+	}
+
+	r := NewRouter(WithTemplates(tmpl))
+
+	names := r.DefinedTemplateNames()
+	want := []string{"partials/footer", "partials/nav", "views/home.html", "views/partials/shared.html", "views/users/list.html"}
+	if !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+
+	r.GET("/", func(c *Context) error {
+		return c.ExecuteTemplate("views/home.html", Map{"title": "Rex"})
+	})
+	r.GET("/partial", func(c *Context) error {
+		return c.ExecuteTemplate("views/partials/shared.html", Map{})
+	})
+	r.GET("/render-partial", func(c *Context) error {
+		return c.Render("views/partials/shared", Map{})
+	})
+
+	res := r.Test(NewTestRequest(http.MethodGet, "/").Build())
+	if res.Body.String() != "<main><nav>Rex</nav>home</main>" {
+		t.Errorf("expected the page to use the partial define, got %q", res.Body.String())
+	}
+
+	for _, path := range []string{"/partial", "/render-partial"} {
+		res = r.Test(NewTestRequest(http.MethodGet, path).Build())
+		if res.Code != http.StatusInternalServerError || !strings.Contains(res.Body.String(), "is a partial") {
+			t.Errorf("%s: expected a partial error, got %d %q", path, res.Code, res.Body.String())
+		}
+	}
+}
+
+func TestParseTemplatesWithDir(t *testing.T) {
+	dir := t.TempDir()
+	for name, file := range templateSetFS() {
+		path := filepath.Join(dir, strings.TrimPrefix(name, "views/"))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, file.Data, 0644)
+	}
+
+	// The broken email template fails without the exclusion.
+	if _, err := ParseTemplates(dir, nil); err == nil {
+		t.Fatal("expected the email template to fail parsing")
+	}
+
+	tmpl, err := ParseTemplatesWith(dir, nil, TemplateOptions{Exclude: []string{"emails"}, Partials: "partials/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tmpl.Lookup("drafts/old.html") == nil || tmpl.Lookup("partials/nav") == nil {
+		t.Error("expected drafts and partials to be parsed")
+	}
+
+	if err := checkRenderable(templateInfo(tmpl), "partials/shared.html"); err == nil {
+		t.Error("expected partials/shared.html to be a partial")
+	}
+
+	if err := checkRenderable(templateInfo(tmpl), "home.html"); err != nil {
+		t.Errorf("expected home.html to be renderable, got %v", err)
+	}
+}
+
+func TestValidateTemplates(t *testing.T) {
+	tmpl, err := ParseTemplatesFSWith(templateSetFS(), "views", nil, TemplateOptions{Exclude: []string{"emails"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter(WithTemplates(tmpl))
+	if err := r.ValidateTemplates("views/home", "views/users/list.html", "partials/nav"); err != nil {
+		t.Errorf("expected templates to be valid, got %v", err)
+	}
+
+	err = r.ValidateTemplates("views/home", "views/users/lsit", "nothing/like/this")
+	if err == nil {
+		t.Fatal("expected missing templates to fail validation")
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, `"views/users/lsit" (did you mean "views/users/list.html"?)`) {
+		t.Errorf("expected a suggestion for the typo, got %q", msg)
+	}
+
+	if !strings.Contains(msg, `"nothing/like/this"`) || strings.Contains(msg, `"views/home"`) {
+		t.Errorf("expected only the missing templates to be listed, got %q", msg)
+	}
+
+	// The configured layout is always validated.
+	r = NewRouter(WithTemplates(tmpl), BaseLayout("views/layout.html"))
+	if err := r.ValidateTemplates(); err == nil || !strings.Contains(err.Error(), "views/layout.html") {
+		t.Errorf("expected the missing base layout to be reported, got %v", err)
+	}
+
+	if err := NewRouter().ValidateTemplates("home"); err == nil {
+		t.Error("expected an error without templates")
+	}
+}
+
+func TestTemplateMissingKeyDebugSource(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/profile.html": {Data: []byte("<h1>{{.user}}</h1>\n<p>one</p>\n<p>two</p>\n<p>{{.email}}</p>\n<p>three</p>\n")},
+		"views/layout.html":  {Data: []byte(`<main>{{.content}}</main>`)},
+	}
+
+	tmpl, err := ParseTemplatesFS(fsys, "views", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRouter(WithTemplates(tmpl), BaseLayout("views/layout.html"), TemplateMissingKey("error"))
+	r.GET("/profile", func(c *Context) error {
+		return c.Render("views/profile", Map{"user": "rex"})
+	})
+
+	// Production: the terse error.
+	res := r.Test(NewTestRequest(http.MethodGet, "/profile").Build())
+	if res.Code != http.StatusInternalServerError || strings.Contains(res.Body.String(), "<p>two</p>") {
+		t.Errorf("expected a terse 500, got %d %q", res.Code, res.Body.String())
+	}
+
+	Debug = true
+	defer func() { Debug = false }()
+
+	res = r.Test(NewTestRequest(http.MethodGet, "/profile").Header("Accept", "application/json").Build())
+
+	var info struct {
+		Template struct {
+			File   string       `json:"file"`
+			Line   int          `json:"line"`
+			Source []SourceLine `json:"source"`
+		} `json:"template"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &info); err != nil {
+		t.Fatalf("expected a JSON debug response: %v\n%s", err, res.Body.String())
+	}
+
+	if info.Template.File != "views/profile.html" || info.Template.Line != 4 {
+		t.Errorf("expected views/profile.html:4, got %s:%d", info.Template.File, info.Template.Line)
+	}
+
+	// Three lines before the failing line and as many after as the file has.
+	if len(info.Template.Source) != 6 || info.Template.Source[0].Number != 1 {
+		t.Fatalf("unexpected source %+v", info.Template.Source)
+	}
+
+	current := info.Template.Source[3]
+	if !current.Current || current.Text != "<p>{{.email}}</p>" {
+		t.Errorf("expected line 4 to be marked, got %+v", current)
+	}
+
+	res = r.Test(NewTestRequest(http.MethodGet, "/profile").Build())
+	body := res.Body.String()
+	if !strings.Contains(body, "views/profile.html:4") || !strings.Contains(body, "&lt;p&gt;{{.email}}&lt;/p&gt;") {
+		t.Errorf("expected the HTML debug page to show the snippet, got %q", body)
+	}
+}
+
+func TestTemplateMissingKeyInvalidMode(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected an invalid mode to panic")
+		}
+	}()
+	TemplateMissingKey("strict")
+}
+
+func TestParsedTemplateInfoReleased(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "home.html"), []byte("home"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int {
+		parsedSetsMu.Lock()
+		defer parsedSetsMu.Unlock()
+		return len(parsedSets)
+	}
+
+	before := count()
+	for range 5 {
+		if _, err := ParseTemplates(dir, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 50 && count() > before; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := count(); n > before {
+		t.Errorf("expected the info of collected template sets to be released, %d entries left", n-before)
+	}
+}
+
+func TestTemplateErrorSourceInDefine(t *testing.T) {
+	fsys := fstest.MapFS{
+		"views/home.html":          {Data: []byte(`<main>{{template "partials/nav" .}}</main>`)},
+		"views/links.html":         {Data: []byte(`{{template "partials/link" .}}`)},
+		"views/partials/nav.html":  {Data: []byte("{{define \"partials/nav\"}}\n<nav>\n{{.user.Name}}\n</nav>\n{{end}}")},
+		"views/partials/link.html": {Data: []byte("{{define \"partials/link\"}}\n<a href=\"{{if .C}}/a/{{else}}/b?q={{end}}{{.X}}\">\n{{end}}")},
+	}
+
+	tmpl, err := ParseTemplatesFS(fsys, "views", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	execErr := func(name string) error {
+		var b strings.Builder
+		err := tmpl.ExecuteTemplate(&b, name, Map{"user": 1})
+		if err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+		return err
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		file string
+		line int
+		text string
+	}{
+		{"execution", execErr("views/home.html"), "views/partials/nav.html", 3, "{{.user.Name}}"},
+		{"escaping", execErr("views/links.html"), "views/partials/link.html", 2, `<a href="{{if .C}}/a/{{else}}/b?q={{end}}{{.X}}">`},
+		{"define name", errors.New(`template: partials/nav:3:7: executing "partials/nav"`), "views/partials/nav.html", 3, "{{.user.Name}}"},
+	}
+
+	for _, tt := range tests {
+		file, line, source, ok := templateErrorSource(tmpl, templateInfo(tmpl), tt.err)
+		if !ok {
+			t.Errorf("%s: expected the source of %v", tt.name, tt.err)
+			continue
+		}
+
+		if file != tt.file || line != tt.line {
+			t.Errorf("%s: expected %s:%d, got %s:%d", tt.name, tt.file, tt.line, file, line)
+		}
+
+		if i := slices.IndexFunc(source, func(l SourceLine) bool { return l.Current }); i < 0 || source[i].Text != tt.text {
+			t.Errorf("%s: expected the failing line to be marked, got %+v", tt.name, source)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package rex
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// flushMarker is written by the flush template func. RenderStream flushes the response
+// where it appears, the other renders drop it.
+const flushMarker = "<!--rex:flush-->"
+
+// flushMarkerBytes is flushMarker as a byte slice.
+var flushMarkerBytes = []byte(flushMarker)
+
+// TemplateIter is a sequence of values ranged over in templates with the iter func,
+// without materializing it in a slice first:
+//
+//	c.RenderStream("orders.html", rex.Map{"Orders": rex.TemplateSeq(store.Orders(ctx))})
+//
+//	{{range iter .Orders}}<tr><td>{{.ID}}</td></tr>{{flush}}{{end}}
+//
+// Pass it at the top level of the data of Render or RenderStream, which stop the sequence
+// and wait for it after the template ran, including when the template failed half-way.
+type TemplateIter[T any] struct {
+	seq func(yield func(T) bool)
+	ch  <-chan T
+
+	mu      sync.Mutex
+	stop    chan struct{}
+	running sync.WaitGroup
+	err     error // panic of the sequence
+}
+
+// TemplateSeq returns a TemplateIter of seq, e.g an iter.Seq[T]. The sequence runs on
+// its own goroutine while the template ranges over it, so it is stopped early if the
+// template stops ranging.
+func TemplateSeq[T any](seq func(yield func(T) bool)) *TemplateIter[T] {
+	return &TemplateIter[T]{seq: seq, stop: make(chan struct{})}
+}
+
+// TemplateChan returns a TemplateIter of the values received from ch until it is closed.
+// The sender owns ch and must stop sending once the request is done.
+func TemplateChan[T any](ch <-chan T) *TemplateIter[T] {
+	return &TemplateIter[T]{ch: ch, stop: make(chan struct{})}
+}
+
+// templateRanger is implemented by TemplateIter.
+type templateRanger interface {
+	rangeChan() any
+	stopRange() error
+}
+
+// rangeChan returns a channel of the values of the sequence for the range action.
+func (it *TemplateIter[T]) rangeChan() any {
+	if it.ch != nil {
+		return it.ch
+	}
+
+	ch := make(chan T)
+	it.running.Add(1)
+	go func() {
+		defer it.running.Done()
+		defer close(ch)
+		defer func() {
+			if r := recover(); r != nil {
+				it.mu.Lock()
+				it.err = fmt.Errorf("rex: template iterator panicked: %v", r)
+				it.mu.Unlock()
+			}
+		}()
+
+		it.seq(func(v T) bool {
+			select {
+			case ch <- v:
+				return true
+			case <-it.stop:
+				return false
+			}
+		})
+	}()
+	return ch
+}
+
+// stopRange stops the running sequences, waits for them and returns the panic of one.
+func (it *TemplateIter[T]) stopRange() error {
+	it.mu.Lock()
+	select {
+	case <-it.stop:
+	default:
+		close(it.stop)
+	}
+	it.mu.Unlock()
+
+	it.running.Wait()
+
+	it.mu.Lock()
+	defer it.mu.Unlock()
+	return it.err
+}
+
+// templateIter is the iter template func.
+func templateIter(v any) (any, error) {
+	it, ok := v.(templateRanger)
+	if !ok {
+		return nil, fmt.Errorf("iter: expected a rex.TemplateIter, got %T", v)
+	}
+	return it.rangeChan(), nil
+}
+
+// stripFlushMarkers removes the flush markers of a buffered page.
+func stripFlushMarkers(page string) string {
+	if !strings.Contains(page, flushMarker) {
+		return page
+	}
+	return strings.ReplaceAll(page, flushMarker, "")
+}
+
+// templateFlush is the flush template func.
+func templateFlush() template.HTML {
+	return flushMarker
+}
+
+// stopTemplateIters stops the TemplateIter values of data after a template ran.
+func stopTemplateIters(data Map) error {
+	var err error
+	for _, v := range data {
+		if it, ok := v.(templateRanger); ok {
+			if stopErr := it.stopRange(); stopErr != nil && err == nil {
+				err = stopErr
+			}
+		}
+	}
+	return err
+}
+
+// RenderStream renders the template name straight to the response, so that large pages,
+// like a table of a TemplateIter, reach the browser as they are rendered. The response is
+// flushed where the template calls {{flush}} and every StreamFlushBytes or StreamFlushInterval,
+// see FlushEvery and FlushInterval. The template stops with ErrClientGone once the client
+// went away.
+//
+// Unlike Render, the base layout is not applied because the content can not be post-processed
+// once it was sent: the page includes its own head and foot, e.g with {{template "header" .}}.
+// Render ETags and StaticFor page caching do not apply either, and an error after the first
+// write can no longer change the status or render the error page, it is only logged.
+// The data is prepared like for Render.
+func (c *Context) RenderStream(name string, data Map, options ...StreamOption) error {
+	if c.router.template == nil {
+		return fmt.Errorf("no template is configured")
+	}
+
+	if filepath.Ext(name) == "" {
+		name += ".html"
+	}
+
+	if err := checkRenderable(c.router.templateInfo, name); err != nil {
+		return err
+	}
+
+	if err := c.prepareViewData(data); err != nil {
+		return err
+	}
+
+	c.SetHeader("Content-Type", "text/html")
+	stream := c.StreamingWriter(options...)
+
+	err := c.executeTemplate(&flushMarkerWriter{w: stream, stream: stream}, name, name, data)
+	if stopErr := stopTemplateIters(data); err == nil {
+		err = stopErr
+	}
+
+	if err != nil {
+		return newTemplateError(c.router.template, c.router.templateInfo, name, data, err)
+	}
+	return stream.Flush()
+}
+
+// flushMarkerWriter removes the flush markers written by templates to w and,
+// if stream is set, flushes the stream where they were.
+type flushMarkerWriter struct {
+	w      io.Writer
+	stream *StreamingWriter
+}
+
+func (w *flushMarkerWriter) Write(p []byte) (int, error) {
+	n := 0
+	for {
+		i := bytes.Index(p, flushMarkerBytes)
+		if i < 0 {
+			m, err := w.w.Write(p)
+			return n + m, err
+		}
+
+		if i > 0 {
+			m, err := w.w.Write(p[:i])
+			n += m
+			if err != nil {
+				return n, err
+			}
+		}
+		n += len(flushMarker)
+		p = p[i+len(flushMarker):]
+
+		if w.stream != nil {
+			if err := w.stream.Flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+}
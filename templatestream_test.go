@@ -0,0 +1,263 @@
+package rex_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func newStreamRouter(t testing.TB) *rex.Router {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"base.html":  `<main>{{ .Content }}</main>`,
+		"rows.html":  `<table>{{range iter .Rows}}<tr><td>{{.}}</td></tr>{{flush}}{{end}}</table>`,
+		"slice.html": `<table>{{range .Rows}}<tr><td>{{.}}</td></tr>{{end}}</table>`,
+		"fails.html": `{{range iter .Rows}}{{if eq . 5}}{{.Missing}}{{end}}<p>{{.}}</p>{{end}}`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return rex.NewRouter(
+		rex.WithTemplates(rex.Must(rex.ParseTemplates(dir, nil))),
+		rex.BaseLayout("base.html"),
+	)
+}
+
+// countTo returns a sequence of the integers from 0 to n-1 that records how far it got
+// and whether it returned.
+func countTo(n int, yielded *atomic.Int64, returned *atomic.Bool) func(yield func(int) bool) {
+	return func(yield func(int) bool) {
+		defer returned.Store(true)
+		for i := 0; i < n; i++ {
+			if !yield(i) {
+				return
+			}
+			yielded.Add(1)
+		}
+	}
+}
+
+func TestRenderStreamIterator(t *testing.T) {
+	r := newStreamRouter(t)
+
+	var yielded atomic.Int64
+	var returned atomic.Bool
+	r.GET("/rows", func(c *rex.Context) error {
+		return c.RenderStream("rows", rex.Map{"Rows": rex.TemplateSeq(countTo(10_000, &yielded, &returned))})
+	})
+
+	w := &flushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/rows", nil))
+
+	body := w.Body.String()
+	if w.Code != http.StatusOK || strings.Count(body, "<tr>") != 10_000 {
+		t.Fatalf("expected 10000 rows, got %d with %d rows", w.Code, strings.Count(body, "<tr>"))
+	}
+
+	if !strings.HasPrefix(body, "<table><tr><td>0</td></tr>") || !strings.HasSuffix(body, "<tr><td>9999</td></tr></table>") {
+		t.Errorf("unexpected body %q...%q", body[:40], body[len(body)-40:])
+	}
+
+	if strings.Contains(body, "<main>") || strings.Contains(body, "rex:flush") {
+		t.Errorf("expected no layout and no flush markers, got %q", body[:40])
+	}
+
+	if w.flushes < 10_000 {
+		t.Errorf("expected a flush per row, got %d", w.flushes)
+	}
+
+	if yielded.Load() != 10_000 || !returned.Load() {
+		t.Errorf("expected the sequence to complete, yielded %d", yielded.Load())
+	}
+}
+
+func TestRenderStreamChannel(t *testing.T) {
+	r := newStreamRouter(t)
+	r.GET("/rows", func(c *rex.Context) error {
+		ch := make(chan string)
+		go func() {
+			defer close(ch)
+			for _, name := range []string{"a", "b", "c"} {
+				ch <- name
+			}
+		}()
+		return c.RenderStream("rows", rex.Map{"Rows": rex.TemplateChan(ch)})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/rows", nil))
+	if res.BodyString() != "<table><tr><td>a</td></tr><tr><td>b</td></tr><tr><td>c</td></tr></table>" {
+		t.Errorf("unexpected body %q", res.BodyString())
+	}
+
+	if res.Header("Content-Type") != "text/html" {
+		t.Errorf("expected text/html, got %q", res.Header("Content-Type"))
+	}
+}
+
+func TestRenderIteratorStripsFlush(t *testing.T) {
+	r := newStreamRouter(t)
+
+	var yielded atomic.Int64
+	var returned atomic.Bool
+	r.GET("/rows", func(c *rex.Context) error {
+		return c.Render("rows", rex.Map{"Rows": rex.TemplateSeq(countTo(2, &yielded, &returned))})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/rows", nil))
+	if res.BodyString() != "<main><table><tr><td>0</td></tr><tr><td>1</td></tr></table></main>" {
+		t.Errorf("expected the layout without flush markers, got %q", res.BodyString())
+	}
+}
+
+func TestTemplateIterStoppedOnError(t *testing.T) {
+	for _, render := range []string{"Render", "RenderStream"} {
+		t.Run(render, func(t *testing.T) {
+			r := newStreamRouter(t)
+
+			var yielded atomic.Int64
+			var returned atomic.Bool
+			var err error
+			r.GET("/fails", func(c *rex.Context) error {
+				data := rex.Map{"Rows": rex.TemplateSeq(countTo(10_000, &yielded, &returned))}
+				if render == "Render" {
+					err = c.Render("fails", data)
+				} else {
+					err = c.RenderStream("fails", data)
+				}
+				return err
+			})
+
+			r.Test(httptest.NewRequest(http.MethodGet, "/fails", nil))
+
+			if !returned.Load() || yielded.Load() > 10 {
+				t.Errorf("expected the sequence to be stopped before the render returned, yielded %d", yielded.Load())
+			}
+
+			var te rex.TemplateError
+			if !errors.As(err, &te) {
+				t.Errorf("expected a TemplateError, got %v", err)
+			}
+		})
+	}
+}
+
+func TestTemplateIterPanic(t *testing.T) {
+	r := newStreamRouter(t)
+	r.GET("/rows", func(c *rex.Context) error {
+		return c.Render("rows", rex.Map{"Rows": rex.TemplateSeq(func(yield func(int) bool) {
+			yield(1)
+			panic("cursor closed")
+		})})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/rows", nil))
+	if res.Code != http.StatusInternalServerError || strings.Contains(res.BodyString(), "<table>") {
+		t.Errorf("expected the panic of the sequence to fail the render, got %d %q", res.Code, res.BodyString())
+	}
+}
+
+func TestRenderStreamClientGone(t *testing.T) {
+	r := newStreamRouter(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var yielded atomic.Int64
+	var returned atomic.Bool
+	var err error
+	r.GET("/rows", func(c *rex.Context) error {
+		seq := countTo(10_000, &yielded, &returned)
+		err = c.RenderStream("rows", rex.Map{"Rows": rex.TemplateSeq(func(yield func(int) bool) {
+			seq(func(i int) bool {
+				if i == 100 {
+					cancel()
+				}
+				return yield(i)
+			})
+		})})
+		return err
+	})
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/rows", nil).WithContext(ctx))
+
+	if !errors.Is(err, rex.ErrClientGone) {
+		t.Errorf("expected ErrClientGone, got %v", err)
+	}
+
+	if !returned.Load() || yielded.Load() > 200 {
+		t.Errorf("expected the sequence to stop with the client, yielded %d", yielded.Load())
+	}
+}
+
+func TestIterRequiresTemplateIter(t *testing.T) {
+	r := newStreamRouter(t)
+	r.GET("/rows", func(c *rex.Context) error {
+		return c.Render("rows", rex.Map{"Rows": []int{1, 2}})
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/rows", nil))
+	if res.Code != http.StatusInternalServerError {
+		t.Errorf("expected iter of a slice to fail, got %d", res.Code)
+	}
+}
+
+// discardWriter is a ResponseWriter that does not keep the body.
+type discardWriter struct {
+	header http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+func (w *discardWriter) Flush()                      {}
+
+func benchmarkRows(b *testing.B, handler rex.HandlerFunc) {
+	r := newStreamRouter(b)
+	r.GET("/rows", handler)
+	req := httptest.NewRequest(http.MethodGet, "/rows", nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.ServeHTTP(&discardWriter{header: make(http.Header)}, req)
+	}
+}
+
+const benchmarkRowCount = 10_000
+
+func BenchmarkRenderSlice(b *testing.B) {
+	benchmarkRows(b, func(c *rex.Context) error {
+		rows := make([]string, benchmarkRowCount)
+		for i := range rows {
+			rows[i] = fmt.Sprintf("row %d", i)
+		}
+		return c.Render("slice", rex.Map{"Rows": rows})
+	})
+}
+
+func BenchmarkRenderStreamIter(b *testing.B) {
+	benchmarkRows(b, func(c *rex.Context) error {
+		return c.RenderStream("rows", rex.Map{"Rows": rex.TemplateSeq(func(yield func(string) bool) {
+			for i := 0; i < benchmarkRowCount; i++ {
+				if !yield(fmt.Sprintf("row %d", i)) {
+					return
+				}
+			}
+		})}, rex.FlushEvery(32<<10))
+	})
+}
@@ -0,0 +1,187 @@
+package rex
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+)
+
+// TestResponse wraps the recorded response of a request executed with r.Test.
+type TestResponse struct {
+	*httptest.ResponseRecorder
+}
+
+// Test executes req against the router, including all middleware,
+// and returns the recorded response. It does not start a server.
+//
+// Example:
+//
+//	res := r.Test(rex.NewTestRequest("GET", "/users").Build())
+//	if res.Status() != 200 { ... }
+func (r *Router) Test(req *http.Request) *TestResponse {
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return &TestResponse{ResponseRecorder: w}
+}
+
+// Status returns the response status code.
+func (res *TestResponse) Status() int {
+	return res.Code
+}
+
+// BodyString returns the response body as a string.
+func (res *TestResponse) BodyString() string {
+	return res.Body.String()
+}
+
+// JSON decodes the response body into v.
+func (res *TestResponse) JSON(v any) error {
+	return json.Unmarshal(res.Body.Bytes(), v)
+}
+
+// Header returns the value of the response header k.
+func (res *TestResponse) Header(k string) string {
+	return res.Result().Header.Get(k)
+}
+
+// Cookies returns the cookies set by the response.
+func (res *TestResponse) Cookies() []*http.Cookie {
+	return res.Result().Cookies()
+}
+
+type testFile struct {
+	field, filename string
+	content         []byte
+}
+
+// TestRequest is a fluent builder for requests used in tests.
+// Create one with NewTestRequest and call Build to get the *http.Request.
+type TestRequest struct {
+	method      string
+	target      string
+	headers     http.Header
+	cookies     []*http.Cookie
+	body        io.Reader
+	contentType string
+	form        url.Values
+	files       []testFile
+	err         error
+}
+
+// NewTestRequest creates a request builder for the given method and target.
+func NewTestRequest(method, target string) *TestRequest {
+	return &TestRequest{
+		method:  method,
+		target:  target,
+		headers: make(http.Header),
+	}
+}
+
+// Header sets a request header.
+func (b *TestRequest) Header(key, value string) *TestRequest {
+	b.headers.Set(key, value)
+	return b
+}
+
+// Cookie adds a cookie to the request.
+func (b *TestRequest) Cookie(c *http.Cookie) *TestRequest {
+	b.cookies = append(b.cookies, c)
+	return b
+}
+
+// Query adds a query parameter to the target URL.
+func (b *TestRequest) Query(key, value string) *TestRequest {
+	sep := "?"
+	if strings.Contains(b.target, "?") {
+		sep = "&"
+	}
+	b.target += sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+	return b
+}
+
+// Body sets a raw request body with the given content type.
+func (b *TestRequest) Body(body io.Reader, contentType string) *TestRequest {
+	b.body = body
+	b.contentType = contentType
+	return b
+}
+
+// JSON encodes v as the request body with the application/json content type.
+func (b *TestRequest) JSON(v any) *TestRequest {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	return b.Body(bytes.NewReader(data), ContentTypeJSON)
+}
+
+// Form sets url-encoded form values as the request body.
+// If File is also called, the values are sent as multipart form fields instead.
+func (b *TestRequest) Form(values url.Values) *TestRequest {
+	if b.form == nil {
+		b.form = make(url.Values)
+	}
+
+	for k, v := range values {
+		b.form[k] = append(b.form[k], v...)
+	}
+	return b
+}
+
+// File adds a file part to a multipart/form-data body.
+func (b *TestRequest) File(field, filename string, content []byte) *TestRequest {
+	b.files = append(b.files, testFile{field: field, filename: filename, content: content})
+	return b
+}
+
+// Build returns the *http.Request. It panics if the body could not be encoded.
+func (b *TestRequest) Build() *http.Request {
+	if b.err != nil {
+		panic(b.err)
+	}
+
+	body, contentType := b.body, b.contentType
+
+	if len(b.files) > 0 {
+		buf := &bytes.Buffer{}
+		mw := multipart.NewWriter(buf)
+		for k, values := range b.form {
+			for _, v := range values {
+				mw.WriteField(k, v)
+			}
+		}
+
+		for _, f := range b.files {
+			part, err := mw.CreateFormFile(f.field, f.filename)
+			if err != nil {
+				panic(err)
+			}
+			part.Write(f.content)
+		}
+		mw.Close()
+
+		body, contentType = buf, mw.FormDataContentType()
+	} else if b.form != nil {
+		body, contentType = strings.NewReader(b.form.Encode()), ContentTypeUrlEncoded
+	}
+
+	req := httptest.NewRequest(b.method, b.target, body)
+	for k, v := range b.headers {
+		req.Header[k] = v
+	}
+
+	if contentType != "" && req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	for _, c := range b.cookies {
+		req.AddCookie(c)
+	}
+	return req
+}
@@ -0,0 +1,101 @@
+package rex_test
+
+import (
+	"io"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+)
+
+func TestTestRequestMultipartRoundTrip(t *testing.T) {
+	type Upload struct {
+		Title string   `form:"title"`
+		Tags  []string `form:"tags"`
+	}
+
+	r := rex.NewRouter()
+	r.Use(func(next rex.HandlerFunc) rex.HandlerFunc {
+		return func(c *rex.Context) error {
+			c.SetHeader("X-Middleware", "ran")
+			return next(c)
+		}
+	})
+
+	r.POST("/upload", func(c *rex.Context) error {
+		var u Upload
+		if err := c.BodyParser(&u); err != nil {
+			return err
+		}
+
+		file, header, err := c.FormFile("document")
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		content, err := io.ReadAll(file)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(rex.Map{
+			"title":    u.Title,
+			"tags":     u.Tags,
+			"filename": header.Filename,
+			"content":  string(content),
+		})
+	})
+
+	req := rex.NewTestRequest("POST", "/upload").
+		Form(url.Values{"title": {"Report"}, "tags": {"a", "b"}}).
+		File("document", "report.txt", []byte("hello")).
+		Header("Accept", "application/json").
+		Build()
+
+	res := r.Test(req)
+	if res.Status() != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", res.Status(), res.BodyString())
+	}
+
+	if res.Header("X-Middleware") != "ran" {
+		t.Error("expected middleware to run")
+	}
+
+	var body struct {
+		Title    string   `json:"title"`
+		Tags     []string `json:"tags"`
+		Filename string   `json:"filename"`
+		Content  string   `json:"content"`
+	}
+
+	if err := res.JSON(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Title != "Report" || len(body.Tags) != 2 || body.Filename != "report.txt" || body.Content != "hello" {
+		t.Errorf("unexpected body: %+v", body)
+	}
+}
+
+func TestTestRequestFormAndCookie(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/login", func(c *rex.Context) error {
+		cookie, err := c.Request.Cookie("session")
+		if err != nil {
+			return err
+		}
+		return c.String(c.ContentType() + " " + c.FormValue("username") + " " + cookie.Value)
+	})
+
+	res := r.Test(rex.NewTestRequest("POST", "/login").
+		Form(url.Values{"username": {"rex"}}).
+		Cookie(&http.Cookie{Name: "session", Value: "abc"}).
+		Build())
+
+	expected := rex.ContentTypeUrlEncoded + " rex abc"
+	if res.BodyString() != expected {
+		t.Errorf("expected %q, got %q", expected, res.BodyString())
+	}
+}
@@ -0,0 +1,97 @@
+package rex
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timing is a named duration recorded with c.Timing.
+type Timing struct {
+	Name     string
+	Duration time.Duration
+}
+
+// WithServerTiming enables the Server-Timing response header.
+// Segments recorded with c.Timing are sent just before the first body write
+// together with a "total" entry measuring the time since the request started.
+// Browser devtools display the breakdown in the network panel.
+func WithServerTiming(enabled bool) RouterOption {
+	return func(r *Router) {
+		r.serverTiming = enabled
+	}
+}
+
+// Timing starts a named segment and returns a function that stops it.
+//
+// Example:
+//
+//	stop := c.Timing("db")
+//	users, err := db.ListUsers()
+//	stop()
+//
+// Segments stopped after the response headers are written can not be sent in the
+// Server-Timing header. They are still available with c.Timings().
+func (c *Context) Timing(name string) func() {
+	start := time.Now()
+	return func() {
+		t := Timing{Name: name, Duration: time.Since(start)}
+
+		c.mu.Lock()
+		c.timings = append(c.timings, t)
+		sent := c.timingsSent
+		c.mu.Unlock()
+
+		if sent && c.router != nil && c.router.serverTiming {
+			c.router.logger.Debug("server timing recorded after headers were sent, dropped from header",
+				"name", name, "path", c.Request.URL.Path)
+		}
+	}
+}
+
+// Timings returns the segments recorded with c.Timing so far.
+func (c *Context) Timings() []Timing {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]Timing(nil), c.timings...)
+}
+
+// writeServerTiming sets the Server-Timing header from the recorded segments.
+// It is installed as the ResponseWriter pre-write hook.
+func (c *Context) writeServerTiming() {
+	c.mu.Lock()
+	c.timingsSent = true
+	timings := c.timings
+	c.mu.Unlock()
+
+	total := Timing{Name: "total", Duration: time.Since(c.startTime)}
+	c.Response.Header().Set("Server-Timing", formatServerTiming(append(timings, total)))
+}
+
+// formatServerTiming formats timings as a Server-Timing header value
+// with durations in milliseconds.
+func formatServerTiming(timings []Timing) string {
+	parts := make([]string, 0, len(timings))
+	for _, t := range timings {
+		ms := float64(t.Duration) / float64(time.Millisecond)
+		parts = append(parts, fmt.Sprintf("%s;dur=%.3f", serverTimingName(t.Name), ms))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// serverTimingName replaces characters that are not valid in a header token.
+func serverTimingName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r >= 0x7f || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// installServerTiming registers the pre-write hook on the response writer.
+func (c *Context) installServerTiming() {
+	if w, ok := c.Response.(*ResponseWriter); ok {
+		w.onBeforeWrite(c.writeServerTiming)
+	}
+}
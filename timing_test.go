@@ -0,0 +1,108 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// parseServerTiming returns the durations in milliseconds keyed by name.
+func parseServerTiming(t *testing.T, header string) map[string]float64 {
+	t.Helper()
+	out := make(map[string]float64)
+	for _, part := range strings.Split(header, ", ") {
+		name, dur, ok := strings.Cut(part, ";dur=")
+		if !ok {
+			t.Fatalf("malformed Server-Timing entry %q", part)
+		}
+
+		ms, err := strconv.ParseFloat(dur, 64)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out[name] = ms
+	}
+	return out
+}
+
+func TestServerTiming(t *testing.T) {
+	r := rex.NewRouter(rex.WithServerTiming(true))
+
+	r.GET("/", func(c *rex.Context) error {
+		stop := c.Timing("db")
+		time.Sleep(10 * time.Millisecond)
+		stop()
+
+		stop = c.Timing("render")
+		time.Sleep(5 * time.Millisecond)
+		stop()
+
+		if len(c.Timings()) != 2 {
+			t.Errorf("expected 2 timings, got %d", len(c.Timings()))
+		}
+		return c.String("ok")
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	timings := parseServerTiming(t, res.Header("Server-Timing"))
+
+	if timings["db"] < 10 {
+		t.Errorf("expected db >= 10ms, got %v", timings["db"])
+	}
+
+	if timings["render"] < 5 {
+		t.Errorf("expected render >= 5ms, got %v", timings["render"])
+	}
+
+	if timings["total"] < timings["db"]+timings["render"] {
+		t.Errorf("expected total >= sum of segments, got %v", timings["total"])
+	}
+}
+
+func TestServerTimingStreaming(t *testing.T) {
+	r := rex.NewRouter(rex.WithServerTiming(true))
+
+	r.GET("/stream", func(c *rex.Context) error {
+		c.Write([]byte("chunk 1\n"))
+		c.Response.(http.Flusher).Flush()
+
+		stop := c.Timing("late")
+		time.Sleep(time.Millisecond)
+		stop()
+
+		_, err := c.Write([]byte("chunk 2\n"))
+		return err
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if res.BodyString() != "chunk 1\nchunk 2\n" {
+		t.Fatalf("unexpected body %q", res.BodyString())
+	}
+
+	timings := parseServerTiming(t, res.Header("Server-Timing"))
+	if _, ok := timings["late"]; ok {
+		t.Error("segments recorded after the first write must not be sent")
+	}
+
+	if _, ok := timings["total"]; !ok {
+		t.Error("expected total entry")
+	}
+}
+
+func TestServerTimingDisabled(t *testing.T) {
+	r := rex.NewRouter()
+	r.GET("/", func(c *rex.Context) error {
+		c.Timing("db")()
+		return c.String("ok")
+	})
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if res.Header("Server-Timing") != "" {
+		t.Errorf("expected no Server-Timing header, got %q", res.Header("Server-Timing"))
+	}
+}
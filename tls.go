@@ -0,0 +1,195 @@
+package rex
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// WithAutoReloadCerts loads the certificate and key from disk and reloads them when
+// either file changes, so that certificates can be rotated without a restart.
+// The files are checked at most once per interval during TLS handshakes and
+// immediately when the process receives SIGHUP. A zero interval only reloads on SIGHUP.
+// A failed reload keeps serving the previous certificate.
+// It panics if the certificate cannot be loaded initially.
+//
+// Start the server with ListenAndServeTLS("", "") as the certificate comes from the TLS config.
+// Apply it after WithTLSConfig, which replaces the TLS config.
+func WithAutoReloadCerts(certPath, keyPath string, interval time.Duration) ServerOption {
+	return func(s *Server) {
+		reloader, err := newCertReloader(certPath, keyPath, interval)
+		if err != nil {
+			panic(fmt.Errorf("rex: failed to load TLS certificate: %w", err))
+		}
+
+		s.tlsConfig().GetCertificate = reloader.GetCertificate
+
+		hup := make(chan os.Signal, 1)
+		stop := make(chan struct{})
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for {
+				select {
+				case <-hup:
+					reloader.reload(true)
+				case <-stop:
+					signal.Stop(hup)
+					return
+				}
+			}
+		}()
+		s.RegisterOnShutdown(func() { close(stop) })
+	}
+}
+
+// certReloader serves the certificate at certPath and keyPath, reloading it when it changes.
+type certReloader struct {
+	certPath, keyPath string
+	interval          time.Duration
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	certMod   time.Time
+	keyMod    time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(certPath, keyPath string, interval time.Duration) (*certReloader, error) {
+	cr := &certReloader{certPath: certPath, keyPath: keyPath, interval: interval}
+	if err := cr.load(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+// load parses the key pair and swaps it in.
+func (cr *certReloader) load() error {
+	certStat, err := os.Stat(cr.certPath)
+	if err != nil {
+		return err
+	}
+
+	keyStat, err := os.Stat(cr.keyPath)
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(cr.certPath, cr.keyPath)
+	if err != nil {
+		return err
+	}
+
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.certMod = certStat.ModTime()
+	cr.keyMod = keyStat.ModTime()
+	cr.lastCheck = time.Now()
+	cr.mu.Unlock()
+	return nil
+}
+
+// reload reloads the key pair if the files changed since the last load or force is true.
+func (cr *certReloader) reload(force bool) {
+	if !force {
+		certStat, err1 := os.Stat(cr.certPath)
+		keyStat, err2 := os.Stat(cr.keyPath)
+
+		cr.mu.Lock()
+		cr.lastCheck = time.Now()
+		unchanged := err1 != nil || err2 != nil ||
+			(certStat.ModTime().Equal(cr.certMod) && keyStat.ModTime().Equal(cr.keyMod))
+		cr.mu.Unlock()
+
+		if unchanged {
+			return
+		}
+	}
+
+	if err := cr.load(); err != nil {
+		log.Printf("rex: failed to reload TLS certificate, keeping the previous one: %v", err)
+	}
+}
+
+// GetCertificate implements tls.Config.GetCertificate.
+func (cr *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	due := cr.interval > 0 && time.Since(cr.lastCheck) >= cr.interval
+	cr.mu.RUnlock()
+
+	if due {
+		cr.reload(false)
+	}
+
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// WithAutocert obtains and renews certificates for hosts from Let's Encrypt
+// using golang.org/x/crypto/acme/autocert. Certificates are cached in cacheDir.
+// ListenAndServeTLS also starts a companion server on :80 that answers HTTP-01
+// challenges and redirects all other requests to https.
+// Start the server with ListenAndServeTLS("", "").
+//
+// Example:
+//
+//	srv := rex.NewServer(":443", r, rex.WithAutocert([]string{"example.com"}, "/var/cache/certs"))
+//	log.Fatal(srv.ListenAndServeTLS("", ""))
+func WithAutocert(hosts []string, cacheDir string) ServerOption {
+	return func(s *Server) {
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(hosts...),
+			Cache:      autocert.DirCache(cacheDir),
+		}
+
+		config := s.tlsConfig()
+		config.GetCertificate = m.GetCertificate
+		if !slices.Contains(config.NextProtos, acme.ALPNProto) {
+			config.NextProtos = append(config.NextProtos, acme.ALPNProto)
+		}
+
+		s.challengeServer = &http.Server{
+			Addr:              ":80",
+			Handler:           m.HTTPHandler(nil),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		s.RegisterOnShutdown(func() { s.challengeServer.Close() })
+	}
+}
+
+// tlsConfig returns the TLS config of the server, creating one with HTTP/2 enabled if needed.
+func (s *Server) tlsConfig() *tls.Config {
+	if s.TLSConfig == nil {
+		s.TLSConfig = &tls.Config{}
+	}
+
+	if len(s.TLSConfig.NextProtos) == 0 {
+		s.TLSConfig.NextProtos = []string{"h2", "http/1.1"}
+	}
+	return s.TLSConfig
+}
+
+// ListenAndServeTLS starts the companion HTTP-01 challenge server if WithAutocert
+// is used and then calls http.Server.ListenAndServeTLS.
+// certFile and keyFile may be empty if the TLS config provides the certificate.
+func (s *Server) ListenAndServeTLS(certFile, keyFile string) error {
+	if s.challengeServer != nil {
+		go func() {
+			if err := s.challengeServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("rex: acme challenge server: %v", err)
+			}
+		}()
+	}
+	return s.Server.ListenAndServeTLS(certFile, keyFile)
+}
@@ -0,0 +1,273 @@
+package rex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	// UploadIDHeader is the request header carrying the ID of a tracked upload.
+	UploadIDHeader = "X-Upload-ID"
+
+	// UploadIDParam is the query parameter carrying the ID of a tracked upload,
+	// for HTML forms that can not set headers.
+	UploadIDParam = "upload_id"
+
+	// UploadProgressTTL is how long the progress of a completed or aborted upload remains available.
+	UploadProgressTTL = time.Minute
+
+	// UploadProgressInterval is the interval between events of an UploadProgressHandler stream.
+	UploadProgressInterval = 500 * time.Millisecond
+
+	// MaxTrackedUploads is the number of uploads whose progress is kept at a time.
+	// Uploads started while the registry is full are not tracked.
+	MaxTrackedUploads = 10000
+)
+
+// maxUploadIDLength is the length limit of upload IDs, see validUploadID.
+const maxUploadIDLength = 128
+
+// UploadProgress is the progress of an upload tracked with TrackUploadProgress.
+type UploadProgress struct {
+	Received int64   `json:"received"` // bytes of the body read so far
+	Total    int64   `json:"total"`    // Content-Length of the request, -1 if unknown
+	Percent  float64 `json:"percent"`  // 0 if the total is unknown
+	Done     bool    `json:"done"`     // the request completed
+	Aborted  bool    `json:"aborted"`  // reading the body failed, e.g the client disconnected
+}
+
+// uploadRegistry stores the progress of uploads by ID.
+type uploadRegistry struct {
+	mu      sync.Mutex
+	entries map[string]*uploadEntry
+}
+
+// uploadEntry is the progress of one upload.
+type uploadEntry struct {
+	received atomic.Int64
+	total    int64
+
+	// Guarded by the registry mutex.
+	done    bool
+	aborted bool
+	expires time.Time // zero while the upload is in progress
+}
+
+func newUploadRegistry() *uploadRegistry {
+	return &uploadRegistry{entries: make(map[string]*uploadEntry)}
+}
+
+// start registers an upload, replacing a previous upload with the same ID.
+// It returns nil if MaxTrackedUploads uploads are already tracked.
+func (u *uploadRegistry) start(id string, total int64) *uploadEntry {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.sweep()
+	if _, ok := u.entries[id]; !ok && len(u.entries) >= MaxTrackedUploads {
+		return nil
+	}
+
+	entry := &uploadEntry{total: total}
+	u.entries[id] = entry
+	return entry
+}
+
+// finish marks the upload as done and schedules its removal.
+func (u *uploadRegistry) finish(entry *uploadEntry, aborted bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	entry.done = true
+	entry.aborted = entry.aborted || aborted
+	entry.expires = time.Now().Add(UploadProgressTTL)
+}
+
+// get returns the progress of the upload with id.
+func (u *uploadRegistry) get(id string) (UploadProgress, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.sweep()
+	entry, ok := u.entries[id]
+	if !ok {
+		return UploadProgress{}, false
+	}
+
+	progress := UploadProgress{
+		Received: entry.received.Load(),
+		Total:    entry.total,
+		Done:     entry.done,
+		Aborted:  entry.aborted,
+	}
+
+	if progress.Total > 0 {
+		percent := float64(progress.Received) * 100 / float64(progress.Total)
+		progress.Percent = math.Round(min(percent, 100)*10) / 10
+	}
+	return progress, true
+}
+
+// sweep removes expired uploads. The caller holds the mutex.
+func (u *uploadRegistry) sweep() {
+	now := time.Now()
+	for id, entry := range u.entries {
+		if !entry.expires.IsZero() && now.After(entry.expires) {
+			delete(u.entries, id)
+		}
+	}
+}
+
+// progressReader counts the bytes read from a request body.
+type progressReader struct {
+	io.ReadCloser
+	entry  *uploadEntry
+	failed bool // a read returned an error other than io.EOF
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.ReadCloser.Read(b)
+	p.entry.received.Add(int64(n))
+	if err != nil && !errors.Is(err, io.EOF) {
+		p.failed = true
+	}
+	return n, err
+}
+
+// validUploadID reports whether id is at most maxUploadIDLength letters, digits, '-' or '_'.
+func validUploadID(id string) bool {
+	if id == "" || len(id) > maxUploadIDLength {
+		return false
+	}
+
+	for _, ch := range []byte(id) {
+		switch {
+		case 'a' <= ch && ch <= 'z', 'A' <= ch && ch <= 'Z', '0' <= ch && ch <= '9', ch == '-', ch == '_':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// uploadID returns the upload ID of the request from UploadIDHeader or UploadIDParam.
+func uploadID(req *http.Request) string {
+	if id := req.Header.Get(UploadIDHeader); id != "" {
+		return id
+	}
+	return req.URL.Query().Get(UploadIDParam)
+}
+
+// TrackUploadProgress is a middleware that records how much of the request body has been read
+// for requests with an upload ID in the UploadIDHeader header or UploadIDParam query parameter.
+// The client picks the ID, up to 128 letters, digits, '-' or '_' e.g a UUID, and polls
+// UploadProgressHandler with it while uploading. Requests without a valid ID are not tracked,
+// nor are uploads beyond MaxTrackedUploads. Progress is kept in memory,
+// so it is only visible on the instance receiving the upload.
+// An upload whose handler panics is marked as aborted.
+//
+// Example:
+//
+//	r.POST("/videos", uploadVideo, rex.TrackUploadProgress())
+//	r.UploadProgressHandler("/upload-progress")
+func TrackUploadProgress() Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(c *Context) error {
+			id := uploadID(c.Request)
+			if !validUploadID(id) || c.Request.Body == nil {
+				return next(c)
+			}
+
+			uploads := c.router.uploads
+			entry := uploads.start(id, c.Request.ContentLength)
+			if entry == nil {
+				return next(c)
+			}
+
+			reader := &progressReader{ReadCloser: c.Request.Body, entry: entry}
+			c.Request.Body = reader
+
+			// Finish even if the handler panics, the upload would stay in progress forever.
+			completed := false
+			defer func() {
+				uploads.finish(entry, reader.failed || !completed)
+			}()
+
+			err := next(c)
+			completed = true
+			return err
+		}
+	}
+}
+
+// UploadProgressHandler registers a GET route at pattern answering the UploadProgress of the
+// upload whose ID is passed in the UploadIDParam query parameter or the UploadIDHeader header.
+// Requests accepting text/event-stream get a stream of progress events, every
+// UploadProgressInterval, until the upload is done. Unknown IDs are answered with 404 Not Found.
+func (r *Router) UploadProgressHandler(pattern string, middlewares ...Middleware) *Route {
+	return r.GET(pattern, func(c *Context) error {
+		id := uploadID(c.Request)
+		if id == "" {
+			return NewError(http.StatusBadRequest, fmt.Sprintf("missing %s query parameter", UploadIDParam))
+		}
+
+		if !validUploadID(id) {
+			return NewError(http.StatusBadRequest, "invalid upload ID")
+		}
+
+		progress, ok := c.router.uploads.get(id)
+		if !ok {
+			return NewError(http.StatusNotFound, "unknown upload")
+		}
+
+		if !strings.Contains(c.Request.Header.Get("Accept"), ContentTypeEventStream) {
+			c.SetHeader("Cache-Control", "no-store")
+			return c.JSON(progress)
+		}
+		return c.streamUploadProgress(id, progress)
+	}, middlewares...)
+}
+
+// streamUploadProgress writes progress events until the upload is done or the client disconnects.
+func (c *Context) streamUploadProgress(id string, progress UploadProgress) error {
+	c.SetHeader("Content-Type", ContentTypeEventStream)
+	c.SetHeader("Cache-Control", "no-store")
+
+	ticker := time.NewTicker(UploadProgressInterval)
+	defer ticker.Stop()
+
+	for {
+		data, err := json.Marshal(progress)
+		if err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintf(c.Response, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		c.rw.Flush()
+
+		if progress.Done {
+			return nil
+		}
+
+		select {
+		case <-c.Request.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		var ok bool
+		if progress, ok = c.router.uploads.get(id); !ok {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package rex_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+// chunkedReader returns one chunk per value received on next.
+type chunkedReader struct {
+	chunk []byte
+	next  chan struct{}
+}
+
+func (r *chunkedReader) Read(b []byte) (int, error) {
+	if _, ok := <-r.next; !ok {
+		return 0, io.EOF
+	}
+	return copy(b, r.chunk), nil
+}
+
+func uploadProgress(t *testing.T, r *rex.Router, id string) rex.UploadProgress {
+	t.Helper()
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/upload-progress?upload_id="+id, nil))
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", res.Code, res.BodyString())
+	}
+
+	var progress rex.UploadProgress
+	if err := json.Unmarshal(res.Body.Bytes(), &progress); err != nil {
+		t.Fatal(err)
+	}
+	return progress
+}
+
+func TestUploadProgress(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/upload", func(c *rex.Context) error {
+		_, err := io.Copy(io.Discard, c.Request.Body)
+		if err != nil {
+			return err
+		}
+		return c.String("uploaded")
+	}, rex.TrackUploadProgress())
+	r.UploadProgressHandler("/upload-progress")
+
+	body := &chunkedReader{chunk: []byte(strings.Repeat("x", 100)), next: make(chan struct{})}
+	req := httptest.NewRequest(http.MethodPost, "/upload", body)
+	req.ContentLength = 400
+	req.Header.Set(rex.UploadIDHeader, "video-1")
+
+	done := make(chan *rex.TestResponse)
+	go func() { done <- r.Test(req) }()
+
+	var last int64
+	for i := 0; i < 4; i++ {
+		body.next <- struct{}{}
+
+		// Wait for the chunk to be counted.
+		deadline := time.Now().Add(time.Second)
+		var progress rex.UploadProgress
+		for time.Now().Before(deadline) {
+			if progress = uploadProgress(t, r, "video-1"); progress.Received > last {
+				break
+			}
+			time.Sleep(time.Millisecond)
+		}
+
+		if progress.Received <= last || progress.Total != 400 || progress.Done {
+			t.Fatalf("chunk %d: expected increasing progress, got %+v", i, progress)
+		}
+		last = progress.Received
+	}
+
+	close(body.next)
+	if res := <-done; res.BodyString() != "uploaded" {
+		t.Fatalf("expected the upload to succeed, got %q", res.BodyString())
+	}
+
+	progress := uploadProgress(t, r, "video-1")
+	want := rex.UploadProgress{Received: 400, Total: 400, Percent: 100, Done: true}
+	if progress != want {
+		t.Errorf("expected %+v, got %+v", want, progress)
+	}
+}
+
+func TestUploadProgressExpires(t *testing.T) {
+	oldTTL := rex.UploadProgressTTL
+	rex.UploadProgressTTL = 20 * time.Millisecond
+	defer func() { rex.UploadProgressTTL = oldTTL }()
+
+	r := rex.NewRouter()
+	r.POST("/upload", func(c *rex.Context) error {
+		_, err := io.Copy(io.Discard, c.Request.Body)
+		return err
+	}, rex.TrackUploadProgress())
+	r.UploadProgressHandler("/upload-progress")
+
+	r.Test(httptest.NewRequest(http.MethodPost, "/upload?upload_id=doc-1", strings.NewReader("contents")))
+
+	if progress := uploadProgress(t, r, "doc-1"); !progress.Done || progress.Received != 8 {
+		t.Fatalf("expected the completed upload, got %+v", progress)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/upload-progress?upload_id=doc-1", nil))
+	if res.Code != http.StatusNotFound {
+		t.Errorf("expected the entry to expire, got %d", res.Code)
+	}
+}
+
+// failingReader fails after returning its data, like a dropped connection.
+type failingReader struct{ data *strings.Reader }
+
+func (r failingReader) Read(b []byte) (int, error) {
+	if n, _ := r.data.Read(b); n > 0 {
+		return n, nil
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestUploadProgressAborted(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/upload", func(c *rex.Context) error {
+		_, err := io.Copy(io.Discard, c.Request.Body)
+		return err
+	}, rex.TrackUploadProgress())
+	r.UploadProgressHandler("/upload-progress")
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", failingReader{strings.NewReader("part")})
+	req.ContentLength = 100
+	req.Header.Set(rex.UploadIDHeader, "big-1")
+	r.Test(req)
+
+	progress := uploadProgress(t, r, "big-1")
+	if !progress.Done || !progress.Aborted || progress.Received != 4 || progress.Percent != 4 {
+		t.Errorf("expected an aborted upload, got %+v", progress)
+	}
+}
+
+func TestUploadProgressPanic(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/upload", func(c *rex.Context) error {
+		io.ReadAll(c.Request.Body)
+		panic("disk full")
+	}, rex.TrackUploadProgress())
+	r.UploadProgressHandler("/upload-progress")
+
+	func() {
+		defer func() { recover() }()
+		r.Test(httptest.NewRequest(http.MethodPost, "/upload?upload_id=crash-1", strings.NewReader("data")))
+	}()
+
+	if progress := uploadProgress(t, r, "crash-1"); !progress.Done || !progress.Aborted {
+		t.Errorf("expected the upload of a panicking handler to be aborted, got %+v", progress)
+	}
+}
+
+func TestUploadProgressLimits(t *testing.T) {
+	oldMax := rex.MaxTrackedUploads
+	rex.MaxTrackedUploads = 1
+	defer func() { rex.MaxTrackedUploads = oldMax }()
+
+	r := rex.NewRouter()
+	r.POST("/upload", func(c *rex.Context) error {
+		_, err := io.Copy(io.Discard, c.Request.Body)
+		return err
+	}, rex.TrackUploadProgress())
+	r.UploadProgressHandler("/upload-progress")
+
+	for _, id := range []string{"first", "second"} {
+		r.Test(httptest.NewRequest(http.MethodPost, "/upload?upload_id="+id, strings.NewReader("data")))
+	}
+
+	uploadProgress(t, r, "first")
+	if res := r.Test(httptest.NewRequest(http.MethodGet, "/upload-progress?upload_id=second", nil)); res.Code != http.StatusNotFound {
+		t.Errorf("expected uploads beyond MaxTrackedUploads not to be tracked, got %d", res.Code)
+	}
+
+	invalid := "/upload-progress?upload_id=" + strings.Repeat("x", 200)
+	if res := r.Test(httptest.NewRequest(http.MethodGet, invalid, nil)); res.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid upload ID to be rejected, got %d", res.Code)
+	}
+}
+
+func TestUploadProgressStream(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/upload", func(c *rex.Context) error {
+		_, err := io.Copy(io.Discard, c.Request.Body)
+		return err
+	}, rex.TrackUploadProgress())
+	r.UploadProgressHandler("/upload-progress")
+
+	r.Test(httptest.NewRequest(http.MethodPost, "/upload?upload_id=doc-1", strings.NewReader("contents")))
+
+	req := httptest.NewRequest(http.MethodGet, "/upload-progress?upload_id=doc-1", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	res := r.Test(req)
+
+	if res.Header("Content-Type") != "text/event-stream" {
+		t.Errorf("expected an event stream, got %q", res.Header("Content-Type"))
+	}
+
+	want := `data: {"received":8,"total":8,"percent":100,"done":true,"aborted":false}` + "\n\n"
+	if res.BodyString() != want {
+		t.Errorf("expected %q, got %q", want, res.BodyString())
+	}
+
+	res = r.Test(httptest.NewRequest(http.MethodGet, "/upload-progress?upload_id=unknown", nil))
+	if res.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown upload, got %d", res.Code)
+	}
+}
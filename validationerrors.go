@@ -0,0 +1,81 @@
+package rex
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationErrorFormatter converts validation errors to the body of the response
+// written by HandleValidationErrors.
+type ValidationErrorFormatter func(c *Context, errs validator.ValidationErrors) any
+
+// ValidationErrorItem is a failed validation rule in the response of DefaultValidationErrorFormatter.
+type ValidationErrorItem struct {
+	Field   string `json:"field"`           // json or form tag name, e.g "email" or "address.city"
+	Rule    string `json:"rule"`            // the failed rule, e.g "min"
+	Param   string `json:"param,omitempty"` // the rule parameter, e.g "10" for min=10
+	Message string `json:"message"`         // translated message
+}
+
+// WithValidationErrorFormatter sets the function converting validation errors to the JSON
+// response of HandleValidationErrors and the "validation_errors" of the error template.
+// The default is DefaultValidationErrorFormatter.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.WithValidationErrorFormatter(func(c *rex.Context, errs validator.ValidationErrors) any {
+//		return rex.Map{"message": "invalid input", "fields": c.TranslateErrors(errs)}
+//	}))
+func WithValidationErrorFormatter(fn ValidationErrorFormatter) RouterOption {
+	return func(r *Router) {
+		r.validationErrorFormatter = fn
+	}
+}
+
+// DefaultValidationErrorFormatter returns {"errors": [...ValidationErrorItem]}.
+func DefaultValidationErrorFormatter(c *Context, errs validator.ValidationErrors) any {
+	return Map{"errors": c.ValidationErrorItems(errs)}
+}
+
+// ValidationErrorItems converts errs to items with the json or form tag names of the fields.
+// Nested fields are joined with dots, without the name of the validated struct.
+func (c *Context) ValidationErrorItems(errs validator.ValidationErrors) []ValidationErrorItem {
+	items := make([]ValidationErrorItem, 0, len(errs))
+	for _, fe := range errs {
+		items = append(items, ValidationErrorItem{
+			Field:   fieldPath(fe),
+			Rule:    fe.Tag(),
+			Param:   fe.Param(),
+			Message: fe.Translate(c.router.translator),
+		})
+	}
+	return items
+}
+
+// fieldPath returns the namespace of fe without the struct name, e.g "address.city"
+// for "User.address.city". Namespaces of slice elements like "[1].id" are kept.
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if strings.HasPrefix(ns, "[") {
+		return ns
+	}
+
+	if _, rest, ok := strings.Cut(ns, "."); ok {
+		return rest
+	}
+	return fe.Field()
+}
+
+// validationTagName names fields in validation errors after their json tag,
+// then their form tag, then the struct field name.
+func validationTagName(field reflect.StructField) string {
+	for _, key := range []string{"json", "form"} {
+		name, _, _ := strings.Cut(field.Tag.Get(key), ",")
+		if name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
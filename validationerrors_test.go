@@ -0,0 +1,163 @@
+package rex_test
+
+import (
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/go-playground/validator/v10"
+)
+
+type address struct {
+	City string `json:"city" validate:"required"`
+}
+
+type account struct {
+	Email    string  `json:"email" validate:"required,email"`
+	Password string  `json:"password" validate:"min=10"`
+	Address  address `json:"address"`
+	Nickname string  `json:"-" form:"nick" validate:"required"`
+}
+
+type validationBody struct {
+	Errors []rex.ValidationErrorItem `json:"errors"`
+}
+
+func postValidation(t *testing.T, r *rex.Router, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestValidationErrorsUseJSONTagNames(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/", func(c *rex.Context) error {
+		var in account
+		return c.BodyParser(&in)
+	})
+
+	w := postValidation(t, r, rex.ContentTypeJSON, `{"email": "nope", "password": "short"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d %s", w.Code, w.Body.String())
+	}
+
+	var body validationBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]rex.ValidationErrorItem{
+		"email":        {Field: "email", Rule: "email", Message: "email must be a valid email address"},
+		"password":     {Field: "password", Rule: "min", Param: "10", Message: "password must be at least 10 characters in length"},
+		"address.city": {Field: "address.city", Rule: "required", Message: "city is a required field"},
+		"nick":         {Field: "nick", Rule: "required", Message: "nick is a required field"},
+	}
+
+	if len(body.Errors) != len(want) {
+		t.Fatalf("expected %d errors, got %+v", len(want), body.Errors)
+	}
+
+	for _, item := range body.Errors {
+		if item != want[item.Field] {
+			t.Errorf("expected %+v, got %+v", want[item.Field], item)
+		}
+	}
+}
+
+func TestValidationErrorsUseFormTagNames(t *testing.T) {
+	type signup struct {
+		Username string `form:"user_name" validate:"required"`
+	}
+
+	r := rex.NewRouter()
+	r.POST("/", func(c *rex.Context) error {
+		var in signup
+		return c.BodyParser(&in)
+	})
+
+	w := postValidation(t, r, rex.ContentTypeUrlEncoded, url.Values{"user_name": {""}}.Encode())
+
+	var body validationBody
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(body.Errors) != 1 || body.Errors[0].Field != "user_name" || body.Errors[0].Rule != "required" {
+		t.Errorf("expected the form field name, got %s", w.Body.String())
+	}
+}
+
+func TestTranslateErrorsUsesTagNames(t *testing.T) {
+	r := rex.NewRouter()
+	r.POST("/", func(c *rex.Context) error {
+		var in account
+		err := c.BodyParser(&in)
+
+		var errs validator.ValidationErrors
+		if !errors.As(err, &errs) {
+			t.Fatalf("expected validation errors, got %v", err)
+		}
+
+		translated := c.TranslateErrors(errs)
+		if translated["account.email"] != "email is a required field" {
+			t.Errorf("expected the keys to use the json tag names, got %v", translated)
+		}
+		return nil
+	})
+
+	postValidation(t, r, rex.ContentTypeJSON, `{}`)
+}
+
+func TestCustomValidationErrorFormatter(t *testing.T) {
+	r := rex.NewRouter(rex.WithValidationErrorFormatter(func(c *rex.Context, errs validator.ValidationErrors) any {
+		fields := make([]string, 0, len(errs))
+		for _, item := range c.ValidationErrorItems(errs) {
+			fields = append(fields, item.Field)
+		}
+		return rex.Map{"invalid": fields}
+	}))
+
+	r.POST("/", func(c *rex.Context) error {
+		var in struct {
+			Email string `json:"email" validate:"required"`
+		}
+		return c.BodyParser(&in)
+	})
+
+	w := postValidation(t, r, rex.ContentTypeJSON, `{}`)
+	if got := strings.TrimSpace(w.Body.String()); w.Code != http.StatusBadRequest || got != `{"invalid":["email"]}` {
+		t.Errorf("expected the custom formatter to shape the response, got %d %s", w.Code, got)
+	}
+}
+
+func TestValidationErrorsErrorTemplate(t *testing.T) {
+	tmpl := template.Must(template.New("error.html").Parse(
+		`{{.status}}:{{range .validation_errors.errors}}[{{.Field}} {{.Rule}}]{{end}}`))
+	r := rex.NewRouter(rex.WithTemplates(tmpl), rex.ErrorTemplate("error.html"), rex.BaseLayout("error.html"))
+	r.POST("/", func(c *rex.Context) error {
+		var in struct {
+			Email string `form:"email" validate:"required"`
+		}
+		return c.BodyParser(&in)
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("email="))
+	req.Header.Set("Content-Type", rex.ContentTypeUrlEncoded)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "400:[email required]" {
+		t.Errorf("expected the error template to receive the validation errors, got %q", w.Body.String())
+	}
+}
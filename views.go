@@ -0,0 +1,123 @@
+package rex
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// ViewRequestKey is the reserved view data key holding the ViewRequest.
+// Templates access request information as {{ .Request.Path }}.
+const ViewRequestKey = "Request"
+
+// ViewTimezoneKey is the view data key holding the request timezone (a *time.Location)
+// when PassContextToViews is enabled, see Context.Timezone.
+// Templates format dates in it as {{ (.CreatedAt.In .timezone).Format "02 Jan 2006 15:04" }}.
+const ViewTimezoneKey = "timezone"
+
+// ViewRequest is the per-request view context injected into views under
+// ViewRequestKey when PassContextToViews is enabled.
+type ViewRequest struct {
+	Path      string     // Request path
+	Method    string     // Request method
+	Query     url.Values // Parsed query parameters
+	Route     string     // Pattern of the matched route e.g "/users/{id}"
+	CSRFToken string     // CSRF token set by the csrf middleware, if any
+	Flashes   []Flash    // Flash messages from RedirectWithFlash
+	User      any        // User returned by the WithViewUserFunc extractor, nil if anonymous
+}
+
+// Authenticated reports whether the view user is set.
+func (v ViewRequest) Authenticated() bool {
+	return v.User != nil
+}
+
+// WithViewUserFunc sets the function used to extract the current user for ViewRequest.User.
+// The function should return nil for anonymous requests.
+//
+// Example:
+//
+//	r := rex.NewRouter(rex.PassContextToViews(true), rex.WithViewUserFunc(func(c *rex.Context) any {
+//		user, _ := c.Get("user")
+//		return user
+//	}))
+func WithViewUserFunc(fn func(c *Context) any) RouterOption {
+	return func(r *Router) {
+		r.viewUserFunc = fn
+	}
+}
+
+// viewRequest builds the view context for the current request.
+func (c *Context) viewRequest() ViewRequest {
+	v := ViewRequest{
+		Path:    c.Request.URL.Path,
+		Method:  c.Request.Method,
+		Query:   c.Request.URL.Query(),
+		Route:   c.Pattern(),
+		Flashes: c.Flashes(),
+	}
+
+	if token, ok := c.Get("csrf_token"); ok {
+		v.CSRFToken, _ = token.(string)
+	}
+
+	if c.router.viewUserFunc != nil {
+		v.User = c.router.viewUserFunc(c)
+	}
+	return v
+}
+
+// injectViewRequest adds the ViewRequest to data.
+// It returns an error if data already uses the reserved key.
+func (c *Context) injectViewRequest(data Map) error {
+	if _, ok := data[ViewRequestKey]; ok {
+		return fmt.Errorf("rex: view data key %q is reserved for the request context", ViewRequestKey)
+	}
+	data[ViewRequestKey] = c.viewRequest()
+	return nil
+}
+
+// injectTimezone adds the request timezone to data unless the key is already set.
+func (c *Context) injectTimezone(data Map) {
+	if _, ok := data[ViewTimezoneKey]; !ok {
+		data[ViewTimezoneKey] = c.Timezone()
+	}
+}
+
+// ViewData is a builder for view data passed to c.Render.
+//
+// Example:
+//
+//	data := rex.NewViewData().Set("Title", "Home").SetUser(user).Map()
+//	return c.Render("home", data)
+type ViewData struct {
+	data Map
+}
+
+// NewViewData creates an empty ViewData.
+func NewViewData() *ViewData {
+	return &ViewData{data: make(Map)}
+}
+
+// Set sets key to value.
+func (v *ViewData) Set(key string, value any) *ViewData {
+	v.data[key] = value
+	return v
+}
+
+// SetUser sets the "User" key.
+func (v *ViewData) SetUser(user any) *ViewData {
+	return v.Set("User", user)
+}
+
+// Merge copies all keys from m, overwriting existing keys.
+func (v *ViewData) Merge(m Map) *ViewData {
+	for k, value := range m {
+		v.data[k] = value
+	}
+	return v
+}
+
+// Map returns the view data.
+func (v *ViewData) Map() Map {
+	return v.data
+}
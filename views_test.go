@@ -0,0 +1,138 @@
+package rex_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+)
+
+func newViewRouter(t *testing.T) *rex.Router {
+	t.Helper()
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"base.html": `<title>{{ .Title }}</title><nav>{{ .Request.Method }} {{ .Request.Path }} {{ .Request.Route }} ` +
+			`{{ if .Request.Authenticated }}hello {{ .Request.User }}{{ else }}anonymous{{ end }}</nav>{{ .Content }}`,
+		"home.html": `<p>page={{ .Request.Query.Get "page" }}</p>`,
+		"tz.html":   `<p>{{ .timezone }} {{ (.StartsAt.In .timezone).Format "15:04" }}</p>`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	templ := rex.Must(rex.ParseTemplates(dir, nil))
+	r := rex.NewRouter(
+		rex.WithTemplates(templ),
+		rex.BaseLayout("base.html"),
+		rex.ContentBlock("Content"),
+		rex.PassContextToViews(true),
+		rex.WithViewUserFunc(func(c *rex.Context) any {
+			if name := c.Request.Header.Get("X-User"); name != "" {
+				return name
+			}
+			return nil
+		}),
+	)
+
+	r.GET("/home/{section}", func(c *rex.Context) error {
+		return c.Render("home", rex.NewViewData().Set("Title", "Home").Map())
+	})
+
+	r.GET("/tz", func(c *rex.Context) error {
+		c.SetTimezone(time.FixedZone("EAT", 3*60*60))
+		startsAt := time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC)
+		return c.Render("tz", rex.Map{"StartsAt": startsAt})
+	})
+
+	r.GET("/reserved", func(c *rex.Context) error {
+		return c.Render("home", rex.Map{"Request": "mine"})
+	})
+	return r
+}
+
+func TestViewRequestContext(t *testing.T) {
+	r := newViewRouter(t)
+
+	tests := []struct {
+		name string
+		user string
+		want string
+	}{
+		{"authenticated", "rex", "<nav>GET /home/news /home/{section} hello rex</nav>"},
+		{"anonymous", "", "<nav>GET /home/news /home/{section} anonymous</nav>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/home/news?page=2", nil)
+			if tt.user != "" {
+				req.Header.Set("X-User", tt.user)
+			}
+
+			res := r.Test(req)
+			if res.Status() != http.StatusOK {
+				t.Fatalf("expected status 200, got %d: %s", res.Status(), res.BodyString())
+			}
+
+			body := res.BodyString()
+			if !strings.Contains(body, "<title>Home</title>") {
+				t.Errorf("expected title in %q", body)
+			}
+
+			if !strings.Contains(body, tt.want) {
+				t.Errorf("expected %q in %q", tt.want, body)
+			}
+
+			if !strings.Contains(body, "<p>page=2</p>") {
+				t.Errorf("expected query in %q", body)
+			}
+		})
+	}
+}
+
+func TestViewRequestReservedKey(t *testing.T) {
+	r := newViewRouter(t)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/reserved", nil))
+	if res.Status() != http.StatusInternalServerError {
+		t.Errorf("expected status 500, got %d", res.Status())
+	}
+
+	if !strings.Contains(res.BodyString(), "reserved") {
+		t.Errorf("expected reserved key error, got %q", res.BodyString())
+	}
+}
+
+func TestViewData(t *testing.T) {
+	data := rex.NewViewData().
+		Set("Title", "Users").
+		SetUser("rex").
+		Merge(rex.Map{"Count": 2, "Title": "All users"}).
+		Map()
+
+	if data["Title"] != "All users" || data["User"] != "rex" || data["Count"] != 2 {
+		t.Errorf("unexpected view data %v", data)
+	}
+}
+
+func TestViewTimezone(t *testing.T) {
+	r := newViewRouter(t)
+
+	res := r.Test(httptest.NewRequest(http.MethodGet, "/tz", nil))
+	if res.Status() != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", res.Status(), res.BodyString())
+	}
+
+	if body := res.BodyString(); !strings.Contains(body, "<p>EAT 09:00</p>") {
+		t.Errorf("expected the request timezone in %q", body)
+	}
+}
@@ -0,0 +1,364 @@
+// Package wizard accumulates the data of server-rendered multi-step forms, e.g
+// contact → shipping → payment → confirm, across requests.
+//
+// The state is a struct whose fields are assigned to steps with a `step` tag. Each step
+// merges the submitted fields of that step and validates them; the last step validates
+// the whole struct and clears the state. The state is kept in an encrypted cookie,
+// or in a gorilla session store with WithSession.
+//
+// Example:
+//
+//	type Order struct {
+//		Email   string `form:"email" step:"contact" validate:"required,email"`
+//		Address string `form:"address" step:"shipping" validate:"required"`
+//		Card    string `form:"card" step:"payment" validate:"required"`
+//	}
+//
+//	r.POST("/checkout/{step}", func(c *rex.Context) error {
+//		w := wizard.New[Order](c, "checkout", wizard.WithKeys(hashKey, blockKey))
+//		if err := w.Merge(c.Param("step")); err != nil {
+//			if errors.Is(err, wizard.ErrExpired) {
+//				w.Reset()
+//				return c.RedirectWithFlash("/checkout/contact", "warning", "Your session expired, please start over")
+//			}
+//			return err
+//		}
+//		...
+//	})
+package wizard
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+var (
+	// ErrExpired is returned when the stored state is older than the TTL, was written
+	// with other keys or is malformed. Handlers usually Reset and restart the wizard.
+	ErrExpired = errors.New("wizard: the stored state expired, start over")
+
+	// ErrTooLarge is returned when the state exceeds the maximum size, see WithMaxSize.
+	ErrTooLarge = errors.New("wizard: the state is too large to store")
+)
+
+// StateError is the error of a wizard whose state can not be loaded or saved.
+// Err is ErrExpired or ErrTooLarge.
+type StateError struct {
+	Name string // name of the wizard
+	Err  error
+}
+
+func (e *StateError) Error() string {
+	return fmt.Sprintf("%v (wizard %q)", e.Err, e.Name)
+}
+
+func (e *StateError) Unwrap() error {
+	return e.Err
+}
+
+// Defaults of the wizard options.
+var (
+	DefaultTTL     = 30 * time.Minute
+	DefaultMaxSize = 4096 // bytes, the cookie size limit of browsers
+)
+
+// sessionName is the name of the session holding the states of all wizards, see WithSession.
+const sessionName = "rex_wizard"
+
+// defaultKeys encrypt the cookies of wizards created without WithKeys.
+// They change when the process restarts, which expires all stored states.
+var defaultKeys = [2][]byte{securecookie.GenerateRandomKey(32), securecookie.GenerateRandomKey(32)}
+
+// Option configures a Wizard.
+type Option func(*config)
+
+type config struct {
+	hashKey  []byte
+	blockKey []byte
+	store    sessions.Store
+	ttl      time.Duration
+	maxSize  int
+}
+
+// WithKeys sets the keys authenticating and encrypting the state cookie,
+// see securecookie.New. Use the same keys on all instances of the application.
+// Without keys, the cookie is encrypted with random keys generated at startup.
+func WithKeys(hashKey, blockKey []byte) Option {
+	return func(c *config) {
+		c.hashKey, c.blockKey = hashKey, blockKey
+	}
+}
+
+// WithSession keeps the state in the session store instead of a cookie,
+// e.g the store of the application's session middleware.
+func WithSession(store sessions.Store) Option {
+	return func(c *config) {
+		c.store = store
+	}
+}
+
+// WithTTL sets how long the state is kept after the last merged step. Default is DefaultTTL.
+func WithTTL(ttl time.Duration) Option {
+	return func(c *config) {
+		c.ttl = ttl
+	}
+}
+
+// WithMaxSize sets the maximum size in bytes of the stored state. Default is DefaultMaxSize.
+func WithMaxSize(n int) Option {
+	return func(c *config) {
+		c.maxSize = n
+	}
+}
+
+// Wizard is the state of a multi-step form for one request.
+type Wizard[T any] struct {
+	c    *rex.Context
+	name string
+	cfg  config
+
+	state state[T]
+	err   error // error loading the state
+}
+
+// state is the stored representation of a wizard.
+type state[T any] struct {
+	Saved int64    `json:"saved"` // unix milliseconds of the last save
+	Steps []string `json:"steps"` // merged steps in order of first submission
+	Data  T        `json:"data"`
+}
+
+// New loads the state of the wizard name for the request. A missing state starts
+// an empty wizard. An expired or invalid state also starts an empty wizard and is reported
+// by Err and the other methods, so that the handler can ask the user to start over.
+// T must be a struct.
+func New[T any](c *rex.Context, name string, options ...Option) *Wizard[T] {
+	cfg := config{
+		hashKey:  defaultKeys[0],
+		blockKey: defaultKeys[1],
+		ttl:      DefaultTTL,
+		maxSize:  DefaultMaxSize,
+	}
+
+	for _, option := range options {
+		option(&cfg)
+	}
+
+	if reflect.TypeFor[T]().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("wizard: %q: the state must be a struct, got %s", name, reflect.TypeFor[T]()))
+	}
+
+	w := &Wizard[T]{c: c, name: name, cfg: cfg}
+	w.err = w.load()
+	return w
+}
+
+// Err returns the error loading the stored state, a *StateError wrapping ErrExpired, or nil.
+func (w *Wizard[T]) Err() error {
+	return w.err
+}
+
+// Data returns the accumulated state, e.g to prefill the form of a step.
+func (w *Wizard[T]) Data() *T {
+	return &w.state.Data
+}
+
+// Steps returns the steps merged so far.
+func (w *Wizard[T]) Steps() []string {
+	return slices.Clone(w.state.Steps)
+}
+
+// Merge parses the submitted form and applies the fields of step, those with a matching `step`
+// tag, to the state, then stores it. Fields missing from the form keep their stored value, and
+// fields of other steps are never changed, so going back and resubmitting a step only overwrites
+// that step. Keys are resolved like BodyParser and only submitted keys are applied, see
+// rex.Context.ChangedFields.
+//
+// Merge returns the load error if the stored state expired, the form errors of
+// rex.Context.DecodeForm and a *StateError wrapping ErrTooLarge if the state can not be stored.
+func (w *Wizard[T]) Merge(step string) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	var submitted T
+	if err := w.c.DecodeForm(&submitted); err != nil {
+		return err
+	}
+
+	fields := stepFields(reflect.TypeFor[T](), step)
+	data := reflect.ValueOf(&w.state.Data).Elem()
+	values := reflect.ValueOf(&submitted).Elem()
+	for _, name := range w.c.ChangedFields(&submitted) {
+		if slices.Contains(fields, name) {
+			data.FieldByName(name).Set(values.FieldByName(name))
+		}
+	}
+
+	if !slices.Contains(w.state.Steps, step) {
+		w.state.Steps = append(w.state.Steps, step)
+	}
+	return w.save()
+}
+
+// Validate validates the fields of step in the state with the router's validator.
+// It returns the validator.ValidationErrors of those fields, nil if step has no fields.
+func (w *Wizard[T]) Validate(step string) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	fields := stepFields(reflect.TypeFor[T](), step)
+	if len(fields) == 0 {
+		return nil
+	}
+	return w.c.ValidateFields(&w.state.Data, fields...)
+}
+
+// Complete validates the whole state and, if it is valid, clears the stored state and returns it.
+func (w *Wizard[T]) Complete() (T, error) {
+	var zero T
+	if w.err != nil {
+		return zero, w.err
+	}
+
+	if err := w.c.ValidateFields(&w.state.Data); err != nil {
+		return zero, err
+	}
+
+	data := w.state.Data
+	w.Reset()
+	return data, nil
+}
+
+// Reset clears the state, the stored one and the load error, to start over.
+func (w *Wizard[T]) Reset() error {
+	w.state, w.err = state[T]{}, nil
+
+	if w.cfg.store != nil {
+		session, _ := w.cfg.store.Get(w.c.Request, sessionName)
+		delete(session.Values, w.name)
+		return session.Save(w.c.Request, w.c.Response)
+	}
+
+	http.SetCookie(w.c.Response, &http.Cookie{
+		Name:     w.cookieName(),
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
+
+// stepFields returns the names of the fields of t whose step tag lists step.
+// A field shared by several steps lists them separated by commas, e.g `step:"shipping,billing"`.
+func stepFields(t reflect.Type, step string) []string {
+	var fields []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() && slices.Contains(strings.Split(field.Tag.Get("step"), ","), step) {
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+func (w *Wizard[T]) cookieName() string {
+	return "rex_wizard_" + w.name
+}
+
+func (w *Wizard[T]) codec() *securecookie.SecureCookie {
+	codec := securecookie.New(w.cfg.hashKey, w.cfg.blockKey)
+	codec.SetSerializer(securecookie.NopEncoder{})
+	codec.MaxLength(0) // checked by save
+	return codec
+}
+
+// load reads the stored state, leaving an empty state if there is none.
+func (w *Wizard[T]) load() error {
+	var payload []byte
+	if w.cfg.store != nil {
+		session, err := w.cfg.store.Get(w.c.Request, sessionName)
+		if err != nil {
+			return &StateError{Name: w.name, Err: ErrExpired}
+		}
+
+		value, ok := session.Values[w.name].(string)
+		if !ok {
+			return nil
+		}
+		payload = []byte(value)
+	} else {
+		cookie, err := w.c.Request.Cookie(w.cookieName())
+		if err != nil || cookie.Value == "" {
+			return nil
+		}
+
+		if err := w.codec().Decode(w.cookieName(), cookie.Value, &payload); err != nil {
+			return &StateError{Name: w.name, Err: ErrExpired}
+		}
+	}
+
+	var loaded state[T]
+	if err := json.Unmarshal(payload, &loaded); err != nil {
+		return &StateError{Name: w.name, Err: ErrExpired}
+	}
+
+	if time.Since(time.UnixMilli(loaded.Saved)) > w.cfg.ttl {
+		return &StateError{Name: w.name, Err: ErrExpired}
+	}
+
+	w.state = loaded
+	return nil
+}
+
+// save stores the state, refreshing its TTL.
+func (w *Wizard[T]) save() error {
+	w.state.Saved = time.Now().UnixMilli()
+	payload, err := json.Marshal(w.state)
+	if err != nil {
+		return err
+	}
+
+	if w.cfg.store != nil {
+		if len(payload) > w.cfg.maxSize {
+			return &StateError{Name: w.name, Err: ErrTooLarge}
+		}
+
+		session, _ := w.cfg.store.Get(w.c.Request, sessionName)
+		session.Values[w.name] = string(payload)
+		return session.Save(w.c.Request, w.c.Response)
+	}
+
+	value, err := w.codec().Encode(w.cookieName(), payload)
+	if err != nil {
+		return err
+	}
+
+	if len(w.cookieName())+len(value) > w.cfg.maxSize {
+		return &StateError{Name: w.name, Err: ErrTooLarge}
+	}
+
+	http.SetCookie(w.c.Response, &http.Cookie{
+		Name:     w.cookieName(),
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(w.cfg.ttl / time.Second),
+		HttpOnly: true,
+		Secure:   w.c.Request.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return nil
+}
@@ -0,0 +1,218 @@
+package wizard_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/wizard"
+	"github.com/gorilla/securecookie"
+	"github.com/gorilla/sessions"
+)
+
+type Order struct {
+	Name    string `form:"name" step:"contact" validate:"required"`
+	Email   string `form:"email" step:"contact" validate:"required,email"`
+	Address string `form:"address" step:"shipping" validate:"required"`
+	City    string `form:"city" step:"shipping"`
+	Card    string `form:"card" step:"payment" validate:"required,len=4"`
+}
+
+var keys = []wizard.Option{wizard.WithKeys(securecookie.GenerateRandomKey(32), securecookie.GenerateRandomKey(32))}
+
+func newRouter(t *testing.T, options ...wizard.Option) (*rex.Router, *Order) {
+	t.Helper()
+
+	completed := new(Order)
+	r := rex.NewRouter()
+	r.POST("/checkout/{step}", func(c *rex.Context) error {
+		w := wizard.New[Order](c, "checkout", options...)
+
+		step := c.Param("step")
+		if step == "confirm" {
+			order, err := w.Complete()
+			if err != nil {
+				return err
+			}
+			*completed = order
+			return c.String("ordered")
+		}
+
+		if err := w.Merge(step); err != nil {
+			return err
+		}
+
+		if err := w.Validate(step); err != nil {
+			return err
+		}
+		return c.JSON(w.Data())
+	})
+	return r, completed
+}
+
+// client submits forms with the cookies of the previous responses.
+type client struct {
+	t       *testing.T
+	r       *rex.Router
+	cookies map[string]*http.Cookie
+}
+
+func (cl *client) submit(step string, form url.Values) *rex.TestResponse {
+	cl.t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/checkout/"+step, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cl.cookies {
+		req.AddCookie(cookie)
+	}
+
+	res := cl.r.Test(req)
+	for _, cookie := range res.Result().Cookies() {
+		cl.cookies[cookie.Name] = cookie
+	}
+	return res
+}
+
+func newClient(t *testing.T, r *rex.Router) *client {
+	return &client{t: t, r: r, cookies: map[string]*http.Cookie{}}
+}
+
+func TestThreeStepFlow(t *testing.T) {
+	r, completed := newRouter(t, keys...)
+	cl := newClient(t, r)
+
+	steps := []struct {
+		step string
+		form url.Values
+	}{
+		{"contact", url.Values{"name": {"Ann"}, "email": {"ann@example.com"}}},
+		{"shipping", url.Values{"address": {"1 Main St"}, "city": {"Kampala"}}},
+		{"payment", url.Values{"card": {"4242"}}},
+	}
+
+	for _, s := range steps {
+		if res := cl.submit(s.step, s.form); res.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d %q", s.step, res.Code, res.BodyString())
+		}
+	}
+
+	if res := cl.submit("confirm", nil); res.BodyString() != "ordered" {
+		t.Fatalf("expected the order to complete, got %d %q", res.Code, res.BodyString())
+	}
+
+	want := Order{Name: "Ann", Email: "ann@example.com", Address: "1 Main St", City: "Kampala", Card: "4242"}
+	if *completed != want {
+		t.Errorf("expected %+v, got %+v", want, *completed)
+	}
+
+	// Completing clears the state.
+	if res := cl.submit("confirm", nil); res.Code == http.StatusOK {
+		t.Error("expected the cleared state to fail validation")
+	}
+}
+
+func TestResubmitOverwritesOnlyThatStep(t *testing.T) {
+	r, completed := newRouter(t, keys...)
+	cl := newClient(t, r)
+
+	cl.submit("contact", url.Values{"name": {"Ann"}, "email": {"ann@example.com"}})
+	cl.submit("shipping", url.Values{"address": {"1 Main St"}, "city": {"Kampala"}})
+
+	// Going back to shipping, a key of the contact step is ignored and the city is kept.
+	cl.submit("shipping", url.Values{"address": {"2 Side St"}, "name": {"Mallory"}})
+	cl.submit("payment", url.Values{"card": {"4242"}})
+	cl.submit("confirm", nil)
+
+	want := Order{Name: "Ann", Email: "ann@example.com", Address: "2 Side St", City: "Kampala", Card: "4242"}
+	if *completed != want {
+		t.Errorf("expected %+v, got %+v", want, *completed)
+	}
+}
+
+func TestStepValidation(t *testing.T) {
+	r, _ := newRouter(t, keys...)
+	cl := newClient(t, r)
+
+	// Fields of later steps are not validated yet.
+	if res := cl.submit("contact", url.Values{"name": {"Ann"}, "email": {"ann@example.com"}}); res.Code != http.StatusOK {
+		t.Fatalf("expected the contact step to be valid, got %d %q", res.Code, res.BodyString())
+	}
+
+	if res := cl.submit("payment", url.Values{"card": {"42"}}); res.Code != http.StatusBadRequest {
+		t.Errorf("expected an invalid card to be rejected, got %d %q", res.Code, res.BodyString())
+	}
+}
+
+func TestTooLarge(t *testing.T) {
+	var err error
+	r := rex.NewRouter()
+	r.POST("/checkout/{step}", func(c *rex.Context) error {
+		err = wizard.New[Order](c, "checkout", append(keys, wizard.WithMaxSize(256))...).Merge(c.Param("step"))
+		return nil
+	})
+
+	cl := newClient(t, r)
+	cl.submit("shipping", url.Values{"address": {strings.Repeat("a", 300)}})
+
+	var stateErr *wizard.StateError
+	if !errors.Is(err, wizard.ErrTooLarge) || !errors.As(err, &stateErr) || stateErr.Name != "checkout" {
+		t.Errorf("expected a StateError wrapping ErrTooLarge, got %v", err)
+	}
+
+	if len(cl.cookies) != 0 {
+		t.Errorf("expected no cookie to be set, got %v", cl.cookies)
+	}
+}
+
+func TestExpired(t *testing.T) {
+	r, _ := newRouter(t, append(keys, wizard.WithTTL(20*time.Millisecond))...)
+	cl := newClient(t, r)
+
+	cl.submit("contact", url.Values{"name": {"Ann"}, "email": {"ann@example.com"}})
+	time.Sleep(40 * time.Millisecond)
+
+	var err error
+	r.POST("/expired", func(c *rex.Context) error {
+		w := wizard.New[Order](c, "checkout", append(keys, wizard.WithTTL(20*time.Millisecond))...)
+		err = w.Merge("shipping")
+
+		w.Reset()
+		if w.Err() != nil || w.Data().Name != "" {
+			t.Error("expected Reset to start over")
+		}
+		return nil
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/expired", nil)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cl.cookies {
+		req.AddCookie(cookie)
+	}
+	r.Test(req)
+
+	if !errors.Is(err, wizard.ErrExpired) {
+		t.Errorf("expected ErrExpired, got %v", err)
+	}
+}
+
+func TestSessionStore(t *testing.T) {
+	store := sessions.NewCookieStore(securecookie.GenerateRandomKey(32))
+	r, completed := newRouter(t, wizard.WithSession(store))
+	cl := newClient(t, r)
+
+	cl.submit("contact", url.Values{"name": {"Ann"}, "email": {"ann@example.com"}})
+	cl.submit("shipping", url.Values{"address": {"1 Main St"}})
+	cl.submit("payment", url.Values{"card": {"4242"}})
+	if res := cl.submit("confirm", nil); res.BodyString() != "ordered" {
+		t.Fatalf("expected the order to complete, got %d %q", res.Code, res.BodyString())
+	}
+
+	if completed.Email != "ann@example.com" || completed.Card != "4242" {
+		t.Errorf("unexpected order %+v", *completed)
+	}
+}
@@ -0,0 +1,60 @@
+package rex
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// AssertNotWritten checks that the response has not been started, at the decision point
+// of a middleware that may still reject the request, e.g an auth middleware about to
+// answer 401. A middleware or handler that wrote first, for example because the chain is
+// misordered or an outer middleware decides after calling next, would let the output
+// through before the rejection.
+//
+// In Debug mode it panics with label and the stack of the first write. Otherwise it logs
+// an error with label and the request path and returns, the rejection has no effect then.
+//
+// Example:
+//
+//	if !allowed {
+//		c.AssertNotWritten("ipfilter")
+//		return c.WriteHeader(http.StatusForbidden)
+//	}
+func (c *Context) AssertNotWritten(label string) {
+	if c.rw == nil || !c.rw.statusSent {
+		return
+	}
+
+	if Debug {
+		panic(fmt.Sprintf("rex: %s: the response was already written with status %d before the decision. First write:\n%s",
+			label, c.rw.status, formatCallers(c.rw.firstWrite)))
+	}
+
+	c.router.logger.Error("response written before the decision of a middleware",
+		"label", label, "status", c.rw.status, "path", c.Request.URL.Path)
+}
+
+// callers returns the program counters of the stack, skipping skip frames like runtime.Callers.
+func callers(skip int) []uintptr {
+	pcs := make([]uintptr, 32)
+	return pcs[:runtime.Callers(skip, pcs)]
+}
+
+// formatCallers formats program counters like a stack trace, one "function\n\tfile:line" per frame.
+func formatCallers(pcs []uintptr) string {
+	if len(pcs) == 0 {
+		return "\t(unknown)\n"
+	}
+
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
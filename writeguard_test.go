@@ -0,0 +1,83 @@
+package rex_test
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/abiiranathan/rex"
+	"github.com/abiiranathan/rex/middleware/auth"
+)
+
+// eagerBanner writes a banner before calling next, ahead of the auth middleware.
+func eagerBanner(next rex.HandlerFunc) rex.HandlerFunc {
+	return func(c *rex.Context) error {
+		c.Write([]byte("welcome\n"))
+		return next(c)
+	}
+}
+
+func misorderedRouter(options ...rex.RouterOption) *rex.Router {
+	r := rex.NewRouter(options...)
+	r.GET("/admin", func(c *rex.Context) error {
+		return c.String("secret")
+	}, eagerBanner, auth.BasicAuth("admin", "pw"))
+	return r
+}
+
+func TestAssertNotWrittenPanicsInDebug(t *testing.T) {
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	r := misorderedRouter()
+
+	var msg string
+	func() {
+		defer func() { msg = fmt.Sprint(recover()) }()
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/admin", nil))
+	}()
+
+	if !strings.Contains(msg, "auth.BasicAuth: the response was already written") {
+		t.Fatalf("expected a panic with the label, got %q", msg)
+	}
+
+	if !strings.Contains(msg, "eagerBanner") {
+		t.Errorf("expected the stack of the first write, got %q", msg)
+	}
+}
+
+func TestAssertNotWrittenLogsInProduction(t *testing.T) {
+	var logs bytes.Buffer
+	r := misorderedRouter(rex.WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+
+	if w.Code != http.StatusOK || w.Body.String() != "welcome\n" {
+		t.Errorf("expected the request to continue with the written response, got %d %q", w.Code, w.Body.String())
+	}
+
+	if !strings.Contains(logs.String(), "label=auth.BasicAuth") || !strings.Contains(logs.String(), "path=/admin") {
+		t.Errorf("expected the label to be logged, got %q", logs.String())
+	}
+}
+
+func TestAssertNotWrittenBeforeWrite(t *testing.T) {
+	rex.Debug = true
+	defer func() { rex.Debug = false }()
+
+	r := rex.NewRouter()
+	r.GET("/admin", func(c *rex.Context) error {
+		return c.String("secret")
+	}, auth.BasicAuth("admin", "pw"))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401, got %d", w.Code)
+	}
+}
@@ -15,8 +15,55 @@ type ResponseWriter struct {
 	status     int                 // The status code of the response
 	size       int                 // The size of the response sent so far
 	statusSent bool                // If the status has been sent
-	skipBody   bool                // If its a HEAD request, we should skip the body
+	skipBody   bool                // Skip the body of HEAD requests and 1xx, 204 and 304 responses
 	latency    time.Duration       // The latency of the response.
+
+	// Called once in order before the status is written, e.g. to set the Server-Timing header.
+	beforeWrite []func()
+
+	// Hooks registered with Context.BeforeWriteHeader and the error of the one that rejected
+	// the response. Writes fail with headerErr until the router hands it to the error handler.
+	beforeHeader []func() error
+	headerErr    error
+
+	// Trailers set with SetTrailer and the keys announced with DeclareTrailers.
+	trailers http.Header
+	declared map[string]bool
+
+	// Response size limit set with WithMaxResponseBytes, 0 if unlimited.
+	limit     int64
+	exceeded  bool  // A write exceeded the limit.
+	attempted int64 // Size the response would have had with the rejected write.
+
+	// Callers of the first write in Debug mode, reported by Context.AssertNotWritten.
+	firstWrite []uintptr
+}
+
+// onBeforeWrite registers fn to run right before the status is written.
+// It has no effect once the header has been written.
+func (w *ResponseWriter) onBeforeWrite(fn func()) {
+	if w.statusSent {
+		return
+	}
+	w.beforeWrite = append(w.beforeWrite, fn)
+}
+
+// checkHeader runs the BeforeWriteHeader hooks once, with status reported by Status.
+// If one fails, nothing is sent and the status is restored.
+func (w *ResponseWriter) checkHeader(status int) bool {
+	hooks := w.beforeHeader
+	w.beforeHeader = nil
+
+	previous := w.status
+	w.status = status
+	for _, hook := range hooks {
+		if err := hook(); err != nil {
+			w.status = previous
+			w.headerErr = err
+			return false
+		}
+	}
+	return true
 }
 
 // ResponseWriter interface
@@ -27,20 +74,57 @@ func (rw *ResponseWriter) Header() http.Header {
 // WriteHeader writes the status code to the response.
 // Calling the method more than once will have no effect.
 func (w *ResponseWriter) WriteHeader(status int) {
-	if w.statusSent {
+	if w.statusSent || w.headerErr != nil {
+		return
+	}
+
+	if len(w.beforeHeader) > 0 && !w.checkHeader(status) {
 		return
 	}
+
+	hooks := w.beforeWrite
+	w.beforeWrite = nil
+	for _, hook := range hooks {
+		hook()
+	}
+
+	// Responses with these statuses must not have a body (RFC 9110).
+	if !bodyAllowed(status) {
+		w.skipBody = true
+		w.writer.Header().Del("Content-Length")
+	}
+
+	if Debug {
+		w.firstWrite = callers(3)
+	}
+
 	w.status = status
 	w.writer.WriteHeader(status)
 	w.statusSent = true
 }
 
+// bodyAllowed reports whether a response with status may have a body.
+func bodyAllowed(status int) bool {
+	return (status < 100 || status >= 200) && status != http.StatusNoContent && status != http.StatusNotModified
+}
+
 // Write writes the data to the connection as part of an HTTP reply.
 // Satisfies the io.Writer interface.
 // Calling this with a HEAD request will only write the headers if they haven't been written yet.
 func (w *ResponseWriter) Write(b []byte) (int, error) {
+	if w.headerErr != nil {
+		return 0, w.headerErr
+	}
+
+	if !w.skipBody && w.exceedsLimit(len(b)) {
+		return 0, ErrResponseTooLarge
+	}
+
 	if !w.statusSent {
 		w.WriteHeader(http.StatusOK)
+		if w.headerErr != nil {
+			return 0, w.headerErr
+		}
 	}
 
 	// If it's a HEAD request, we should skip the body
@@ -61,9 +145,80 @@ func (w *ResponseWriter) Size() int {
 	return w.size
 }
 
+// WroteHeader reports whether the status and headers have been sent.
+func (w *ResponseWriter) WroteHeader() bool {
+	return w.statusSent
+}
+
+// DeclareTrailers announces the trailer keys in the Trailer header.
+// It must be called before the header is written, otherwise it returns an error.
+func (w *ResponseWriter) DeclareTrailers(keys ...string) error {
+	if w.statusSent {
+		return fmt.Errorf("rex: trailers must be declared before the response is written")
+	}
+
+	if w.declared == nil {
+		w.declared = make(map[string]bool)
+	}
+
+	for _, key := range keys {
+		key = http.CanonicalHeaderKey(key)
+		if !w.declared[key] {
+			w.declared[key] = true
+			w.writer.Header().Add("Trailer", key)
+		}
+	}
+	return nil
+}
+
+// SetTrailer sets a trailer that is sent after the body when the handler completes.
+// Undeclared trailers are sent with http.TrailerPrefix, which only works for
+// chunked HTTP/1.1 and HTTP/2 responses; declare them with DeclareTrailers to be safe.
+func (w *ResponseWriter) SetTrailer(key, value string) {
+	if w.trailers == nil {
+		w.trailers = make(http.Header)
+	}
+	w.trailers.Set(key, value)
+}
+
+// writeTrailers moves the trailers into the header map after the body was written.
+func (w *ResponseWriter) writeTrailers() {
+	if len(w.trailers) == 0 {
+		return
+	}
+
+	if !w.statusSent {
+		w.WriteHeader(w.status)
+	}
+
+	header := w.writer.Header()
+	for key, values := range w.trailers {
+		if !w.declared[key] {
+			key = http.TrailerPrefix + key
+		}
+		header[key] = values
+	}
+	w.trailers = nil
+}
+
+// Written reports whether any part of the body has been written.
+// Once output has started, a new response can not be written on the same connection.
+func (w *ResponseWriter) Written() bool {
+	return w.size > 0
+}
+
 // Implements the http.Flusher interface to allow an HTTP handler to flush buffered data to the client.
 // This is useful for chunked responses and server-sent events.
 func (w *ResponseWriter) Flush() {
+	if !w.statusSent {
+		// Flushing sends the status, run the hooks first.
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if w.headerErr != nil {
+		return
+	}
+
 	if f, ok := w.writer.(http.Flusher); ok {
 		f.Flush()
 	}
@@ -81,11 +236,23 @@ func (w *ResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 // All data is written in a single call to Write, so the data should be buffered.
 // The return value is the number of bytes written and an error, if any.
 func (w *ResponseWriter) ReadFrom(r io.Reader) (n int64, err error) {
+	if w.limit > 0 {
+		return w.limitedReadFrom(r)
+	}
+
 	if !w.statusSent {
 		// The status will be StatusOK if WriteHeader has not been called yet
 		w.WriteHeader(http.StatusOK)
 	}
 
+	if w.headerErr != nil {
+		return 0, w.headerErr
+	}
+
+	if w.skipBody {
+		return io.Copy(io.Discard, r)
+	}
+
 	n, err = io.Copy(w.writer, r)
 	w.size += int(n)
 	return
@@ -96,3 +263,10 @@ func (w *ResponseWriter) ReadFrom(r io.Reader) (n int64, err error) {
 func (w *ResponseWriter) Unwrap() http.ResponseWriter {
 	return w.writer
 }
+
+// responseState is implemented by response writers that track what has been sent.
+// Writers swapped in by middleware can implement it to report their own state.
+type responseState interface {
+	WroteHeader() bool
+	Size() int
+}